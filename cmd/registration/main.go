@@ -58,7 +58,7 @@ func newRegistrationCommand() *cobra.Command {
 
 	cmd.AddCommand(hub.NewController())
 	cmd.AddCommand(spoke.NewAgent())
-	cmd.AddCommand(webhook.NewAdmissionHook())
+	cmd.AddCommand(webhook.NewWebhookServer())
 
 	return cmd
 }
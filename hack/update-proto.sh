@@ -0,0 +1,11 @@
+#!/bin/bash
+
+# Generates the messages and the stubs of the gRPC Registration service from pkg/grpcregistration/registration.proto.
+# It requires protoc, protoc-gen-go v1.27.1 and protoc-gen-go-grpc v1.1.0 in the PATH.
+
+cd "$(dirname "${BASH_SOURCE}")/../pkg/grpcregistration"
+
+protoc --proto_path=. \
+    --go_out=. --go_opt=paths=source_relative \
+    --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+    registration.proto
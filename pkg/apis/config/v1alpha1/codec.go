@@ -0,0 +1,59 @@
+package v1alpha1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+var (
+	scheme = runtime.NewScheme()
+	// the unknown and duplicated fields are rejected, so the typos in the configuration files are reported
+	codecs = serializer.NewCodecFactory(scheme, serializer.EnableStrict)
+)
+
+func init() {
+	utilruntime.Must(AddToScheme(scheme))
+}
+
+// DecodeHubConfiguration decodes a hub configuration from yaml or json data, and sets the default values
+func DecodeHubConfiguration(data []byte) (*HubConfiguration, error) {
+	obj, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	config, ok := obj.(*HubConfiguration)
+	if !ok {
+		return nil, fmt.Errorf("expected HubConfiguration, but got %T", obj)
+	}
+	return config, nil
+}
+
+// DecodeSpokeAgentConfiguration decodes an agent configuration from yaml or json data, and sets the default values
+func DecodeSpokeAgentConfiguration(data []byte) (*SpokeAgentConfiguration, error) {
+	obj, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	config, ok := obj.(*SpokeAgentConfiguration)
+	if !ok {
+		return nil, fmt.Errorf("expected SpokeAgentConfiguration, but got %T", obj)
+	}
+	return config, nil
+}
+
+// Encode encodes a configuration to json data
+func Encode(obj runtime.Object) ([]byte, error) {
+	return runtime.Encode(codecs.LegacyCodec(SchemeGroupVersion), obj)
+}
+
+func decode(data []byte) (runtime.Object, error) {
+	obj, _, err := codecs.UniversalDeserializer().Decode(data, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	scheme.Default(obj)
+	return obj, nil
+}
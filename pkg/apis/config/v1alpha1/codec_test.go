@@ -0,0 +1,227 @@
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDecodeHubConfiguration(t *testing.T) {
+	cases := []struct {
+		name           string
+		data           string
+		expectedErr    bool
+		expectedConfig *HubConfiguration
+	}{
+		{
+			name: "defaults",
+			data: `apiVersion: registration.config.open-cluster-management.io/v1alpha1
+kind: HubConfiguration
+`,
+			expectedConfig: &HubConfiguration{WebhookFailurePolicy: "Fail"},
+		},
+		{
+			name: "full configuration",
+			data: `apiVersion: registration.config.open-cluster-management.io/v1alpha1
+kind: HubConfiguration
+featureGates:
+  DefaultClusterSet: true
+webhookFailurePolicy: Ignore
+webhookExcludedNamespaces:
+- kube-system
+`,
+			expectedConfig: &HubConfiguration{
+				FeatureGates:              map[string]bool{"DefaultClusterSet": true},
+				WebhookFailurePolicy:      "Ignore",
+				WebhookExcludedNamespaces: []string{"kube-system"},
+			},
+		},
+		{
+			name: "unknown field",
+			data: `apiVersion: registration.config.open-cluster-management.io/v1alpha1
+kind: HubConfiguration
+webhookFailurePolicies: Ignore
+`,
+			expectedErr: true,
+		},
+		{
+			name: "unexpected kind",
+			data: `apiVersion: registration.config.open-cluster-management.io/v1alpha1
+kind: SpokeAgentConfiguration
+`,
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config, err := DecodeHubConfiguration([]byte(c.data))
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			config.TypeMeta = metav1.TypeMeta{}
+			if !reflect.DeepEqual(config, c.expectedConfig) {
+				t.Errorf("expected %#v, but got %#v", c.expectedConfig, config)
+			}
+		})
+	}
+}
+
+func TestDecodeSpokeAgentConfiguration(t *testing.T) {
+	cases := []struct {
+		name           string
+		data           string
+		expectedErr    bool
+		expectedConfig *SpokeAgentConfiguration
+	}{
+		{
+			name: "defaults",
+			data: `{"apiVersion":"registration.config.open-cluster-management.io/v1alpha1","kind":"SpokeAgentConfiguration"}`,
+			expectedConfig: &SpokeAgentConfiguration{
+				HubKubeconfigSecret:              "hub-kubeconfig-secret",
+				HubKubeconfigDir:                 "/spoke/hub-kubeconfig",
+				ClusterHealthCheckPeriod:         metav1.Duration{Duration: time.Minute},
+				MaxCustomClusterClaims:           int32Ptr(20),
+				MaxConcurrentAddOnRegistrations:  int32Ptr(10),
+				AddOnRegistrationStaggerInterval: metav1.Duration{Duration: 2 * time.Second},
+				ControllerProgressDeadline:       metav1.Duration{Duration: 10 * time.Minute},
+				SlowSyncThreshold:                metav1.Duration{Duration: 10 * time.Second},
+				RetryBaseDelay:                   metav1.Duration{Duration: 5 * time.Millisecond},
+				RetryMaxDelay:                    metav1.Duration{Duration: 1000 * time.Second},
+				RetryQPS:                         10,
+			},
+		},
+		{
+			name: "disable addon registration throttling",
+			data: `apiVersion: registration.config.open-cluster-management.io/v1alpha1
+kind: SpokeAgentConfiguration
+clusterName: cluster1
+bootstrapKubeconfig: /spoke/bootstrap/kubeconfig
+clusterHealthCheckPeriod: 30s
+maxConcurrentAddOnRegistrations: 0
+`,
+			expectedConfig: &SpokeAgentConfiguration{
+				ClusterName:                      "cluster1",
+				BootstrapKubeconfig:              "/spoke/bootstrap/kubeconfig",
+				HubKubeconfigSecret:              "hub-kubeconfig-secret",
+				HubKubeconfigDir:                 "/spoke/hub-kubeconfig",
+				ClusterHealthCheckPeriod:         metav1.Duration{Duration: 30 * time.Second},
+				MaxCustomClusterClaims:           int32Ptr(20),
+				MaxConcurrentAddOnRegistrations:  int32Ptr(0),
+				AddOnRegistrationStaggerInterval: metav1.Duration{Duration: 2 * time.Second},
+				ControllerProgressDeadline:       metav1.Duration{Duration: 10 * time.Minute},
+				SlowSyncThreshold:                metav1.Duration{Duration: 10 * time.Second},
+				RetryBaseDelay:                   metav1.Duration{Duration: 5 * time.Millisecond},
+				RetryMaxDelay:                    metav1.Duration{Duration: 1000 * time.Second},
+				RetryQPS:                         10,
+			},
+		},
+		{
+			name: "unknown version",
+			data: `apiVersion: registration.config.open-cluster-management.io/v1
+kind: SpokeAgentConfiguration
+`,
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config, err := DecodeSpokeAgentConfiguration([]byte(c.data))
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			config.TypeMeta = metav1.TypeMeta{}
+			if !reflect.DeepEqual(config, c.expectedConfig) {
+				t.Errorf("expected %#v, but got %#v", c.expectedConfig, config)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	hubConfig := &HubConfiguration{
+		FeatureGates:              map[string]bool{"DefaultClusterSet": true},
+		WebhookFailurePolicy:      "Ignore",
+		WebhookExcludedNamespaces: []string{"kube-system", "open-cluster-management"},
+	}
+	data, err := Encode(hubConfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decodedHubConfig, err := DecodeHubConfiguration(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decodedHubConfig.TypeMeta = metav1.TypeMeta{}
+	if !reflect.DeepEqual(hubConfig, decodedHubConfig) {
+		t.Errorf("expected %#v, but got %#v", hubConfig, decodedHubConfig)
+	}
+
+	agentConfig := &SpokeAgentConfiguration{
+		FeatureGates:                     map[string]bool{"AddonManagement": true},
+		ClusterName:                      "cluster1",
+		BootstrapKubeconfig:              "/spoke/bootstrap/kubeconfig",
+		HubKubeconfigSecret:              "secret1",
+		HubKubeconfigDir:                 "/spoke/hub",
+		SpokeKubeconfig:                  "/spoke/kubeconfig",
+		SpokeExternalServerURLs:          []string{"https://127.0.0.1:6443"},
+		ClusterHealthCheckPeriod:         metav1.Duration{Duration: 30 * time.Second},
+		MaxCustomClusterClaims:           int32Ptr(5),
+		MaxConcurrentAddOnRegistrations:  int32Ptr(0),
+		AddOnRegistrationStaggerInterval: metav1.Duration{Duration: time.Second},
+		ControllerProgressDeadline:       metav1.Duration{Duration: 5 * time.Minute},
+		SlowSyncThreshold:                metav1.Duration{Duration: 5 * time.Second},
+		RetryBaseDelay:                   metav1.Duration{Duration: 10 * time.Millisecond},
+		RetryMaxDelay:                    metav1.Duration{Duration: time.Minute},
+		RetryQPS:                         2.5,
+	}
+	data, err = Encode(agentConfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decodedAgentConfig, err := DecodeSpokeAgentConfiguration(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decodedAgentConfig.TypeMeta = metav1.TypeMeta{}
+	if !reflect.DeepEqual(agentConfig, decodedAgentConfig) {
+		t.Errorf("expected %#v, but got %#v", agentConfig, decodedAgentConfig)
+	}
+}
+
+func TestValidateSpokeAgentConfiguration(t *testing.T) {
+	config := &SpokeAgentConfiguration{
+		SpokeExternalServerURLs:         []string{"http://127.0.0.1:8080"},
+		MaxConcurrentAddOnRegistrations: int32Ptr(-1),
+		RetryMaxDelay:                   metav1.Duration{Duration: -time.Second},
+	}
+	SetDefaults_SpokeAgentConfiguration(config)
+
+	errs := ValidateSpokeAgentConfiguration(config)
+	expected := "[spokeExternalServerURLs[0]: Invalid value: \"http://127.0.0.1:8080\": must be a https url, " +
+		"maxConcurrentAddOnRegistrations: Invalid value: -1: must be greater than or equal to zero, " +
+		"retryMaxDelay: Invalid value: \"-1s\": must be greater than zero, " +
+		"retryMaxDelay: Invalid value: \"-1s\": must not be less than retryBaseDelay]"
+	if errs.ToAggregate() == nil || errs.ToAggregate().Error() != expected {
+		t.Errorf("expected %q, but got %v", expected, errs.ToAggregate())
+	}
+
+	if errs := ValidateHubConfiguration(&HubConfiguration{WebhookFailurePolicy: "Retry"}); len(errs) != 1 {
+		t.Errorf("expected one error, but got %v", errs)
+	}
+}
@@ -0,0 +1,84 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto copies the receiver into out
+func (in *HubConfiguration) DeepCopyInto(out *HubConfiguration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.FeatureGates = copyFeatureGates(in.FeatureGates)
+	if in.WebhookExcludedNamespaces != nil {
+		out.WebhookExcludedNamespaces = make([]string, len(in.WebhookExcludedNamespaces))
+		copy(out.WebhookExcludedNamespaces, in.WebhookExcludedNamespaces)
+	}
+}
+
+// DeepCopy returns a deep copy of the receiver
+func (in *HubConfiguration) DeepCopy() *HubConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(HubConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject returns a deep copy of the receiver as a runtime.Object
+func (in *HubConfiguration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto copies the receiver into out
+func (in *SpokeAgentConfiguration) DeepCopyInto(out *SpokeAgentConfiguration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.FeatureGates = copyFeatureGates(in.FeatureGates)
+	if in.SpokeExternalServerURLs != nil {
+		out.SpokeExternalServerURLs = make([]string, len(in.SpokeExternalServerURLs))
+		copy(out.SpokeExternalServerURLs, in.SpokeExternalServerURLs)
+	}
+	out.ClusterHealthCheckPeriod = in.ClusterHealthCheckPeriod
+	if in.MaxCustomClusterClaims != nil {
+		out.MaxCustomClusterClaims = new(int32)
+		*out.MaxCustomClusterClaims = *in.MaxCustomClusterClaims
+	}
+	if in.MaxConcurrentAddOnRegistrations != nil {
+		out.MaxConcurrentAddOnRegistrations = new(int32)
+		*out.MaxConcurrentAddOnRegistrations = *in.MaxConcurrentAddOnRegistrations
+	}
+	out.AddOnRegistrationStaggerInterval = in.AddOnRegistrationStaggerInterval
+}
+
+// DeepCopy returns a deep copy of the receiver
+func (in *SpokeAgentConfiguration) DeepCopy() *SpokeAgentConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(SpokeAgentConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject returns a deep copy of the receiver as a runtime.Object
+func (in *SpokeAgentConfiguration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+func copyFeatureGates(in map[string]bool) map[string]bool {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]bool, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+package v1alpha1
+
+import (
+	"time"
+)
+
+// SetDefaults_HubConfiguration sets the default values of the hub configuration, which are the same as the
+// defaults of the flags.
+func SetDefaults_HubConfiguration(obj *HubConfiguration) {
+	if len(obj.WebhookFailurePolicy) == 0 {
+		obj.WebhookFailurePolicy = "Fail"
+	}
+}
+
+// SetDefaults_SpokeAgentConfiguration sets the default values of the agent configuration, which are the same as the
+// defaults of the flags.
+func SetDefaults_SpokeAgentConfiguration(obj *SpokeAgentConfiguration) {
+	if len(obj.HubKubeconfigSecret) == 0 {
+		obj.HubKubeconfigSecret = "hub-kubeconfig-secret"
+	}
+	if len(obj.HubKubeconfigDir) == 0 {
+		obj.HubKubeconfigDir = "/spoke/hub-kubeconfig"
+	}
+	if obj.ClusterHealthCheckPeriod.Duration == 0 {
+		obj.ClusterHealthCheckPeriod.Duration = 1 * time.Minute
+	}
+	if obj.MaxCustomClusterClaims == nil {
+		obj.MaxCustomClusterClaims = int32Ptr(20)
+	}
+	if obj.MaxConcurrentAddOnRegistrations == nil {
+		obj.MaxConcurrentAddOnRegistrations = int32Ptr(10)
+	}
+	if obj.AddOnRegistrationStaggerInterval.Duration == 0 {
+		obj.AddOnRegistrationStaggerInterval.Duration = 2 * time.Second
+	}
+	if obj.ControllerProgressDeadline.Duration == 0 {
+		obj.ControllerProgressDeadline.Duration = 10 * time.Minute
+	}
+	if obj.SlowSyncThreshold.Duration == 0 {
+		obj.SlowSyncThreshold.Duration = 10 * time.Second
+	}
+	if obj.RetryBaseDelay.Duration == 0 {
+		obj.RetryBaseDelay.Duration = 5 * time.Millisecond
+	}
+	if obj.RetryMaxDelay.Duration == 0 {
+		obj.RetryMaxDelay.Duration = 1000 * time.Second
+	}
+	if obj.RetryQPS == 0 {
+		obj.RetryQPS = 10
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
@@ -0,0 +1,7 @@
+// Package v1alpha1 contains the configuration API of the registration hub controller and the registration agent.
+// The configuration is read from the file passed with --config, and the flags set on the command line take
+// precedence over it.
+//
+// +k8s:deepcopy-gen=package
+// +groupName=registration.config.open-cluster-management.io
+package v1alpha1
@@ -0,0 +1,37 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupName is the group name of the configuration API
+const GroupName = "registration.config.open-cluster-management.io"
+
+var (
+	// SchemeGroupVersion is the group version of the configuration API
+	SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha1"}
+
+	// SchemeBuilder registers the types and the defaulting funcs of the configuration API
+	SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes, addDefaultingFuncs)
+	// AddToScheme adds the types and the defaulting funcs of the configuration API to a scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&HubConfiguration{},
+		&SpokeAgentConfiguration{},
+	)
+	return nil
+}
+
+func addDefaultingFuncs(scheme *runtime.Scheme) error {
+	scheme.AddTypeDefaultingFunc(&HubConfiguration{}, func(obj interface{}) {
+		SetDefaults_HubConfiguration(obj.(*HubConfiguration))
+	})
+	scheme.AddTypeDefaultingFunc(&SpokeAgentConfiguration{}, func(obj interface{}) {
+		SetDefaults_SpokeAgentConfiguration(obj.(*SpokeAgentConfiguration))
+	})
+	return nil
+}
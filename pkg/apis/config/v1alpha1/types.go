@@ -0,0 +1,102 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HubConfiguration is the configuration of the registration hub controller, the fields match the flags of the
+// hub controller.
+type HubConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// FeatureGates enables or disables the features of the hub controller, see --feature-gates.
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+
+	// WebhookFailurePolicy is the failure policy of the registration webhooks, Fail or Ignore. Defaults to Fail.
+	// +optional
+	WebhookFailurePolicy string `json:"webhookFailurePolicy,omitempty"`
+
+	// WebhookExcludedNamespaces are the namespaces whose objects are not validated or mutated by the registration
+	// webhooks.
+	// +optional
+	WebhookExcludedNamespaces []string `json:"webhookExcludedNamespaces,omitempty"`
+}
+
+// SpokeAgentConfiguration is the configuration of the registration agent, the fields match the flags of the agent.
+// The agent reloads the configuration file once it is changed. The feature gates checked whenever the features are
+// used, ClusterHealthCheckPeriod, ControllerProgressDeadline, SlowSyncThreshold and the retry limits are applied
+// while the agent runs, and the agent is restarted if any of the other fields is changed.
+type SpokeAgentConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// FeatureGates enables or disables the features of the agent, see --feature-gates.
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+
+	// ClusterName is the name of the managed cluster. A random name is generated if it is not set.
+	// +optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// BootstrapKubeconfig is the path of the kubeconfig file for agent bootstrap.
+	// +optional
+	BootstrapKubeconfig string `json:"bootstrapKubeconfig,omitempty"`
+
+	// HubKubeconfigSecret is the name of secret in component namespace storing kubeconfig for hub. Defaults to
+	// hub-kubeconfig-secret.
+	// +optional
+	HubKubeconfigSecret string `json:"hubKubeconfigSecret,omitempty"`
+
+	// HubKubeconfigDir is the mount path of the hub kubeconfig secret in the container. Defaults to
+	// /spoke/hub-kubeconfig.
+	// +optional
+	HubKubeconfigDir string `json:"hubKubeconfigDir,omitempty"`
+
+	// SpokeKubeconfig is the path of the kubeconfig file for the managed cluster.
+	// +optional
+	SpokeKubeconfig string `json:"spokeKubeconfig,omitempty"`
+
+	// SpokeExternalServerURLs are the https URLs of the managed cluster apiserver reachable from the hub.
+	// +optional
+	SpokeExternalServerURLs []string `json:"spokeExternalServerURLs,omitempty"`
+
+	// ClusterHealthCheckPeriod is the period to check the health of the managed cluster apiserver. Defaults to 1m.
+	// +optional
+	ClusterHealthCheckPeriod metav1.Duration `json:"clusterHealthCheckPeriod,omitempty"`
+
+	// MaxCustomClusterClaims is the max number of custom cluster claims to expose. Defaults to 20.
+	// +optional
+	MaxCustomClusterClaims *int32 `json:"maxCustomClusterClaims,omitempty"`
+
+	// MaxConcurrentAddOnRegistrations is the max number of addon registrations in flight, i.e. waiting for their
+	// client certificates to be issued, 0 disables the limit. Defaults to 10.
+	// +optional
+	MaxConcurrentAddOnRegistrations *int32 `json:"maxConcurrentAddOnRegistrations,omitempty"`
+
+	// AddOnRegistrationStaggerInterval is the interval to retry the addon registrations deferred once
+	// MaxConcurrentAddOnRegistrations is reached. Defaults to 2s.
+	// +optional
+	AddOnRegistrationStaggerInterval metav1.Duration `json:"addOnRegistrationStaggerInterval,omitempty"`
+
+	// ControllerProgressDeadline is the period a controller with pending work is allowed to make no progress before
+	// its health check fails. Defaults to 10m.
+	// +optional
+	ControllerProgressDeadline metav1.Duration `json:"controllerProgressDeadline,omitempty"`
+
+	// SlowSyncThreshold is the duration after which a sync of a controller is reported as slow. Defaults to 10s.
+	// +optional
+	SlowSyncThreshold metav1.Duration `json:"slowSyncThreshold,omitempty"`
+
+	// RetryBaseDelay is the delay before the first retry of a key failed to sync, it doubles on each failure up to
+	// RetryMaxDelay. Defaults to 5ms.
+	// +optional
+	RetryBaseDelay metav1.Duration `json:"retryBaseDelay,omitempty"`
+
+	// RetryMaxDelay is the max delay before a retry of a key failed to sync. Defaults to 1000s.
+	// +optional
+	RetryMaxDelay metav1.Duration `json:"retryMaxDelay,omitempty"`
+
+	// RetryQPS is the max number of the retries of the failed keys per second of a controller. Defaults to 10.
+	// +optional
+	RetryQPS float32 `json:"retryQPS,omitempty"`
+}
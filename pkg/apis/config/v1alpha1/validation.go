@@ -0,0 +1,78 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// ValidateHubConfiguration validates a defaulted hub configuration
+func ValidateHubConfiguration(obj *HubConfiguration) field.ErrorList {
+	errs := field.ErrorList{}
+
+	switch obj.WebhookFailurePolicy {
+	case "Fail", "Ignore":
+	default:
+		errs = append(errs, field.NotSupported(field.NewPath("webhookFailurePolicy"), obj.WebhookFailurePolicy, []string{"Fail", "Ignore"}))
+	}
+
+	return errs
+}
+
+// ValidateSpokeAgentConfiguration validates a defaulted agent configuration
+func ValidateSpokeAgentConfiguration(obj *SpokeAgentConfiguration) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for i, serverURL := range obj.SpokeExternalServerURLs {
+		if !helpers.IsValidHTTPSURL(serverURL) {
+			errs = append(errs, field.Invalid(field.NewPath("spokeExternalServerURLs").Index(i), serverURL, "must be a https url"))
+		}
+	}
+
+	if obj.ClusterHealthCheckPeriod.Duration <= 0 {
+		errs = append(errs, field.Invalid(field.NewPath("clusterHealthCheckPeriod"), obj.ClusterHealthCheckPeriod.String(),
+			"must be greater than zero"))
+	}
+
+	if obj.MaxCustomClusterClaims != nil && *obj.MaxCustomClusterClaims < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("maxCustomClusterClaims"), *obj.MaxCustomClusterClaims,
+			"must be greater than or equal to zero"))
+	}
+
+	if obj.MaxConcurrentAddOnRegistrations != nil && *obj.MaxConcurrentAddOnRegistrations < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("maxConcurrentAddOnRegistrations"), *obj.MaxConcurrentAddOnRegistrations,
+			"must be greater than or equal to zero"))
+	}
+
+	if obj.AddOnRegistrationStaggerInterval.Duration <= 0 {
+		errs = append(errs, field.Invalid(field.NewPath("addOnRegistrationStaggerInterval"), obj.AddOnRegistrationStaggerInterval.String(),
+			"must be greater than zero"))
+	}
+
+	for _, duration := range []struct {
+		name  string
+		value metav1.Duration
+	}{
+		{name: "controllerProgressDeadline", value: obj.ControllerProgressDeadline},
+		{name: "slowSyncThreshold", value: obj.SlowSyncThreshold},
+		{name: "retryBaseDelay", value: obj.RetryBaseDelay},
+		{name: "retryMaxDelay", value: obj.RetryMaxDelay},
+	} {
+		if duration.value.Duration <= 0 {
+			errs = append(errs, field.Invalid(field.NewPath(duration.name), duration.value.Duration.String(),
+				"must be greater than zero"))
+		}
+	}
+
+	if obj.RetryQPS <= 0 {
+		errs = append(errs, field.Invalid(field.NewPath("retryQPS"), obj.RetryQPS, "must be greater than zero"))
+	}
+
+	if obj.RetryMaxDelay.Duration < obj.RetryBaseDelay.Duration {
+		errs = append(errs, field.Invalid(field.NewPath("retryMaxDelay"), obj.RetryMaxDelay.Duration.String(),
+			"must not be less than retryBaseDelay"))
+	}
+
+	return errs
+}
@@ -2,10 +2,12 @@ package clientcert
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509/pkix"
+	"encoding/binary"
 	"fmt"
-	"math/rand"
+	"math"
 	"reflect"
 	"time"
 
@@ -13,11 +15,13 @@ import (
 	"github.com/openshift/library-go/pkg/operator/events"
 
 	"github.com/pkg/errors"
+	certificatesv1 "k8s.io/api/certificates/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	certificatesinformers "k8s.io/client-go/informers/certificates"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
@@ -25,7 +29,9 @@ import (
 	certutil "k8s.io/client-go/util/cert"
 	"k8s.io/client-go/util/keyutil"
 	"k8s.io/klog/v2"
+	"open-cluster-management.io/registration/pkg/debug"
 	"open-cluster-management.io/registration/pkg/features"
+	"open-cluster-management.io/registration/pkg/health"
 	"open-cluster-management.io/registration/pkg/helpers"
 )
 
@@ -45,6 +51,38 @@ const (
 	AddonNameLabel   = "open-cluster-management.io/addon-name"
 )
 
+const (
+	// CertificateOriginAnnotation is set on the secret once the controller stores a new certificate in it, the value
+	// tells how the certificate is issued.
+	CertificateOriginAnnotation = "open-cluster-management.io/certificate-origin"
+	// CertificateOriginBootstrap means the certificate is issued while there is no valid certificate in the secret,
+	// e.g. the certificate requested with the bootstrap kubeconfig of the registration agent.
+	CertificateOriginBootstrap = "bootstrap"
+	// CertificateOriginRotated means the certificate is issued to replace a valid certificate in the secret.
+	CertificateOriginRotated = "rotated"
+)
+
+// clientCertUsages are the key usages of client certificates
+var clientCertUsages = []certificatesv1.KeyUsage{
+	certificatesv1.UsageDigitalSignature,
+	certificatesv1.UsageKeyEncipherment,
+	certificatesv1.UsageClientAuth,
+}
+
+// defaultRotationThreshold is the default ratio of the certificate lifetime remaining at which the client certificate
+// is rotated
+const defaultRotationThreshold = 0.2
+
+// defaultRotationJitterFactor is the default rotation jitter in proportion to the rotation threshold, or to the
+// lifetime beyond the threshold if it is less
+const defaultRotationJitterFactor = 0.25
+
+// defaultRotationJitter returns the default rotation jitter of the threshold, which always leaves the rotation point
+// below the whole lifetime of the certificate
+func defaultRotationJitter(threshold float64) float64 {
+	return math.Min(threshold, 1-threshold) * defaultRotationJitterFactor
+}
+
 // ControllerResyncInterval is exposed so that integration tests can crank up the constroller sync speed.
 var ControllerResyncInterval = 5 * time.Minute
 
@@ -59,9 +97,16 @@ type CSROption struct {
 	DNSNames []string
 	// SignerName is the name of the signer specified in the created csrs
 	SignerName string
+	// Usages are the key usages specified in the created csrs. The usages of client certificates are used
+	// if it is empty.
+	Usages []certificatesv1.KeyUsage
 
 	// EventFilterFunc matches csrs created with above options
 	EventFilterFunc factory.EventFilterFunc
+
+	// SignerChecker verifies the signer before a csr is created, no csr is created if the signer is not expected to
+	// sign it. All signers are accepted if it is nil.
+	SignerChecker SignerChecker
 }
 
 // ClientCertOption includes options that is used to create client certificate
@@ -76,6 +121,23 @@ type ClientCertOption struct {
 	// AdditonalSecretDataSensitive is true indicates the client cert is sensitive to the AdditonalSecretData.
 	// That means once AdditonalSecretData changes, the client cert will be recreated.
 	AdditionalSecretDataSensitive bool
+	// SecretLabels contains labels that will be added on the client certificate secret
+	SecretLabels map[string]string
+	// RotationThreshold is the ratio of the certificate lifetime remaining at which the client certificate
+	// will be rotated. The default value 0.2 is used if it is not set.
+	RotationThreshold float64
+	// RotationJitter is the ratio of the certificate lifetime the rotations are spread over. Each certificate is
+	// rotated at a point between RotationThreshold and RotationThreshold+RotationJitter of its life remaining, which
+	// is derived from the certificate, so the certificates issued at the same time, e.g. to the agents bootstrapped
+	// together, are not rotated at once. A quarter of RotationThreshold, or of the lifetime beyond it if it is less, is
+	// used if it is negative, and the certificate is rotated right at RotationThreshold if it is 0.
+	// RotationThreshold+RotationJitter must be less than 1.
+	RotationJitter float64
+	// KeyType and KeyBitSize are the type and the size of the private key generated for each csr, see ValidateKey.
+	// An ECDSA P-256 key is generated if they are not set. A certificate with another key is replaced at its next
+	// rotation.
+	KeyType    string
+	KeyBitSize int
 }
 
 // clientCertificateController implements the common logic of hub client certification creation/rotation. It
@@ -86,9 +148,12 @@ type ClientCertOption struct {
 type clientCertificateController struct {
 	ClientCertOption
 	CSROption
-	csrControl
+	csrControl CSRControl
+	// issuer issues the certificates instead of the csrs of the csrControl if it is set
+	issuer          CertificateIssuer
 	spokeCoreClient corev1client.CoreV1Interface
 	controllerName  string
+	statusUpdater   StatusUpdateFunc
 
 	// csrName is the name of csr created by controller and waiting for approval.
 	csrName string
@@ -101,69 +166,82 @@ type clientCertificateController struct {
 	//   3. csrName set, keyData set: we are waiting for a new cert to be signed.
 	//   4. csrName empty, keydata set: the CSR failed to create, this shouldn't happen, it's a bug.
 	keyData []byte
+
+	// csrCreatedTime is the time the controller created the csr, and issuedObservedTime is the time the controller
+	// observed the certificate issued for the csr the first time
+	csrCreatedTime     time.Time
+	issuedObservedTime time.Time
+
+	// certCache caches the certificate parsed from the secret, so it is not parsed on every sync
+	certCache certificateCache
+
+	// lazyCSRInformer is set if the csr informer watches the csrs only while it is acquired, the controller acquires
+	// it once it creates a csr and releases it once the csr is processed.
+	lazyCSRInformer lazyInformer
 }
 
-// NewClientCertificateController return an instance of clientCertificateController
-func NewClientCertificateController(
-	clientCertOption ClientCertOption,
-	csrOption CSROption,
-	hubCSRInformer certificatesinformers.Interface,
-	spokeSecretInformer corev1informers.SecretInformer,
-	spokeKubeClient kubernetes.Interface,
-	hubKubeClient kubernetes.Interface,
-	recorder events.Recorder,
-	controllerName string,
-) (factory.Controller, error) {
-	var csrCtrl csrControl = nil
+// lazyInformer is implemented by the informers which watch the objects only while they are acquired, see
+// helpers.LazyInformer
+type lazyInformer interface {
+	Acquire(ctx context.Context, holder string)
+	Release(holder string)
+}
+
+// NewCSRControl returns a CSRControl using the csr api served by the hub. The v1beta1 csr api is used only if the
+// V1beta1CSRAPICompatibility feature is enabled and the hub does not serve the v1 one.
+func NewCSRControl(hubCSRInformer certificatesinformers.Interface, hubKubeClient kubernetes.Interface) (CSRControl, error) {
 	if features.DefaultSpokeMutableFeatureGate.Enabled(features.V1beta1CSRAPICompatibility) {
 		v1CSRSupported, v1beta1CSRSupported, err := helpers.IsCSRSupported(hubKubeClient)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed CSR api discovery")
 		}
 		if !v1CSRSupported && v1beta1CSRSupported {
-			csrCtrl = &v1beta1CSRControl{
+			klog.Info("Using v1beta1 CSR api to manage spoke client certificate")
+			return &v1beta1CSRControl{
 				hubCSRInformer: hubCSRInformer.V1beta1().CertificateSigningRequests(),
 				hubCSRLister:   hubCSRInformer.V1beta1().CertificateSigningRequests().Lister(),
 				hubCSRClient:   hubKubeClient.CertificatesV1beta1().CertificateSigningRequests(),
-			}
-			klog.Info("Using v1beta1 CSR api to manage spoke client certificate")
-		}
-	}
-	if csrCtrl == nil {
-		csrCtrl = &v1CSRControl{
-			hubCSRInformer: hubCSRInformer.V1().CertificateSigningRequests(),
-			hubCSRLister:   hubCSRInformer.V1().CertificateSigningRequests().Lister(),
-			hubCSRClient:   hubKubeClient.CertificatesV1().CertificateSigningRequests(),
+			}, nil
 		}
 	}
-	return newClientCertificateController(
-		clientCertOption,
-		csrOption,
-		csrCtrl,
-		spokeSecretInformer,
-		spokeKubeClient.CoreV1(),
-		recorder,
-		controllerName), nil
+	return &v1CSRControl{
+		hubCSRInformer: hubCSRInformer.V1().CertificateSigningRequests(),
+		hubCSRLister:   hubCSRInformer.V1().CertificateSigningRequests().Lister(),
+		hubCSRClient:   hubKubeClient.CertificatesV1().CertificateSigningRequests(),
+	}, nil
 }
 
+// newClientCertificateController returns an instance of clientCertificateController with the completed options,
+// see NewController. The csrControl is nil if the certificates are issued by the issuer of the options.
 func newClientCertificateController(
-	clientCertOption ClientCertOption,
-	csrOption CSROption,
-	csrControl csrControl,
+	o *controllerOptions,
+	csrControl CSRControl,
 	spokeSecretInformer corev1informers.SecretInformer,
 	spokeCoreClient corev1client.CoreV1Interface,
 	recorder events.Recorder,
 	controllerName string,
 ) factory.Controller {
 	c := clientCertificateController{
-		ClientCertOption: clientCertOption,
-		CSROption:        csrOption,
+		ClientCertOption: o.ClientCertOption,
+		CSROption:        o.CSROption,
 		csrControl:       csrControl,
+		issuer:           o.issuer,
 		spokeCoreClient:  spokeCoreClient,
 		controllerName:   controllerName,
+		statusUpdater:    o.statusUpdater,
+	}
+
+	controllerFactory := factory.New()
+	if csrControl != nil {
+		if informer, ok := csrControl.Informer().(lazyInformer); ok {
+			c.lazyCSRInformer = informer
+		}
+		controllerFactory = controllerFactory.WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, c.EventFilterFunc, csrControl.Informer())
 	}
 
-	return factory.New().
+	return controllerFactory.
 		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
 			return factory.DefaultQueueKey
 		}, func(obj interface{}) bool {
@@ -177,15 +255,14 @@ func newClientCertificateController(
 			}
 			return false
 		}, spokeSecretInformer.Informer()).
-		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
-			return factory.DefaultQueueKey
-		}, c.EventFilterFunc, csrControl.informer()).
-		WithSync(c.sync).
+		WithSync(health.WrapSync(controllerName, c.sync)).
 		ResyncEvery(ControllerResyncInterval).
 		ToController(controllerName, recorder)
 }
 
 func (c *clientCertificateController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	defer c.recordState()
+
 	// get secret containing client certificate
 	secret, err := c.spokeCoreClient.Secrets(c.SecretNamespace).Get(ctx, c.SecretName, metav1.GetOptions{})
 	switch {
@@ -199,6 +276,7 @@ func (c *clientCertificateController) sync(ctx context.Context, syncCtx factory.
 	case err != nil:
 		return fmt.Errorf("unable to get secret %q: %w", c.SecretNamespace+"/"+c.SecretName, err)
 	}
+	c.recordExpiration(secret)
 
 	// reconcile pending csr if exists
 	if len(c.csrName) > 0 {
@@ -210,7 +288,7 @@ func (c *clientCertificateController) sync(ctx context.Context, syncCtx factory.
 			}
 
 			// skip if csr is not approved yet
-			isApproved, err := c.csrControl.isApproved(c.csrName)
+			isApproved, err := c.csrControl.IsApproved(c.csrName)
 			if err != nil {
 				return nil, err
 			}
@@ -219,13 +297,19 @@ func (c *clientCertificateController) sync(ctx context.Context, syncCtx factory.
 			}
 
 			// skip if csr is not issued
-			certData, err := c.csrControl.getIssuedCertificate(c.csrName)
+			certData, err := c.csrControl.GetIssuedCertificate(c.csrName)
 			if err != nil {
 				return nil, err
 			}
 			if len(certData) == 0 {
 				return nil, nil
 			}
+			if c.issuedObservedTime.IsZero() {
+				c.issuedObservedTime = time.Now()
+				if !c.csrCreatedTime.IsZero() {
+					csrPendingDuration.WithLabelValues(c.controllerName).Observe(c.issuedObservedTime.Sub(c.csrCreatedTime).Seconds())
+				}
+			}
 
 			klog.V(4).Infof("Sync csr %v", c.csrName)
 			// check if cert in csr status matches with the corresponding private key
@@ -247,36 +331,37 @@ func (c *clientCertificateController) sync(ctx context.Context, syncCtx factory.
 
 		if err != nil {
 			c.reset()
-			return err
+			updateErr := c.updateStatus(ctx, metav1.Condition{
+				Type:    ClientCertificateRotatedCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  ClientCertificateUpdateFailedReason,
+				Message: fmt.Sprintf("Failed to rotate client certificate: %v", err),
+			})
+			return utilerrors.NewAggregate([]error{err, updateErr})
 		}
 		if len(newSecretConfig) == 0 {
 			return nil
 		}
-		// append additional data into client certificate secret
-		for k, v := range c.AdditionalSecretData {
-			newSecretConfig[k] = v
-		}
-		secret.Data = newSecretConfig
-		// save the changes into secret
-		if err := saveSecret(c.spokeCoreClient, c.SecretNamespace, secret); err != nil {
-			return err
-		}
-		syncCtx.Recorder().Eventf("ClientCertificateCreated", "A new client certificate for %s is available", c.controllerName)
-		c.reset()
-		return nil
+		return c.applyCertificate(ctx, syncCtx, secret, newSecretConfig)
 	}
 
 	// create a csr to request new client certificate if
 	// a. there is no valid client certificate issued for the current cluster/agent;
-	// b. client certificate is sensitive to the additional secret data and the data changes;
-	// c. client certificate exists and has less than a random percentage range from 20% to 25% of its life remaining;
+	// b. the certificate does not include all of the DNS names;
+	// c. client certificate is sensitive to the additional secret data and the data changes;
+	// d. client certificate exists and has less than a percentage range from 20% to 25% (by default) of its life
+	//    remaining, which is fixed for each certificate;
 	shouldCreate, err := shouldCreateCSR(
 		c.controllerName,
 		secret,
 		syncCtx.Recorder(),
 		c.Subject,
+		c.DNSNames,
 		c.AdditionalSecretDataSensitive,
-		c.AdditionalSecretData)
+		c.AdditionalSecretData,
+		c.RotationThreshold,
+		c.RotationJitter,
+		&c.certCache)
 	if err != nil {
 		return err
 	}
@@ -284,8 +369,22 @@ func (c *clientCertificateController) sync(ctx context.Context, syncCtx factory.
 		return nil
 	}
 
+	// fail fast instead of creating a csr which is never signed
+	if c.SignerChecker != nil {
+		if err := c.SignerChecker(ctx, c.SignerName); err != nil {
+			syncCtx.Recorder().Warningf("SignerUnavailable", "No csr is created for %s: %v", c.controllerName, err)
+			updateErr := c.updateStatus(ctx, metav1.Condition{
+				Type:    ClientCertificateRotatedCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  SignerUnavailableReason,
+				Message: fmt.Sprintf("Failed to request client certificate: %v", err),
+			})
+			return utilerrors.NewAggregate([]error{err, updateErr})
+		}
+	}
+
 	// create a new private key
-	keyData, err := keyutil.MakeEllipticPrivateKeyPEM()
+	keyData, err := newPrivateKeyPEM(c.KeyType, c.KeyBitSize)
 	if err != nil {
 		return err
 	}
@@ -298,28 +397,127 @@ func (c *clientCertificateController) sync(ctx context.Context, syncCtx factory.
 	if err != nil {
 		return fmt.Errorf("unable to generate certificate request: %w", err)
 	}
-	createdCSRName, err := c.csrControl.create(ctx, syncCtx.Recorder(), c.ObjectMeta, csrData, c.SignerName)
+	usages := c.Usages
+	if len(usages) == 0 {
+		usages = clientCertUsages
+	}
+
+	if c.issuer != nil {
+		certData, err := c.issuer.Issue(ctx, syncCtx.Recorder(), csrData, usages)
+		if err == nil {
+			_, err = tls.X509KeyPair(certData, keyData)
+		}
+		if err != nil {
+			updateErr := c.updateStatus(ctx, metav1.Condition{
+				Type:    ClientCertificateRotatedCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  ClientCertificateUpdateFailedReason,
+				Message: fmt.Sprintf("Failed to rotate client certificate: %v", err),
+			})
+			return utilerrors.NewAggregate([]error{err, updateErr})
+		}
+		c.issuedObservedTime = time.Now()
+		return c.applyCertificate(ctx, syncCtx, secret, map[string][]byte{
+			TLSCertFile: certData,
+			TLSKeyFile:  keyData,
+		})
+	}
+
+	createdCSRName, err := c.csrControl.Create(ctx, syncCtx.Recorder(), c.ObjectMeta, csrData, c.SignerName, usages)
 	if err != nil {
 		return err
 	}
 	c.keyData = keyData
 	c.csrName = createdCSRName
+	c.csrCreatedTime = time.Now()
+	// watch the csrs until the created one is processed
+	if c.lazyCSRInformer != nil {
+		c.lazyCSRInformer.Acquire(ctx, c.controllerName)
+	}
 	return nil
 }
 
-func saveSecret(spokeCoreClient corev1client.CoreV1Interface, secretNamespace string, secret *corev1.Secret) error {
-	var err error
-	if secret.ResourceVersion == "" {
-		_, err = spokeCoreClient.Secrets(secretNamespace).Create(context.Background(), secret, metav1.CreateOptions{})
+// applyCertificate stores the issued certificate and its private key in the data, with the additional data, in the
+// secret, which still holds the previous certificate, and reports the rotation.
+func (c *clientCertificateController) applyCertificate(ctx context.Context, syncCtx factory.SyncContext,
+	secret *corev1.Secret, newSecretConfig map[string][]byte) error {
+	// append additional data into client certificate secret
+	for k, v := range c.AdditionalSecretData {
+		newSecretConfig[k] = v
+	}
+	// the secret still holds the previous certificate here
+	origin := CertificateOriginBootstrap
+	if hasValidClientCertificate(c.Subject, secret, &c.certCache) {
+		origin = CertificateOriginRotated
+	}
+	// only the fields managed by the controller are applied, the others are kept
+	appliedSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   c.SecretNamespace,
+			Name:        c.SecretName,
+			Labels:      c.SecretLabels,
+			Annotations: map[string]string{CertificateOriginAnnotation: origin},
+		},
+		Data: newSecretConfig,
+	}
+	if _, err := helpers.ApplySecret(ctx, c.spokeCoreClient, secret, appliedSecret); err != nil {
 		return err
 	}
-	_, err = spokeCoreClient.Secrets(secretNamespace).Update(context.Background(), secret, metav1.UpdateOptions{})
-	return err
+	csrPersistenceDuration.Observe(time.Since(c.issuedObservedTime).Seconds())
+	clientCertRotations.WithLabelValues(c.controllerName, origin).Inc()
+	syncCtx.Recorder().Eventf("ClientCertificateCreated", "A new client certificate for %s is available", c.controllerName)
+	c.reset()
+	return c.updateStatus(ctx, metav1.Condition{
+		Type:    ClientCertificateRotatedCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  ClientCertificateUpdatedReason,
+		Message: "Client certificate is rotated",
+	})
+}
+
+// updateStatus reports the condition to the status updater if it is set
+func (c *clientCertificateController) updateStatus(ctx context.Context, cond metav1.Condition) error {
+	if c.statusUpdater == nil {
+		return nil
+	}
+	return c.statusUpdater(ctx, cond)
+}
+
+// clientCertificateState is the internal state of the controller served on the debug endpoint
+type clientCertificateState struct {
+	Secret     string `json:"secret"`
+	CSRName    string `json:"csrName"`
+	HasKeyData bool   `json:"hasKeyData"`
+}
+
+// recordState records the internal state of the controller for debugging, the private key itself is not recorded
+func (c *clientCertificateController) recordState() {
+	debug.RecordState(c.controllerName, clientCertificateState{
+		Secret:     c.SecretNamespace + "/" + c.SecretName,
+		CSRName:    c.csrName,
+		HasKeyData: len(c.keyData) > 0,
+	})
+}
+
+// recordExpiration records the expiration of the certificate in the secret, the metric is removed if there is no
+// certificate in the secret
+func (c *clientCertificateController) recordExpiration(secret *corev1.Secret) {
+	_, notAfter, err := getCertValidityPeriod(secret, &c.certCache)
+	if err != nil {
+		clientCertExpiration.DeleteLabelValues(c.controllerName)
+		return
+	}
+	clientCertExpiration.WithLabelValues(c.controllerName).Set(float64(notAfter.Unix()))
 }
 
 func (c *clientCertificateController) reset() {
 	c.csrName = ""
 	c.keyData = nil
+	c.csrCreatedTime = time.Time{}
+	c.issuedObservedTime = time.Time{}
+	if c.lazyCSRInformer != nil {
+		c.lazyCSRInformer.Release(c.controllerName)
+	}
 }
 
 func shouldCreateCSR(
@@ -327,15 +525,21 @@ func shouldCreateCSR(
 	secret *corev1.Secret,
 	recorder events.Recorder,
 	subject *pkix.Name,
+	dnsNames []string,
 	additionalSecretDataSensitive bool,
-	additionalSecretData map[string][]byte) (bool, error) {
+	additionalSecretData map[string][]byte,
+	rotationThreshold float64,
+	rotationJitter float64,
+	certCache *certificateCache) (bool, error) {
 	switch {
-	case !hasValidClientCertificate(subject, secret):
+	case !hasValidClientCertificate(subject, secret, certCache):
 		recorder.Eventf("NoValidCertificateFound", "No valid client certificate for %s is found. Bootstrap is required", controllerName)
+	case !hasDNSNames(dnsNames, secret, certCache):
+		recorder.Eventf("DNSNamesChanged", "The DNS names are changed. Re-create the certificate for %s", controllerName)
 	case additionalSecretDataSensitive && !hasAdditionalSecretData(additionalSecretData, secret):
 		recorder.Eventf("AdditonalSecretDataChanged", "The additonal secret data is changed. Re-create the client certificate for %s", controllerName)
 	default:
-		notBefore, notAfter, err := getCertValidityPeriod(secret)
+		notBefore, notAfter, err := getCertValidityPeriod(secret, certCache)
 		if err != nil {
 			return false, err
 		}
@@ -343,9 +547,10 @@ func shouldCreateCSR(
 		total := notAfter.Sub(*notBefore)
 		remaining := time.Until(*notAfter)
 		klog.V(4).Infof("Client certificate for %s: time total=%v, remaining=%v, remaining/total=%v", controllerName, total, remaining, remaining.Seconds()/total.Seconds())
-		threshold := jitter(0.2, 0.25)
+		threshold := rotationPoint(rotationThreshold, rotationJitter, secret.Data[TLSCertFile])
 		if remaining.Seconds()/total.Seconds() > threshold {
-			// Do nothing if the client certificate is valid and has more than a random percentage range from 20% to 25% of its life remaining
+			// Do nothing if the client certificate is valid and has more than a percentage range from 20% to 25%
+			// (by default) of its life remaining
 			klog.V(4).Infof("Client certificate for %s is valid and has more than %.2f%% of its life remaining", controllerName, threshold*100)
 			return false, nil
 		}
@@ -354,6 +559,19 @@ func shouldCreateCSR(
 	return true, nil
 }
 
+// hasDNSNames checks if the certificate in the secret includes all of the DNS names.
+func hasDNSNames(dnsNames []string, secret *corev1.Secret, certCache *certificateCache) bool {
+	if len(dnsNames) == 0 {
+		return true
+	}
+
+	certs, err := certCache.get(secret.Data[TLSCertFile])
+	if err != nil {
+		return false
+	}
+	return certs.hasDNSNames(dnsNames)
+}
+
 // hasAdditonalSecretData checks if the secret includes the expected additional secret data.
 func hasAdditionalSecretData(additionalSecretData map[string][]byte, secret *corev1.Secret) bool {
 	for k, v := range additionalSecretData {
@@ -369,17 +587,26 @@ func hasAdditionalSecretData(additionalSecretData map[string][]byte, secret *cor
 	return true
 }
 
-func jitter(percentage float64, maxFactor float64) float64 {
-	if maxFactor <= 0.0 {
-		maxFactor = 1.0
+// rotationPoint returns the ratio of the lifetime remaining at which the certificate is rotated. It is between the
+// threshold and the threshold plus the jitter, and is drawn from the hash of the certificate rather than at random on
+// each sync, otherwise the rotations of the certificates issued together would converge at the start of the window
+// after a few syncs.
+func rotationPoint(threshold, jitter float64, certData []byte) float64 {
+	if threshold <= 0 {
+		threshold = defaultRotationThreshold
 	}
-	newPercentage := percentage + percentage*rand.Float64()*maxFactor
-	return newPercentage
+	if jitter < 0 {
+		jitter = defaultRotationJitter(threshold)
+	}
+	sum := sha256.Sum256(certData)
+	factor := float64(binary.BigEndian.Uint64(sum[:8])) / math.MaxUint64
+	return threshold + jitter*factor
 }
 
-func hasValidClientCertificate(subject *pkix.Name, secret *corev1.Secret) bool {
-	if valid, err := IsCertificateValid(secret.Data[TLSCertFile], subject); err == nil {
-		return valid
+func hasValidClientCertificate(subject *pkix.Name, secret *corev1.Secret, certCache *certificateCache) bool {
+	certs, err := certCache.get(secret.Data[TLSCertFile])
+	if err != nil {
+		return false
 	}
-	return false
+	return certs.isValid(subject, time.Now())
 }
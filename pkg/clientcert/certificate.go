@@ -3,8 +3,8 @@ package clientcert
 import (
 	"context"
 	"crypto/x509/pkix"
-	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -18,7 +18,6 @@ import (
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
-	certutil "k8s.io/client-go/util/cert"
 	"k8s.io/klog/v2"
 )
 
@@ -63,42 +62,16 @@ func HasValidHubKubeconfig(secret *corev1.Secret, subject *pkix.Name) bool {
 // 1) All certs in client certificate are not expired.
 // 2) At least one cert matches the given subject if specified
 func IsCertificateValid(certData []byte, subject *pkix.Name) (bool, error) {
-	certs, err := certutil.ParseCertsPEM(certData)
+	certs, err := parseCertificates(certData)
 	if err != nil {
-		return false, errors.New("unable to parse certificate")
-	}
-
-	if len(certs) == 0 {
-		return false, errors.New("no cert found in certificate")
-	}
-
-	now := time.Now()
-	// make sure no cert in the certificate chain expired
-	for _, cert := range certs {
-		if now.After(cert.NotAfter) {
-			klog.V(4).Infof("Part of the certificate is expired: %v", cert.NotAfter)
-			return false, nil
-		}
-	}
-
-	if subject == nil {
-		return true, nil
-	}
-
-	// check subject of certificates
-	for _, cert := range certs {
-		if cert.Subject.CommonName != subject.CommonName {
-			continue
-		}
-		return true, nil
+		return false, err
 	}
-
-	klog.V(4).Infof("Certificate is not issued for subject (cn=%s)", subject.CommonName)
-	return false, nil
+	return certs.isValid(subject, time.Now()), nil
 }
 
-// getCertValidityPeriod returns the validity period of the client certificate in the secret
-func getCertValidityPeriod(secret *corev1.Secret) (*time.Time, *time.Time, error) {
+// getCertValidityPeriod returns the validity period of the client certificate in the secret, the certificate is parsed
+// with the certificate cache.
+func getCertValidityPeriod(secret *corev1.Secret, certCache *certificateCache) (*time.Time, *time.Time, error) {
 	if secret.Data == nil {
 		return nil, nil, fmt.Errorf("no client certificate found in secret %q", secret.Namespace+"/"+secret.Name)
 	}
@@ -108,37 +81,16 @@ func getCertValidityPeriod(secret *corev1.Secret) (*time.Time, *time.Time, error
 		return nil, nil, fmt.Errorf("no client certificate found in secret %q", secret.Namespace+"/"+secret.Name)
 	}
 
-	certs, err := certutil.ParseCertsPEM(certData)
+	certs, err := certCache.get(certData)
 	if err != nil {
-		return nil, nil, fmt.Errorf("unable to parse TLS certificates: %w", err)
+		return nil, nil, err
 	}
-
-	if len(certs) == 0 {
-		return nil, nil, errors.New("no cert found in certificate")
-	}
-
-	// find out the validity period for all certs in the certificate chain
-	var notBefore, notAfter *time.Time
-	for index, cert := range certs {
-		if index == 0 {
-			notBefore = &cert.NotBefore
-			notAfter = &cert.NotAfter
-			continue
-		}
-
-		if notBefore.Before(cert.NotBefore) {
-			notBefore = &cert.NotBefore
-		}
-
-		if notAfter.After(cert.NotAfter) {
-			notAfter = &cert.NotAfter
-		}
-	}
-
-	return notBefore, notAfter, nil
+	return &certs.notBefore, &certs.notAfter, nil
 }
 
-// BuildKubeconfig builds a kubeconfig based on a rest config template with a cert/key pair
+// BuildKubeconfig builds a kubeconfig based on a rest config template with a cert/key pair. The proxy and
+// the tls server name in the rest config are kept in the kubeconfig, so the clients using the kubeconfig
+// reach the apiserver through the same endpoint.
 func BuildKubeconfig(clientConfig *restclient.Config, certPath, keyPath string) clientcmdapi.Config {
 	// Build kubeconfig.
 	kubeconfig := clientcmdapi.Config{
@@ -147,6 +99,8 @@ func BuildKubeconfig(clientConfig *restclient.Config, certPath, keyPath string)
 			Server:                   clientConfig.Host,
 			InsecureSkipTLSVerify:    false,
 			CertificateAuthorityData: clientConfig.CAData,
+			TLSServerName:            clientConfig.ServerName,
+			ProxyURL:                 proxyURL(clientConfig),
 		}},
 		// Define auth based on the obtained client cert.
 		AuthInfos: map[string]*clientcmdapi.AuthInfo{"default-auth": {
@@ -165,14 +119,47 @@ func BuildKubeconfig(clientConfig *restclient.Config, certPath, keyPath string)
 	return kubeconfig
 }
 
-type csrControl interface {
-	create(ctx context.Context, recorder events.Recorder, objMeta metav1.ObjectMeta, csrData []byte, signerName string) (string, error)
-	isApproved(name string) (bool, error)
-	getIssuedCertificate(name string) ([]byte, error)
-	informer() cache.SharedIndexInformer
+// CSRControl creates the csrs on the hub and watches them for the client certificate controller. It hides the
+// version of the csr api served by the hub, and can be replaced with a fake one in unit tests.
+type CSRControl interface {
+	// Create creates a csr with the certificate request data, and returns the name of the created csr.
+	Create(ctx context.Context, recorder events.Recorder, objMeta metav1.ObjectMeta, csrData []byte, signerName string, usages []certificates.KeyUsage) (string, error)
+	// IsApproved returns true if the csr is approved and not denied.
+	IsApproved(name string) (bool, error)
+	// GetIssuedCertificate returns the certificate issued for the csr, or an empty one if it is not issued yet.
+	GetIssuedCertificate(name string) ([]byte, error)
+	// Informer returns the informer of the csrs, which is started by the caller.
+	Informer() cache.SharedIndexInformer
+}
+
+// CertificateIssuer issues the certificates synchronously, e.g. with an external PKI, instead of the csrs which
+// are approved and signed later.
+type CertificateIssuer interface {
+	// Issue signs the certificate request data with the usages, and returns the issued certificate.
+	Issue(ctx context.Context, recorder events.Recorder, csrData []byte, usages []certificates.KeyUsage) ([]byte, error)
+}
+
+// proxyURL returns the url of the proxy which the rest config uses to connect to the apiserver. An
+// empty string is returned if no proxy is set in the rest config.
+func proxyURL(clientConfig *restclient.Config) string {
+	if clientConfig.Proxy == nil {
+		return ""
+	}
+
+	req, err := http.NewRequest(http.MethodGet, clientConfig.Host, nil)
+	if err != nil {
+		klog.Warningf("unable to build request for host %q: %v", clientConfig.Host, err)
+		return ""
+	}
+
+	u, err := clientConfig.Proxy(req)
+	if err != nil || u == nil {
+		return ""
+	}
+	return u.String()
 }
 
-var _ csrControl = &v1CSRControl{}
+var _ CSRControl = &v1CSRControl{}
 
 type v1CSRControl struct {
 	hubCSRInformer certificatesinformers.CertificateSigningRequestInformer
@@ -180,7 +167,7 @@ type v1CSRControl struct {
 	hubCSRClient   csrclient.CertificateSigningRequestInterface
 }
 
-func (v *v1CSRControl) isApproved(name string) (bool, error) {
+func (v *v1CSRControl) IsApproved(name string) (bool, error) {
 	csr, err := v.get(name)
 	if err != nil {
 		return false, err
@@ -197,7 +184,7 @@ func (v *v1CSRControl) isApproved(name string) (bool, error) {
 	return approved, nil
 }
 
-func (v *v1CSRControl) getIssuedCertificate(name string) ([]byte, error) {
+func (v *v1CSRControl) GetIssuedCertificate(name string) ([]byte, error) {
 	csr, err := v.get(name)
 	if err != nil {
 		return nil, err
@@ -206,16 +193,12 @@ func (v *v1CSRControl) getIssuedCertificate(name string) ([]byte, error) {
 	return v1CSR.Status.Certificate, nil
 }
 
-func (v *v1CSRControl) create(ctx context.Context, recorder events.Recorder, objMeta metav1.ObjectMeta, csrData []byte, signerName string) (string, error) {
+func (v *v1CSRControl) Create(ctx context.Context, recorder events.Recorder, objMeta metav1.ObjectMeta, csrData []byte, signerName string, usages []certificates.KeyUsage) (string, error) {
 	csr := &certificates.CertificateSigningRequest{
 		ObjectMeta: objMeta,
 		Spec: certificates.CertificateSigningRequestSpec{
-			Request: csrData,
-			Usages: []certificates.KeyUsage{
-				certificates.UsageDigitalSignature,
-				certificates.UsageKeyEncipherment,
-				certificates.UsageClientAuth,
-			},
+			Request:    csrData,
+			Usages:     usages,
 			SignerName: signerName,
 		},
 	}
@@ -228,7 +211,7 @@ func (v *v1CSRControl) create(ctx context.Context, recorder events.Recorder, obj
 	return req.Name, nil
 }
 
-func (v *v1CSRControl) informer() cache.SharedIndexInformer {
+func (v *v1CSRControl) Informer() cache.SharedIndexInformer {
 	return v.hubCSRInformer.Informer()
 }
 
@@ -0,0 +1,101 @@
+package clientcert
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/klog/v2"
+)
+
+// parsedCertificates is the certificate chain parsed from the pem data of a client certificate
+type parsedCertificates struct {
+	certs []*x509.Certificate
+	// notBefore and notAfter are the validity period of the certificate chain, which is the intersection of the
+	// validity periods of all of the certs in the chain
+	notBefore time.Time
+	notAfter  time.Time
+}
+
+// parseCertificates parses the pem data of a client certificate
+func parseCertificates(certData []byte) (*parsedCertificates, error) {
+	certs, err := certutil.ParseCertsPEM(certData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse TLS certificates: %w", err)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no cert found in certificate")
+	}
+
+	parsed := &parsedCertificates{
+		certs:     certs,
+		notBefore: certs[0].NotBefore,
+		notAfter:  certs[0].NotAfter,
+	}
+	for _, cert := range certs[1:] {
+		if parsed.notBefore.Before(cert.NotBefore) {
+			parsed.notBefore = cert.NotBefore
+		}
+		if parsed.notAfter.After(cert.NotAfter) {
+			parsed.notAfter = cert.NotAfter
+		}
+	}
+	return parsed, nil
+}
+
+// isValid returns true if no cert in the chain is expired and at least one cert matches the subject if specified
+func (p *parsedCertificates) isValid(subject *pkix.Name, now time.Time) bool {
+	// make sure no cert in the certificate chain expired
+	for _, cert := range p.certs {
+		if now.After(cert.NotAfter) {
+			klog.V(4).Infof("Part of the certificate is expired: %v", cert.NotAfter)
+			return false
+		}
+	}
+
+	if subject == nil {
+		return true
+	}
+
+	// check subject of certificates
+	for _, cert := range p.certs {
+		if cert.Subject.CommonName == subject.CommonName {
+			return true
+		}
+	}
+
+	klog.V(4).Infof("Certificate is not issued for subject (cn=%s)", subject.CommonName)
+	return false
+}
+
+// hasDNSNames returns true if the first cert in the chain includes all of the DNS names
+func (p *parsedCertificates) hasDNSNames(dnsNames []string) bool {
+	return sets.NewString(p.certs[0].DNSNames...).HasAll(dnsNames...)
+}
+
+// certificateCache caches the certificate parsed from the secret the controller synced last time. The certificate
+// only changes once it is rotated, so it is parsed again only if the pem data in the secret changes. It is used by
+// the sync of a controller only and is not safe for concurrent use.
+type certificateCache struct {
+	cached   bool
+	certData []byte
+	certs    *parsedCertificates
+	err      error
+}
+
+// get returns the certificate parsed from the pem data, it is parsed only if the data differs from the cached one.
+func (c *certificateCache) get(certData []byte) (*parsedCertificates, error) {
+	if c.cached && bytes.Equal(c.certData, certData) {
+		return c.certs, c.err
+	}
+
+	c.certs, c.err = parseCertificates(certData)
+	c.certData = append([]byte{}, certData...)
+	c.cached = true
+	return c.certs, c.err
+}
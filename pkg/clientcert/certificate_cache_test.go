@@ -0,0 +1,88 @@
+package clientcert
+
+import (
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestCertificateCache(t *testing.T) {
+	cert1 := testinghelpers.NewTestCert("cluster1", 60*time.Second).Cert
+	cert2 := testinghelpers.NewTestCert("cluster2", 60*time.Second).Cert
+
+	certCache := &certificateCache{}
+	certs1, err := certCache.get(cert1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !certs1.isValid(&pkix.Name{CommonName: "cluster1"}, time.Now()) {
+		t.Errorf("expected the certificate of cluster1 to be valid")
+	}
+
+	// the certificate is parsed only once if the data does not change
+	if certs, _ := certCache.get(append([]byte{}, cert1...)); certs != certs1 {
+		t.Errorf("expected the cached certificate to be returned")
+	}
+
+	// the certificate is parsed again once the data changes
+	certs2, err := certCache.get(cert2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if certs2 == certs1 || certs2.isValid(&pkix.Name{CommonName: "cluster1"}, time.Now()) {
+		t.Errorf("expected the certificate of cluster2 to be parsed")
+	}
+
+	// the error of the bad data is cached as well
+	if _, err := certCache.get([]byte("bad cert")); err == nil {
+		t.Errorf("expected error for bad cert")
+	}
+	if _, err := certCache.get([]byte("bad cert")); err == nil {
+		t.Errorf("expected the cached error for bad cert")
+	}
+}
+
+func BenchmarkHasValidClientCertificate(b *testing.B) {
+	secret := testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "",
+		testinghelpers.NewTestCert("cluster1", 60*time.Minute), map[string][]byte{})
+	subject := &pkix.Name{CommonName: "cluster1"}
+
+	b.Run("parsed on every call", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			hasValidClientCertificate(subject, secret, &certificateCache{})
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		certCache := &certificateCache{}
+		for i := 0; i < b.N; i++ {
+			hasValidClientCertificate(subject, secret, certCache)
+		}
+	})
+}
+
+func BenchmarkShouldCreateCSR(b *testing.B) {
+	secret := testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "",
+		testinghelpers.NewTestCert("cluster1", 60*time.Minute), map[string][]byte{})
+	subject := &pkix.Name{CommonName: "cluster1"}
+	recorder := events.NewInMemoryRecorder("test")
+
+	b.Run("parsed on every call", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := shouldCreateCSR("test", secret, recorder, subject, nil, false, nil, 0, 0, &certificateCache{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		certCache := &certificateCache{}
+		for i := 0; i < b.N; i++ {
+			if _, err := shouldCreateCSR("test", secret, recorder, subject, nil, false, nil, 0, 0, certCache); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -2,6 +2,8 @@ package clientcert
 
 import (
 	"crypto/x509/pkix"
+	"net/http"
+	"net/url"
 	"testing"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	certificates "k8s.io/api/certificates/v1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/client-go/listers/certificates/v1"
+	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	certutil "k8s.io/client-go/util/cert"
 
@@ -49,7 +52,7 @@ func TestIsCSRApproved(t *testing.T) {
 			ctrl := &v1CSRControl{
 				hubCSRLister: lister,
 			}
-			csrApproved, err := ctrl.isApproved(c.csr.Name)
+			csrApproved, err := ctrl.IsApproved(c.csr.Name)
 			assert.NoError(t, err)
 			if csrApproved != c.csrApproved {
 				t.Errorf("expected %t, but got %t", c.csrApproved, csrApproved)
@@ -216,7 +219,7 @@ func TestGetCertValidityPeriod(t *testing.T) {
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			notBefore, notAfter, err := getCertValidityPeriod(c.secret)
+			notBefore, notAfter, err := getCertValidityPeriod(c.secret, &certificateCache{})
 			testinghelpers.AssertError(t, err, c.expectedErr)
 			if err != nil {
 				return
@@ -230,3 +233,41 @@ func TestGetCertValidityPeriod(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildKubeconfig(t *testing.T) {
+	proxy, _ := url.Parse("http://proxy.example.com:3128")
+
+	cases := []struct {
+		name                  string
+		clientConfig          *restclient.Config
+		expectedProxyURL      string
+		expectedTLSServerName string
+	}{
+		{
+			name:         "without proxy",
+			clientConfig: &restclient.Config{Host: "https://127.0.0.1:6443"},
+		},
+		{
+			name: "with proxy and tls server name",
+			clientConfig: &restclient.Config{
+				Host:            "https://127.0.0.1:6443",
+				Proxy:           http.ProxyURL(proxy),
+				TLSClientConfig: restclient.TLSClientConfig{ServerName: "hub.example.com"},
+			},
+			expectedProxyURL:      "http://proxy.example.com:3128",
+			expectedTLSServerName: "hub.example.com",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeconfig := BuildKubeconfig(c.clientConfig, TLSCertFile, TLSKeyFile)
+			cluster := kubeconfig.Clusters["default-cluster"]
+			if cluster.ProxyURL != c.expectedProxyURL {
+				t.Errorf("expected proxy url %q, but got %q", c.expectedProxyURL, cluster.ProxyURL)
+			}
+			if cluster.TLSServerName != c.expectedTLSServerName {
+				t.Errorf("expected tls server name %q, but got %q", c.expectedTLSServerName, cluster.TLSServerName)
+			}
+		})
+	}
+}
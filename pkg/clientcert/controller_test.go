@@ -2,21 +2,30 @@ package clientcert
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
+	"math/big"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/openshift/library-go/pkg/crypto"
 	"github.com/openshift/library-go/pkg/operator/events"
 	certificates "k8s.io/api/certificates/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/rand"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/component-base/metrics/testutil"
 
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 	"open-cluster-management.io/registration/pkg/hub/user"
@@ -44,6 +53,11 @@ func TestSync(t *testing.T) {
 		keyDataExpected              bool
 		csrNameExpected              bool
 		additonalSecretDataSensitive bool
+		dnsNames                     []string
+		signerChecker                SignerChecker
+		statusUpdateErr              error
+		expectedErr                  string
+		expectedCondition            *metav1.Condition
 		validateActions              func(t *testing.T, hubActions, agentActions []clienttesting.Action)
 	}{
 		{
@@ -61,22 +75,68 @@ func TestSync(t *testing.T) {
 				testinghelpers.AssertActions(t, agentActions, "get")
 			},
 		},
+		{
+			name:     "signer unavailable",
+			secrets:  []runtime.Object{},
+			queueKey: "key",
+			signerChecker: func(ctx context.Context, signerName string) error {
+				return fmt.Errorf("signer %q is not allowed", signerName)
+			},
+			expectedErr: "signer \"kubernetes.io/kube-apiserver-client\" is not allowed",
+			expectedCondition: &metav1.Condition{
+				Type:    ClientCertificateRotatedCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  SignerUnavailableReason,
+				Message: "Failed to request client certificate: signer \"kubernetes.io/kube-apiserver-client\" is not allowed",
+			},
+			validateActions: func(t *testing.T, hubActions, agentActions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, hubActions)
+				testinghelpers.AssertActions(t, agentActions, "get")
+			},
+		},
+		{
+			name:     "signer unavailable and status update failed",
+			secrets:  []runtime.Object{},
+			queueKey: "key",
+			signerChecker: func(ctx context.Context, signerName string) error {
+				return fmt.Errorf("signer %q is not allowed", signerName)
+			},
+			statusUpdateErr: fmt.Errorf("conflict"),
+			expectedErr:     "[signer \"kubernetes.io/kube-apiserver-client\" is not allowed, conflict]",
+			expectedCondition: &metav1.Condition{
+				Type:    ClientCertificateRotatedCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  SignerUnavailableReason,
+				Message: "Failed to request client certificate: signer \"kubernetes.io/kube-apiserver-client\" is not allowed",
+			},
+			validateActions: func(t *testing.T, hubActions, agentActions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, hubActions)
+				testinghelpers.AssertActions(t, agentActions, "get")
+			},
+		},
 		{
 			name:     "syc csr after bootstrap",
 			queueKey: testSecretName,
 			secrets: []runtime.Object{
-				testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "1", nil, map[string][]byte{
+				newSecretWithLabels(testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "1", nil, map[string][]byte{
 					ClusterNameFile: []byte(testinghelpers.TestManagedClusterName),
 					AgentNameFile:   []byte(testAgentName),
-				},
-				),
+				}), map[string]string{"backup": "true"}),
 			},
 			approvedCSRCert: testinghelpers.NewTestCert(commonName, 10*time.Second),
+			expectedCondition: &metav1.Condition{
+				Type:    ClientCertificateRotatedCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  ClientCertificateUpdatedReason,
+				Message: "Client certificate is rotated",
+			},
 			validateActions: func(t *testing.T, hubActions, agentActions []clienttesting.Action) {
 				testinghelpers.AssertActions(t, hubActions, "get", "get")
-				testinghelpers.AssertActions(t, agentActions, "get", "update")
-				actual := agentActions[1].(clienttesting.UpdateActionImpl).Object
-				secret := actual.(*corev1.Secret)
+				testinghelpers.AssertActions(t, agentActions, "get", "patch")
+				secret := testinghelpers.AppliedSecret(t, agentActions[1])
+				if len(secret.Labels) != 0 {
+					t.Errorf("expected the labels not managed by the controller are not applied, but got %v", secret.Labels)
+				}
 				valid, err := IsCertificateValid(secret.Data[TLSCertFile], testSubject)
 				if err != nil {
 					t.Errorf("unexpected error: %v", err)
@@ -84,6 +144,19 @@ func TestSync(t *testing.T) {
 				if !valid {
 					t.Error("client certificate is invalid")
 				}
+				if origin := secret.Annotations[CertificateOriginAnnotation]; origin != CertificateOriginBootstrap {
+					t.Errorf("expected certificate origin %q, but got %q", CertificateOriginBootstrap, origin)
+				}
+				if count, err := testutil.GetHistogramMetricCount(csrPersistenceDuration.ObserverMetric); err != nil || count == 0 {
+					t.Errorf("expected the persistence duration is recorded, but got %d, %v", count, err)
+				}
+				if count, err := testutil.GetHistogramMetricCount(csrPendingDuration.WithLabelValues("test-agent")); err != nil || count == 0 {
+					t.Errorf("expected the pending duration is recorded, but got %d, %v", count, err)
+				}
+				rotations, err := testutil.GetCounterMetricValue(clientCertRotations.WithLabelValues("test-agent", CertificateOriginBootstrap))
+				if err != nil || rotations == 0 {
+					t.Errorf("expected the rotation is counted, but got %v, %v", rotations, err)
+				}
 			},
 		},
 		{
@@ -99,6 +172,10 @@ func TestSync(t *testing.T) {
 			validateActions: func(t *testing.T, hubActions, agentActions []clienttesting.Action) {
 				testinghelpers.AssertNoActions(t, hubActions)
 				testinghelpers.AssertActions(t, agentActions, "get")
+				expiration, err := testutil.GetGaugeMetricValue(clientCertExpiration.WithLabelValues("test-agent"))
+				if err != nil || expiration < float64(time.Now().Add(9000*time.Second).Unix()) {
+					t.Errorf("expected the expiration of the certificate is recorded, but got %v, %v", expiration, err)
+				}
 			},
 		},
 		{
@@ -143,6 +220,23 @@ func TestSync(t *testing.T) {
 				testinghelpers.AssertActions(t, agentActions, "get")
 			},
 		},
+		{
+			name:     "sync when dns names change",
+			queueKey: testSecretName,
+			secrets: []runtime.Object{
+				testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "1", testinghelpers.NewTestCert(commonName, 10000*time.Second), map[string][]byte{
+					ClusterNameFile: []byte(testinghelpers.TestManagedClusterName),
+					AgentNameFile:   []byte(testAgentName),
+				}),
+			},
+			keyDataExpected: true,
+			csrNameExpected: true,
+			dnsNames:        []string{"test.testns.svc"},
+			validateActions: func(t *testing.T, hubActions, agentActions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, hubActions, "create")
+				testinghelpers.AssertActions(t, agentActions, "get")
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -167,6 +261,7 @@ func TestSync(t *testing.T) {
 				},
 			)
 			agentKubeClient := kubefake.NewSimpleClientset(c.secrets...)
+			testinghelpers.AddSecretApplyReactor(&agentKubeClient.Fake, agentKubeClient.Tracker())
 
 			clientCertOption := ClientCertOption{
 				SecretNamespace: testNamespace,
@@ -181,8 +276,10 @@ func TestSync(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{
 					GenerateName: "test-",
 				},
-				Subject:    testSubject,
-				SignerName: certificates.KubeAPIServerClientSignerName,
+				Subject:       testSubject,
+				DNSNames:      c.dnsNames,
+				SignerName:    certificates.KubeAPIServerClientSignerName,
+				SignerChecker: c.signerChecker,
 			}
 
 			controller := &clientCertificateController{
@@ -193,15 +290,20 @@ func TestSync(t *testing.T) {
 				controllerName:   "test-agent",
 			}
 
+			var actualCondition *metav1.Condition
+			controller.statusUpdater = func(ctx context.Context, cond metav1.Condition) error {
+				actualCondition = &cond
+				return c.statusUpdateErr
+			}
+
 			if c.approvedCSRCert != nil {
 				controller.csrName = testCSRName
+				controller.csrCreatedTime = time.Now().Add(-time.Minute)
 				controller.keyData = c.approvedCSRCert.Key
 			}
 
 			err := controller.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, c.queueKey))
-			if err != nil {
-				t.Errorf("unexpected error %v", err)
-			}
+			testinghelpers.AssertError(t, err, c.expectedErr)
 
 			hasKeyData := controller.keyData != nil
 			if c.keyDataExpected != hasKeyData {
@@ -213,12 +315,142 @@ func TestSync(t *testing.T) {
 				t.Error("controller.csrName should be set")
 			}
 
+			if !reflect.DeepEqual(c.expectedCondition, actualCondition) {
+				t.Errorf("expected condition %v, but got %v", c.expectedCondition, actualCondition)
+			}
+
 			c.validateActions(t, hubKubeClient.Actions(), agentKubeClient.Actions())
 		})
 	}
 }
 
-var _ csrControl = &mockCSRControl{}
+func TestSyncWithIssuer(t *testing.T) {
+	cases := []struct {
+		name              string
+		issueErr          error
+		expectedErr       string
+		expectedCondition metav1.Condition
+		validateActions   func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name: "certificate issued",
+			expectedCondition: metav1.Condition{
+				Type:    ClientCertificateRotatedCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  ClientCertificateUpdatedReason,
+				Message: "Client certificate is rotated",
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				secret := testinghelpers.AppliedSecret(t, actions[1])
+				if len(secret.Data[TLSCertFile]) == 0 || len(secret.Data[TLSKeyFile]) == 0 {
+					t.Errorf("expected the issued certificate and its key to be stored in the secret")
+				}
+			},
+		},
+		{
+			name:        "certificate not issued",
+			issueErr:    fmt.Errorf("permission denied"),
+			expectedErr: "permission denied",
+			expectedCondition: metav1.Condition{
+				Type:    ClientCertificateRotatedCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  ClientCertificateUpdateFailedReason,
+				Message: "Failed to rotate client certificate: permission denied",
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			issuer, err := newFakeIssuer(c.issueErr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			agentKubeClient := kubefake.NewSimpleClientset()
+			testinghelpers.AddSecretApplyReactor(&agentKubeClient.Fake, agentKubeClient.Tracker())
+
+			controller := &clientCertificateController{
+				ClientCertOption: ClientCertOption{SecretNamespace: testNamespace, SecretName: testSecretName},
+				CSROption:        CSROption{Subject: &pkix.Name{CommonName: commonName}},
+				issuer:           issuer,
+				spokeCoreClient:  agentKubeClient.CoreV1(),
+				controllerName:   "test-agent",
+			}
+			var actualCondition metav1.Condition
+			controller.statusUpdater = func(ctx context.Context, cond metav1.Condition) error {
+				actualCondition = cond
+				return nil
+			}
+
+			err = controller.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testSecretName))
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if !reflect.DeepEqual(c.expectedCondition, actualCondition) {
+				t.Errorf("expected condition %v, but got %v", c.expectedCondition, actualCondition)
+			}
+			if len(controller.csrName) > 0 || len(controller.keyData) > 0 {
+				t.Errorf("expected no pending request")
+			}
+			c.validateActions(t, agentKubeClient.Actions())
+		})
+	}
+}
+
+// fakeIssuer signs the certificate requests with a self-signed CA, or fails with err if it is set
+type fakeIssuer struct {
+	ca  *crypto.TLSCertificateConfig
+	err error
+}
+
+func newFakeIssuer(err error) (*fakeIssuer, error) {
+	ca, caErr := crypto.MakeSelfSignedCAConfigForDuration("test-issuer", time.Hour)
+	if caErr != nil {
+		return nil, caErr
+	}
+	return &fakeIssuer{ca: ca, err: err}, nil
+}
+
+func (f *fakeIssuer) Issue(ctx context.Context, recorder events.Recorder, csrData []byte,
+	usages []certificates.KeyUsage) ([]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	block, _ := pem.Decode(csrData)
+	if block == nil {
+		return nil, fmt.Errorf("invalid certificate request")
+	}
+	x509cr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      x509cr.Subject,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, f.ca.Certs[0], x509cr.PublicKey, f.ca.Key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: certutil.CertificateBlockType, Bytes: der}), nil
+}
+
+func newSecretWithLabels(secret *corev1.Secret, labels map[string]string) *corev1.Secret {
+	secret.Labels = labels
+	return secret
+}
+
+var _ CSRControl = &mockCSRControl{}
 
 type mockCSRControl struct {
 	approved       bool
@@ -226,7 +458,7 @@ type mockCSRControl struct {
 	csrClient      *clienttesting.Fake
 }
 
-func (m *mockCSRControl) create(ctx context.Context, recorder events.Recorder, objMeta metav1.ObjectMeta, csrData []byte, signerName string) (string, error) {
+func (m *mockCSRControl) Create(ctx context.Context, recorder events.Recorder, objMeta metav1.ObjectMeta, csrData []byte, signerName string, usages []certificates.KeyUsage) (string, error) {
 	mockCSR := &unstructured.Unstructured{}
 	m.csrClient.Invokes(clienttesting.CreateActionImpl{
 		ActionImpl: clienttesting.ActionImpl{
@@ -234,10 +466,10 @@ func (m *mockCSRControl) create(ctx context.Context, recorder events.Recorder, o
 		},
 		Object: mockCSR,
 	}, nil)
-	return objMeta.Name + rand.String(4), nil
+	return objMeta.Name + utilrand.String(4), nil
 }
 
-func (m *mockCSRControl) isApproved(name string) (bool, error) {
+func (m *mockCSRControl) IsApproved(name string) (bool, error) {
 	m.csrClient.Invokes(clienttesting.GetActionImpl{
 		ActionImpl: clienttesting.ActionImpl{
 			Verb: "get",
@@ -246,7 +478,7 @@ func (m *mockCSRControl) isApproved(name string) (bool, error) {
 	return m.approved, nil
 }
 
-func (m *mockCSRControl) getIssuedCertificate(name string) ([]byte, error) {
+func (m *mockCSRControl) GetIssuedCertificate(name string) ([]byte, error) {
 	m.csrClient.Invokes(clienttesting.GetActionImpl{
 		ActionImpl: clienttesting.ActionImpl{
 			Verb: "get",
@@ -255,6 +487,84 @@ func (m *mockCSRControl) getIssuedCertificate(name string) ([]byte, error) {
 	return m.issuedCertData, nil
 }
 
-func (m *mockCSRControl) informer() cache.SharedIndexInformer {
+func (m *mockCSRControl) Informer() cache.SharedIndexInformer {
 	panic("implement me")
 }
+
+func TestNewCSRControl(t *testing.T) {
+	hubKubeClient := kubefake.NewSimpleClientset(testinghelpers.NewApprovedCSR(testinghelpers.CSRHolder{Name: testCSRName}))
+	informerFactory := informers.NewSharedInformerFactory(hubKubeClient, 10*time.Minute)
+
+	csrControl, err := NewCSRControl(informerFactory.Certificates(), hubKubeClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := csrControl.(*v1CSRControl); !ok {
+		t.Errorf("expected v1 csr control, but got %T", csrControl)
+	}
+
+	// the csr is fetched from the hub apiserver since it is not cached by the informer
+	approved, err := csrControl.IsApproved(testCSRName)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !approved {
+		t.Errorf("expected csr %q is approved", testCSRName)
+	}
+}
+
+func TestRotationPoint(t *testing.T) {
+	cases := []struct {
+		name        string
+		threshold   float64
+		jitter      float64
+		expectedMin float64
+		expectedMax float64
+	}{
+		{
+			name:        "default window",
+			jitter:      -1,
+			expectedMin: 0.2,
+			expectedMax: 0.25,
+		},
+		{
+			name:        "custom window",
+			threshold:   0.3,
+			jitter:      0.2,
+			expectedMin: 0.3,
+			expectedMax: 0.5,
+		},
+		{
+			name:        "no jitter",
+			threshold:   0.3,
+			expectedMin: 0.3,
+			expectedMax: 0.3,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			min, max := 1.0, 0.0
+			for i := 0; i < 100; i++ {
+				certData := []byte(fmt.Sprintf("cert-%d", i))
+				point := rotationPoint(c.threshold, c.jitter, certData)
+				if point != rotationPoint(c.threshold, c.jitter, certData) {
+					t.Fatalf("expected the rotation point of a certificate is fixed")
+				}
+				if point < c.expectedMin || point > c.expectedMax {
+					t.Fatalf("expected the rotation point in [%v, %v], but got %v", c.expectedMin, c.expectedMax, point)
+				}
+				if point < min {
+					min = point
+				}
+				if point > max {
+					max = point
+				}
+			}
+			// the rotation points of the certificates are spread over the window
+			if max-min < (c.expectedMax-c.expectedMin)/2 {
+				t.Errorf("expected the rotation points spread over [%v, %v], but got [%v, %v]", c.expectedMin, c.expectedMax, min, max)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+// package clientcert provides a controller which requests a certificate with csrs on the hub, stores it in a
+// secret, and renews it before it expires. It is used by the registration agent and the addon registrations, and
+// can be used by other agents of open-cluster-management which need a certificate signed on the hub.
+//
+// The controller is built with NewController and configured with options, for example
+//
+//	ctrl, err := clientcert.NewController(namespace, secretName, hubCSRInformer, secretInformer,
+//		spokeKubeClient, hubKubeClient, recorder, "MyClientCertController",
+//		clientcert.WithSubject(&pkix.Name{CommonName: "my-agent"}),
+//		clientcert.WithRenewalThreshold(0.3),
+//		clientcert.WithStatusUpdater(updateStatus),
+//	)
+//
+// The certificate and the private key are stored in the secret with the keys TLSCertFile and TLSKeyFile. The other
+// components can build a client config from the secret with BuildKubeconfigFromSecret and BuildRestConfigFromSecret.
+//
+// For the hubs authenticating the agents with cloud identities instead of client certificates, BuildExecKubeconfig
+// builds a kubeconfig with an exec credential plugin, e.g. NewAzureWorkloadIdentityExecConfig or
+// NewGCPWorkloadIdentityExecConfig, and RunExecPlugin checks the plugin returns a credential.
+package clientcert
@@ -0,0 +1,128 @@
+package clientcert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	restclient "k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// ExecPluginAPIVersion is the version of the ExecCredential exchanged with the exec credential plugins
+	ExecPluginAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+	// AzureServerID is the application id of the Azure Kubernetes Service AAD server, which the tokens of the AKS
+	// clusters with Azure AD integration are requested for
+	AzureServerID = "6dae42f8-4368-4678-94ff-3960e28e3630"
+)
+
+// NewAzureWorkloadIdentityExecConfig returns the exec credential plugin config requesting the tokens of the Azure
+// workload identity of the pod from Azure AD with kubelogin. The client id, the tenant id and the federated token
+// file of the identity are read from the environment variables injected by the Azure workload identity webhook.
+func NewAzureWorkloadIdentityExecConfig(serverID string) *clientcmdapi.ExecConfig {
+	return &clientcmdapi.ExecConfig{
+		APIVersion: ExecPluginAPIVersion,
+		Command:    "kubelogin",
+		Args:       []string{"get-token", "--login", "workloadidentity", "--server-id", serverID},
+		InstallHint: "kubelogin is required to authenticate to the hub with the Azure workload identity, see " +
+			"https://azure.github.io/kubelogin/install.html",
+	}
+}
+
+// NewGCPWorkloadIdentityExecConfig returns the exec credential plugin config requesting the tokens of the Google
+// service account of the pod with gke-gcloud-auth-plugin, which uses the application default credentials, e.g. the
+// GKE workload identity.
+func NewGCPWorkloadIdentityExecConfig() *clientcmdapi.ExecConfig {
+	return &clientcmdapi.ExecConfig{
+		APIVersion:         ExecPluginAPIVersion,
+		Command:            "gke-gcloud-auth-plugin",
+		ProvideClusterInfo: true,
+		InstallHint: "gke-gcloud-auth-plugin is required to authenticate to the hub with the Google service account, see " +
+			"https://cloud.google.com/blog/products/containers-kubernetes/kubectl-auth-changes-in-gke",
+	}
+}
+
+// BuildExecKubeconfig builds a kubeconfig based on a rest config template, which authenticates with the
+// credentials returned by the exec credential plugin.
+func BuildExecKubeconfig(clientConfig *restclient.Config, execConfig *clientcmdapi.ExecConfig) clientcmdapi.Config {
+	kubeconfig := BuildKubeconfig(clientConfig, "", "")
+	kubeconfig.AuthInfos = map[string]*clientcmdapi.AuthInfo{"default-auth": {
+		Exec: execConfig,
+	}}
+	return kubeconfig
+}
+
+// ExecCredentialStatus is the status of the ExecCredential returned by an exec credential plugin
+type ExecCredentialStatus struct {
+	Token                 string     `json:"token,omitempty"`
+	ClientCertificateData string     `json:"clientCertificateData,omitempty"`
+	ClientKeyData         string     `json:"clientKeyData,omitempty"`
+	ExpirationTimestamp   *time.Time `json:"expirationTimestamp,omitempty"`
+}
+
+// ExecFunc runs an exec credential plugin and returns the credential, it is replaced in unit tests
+type ExecFunc func(ctx context.Context, execConfig *clientcmdapi.ExecConfig, cluster *clientcmdapi.Cluster) (*ExecCredentialStatus, error)
+
+// RunExecPlugin runs the exec credential plugin non-interactively in the same way the clients do, and returns the
+// credential. The kubeconfig is refreshed by the clients automatically once the credential expires, the agent runs
+// the plugin itself to make sure the credential is available before the kubeconfig is used, and to surface the
+// errors of the plugin before the credential in use expires.
+func RunExecPlugin(ctx context.Context, execConfig *clientcmdapi.ExecConfig, cluster *clientcmdapi.Cluster) (*ExecCredentialStatus, error) {
+	spec := map[string]interface{}{"interactive": false}
+	if execConfig.ProvideClusterInfo && cluster != nil {
+		spec["cluster"] = map[string]interface{}{
+			"server":                     cluster.Server,
+			"tls-server-name":            cluster.TLSServerName,
+			"certificate-authority-data": cluster.CertificateAuthorityData,
+			"proxy-url":                  cluster.ProxyURL,
+		}
+	}
+	execInfo, err := json.Marshal(map[string]interface{}{
+		"apiVersion": execConfig.APIVersion,
+		"kind":       "ExecCredential",
+		"spec":       spec,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, execConfig.Command, execConfig.Args...)
+	cmd.Env = append(os.Environ(), "KUBERNETES_EXEC_INFO="+string(execInfo))
+	for _, env := range execConfig.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Run(); err != nil {
+		if len(execConfig.InstallHint) > 0 {
+			if _, ok := err.(*exec.Error); ok {
+				return nil, fmt.Errorf("unable to run exec plugin %q: %w. %s", execConfig.Command, err, execConfig.InstallHint)
+			}
+		}
+		return nil, fmt.Errorf("exec plugin %q failed: %w: %s", execConfig.Command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	credential := struct {
+		APIVersion string                `json:"apiVersion"`
+		Kind       string                `json:"kind"`
+		Status     *ExecCredentialStatus `json:"status"`
+	}{}
+	if err := json.Unmarshal(stdout.Bytes(), &credential); err != nil {
+		return nil, fmt.Errorf("unable to parse the output of exec plugin %q: %w", execConfig.Command, err)
+	}
+	switch {
+	case credential.Kind != "ExecCredential" || credential.APIVersion != execConfig.APIVersion:
+		return nil, fmt.Errorf("exec plugin %q returned %s %s instead of %s ExecCredential",
+			execConfig.Command, credential.APIVersion, credential.Kind, execConfig.APIVersion)
+	case credential.Status == nil || (len(credential.Status.Token) == 0 && len(credential.Status.ClientCertificateData) == 0):
+		return nil, fmt.Errorf("exec plugin %q returned no credential", execConfig.Command)
+	}
+	return credential.Status, nil
+}
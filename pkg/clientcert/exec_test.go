@@ -0,0 +1,108 @@
+package clientcert
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	restclient "k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+// newTestExecPlugin writes a shell script as an exec credential plugin and returns its path
+func newTestExecPlugin(t *testing.T, script string) string {
+	plugin := path.Join(t.TempDir(), "plugin")
+	testinghelpers.WriteFile(plugin, []byte("#!/bin/sh\n"+script+"\n"))
+	if err := os.Chmod(plugin, 0700); err != nil {
+		t.Fatal(err)
+	}
+	return plugin
+}
+
+func TestRunExecPlugin(t *testing.T) {
+	cases := []struct {
+		name               string
+		script             string
+		command            string
+		installHint        string
+		expectedErr        string
+		expectedExpiration string
+	}{
+		{
+			name:               "token",
+			script:             `echo '{"apiVersion":"client.authentication.k8s.io/v1beta1","kind":"ExecCredential","status":{"token":"token","expirationTimestamp":"2030-01-01T00:00:00Z"}}'`,
+			expectedExpiration: "2030-01-01T00:00:00Z",
+		},
+		{
+			name:   "exec info",
+			script: `echo "$KUBERNETES_EXEC_INFO" | grep -q '"interactive":false' && echo '{"apiVersion":"client.authentication.k8s.io/v1beta1","kind":"ExecCredential","status":{"token":"token"}}'`,
+		},
+		{
+			name:        "plugin failed",
+			script:      "echo 'identity not found' >&2; exit 1",
+			expectedErr: "exec plugin \"plugin\" failed: exit status 1: identity not found",
+		},
+		{
+			name:        "no credential",
+			script:      `echo '{"apiVersion":"client.authentication.k8s.io/v1beta1","kind":"ExecCredential","status":{}}'`,
+			expectedErr: "exec plugin \"plugin\" returned no credential",
+		},
+		{
+			name:        "unexpected version",
+			script:      `echo '{"apiVersion":"client.authentication.k8s.io/v1alpha1","kind":"ExecCredential","status":{"token":"token"}}'`,
+			expectedErr: "exec plugin \"plugin\" returned client.authentication.k8s.io/v1alpha1 ExecCredential instead of client.authentication.k8s.io/v1beta1 ExecCredential",
+		},
+		{
+			name:        "plugin not installed",
+			command:     "not-installed-plugin",
+			installHint: "not-installed-plugin is required",
+			expectedErr: "unable to run exec plugin \"not-installed-plugin\": exec: \"not-installed-plugin\": executable file not found in $PATH. not-installed-plugin is required",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			command := c.command
+			if len(command) == 0 {
+				command = newTestExecPlugin(t, c.script)
+			}
+			credential, err := RunExecPlugin(context.TODO(), &clientcmdapi.ExecConfig{
+				APIVersion:  ExecPluginAPIVersion,
+				Command:     command,
+				InstallHint: c.installHint,
+			}, nil)
+			if err != nil && len(c.command) == 0 {
+				// the error refers to the full path of the test plugin
+				err = errors.New(strings.ReplaceAll(err.Error(), path.Dir(command)+"/", ""))
+			}
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if err != nil {
+				return
+			}
+			if credential.Token != "token" {
+				t.Errorf("expected the token, but got %q", credential.Token)
+			}
+			if len(c.expectedExpiration) > 0 &&
+				(credential.ExpirationTimestamp == nil || credential.ExpirationTimestamp.Format(time.RFC3339) != c.expectedExpiration) {
+				t.Errorf("expected the credential to expire at %s, but got %v", c.expectedExpiration, credential.ExpirationTimestamp)
+			}
+		})
+	}
+}
+
+func TestBuildExecKubeconfig(t *testing.T) {
+	kubeconfig := BuildExecKubeconfig(&restclient.Config{Host: "https://hub.example.com"}, NewGCPWorkloadIdentityExecConfig())
+	exec := kubeconfig.AuthInfos["default-auth"].Exec
+	if exec == nil || exec.Command != "gke-gcloud-auth-plugin" || !exec.ProvideClusterInfo {
+		t.Errorf("expected the kubeconfig to authenticate with gke-gcloud-auth-plugin, but got %v", exec)
+	}
+	if kubeconfig.AuthInfos["default-auth"].ClientCertificate != "" {
+		t.Errorf("expected no client certificate in the kubeconfig")
+	}
+}
@@ -0,0 +1,85 @@
+package clientcert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"k8s.io/client-go/util/keyutil"
+)
+
+const (
+	// KeyTypeECDSA and KeyTypeRSA are the supported types of the private keys of the client certificates
+	KeyTypeECDSA = "ECDSA"
+	KeyTypeRSA   = "RSA"
+
+	defaultECDSAKeyBitSize = 256
+	defaultRSAKeyBitSize   = 2048
+)
+
+// supportedKeyBitSizes are the supported sizes of each key type, i.e. the curves P-256 and P-384 of ECDSA, and
+// the RSA moduli of 2048, 3072 and 4096 bits
+var supportedKeyBitSizes = map[string][]int{
+	KeyTypeECDSA: {256, 384},
+	KeyTypeRSA:   {2048, 3072, 4096},
+}
+
+// ValidateKey returns an error if the type or the size of the private key is not supported. The empty type means
+// ECDSA, and the zero size means the default size of the type, 256 bits for ECDSA and 2048 bits for RSA.
+func ValidateKey(keyType string, keyBitSize int) error {
+	if len(keyType) == 0 {
+		keyType = KeyTypeECDSA
+	}
+	sizes, ok := supportedKeyBitSizes[keyType]
+	if !ok {
+		return fmt.Errorf("the key type %q is not supported, it must be %s or %s", keyType, KeyTypeECDSA, KeyTypeRSA)
+	}
+	if keyBitSize == 0 {
+		return nil
+	}
+	for _, size := range sizes {
+		if keyBitSize == size {
+			return nil
+		}
+	}
+	return fmt.Errorf("the key bit size %d is not supported by %s, it must be one of %v", keyBitSize, keyType, sizes)
+}
+
+// newPrivateKeyPEM generates a private key of the type and the size in PEM, see ValidateKey
+func newPrivateKeyPEM(keyType string, keyBitSize int) ([]byte, error) {
+	if err := ValidateKey(keyType, keyBitSize); err != nil {
+		return nil, err
+	}
+
+	if keyType == KeyTypeRSA {
+		if keyBitSize == 0 {
+			keyBitSize = defaultRSAKeyBitSize
+		}
+		key, err := rsa.GenerateKey(rand.Reader, keyBitSize)
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate RSA key: %w", err)
+		}
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  keyutil.RSAPrivateKeyBlockType,
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		}), nil
+	}
+
+	curve := elliptic.P256()
+	if keyBitSize == 384 {
+		curve = elliptic.P384()
+	}
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate ECDSA key: %w", err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal ECDSA key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: keyutil.ECPrivateKeyBlockType, Bytes: der}), nil
+}
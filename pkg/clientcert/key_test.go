@@ -0,0 +1,81 @@
+package clientcert
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"testing"
+
+	"k8s.io/client-go/util/keyutil"
+)
+
+func TestNewPrivateKeyPEM(t *testing.T) {
+	cases := []struct {
+		name         string
+		keyType      string
+		keyBitSize   int
+		expectedType string
+		expectedSize int
+		expectedErr  string
+	}{
+		{
+			name:         "default key",
+			expectedType: KeyTypeECDSA,
+			expectedSize: 256,
+		},
+		{
+			name:         "ECDSA P-384 key",
+			keyType:      KeyTypeECDSA,
+			keyBitSize:   384,
+			expectedType: KeyTypeECDSA,
+			expectedSize: 384,
+		},
+		{
+			name:         "default RSA key",
+			keyType:      KeyTypeRSA,
+			expectedType: KeyTypeRSA,
+			expectedSize: 2048,
+		},
+		{
+			name:        "unsupported key type",
+			keyType:     "Ed25519",
+			expectedErr: "the key type \"Ed25519\" is not supported, it must be ECDSA or RSA",
+		},
+		{
+			name:        "unsupported key bit size",
+			keyType:     KeyTypeRSA,
+			keyBitSize:  1024,
+			expectedErr: "the key bit size 1024 is not supported by RSA, it must be one of [2048 3072 4096]",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			keyData, err := newPrivateKeyPEM(c.keyType, c.keyBitSize)
+			if len(c.expectedErr) > 0 {
+				if err == nil || err.Error() != c.expectedErr {
+					t.Fatalf("expected error %q, but got %v", c.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			key, err := keyutil.ParsePrivateKeyPEM(keyData)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var keyType string
+			var keySize int
+			switch k := key.(type) {
+			case *ecdsa.PrivateKey:
+				keyType, keySize = KeyTypeECDSA, k.Curve.Params().BitSize
+			case *rsa.PrivateKey:
+				keyType, keySize = KeyTypeRSA, k.N.BitLen()
+			}
+			if keyType != c.expectedType || keySize != c.expectedSize {
+				t.Errorf("expected %s key of %d bits, but got %s key of %d bits", c.expectedType, c.expectedSize, keyType, keySize)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package clientcert
+
+import (
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// KubeconfigOverrides overrides the cluster stanzas of a kubeconfig, the empty fields are ignored.
+type KubeconfigOverrides struct {
+	// Server is the address of the apiserver, e.g. the address of the hub apiserver reachable from the component
+	// which is different from the one the agent uses.
+	Server string
+	// ProxyURL is the url of the proxy to connect to the apiserver.
+	ProxyURL string
+	// TLSServerName is the server name used to verify the serving certificate of the apiserver.
+	TLSServerName string
+}
+
+// BuildKubeconfigFromSecret builds a self-contained kubeconfig from a hub kubeconfig secret maintained by the
+// registration agent, so that the other components are able to consume the registration credentials without
+// mounting the secret. The kubeconfig in the secret refers to the certificate and the key with file paths, which
+// are resolved to the data of the keys with the same base name in the secret. The inline data in the kubeconfig
+// is kept as it is.
+func BuildKubeconfigFromSecret(secret *corev1.Secret, overrides KubeconfigOverrides) (*clientcmdapi.Config, error) {
+	kubeconfigData, ok := secret.Data[KubeconfigFile]
+	if !ok {
+		return nil, fmt.Errorf("no %q found in secret %s/%s", KubeconfigFile, secret.Namespace, secret.Name)
+	}
+	kubeconfig, err := clientcmd.Load(kubeconfigData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load kubeconfig in secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	resolve := func(filePath string, data []byte) ([]byte, error) {
+		if len(data) > 0 || len(filePath) == 0 {
+			return data, nil
+		}
+		key := path.Base(filePath)
+		fileData, ok := secret.Data[key]
+		if !ok {
+			return nil, fmt.Errorf("file %q referred by the kubeconfig is not found in secret %s/%s", filePath, secret.Namespace, secret.Name)
+		}
+		return fileData, nil
+	}
+
+	for name, cluster := range kubeconfig.Clusters {
+		if cluster.CertificateAuthorityData, err = resolve(cluster.CertificateAuthority, cluster.CertificateAuthorityData); err != nil {
+			return nil, err
+		}
+		cluster.CertificateAuthority = ""
+		if len(overrides.Server) > 0 {
+			cluster.Server = overrides.Server
+		}
+		if len(overrides.ProxyURL) > 0 {
+			cluster.ProxyURL = overrides.ProxyURL
+		}
+		if len(overrides.TLSServerName) > 0 {
+			cluster.TLSServerName = overrides.TLSServerName
+		}
+		kubeconfig.Clusters[name] = cluster
+	}
+
+	for name, authInfo := range kubeconfig.AuthInfos {
+		if authInfo.ClientCertificateData, err = resolve(authInfo.ClientCertificate, authInfo.ClientCertificateData); err != nil {
+			return nil, err
+		}
+		authInfo.ClientCertificate = ""
+		if authInfo.ClientKeyData, err = resolve(authInfo.ClientKey, authInfo.ClientKeyData); err != nil {
+			return nil, err
+		}
+		authInfo.ClientKey = ""
+		kubeconfig.AuthInfos[name] = authInfo
+	}
+
+	return kubeconfig, nil
+}
+
+// BuildRestConfigFromSecret builds a rest config from a hub kubeconfig secret maintained by the registration agent,
+// see BuildKubeconfigFromSecret.
+func BuildRestConfigFromSecret(secret *corev1.Secret, overrides KubeconfigOverrides) (*restclient.Config, error) {
+	kubeconfig, err := BuildKubeconfigFromSecret(secret, overrides)
+	if err != nil {
+		return nil, err
+	}
+	return clientcmd.NewDefaultClientConfig(*kubeconfig, &clientcmd.ConfigOverrides{}).ClientConfig()
+}
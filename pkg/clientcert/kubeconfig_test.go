@@ -0,0 +1,142 @@
+package clientcert
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestBuildKubeconfigFromSecret(t *testing.T) {
+	cert := testinghelpers.NewTestCert("test", 60*time.Second)
+
+	newSecret := func(kubeconfig *clientcmdapi.Config, data map[string][]byte) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "hub-kubeconfig-secret"},
+			Data:       map[string][]byte{},
+		}
+		if kubeconfig != nil {
+			kubeconfigData, err := clientcmd.Write(*kubeconfig)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			secret.Data[KubeconfigFile] = kubeconfigData
+		}
+		for k, v := range data {
+			secret.Data[k] = v
+		}
+		return secret
+	}
+
+	pathKubeconfig := BuildKubeconfig(&restclient.Config{
+		Host: "https://hub:6443",
+		TLSClientConfig: restclient.TLSClientConfig{
+			CAData: []byte("ca"),
+		},
+	}, TLSCertFile, TLSKeyFile)
+
+	inlineKubeconfig := BuildKubeconfig(&restclient.Config{Host: "https://hub:6443"}, "", "")
+	inlineKubeconfig.AuthInfos["default-auth"].ClientCertificateData = []byte("inline-cert")
+	inlineKubeconfig.AuthInfos["default-auth"].ClientKeyData = []byte("inline-key")
+
+	cases := []struct {
+		name                  string
+		secret                *corev1.Secret
+		overrides             KubeconfigOverrides
+		expectedErr           string
+		expectedServer        string
+		expectedProxyURL      string
+		expectedTLSServerName string
+		expectedCertData      []byte
+		expectedKeyData       []byte
+	}{
+		{
+			name:        "no kubeconfig",
+			secret:      newSecret(nil, nil),
+			expectedErr: "no \"kubeconfig\" found in secret ns1/hub-kubeconfig-secret",
+		},
+		{
+			name:        "referred file is missing",
+			secret:      newSecret(&pathKubeconfig, map[string][]byte{TLSKeyFile: cert.Key}),
+			expectedErr: "file \"tls.crt\" referred by the kubeconfig is not found in secret ns1/hub-kubeconfig-secret",
+		},
+		{
+			name:             "resolve file paths",
+			secret:           newSecret(&pathKubeconfig, map[string][]byte{TLSCertFile: cert.Cert, TLSKeyFile: cert.Key}),
+			expectedServer:   "https://hub:6443",
+			expectedCertData: cert.Cert,
+			expectedKeyData:  cert.Key,
+		},
+		{
+			name:             "keep inline data",
+			secret:           newSecret(&inlineKubeconfig, map[string][]byte{TLSCertFile: cert.Cert, TLSKeyFile: cert.Key}),
+			expectedServer:   "https://hub:6443",
+			expectedCertData: []byte("inline-cert"),
+			expectedKeyData:  []byte("inline-key"),
+		},
+		{
+			name:   "override cluster",
+			secret: newSecret(&pathKubeconfig, map[string][]byte{TLSCertFile: cert.Cert, TLSKeyFile: cert.Key}),
+			overrides: KubeconfigOverrides{
+				Server:        "https://hub.internal:6443",
+				ProxyURL:      "http://proxy:3128",
+				TLSServerName: "hub",
+			},
+			expectedServer:        "https://hub.internal:6443",
+			expectedProxyURL:      "http://proxy:3128",
+			expectedTLSServerName: "hub",
+			expectedCertData:      cert.Cert,
+			expectedKeyData:       cert.Key,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeconfig, err := BuildKubeconfigFromSecret(c.secret, c.overrides)
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if err != nil {
+				return
+			}
+
+			cluster := kubeconfig.Clusters["default-cluster"]
+			if cluster.Server != c.expectedServer {
+				t.Errorf("expected server %q, but got %q", c.expectedServer, cluster.Server)
+			}
+			if cluster.ProxyURL != c.expectedProxyURL {
+				t.Errorf("expected proxy url %q, but got %q", c.expectedProxyURL, cluster.ProxyURL)
+			}
+			if cluster.TLSServerName != c.expectedTLSServerName {
+				t.Errorf("expected tls server name %q, but got %q", c.expectedTLSServerName, cluster.TLSServerName)
+			}
+
+			authInfo := kubeconfig.AuthInfos["default-auth"]
+			if len(authInfo.ClientCertificate) > 0 || len(authInfo.ClientKey) > 0 {
+				t.Errorf("expected no file paths in kubeconfig, but got %q and %q", authInfo.ClientCertificate, authInfo.ClientKey)
+			}
+			if !bytes.Equal(authInfo.ClientCertificateData, c.expectedCertData) {
+				t.Errorf("expected cert data %q, but got %q", c.expectedCertData, authInfo.ClientCertificateData)
+			}
+			if !bytes.Equal(authInfo.ClientKeyData, c.expectedKeyData) {
+				t.Errorf("expected key data %q, but got %q", c.expectedKeyData, authInfo.ClientKeyData)
+			}
+
+			restConfig, err := BuildRestConfigFromSecret(c.secret, c.overrides)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if restConfig.Host != c.expectedServer {
+				t.Errorf("expected host %q, but got %q", c.expectedServer, restConfig.Host)
+			}
+			if !bytes.Equal(restConfig.CertData, c.expectedCertData) {
+				t.Errorf("expected cert data %q in rest config, but got %q", c.expectedCertData, restConfig.CertData)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package clientcert
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var csrPersistenceDuration = metrics.NewHistogram(
+	&metrics.HistogramOpts{
+		Name: "open_cluster_management_registration_csr_persistence_duration_seconds",
+		Help: "Duration from the certificate of a csr is observed issued by the agent to it is persisted in the secret. " +
+			"Together with the csr approval and issuance durations on the hub, it tells the delays on the agent from the ones on the hub.",
+		Buckets: []float64{0.1, 0.5, 1, 5, 15, 30, 60, 300, 600},
+	},
+)
+
+var clientCertExpiration = metrics.NewGaugeVec(
+	&metrics.GaugeOpts{
+		Name: "open_cluster_management_registration_client_cert_expiration_timestamp_seconds",
+		Help: "Unix time in seconds the client certificate in the secret of each client certificate controller expires, " +
+			"e.g. alert on it minus time() to renew the hub kubeconfig before it expires.",
+	},
+	[]string{"controller"},
+)
+
+var clientCertRotations = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Name: "open_cluster_management_registration_client_cert_rotations_total",
+		Help: "Number of the client certificates stored in the secret by each client certificate controller, the origin " +
+			"is bootstrap if there is no valid certificate in the secret before, or rotated otherwise.",
+	},
+	[]string{"controller", "origin"},
+)
+
+var csrPendingDuration = metrics.NewHistogramVec(
+	&metrics.HistogramOpts{
+		Name:    "open_cluster_management_registration_csr_pending_duration_seconds",
+		Help:    "Duration from a csr is created by each client certificate controller to its certificate is observed issued.",
+		Buckets: []float64{1, 5, 15, 30, 60, 300, 600, 1800, 3600, 21600, 86400},
+	},
+	[]string{"controller"},
+)
+
+func init() {
+	legacyregistry.MustRegister(csrPersistenceDuration)
+	legacyregistry.MustRegister(clientCertExpiration)
+	legacyregistry.MustRegister(clientCertRotations)
+	legacyregistry.MustRegister(csrPendingDuration)
+}
+
+// ForgetMetrics removes the metrics of a client certificate controller, it is called once the controller is stopped,
+// e.g. the registration of an addon is removed, so the expiration of the certificate no longer managed is not
+// alerted on.
+func ForgetMetrics(controllerName string) {
+	clientCertExpiration.DeleteLabelValues(controllerName)
+	csrPendingDuration.DeleteLabelValues(controllerName)
+	clientCertRotations.DeleteLabelValues(controllerName, CertificateOriginBootstrap)
+	clientCertRotations.DeleteLabelValues(controllerName, CertificateOriginRotated)
+}
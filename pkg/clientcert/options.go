@@ -0,0 +1,273 @@
+package clientcert
+
+import (
+	"context"
+	"crypto/x509/pkix"
+	"fmt"
+	"strings"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	certificatesinformers "k8s.io/client-go/informers/certificates"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ClientCertificateRotatedCondition is the type of the condition reported to the StatusUpdateFunc once the
+	// controller tries to update the client certificate with an issued csr.
+	ClientCertificateRotatedCondition = "ClientCertificateRotated"
+
+	// ClientCertificateUpdatedReason is the reason of the condition when the client certificate is updated.
+	ClientCertificateUpdatedReason = "ClientCertificateUpdated"
+	// ClientCertificateUpdateFailedReason is the reason of the condition when the client certificate cannot be
+	// updated with the issued csr.
+	ClientCertificateUpdateFailedReason = "ClientCertificateUpdateFailed"
+	// SignerUnavailableReason is the reason of the condition when no csr is created since the signer is rejected by
+	// the SignerChecker.
+	SignerUnavailableReason = "SignerUnavailable"
+)
+
+// StatusUpdateFunc is called with a ClientCertificateRotatedCondition each time the controller updates the client
+// certificate or fails to, so that the owner of the certificate, like a ManagedCluster or a ManagedClusterAddOn,
+// can surface the state of the certificate.
+type StatusUpdateFunc func(ctx context.Context, cond metav1.Condition) error
+
+// Option configures the controller returned by NewController.
+type Option func(*controllerOptions)
+
+type controllerOptions struct {
+	ClientCertOption
+	CSROption
+	statusUpdater StatusUpdateFunc
+	csrControl    CSRControl
+	issuer        CertificateIssuer
+}
+
+// WithSigner sets the signer of the csrs. The kube-apiserver client signer is used by default.
+func WithSigner(signerName string) Option {
+	return func(o *controllerOptions) {
+		o.SignerName = signerName
+	}
+}
+
+// WithSubject sets the subject of the client certificate. It is required.
+func WithSubject(subject *pkix.Name) Option {
+	return func(o *controllerOptions) {
+		o.Subject = subject
+	}
+}
+
+// WithDNSNames sets the DNS names of the certificate.
+func WithDNSNames(dnsNames ...string) Option {
+	return func(o *controllerOptions) {
+		o.DNSNames = dnsNames
+	}
+}
+
+// WithUsages sets the key usages of the csrs. The usages of client certificates are used by default.
+func WithUsages(usages ...certificatesv1.KeyUsage) Option {
+	return func(o *controllerOptions) {
+		o.Usages = usages
+	}
+}
+
+// WithRenewalThreshold sets the ratio of the certificate lifetime remaining at which the certificate is renewed.
+// It must be in (0, 1), and 0.2 is used by default.
+func WithRenewalThreshold(threshold float64) Option {
+	return func(o *controllerOptions) {
+		o.RotationThreshold = threshold
+	}
+}
+
+// WithRenewalJitter sets the ratio of the certificate lifetime the renewals are spread over, so the certificate is
+// renewed between threshold and threshold+jitter of its lifetime remaining. A quarter of the threshold is used by
+// default or if the jitter is negative, and 0 turns the jitter off, see ValidateRenewal.
+func WithRenewalJitter(jitter float64) Option {
+	return func(o *controllerOptions) {
+		o.RotationJitter = jitter
+	}
+}
+
+// WithKey sets the type and the size of the private keys, e.g. KeyTypeECDSA and 384 for the P-384 curve. An
+// ECDSA P-256 key is generated by default.
+func WithKey(keyType string, keyBitSize int) Option {
+	return func(o *controllerOptions) {
+		o.KeyType = keyType
+		o.KeyBitSize = keyBitSize
+	}
+}
+
+// WithStatusUpdater sets the function which is called with the state of the certificate rotation.
+func WithStatusUpdater(statusUpdater StatusUpdateFunc) Option {
+	return func(o *controllerOptions) {
+		o.statusUpdater = statusUpdater
+	}
+}
+
+// WithSignerChecker sets the function which verifies the signer before a csr is created. All signers are
+// accepted by default.
+func WithSignerChecker(checker SignerChecker) Option {
+	return func(o *controllerOptions) {
+		o.SignerChecker = checker
+	}
+}
+
+// WithCSRObjectMeta sets the ObjectMeta of the csrs and the function to match the created csrs. The csrs are
+// generated with the name of the secret as prefix, and matched by their name prefix and labels by default.
+func WithCSRObjectMeta(objectMeta metav1.ObjectMeta, eventFilterFunc factory.EventFilterFunc) Option {
+	return func(o *controllerOptions) {
+		o.ObjectMeta = objectMeta
+		o.EventFilterFunc = eventFilterFunc
+	}
+}
+
+// WithSecretData adds the data into the secret besides the certificate and key. If sensitive is true, the
+// certificate is recreated once the data changes.
+func WithSecretData(data map[string][]byte, sensitive bool) Option {
+	return func(o *controllerOptions) {
+		o.AdditionalSecretData = data
+		o.AdditionalSecretDataSensitive = sensitive
+	}
+}
+
+// WithCSRControl sets the CSRControl which requests the certificates instead of the csrs on the hub. The csr api of
+// the hub is not discovered then, so the hub csr informer and client of NewController may be nil, and the caller is
+// responsible to start the informer of the CSRControl.
+func WithCSRControl(csrControl CSRControl) Option {
+	return func(o *controllerOptions) {
+		o.csrControl = csrControl
+	}
+}
+
+// WithCertificateIssuer sets the CertificateIssuer which issues the certificates synchronously, e.g.
+// NewVaultIssuer, instead of the csrs on the hub. The hub csr informer and client of NewController may be nil then.
+func WithCertificateIssuer(issuer CertificateIssuer) Option {
+	return func(o *controllerOptions) {
+		o.issuer = issuer
+	}
+}
+
+// WithSecretLabels adds the labels on the secret.
+func WithSecretLabels(labels map[string]string) Option {
+	return func(o *controllerOptions) {
+		o.SecretLabels = labels
+	}
+}
+
+// NewController returns a controller which creates a certificate with csrs on the hub, stores it in the secret
+// secretNamespace/secretName on the spoke, and renews it before it expires. The csr api of the hub is discovered
+// with the hub client unless the certificates are requested with WithCSRControl or WithCertificateIssuer.
+func NewController(
+	secretNamespace, secretName string,
+	hubCSRInformer certificatesinformers.Interface,
+	spokeSecretInformer corev1informers.SecretInformer,
+	spokeKubeClient kubernetes.Interface,
+	hubKubeClient kubernetes.Interface,
+	recorder events.Recorder,
+	controllerName string,
+	opts ...Option,
+) (factory.Controller, error) {
+	o := &controllerOptions{
+		ClientCertOption: ClientCertOption{
+			SecretNamespace: secretNamespace,
+			SecretName:      secretName,
+			RotationJitter:  -1,
+		},
+		CSROption: CSROption{
+			SignerName: certificatesv1.KubeAPIServerClientSignerName,
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.complete(); err != nil {
+		return nil, err
+	}
+
+	csrControl := o.csrControl
+	if csrControl == nil && o.issuer == nil {
+		var err error
+		if csrControl, err = NewCSRControl(hubCSRInformer, hubKubeClient); err != nil {
+			return nil, err
+		}
+	}
+	return newClientCertificateController(o, csrControl, spokeSecretInformer, spokeKubeClient.CoreV1(), recorder,
+		controllerName), nil
+}
+
+// ValidateRenewal returns an error if the renewal threshold or the renewal jitter is out of range. A threshold and
+// a jitter whose sum reaches the whole lifetime are rejected, otherwise a certificate could be renewed right after
+// it is issued. The zero threshold means the default threshold, and a negative jitter means the default jitter,
+// while the zero jitter turns the jitter off.
+func ValidateRenewal(threshold, jitter float64) error {
+	if threshold == 0 {
+		threshold = defaultRotationThreshold
+	}
+	if threshold < 0 || threshold >= 1 {
+		return fmt.Errorf("the renewal threshold %v is not in range (0, 1)", threshold)
+	}
+	if jitter < 0 {
+		jitter = defaultRotationJitter(threshold)
+	}
+	if threshold+jitter >= 1 {
+		return fmt.Errorf("the renewal threshold %v plus the renewal jitter %v must be less than 1", threshold, jitter)
+	}
+	return nil
+}
+
+// complete defaults and validates the options
+func (o *controllerOptions) complete() error {
+	if o.Subject == nil {
+		return fmt.Errorf("the subject of the certificate is required")
+	}
+	if len(o.SignerName) == 0 {
+		return fmt.Errorf("the signer of the csrs is required")
+	}
+	if o.RotationThreshold == 0 {
+		o.RotationThreshold = defaultRotationThreshold
+	}
+	if err := ValidateRenewal(o.RotationThreshold, o.RotationJitter); err != nil {
+		return err
+	}
+	if o.RotationJitter < 0 {
+		o.RotationJitter = defaultRotationJitter(o.RotationThreshold)
+	}
+	if err := ValidateKey(o.KeyType, o.KeyBitSize); err != nil {
+		return err
+	}
+
+	if len(o.ObjectMeta.Name) == 0 && len(o.ObjectMeta.GenerateName) == 0 {
+		o.ObjectMeta.GenerateName = fmt.Sprintf("%s-", o.SecretName)
+	}
+	if o.EventFilterFunc == nil {
+		o.EventFilterFunc = csrEventFilterFunc(o.ObjectMeta)
+	}
+	return nil
+}
+
+// csrEventFilterFunc matches the csrs created with the ObjectMeta by their name prefix and labels
+func csrEventFilterFunc(objectMeta metav1.ObjectMeta) factory.EventFilterFunc {
+	return func(obj interface{}) bool {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return false
+		}
+		if len(objectMeta.GenerateName) > 0 && !strings.HasPrefix(accessor.GetName(), objectMeta.GenerateName) {
+			return false
+		}
+		if len(objectMeta.Name) > 0 && accessor.GetName() != objectMeta.Name {
+			return false
+		}
+		labels := accessor.GetLabels()
+		for k, v := range objectMeta.Labels {
+			if labels[k] != v {
+				return false
+			}
+		}
+		return true
+	}
+}
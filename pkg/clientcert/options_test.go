@@ -0,0 +1,223 @@
+package clientcert
+
+import (
+	"crypto/x509/pkix"
+	"math"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNewController(t *testing.T) {
+	cases := []struct {
+		name        string
+		opts        []Option
+		expectedErr bool
+	}{
+		{
+			name:        "no subject",
+			expectedErr: true,
+		},
+		{
+			name: "no signer",
+			opts: []Option{
+				WithSubject(&pkix.Name{CommonName: "test"}),
+				WithSigner(""),
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid renewal threshold",
+			opts: []Option{
+				WithSubject(&pkix.Name{CommonName: "test"}),
+				WithRenewalThreshold(1.5),
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid renewal jitter",
+			opts: []Option{
+				WithSubject(&pkix.Name{CommonName: "test"}),
+				WithRenewalJitter(0.8),
+			},
+			expectedErr: true,
+		},
+		{
+			name: "unsupported key",
+			opts: []Option{
+				WithSubject(&pkix.Name{CommonName: "test"}),
+				WithKey(KeyTypeECDSA, 521),
+			},
+			expectedErr: true,
+		},
+		{
+			name: "valid options",
+			opts: []Option{
+				WithSubject(&pkix.Name{CommonName: "test"}),
+				WithSigner("example.com/signer"),
+				WithDNSNames("test.testns.svc"),
+				WithUsages(certificatesv1.UsageServerAuth),
+				WithRenewalThreshold(0.5),
+				WithRenewalJitter(0.1),
+				WithSecretData(map[string][]byte{"key": []byte("value")}, true),
+				WithSecretLabels(map[string]string{"app": "test"}),
+				WithKey(KeyTypeRSA, 3072),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+			_, err := NewController(testNamespace, testSecretName, informerFactory.Certificates(),
+				informerFactory.Core().V1().Secrets(), kubeClient, kubeClient, eventstesting.NewTestingEventRecorder(t),
+				"test", c.opts...)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but got nil")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCSREventFilterFunc(t *testing.T) {
+	o := &controllerOptions{
+		ClientCertOption: ClientCertOption{SecretName: testSecretName},
+		CSROption: CSROption{
+			Subject:    &pkix.Name{CommonName: "test"},
+			SignerName: certificatesv1.KubeAPIServerClientSignerName,
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{"app": "test"},
+			},
+		},
+	}
+	if err := o.complete(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o.ObjectMeta.GenerateName != testSecretName+"-" {
+		t.Errorf("expected generate name %q, but got %q", testSecretName+"-", o.ObjectMeta.GenerateName)
+	}
+
+	cases := []struct {
+		name     string
+		csr      *certificatesv1.CertificateSigningRequest
+		expected bool
+	}{
+		{
+			name: "matched csr",
+			csr: &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: testSecretName + "-abcde", Labels: map[string]string{"app": "test"}},
+			},
+			expected: true,
+		},
+		{
+			name: "name not matched",
+			csr: &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "other-abcde", Labels: map[string]string{"app": "test"}},
+			},
+		},
+		{
+			name: "labels not matched",
+			csr: &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: testSecretName + "-abcde"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := o.EventFilterFunc(c.csr); actual != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCompleteRenewal(t *testing.T) {
+	cases := []struct {
+		name              string
+		threshold         float64
+		jitter            float64
+		expectedThreshold float64
+		expectedJitter    float64
+		expectedErr       string
+	}{
+		{
+			name:              "default threshold and jitter",
+			jitter:            -1,
+			expectedThreshold: 0.2,
+			expectedJitter:    0.05,
+		},
+		{
+			name:              "default jitter of a high threshold",
+			threshold:         0.9,
+			jitter:            -1,
+			expectedThreshold: 0.9,
+			expectedJitter:    0.025,
+		},
+		{
+			name:              "jitter turned off",
+			threshold:         0.5,
+			expectedThreshold: 0.5,
+			expectedJitter:    0,
+		},
+		{
+			name:              "jitter right below the lifetime",
+			threshold:         0.5,
+			jitter:            0.49,
+			expectedThreshold: 0.5,
+			expectedJitter:    0.49,
+		},
+		{
+			name:        "jitter up to the lifetime",
+			threshold:   0.5,
+			jitter:      0.5,
+			expectedErr: "the renewal threshold 0.5 plus the renewal jitter 0.5 must be less than 1",
+		},
+		{
+			name:        "jitter beyond the lifetime",
+			threshold:   0.5,
+			jitter:      0.7,
+			expectedErr: "the renewal threshold 0.5 plus the renewal jitter 0.7 must be less than 1",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			o := &controllerOptions{
+				ClientCertOption: ClientCertOption{
+					SecretName:        testSecretName,
+					RotationThreshold: c.threshold,
+					RotationJitter:    c.jitter,
+				},
+				CSROption: CSROption{
+					Subject:    &pkix.Name{CommonName: "test"},
+					SignerName: certificatesv1.KubeAPIServerClientSignerName,
+				},
+			}
+			err := o.complete()
+			if len(c.expectedErr) > 0 {
+				if err == nil || err.Error() != c.expectedErr {
+					t.Fatalf("expected error %q, but got %v", c.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(o.RotationThreshold-c.expectedThreshold) > 1e-9 {
+				t.Errorf("expected threshold %v, but got %v", c.expectedThreshold, o.RotationThreshold)
+			}
+			if math.Abs(o.RotationJitter-c.expectedJitter) > 1e-9 {
+				t.Errorf("expected jitter %v, but got %v", c.expectedJitter, o.RotationJitter)
+			}
+		})
+	}
+}
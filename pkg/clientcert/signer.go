@@ -0,0 +1,24 @@
+package clientcert
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// SignerChecker returns an error if the csrs with the signer are not expected to be signed, e.g. the signer is not
+// allowed or not known on the hub. The controller reports the error in the ClientCertificateRotatedCondition
+// instead of creating a csr which stays pending forever.
+type SignerChecker func(ctx context.Context, signerName string) error
+
+// NewSignerAllowlist returns a SignerChecker which accepts only the signers in the list
+func NewSignerAllowlist(signerNames ...string) SignerChecker {
+	allowed := sets.NewString(signerNames...)
+	return func(ctx context.Context, signerName string) error {
+		if !allowed.Has(signerName) {
+			return fmt.Errorf("signer %q is not one of the allowed signers %q", signerName, allowed.List())
+		}
+		return nil
+	}
+}
@@ -0,0 +1,42 @@
+package clientcert
+
+import (
+	"context"
+	"testing"
+
+	certificates "k8s.io/api/certificates/v1"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestSignerAllowlist(t *testing.T) {
+	cases := []struct {
+		name        string
+		allowlist   []string
+		signerName  string
+		expectedErr string
+	}{
+		{
+			name:       "allowed signer",
+			allowlist:  []string{certificates.KubeAPIServerClientSignerName, "example.com/mtls"},
+			signerName: "example.com/mtls",
+		},
+		{
+			name:        "unknown signer",
+			allowlist:   []string{"example.com/mtls", certificates.KubeAPIServerClientSignerName},
+			signerName:  "example.com/unknown",
+			expectedErr: "signer \"example.com/unknown\" is not one of the allowed signers [\"example.com/mtls\" \"kubernetes.io/kube-apiserver-client\"]",
+		},
+		{
+			name:        "empty allowlist",
+			signerName:  certificates.KubeAPIServerClientSignerName,
+			expectedErr: "signer \"kubernetes.io/kube-apiserver-client\" is not one of the allowed signers []",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := NewSignerAllowlist(c.allowlist...)(context.TODO(), c.signerName)
+			testinghelpers.AssertError(t, err, c.expectedErr)
+		})
+	}
+}
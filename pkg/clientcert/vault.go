@@ -0,0 +1,192 @@
+package clientcert
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	certutil "k8s.io/client-go/util/cert"
+
+	"open-cluster-management.io/registration/pkg/fips"
+)
+
+const (
+	// DefaultVaultAuthPath is the default mount path of the Kubernetes auth method in Vault
+	DefaultVaultAuthPath = "kubernetes"
+	// DefaultVaultPKIPath is the default mount path of the PKI secrets engine in Vault
+	DefaultVaultPKIPath = "pki"
+	// DefaultVaultTokenFile is the service account token of the agent, which logs in to Vault
+	DefaultVaultTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// VaultConfig configures a CertificateIssuer requesting the certificates from a Vault PKI role
+type VaultConfig struct {
+	// Address is the address of Vault, e.g. https://vault.example.com:8200
+	Address string
+	// CAFile is the CA bundle to verify the serving certificate of Vault, the system roots are used if it is empty
+	CAFile string
+	// AuthPath and AuthRole are the mount path and the role of the Kubernetes auth method the agent logs in with
+	AuthPath string
+	AuthRole string
+	// PKIPath and PKIRole are the mount path and the role of the PKI secrets engine which signs the certificates
+	PKIPath string
+	PKIRole string
+	// TokenFile is the service account token the agent logs in with, it is read on each login since the projected
+	// tokens are rotated.
+	TokenFile string
+}
+
+// vaultIssuer issues the certificates with a Vault PKI role instead of the csrs on the hub, for the installations
+// whose policy forbids signing the cross-cluster identities with the csrs of the hub.
+type vaultIssuer struct {
+	config     VaultConfig
+	httpClient *http.Client
+}
+
+var _ CertificateIssuer = &vaultIssuer{}
+
+// NewVaultIssuer returns a CertificateIssuer which issues the certificates with a Vault PKI role. The agent logs in
+// to Vault with the Kubernetes auth method, and the certificate requests are signed with the sign endpoint of the
+// role, so the role decides the subject organizations, the key usages and the TTL of the certificates, and limits
+// the common names the agent is allowed to request.
+func NewVaultIssuer(config VaultConfig) (CertificateIssuer, error) {
+	if len(config.Address) == 0 || len(config.AuthRole) == 0 || len(config.PKIRole) == 0 {
+		return nil, fmt.Errorf("the address, the auth role and the pki role of Vault are required")
+	}
+	if len(config.AuthPath) == 0 {
+		config.AuthPath = DefaultVaultAuthPath
+	}
+	if len(config.PKIPath) == 0 {
+		config.PKIPath = DefaultVaultPKIPath
+	}
+	if len(config.TokenFile) == 0 {
+		config.TokenFile = DefaultVaultTokenFile
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// the TLS config is restricted to the approved settings in the FIPS mode
+	transport.TLSClientConfig = fips.RestrictTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12})
+	if len(config.CAFile) > 0 {
+		pool, err := certutil.NewPool(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the CA bundle of Vault: %w", err)
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	return &vaultIssuer{
+		config:     config,
+		httpClient: &http.Client{Transport: transport, Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Issue logs in to Vault and signs the certificate request with the PKI role. The key usages are defined by the
+// role.
+func (v *vaultIssuer) Issue(ctx context.Context, recorder events.Recorder, csrData []byte,
+	usages []certificatesv1.KeyUsage) ([]byte, error) {
+	token, err := v.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+	certData, err := v.sign(ctx, token, csrData)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder.Eventf("VaultCertificateIssued", "A certificate is issued by Vault PKI role %q", v.pkiRolePath())
+	return certData, nil
+}
+
+// login logs in to Vault with the service account token, and returns the client token
+func (v *vaultIssuer) login(ctx context.Context) (string, error) {
+	jwt, err := ioutil.ReadFile(path.Clean(v.config.TokenFile))
+	if err != nil {
+		return "", fmt.Errorf("unable to read the service account token: %w", err)
+	}
+
+	resp := struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}{}
+	if err := v.post(ctx, path.Join("auth", v.config.AuthPath, "login"), "", map[string]interface{}{
+		"role": v.config.AuthRole,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	}, &resp); err != nil {
+		return "", fmt.Errorf("unable to log in to Vault with role %q: %w", v.config.AuthRole, err)
+	}
+	if len(resp.Auth.ClientToken) == 0 {
+		return "", fmt.Errorf("no client token is returned by Vault for role %q", v.config.AuthRole)
+	}
+	return resp.Auth.ClientToken, nil
+}
+
+// sign signs the certificate request with the PKI role, and returns the issued certificate. The common name is
+// taken from the certificate request.
+func (v *vaultIssuer) sign(ctx context.Context, token string, csrData []byte) ([]byte, error) {
+	resp := struct {
+		Data struct {
+			Certificate string `json:"certificate"`
+		} `json:"data"`
+	}{}
+	if err := v.post(ctx, path.Join(v.config.PKIPath, "sign", v.config.PKIRole), token, map[string]interface{}{
+		"csr": string(csrData),
+	}, &resp); err != nil {
+		return nil, fmt.Errorf("unable to sign the certificate with Vault PKI role %q: %w", v.pkiRolePath(), err)
+	}
+	if len(resp.Data.Certificate) == 0 {
+		return nil, fmt.Errorf("no certificate is returned by Vault PKI role %q", v.pkiRolePath())
+	}
+	return []byte(resp.Data.Certificate), nil
+}
+
+// post posts the request to the path of the Vault api, and decodes the response into out
+func (v *vaultIssuer) post(ctx context.Context, apiPath, token string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	url := strings.TrimSuffix(v.config.Address, "/") + "/v1/" + apiPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(token) > 0 {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		errResp := struct {
+			Errors []string `json:"errors"`
+		}{}
+		if err := json.Unmarshal(data, &errResp); err == nil && len(errResp.Errors) > 0 {
+			return fmt.Errorf("%s: %s", resp.Status, strings.Join(errResp.Errors, ", "))
+		}
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (v *vaultIssuer) pkiRolePath() string {
+	return path.Join(v.config.PKIPath, "roles", v.config.PKIRole)
+}
@@ -0,0 +1,95 @@
+package clientcert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+// newFakeVault returns a Vault server which accepts the token "jwt" for the auth role "agent" and signs the csrs
+// with the pki role "cluster"
+func newFakeVault(t *testing.T, certData []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		switch r.URL.Path {
+		case "/v1/auth/kubernetes/login":
+			if body["role"] != "agent" || body["jwt"] != "jwt" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"token"}}`))
+		case "/v1/pki/sign/cluster":
+			if r.Header.Get("X-Vault-Token") != "token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if len(body["csr"].(string)) == 0 {
+				t.Errorf("expected the csr to be signed")
+			}
+			if len(body) != 1 {
+				t.Errorf("expected the role to define the certificate, but got %v", body)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"certificate": string(certData)}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestVaultIssuer(t *testing.T) {
+	cert := testinghelpers.NewTestCert("system:open-cluster-management:cluster1:agent1", time.Hour)
+	server := newFakeVault(t, cert.Cert)
+	defer server.Close()
+
+	tokenFile := path.Join(t.TempDir(), "token")
+	testinghelpers.WriteFile(tokenFile, []byte("jwt\n"))
+
+	cases := []struct {
+		name        string
+		authRole    string
+		expectedErr string
+	}{
+		{
+			name:        "login denied",
+			authRole:    "other",
+			expectedErr: "unable to log in to Vault with role \"other\": 403 Forbidden: permission denied",
+		},
+		{
+			name:     "certificate issued",
+			authRole: "agent",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			issuer, err := NewVaultIssuer(VaultConfig{
+				Address:   server.URL,
+				AuthRole:  c.authRole,
+				PKIRole:   "cluster",
+				TokenFile: tokenFile,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			certData, err := issuer.Issue(context.TODO(), eventstesting.NewTestingEventRecorder(t), []byte("csr"),
+				clientCertUsages)
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if err == nil && string(certData) != string(cert.Cert) {
+				t.Errorf("expected the certificate to be issued, but got %q", certData)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+// package cloudevents publishes the status and the heartbeats of the managed clusters as CloudEvents over MQTT,
+// for the edge fleets whose links to the hub kube-apiserver are unreliable. The agents publish the events to the
+// topics of their clusters on an MQTT broker, and the hub consumes them from the broker and reconciles them into
+// the ManagedClusters and their leases.
+//
+// The events are encoded in the structured mode of the CloudEvents JSON format. The broker is expected to
+// authenticate the agents, and to authorize an agent to publish to the topics of its own cluster only.
+package cloudevents
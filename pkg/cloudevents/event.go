@@ -0,0 +1,102 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/cloudevents/sdk-go/v2/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+const (
+	// ClusterStatusEventType is the type of the events with the status of a managed cluster in the data, the
+	// status has the conditions of the cluster reported by the agent, and the version and the resources of the
+	// cluster if it is available.
+	ClusterStatusEventType = "io.open-cluster-management.registration.cluster.status"
+	// ClusterHeartbeatEventType is the type of the events renewing the lease of a managed cluster, they have no data
+	ClusterHeartbeatEventType = "io.open-cluster-management.registration.cluster.heartbeat"
+
+	// ClusterNameExtension is the extension attribute with the name of the managed cluster of an event
+	ClusterNameExtension = "clustername"
+
+	// TopicPrefix is the prefix of the topics of the events, an agent publishes the events of its cluster to
+	// <TopicPrefix>/<cluster name>/status and <TopicPrefix>/<cluster name>/heartbeat
+	TopicPrefix = "open-cluster-management/registration"
+	// ClusterEventsTopicFilter matches the topics of the events of all of the clusters
+	ClusterEventsTopicFilter = TopicPrefix + "/+/+"
+)
+
+// eventTopicSuffixes are the last levels of the topics of the event types
+var eventTopicSuffixes = map[string]string{
+	ClusterStatusEventType:    "status",
+	ClusterHeartbeatEventType: "heartbeat",
+}
+
+// Event is a CloudEvent of a managed cluster, it is encoded in the structured mode of the JSON format
+type Event = event.Event
+
+// NewEvent returns an event of the type published by the agent of the cluster, data is encoded in JSON if it is
+// not nil.
+func NewEvent(eventType, clusterName, agentName string, data interface{}) (*Event, error) {
+	evt := event.New()
+	evt.SetID(string(uuid.NewUUID()))
+	evt.SetSource(fmt.Sprintf("%s/%s/%s", TopicPrefix, clusterName, agentName))
+	evt.SetType(eventType)
+	evt.SetTime(time.Now())
+	evt.SetExtension(ClusterNameExtension, clusterName)
+	if data != nil {
+		if err := evt.SetData(event.ApplicationJSON, data); err != nil {
+			return nil, fmt.Errorf("unable to encode the data of event %q: %w", eventType, err)
+		}
+	}
+	if err := evt.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid event %q: %w", eventType, err)
+	}
+	return &evt, nil
+}
+
+// ClusterName returns the name of the managed cluster of the event
+func ClusterName(evt *Event) string {
+	clusterName, _ := types.ToString(evt.Extensions()[ClusterNameExtension])
+	return clusterName
+}
+
+// Topic returns the topic the event is published to
+func Topic(evt *Event) string {
+	return fmt.Sprintf("%s/%s/%s", TopicPrefix, ClusterName(evt), eventTopicSuffixes[evt.Type()])
+}
+
+// DecodeData decodes the JSON data of the event into v
+func DecodeData(evt *Event, v interface{}) error {
+	if len(evt.Data()) == 0 {
+		return fmt.Errorf("event %q has no data", evt.ID())
+	}
+	return evt.DataAs(v)
+}
+
+// ParseEvent decodes the event published to topic, the topic must be the topic of the cluster and the type of
+// the event, so an agent authorized to publish to the topics of its own cluster is not able to publish the events
+// of the other clusters.
+func ParseEvent(topic string, payload []byte) (*Event, error) {
+	evt := event.New()
+	if err := json.Unmarshal(payload, &evt); err != nil {
+		return nil, fmt.Errorf("unable to decode the event published to %q: %w", topic, err)
+	}
+	if evt.SpecVersion() != event.CloudEventsVersionV1 {
+		return nil, fmt.Errorf("unsupported specversion %q of event %q", evt.SpecVersion(), evt.ID())
+	}
+	if err := evt.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid event published to %q: %w", topic, err)
+	}
+	if _, ok := eventTopicSuffixes[evt.Type()]; !ok {
+		return nil, fmt.Errorf("unsupported type %q of event %q", evt.Type(), evt.ID())
+	}
+	clusterName := ClusterName(&evt)
+	if len(clusterName) == 0 || strings.Contains(clusterName, "/") || Topic(&evt) != topic {
+		return nil, fmt.Errorf("event %q of cluster %q is published to topic %q", evt.ID(), clusterName, topic)
+	}
+	return &evt, nil
+}
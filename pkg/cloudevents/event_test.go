@@ -0,0 +1,78 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestParseEvent(t *testing.T) {
+	statusEvent, err := NewEvent(ClusterStatusEventType, "cluster1", "agent1", &clusterv1.ManagedClusterStatus{
+		Conditions: []metav1.Condition{{Type: clusterv1.ManagedClusterConditionAvailable, Status: metav1.ConditionTrue}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	statusPayload, _ := json.Marshal(statusEvent)
+	unknownEvent := statusEvent.Clone()
+	unknownEvent.SetType("io.example.unknown")
+	unknownPayload, _ := json.Marshal(unknownEvent)
+
+	cases := []struct {
+		name        string
+		topic       string
+		payload     []byte
+		expectedErr string
+	}{
+		{
+			name:        "not json",
+			topic:       "open-cluster-management/registration/cluster1/status",
+			payload:     []byte("status"),
+			expectedErr: "unable to decode the event published to \"open-cluster-management/registration/cluster1/status\": invalid character 's' looking for beginning of value",
+		},
+		{
+			name:        "unknown type",
+			topic:       "open-cluster-management/registration/cluster1/status",
+			payload:     unknownPayload,
+			expectedErr: "unsupported type \"io.example.unknown\" of event \"" + statusEvent.ID() + "\"",
+		},
+		{
+			name:        "published to the topic of another cluster",
+			topic:       "open-cluster-management/registration/cluster2/status",
+			payload:     statusPayload,
+			expectedErr: "event \"" + statusEvent.ID() + "\" of cluster \"cluster1\" is published to topic \"open-cluster-management/registration/cluster2/status\"",
+		},
+		{
+			name:        "published to the topic of another type",
+			topic:       "open-cluster-management/registration/cluster1/heartbeat",
+			payload:     statusPayload,
+			expectedErr: "event \"" + statusEvent.ID() + "\" of cluster \"cluster1\" is published to topic \"open-cluster-management/registration/cluster1/heartbeat\"",
+		},
+		{
+			name:    "status event",
+			topic:   "open-cluster-management/registration/cluster1/status",
+			payload: statusPayload,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			event, err := ParseEvent(c.topic, c.payload)
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if err != nil {
+				return
+			}
+			status := &clusterv1.ManagedClusterStatus{}
+			if err := DecodeData(event, status); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(status.Conditions) != 1 || event.Source() != "open-cluster-management/registration/cluster1/agent1" {
+				t.Errorf("unexpected event %v", event)
+			}
+		})
+	}
+}
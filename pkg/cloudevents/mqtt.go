@@ -0,0 +1,192 @@
+package cloudevents
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultMQTTKeepAlive is the keep alive interval of the connections to the broker if it is not configured
+	DefaultMQTTKeepAlive = 60 * time.Second
+
+	// mqttProtocolVersion is the protocol version of the connections, MQTT 3.1.1
+	mqttProtocolVersion = 4
+	// mqttQoS is the QoS of the publications and the subscriptions, the messages are delivered at least once
+	mqttQoS = 1
+	// mqttDisconnectQuiesce is the time in milliseconds the client waits for the pending work to complete
+	// before it disconnects
+	mqttDisconnectQuiesce = 250
+)
+
+// Publisher publishes the events of the clusters, it is implemented by MQTTClient
+type Publisher interface {
+	PublishEvent(ctx context.Context, event *Event) error
+}
+
+// Subscriber subscribes to the messages of the topics matching a filter, it is implemented by MQTTClient
+type Subscriber interface {
+	Subscribe(ctx context.Context, filter string, handler func(topic string, payload []byte))
+}
+
+// MQTTConfig configures the connections to an MQTT broker
+type MQTTConfig struct {
+	// BrokerAddress is the address of the broker, tls://<host>:<port> for the connections with TLS, or
+	// tcp://<host>:<port> for plain connections
+	BrokerAddress string
+	// ClientID is the client identifier of the connections, it must be unique on the broker
+	ClientID string
+	// Username and Password authenticate the client to the broker if Username is set
+	Username string
+	Password string
+	// TLSConfig is the tls config of the connections to a tls:// address
+	TLSConfig *tls.Config
+	// KeepAlive is the max interval between two packets sent to the broker, DefaultMQTTKeepAlive is used if it
+	// is zero
+	KeepAlive time.Duration
+}
+
+// IsValidBrokerAddress returns true if the address is a tls:// or tcp:// address with a host and a port
+func IsValidBrokerAddress(address string) bool {
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "tls" && u.Scheme != "tcp") || len(u.Path) > 0 {
+		return false
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	return err == nil && len(host) > 0 && len(port) > 0
+}
+
+// MQTTClient publishes and subscribes to the messages of an MQTT broker with QoS 1. The client connects to the
+// broker on demand with a clean session, and reconnects once the connection is lost. The subscription is made
+// again on each connection, so the messages published while it is disconnected are not delivered to it.
+type MQTTClient struct {
+	brokerAddress string
+	client        mqtt.Client
+
+	// connectLock serializes the connections made on demand
+	connectLock sync.Mutex
+
+	lock    sync.Mutex
+	filter  string
+	handler mqtt.MessageHandler
+}
+
+// NewMQTTClient returns a client of the broker in config
+func NewMQTTClient(config MQTTConfig) *MQTTClient {
+	if config.KeepAlive <= 0 {
+		config.KeepAlive = DefaultMQTTKeepAlive
+	}
+	c := &MQTTClient{brokerAddress: config.BrokerAddress}
+	c.client = mqtt.NewClient(mqtt.NewClientOptions().
+		AddBroker(config.BrokerAddress).
+		SetClientID(config.ClientID).
+		SetProtocolVersion(mqttProtocolVersion).
+		SetUsername(config.Username).
+		SetPassword(config.Password).
+		SetTLSConfig(config.TLSConfig).
+		SetKeepAlive(config.KeepAlive).
+		SetCleanSession(true).
+		SetAutoReconnect(true).
+		SetOnConnectHandler(c.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			klog.Warningf("Lost the connection to MQTT broker %q: %v", config.BrokerAddress, err)
+		}))
+	return c
+}
+
+// Publish publishes the payload to the topic, and waits until the broker acknowledges it or the context is done
+func (c *MQTTClient) Publish(ctx context.Context, topic string, payload []byte) error {
+	if err := c.connect(ctx); err != nil {
+		return err
+	}
+	return waitForToken(ctx, c.client.Publish(topic, mqttQoS, false, payload))
+}
+
+// PublishEvent publishes the event to its topic in the structured mode of the JSON format, see Publish
+func (c *MQTTClient) PublishEvent(ctx context.Context, event *Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return c.Publish(ctx, Topic(event), payload)
+}
+
+// Subscribe subscribes to the topics matching filter, and calls handler with the topic and the payload of each
+// message published to them. It retries until the first connection is made, and blocks until the context is
+// done. The messages are acknowledged after handler returns, handler is called by one routine at a time.
+// Subscribe is expected to be called once, before the client publishes any message.
+func (c *MQTTClient) Subscribe(ctx context.Context, filter string, handler func(topic string, payload []byte)) {
+	c.lock.Lock()
+	c.filter = filter
+	c.handler = func(_ mqtt.Client, message mqtt.Message) {
+		handler(message.Topic(), message.Payload())
+	}
+	c.lock.Unlock()
+
+	// the subscription is made by onConnect once the client is connected
+	_ = wait.PollImmediateUntil(time.Second, func() (bool, error) {
+		if err := c.connect(ctx); err != nil {
+			klog.Warningf("Unable to connect to MQTT broker %q: %v", c.brokerAddress, err)
+			return false, nil
+		}
+		return true, nil
+	}, ctx.Done())
+
+	<-ctx.Done()
+	c.Close()
+}
+
+// Close disconnects from the broker
+func (c *MQTTClient) Close() {
+	c.client.Disconnect(mqttDisconnectQuiesce)
+}
+
+// connect connects to the broker unless the client is connected or reconnecting
+func (c *MQTTClient) connect(ctx context.Context) error {
+	if !IsValidBrokerAddress(c.brokerAddress) {
+		return fmt.Errorf("invalid MQTT broker address %q", c.brokerAddress)
+	}
+
+	c.connectLock.Lock()
+	defer c.connectLock.Unlock()
+	if c.client.IsConnected() {
+		return nil
+	}
+	return waitForToken(ctx, c.client.Connect())
+}
+
+// onConnect subscribes to the filter of Subscribe on each connection, since the subscriptions of a clean session
+// are dropped once the connection is lost
+func (c *MQTTClient) onConnect(client mqtt.Client) {
+	c.lock.Lock()
+	filter, handler := c.filter, c.handler
+	c.lock.Unlock()
+	if handler == nil {
+		return
+	}
+
+	token := client.Subscribe(filter, mqttQoS, handler)
+	token.Wait()
+	if err := token.Error(); err != nil {
+		klog.Warningf("Unable to subscribe to %q of MQTT broker %q: %v", filter, c.brokerAddress, err)
+	}
+}
+
+// waitForToken waits until the operation of the token completes or the context is done
+func waitForToken(ctx context.Context, token mqtt.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
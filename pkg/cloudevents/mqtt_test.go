@@ -0,0 +1,279 @@
+package cloudevents
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+// the control packet types of MQTT 3.1.1 handled by the fake broker
+const (
+	mqttConnect    byte = 1
+	mqttConnAck    byte = 2
+	mqttPublish    byte = 3
+	mqttPubAck     byte = 4
+	mqttSubscribe  byte = 8
+	mqttSubAck     byte = 9
+	mqttPingReq    byte = 12
+	mqttPingResp   byte = 13
+	mqttDisconnect byte = 14
+)
+
+// fakeBroker is an MQTT broker which forwards the messages to the subscriptions with the topic filters of single
+// level wildcards. The clients with the id "denied" are refused.
+type fakeBroker struct {
+	listener net.Listener
+
+	lock          sync.Mutex
+	conns         []net.Conn
+	subscriptions map[net.Conn]string
+	writeLocks    map[net.Conn]*sync.Mutex
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b := &fakeBroker{
+		listener:      listener,
+		subscriptions: map[net.Conn]string{},
+		writeLocks:    map[net.Conn]*sync.Mutex{},
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			b.lock.Lock()
+			b.conns = append(b.conns, conn)
+			b.writeLocks[conn] = &sync.Mutex{}
+			b.lock.Unlock()
+			go b.serve(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		b.disconnectAll()
+	})
+	return b
+}
+
+func (b *fakeBroker) address() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+// disconnectAll closes the connections of all of the clients
+func (b *fakeBroker) disconnectAll() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, conn := range b.conns {
+		conn.Close()
+	}
+	b.conns = nil
+	b.subscriptions = map[net.Conn]string{}
+}
+
+func (b *fakeBroker) write(conn net.Conn, header byte, body []byte) {
+	b.lock.Lock()
+	writeLock := b.writeLocks[conn]
+	b.lock.Unlock()
+	writeLock.Lock()
+	defer writeLock.Unlock()
+	_, _ = conn.Write(append(append([]byte{header}, encodeRemainingLength(len(body))...), body...))
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	for {
+		header, body, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		switch header >> 4 {
+		case mqttConnect:
+			// the client id follows the protocol name, level, flags and keep alive
+			clientIDLength := int(binary.BigEndian.Uint16(body[10:]))
+			if string(body[12:12+clientIDLength]) == "denied" {
+				b.write(conn, mqttConnAck<<4, []byte{0, 5})
+				return
+			}
+			b.write(conn, mqttConnAck<<4, []byte{0, 0})
+		case mqttSubscribe:
+			filterLength := int(binary.BigEndian.Uint16(body[2:]))
+			b.lock.Lock()
+			b.subscriptions[conn] = string(body[4 : 4+filterLength])
+			b.lock.Unlock()
+			b.write(conn, mqttSubAck<<4, []byte{body[0], body[1], 1})
+		case mqttPublish:
+			topicLength := int(binary.BigEndian.Uint16(body))
+			topic, id, payload := string(body[2:2+topicLength]), body[2+topicLength:4+topicLength], body[4+topicLength:]
+			b.write(conn, mqttPubAck<<4, id)
+			b.lock.Lock()
+			subscribers := []net.Conn{}
+			for subscriber, filter := range b.subscriptions {
+				if topicMatches(filter, topic) {
+					subscribers = append(subscribers, subscriber)
+				}
+			}
+			b.lock.Unlock()
+			for _, subscriber := range subscribers {
+				b.write(subscriber, mqttPublish<<4|1<<1, append(append(encodeString(topic), 0, 1), payload...))
+			}
+		case mqttPingReq:
+			b.write(conn, mqttPingResp<<4, nil)
+		case mqttDisconnect:
+			conn.Close()
+			return
+		}
+	}
+}
+
+func topicMatches(filter, topic string) bool {
+	filterLevels, topicLevels := strings.Split(filter, "/"), strings.Split(topic, "/")
+	if len(filterLevels) != len(topicLevels) {
+		return false
+	}
+	for i := range filterLevels {
+		if filterLevels[i] != "+" && filterLevels[i] != topicLevels[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMQTTClient(t *testing.T) {
+	broker := newFakeBroker(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	received := make(chan *Event, 10)
+	subscriber := NewMQTTClient(MQTTConfig{BrokerAddress: broker.address(), ClientID: "hub"})
+	go subscriber.Subscribe(ctx, ClusterEventsTopicFilter, func(topic string, payload []byte) {
+		event, err := ParseEvent(topic, payload)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		received <- event
+	})
+
+	publisher := NewMQTTClient(MQTTConfig{BrokerAddress: broker.address(), ClientID: "cluster1", Username: "cluster1", Password: "secret"})
+	defer publisher.Close()
+
+	// publish until the subscription is made
+	publishUntilReceived := func() {
+		event, err := NewEvent(ClusterHeartbeatEventType, "cluster1", "agent1", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for {
+			if err := publisher.PublishEvent(ctx, event); err != nil {
+				t.Logf("unable to publish: %v", err)
+			}
+			select {
+			case actual := <-received:
+				if actual.ID() != event.ID() || actual.Type() != ClusterHeartbeatEventType || ClusterName(actual) != "cluster1" {
+					t.Errorf("unexpected event %v", actual)
+				}
+				return
+			case <-time.After(100 * time.Millisecond):
+			case <-ctx.Done():
+				t.Fatalf("the event is not received")
+			}
+		}
+	}
+	publishUntilReceived()
+
+	// both of the clients reconnect once the connections are lost
+	broker.disconnectAll()
+	publishUntilReceived()
+}
+
+func TestMQTTConnectionRefused(t *testing.T) {
+	broker := newFakeBroker(t)
+	client := NewMQTTClient(MQTTConfig{BrokerAddress: broker.address(), ClientID: "denied"})
+	err := client.Publish(context.TODO(), "topic", []byte("message"))
+	testinghelpers.AssertError(t, err, "not Authorized")
+
+	client = NewMQTTClient(MQTTConfig{BrokerAddress: "http://" + broker.listener.Addr().String(), ClientID: "cluster1"})
+	err = client.Publish(context.TODO(), "topic", []byte("message"))
+	testinghelpers.AssertError(t, err, "invalid MQTT broker address \"http://"+broker.listener.Addr().String()+"\"")
+}
+
+func TestIsValidBrokerAddress(t *testing.T) {
+	cases := map[string]bool{
+		"tls://broker.example.com:8883":       true,
+		"tcp://127.0.0.1:1883":                true,
+		"broker.example.com:8883":             false,
+		"tls://broker.example.com":            false,
+		"mqtt://broker.example.com:1883":      false,
+		"tls://broker.example.com:8883/topic": false,
+	}
+	for address, expected := range cases {
+		if actual := IsValidBrokerAddress(address); actual != expected {
+			t.Errorf("expected %t for %q, but got %t", expected, address, actual)
+		}
+	}
+}
+
+// readPacket reads a packet, and returns the first byte of its fixed header and the rest of the packet
+func readPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		digit, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+// encodeRemainingLength encodes the length of a packet after its fixed header in the variable length encoding
+func encodeRemainingLength(length int) []byte {
+	encoded := []byte{}
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		encoded = append(encoded, digit)
+		if length == 0 {
+			return encoded
+		}
+	}
+}
+
+// encodeString encodes a string with its length prefixed
+func encodeString(s string) []byte {
+	encoded := make([]byte, 2, 2+len(s))
+	binary.BigEndian.PutUint16(encoded, uint16(len(s)))
+	return append(encoded, s...)
+}
@@ -0,0 +1,48 @@
+package cloudevents
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"path"
+
+	"open-cluster-management.io/registration/pkg/fips"
+)
+
+// NewMQTTTLSConfig returns the tls config of the connections to the broker. The serving certificate of the broker
+// is verified with the CA bundle in caFile, or the system roots if it is empty. The client certificate in certFile
+// and keyFile is presented to the broker if they are set, it is loaded on each handshake, so a rotated client
+// certificate, e.g. the client certificate of the agent in the hub kubeconfig secret, is picked up on reconnection.
+// The config is restricted to the FIPS-approved settings in the FIPS mode.
+func NewMQTTTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(caFile) > 0 {
+		caData, err := ioutil.ReadFile(path.Clean(caFile))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file %q: %w", caFile, err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificate found in CA file %q", caFile)
+		}
+	}
+	if len(certFile) > 0 || len(keyFile) > 0 {
+		// fail fast if the client certificate is not loadable at all
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %w", err)
+		}
+		if err := fips.ValidateKeyPair(cert); err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("unable to load client certificate: %w", err)
+			}
+			return &cert, nil
+		}
+	}
+	return fips.RestrictTLSConfig(tlsConfig), nil
+}
@@ -1,24 +1,116 @@
 package hub
 
 import (
+	"fmt"
+	"io/ioutil"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 
+	"open-cluster-management.io/registration/pkg/diagnostics"
 	"open-cluster-management.io/registration/pkg/features"
 	"open-cluster-management.io/registration/pkg/hub"
+	"open-cluster-management.io/registration/pkg/hub/preflight"
 	"open-cluster-management.io/registration/pkg/version"
 )
 
 func NewController() *cobra.Command {
+	opts := hub.NewHubManagerOptions()
 	cmd := controllercmd.
-		NewControllerCommandConfig("registration-controller", version.Get(), hub.RunControllerManager).
+		NewControllerCommandConfig("registration-controller", version.Get(), opts.RunControllerManager).
 		NewCommand()
 	cmd.Use = "controller"
 	cmd.Short = "Start the Cluster Registration Controller"
 
 	flags := cmd.Flags()
-	features.DefaultHubMutableFeatureGate.AddFlag(flags)
+	opts.AddFlags(flags)
+
+	var validateOnly bool
+	flags.BoolVar(&validateOnly, "validate-only", validateOnly,
+		"Validate the flags, the configuration file, the serving certificate of the registration webhook, and the "+
+			"crds and the rbac on the hub, print a json report and exit without starting the controllers. It exits "+
+			"with an error if any check fails, e.g. in an init container gating an upgrade.")
+	run := cmd.Run
+	cmd.Run = nil
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if !validateOnly && len(opts.InstanceName) == 0 {
+			run(cmd, args)
+			return nil
+		}
+		if !validateOnly {
+			return runInstance(cmd, args, opts)
+		}
+		cmd.SilenceUsage = true
+		return runPreflightChecks(cmd, opts)
+	}
+
+	diagnosticsOpts := hub.NewHubManagerOptions()
+	cmd.AddCommand(diagnostics.NewCommand(features.Hub, diagnosticsOpts.AddFlags, diagnosticsOpts.DiagnosticsSources))
 
 	return cmd
 }
+
+// runInstance runs an instance of the hub controller with its own leader election lease. The lease is named after
+// the component name of the library-go command, so the command is rebuilt with the name of the instance, and the
+// flags set on the command line are copied to it.
+func runInstance(cmd *cobra.Command, args []string, opts *hub.HubManagerOptions) error {
+	instanceCmd := controllercmd.
+		NewControllerCommandConfig(opts.ComponentName(), version.Get(), opts.RunControllerManager).
+		NewCommand()
+
+	var err error
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		instanceFlag := instanceCmd.Flags().Lookup(flag.Name)
+		if instanceFlag == nil || err != nil {
+			return
+		}
+		if value, ok := flag.Value.(pflag.SliceValue); ok {
+			err = instanceFlag.Value.(pflag.SliceValue).Replace(value.GetSlice())
+			return
+		}
+		err = instanceFlag.Value.Set(flag.Value.String())
+	})
+	if err != nil {
+		return fmt.Errorf("unable to run the instance %q: %w", opts.InstanceName, err)
+	}
+	instanceCmd.Run(instanceCmd, args)
+	return nil
+}
+
+// runPreflightChecks runs the checks of the hub controller with the flags of library-go, and prints the report
+func runPreflightChecks(cmd *cobra.Command, opts *hub.HubManagerOptions) error {
+	flagValue := func(name string) string {
+		if flag := cmd.Flags().Lookup(name); flag != nil {
+			return flag.Value.String()
+		}
+		return ""
+	}
+
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", flagValue("kubeconfig"))
+	if err != nil {
+		return fmt.Errorf("unable to load kubeconfig: %w", err)
+	}
+	// the namespace is detected as library-go does if the hub controller runs in a pod
+	namespace := flagValue("namespace")
+	if len(namespace) == 0 {
+		if data, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+			namespace = string(data)
+		}
+	}
+
+	checks, err := opts.PreflightChecks(kubeConfig, flagValue("config"), namespace)
+	if err != nil {
+		return err
+	}
+	failed, err := preflight.PrintReport(cmd.OutOrStdout(), preflight.Run(cmd.Context(), checks))
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 
+	"open-cluster-management.io/registration/pkg/diagnostics"
+	"open-cluster-management.io/registration/pkg/features"
 	"open-cluster-management.io/registration/pkg/spoke"
 	"open-cluster-management.io/registration/pkg/version"
 )
@@ -22,5 +24,13 @@ func NewAgent() *cobra.Command {
 	agentOptions.AddFlags(flags)
 
 	flags.BoolVar(&cmdConfig.DisableLeaderElection, "disable-leader-election", false, "Disable leader election for the agent.")
+	// the agent reloads its configuration file instead of being restarted by library-go once the file is changed
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		return agentOptions.TakeOverConfigFile(cmd.Flags())
+	}
+
+	diagnosticsOptions := spoke.NewSpokeAgentOptions()
+	cmd.AddCommand(diagnostics.NewCommand(features.Spoke, diagnosticsOptions.AddFlags, diagnosticsOptions.DiagnosticsSources))
+	cmd.AddCommand(newCheckCommand())
 	return cmd
 }
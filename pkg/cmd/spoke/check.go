@@ -0,0 +1,52 @@
+package spoke
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"open-cluster-management.io/registration/pkg/spoke"
+	"open-cluster-management.io/registration/pkg/spoke/selfcheck"
+)
+
+// newCheckCommand returns the subcommand which verifies the prerequisites of the agent before it is deployed
+func newCheckCommand() *cobra.Command {
+	agentOptions := spoke.NewSpokeAgentOptions()
+	var kubeconfig string
+	maxClockSkew := selfcheck.DefaultMaxClockSkew
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Verify the prerequisites of the Cluster Registration Agent",
+		Long: "Verify the bootstrap kubeconfig, the reachability of the hub, the rbac of the agent on the hub and " +
+			"the managed cluster, the writability of the hub kubeconfig secret and the clock skew to the hub, and " +
+			"print a pass/fail report. It exits with an error if any check fails.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+			if err != nil {
+				return fmt.Errorf("unable to load kubeconfig: %w", err)
+			}
+			checker, err := agentOptions.SelfChecker(kubeConfig, maxClockSkew)
+			if err != nil {
+				return err
+			}
+			if failed := selfcheck.PrintReport(cmd.OutOrStdout(), checker.Run(cmd.Context())); failed > 0 {
+				return fmt.Errorf("%d checks failed", failed)
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	agentOptions.AddFlags(flags)
+	flags.StringVar(&kubeconfig, "kubeconfig", kubeconfig,
+		"The kubeconfig of the cluster the agent runs on, the in-cluster config is used if it is empty.")
+	flags.StringVar(&agentOptions.ComponentNamespace, "component-namespace", agentOptions.ComponentNamespace,
+		"The namespace the agent runs in, it is detected if the check runs in a pod, otherwise it defaults to "+
+			"open-cluster-management-agent.")
+	flags.DurationVar(&maxClockSkew, "max-clock-skew", maxClockSkew,
+		"The max clock skew allowed between the managed cluster and the hub.")
+	return cmd
+}
@@ -0,0 +1,116 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// Path is the path of the endpoint which dumps the internal state of the controllers
+const Path = "/debug/registration"
+
+// workQueueDepthMetric is the metric of the workqueue package which reports the length of the controller queues
+const workQueueDepthMetric = "workqueue_depth"
+
+var (
+	stateLock sync.RWMutex
+	states    = map[string]interface{}{}
+)
+
+// RecordState records the latest state of a controller, which replaces the state recorded before. The state is
+// encoded to json when it is dumped, so it must be a snapshot which is not modified after it is recorded, and it
+// must not contain any sensitive data, e.g. private keys.
+func RecordState(controller string, state interface{}) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+	states[controller] = state
+}
+
+// ForgetState removes the state of a controller, it is called once the controller is stopped.
+func ForgetState(controller string) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+	delete(states, controller)
+}
+
+// Dump is the content of the debug endpoint
+type Dump struct {
+	// Controllers is the latest state recorded by each controller
+	Controllers map[string]interface{} `json:"controllers"`
+	// QueueLengths is the number of the keys waiting in the queue of each controller
+	QueueLengths map[string]float64 `json:"queueLengths"`
+}
+
+// NewDump returns a dump of the recorded controller states and the queue lengths. The queue lengths are read from
+// the given metrics gatherer, it defaults to the legacy registry the workqueue metrics are registered in.
+func NewDump(gatherer metrics.Gatherer) (*Dump, error) {
+	if gatherer == nil {
+		gatherer = legacyregistry.DefaultGatherer
+	}
+
+	dump := &Dump{
+		Controllers:  map[string]interface{}{},
+		QueueLengths: map[string]float64{},
+	}
+
+	stateLock.RLock()
+	for name, state := range states {
+		dump.Controllers[name] = state
+	}
+	stateLock.RUnlock()
+
+	families, err := gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+	for _, family := range families {
+		if family.GetName() != workQueueDepthMetric {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" {
+					dump.QueueLengths[label.GetValue()] = metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return dump, nil
+}
+
+// Handler serves the dump of the controller states in json
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		dump, err := NewDump(nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data, err := json.MarshalIndent(dump, "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+}
+
+// Serve serves the debug endpoint on the given address until the context is done. The endpoint is not protected
+// by any authentication, so it should be bound to a loopback address, e.g. "127.0.0.1:8000", and be reached with
+// port forwarding.
+func Serve(ctx context.Context, address string) error {
+	mux := http.NewServeMux()
+	mux.Handle(Path, Handler())
+	return helpers.ServeHTTP(ctx, "debug", address, mux)
+}
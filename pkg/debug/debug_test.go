@@ -0,0 +1,87 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"k8s.io/component-base/metrics"
+)
+
+func TestNewDump(t *testing.T) {
+	registry := metrics.NewKubeRegistry()
+	depth := metrics.NewGaugeVec(&metrics.GaugeOpts{Name: workQueueDepthMetric}, []string{"name"})
+	registry.MustRegister(depth)
+	depth.WithLabelValues("CSRApprovingController").Set(3)
+	depth.WithLabelValues("ManagedClusterLeaseController").Set(0)
+
+	RecordState("controller1", map[string]string{"csrName": "csr1"})
+	RecordState("controller2", "stale")
+	RecordState("controller2", "latest")
+	RecordState("controller3", "stopped")
+	ForgetState("controller3")
+
+	dump, err := NewDump(registry)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedControllers := map[string]interface{}{
+		"controller1": map[string]string{"csrName": "csr1"},
+		"controller2": "latest",
+	}
+	if !reflect.DeepEqual(dump.Controllers, expectedControllers) {
+		t.Errorf("expected controllers %v, but got %v", expectedControllers, dump.Controllers)
+	}
+	expectedQueueLengths := map[string]float64{
+		"CSRApprovingController":        3,
+		"ManagedClusterLeaseController": 0,
+	}
+	if !reflect.DeepEqual(dump.QueueLengths, expectedQueueLengths) {
+		t.Errorf("expected queue lengths %v, but got %v", expectedQueueLengths, dump.QueueLengths)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	RecordState("controller1", map[string]string{"csrName": "csr1"})
+
+	cases := []struct {
+		name         string
+		method       string
+		expectedCode int
+	}{
+		{
+			name:         "get",
+			method:       http.MethodGet,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "post",
+			method:       http.MethodPost,
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			Handler().ServeHTTP(recorder, httptest.NewRequest(c.method, Path, nil))
+			if recorder.Code != c.expectedCode {
+				t.Fatalf("expected code %d, but got %d", c.expectedCode, recorder.Code)
+			}
+			if recorder.Code != http.StatusOK {
+				return
+			}
+
+			dump := &Dump{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), dump); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := dump.Controllers["controller1"]; !ok {
+				t.Errorf("expected the state of controller1 in %s", recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+// package debug serves the internal state of the registration controllers to speed up troubleshooting. The
+// controllers record snapshots of their state with RecordState, and the hub controller and the agent serve them
+// together with the queue lengths of the controllers on the /debug/registration endpoint when the
+// "--debug-bind-address" flag is set, for example
+//
+//	kubectl -n open-cluster-management-agent port-forward deploy/klusterlet-registration-agent 8000
+//	curl http://127.0.0.1:8000/debug/registration
+package debug
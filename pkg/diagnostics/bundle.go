@@ -0,0 +1,154 @@
+package diagnostics
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// errorsFile is the file of the bundle listing the errors of the sources
+const errorsFile = "errors.txt"
+
+// Source collects a part of the diagnostics bundle. A failed source does not fail the collection, its error is
+// recorded in the bundle instead, so a bundle is still collected from a partially broken component.
+type Source func(ctx context.Context, bundle *Bundle) error
+
+// Bundle writes the files of a diagnostics bundle into a gzipped tarball
+type Bundle struct {
+	root string
+	now  time.Time
+	gzip *gzip.Writer
+	tar  *tar.Writer
+}
+
+// NewBundle returns a Bundle writing to w, the files are put in the root directory of the tarball
+func NewBundle(w io.Writer, root string, now time.Time) *Bundle {
+	gzipWriter := gzip.NewWriter(w)
+	return &Bundle{
+		root: root,
+		now:  now,
+		gzip: gzipWriter,
+		tar:  tar.NewWriter(gzipWriter),
+	}
+}
+
+// AddFile adds a file to the bundle, the PEM encoded private keys in the data are redacted.
+func (b *Bundle) AddFile(name string, data []byte) error {
+	data = RedactKeyMaterial(data)
+	header := &tar.Header{
+		Name:    path.Join(b.root, name),
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: b.now,
+	}
+	if err := b.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := b.tar.Write(data)
+	return err
+}
+
+// AddYAML adds a value encoded in yaml to the bundle
+func (b *Bundle) AddYAML(name string, value interface{}) error {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("unable to encode %s: %w", name, err)
+	}
+	return b.AddFile(name, data)
+}
+
+// AddObject adds a kube object or a list of objects encoded in yaml to the bundle. The managed fields are dropped
+// to keep the bundle readable, and the secrets are redacted with RedactSecret.
+func (b *Bundle) AddObject(name string, obj runtime.Object) error {
+	obj = obj.DeepCopyObject()
+	if meta.IsListType(obj) {
+		items, err := meta.ExtractList(obj)
+		if err != nil {
+			return fmt.Errorf("unable to extract %s: %w", name, err)
+		}
+		for i := range items {
+			items[i] = sanitizeObject(items[i])
+		}
+		if err := meta.SetList(obj, items); err != nil {
+			return fmt.Errorf("unable to sanitize %s: %w", name, err)
+		}
+	} else {
+		obj = sanitizeObject(obj)
+	}
+	return b.AddYAML(name, obj)
+}
+
+// Close flushes the bundle, it must be called once all of the files are added.
+func (b *Bundle) Close() error {
+	if err := b.tar.Close(); err != nil {
+		return err
+	}
+	return b.gzip.Close()
+}
+
+func sanitizeObject(obj runtime.Object) runtime.Object {
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return RedactObject(obj)
+}
+
+// Collect collects a diagnostics bundle from the sources into w. The errors of the sources are written to the
+// errors.txt of the bundle, an error is only returned if the bundle is not able to be written.
+func Collect(ctx context.Context, w io.Writer, root string, sources ...Source) error {
+	bundle := NewBundle(w, root, time.Now())
+	errs := []string{}
+	for _, source := range sources {
+		if err := source(ctx, bundle); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		if err := bundle.AddFile(errorsFile, []byte(strings.Join(errs, "\n")+"\n")); err != nil {
+			return err
+		}
+	}
+	return bundle.Close()
+}
+
+// ConditionRecord is a condition of an object in the recent conditions of a bundle
+type ConditionRecord struct {
+	Object           string `json:"object"`
+	metav1.Condition `json:",inline"`
+}
+
+// RecentConditions returns at most limit conditions of the objects, the latest transitions come first. The
+// conditions are keyed by the names of the objects they belong to.
+func RecentConditions(conditions map[string][]metav1.Condition, limit int) []ConditionRecord {
+	records := []ConditionRecord{}
+	for object, objectConditions := range conditions {
+		for _, condition := range objectConditions {
+			records = append(records, ConditionRecord{Object: object, Condition: condition})
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		ti, tj := records[i].LastTransitionTime, records[j].LastTransitionTime
+		if !ti.Equal(&tj) {
+			return tj.Before(&ti)
+		}
+		if records[i].Object != records[j].Object {
+			return records[i].Object < records[j].Object
+		}
+		return records[i].Type < records[j].Type
+	})
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records
+}
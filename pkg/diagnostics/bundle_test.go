@@ -0,0 +1,149 @@
+package diagnostics
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+// readBundle returns the files of a bundle keyed by their names
+func readBundle(t *testing.T, data []byte) map[string]string {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+	files := map[string]string{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		content, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		files[header.Name] = string(content)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	cert := testinghelpers.NewTestCert("test", 60*time.Second)
+	secret := testinghelpers.NewHubKubeconfigSecret("ns", "secret", "1", cert, map[string][]byte{})
+	secret.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "agent"}}
+
+	cases := []struct {
+		name          string
+		sources       []Source
+		expectedFiles []string
+		validate      func(t *testing.T, files map[string]string)
+	}{
+		{
+			name: "objects",
+			sources: []Source{
+				func(ctx context.Context, bundle *Bundle) error {
+					return bundle.AddObject("secrets.yaml", &corev1.SecretList{Items: []corev1.Secret{*secret}})
+				},
+				func(ctx context.Context, bundle *Bundle) error {
+					return bundle.AddObject("secret.yaml", secret)
+				},
+			},
+			expectedFiles: []string{"bundle/secret.yaml", "bundle/secrets.yaml"},
+			validate: func(t *testing.T, files map[string]string) {
+				for name, content := range files {
+					if strings.Contains(content, "managedFields") {
+						t.Errorf("expected the managed fields to be dropped from %s", name)
+					}
+					if !strings.Contains(content, "tls.key: UkVEQUNURUQ=") {
+						t.Errorf("expected the key to be redacted in %s, but got %s", name, content)
+					}
+				}
+			},
+		},
+		{
+			name: "failed sources",
+			sources: []Source{
+				func(ctx context.Context, bundle *Bundle) error {
+					return fmt.Errorf("source1 failed")
+				},
+				func(ctx context.Context, bundle *Bundle) error {
+					return bundle.AddFile("key.pem", cert.Key)
+				},
+				func(ctx context.Context, bundle *Bundle) error {
+					return fmt.Errorf("source3 failed")
+				},
+			},
+			expectedFiles: []string{"bundle/errors.txt", "bundle/key.pem"},
+			validate: func(t *testing.T, files map[string]string) {
+				if files["bundle/errors.txt"] != "source1 failed\nsource3 failed\n" {
+					t.Errorf("unexpected errors %q", files["bundle/errors.txt"])
+				}
+				if files["bundle/key.pem"] != Redacted+"\n" {
+					t.Errorf("expected the key to be redacted, but got %q", files["bundle/key.pem"])
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			output := &bytes.Buffer{}
+			if err := Collect(context.TODO(), output, "bundle", c.sources...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			files := readBundle(t, output.Bytes())
+			names := []string{}
+			for name := range files {
+				names = append(names, name)
+			}
+			if len(names) != len(c.expectedFiles) {
+				t.Fatalf("expected files %v, but got %v", c.expectedFiles, names)
+			}
+			for _, name := range c.expectedFiles {
+				if _, ok := files[name]; !ok {
+					t.Errorf("expected file %s in %v", name, names)
+				}
+			}
+			c.validate(t, files)
+		})
+	}
+}
+
+func TestRecentConditions(t *testing.T) {
+	now := time.Now()
+	conditions := map[string][]metav1.Condition{
+		"managedcluster/cluster1": {
+			{Type: "Available", LastTransitionTime: metav1.NewTime(now.Add(-3 * time.Minute))},
+			{Type: "Joined", LastTransitionTime: metav1.NewTime(now.Add(-1 * time.Minute))},
+		},
+		"managedcluster/cluster2": {
+			{Type: "Available", LastTransitionTime: metav1.NewTime(now.Add(-2 * time.Minute))},
+		},
+	}
+
+	records := RecentConditions(conditions, 2)
+	actual := []string{}
+	for _, record := range records {
+		actual = append(actual, record.Object+"/"+record.Type)
+	}
+	expected := []string{"managedcluster/cluster1/Joined", "managedcluster/cluster2/Available"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, but got %v", expected, actual)
+	}
+}
@@ -0,0 +1,83 @@
+package diagnostics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/features"
+)
+
+// SourcesFunc returns the sources of the objects of a component, which are read with the kubeconfig
+type SourcesFunc func(kubeConfig *rest.Config) ([]Source, error)
+
+// NewCommand returns the diagnostics subcommand of a component. The flags of the component are added by addFlags,
+// so the subcommand accepts the same flags as the component, and the objects are collected by the sources
+// returned by sourcesFunc.
+func NewCommand(component features.Component, addFlags func(fs *pflag.FlagSet), sourcesFunc SourcesFunc) *cobra.Command {
+	var kubeconfig, output, debugURL string
+	cmd := &cobra.Command{
+		Use:   "diagnostics",
+		Short: fmt.Sprintf("Collect a diagnostics bundle of the %s for support", component),
+		Long: "Collect a diagnostics bundle for support, which includes the sanitized options, the feature gates, " +
+			"the controller states, the recent conditions and the relevant objects. The key material is redacted.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+			if err != nil {
+				return fmt.Errorf("unable to load kubeconfig: %w", err)
+			}
+			sources, err := sourcesFunc(kubeConfig)
+			if err != nil {
+				return err
+			}
+			sources = append([]Source{
+				VersionSource(),
+				OptionsSource(cmd.Flags()),
+				FeatureGatesSource(component),
+				ControllerStatesSource(&http.Client{Timeout: 30 * time.Second}, debugURL),
+			}, sources...)
+
+			root := fmt.Sprintf("registration-%s-diagnostics-%s", component, time.Now().UTC().Format("20060102-150405"))
+			if len(output) == 0 {
+				output = root + ".tar.gz"
+			}
+			var w io.Writer = os.Stdout
+			if output != "-" {
+				file, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				w = file
+			}
+
+			if err := Collect(cmd.Context(), w, root, sources...); err != nil {
+				return fmt.Errorf("unable to write the diagnostics bundle: %w", err)
+			}
+			if output != "-" {
+				klog.Infof("The diagnostics bundle is written to %s", output)
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	addFlags(flags)
+	flags.StringVar(&kubeconfig, "kubeconfig", kubeconfig,
+		"The kubeconfig of the cluster the objects are collected from, the in-cluster config is used if it is empty.")
+	flags.StringVar(&output, "output", output,
+		"The file the bundle is written to, it defaults to a timestamped file in the current directory. "+
+			"The bundle is written to stdout if it is \"-\".")
+	flags.StringVar(&debugURL, "debug-url", debugURL,
+		"The url of the debug endpoint of the running "+string(component)+" to collect the controller states from, "+
+			"e.g. http://127.0.0.1:8000/debug/registration. The controller states are not collected if it is empty.")
+	return cmd
+}
@@ -0,0 +1,13 @@
+// package diagnostics collects a diagnostics bundle of the hub controller or the agent for support. The bundle is
+// a gzipped tarball of the sanitized options, the feature gates, the controller states served on the debug
+// endpoint, the recent conditions and the relevant objects. It is collected with the "diagnostics" subcommand of
+// the component, which accepts the same options as the component, for example
+//
+//	kubectl -n open-cluster-management-agent exec deploy/klusterlet-registration-agent -- \
+//		registration agent diagnostics --cluster-name=cluster1 --debug-url=http://127.0.0.1:8000/debug/registration \
+//		--output=- > diagnostics.tar.gz
+//
+// The key material is redacted before it is written to the bundle: the secrets only keep their certificates and
+// identities, the kubeconfigs lose their client keys, tokens and passwords, and any PEM encoded private key is
+// replaced.
+package diagnostics
@@ -0,0 +1,116 @@
+package diagnostics
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+)
+
+// Redacted replaces the sensitive values in a bundle
+const Redacted = "REDACTED"
+
+// privateKeyPattern matches the PEM encoded private keys, e.g. "RSA PRIVATE KEY" and "EC PRIVATE KEY"
+var privateKeyPattern = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)
+
+// sensitiveFlagKeywords are the keywords of the names of the flags whose values are redacted
+var sensitiveFlagKeywords = []string{"token", "password"}
+
+// preservedSecretKeys are the keys of a secret whose values are not sensitive, e.g. the certificates and the
+// identity of the agent in the hub kubeconfig secret.
+var preservedSecretKeys = map[string]bool{
+	clientcert.TLSCertFile:     true,
+	clientcert.ClusterNameFile: true,
+	clientcert.AgentNameFile:   true,
+	"ca.crt":                   true,
+}
+
+// RedactKeyMaterial replaces the PEM encoded private keys in the data
+func RedactKeyMaterial(data []byte) []byte {
+	return privateKeyPattern.ReplaceAll(data, []byte(Redacted))
+}
+
+// RedactKubeconfig removes the client keys, tokens and passwords from a kubeconfig. The kubeconfig is replaced
+// entirely if it is not able to be parsed.
+func RedactKubeconfig(data []byte) []byte {
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return []byte(Redacted)
+	}
+	for _, authInfo := range config.AuthInfos {
+		if len(authInfo.ClientKeyData) > 0 {
+			authInfo.ClientKeyData = []byte(Redacted)
+		}
+		if len(authInfo.Token) > 0 {
+			authInfo.Token = Redacted
+		}
+		if len(authInfo.Password) > 0 {
+			authInfo.Password = Redacted
+		}
+		authInfo.AuthProvider = nil
+		authInfo.Exec = nil
+	}
+	redacted, err := clientcmd.Write(*config)
+	if err != nil {
+		return []byte(Redacted)
+	}
+	return redacted
+}
+
+// RedactSecret returns a copy of a secret which only keeps the certificates and the identity of the agent, the
+// kubeconfig is kept without its credentials and the other values are redacted.
+func RedactSecret(secret *corev1.Secret) *corev1.Secret {
+	secret = secret.DeepCopy()
+	for key, value := range secret.Data {
+		switch {
+		case key == clientcert.KubeconfigFile:
+			secret.Data[key] = RedactKubeconfig(value)
+		case preservedSecretKeys[key]:
+			secret.Data[key] = RedactKeyMaterial(value)
+		default:
+			secret.Data[key] = []byte(Redacted)
+		}
+	}
+	for key := range secret.StringData {
+		secret.StringData[key] = Redacted
+	}
+	// the last applied configuration annotation may contain the data of the secret
+	delete(secret.Annotations, corev1.LastAppliedConfigAnnotation)
+	return secret
+}
+
+// RedactObject redacts the secrets, the other objects are returned as is
+func RedactObject(obj runtime.Object) runtime.Object {
+	switch o := obj.(type) {
+	case *corev1.Secret:
+		return RedactSecret(o)
+	case *corev1.SecretList:
+		list := o.DeepCopy()
+		for i := range list.Items {
+			list.Items[i] = *RedactSecret(&list.Items[i])
+		}
+		return list
+	}
+	return obj
+}
+
+// RedactFlags returns the values of the flags keyed by their names, the values of the flags named after tokens or
+// passwords are redacted.
+func RedactFlags(fs *pflag.FlagSet) map[string]string {
+	flags := map[string]string{}
+	fs.VisitAll(func(flag *pflag.Flag) {
+		value := flag.Value.String()
+		for _, keyword := range sensitiveFlagKeywords {
+			if strings.Contains(strings.ToLower(flag.Name), keyword) && len(value) > 0 {
+				value = Redacted
+			}
+		}
+		flags[flag.Name] = string(RedactKeyMaterial([]byte(value)))
+	})
+	return flags
+}
@@ -0,0 +1,69 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/spf13/pflag"
+
+	"open-cluster-management.io/registration/pkg/features"
+	"open-cluster-management.io/registration/pkg/version"
+)
+
+// DefaultConditionLimit is the number of the recent conditions written to a bundle
+const DefaultConditionLimit = 100
+
+// VersionSource adds the version of the binary to the bundle
+func VersionSource() Source {
+	return func(ctx context.Context, bundle *Bundle) error {
+		return bundle.AddYAML("version.yaml", version.Get())
+	}
+}
+
+// OptionsSource adds the sanitized values of the flags to the bundle, see RedactFlags
+func OptionsSource(fs *pflag.FlagSet) Source {
+	return func(ctx context.Context, bundle *Bundle) error {
+		return bundle.AddYAML("options.yaml", RedactFlags(fs))
+	}
+}
+
+// FeatureGatesSource adds the states of the feature gates of a component to the bundle
+func FeatureGatesSource(component features.Component) Source {
+	return func(ctx context.Context, bundle *Bundle) error {
+		return bundle.AddYAML("feature-gates.yaml", features.FeatureGateStates(component))
+	}
+}
+
+// ControllerStatesSource adds the controller states served on the debug endpoint of a running component to the
+// bundle. It does nothing if the url is empty.
+func ControllerStatesSource(client *http.Client, url string) Source {
+	return func(ctx context.Context, bundle *Bundle) error {
+		if len(url) == 0 {
+			return nil
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("unable to get the controller states from %q: %w", url, err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("unable to get the controller states from %q: %w", url, err)
+		}
+		defer resp.Body.Close()
+
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("unable to read the controller states from %q: %w", url, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unable to get the controller states from %q: %s", url, resp.Status)
+		}
+		if indented, err := json.MarshalIndent(json.RawMessage(data), "", "  "); err == nil {
+			data = indented
+		}
+		return bundle.AddFile("controller-states.json", data)
+	}
+}
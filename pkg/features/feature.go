@@ -2,7 +2,6 @@ package features
 
 import (
 	"k8s.io/apimachinery/pkg/util/runtime"
-	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/component-base/featuregate"
 )
 
@@ -41,6 +40,92 @@ const (
 	// means that all the approved CSR objects will be signed by the built-in CSR controller in
 	// kube-controller-manager.
 	V1beta1CSRAPICompatibility featuregate.Feature = "V1beta1CSRAPICompatibility"
+
+	// AggregatedAddOnHeartbeat will make the spoke registration agent to summarize the lease status of all
+	// addons into an annotation of the managed cluster lease on the hub, instead of updating the status of
+	// each managed cluster addon. The registration hub controller fans out the summary to the available
+	// condition of each managed cluster addon whenever an agent writes it, so this feature is only a feature
+	// of the agent. It is deprecated on the hub controller and has no effect there.
+	AggregatedAddOnHeartbeat featuregate.Feature = "AggregatedAddOnHeartbeat"
+
+	// WebhookServingCertRotation will make the registration hub controller to issue the serving certificate of
+	// the registration webhook with csrs signed by an in-tree signer, rotate it before it expires and inject the
+	// CA bundle into the webhook apiservice and configurations. The webhook deployment should mount the secret
+	// "managedcluster-admission-serving-cert" and serve it with "--tls-cert-file" and "--tls-private-key-file"
+	// when this feature is enabled.
+	WebhookServingCertRotation featuregate.Feature = "WebhookServingCertRotation"
+
+	// TokenRegistration will make the registration hub controller to provision a service account in the namespace
+	// of each accepted managed cluster annotated with the token registration driver, and rotate its token in the
+	// secret "registration-agent-token", for the hubs which do not accept client certificates. The agents of these
+	// clusters run with "--registration-driver=token" and authenticate to the hub with the tokens.
+	TokenRegistration featuregate.Feature = "TokenRegistration"
+
+	// AWSIAMRegistration will make the registration hub controller to map the IAM role of each accepted managed
+	// cluster annotated with the aws iam registration driver to its agent in the aws-auth configmap, for the EKS
+	// hubs. The agents of these clusters run with "--registration-driver=awsiam" and authenticate to the hub with
+	// the IAM roles.
+	AWSIAMRegistration featuregate.Feature = "AWSIAMRegistration"
+
+	// GRPCRegistration will make the spoke registration agent to register the cluster, heartbeat and report the
+	// status of the cluster over the gRPC Registration service at "--grpc-server-address" instead of the hub
+	// kube-apiserver when it runs with "--registration-transport=grpc", for the hubs which are not Kubernetes
+	// clusters. The controllers depending on the hub kube-apiserver, e.g. the addon registration, are not started.
+	GRPCRegistration featuregate.Feature = "GRPCRegistration"
+
+	// CloudEventsTransport will make the spoke registration agent to publish the status and the heartbeats of the
+	// cluster as CloudEvents to the MQTT broker at "--cloudevents-broker-address" instead of updating them on the
+	// hub kube-apiserver, and the registration hub controller to consume them from the broker at the same flag and
+	// reconcile them into the ManagedClusters and their leases, for the edge fleets behind unreliable links.
+	CloudEventsTransport featuregate.Feature = "CloudEventsTransport"
+
+	// ClusterProfile will make the registration hub controller to maintain a ClusterProfile of the SIG-Multicluster
+	// cluster inventory API for each managed cluster in the namespace "--cluster-profile-namespace", and to import
+	// the ClusterProfiles of the other cluster managers as managed clusters with "--import-cluster-profiles". The
+	// CRD of the ClusterProfiles should be installed on the hub when this feature is enabled.
+	ClusterProfile featuregate.Feature = "ClusterProfile"
+
+	// ClusterProperty will make the spoke registration agent to import the ClusterProperties of the About API
+	// (about.k8s.io) on the managed cluster as cluster claims, e.g. "cluster.clusterset.k8s.io" as the claim
+	// "id.k8s.io", and to write the other cluster claims back as ClusterProperties with
+	// "--write-back-cluster-properties". The CRD of the ClusterProperties should be installed on the managed cluster
+	// when this feature is enabled.
+	ClusterProperty featuregate.Feature = "ClusterProperty"
+
+	// ClaimsOnlyRegistration will make the spoke registration agent to register an endpoint which is not a
+	// Kubernetes cluster, e.g. an edge gateway or a VM, when it runs with "--claims-only". The agent has no spoke
+	// kubeconfig, and reports only the claims in "--claims" and the heartbeats of the endpoint.
+	ClaimsOnlyRegistration featuregate.Feature = "ClaimsOnlyRegistration"
+
+	// ClusterAPIImport will make the registration hub controller to create an accepted managed cluster for each
+	// Cluster of Cluster API (cluster.x-k8s.io) on the hub, to keep the phase of the Cluster in a label of the
+	// managed cluster, and to delete the managed cluster with the Cluster. The CRD of the Clusters should be
+	// installed on the hub when this feature is enabled.
+	ClusterAPIImport featuregate.Feature = "ClusterAPIImport"
+
+	// ReverseTunnelBootstrap will make the spoke registration agent to request the client certificate of the
+	// reverse tunnel agent, e.g. the proxy agent of cluster-proxy, with the signer "--reverse-tunnel-signer-name"
+	// when it runs with "--bootstrap-reverse-tunnel", and the registration hub controller to approve these csrs of
+	// the accepted managed clusters, so the hub reaches the managed clusters once they join without waiting for an
+	// addon to be installed. The certificates are signed by the signer of the reverse tunnel, not the hub.
+	ReverseTunnelBootstrap featuregate.Feature = "ReverseTunnelBootstrap"
+
+	// InventoryExport will make the registration hub controller to export the inventory of the managed clusters,
+	// their names, clustersets, claims and availability, to the sink at "--inventory-export-sink" every
+	// "--inventory-export-interval", e.g. an S3 object, an HTTP endpoint or a Kafka topic, for the external CMDBs.
+	InventoryExport featuregate.Feature = "InventoryExport"
+
+	// HubCARotation will make the registration hub controller to publish the hub CA bundle configmap in the namespace
+	// of the hub controller to the managed clusters, including the new CA while it is rotated, and to retire the old
+	// CA once all accepted clusters trust the new one, and the spoke registration agent to update the CA of its hub
+	// kubeconfig with the published bundle after the hub is verified with it.
+	HubCARotation featuregate.Feature = "HubCARotation"
+
+	// AgentConfigPropagation will make the registration hub controller to publish the agent settings, e.g. the log
+	// level and the feature gates, in the configmap "registration-agent-config" in the namespace of the hub
+	// controller, overridden with the annotations of each managed cluster, to the managed cluster namespaces, and
+	// the spoke registration agent to apply the settings published to its cluster while it runs.
+	AgentConfigPropagation featuregate.Feature = "AgentConfigPropagation"
 )
 
 var (
@@ -52,9 +137,10 @@ var (
 )
 
 func init() {
-	runtime.Must(DefaultSpokeMutableFeatureGate.Add(defaultSpokeRegistrationFeatureGates))
-	runtime.Must(DefaultHubMutableFeatureGate.Add(defaultHubRegistrationFeatureGates))
-	runtime.Must(utilfeature.DefaultMutableFeatureGate.Add(defaultHubRegistrationFeatureGates))
+	runtime.Must(RegisterFeatureGates(Spoke, defaultSpokeRegistrationFeatureGates))
+	runtime.Must(RegisterFeatureGates(Hub, defaultHubRegistrationFeatureGates))
+	runtime.Must(RegisterFeatureGates(Webhook, defaultWebhookRegistrationFeatureGates))
+	runtime.Must(RegisterFeatureGates(Webhook, deprecatedWebhookRegistrationFeatureGates()))
 }
 
 // defaultSpokeRegistrationFeatureGates consists of all known ocm-registration
@@ -64,11 +150,50 @@ var defaultSpokeRegistrationFeatureGates = map[featuregate.Feature]featuregate.F
 	ClusterClaim:               {Default: true, PreRelease: featuregate.Beta},
 	AddonManagement:            {Default: false, PreRelease: featuregate.Alpha},
 	V1beta1CSRAPICompatibility: {Default: false, PreRelease: featuregate.Alpha},
+	AggregatedAddOnHeartbeat:   {Default: false, PreRelease: featuregate.Alpha},
+	GRPCRegistration:           {Default: false, PreRelease: featuregate.Alpha},
+	CloudEventsTransport:       {Default: false, PreRelease: featuregate.Alpha},
+	ClusterProperty:            {Default: false, PreRelease: featuregate.Alpha},
+	ClaimsOnlyRegistration:     {Default: false, PreRelease: featuregate.Alpha},
+	ReverseTunnelBootstrap:     {Default: false, PreRelease: featuregate.Alpha},
+	HubCARotation:              {Default: false, PreRelease: featuregate.Alpha},
+	AgentConfigPropagation:     {Default: false, PreRelease: featuregate.Alpha},
+}
+
+// defaultWebhookRegistrationFeatureGates consists of all known ocm-registration feature keys for registration
+// webhook server. They are added into the feature gate of the generic apiserver which the webhook server is built on.
+var defaultWebhookRegistrationFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
+	DefaultClusterSet: {Default: false, PreRelease: featuregate.Alpha},
+}
+
+// deprecatedWebhookRegistrationFeatureGates returns the hub features which are not used by the webhook server.
+// The webhook server accepted all of the hub features before it was separated from the hub controller, so they
+// are still accepted as deprecated features, which are no-ops and log a deprecation warning once they are set.
+func deprecatedWebhookRegistrationFeatureGates() map[featuregate.Feature]featuregate.FeatureSpec {
+	features := map[featuregate.Feature]featuregate.FeatureSpec{}
+	for name := range defaultHubRegistrationFeatureGates {
+		if _, ok := defaultWebhookRegistrationFeatureGates[name]; ok {
+			continue
+		}
+		features[name] = featuregate.FeatureSpec{Default: false, PreRelease: featuregate.Deprecated}
+	}
+	return features
 }
 
 // defaultHubRegistrationFeatureGates consists of all known ocm-registration
 // feature keys for registration hub controller.  To add a new feature, define a key for it above and
 // add it here.
 var defaultHubRegistrationFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
-	DefaultClusterSet: {Default: false, PreRelease: featuregate.Alpha},
+	DefaultClusterSet:          {Default: false, PreRelease: featuregate.Alpha},
+	AggregatedAddOnHeartbeat:   {Default: false, PreRelease: featuregate.Deprecated},
+	WebhookServingCertRotation: {Default: false, PreRelease: featuregate.Alpha},
+	TokenRegistration:          {Default: false, PreRelease: featuregate.Alpha},
+	AWSIAMRegistration:         {Default: false, PreRelease: featuregate.Alpha},
+	CloudEventsTransport:       {Default: false, PreRelease: featuregate.Alpha},
+	ClusterProfile:             {Default: false, PreRelease: featuregate.Alpha},
+	ClusterAPIImport:           {Default: false, PreRelease: featuregate.Alpha},
+	ReverseTunnelBootstrap:     {Default: false, PreRelease: featuregate.Alpha},
+	InventoryExport:            {Default: false, PreRelease: featuregate.Alpha},
+	HubCARotation:              {Default: false, PreRelease: featuregate.Alpha},
+	AgentConfigPropagation:     {Default: false, PreRelease: featuregate.Alpha},
 }
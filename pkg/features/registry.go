@@ -0,0 +1,129 @@
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/component-base/featuregate"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// Component is a registration component whose features are gated
+type Component string
+
+const (
+	// Hub is the registration hub controller
+	Hub Component = "hub"
+	// Spoke is the registration agent
+	Spoke Component = "spoke"
+	// Webhook is the registration webhook server
+	Webhook Component = "webhook"
+)
+
+var (
+	registryLock sync.Mutex
+	// registeredFeatureGates records the specs of the features registered by the registration and the downstream
+	// builds for each component. The feature gate of the webhook server also knows the features of the generic
+	// apiserver, which are not recorded here.
+	registeredFeatureGates = map[Component]map[featuregate.Feature]featuregate.FeatureSpec{}
+
+	featureEnabled = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name: "open_cluster_management_registration_feature_enabled",
+			Help: "A metric with a value of '1' if the feature is enabled and '0' otherwise, labeled by component, feature name and stage.",
+		},
+		[]string{"component", "name", "stage"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(featureEnabled)
+}
+
+// FeatureGate returns the mutable feature gate of a component.
+func FeatureGate(component Component) featuregate.MutableFeatureGate {
+	switch component {
+	case Hub:
+		return DefaultHubMutableFeatureGate
+	case Spoke:
+		return DefaultSpokeMutableFeatureGate
+	case Webhook:
+		return utilfeature.DefaultMutableFeatureGate
+	}
+	return nil
+}
+
+// RegisterFeatureGates adds features to the feature gate of a component. The downstream builds use it to gate
+// their own features with the same "--feature-gates" flag and configuration file. It must be called before the
+// flags of the component are added, e.g. in an init func, otherwise an error is returned. Registering a known
+// feature with a different spec also results in an error.
+func RegisterFeatureGates(component Component, features map[featuregate.Feature]featuregate.FeatureSpec) error {
+	gate := FeatureGate(component)
+	if gate == nil {
+		return fmt.Errorf("unknown component %q", component)
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if err := gate.Add(features); err != nil {
+		return fmt.Errorf("unable to register feature gates of %s: %w", component, err)
+	}
+	if _, ok := registeredFeatureGates[component]; !ok {
+		registeredFeatureGates[component] = map[featuregate.Feature]featuregate.FeatureSpec{}
+	}
+	for name, spec := range features {
+		registeredFeatureGates[component][name] = spec
+	}
+	return nil
+}
+
+// ReportFeatureGates reports the state of the registered features of a component with the
+// open_cluster_management_registration_feature_enabled metric and logs the enabled ones. It is called once the
+// feature gate is set from the flags and the configuration file.
+func ReportFeatureGates(component Component) {
+	gate := FeatureGate(component)
+	if gate == nil {
+		return
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	enabled := []string{}
+	for name, spec := range registeredFeatureGates[component] {
+		stage := string(spec.PreRelease)
+		if spec.PreRelease == featuregate.GA {
+			stage = "GA"
+		}
+		value := 0.0
+		if gate.Enabled(name) {
+			value = 1
+			enabled = append(enabled, string(name))
+		}
+		featureEnabled.WithLabelValues(string(component), string(name), stage).Set(value)
+	}
+	sort.Strings(enabled)
+
+	klog.Infof("Enabled feature gates of %s: [%s]", component, strings.Join(enabled, ", "))
+}
+
+// FeatureGateStates returns whether each known feature of a component is enabled, including the features of the
+// generic apiserver for the webhook server.
+func FeatureGateStates(component Component) map[string]bool {
+	gate := FeatureGate(component)
+	if gate == nil {
+		return nil
+	}
+
+	states := map[string]bool{}
+	for name := range gate.GetAll() {
+		states[string(name)] = gate.Enabled(name)
+	}
+	return states
+}
@@ -0,0 +1,122 @@
+package features
+
+import (
+	"testing"
+
+	"k8s.io/component-base/featuregate"
+	"k8s.io/component-base/metrics/testutil"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestRegisterFeatureGates(t *testing.T) {
+	cases := []struct {
+		name        string
+		component   Component
+		features    map[featuregate.Feature]featuregate.FeatureSpec
+		expectedErr string
+	}{
+		{
+			name:        "unknown component",
+			component:   Component("foo"),
+			expectedErr: "unknown component \"foo\"",
+		},
+		{
+			name:      "register a downstream feature",
+			component: Spoke,
+			features: map[featuregate.Feature]featuregate.FeatureSpec{
+				"DownstreamFeature": {Default: true, PreRelease: featuregate.Beta},
+			},
+		},
+		{
+			name:      "register a known feature with the same spec",
+			component: Hub,
+			features: map[featuregate.Feature]featuregate.FeatureSpec{
+				DefaultClusterSet: {Default: false, PreRelease: featuregate.Alpha},
+			},
+		},
+		{
+			name:      "register a known feature with a different spec",
+			component: Hub,
+			features: map[featuregate.Feature]featuregate.FeatureSpec{
+				DefaultClusterSet: {Default: true, PreRelease: featuregate.Beta},
+			},
+			expectedErr: "unable to register feature gates of hub: feature gate \"DefaultClusterSet\" with different spec already exists",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := RegisterFeatureGates(c.component, c.features)
+			testinghelpers.AssertErrorWithPrefix(t, err, c.expectedErr)
+			if err != nil {
+				return
+			}
+			for name, spec := range c.features {
+				if FeatureGate(c.component).Enabled(name) != spec.Default {
+					t.Errorf("expected feature %q to be %v by default", name, spec.Default)
+				}
+			}
+		})
+	}
+}
+
+func TestReportFeatureGates(t *testing.T) {
+	if err := RegisterFeatureGates(Spoke, map[featuregate.Feature]featuregate.FeatureSpec{
+		"ReportedFeature": {Default: false, PreRelease: featuregate.GA},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := FeatureGate(Spoke).SetFromMap(map[string]bool{"ReportedFeature": true, string(ClusterClaim): false}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ReportFeatureGates(Spoke)
+
+	cases := []struct {
+		name          string
+		stage         string
+		expectedValue float64
+	}{
+		{name: "ReportedFeature", stage: "GA", expectedValue: 1},
+		{name: string(ClusterClaim), stage: string(featuregate.Beta), expectedValue: 0},
+		{name: string(AddonManagement), stage: string(featuregate.Alpha), expectedValue: 0},
+	}
+	for _, c := range cases {
+		value, err := testutil.GetGaugeMetricValue(featureEnabled.WithLabelValues(string(Spoke), c.name, c.stage))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if value != c.expectedValue {
+			t.Errorf("expected feature %q to be reported as %v, but got %v", c.name, c.expectedValue, value)
+		}
+	}
+}
+
+func TestFeatureGateStates(t *testing.T) {
+	states := FeatureGateStates(Hub)
+	if enabled, ok := states[string(DefaultClusterSet)]; !ok || enabled != FeatureGate(Hub).Enabled(DefaultClusterSet) {
+		t.Errorf("expected the state of %s in %v", DefaultClusterSet, states)
+	}
+	if states := FeatureGateStates(Component("foo")); states != nil {
+		t.Errorf("expected no states of an unknown component, but got %v", states)
+	}
+}
+
+func TestWebhookAcceptsHubFeatureGates(t *testing.T) {
+	gate := FeatureGate(Webhook).DeepCopy()
+	hubFeatures := map[string]bool{}
+	for name := range defaultHubRegistrationFeatureGates {
+		hubFeatures[string(name)] = true
+	}
+	if err := gate.SetFromMap(hubFeatures); err != nil {
+		t.Fatalf("expected the hub features to be accepted by the webhook server, but got %v", err)
+	}
+	if !gate.Enabled(DefaultClusterSet) {
+		t.Errorf("expected feature %q to be enabled on the webhook server", DefaultClusterSet)
+	}
+	if spec := gate.GetAll()[TokenRegistration]; spec.PreRelease != featuregate.Deprecated {
+		t.Errorf("expected the hub only feature %q to be deprecated on the webhook server, but got %v",
+			TokenRegistration, spec.PreRelease)
+	}
+}
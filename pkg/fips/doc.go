@@ -0,0 +1,7 @@
+// package fips restricts the crypto of the hub and the agent to the FIPS-approved algorithms. The mode is enabled
+// at runtime with the --fips-mode flag, or at build time with the boringcrypto build tag, which additionally links
+// the FIPS-validated BoringCrypto module and restricts all of the TLS connections of the process, including the
+// ones of the kube clients. In the mode, the TLS configs built by the hub and the agent only negotiate the approved
+// cipher suites and curves, and the keys are checked to be approved, i.e. no ed25519 and no RSA key shorter than
+// MinRSAKeySize.
+package fips
@@ -0,0 +1,111 @@
+package fips
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync/atomic"
+)
+
+// MinRSAKeySize is the minimum size of the approved RSA keys in bits
+const MinRSAKeySize = 2048
+
+// CipherSuites are the approved TLS 1.2 cipher suites
+var CipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// CurvePreferences are the approved curves of the TLS key exchanges
+var CurvePreferences = []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+var enabled int32
+
+func init() {
+	if builtIn {
+		Enable()
+	}
+}
+
+// Enable enables the FIPS mode, it is called once the options of the hub or the agent are validated
+func Enable() {
+	atomic.StoreInt32(&enabled, 1)
+}
+
+// Enabled returns true if the FIPS mode is enabled at runtime or at build time
+func Enabled() bool {
+	return atomic.LoadInt32(&enabled) == 1
+}
+
+// BuiltIn returns true if the binary is built with the boringcrypto build tag, the FIPS mode can not be disabled
+// in such a binary
+func BuiltIn() bool {
+	return builtIn
+}
+
+// RestrictTLSConfig restricts the TLS config to the approved versions, cipher suites and curves if the FIPS mode is
+// enabled, and returns the config. TLS 1.3 is not negotiated in the mode, since its cipher suites are not
+// configurable.
+func RestrictTLSConfig(config *tls.Config) *tls.Config {
+	if !Enabled() {
+		return config
+	}
+	config.MinVersion = tls.VersionTLS12
+	config.MaxVersion = tls.VersionTLS12
+	config.CipherSuites = append([]uint16{}, CipherSuites...)
+	config.CurvePreferences = append([]tls.CurveID{}, CurvePreferences...)
+	return config
+}
+
+// ValidatePublicKey returns an error if the public key is not approved, i.e. an ed25519 key, an RSA key shorter
+// than MinRSAKeySize, or an ECDSA key on a curve other than P-256, P-384 and P-521
+func ValidatePublicKey(key crypto.PublicKey) error {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < MinRSAKeySize {
+			return fmt.Errorf("the %d-bit RSA key is shorter than %d bits", k.N.BitLen(), MinRSAKeySize)
+		}
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+		default:
+			return fmt.Errorf("the ECDSA key on curve %s is not FIPS-approved", k.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		return fmt.Errorf("the ed25519 key is not FIPS-approved")
+	default:
+		return fmt.Errorf("the %T key is not FIPS-approved", key)
+	}
+	return nil
+}
+
+// ValidateCertificate returns an error if the FIPS mode is enabled and the key of the certificate is not approved
+func ValidateCertificate(cert *x509.Certificate) error {
+	if !Enabled() {
+		return nil
+	}
+	if err := ValidatePublicKey(cert.PublicKey); err != nil {
+		return fmt.Errorf("the certificate %q is not allowed in the FIPS mode: %w", cert.Subject.CommonName, err)
+	}
+	return nil
+}
+
+// ValidateKeyPair returns an error if the FIPS mode is enabled and the key of the leaf certificate of the key pair
+// is not approved
+func ValidateKeyPair(keyPair tls.Certificate) error {
+	if !Enabled() || len(keyPair.Certificate) == 0 {
+		return nil
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return err
+	}
+	return ValidateCertificate(cert)
+}
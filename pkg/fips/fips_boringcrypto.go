@@ -0,0 +1,10 @@
+//go:build boringcrypto
+// +build boringcrypto
+
+package fips
+
+// restrict all of the TLS connections of the process to the FIPS-approved settings
+import _ "crypto/tls/fipsonly"
+
+// builtIn is true if the binary is built with the FIPS-validated BoringCrypto module
+const builtIn = true
@@ -0,0 +1,7 @@
+//go:build !boringcrypto
+// +build !boringcrypto
+
+package fips
+
+// builtIn is true if the binary is built with the FIPS-validated BoringCrypto module
+const builtIn = false
@@ -0,0 +1,85 @@
+package fips
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"sync/atomic"
+	"testing"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestValidatePublicKey(t *testing.T) {
+	newRSAKey := func(bits int) crypto.PublicKey {
+		key, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &key.PublicKey
+	}
+	newECDSAKey := func(curve elliptic.Curve) crypto.PublicKey {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &key.PublicKey
+	}
+	ed25519Key, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name        string
+		key         crypto.PublicKey
+		expectedErr string
+	}{
+		{
+			name: "rsa 2048",
+			key:  newRSAKey(2048),
+		},
+		{
+			name:        "rsa 1024",
+			key:         newRSAKey(1024),
+			expectedErr: "the 1024-bit RSA key is shorter than 2048 bits",
+		},
+		{
+			name: "ecdsa p256",
+			key:  newECDSAKey(elliptic.P256()),
+		},
+		{
+			name:        "ecdsa p224",
+			key:         newECDSAKey(elliptic.P224()),
+			expectedErr: "the ECDSA key on curve P-224 is not FIPS-approved",
+		},
+		{
+			name:        "ed25519",
+			key:         ed25519Key,
+			expectedErr: "the ed25519 key is not FIPS-approved",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			testinghelpers.AssertError(t, ValidatePublicKey(c.key), c.expectedErr)
+		})
+	}
+}
+
+func TestRestrictTLSConfig(t *testing.T) {
+	if config := RestrictTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}); !BuiltIn() && len(config.CipherSuites) > 0 {
+		t.Errorf("expected the config not to be restricted out of the FIPS mode")
+	}
+
+	Enable()
+	defer atomic.StoreInt32(&enabled, 0)
+	config := RestrictTLSConfig(&tls.Config{})
+	if config.MinVersion != tls.VersionTLS12 || config.MaxVersion != tls.VersionTLS12 ||
+		len(config.CipherSuites) != len(CipherSuites) || len(config.CurvePreferences) != len(CurvePreferences) {
+		t.Errorf("expected the config to be restricted in the FIPS mode, but got %v", config)
+	}
+}
@@ -0,0 +1,116 @@
+package grpcregistration
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// NewClientConfigs converts the client configs of the ManagedCluster api to the messages of the Registration service
+func NewClientConfigs(configs []clusterv1.ClientConfig) []*ClientConfig {
+	var result []*ClientConfig
+	for _, config := range configs {
+		result = append(result, &ClientConfig{Url: config.URL, CaBundle: config.CABundle})
+	}
+	return result
+}
+
+// ToClientConfigs converts the client configs in the messages of the Registration service to the ManagedCluster api
+func ToClientConfigs(configs []*ClientConfig) []clusterv1.ClientConfig {
+	var result []clusterv1.ClientConfig
+	for _, config := range configs {
+		result = append(result, clusterv1.ClientConfig{URL: config.GetUrl(), CABundle: config.GetCaBundle()})
+	}
+	return result
+}
+
+// NewClusterStatus converts the status of the ManagedCluster api to the message of the Registration service
+func NewClusterStatus(status clusterv1.ManagedClusterStatus) *ClusterStatus {
+	result := &ClusterStatus{
+		Capacity:          newQuantities(status.Capacity),
+		Allocatable:       newQuantities(status.Allocatable),
+		KubernetesVersion: status.Version.Kubernetes,
+	}
+	for _, condition := range status.Conditions {
+		result.Conditions = append(result.Conditions, &Condition{
+			Type:               condition.Type,
+			Status:             string(condition.Status),
+			ObservedGeneration: condition.ObservedGeneration,
+			LastTransitionTime: timestamppb.New(condition.LastTransitionTime.Time),
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+		})
+	}
+	for _, claim := range status.ClusterClaims {
+		result.ClusterClaims = append(result.ClusterClaims, &ClusterClaim{Name: claim.Name, Value: claim.Value})
+	}
+	return result
+}
+
+// ToManagedClusterStatus converts the status in the message of the Registration service to the ManagedCluster api.
+// An error is returned if a quantity of the resources is invalid.
+func ToManagedClusterStatus(status *ClusterStatus) (clusterv1.ManagedClusterStatus, error) {
+	capacity, err := toResourceList(status.GetCapacity())
+	if err != nil {
+		return clusterv1.ManagedClusterStatus{}, fmt.Errorf("invalid capacity: %w", err)
+	}
+	allocatable, err := toResourceList(status.GetAllocatable())
+	if err != nil {
+		return clusterv1.ManagedClusterStatus{}, fmt.Errorf("invalid allocatable: %w", err)
+	}
+
+	result := clusterv1.ManagedClusterStatus{
+		Capacity:    capacity,
+		Allocatable: allocatable,
+		Version:     clusterv1.ManagedClusterVersion{Kubernetes: status.GetKubernetesVersion()},
+	}
+	for _, condition := range status.GetConditions() {
+		result.Conditions = append(result.Conditions, metav1.Condition{
+			Type:               condition.GetType(),
+			Status:             metav1.ConditionStatus(condition.GetStatus()),
+			ObservedGeneration: condition.GetObservedGeneration(),
+			LastTransitionTime: metav1.NewTime(condition.GetLastTransitionTime().AsTime().Local()),
+			Reason:             condition.GetReason(),
+			Message:            condition.GetMessage(),
+		})
+	}
+	for _, claim := range status.GetClusterClaims() {
+		result.ClusterClaims = append(result.ClusterClaims, clusterv1.ManagedClusterClaim{
+			Name:  claim.GetName(),
+			Value: claim.GetValue(),
+		})
+	}
+	return result, nil
+}
+
+// newQuantities returns the quantities of the resources in their string form
+func newQuantities(resources clusterv1.ResourceList) map[string]string {
+	if len(resources) == 0 {
+		return nil
+	}
+	quantities := map[string]string{}
+	for name, quantity := range resources {
+		quantities[string(name)] = quantity.String()
+	}
+	return quantities
+}
+
+// toResourceList parses the quantities of the resources in their string form
+func toResourceList(quantities map[string]string) (clusterv1.ResourceList, error) {
+	if len(quantities) == 0 {
+		return nil, nil
+	}
+	resources := clusterv1.ResourceList{}
+	for name, value := range quantities {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("quantity %q of resource %q: %w", value, name, err)
+		}
+		resources[clusterv1.ResourceName(name)] = quantity
+	}
+	return resources, nil
+}
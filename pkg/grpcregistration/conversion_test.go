@@ -0,0 +1,56 @@
+package grpcregistration
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestClusterStatusConversion(t *testing.T) {
+	status := clusterv1.ManagedClusterStatus{
+		Conditions: []metav1.Condition{{
+			Type:               clusterv1.ManagedClusterConditionAvailable,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: 2,
+			LastTransitionTime: metav1.NewTime(time.Now().Truncate(time.Second)),
+			Reason:             "ManagedClusterAvailable",
+			Message:            "Managed cluster is available",
+		}},
+		Capacity: clusterv1.ResourceList{
+			clusterv1.ResourceCPU:    resource.MustParse("4"),
+			clusterv1.ResourceMemory: resource.MustParse("16Gi"),
+		},
+		Allocatable: clusterv1.ResourceList{
+			clusterv1.ResourceCPU: resource.MustParse("3500m"),
+		},
+		Version:       clusterv1.ManagedClusterVersion{Kubernetes: "v1.23.0"},
+		ClusterClaims: []clusterv1.ManagedClusterClaim{{Name: "id.k8s.io", Value: "cluster1"}},
+	}
+
+	converted, err := ToManagedClusterStatus(NewClusterStatus(status))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(converted.Conditions, status.Conditions) || converted.Version != status.Version ||
+		!reflect.DeepEqual(converted.ClusterClaims, status.ClusterClaims) {
+		t.Errorf("expected status %v, but got %v", status, converted)
+	}
+	for name, quantity := range status.Capacity {
+		if actual := converted.Capacity[name]; actual.Cmp(quantity) != 0 {
+			t.Errorf("expected capacity %v of %q, but got %v", quantity, name, converted.Capacity[name])
+		}
+	}
+	cpu := converted.Allocatable[clusterv1.ResourceCPU]
+	if cpu.MilliValue() != 3500 || len(converted.Allocatable) != 1 {
+		t.Errorf("expected allocatable %v, but got %v", status.Allocatable, converted.Allocatable)
+	}
+
+	_, err = ToManagedClusterStatus(&ClusterStatus{Capacity: map[string]string{"cpu": "four"}})
+	testinghelpers.AssertError(t, err, "invalid capacity: quantity \"four\" of resource \"cpu\": quantities must match the regular expression '^([+-]?[0-9.]+)([eEinumkKMGTP]*[-+]?[0-9]*)$'")
+}
@@ -0,0 +1,11 @@
+// package grpcregistration defines an experimental registration protocol over gRPC, for the hubs which are not
+// Kubernetes clusters. The agent registers its cluster, heartbeats and reports the status of the cluster with the
+// Registration service instead of the ManagedCluster and Lease apis of the hub kube-apiserver. The hub implements
+// RegistrationServer and serves it with NewServer, the agent calls it with RegistrationClient.
+//
+// The service is defined in registration.proto, so it can be implemented in other languages with the generated
+// stubs. The go stubs are generated with "make update-proto". The hub serves the service with e.g.
+//
+//	server := grpcregistration.NewServer(myRegistrationServer, grpc.Creds(credentials.NewTLS(tlsConfig)))
+//	server.Serve(listener)
+package grpcregistration
@@ -0,0 +1,30 @@
+package grpcregistration
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// NewServer returns a grpc server serving the Registration service with the implementation. The options
+// configure the server, e.g. the transport credentials.
+func NewServer(srv RegistrationServer, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	RegisterRegistrationServer(server, srv)
+	return server
+}
+
+// Dial connects to the Registration service at the address with the transport credentials, and returns a client
+// with the connection. The connection is closed once the context is done.
+func Dial(ctx context.Context, address string, creds credentials.TransportCredentials) (RegistrationClient, error) {
+	conn, err := grpc.DialContext(ctx, address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	return NewRegistrationClient(conn), nil
+}
@@ -0,0 +1,135 @@
+package grpcregistration
+
+import (
+	"context"
+	"net"
+	"path"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+// fakeRegistrationServer accepts the cluster "cluster1" only, and records the requests
+type fakeRegistrationServer struct {
+	UnimplementedRegistrationServer
+	heartbeats int
+	status     *clusterv1.ManagedClusterStatus
+}
+
+func (s *fakeRegistrationServer) Register(ctx context.Context, req *RegisterRequest) (*RegisterResponse, error) {
+	if req.ClusterName != "cluster1" {
+		return &RegisterResponse{Message: "waiting for approval"}, nil
+	}
+	return &RegisterResponse{Accepted: true}, nil
+}
+
+func (s *fakeRegistrationServer) Heartbeat(ctx context.Context, req *HeartbeatRequest) (*HeartbeatResponse, error) {
+	if req.ClusterName != "cluster1" {
+		return nil, status.Errorf(codes.PermissionDenied, "cluster %q is not accepted", req.ClusterName)
+	}
+	s.heartbeats++
+	return &HeartbeatResponse{LeaseDurationSeconds: 30}, nil
+}
+
+func (s *fakeRegistrationServer) ReportStatus(ctx context.Context, req *ReportStatusRequest) (*ReportStatusResponse, error) {
+	clusterStatus, err := ToManagedClusterStatus(req.Status)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid status: %v", err)
+	}
+	s.status = &clusterStatus
+	return &ReportStatusResponse{}, nil
+}
+
+// newTestClient serves srv on a unix socket, and returns a client connecting to it. The full names of the methods
+// called are appended to methods.
+func newTestClient(t *testing.T, srv RegistrationServer, methods *[]string) RegistrationClient {
+	socketPath := path.Join(t.TempDir(), "registration.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server := NewServer(srv, grpc.UnaryInterceptor(
+		func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			*methods = append(*methods, info.FullMethod)
+			return handler(ctx, req)
+		}))
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(socketPath, grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", addr)
+		}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewRegistrationClient(conn)
+}
+
+func TestRegistrationService(t *testing.T) {
+	srv := &fakeRegistrationServer{}
+	methods := []string{}
+	client := newTestClient(t, srv, &methods)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	resp, err := client.Register(ctx, &RegisterRequest{ClusterName: "cluster2", AgentName: "agent1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Accepted || resp.Message != "waiting for approval" {
+		t.Errorf("expected cluster2 not accepted, but got %v", resp)
+	}
+
+	resp, err = client.Register(ctx, &RegisterRequest{ClusterName: "cluster1", AgentName: "agent1"})
+	if err != nil || !resp.Accepted {
+		t.Errorf("expected cluster1 accepted, but got %v, %v", resp, err)
+	}
+
+	_, err = client.Heartbeat(ctx, &HeartbeatRequest{ClusterName: "cluster2", AgentName: "agent1"})
+	testinghelpers.AssertError(t, err, "rpc error: code = PermissionDenied desc = cluster \"cluster2\" is not accepted")
+
+	heartbeat, err := client.Heartbeat(ctx, &HeartbeatRequest{ClusterName: "cluster1", AgentName: "agent1"})
+	if err != nil || heartbeat.LeaseDurationSeconds != 30 || srv.heartbeats != 1 {
+		t.Errorf("expected a heartbeat with lease duration 30s, but got %v, %v", heartbeat, err)
+	}
+
+	_, err = client.ReportStatus(ctx, &ReportStatusRequest{
+		ClusterName: "cluster1",
+		AgentName:   "agent1",
+		Status: NewClusterStatus(clusterv1.ManagedClusterStatus{
+			Conditions: []metav1.Condition{{Type: clusterv1.ManagedClusterConditionAvailable, Status: metav1.ConditionTrue}},
+			Version:    clusterv1.ManagedClusterVersion{Kubernetes: "v1.23.0"},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if srv.status == nil || srv.status.Version.Kubernetes != "v1.23.0" || len(srv.status.Conditions) != 1 {
+		t.Errorf("unexpected status %v", srv.status)
+	}
+
+	registerMethod := "/" + Registration_ServiceDesc.ServiceName + "/Register"
+	heartbeatMethod := "/" + Registration_ServiceDesc.ServiceName + "/Heartbeat"
+	reportStatusMethod := "/" + Registration_ServiceDesc.ServiceName + "/ReportStatus"
+	expectedMethods := []string{registerMethod, registerMethod, heartbeatMethod, heartbeatMethod, reportStatusMethod}
+	if len(methods) != len(expectedMethods) {
+		t.Fatalf("expected methods %v, but got %v", expectedMethods, methods)
+	}
+	for i := range methods {
+		if methods[i] != expectedMethods[i] {
+			t.Errorf("expected methods %v, but got %v", expectedMethods, methods)
+		}
+	}
+}
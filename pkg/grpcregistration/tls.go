@@ -0,0 +1,40 @@
+package grpcregistration
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"path"
+
+	"open-cluster-management.io/registration/pkg/fips"
+)
+
+// NewClientTLSConfig returns the tls config of the agent to connect to the Registration service. The serving
+// certificate of the hub is verified with the CA bundle in caFile, or the system roots if it is empty. The client
+// certificate in certFile and keyFile is presented to the hub if they are set. The config is restricted to the
+// FIPS-approved settings in the FIPS mode.
+func NewClientTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(caFile) > 0 {
+		caData, err := ioutil.ReadFile(path.Clean(caFile))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file %q: %w", caFile, err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificate found in CA file %q", caFile)
+		}
+	}
+	if len(certFile) > 0 || len(keyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %w", err)
+		}
+		if err := fips.ValidateKeyPair(cert); err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return fips.RestrictTLSConfig(tlsConfig), nil
+}
@@ -0,0 +1,71 @@
+package grpcregistration
+
+import (
+	"path"
+	"testing"
+	"time"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestNewClientTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	cert := testinghelpers.NewTestCert("agent1", time.Hour)
+	certFile, keyFile, invalidCAFile := path.Join(dir, "tls.crt"), path.Join(dir, "tls.key"), path.Join(dir, "invalid.crt")
+	testinghelpers.WriteFile(certFile, cert.Cert)
+	testinghelpers.WriteFile(keyFile, cert.Key)
+	testinghelpers.WriteFile(invalidCAFile, []byte("invalid"))
+
+	cases := []struct {
+		name                    string
+		caFile                  string
+		certFile                string
+		keyFile                 string
+		expectedErr             string
+		expectedRootCAs         bool
+		expectedClientCertCount int
+	}{
+		{
+			name: "system roots",
+		},
+		{
+			name:        "no CA file",
+			caFile:      path.Join(dir, "ca.crt"),
+			expectedErr: "unable to read CA file \"" + path.Join(dir, "ca.crt") + "\": open " + path.Join(dir, "ca.crt") + ": no such file or directory",
+		},
+		{
+			name:        "invalid CA file",
+			caFile:      invalidCAFile,
+			expectedErr: "no certificate found in CA file \"" + invalidCAFile + "\"",
+		},
+		{
+			name:        "client certificate without key",
+			certFile:    certFile,
+			expectedErr: "unable to load client certificate: open : no such file or directory",
+		},
+		{
+			name:                    "client certificate",
+			caFile:                  certFile,
+			certFile:                certFile,
+			keyFile:                 keyFile,
+			expectedRootCAs:         true,
+			expectedClientCertCount: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tlsConfig, err := NewClientTLSConfig(c.caFile, c.certFile, c.keyFile)
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if err != nil {
+				return
+			}
+			if (tlsConfig.RootCAs != nil) != c.expectedRootCAs {
+				t.Errorf("expected root CAs %t, but got %v", c.expectedRootCAs, tlsConfig.RootCAs)
+			}
+			if len(tlsConfig.Certificates) != c.expectedClientCertCount {
+				t.Errorf("expected %d client certificates, but got %d", c.expectedClientCertCount, len(tlsConfig.Certificates))
+			}
+		})
+	}
+}
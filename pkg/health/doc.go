@@ -0,0 +1,14 @@
+// package health checks whether the controllers of the hub controller and the agent make progress. Each
+// controller is registered as a named check, which fails once the queue of the controller has keys waiting or
+// a sync running but no key is processed for the progress deadline, so a silently stuck controller turns into a
+// failing liveness probe. The checks are served on /healthz when the "--health-probe-bind-address" flag is set,
+// and a single controller is checked on /healthz/<controller name>.
+//
+// The syncs of the controllers are wrapped by WrapSync. A sync taking longer than the slow sync threshold is
+// logged and counted with the phase it spent most of its time in, the phases are marked in the sync with
+// EnterPhase. A failed key is retried with the delay of a rate limiter whose limits are set with SetRetryLimits.
+//
+// A controller built with NewPriorityController syncs the keys of some objects, e.g. the joining clusters, with a
+// priority queue of its own, which is checked as the controller named with the suffix "Priority". A WarmUp queues
+// the initial keys of a controller in batches, see SetWarmUpBatches.
+package health
@@ -0,0 +1,235 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// Path is the path of the endpoint which serves the health checks of the controllers
+const Path = "/healthz"
+
+// DefaultProgressDeadline is the default period a controller with pending work is allowed to make no progress
+const DefaultProgressDeadline = 10 * time.Minute
+
+// the metrics of the workqueue package, which are labeled with the name of the controller queues
+const (
+	workQueueDepthMetric          = "workqueue_depth"
+	workQueueWorkDurationMetric   = "workqueue_work_duration_seconds"
+	workQueueLongestRunningMetric = "workqueue_longest_running_processor_seconds"
+)
+
+// queueSample is the state of a controller queue read from the workqueue metrics
+type queueSample struct {
+	depth                 float64
+	processed             uint64
+	longestRunningSeconds float64
+}
+
+// controllerProgress tracks the progress of a controller queue
+type controllerProgress struct {
+	processed    uint64
+	lastProgress time.Time
+}
+
+// Checker checks the progress of the registered controllers with the workqueue metrics
+type Checker struct {
+	lock             sync.Mutex
+	gatherer         metrics.Gatherer
+	progressDeadline time.Duration
+	controllers      map[string]*controllerProgress
+	now              func() time.Time
+}
+
+// NewChecker returns a Checker reading the workqueue metrics from the given gatherer
+func NewChecker(gatherer metrics.Gatherer, progressDeadline time.Duration) *Checker {
+	return &Checker{
+		gatherer:         gatherer,
+		progressDeadline: progressDeadline,
+		controllers:      map[string]*controllerProgress{},
+		now:              time.Now,
+	}
+}
+
+// defaultChecker reads the workqueue metrics from the legacy registry they are registered in
+var defaultChecker = NewChecker(legacyregistry.DefaultGatherer, DefaultProgressDeadline)
+
+// SetProgressDeadline sets the period a controller with pending work is allowed to make no progress, the
+// DefaultProgressDeadline is used if it is not positive.
+func SetProgressDeadline(progressDeadline time.Duration) {
+	if progressDeadline <= 0 {
+		progressDeadline = DefaultProgressDeadline
+	}
+	defaultChecker.lock.Lock()
+	defer defaultChecker.lock.Unlock()
+	defaultChecker.progressDeadline = progressDeadline
+}
+
+// RegisterController registers a controller as a named check, the name is the name of the controller queue.
+func RegisterController(name string) {
+	defaultChecker.Register(name)
+}
+
+// UnregisterController removes the check of a controller, it is called once the controller is stopped.
+func UnregisterController(name string) {
+	defaultChecker.Unregister(name)
+}
+
+// Register registers a controller as a named check
+func (c *Checker) Register(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.controllers[name]; ok {
+		return
+	}
+	c.controllers[name] = &controllerProgress{lastProgress: c.now()}
+}
+
+// Unregister removes the check of a controller
+func (c *Checker) Unregister(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.controllers, name)
+}
+
+// Check checks the progress of the registered controllers. It returns the names of the checked controllers in
+// order, and the errors of the controllers which are stuck.
+func (c *Checker) Check() ([]string, map[string]error, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	samples, err := c.sample()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := c.now()
+	names := []string{}
+	errs := map[string]error{}
+	for name, progress := range c.controllers {
+		names = append(names, name)
+
+		sample := samples[name]
+		// the controller is idle, or it processed keys since the last check
+		if (sample.depth == 0 && sample.longestRunningSeconds == 0) || sample.processed != progress.processed {
+			progress.processed = sample.processed
+			progress.lastProgress = now
+			continue
+		}
+
+		if stalled := now.Sub(progress.lastProgress); stalled > c.progressDeadline {
+			errs[name] = fmt.Errorf("no key is processed for %v, %v keys are waiting and the longest running sync started %.0fs ago",
+				stalled.Round(time.Second), sample.depth, sample.longestRunningSeconds)
+		}
+	}
+	sort.Strings(names)
+	return names, errs, nil
+}
+
+// sample reads the state of the controller queues from the workqueue metrics
+func (c *Checker) sample() (map[string]queueSample, error) {
+	families, err := c.gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	samples := map[string]queueSample{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			name := ""
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" {
+					name = label.GetValue()
+				}
+			}
+			if _, ok := c.controllers[name]; !ok {
+				continue
+			}
+
+			sample := samples[name]
+			switch family.GetName() {
+			case workQueueDepthMetric:
+				sample.depth = metric.GetGauge().GetValue()
+			case workQueueWorkDurationMetric:
+				sample.processed = metric.GetHistogram().GetSampleCount()
+			case workQueueLongestRunningMetric:
+				sample.longestRunningSeconds = metric.GetGauge().GetValue()
+			}
+			samples[name] = sample
+		}
+	}
+	return samples, nil
+}
+
+// Handler serves the checks of the controllers in the format of the kubernetes healthz endpoints. The path
+// /healthz/<controller name> checks a single controller.
+func (c *Checker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		names, errs, err := c.Check()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to read the workqueue metrics: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		if name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, Path), "/"); len(name) > 0 {
+			found := false
+			for _, n := range names {
+				found = found || n == name
+			}
+			switch {
+			case !found:
+				http.Error(w, fmt.Sprintf("controller %q is not found", name), http.StatusNotFound)
+			case errs[name] != nil:
+				http.Error(w, fmt.Sprintf("[-]%s failed: %v", name, errs[name]), http.StatusInternalServerError)
+			default:
+				fmt.Fprint(w, "ok")
+			}
+			return
+		}
+
+		output := &strings.Builder{}
+		for _, name := range names {
+			if errs[name] != nil {
+				fmt.Fprintf(output, "[-]%s failed: %v\n", name, errs[name])
+			} else {
+				fmt.Fprintf(output, "[+]%s ok\n", name)
+			}
+		}
+		if len(errs) > 0 {
+			http.Error(w, output.String()+"healthz check failed", http.StatusInternalServerError)
+			return
+		}
+		if _, verbose := r.URL.Query()["verbose"]; verbose {
+			fmt.Fprint(w, output.String()+"healthz check passed\n")
+			return
+		}
+		fmt.Fprint(w, "ok")
+	})
+}
+
+// Serve serves the checks of the registered controllers on the given address until the context is done. The
+// endpoint is not protected by any authentication, so that it can be used by the probes of kubelet.
+func Serve(ctx context.Context, address string) error {
+	mux := http.NewServeMux()
+	mux.Handle(Path, defaultChecker.Handler())
+	mux.Handle(Path+"/", defaultChecker.Handler())
+	return helpers.ServeHTTP(ctx, "health probes", address, mux)
+}
+
+// RunController registers a controller as a named check and runs it until the context is done, the check is
+// removed once the controller is stopped.
+func RunController(ctx context.Context, controller factory.Controller, workers int) {
+	RegisterController(controller.Name())
+	defer UnregisterController(controller.Name())
+	controller.Run(ctx, workers)
+}
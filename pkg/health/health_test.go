@@ -0,0 +1,159 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/component-base/metrics"
+)
+
+type fakeQueueMetrics struct {
+	depth          *metrics.GaugeVec
+	workDuration   *metrics.HistogramVec
+	longestRunning *metrics.GaugeVec
+}
+
+func newFakeQueueMetrics(registry metrics.KubeRegistry) *fakeQueueMetrics {
+	m := &fakeQueueMetrics{
+		depth:          metrics.NewGaugeVec(&metrics.GaugeOpts{Name: workQueueDepthMetric}, []string{"name"}),
+		workDuration:   metrics.NewHistogramVec(&metrics.HistogramOpts{Name: workQueueWorkDurationMetric}, []string{"name"}),
+		longestRunning: metrics.NewGaugeVec(&metrics.GaugeOpts{Name: workQueueLongestRunningMetric}, []string{"name"}),
+	}
+	registry.MustRegister(m.depth, m.workDuration, m.longestRunning)
+	return m
+}
+
+func TestCheck(t *testing.T) {
+	start := time.Now()
+
+	cases := []struct {
+		name           string
+		depth          float64
+		processed      int
+		longestRunning float64
+		elapsed        time.Duration
+		expectedFailed bool
+	}{
+		{
+			name:    "idle queue",
+			elapsed: time.Hour,
+		},
+		{
+			name:      "keys are processed",
+			depth:     5,
+			processed: 3,
+			elapsed:   time.Hour,
+		},
+		{
+			name:    "keys are pending within the deadline",
+			depth:   5,
+			elapsed: 5 * time.Minute,
+		},
+		{
+			name:           "keys are pending after the deadline",
+			depth:          5,
+			elapsed:        time.Hour,
+			expectedFailed: true,
+		},
+		{
+			name:           "sync is running after the deadline",
+			longestRunning: 3600,
+			elapsed:        time.Hour,
+			expectedFailed: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			registry := metrics.NewKubeRegistry()
+			queueMetrics := newFakeQueueMetrics(registry)
+			queueMetrics.depth.WithLabelValues("other").Set(10)
+
+			now := start
+			checker := NewChecker(registry, 10*time.Minute)
+			checker.now = func() time.Time { return now }
+			checker.Register("controller1")
+
+			queueMetrics.depth.WithLabelValues("controller1").Set(c.depth)
+			queueMetrics.longestRunning.WithLabelValues("controller1").Set(c.longestRunning)
+			for i := 0; i < c.processed; i++ {
+				queueMetrics.workDuration.WithLabelValues("controller1").Observe(0.1)
+			}
+			now = start.Add(c.elapsed)
+
+			names, errs, err := checker.Check()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(names, []string{"controller1"}) {
+				t.Errorf("expected checked controllers [controller1], but got %v", names)
+			}
+			if failed := errs["controller1"] != nil; failed != c.expectedFailed {
+				t.Errorf("expected failed %v, but got %v", c.expectedFailed, errs)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	registry := metrics.NewKubeRegistry()
+	queueMetrics := newFakeQueueMetrics(registry)
+
+	start := time.Now()
+	now := start
+	checker := NewChecker(registry, 10*time.Minute)
+	checker.now = func() time.Time { return now }
+	checker.Register("controller1")
+	checker.Register("controller2")
+	queueMetrics.depth.WithLabelValues("controller2").Set(1)
+	now = start.Add(time.Hour)
+
+	cases := []struct {
+		name         string
+		path         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "all checks",
+			path:         Path,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "[+]controller1 ok\n[-]controller2 failed: no key is processed for 1h0m0s",
+		},
+		{
+			name:         "healthy controller",
+			path:         Path + "/controller1",
+			expectedCode: http.StatusOK,
+			expectedBody: "ok",
+		},
+		{
+			name:         "stuck controller",
+			path:         Path + "/controller2",
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "[-]controller2 failed",
+		},
+		{
+			name:         "unknown controller",
+			path:         Path + "/controller3",
+			expectedCode: http.StatusNotFound,
+			expectedBody: "controller \"controller3\" is not found",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			checker.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, c.path, nil))
+			if recorder.Code != c.expectedCode {
+				t.Errorf("expected code %d, but got %d", c.expectedCode, recorder.Code)
+			}
+			if !strings.HasPrefix(recorder.Body.String(), c.expectedBody) {
+				t.Errorf("expected body with prefix %q, but got %q", c.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package health
+
+import (
+	"context"
+	"sync"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/client-go/tools/cache"
+)
+
+// priorityQueueSuffix is the suffix of the name of the priority queue of a controller
+const priorityQueueSuffix = "Priority"
+
+// priorityController is a controller with two queues, see NewPriorityController
+type priorityController struct {
+	factory.Controller
+	priority factory.Controller
+}
+
+// NewPriorityController returns a controller syncing the keys of the objects which match isPriority with a queue of
+// their own, e.g. the clusters joining the hub, so they are not delayed behind thousands of other keys queued by the
+// initial list after a restart or by the resyncs of the informers. The events of the informers are routed by
+// withInformers, which adds the informers to a factory with the given filter, and both queues are run with the same
+// number of workers. The priority queue is named with the suffix "Priority" and checked as a controller as well.
+// A key is never synced by both queues at the same time, the key moved from a queue to the other one while it is
+// being synced waits for the running sync. The keys of the other objects are warmed up with the optional warmUp.
+func NewPriorityController(name string, recorder events.Recorder, isPriority factory.EventFilterFunc,
+	withInformers func(f *factory.Factory, filter factory.EventFilterFunc) *factory.Factory,
+	sync factory.SyncFunc, warmUp *WarmUp) factory.Controller {
+	sync = newKeyLocks().serialize(sync)
+	isPriorityObject := func(obj interface{}) bool {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		return isPriority(obj)
+	}
+
+	var filter factory.EventFilterFunc = func(obj interface{}) bool {
+		return !isPriorityObject(obj)
+	}
+	f := factory.New()
+	if warmUp != nil {
+		filter = warmUp.Filter(filter)
+		f = f.WithPostStartHooks(warmUp.Run)
+	}
+
+	return &priorityController{
+		Controller: withInformers(f, filter).WithSync(WrapSync(name, sync)).ToController(name, recorder),
+		priority: withInformers(factory.New(), isPriorityObject).
+			WithSync(WrapSync(name, sync)).ToController(name+priorityQueueSuffix, recorder),
+	}
+}
+
+// Run runs both queues of the controller until the context is done
+func (c *priorityController) Run(ctx context.Context, workers int) {
+	go RunController(ctx, c.priority, workers)
+	c.Controller.Run(ctx, workers)
+}
+
+// keyLocks serializes the syncs of the same key across the queues of a controller
+type keyLocks struct {
+	lock  sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	// refs is the number of the syncs holding or waiting for the lock
+	refs int
+}
+
+func newKeyLocks() *keyLocks {
+	return &keyLocks{locks: map[string]*keyLock{}}
+}
+
+// serialize wraps a sync function, the syncs of the same key are run one at a time
+func (l *keyLocks) serialize(sync factory.SyncFunc) factory.SyncFunc {
+	return func(ctx context.Context, syncCtx factory.SyncContext) error {
+		key := syncCtx.QueueKey()
+		l.acquire(key)
+		defer l.release(key)
+		return sync(ctx, syncCtx)
+	}
+}
+
+func (l *keyLocks) acquire(key string) {
+	l.lock.Lock()
+	kl, ok := l.locks[key]
+	if !ok {
+		kl = &keyLock{}
+		l.locks[key] = kl
+	}
+	kl.refs++
+	l.lock.Unlock()
+
+	kl.Lock()
+}
+
+func (l *keyLocks) release(key string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	kl := l.locks[key]
+	kl.Unlock()
+	kl.refs--
+	if kl.refs == 0 {
+		delete(l.locks, key)
+	}
+}
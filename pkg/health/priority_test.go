@@ -0,0 +1,102 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestPriorityController(t *testing.T) {
+	kubeClient := fakekube.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "steady"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "joining", Labels: map[string]string{"joining": "true"}}},
+	)
+	kubeInformers := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+	namespaceInformer := kubeInformers.Core().V1().Namespaces().Informer()
+
+	release := make(chan struct{})
+	synced := make(chan string, 10)
+	ctrl := NewPriorityController("TestController", eventstesting.NewTestingEventRecorder(t),
+		func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			return err == nil && accessor.GetLabels()["joining"] == "true"
+		},
+		func(f *factory.Factory, filter factory.EventFilterFunc) *factory.Factory {
+			return f.WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+				accessor, _ := meta.Accessor(obj)
+				return accessor.GetName()
+			}, filter, namespaceInformer)
+		},
+		func(ctx context.Context, syncCtx factory.SyncContext) error {
+			// the steady keys are blocked until they are released
+			if syncCtx.QueueKey() == "steady" {
+				<-release
+			}
+			synced <- syncCtx.QueueKey()
+			return nil
+		}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer close(release)
+	kubeInformers.Start(ctx.Done())
+	go ctrl.Run(ctx, 1)
+
+	select {
+	case key := <-synced:
+		if key != "joining" {
+			t.Errorf("expected the joining key to be synced first, but got %q", key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the joining key is not synced while the steady key is being synced")
+	}
+}
+
+func TestKeyLocks(t *testing.T) {
+	locks := newKeyLocks()
+	running := map[string]int{}
+	var lock sync.Mutex
+	syncFunc := locks.serialize(func(ctx context.Context, syncCtx factory.SyncContext) error {
+		lock.Lock()
+		running[syncCtx.QueueKey()]++
+		if running[syncCtx.QueueKey()] > 1 {
+			t.Errorf("key %q is synced concurrently", syncCtx.QueueKey())
+		}
+		lock.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		lock.Lock()
+		running[syncCtx.QueueKey()]--
+		lock.Unlock()
+		return nil
+	})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		for _, key := range []string{"key1", "key2"} {
+			wg.Add(1)
+			go func(key string) {
+				defer wg.Done()
+				_ = syncFunc(context.Background(), testinghelpers.NewFakeSyncContext(t, key))
+			}(key)
+		}
+	}
+	wg.Wait()
+
+	if len(locks.locks) != 0 {
+		t.Errorf("expected the locks to be released, but got %v", locks.locks)
+	}
+}
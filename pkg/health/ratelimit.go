@@ -0,0 +1,121 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"golang.org/x/time/rate"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// The default limits of the retries of the failed keys, they are the limits of workqueue.DefaultControllerRateLimiter
+const (
+	DefaultRetryBaseDelay = 5 * time.Millisecond
+	DefaultRetryMaxDelay  = 1000 * time.Second
+	DefaultRetryQPS       = 10
+)
+
+// retryBurstSeconds is the number of seconds of retries allowed in a burst by the overall limit of a controller
+const retryBurstSeconds = 10
+
+// retryLimits are the limits of the retries of the failed keys of the controllers
+type retryLimits struct {
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	qps       float64
+}
+
+var (
+	retryLimitsLock sync.Mutex
+	currentLimits   = retryLimits{baseDelay: DefaultRetryBaseDelay, maxDelay: DefaultRetryMaxDelay, qps: DefaultRetryQPS}
+	// limitsGeneration is increased whenever the limits are set, so that the controllers rebuild their rate limiters
+	limitsGeneration int64
+)
+
+// SetRetryLimits sets the limits of the retries of the failed keys of the controllers. A failed key is retried after
+// a delay growing exponentially from the base delay up to the max delay, and the retries of all of the keys of a
+// controller are limited to qps. The default of a limit is used if it is not positive. The limits may be changed
+// while the controllers run, a controller applies the new limits from its next sync, and the failures counted with
+// the old limits are forgotten.
+func SetRetryLimits(baseDelay, maxDelay time.Duration, qps float64) {
+	if baseDelay <= 0 {
+		baseDelay = DefaultRetryBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryMaxDelay
+	}
+	if qps <= 0 {
+		qps = DefaultRetryQPS
+	}
+	retryLimitsLock.Lock()
+	defer retryLimitsLock.Unlock()
+	newLimits := retryLimits{baseDelay: baseDelay, maxDelay: maxDelay, qps: qps}
+	if newLimits == currentLimits {
+		return
+	}
+	currentLimits = newLimits
+	limitsGeneration++
+}
+
+// newRetryRateLimiter returns a rate limiter of the retries with the current limits, and the generation of the limits
+func newRetryRateLimiter() (workqueue.RateLimiter, int64) {
+	retryLimitsLock.Lock()
+	limits, generation := currentLimits, limitsGeneration
+	retryLimitsLock.Unlock()
+
+	burst := int(limits.qps * retryBurstSeconds)
+	if burst < 1 {
+		burst = 1
+	}
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(limits.baseDelay, limits.maxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(limits.qps), burst)},
+	), generation
+}
+
+// retryLimitsChanged returns true if the limits are set again since the generation
+func retryLimitsChanged(generation int64) bool {
+	retryLimitsLock.Lock()
+	defer retryLimitsLock.Unlock()
+	return limitsGeneration != generation
+}
+
+// LimitRetries wraps the sync function of a controller, a failed key is requeued with the delay of a rate limiter
+// built with the retry limits. The rate limiter of the controller queue created by library-go is not configurable,
+// so the failed key is requeued by the wrapper and the sync is reported successful to the queue. The error is
+// logged in the same way as the controller does.
+func LimitRetries(controller string, syncFunc factory.SyncFunc) factory.SyncFunc {
+	var lock sync.Mutex
+	var limiter workqueue.RateLimiter
+	var generation int64
+	return func(ctx context.Context, syncCtx factory.SyncContext) error {
+		lock.Lock()
+		if limiter == nil || retryLimitsChanged(generation) {
+			limiter, generation = newRetryRateLimiter()
+		}
+		limiter := limiter
+		lock.Unlock()
+
+		key := syncCtx.QueueKey()
+		err := syncFunc(ctx, syncCtx)
+		if err == nil {
+			limiter.Forget(key)
+			return nil
+		}
+		if err != factory.SyntheticRequeueError {
+			utilruntime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controller, key, err))
+		}
+		syncCtx.Queue().AddAfter(key, limiter.When(key))
+		return nil
+	}
+}
+
+// WrapSync wraps the sync function of a controller with LimitRetries and ReportSlowSync, all of the controllers of
+// the hub and the agent are built with it.
+func WrapSync(controller string, sync factory.SyncFunc) factory.SyncFunc {
+	return LimitRetries(controller, ReportSlowSync(controller, sync))
+}
@@ -0,0 +1,93 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestLimitRetries(t *testing.T) {
+	defer SetRetryLimits(DefaultRetryBaseDelay, DefaultRetryMaxDelay, DefaultRetryQPS)
+
+	cases := []struct {
+		name            string
+		baseDelay       time.Duration
+		syncErr         error
+		expectedRequeue bool
+	}{
+		{
+			name:      "successful sync",
+			baseDelay: time.Millisecond,
+		},
+		{
+			name:            "failed sync",
+			baseDelay:       time.Millisecond,
+			syncErr:         errors.New("failed"),
+			expectedRequeue: true,
+		},
+		{
+			name:            "synthetic requeue",
+			baseDelay:       time.Millisecond,
+			syncErr:         factory.SyntheticRequeueError,
+			expectedRequeue: true,
+		},
+		{
+			name:      "failed sync with a long base delay",
+			baseDelay: time.Hour,
+			syncErr:   errors.New("failed"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			SetRetryLimits(c.baseDelay, 0, 0)
+			syncCtx := testinghelpers.NewFakeSyncContext(t, "key1")
+
+			sync := LimitRetries("controller1", func(ctx context.Context, syncCtx factory.SyncContext) error {
+				return c.syncErr
+			})
+			if err := sync(context.TODO(), syncCtx); err != nil {
+				t.Fatalf("expected the error to be handled, but got %v", err)
+			}
+
+			// wait for the delay of the retry
+			time.Sleep(50 * time.Millisecond)
+			if requeued := syncCtx.Queue().Len() > 0; requeued != c.expectedRequeue {
+				t.Errorf("expected requeued %v, but got %v", c.expectedRequeue, requeued)
+			}
+		})
+	}
+}
+
+func TestLimitRetriesWithChangedLimits(t *testing.T) {
+	defer SetRetryLimits(DefaultRetryBaseDelay, DefaultRetryMaxDelay, DefaultRetryQPS)
+
+	SetRetryLimits(time.Hour, 0, 0)
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "key1")
+	sync := LimitRetries("controller1", func(ctx context.Context, syncCtx factory.SyncContext) error {
+		return errors.New("failed")
+	})
+
+	if err := sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("expected the error to be handled, but got %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if syncCtx.Queue().Len() > 0 {
+		t.Errorf("expected the key not to be requeued with the long base delay")
+	}
+
+	// the new limits are applied by the running controller
+	SetRetryLimits(time.Millisecond, 0, 0)
+	if err := sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("expected the error to be handled, but got %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if syncCtx.Queue().Len() == 0 {
+		t.Errorf("expected the key to be requeued with the new base delay")
+	}
+}
@@ -0,0 +1,27 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+)
+
+// ResyncEvery returns a post start hook of a controller which queues the default key of the controller right after
+// it starts and then every interval, like ResyncEvery of the controller factory. The interval is read again after
+// each resync, so it may be changed while the controller runs.
+func ResyncEvery(interval func() time.Duration) factory.PostStartHook {
+	return func(ctx context.Context, syncCtx factory.SyncContext) error {
+		for {
+			syncCtx.Queue().Add(factory.DefaultQueueKey)
+
+			timer := time.NewTimer(interval())
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package health
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestResyncEvery(t *testing.T) {
+	interval := int64(5 * time.Millisecond)
+	syncCtx := testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey)
+	ctx, cancel := context.WithCancel(context.TODO())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := ResyncEvery(func() time.Duration {
+			return time.Duration(atomic.LoadInt64(&interval))
+		})(ctx, syncCtx); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}()
+	drain := func() int {
+		resyncs := 0
+		for syncCtx.Queue().Len() > 0 {
+			key, _ := syncCtx.Queue().Get()
+			syncCtx.Queue().Done(key)
+			resyncs++
+		}
+		return resyncs
+	}
+
+	// the key is queued once the controller starts and then every interval
+	for i := 0; i < 3; i++ {
+		key, _ := syncCtx.Queue().Get()
+		syncCtx.Queue().Done(key)
+		if key != factory.DefaultQueueKey {
+			t.Errorf("expected key %q, but got %q", factory.DefaultQueueKey, key)
+		}
+	}
+
+	// the changed interval is applied once the current one elapses
+	atomic.StoreInt64(&interval, int64(time.Hour))
+	time.Sleep(20 * time.Millisecond)
+	drain()
+	time.Sleep(50 * time.Millisecond)
+	if resyncs := drain(); resyncs > 0 {
+		t.Errorf("expected no resync with the changed interval, but got %d", resyncs)
+	}
+
+	cancel()
+	<-done
+}
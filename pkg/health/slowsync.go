@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// DefaultSlowSyncThreshold is the default duration after which a sync of a controller is reported as slow
+const DefaultSlowSyncThreshold = 10 * time.Second
+
+// defaultPhase is the phase of a sync before the sync enters any phase
+const defaultPhase = "sync"
+
+var slowSyncThreshold = int64(DefaultSlowSyncThreshold)
+
+var slowSyncs = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Name: "open_cluster_management_registration_slow_syncs_total",
+		Help: "Number of the syncs of the controllers which take longer than the slow sync threshold, partitioned by controller and the phase the sync spent most of its time in.",
+	},
+	[]string{"controller", "phase"},
+)
+
+func init() {
+	legacyregistry.MustRegister(slowSyncs)
+}
+
+// SetSlowSyncThreshold sets the duration after which a sync is reported as slow, the DefaultSlowSyncThreshold is
+// used if it is not positive.
+func SetSlowSyncThreshold(threshold time.Duration) {
+	if threshold <= 0 {
+		threshold = DefaultSlowSyncThreshold
+	}
+	atomic.StoreInt64(&slowSyncThreshold, int64(threshold))
+}
+
+type syncPhasesKey struct{}
+
+// syncPhases tracks the time a sync spends in its phases
+type syncPhases struct {
+	lock      sync.Mutex
+	current   string
+	started   time.Time
+	durations map[string]time.Duration
+}
+
+func (p *syncPhases) enter(phase string, now time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.durations[p.current] += now.Sub(p.started)
+	p.current, p.started = phase, now
+}
+
+// finish ends the current phase and returns the phase the sync spent most of its time in
+func (p *syncPhases) finish(now time.Time) (string, time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.durations[p.current] += now.Sub(p.started)
+
+	slowest := p.current
+	for phase, duration := range p.durations {
+		if duration > p.durations[slowest] {
+			slowest = phase
+		}
+	}
+	return slowest, p.durations[slowest]
+}
+
+// EnterPhase marks the start of a phase of the sync running with the context, e.g. "approve" or "update status",
+// the previous phase ends at the same time. It does nothing if the sync is not wrapped by ReportSlowSync.
+func EnterPhase(ctx context.Context, phase string) {
+	if phases, ok := ctx.Value(syncPhasesKey{}).(*syncPhases); ok {
+		phases.enter(phase, time.Now())
+	}
+}
+
+// ReportSlowSync wraps the sync function of a controller, the sync is logged with its queue key and the phase it
+// spent most of its time in, and counted in a metric, if it takes longer than the slow sync threshold.
+func ReportSlowSync(controller string, sync factory.SyncFunc) factory.SyncFunc {
+	return func(ctx context.Context, syncCtx factory.SyncContext) error {
+		start := time.Now()
+		phases := &syncPhases{current: defaultPhase, started: start, durations: map[string]time.Duration{}}
+		err := sync(context.WithValue(ctx, syncPhasesKey{}, phases), syncCtx)
+
+		now := time.Now()
+		elapsed := now.Sub(start)
+		if elapsed <= time.Duration(atomic.LoadInt64(&slowSyncThreshold)) {
+			return err
+		}
+
+		phase, phaseDuration := phases.finish(now)
+		slowSyncs.WithLabelValues(controller, phase).Inc()
+		helpers.ControllerLogger(ctx, controller).Info("Sync is slow",
+			helpers.LogKeyResource, syncCtx.QueueKey(), helpers.LogKeyPhase, phase,
+			"duration", elapsed.Round(time.Millisecond), "phaseDuration", phaseDuration.Round(time.Millisecond))
+		return err
+	}
+}
@@ -0,0 +1,78 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"k8s.io/component-base/metrics/testutil"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestReportSlowSync(t *testing.T) {
+	defer SetSlowSyncThreshold(DefaultSlowSyncThreshold)
+
+	cases := []struct {
+		name          string
+		threshold     time.Duration
+		phases        map[string]time.Duration
+		expectedPhase string
+	}{
+		{
+			name:      "fast sync",
+			threshold: time.Minute,
+			phases:    map[string]time.Duration{"approve": time.Millisecond},
+		},
+		{
+			name:          "slow sync without phases",
+			threshold:     time.Millisecond,
+			phases:        map[string]time.Duration{},
+			expectedPhase: defaultPhase,
+		},
+		{
+			name:          "slow phase",
+			threshold:     time.Millisecond,
+			phases:        map[string]time.Duration{"approve": time.Millisecond, "update approval": 50 * time.Millisecond},
+			expectedPhase: "update approval",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			SetSlowSyncThreshold(c.threshold)
+			slowSyncs.Reset()
+
+			sync := ReportSlowSync("controller1", func(ctx context.Context, syncCtx factory.SyncContext) error {
+				if len(c.phases) == 0 {
+					time.Sleep(10 * time.Millisecond)
+				}
+				for _, phase := range []string{"approve", "update approval"} {
+					if duration, ok := c.phases[phase]; ok {
+						EnterPhase(ctx, phase)
+						time.Sleep(duration)
+					}
+				}
+				return nil
+			})
+			if err := sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "key1")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, phase := range []string{defaultPhase, "approve", "update approval"} {
+				expected := 0.0
+				if phase == c.expectedPhase {
+					expected = 1
+				}
+				count, err := testutil.GetCounterMetricValue(slowSyncs.WithLabelValues("controller1", phase))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if count != expected {
+					t.Errorf("expected %v slow syncs in phase %q, but got %v", expected, phase, count)
+				}
+			}
+		})
+	}
+}
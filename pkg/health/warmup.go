@@ -0,0 +1,166 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+)
+
+// The default size and interval of the batches the initial keys of a controller are queued in during its warm-up
+const (
+	DefaultWarmUpBatchSize     = 100
+	DefaultWarmUpBatchInterval = time.Second
+)
+
+var (
+	warmUpLock          sync.Mutex
+	warmUpBatchSize     = DefaultWarmUpBatchSize
+	warmUpBatchInterval = DefaultWarmUpBatchInterval
+)
+
+// SetWarmUpBatches sets the size and the interval of the batches the initial keys of the controllers are queued in
+// during their warm-up, the default of a setting is used if it is not positive. The settings apply to the controllers
+// which have not started yet.
+func SetWarmUpBatches(batchSize int, interval time.Duration) {
+	if batchSize <= 0 {
+		batchSize = DefaultWarmUpBatchSize
+	}
+	if interval <= 0 {
+		interval = DefaultWarmUpBatchInterval
+	}
+	warmUpLock.Lock()
+	defer warmUpLock.Unlock()
+	warmUpBatchSize, warmUpBatchInterval = batchSize, interval
+}
+
+// WarmUp spreads the initial sync of the objects of a controller over time, so a restarted hub with a large fleet
+// does not sync all of the objects at once. The events received until the controller is started are held, and the
+// keys of the objects are queued in batches once the caches are synced, the objects which have not been synced for
+// the longest time first. The events of a key waiting for its batch are dropped, and the other events pass through.
+//
+// The event filters of the informers of the controller are wrapped by Filter, and Run is added as a post start hook
+// of the controller.
+type WarmUp struct {
+	list       func() ([]runtime.Object, error)
+	keyFunc    factory.ObjectQueueKeyFunc
+	lastSynced func(obj runtime.Object) time.Time
+
+	lock sync.Mutex
+	// started is true once the objects are listed after the caches are synced
+	started bool
+	// done is true once all of the keys are queued
+	done bool
+	// pending are the keys waiting for their batches
+	pending sets.String
+}
+
+// NewWarmUp returns a WarmUp queueing the keys of the listed objects. The objects are ordered by the last time they
+// were synced, which is read from the objects, e.g. the last transition time of their conditions.
+func NewWarmUp(list func() ([]runtime.Object, error), keyFunc factory.ObjectQueueKeyFunc,
+	lastSynced func(obj runtime.Object) time.Time) *WarmUp {
+	return &WarmUp{
+		list:       list,
+		keyFunc:    keyFunc,
+		lastSynced: lastSynced,
+		pending:    sets.NewString(),
+	}
+}
+
+// Filter wraps the event filter of an informer of the controller, the filter is optional. The objects of all of the
+// informers are expected to be queued with the key function of the WarmUp.
+func (w *WarmUp) Filter(filter factory.EventFilterFunc) factory.EventFilterFunc {
+	return func(obj interface{}) bool {
+		if filter != nil && !filter(obj) {
+			return false
+		}
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		runtimeObj, ok := obj.(runtime.Object)
+		if !ok {
+			return true
+		}
+
+		w.lock.Lock()
+		defer w.lock.Unlock()
+		if w.done {
+			return true
+		}
+		key := w.keyFunc(runtimeObj)
+		if !w.started {
+			w.pending.Insert(key)
+			return false
+		}
+		return !w.pending.Has(key)
+	}
+}
+
+// Run queues the keys in batches until all of the keys are queued or the context is done, it is a post start hook
+// of the controller.
+func (w *WarmUp) Run(ctx context.Context, syncCtx factory.SyncContext) error {
+	warmUpLock.Lock()
+	batchSize, interval := warmUpBatchSize, warmUpBatchInterval
+	warmUpLock.Unlock()
+
+	objs, err := w.list()
+	keys := w.start(objs)
+	if err != nil {
+		// sync all of the held keys at once if the objects are not listed
+		w.queue(syncCtx, keys, true)
+		return err
+	}
+
+	for len(keys) > batchSize {
+		w.queue(syncCtx, keys[:batchSize], false)
+		keys = keys[batchSize:]
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+	w.queue(syncCtx, keys, true)
+	return nil
+}
+
+// start marks the listed objects pending and returns all of the pending keys in order. The keys of the held events
+// whose objects are not listed, e.g. the deleted objects, are the first, and the others are ordered by the last time
+// their objects were synced.
+func (w *WarmUp) start(objs []runtime.Object) []string {
+	sort.SliceStable(objs, func(i, j int) bool {
+		return w.lastSynced(objs[i]).Before(w.lastSynced(objs[j]))
+	})
+	listed := []string{}
+	for _, obj := range objs {
+		listed = append(listed, w.keyFunc(obj))
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	keys := append(w.pending.Difference(sets.NewString(listed...)).List(), listed...)
+	w.pending.Insert(listed...)
+	w.started = true
+	return keys
+}
+
+// queue queues the keys which are still pending, the warm-up is done with the last batch
+func (w *WarmUp) queue(syncCtx factory.SyncContext, keys []string, last bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, key := range keys {
+		if w.pending.Has(key) {
+			w.pending.Delete(key)
+			syncCtx.Queue().Add(key)
+		}
+	}
+	if last {
+		w.pending = sets.NewString()
+		w.done = true
+	}
+}
@@ -0,0 +1,78 @@
+package health
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func newNamespace(name string, lastSynced time.Time) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(lastSynced)}}
+}
+
+func TestWarmUp(t *testing.T) {
+	defer SetWarmUpBatches(DefaultWarmUpBatchSize, DefaultWarmUpBatchInterval)
+	SetWarmUpBatches(2, 10*time.Millisecond)
+
+	now := time.Now()
+	objs := []runtime.Object{
+		newNamespace("ns1", now),
+		newNamespace("ns2", now.Add(-3*time.Hour)),
+		newNamespace("ns3", now.Add(-time.Hour)),
+		newNamespace("ns4", now.Add(-2*time.Hour)),
+	}
+	warmUp := NewWarmUp(
+		func() ([]runtime.Object, error) {
+			return objs, nil
+		},
+		func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		},
+		func(obj runtime.Object) time.Time {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetCreationTimestamp().Time
+		},
+	)
+	filter := warmUp.Filter(nil)
+
+	// the events are held until the warm-up starts
+	for _, obj := range objs {
+		if filter(obj) {
+			t.Errorf("expected the event of %v to be held", obj)
+		}
+	}
+	if filter(cache.DeletedFinalStateUnknown{Key: "ns0", Obj: newNamespace("ns0", now)}) {
+		t.Errorf("expected the event of the deleted ns0 to be held")
+	}
+
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "")
+	if err := warmUp.Run(context.Background(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the deleted object is queued first and the others are ordered by the last time they were synced
+	keys := []string{}
+	for syncCtx.Queue().Len() > 0 {
+		key, _ := syncCtx.Queue().Get()
+		keys = append(keys, key.(string))
+		syncCtx.Queue().Done(key)
+	}
+	if expected := []string{"ns0", "ns2", "ns4", "ns3", "ns1"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected keys %v, but got %v", expected, keys)
+	}
+
+	// the events pass through once the warm-up is done
+	if !filter(newNamespace("ns5", now)) {
+		t.Errorf("expected the event to pass through after the warm-up")
+	}
+}
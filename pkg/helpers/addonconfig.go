@@ -0,0 +1,94 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+)
+
+// The keys of the addon registration configuration
+const (
+	AddOnRegistrationConfigSignerName        = "signerName"
+	AddOnRegistrationConfigUser              = "user"
+	AddOnRegistrationConfigGroups            = "groups"
+	AddOnRegistrationConfigOrganizationUnits = "organizationUnits"
+	AddOnRegistrationConfigSecretName        = "secretName"
+	AddOnRegistrationConfigRotationThreshold = "rotationThreshold"
+)
+
+// AddOnRegistrationAutoApproveAnnotation is the annotation of a ClusterManagementAddOn, with which the hub cluster
+// admin allows the registration hub controller to approve the csrs of the addon with the kube-apiserver-client
+// signer. Only the csrs requesting the identities of the addon, see AddOnDefaultGroup, are approved.
+const AddOnRegistrationAutoApproveAnnotation = "addon.open-cluster-management.io/registration-auto-approve"
+
+// AddOnRegistrationConfig is the registration configuration of an addon set on the hub by the hub cluster
+// admin. It is stored in a configmap named "{addon name}-registration-config" in the managed cluster namespace,
+// which the registration agent is only able to read, and once it exists, it takes precedence over the
+// registrations declared in the status of the addon. The csrs of the addon are not approved with it, see
+// AddOnRegistrationAutoApproveAnnotation.
+type AddOnRegistrationConfig struct {
+	// Registration is the registration of the addon. The signer name is "kubernetes.io/kube-apiserver-client"
+	// if it is not set.
+	Registration addonv1alpha1.RegistrationConfig
+	// SecretName is the name of the secret containing the credential on the managed cluster. The default
+	// secret name is used if it is empty.
+	SecretName string
+	// RotationThreshold is the ratio of the certificate lifetime remaining at which the client certificate is
+	// rotated. The default threshold is used if it is zero.
+	RotationThreshold float64
+}
+
+// AddOnRegistrationConfigName returns the name of the configmap containing the registration configuration of an addon
+func AddOnRegistrationConfigName(addOnName string) string {
+	return fmt.Sprintf("%s-registration-config", addOnName)
+}
+
+// ParseAddOnRegistrationConfig parses the registration configuration of an addon from the configmap
+func ParseAddOnRegistrationConfig(configMap *corev1.ConfigMap) (*AddOnRegistrationConfig, error) {
+	config := &AddOnRegistrationConfig{
+		Registration: addonv1alpha1.RegistrationConfig{
+			SignerName: configMap.Data[AddOnRegistrationConfigSignerName],
+			Subject: addonv1alpha1.Subject{
+				User:              configMap.Data[AddOnRegistrationConfigUser],
+				Groups:            splitList(configMap.Data[AddOnRegistrationConfigGroups]),
+				OrganizationUnits: splitList(configMap.Data[AddOnRegistrationConfigOrganizationUnits]),
+			},
+		},
+		SecretName: configMap.Data[AddOnRegistrationConfigSecretName],
+	}
+	if len(config.Registration.SignerName) == 0 {
+		config.Registration.SignerName = certificatesv1.KubeAPIServerClientSignerName
+	}
+
+	if value, ok := configMap.Data[AddOnRegistrationConfigRotationThreshold]; ok {
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", AddOnRegistrationConfigRotationThreshold, value, err)
+		}
+		if threshold <= 0 || threshold >= 1 {
+			return nil, fmt.Errorf("invalid %s %q: it must be between 0 and 1", AddOnRegistrationConfigRotationThreshold, value)
+		}
+		config.RotationThreshold = threshold
+	}
+
+	return config, nil
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return items
+}
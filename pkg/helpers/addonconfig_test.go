@@ -0,0 +1,82 @@
+package helpers
+
+import (
+	"reflect"
+	"testing"
+
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseAddOnRegistrationConfig(t *testing.T) {
+	cases := []struct {
+		name           string
+		data           map[string]string
+		expectedErr    bool
+		expectedConfig *AddOnRegistrationConfig
+	}{
+		{
+			name: "empty configuration",
+			data: map[string]string{},
+			expectedConfig: &AddOnRegistrationConfig{
+				Registration: addonv1alpha1.RegistrationConfig{
+					SignerName: certificatesv1.KubeAPIServerClientSignerName,
+				},
+			},
+		},
+		{
+			name: "full configuration",
+			data: map[string]string{
+				AddOnRegistrationConfigSignerName:        "signer1",
+				AddOnRegistrationConfigUser:              "user1",
+				AddOnRegistrationConfigGroups:            "group1, group2",
+				AddOnRegistrationConfigOrganizationUnits: "ou1",
+				AddOnRegistrationConfigSecretName:        "secret1",
+				AddOnRegistrationConfigRotationThreshold: "0.5",
+			},
+			expectedConfig: &AddOnRegistrationConfig{
+				Registration: addonv1alpha1.RegistrationConfig{
+					SignerName: "signer1",
+					Subject: addonv1alpha1.Subject{
+						User:              "user1",
+						Groups:            []string{"group1", "group2"},
+						OrganizationUnits: []string{"ou1"},
+					},
+				},
+				SecretName:        "secret1",
+				RotationThreshold: 0.5,
+			},
+		},
+		{
+			name: "invalid rotation threshold",
+			data: map[string]string{
+				AddOnRegistrationConfigRotationThreshold: "abc",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "rotation threshold out of range",
+			data: map[string]string{
+				AddOnRegistrationConfigRotationThreshold: "1.5",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config, err := ParseAddOnRegistrationConfig(&corev1.ConfigMap{Data: c.data})
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but got nil")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(config, c.expectedConfig) {
+				t.Errorf("expected %#v, but got %#v", c.expectedConfig, config)
+			}
+		})
+	}
+}
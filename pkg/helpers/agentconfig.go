@@ -0,0 +1,87 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	cliflag "k8s.io/component-base/cli/flag"
+	"k8s.io/utils/pointer"
+)
+
+// AgentConfigConfigMapName is the name of the configmap of the agent settings pushed from the hub. The hub admin
+// tunes the agents of the fleet with the configmap in the namespace of the hub controller, and the hub controller
+// publishes the settings of each cluster in the configmap with the same name in the managed cluster namespace,
+// which is watched by the agent.
+const AgentConfigConfigMapName = "registration-agent-config"
+
+// AgentConfigAnnotationPrefix is the prefix of the annotations of a managed cluster overriding the fleet-wide
+// agent settings for the cluster, e.g. "agent-config.open-cluster-management.io/logLevel: 4".
+const AgentConfigAnnotationPrefix = "agent-config.open-cluster-management.io/"
+
+// The keys of the agent settings in the data of the agent config configmap and in the annotations of a managed
+// cluster. AgentConfigFeatureGatesKey is a comma separated list of feature=bool pairs.
+const (
+	AgentConfigLogLevelKey             = "logLevel"
+	AgentConfigFeatureGatesKey         = "featureGates"
+	AgentConfigLeaseDurationSecondsKey = "leaseDurationSeconds"
+)
+
+var agentConfigKeys = sets.NewString(AgentConfigLogLevelKey, AgentConfigFeatureGatesKey, AgentConfigLeaseDurationSecondsKey)
+
+// AgentSettings are the agent settings pushed from the hub, the settings not set are nil
+type AgentSettings struct {
+	LogLevel             *int32
+	FeatureGates         map[string]bool
+	LeaseDurationSeconds *int32
+}
+
+// ParseAgentSettings parses the agent settings in the data of an agent config configmap
+func ParseAgentSettings(data map[string]string) (*AgentSettings, error) {
+	settings := &AgentSettings{}
+	for _, key := range sets.StringKeySet(data).List() {
+		if !agentConfigKeys.Has(key) {
+			return nil, fmt.Errorf("unknown agent setting %q", key)
+		}
+	}
+
+	if value, ok := data[AgentConfigLogLevelKey]; ok {
+		level, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || level < 0 {
+			return nil, fmt.Errorf("invalid %s %q: must be a non-negative integer", AgentConfigLogLevelKey, value)
+		}
+		settings.LogLevel = pointer.Int32(int32(level))
+	}
+
+	if value, ok := data[AgentConfigFeatureGatesKey]; ok {
+		gates := cliflag.NewMapStringBool(&settings.FeatureGates)
+		if err := gates.Set(strings.TrimSpace(value)); err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", AgentConfigFeatureGatesKey, value, err)
+		}
+	}
+
+	if value, ok := data[AgentConfigLeaseDurationSecondsKey]; ok {
+		seconds, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid %s %q: must be a positive integer", AgentConfigLeaseDurationSecondsKey, value)
+		}
+		settings.LeaseDurationSeconds = pointer.Int32(int32(seconds))
+	}
+	return settings, nil
+}
+
+// AgentConfigData returns the data of the agent config configmap of a managed cluster, which is the fleet-wide
+// settings overridden with the agent config annotations of the cluster
+func AgentConfigData(fleet map[string]string, clusterAnnotations map[string]string) map[string]string {
+	data := map[string]string{}
+	for key, value := range fleet {
+		data[key] = value
+	}
+	for key, value := range clusterAnnotations {
+		if strings.HasPrefix(key, AgentConfigAnnotationPrefix) {
+			data[strings.TrimPrefix(key, AgentConfigAnnotationPrefix)] = value
+		}
+	}
+	return data
+}
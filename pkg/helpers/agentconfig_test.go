@@ -0,0 +1,86 @@
+package helpers
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/utils/pointer"
+)
+
+func TestParseAgentSettings(t *testing.T) {
+	cases := []struct {
+		name             string
+		data             map[string]string
+		expectedSettings *AgentSettings
+		expectedErr      string
+	}{
+		{
+			name:             "no settings",
+			expectedSettings: &AgentSettings{},
+		},
+		{
+			name: "all settings",
+			data: map[string]string{
+				AgentConfigLogLevelKey:             "4",
+				AgentConfigFeatureGatesKey:         "V1beta1CSRAPICompatibility=true, AddonManagement=false",
+				AgentConfigLeaseDurationSecondsKey: "120",
+			},
+			expectedSettings: &AgentSettings{
+				LogLevel:             pointer.Int32(4),
+				FeatureGates:         map[string]bool{"V1beta1CSRAPICompatibility": true, "AddonManagement": false},
+				LeaseDurationSeconds: pointer.Int32(120),
+			},
+		},
+		{
+			name:        "unknown setting",
+			data:        map[string]string{"qps": "10"},
+			expectedErr: "unknown agent setting \"qps\"",
+		},
+		{
+			name:        "invalid log level",
+			data:        map[string]string{AgentConfigLogLevelKey: "-1"},
+			expectedErr: "invalid logLevel \"-1\": must be a non-negative integer",
+		},
+		{
+			name:        "invalid feature gates",
+			data:        map[string]string{AgentConfigFeatureGatesKey: "AddonManagement"},
+			expectedErr: "invalid featureGates \"AddonManagement\": malformed pair, expect string=bool",
+		},
+		{
+			name:        "invalid lease duration",
+			data:        map[string]string{AgentConfigLeaseDurationSecondsKey: "0"},
+			expectedErr: "invalid leaseDurationSeconds \"0\": must be a positive integer",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			settings, err := ParseAgentSettings(c.data)
+			if len(c.expectedErr) > 0 {
+				if err == nil || err.Error() != c.expectedErr {
+					t.Fatalf("expected error %q, but got %v", c.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(settings, c.expectedSettings) {
+				t.Errorf("expected settings %+v, but got %+v", c.expectedSettings, settings)
+			}
+		})
+	}
+}
+
+func TestAgentConfigData(t *testing.T) {
+	data := AgentConfigData(
+		map[string]string{AgentConfigLogLevelKey: "2", AgentConfigLeaseDurationSecondsKey: "60"},
+		map[string]string{
+			AgentConfigAnnotationPrefix + AgentConfigLogLevelKey: "6",
+			"open-cluster-management.io/other":                   "value",
+		},
+	)
+	expected := map[string]string{AgentConfigLogLevelKey: "6", AgentConfigLeaseDurationSecondsKey: "60"}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("expected data %v, but got %v", expected, data)
+	}
+}
@@ -0,0 +1,6 @@
+// Package clusterset holds the label of the ManagedClusterSets. It imports nothing, so both the helpers and the
+// testing helpers, which the tests of the helpers import, are able to share it.
+package clusterset
+
+// Label is the label of a ManagedCluster which specifies the ManagedClusterSet it belongs to
+const Label = "cluster.open-cluster-management.io/clusterset"
@@ -0,0 +1,84 @@
+package helpers
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/conversion"
+)
+
+// StatusTimeTolerance is the tolerance of the timestamps compared by StatusEqual. The timestamps are serialized
+// with the precision of a second, so a timestamp set in memory differs from the one read back from the apiserver
+// by less than a second even though it is not changed.
+const StatusTimeTolerance = time.Second
+
+var statusEquality conversion.Equalities
+
+func init() {
+	statusEquality = conversion.EqualitiesOrDie(
+		func(a, b resource.Quantity) bool {
+			return a.Cmp(b) == 0
+		},
+		func(a, b metav1.Time) bool {
+			return timeEqual(a.Time, b.Time)
+		},
+		func(a, b metav1.MicroTime) bool {
+			return timeEqual(a.Time, b.Time)
+		},
+		func(a, b []metav1.Condition) bool {
+			if len(a) != len(b) {
+				return false
+			}
+			a, b = sortedConditions(a), sortedConditions(b)
+			for i := range a {
+				if !statusEquality.DeepEqual(a[i], b[i]) {
+					return false
+				}
+			}
+			return true
+		},
+		func(a, b []clusterv1.ManagedClusterClaim) bool {
+			if len(a) != len(b) {
+				return false
+			}
+			return reflect.DeepEqual(sortedClaims(a), sortedClaims(b))
+		},
+	)
+}
+
+// StatusEqual returns whether two statuses are semantically equal, the status controllers skip the write of a
+// status which is equal to the current one. Besides the semantic equality of the apimachinery, the order of the
+// conditions and the cluster claims is ignored, and the timestamps are compared within StatusTimeTolerance, so a
+// resync writing the same status in a different order or with a re-generated timestamp does not touch the object.
+func StatusEqual(a, b interface{}) bool {
+	return statusEquality.DeepEqual(a, b)
+}
+
+func timeEqual(a, b time.Time) bool {
+	if a.IsZero() || b.IsZero() {
+		return a.IsZero() == b.IsZero()
+	}
+	diff := a.Sub(b)
+	return diff < StatusTimeTolerance && diff > -StatusTimeTolerance
+}
+
+func sortedConditions(conditions []metav1.Condition) []metav1.Condition {
+	sorted := append([]metav1.Condition{}, conditions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Type < sorted[j].Type
+	})
+	return sorted
+}
+
+func sortedClaims(claims []clusterv1.ManagedClusterClaim) []clusterv1.ManagedClusterClaim {
+	sorted := append([]clusterv1.ManagedClusterClaim{}, claims...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
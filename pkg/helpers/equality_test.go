@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStatusEqual(t *testing.T) {
+	transitionTime := metav1.NewTime(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	joined := metav1.Condition{Type: clusterv1.ManagedClusterConditionJoined, Status: metav1.ConditionTrue, LastTransitionTime: transitionTime}
+	available := metav1.Condition{Type: clusterv1.ManagedClusterConditionAvailable, Status: metav1.ConditionTrue, LastTransitionTime: transitionTime}
+
+	cases := []struct {
+		name     string
+		old      clusterv1.ManagedClusterStatus
+		new      clusterv1.ManagedClusterStatus
+		expected bool
+	}{
+		{
+			name:     "empty and nil conditions",
+			old:      clusterv1.ManagedClusterStatus{Conditions: []metav1.Condition{}},
+			new:      clusterv1.ManagedClusterStatus{},
+			expected: true,
+		},
+		{
+			name:     "conditions in a different order",
+			old:      clusterv1.ManagedClusterStatus{Conditions: []metav1.Condition{joined, available}},
+			new:      clusterv1.ManagedClusterStatus{Conditions: []metav1.Condition{available, joined}},
+			expected: true,
+		},
+		{
+			name: "transition time within the tolerance",
+			old:  clusterv1.ManagedClusterStatus{Conditions: []metav1.Condition{joined}},
+			new: clusterv1.ManagedClusterStatus{Conditions: []metav1.Condition{{
+				Type:               clusterv1.ManagedClusterConditionJoined,
+				Status:             metav1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(transitionTime.Add(500 * time.Millisecond)),
+			}}},
+			expected: true,
+		},
+		{
+			name: "transition time beyond the tolerance",
+			old:  clusterv1.ManagedClusterStatus{Conditions: []metav1.Condition{joined}},
+			new: clusterv1.ManagedClusterStatus{Conditions: []metav1.Condition{{
+				Type:               clusterv1.ManagedClusterConditionJoined,
+				Status:             metav1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(transitionTime.Add(time.Minute)),
+			}}},
+			expected: false,
+		},
+		{
+			name: "condition status changed",
+			old:  clusterv1.ManagedClusterStatus{Conditions: []metav1.Condition{joined, available}},
+			new: clusterv1.ManagedClusterStatus{Conditions: []metav1.Condition{joined, {
+				Type:               clusterv1.ManagedClusterConditionAvailable,
+				Status:             metav1.ConditionFalse,
+				LastTransitionTime: transitionTime,
+			}}},
+			expected: false,
+		},
+		{
+			name: "claims in a different order",
+			old: clusterv1.ManagedClusterStatus{ClusterClaims: []clusterv1.ManagedClusterClaim{
+				{Name: "a", Value: "1"}, {Name: "b", Value: "2"},
+			}},
+			new: clusterv1.ManagedClusterStatus{ClusterClaims: []clusterv1.ManagedClusterClaim{
+				{Name: "b", Value: "2"}, {Name: "a", Value: "1"},
+			}},
+			expected: true,
+		},
+		{
+			name: "claim value changed",
+			old: clusterv1.ManagedClusterStatus{ClusterClaims: []clusterv1.ManagedClusterClaim{
+				{Name: "a", Value: "1"},
+			}},
+			new: clusterv1.ManagedClusterStatus{ClusterClaims: []clusterv1.ManagedClusterClaim{
+				{Name: "a", Value: "2"},
+			}},
+			expected: false,
+		},
+		{
+			name: "quantities in different formats",
+			old: clusterv1.ManagedClusterStatus{Capacity: clusterv1.ResourceList{
+				clusterv1.ResourceMemory: resource.MustParse("1Gi"),
+			}},
+			new: clusterv1.ManagedClusterStatus{Capacity: clusterv1.ResourceList{
+				clusterv1.ResourceMemory: resource.MustParse("1073741824"),
+			}},
+			expected: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := StatusEqual(&c.old, &c.new); actual != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
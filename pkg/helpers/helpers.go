@@ -3,14 +3,17 @@ package helpers
 import (
 	"context"
 	"embed"
+	"encoding/json"
 	"fmt"
 	"net/url"
+	"regexp"
 
 	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
 	addonv1alpha1client "open-cluster-management.io/api/client/addon/clientset/versioned"
 	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 
+	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/openshift/api"
 	"github.com/openshift/library-go/pkg/assets"
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -28,11 +31,14 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/util/retry"
+
+	"open-cluster-management.io/registration/pkg/helpers/clusterset"
 )
 
 var (
@@ -45,6 +51,148 @@ func init() {
 	utilruntime.Must(api.InstallKube(genericScheme))
 }
 
+// ClusterSetLabel is the label of a ManagedCluster which specifies the ManagedClusterSet it belongs to
+const ClusterSetLabel = clusterset.Label
+
+// AddOnRegistrationCleanupFinalizer is added on a ManagedClusterAddOn with registrations by the registration agent.
+// It makes sure the credential secrets on the managed cluster and the csrs on the hub are cleaned up before the
+// addon is removed.
+const AddOnRegistrationCleanupFinalizer = "addon.open-cluster-management.io/registration-cleanup"
+
+// AddOnRegistrationAppliedCondition is set on a ManagedClusterAddOn by the registration agent. It is false if a
+// registration of the addon cannot be started on the managed cluster, e.g. its configuration is invalid.
+const AddOnRegistrationAppliedCondition = "RegistrationApplied"
+
+// AddOnTokenSignerName is a pseudo signer name used in the registrations of a ManagedClusterAddOn. A registration
+// with this signer name is served with a bound service account token requested from the hub instead of a client
+// certificate.
+const AddOnTokenSignerName = "open-cluster-management.io/addon-token"
+
+// CertManagerSignerName is the signer name of the csrs of the registration agents on a hub which signs the client
+// certificates of the agents with a cert-manager issuer instead of the kube-apiserver-client signer.
+const CertManagerSignerName = "open-cluster-management.io/cert-manager"
+
+// DefaultReverseTunnelSignerName is the default signer name of the csrs of the reverse tunnel agent credential,
+// which is signed by the signer of cluster-proxy instead of the hub kube-apiserver.
+const DefaultReverseTunnelSignerName = "open-cluster-management.io/proxy-agent-signer"
+
+// ReverseTunnelLabel is the label of the csrs of the reverse tunnel agent credential requested by the registration
+// agent
+const ReverseTunnelLabel = "open-cluster-management.io/reverse-tunnel"
+
+// ReverseTunnelGroup returns the group of the reverse tunnel agent credential of a managed cluster
+func ReverseTunnelGroup(clusterName string) string {
+	return fmt.Sprintf("system:open-cluster-management:cluster:%s:reverse-tunnel", clusterName)
+}
+
+// ReverseTunnelUser returns the user of the reverse tunnel agent credential requested by an agent of a managed
+// cluster
+func ReverseTunnelUser(clusterName, agentName string) string {
+	return fmt.Sprintf("%s:agent:%s", ReverseTunnelGroup(clusterName), agentName)
+}
+
+// IsClusterClientSignerName returns true if the csrs of the client certificates of the registration agents are
+// allowed to be signed by the signer.
+func IsClusterClientSignerName(signerName string) bool {
+	return signerName == certificatesv1.KubeAPIServerClientSignerName || signerName == CertManagerSignerName
+}
+
+// RegistrationDriverAnnotation is set on a ManagedCluster by the registration agent to tell the hub how the agent
+// authenticates to the hub. The agent authenticates with a client certificate if it is not set.
+const RegistrationDriverAnnotation = "agent.open-cluster-management.io/registration-driver"
+
+// AddOnRegistrationDisabledAnnotation is set on a ManagedCluster by the registration agent which disables the addon
+// registration, the hub does not grant the agent the access to the addons of the cluster then, and removes the
+// registration cleanup finalizers of the deleting addons instead of the agent.
+const AddOnRegistrationDisabledAnnotation = "agent.open-cluster-management.io/addon-registration-disabled"
+
+const (
+	// CSRRegistrationDriver is the registration driver of the agents which authenticate to the hub with client
+	// certificates requested with csrs
+	CSRRegistrationDriver = "csr"
+	// TokenRegistrationDriver is the registration driver of the agents which authenticate to the hub with bound
+	// service account tokens, for the hubs which do not accept client certificates
+	TokenRegistrationDriver = "token"
+	// AWSIAMRegistrationDriver is the registration driver of the agents which authenticate to an EKS hub with an
+	// IAM role, which the hub maps to the identity of the agent in the aws-auth configmap
+	AWSIAMRegistrationDriver = "awsiam"
+	// AzureRegistrationDriver and GCPRegistrationDriver are the registration drivers of the agents which
+	// authenticate to an AKS or GKE hub with the cloud workload identities of their pods, which are bound to the
+	// roles of the agents by the hub admin
+	AzureRegistrationDriver = "azure"
+	GCPRegistrationDriver   = "gcp"
+)
+
+const (
+	// KubeRegistrationTransport is the registration transport of the agents which register to the hub with the
+	// apis of the hub kube-apiserver
+	KubeRegistrationTransport = "kube"
+	// GRPCRegistrationTransport is the registration transport of the agents which register to the hub with the
+	// gRPC Registration service, for the hubs which are not Kubernetes clusters
+	GRPCRegistrationTransport = "grpc"
+)
+
+const (
+	// AWSIAMRoleARNAnnotation is set on a ManagedCluster by the registration agent using the aws iam registration
+	// driver, its value is the ARN of the IAM role the agent assumes to authenticate to the hub.
+	AWSIAMRoleARNAnnotation = "agent.open-cluster-management.io/aws-iam-role-arn"
+	// AWSIAMRoleMappedAnnotation is set on a ManagedCluster by the hub once the IAM role in its value is mapped to
+	// the identity of the agent of the cluster, so the agent can authenticate to the hub with the role.
+	AWSIAMRoleMappedAnnotation = "open-cluster-management.io/aws-iam-role-mapped"
+)
+
+const (
+	// RenameAnnotation is set on a ManagedCluster by the hub admin to rename the cluster, its value is the new name.
+	RenameAnnotation = "cluster.open-cluster-management.io/rename-to"
+	// RenameTargetAnnotation is set on a ManagedCluster being renamed by the hub once the ManagedCluster of the new
+	// name in its value is created, so the registration agent re-registers with the new name.
+	RenameTargetAnnotation = "cluster.open-cluster-management.io/rename-target"
+	// RenamedFromAnnotation is set on the ManagedCluster created for a rename by the hub, its value is the old name.
+	RenamedFromAnnotation = "cluster.open-cluster-management.io/renamed-from"
+)
+
+var awsIAMRoleARNRegexp = regexp.MustCompile(`^arn:aws[a-z-]*:iam::[0-9]{12}:role/[\w+=,.@/-]+$`)
+
+// IsValidAWSIAMRoleARN returns true if the value is the ARN of an IAM role, e.g.
+// arn:aws:iam::123456789012:role/ocm-cluster1
+func IsValidAWSIAMRoleARN(arn string) bool {
+	return awsIAMRoleARNRegexp.MatchString(arn)
+}
+
+const (
+	// RegistrationAgentServiceAccountName is the name of the service account created in the managed cluster
+	// namespace on the hub for the agent of a cluster using the token registration driver
+	RegistrationAgentServiceAccountName = "registration-agent"
+	// RegistrationTokenSecretName is the name of the secret in the managed cluster namespace on the hub, in which
+	// the hub saves the token of the registration agent service account
+	RegistrationTokenSecretName = "registration-agent-token"
+	// RegistrationTokenKey is the key of the token in the data of the registration agent token secret
+	RegistrationTokenKey = "token"
+)
+
+// IsRegistrationAgentServiceAccount returns true if the user is the registration agent service account of the
+// cluster
+func IsRegistrationAgentServiceAccount(username, clusterName string) bool {
+	return username == fmt.Sprintf("system:serviceaccount:%s:%s", clusterName, RegistrationAgentServiceAccountName)
+}
+
+// AddOnHealthSummaryAnnotation is the annotation of the managed cluster lease on the hub, in which the registration
+// agent summarizes the available conditions of all addons when the addon heartbeat is aggregated. The value is a json
+// encoded map of the addon name to its available condition.
+const AddOnHealthSummaryAnnotation = "addon.open-cluster-management.io/health-summary"
+
+// AddOnDefaultGroup returns the default group of the client certificates issued for an addon by the
+// kube-apiserver-client signer.
+func AddOnDefaultGroup(clusterName, addOnName string) string {
+	return fmt.Sprintf("system:open-cluster-management:cluster:%s:addon:%s", clusterName, addOnName)
+}
+
+// AddOnServiceAccountName returns the name of the service account created in the managed cluster namespace on
+// the hub for an addon using token based registration.
+func AddOnServiceAccountName(addOnName string) string {
+	return fmt.Sprintf("%s-addon-agent", addOnName)
+}
+
 type UpdateManagedClusterStatusFunc func(status *clusterv1.ManagedClusterStatus) error
 
 func UpdateManagedClusterStatus(
@@ -68,7 +216,7 @@ func UpdateManagedClusterStatus(
 				return err
 			}
 		}
-		if equality.Semantic.DeepEqual(oldStatus, newStatus) {
+		if StatusEqual(oldStatus, newStatus) {
 			// We return the newStatus which is a deep copy of oldStatus but with all update funcs applied.
 			updatedManagedClusterStatus = newStatus
 			return nil
@@ -87,6 +235,77 @@ func UpdateManagedClusterStatus(
 	return updatedManagedClusterStatus, updated, err
 }
 
+// PatchManagedClusterStatus applies the update funcs to the status of a managed cluster like
+// UpdateManagedClusterStatus, but only the changed fields are sent to the hub with a json merge patch, which
+// saves the bandwidth of the large status. The conditions are also written by the hub and a list is replaced as a
+// whole by a merge patch, so the patch is preconditioned on the resource version and retried on conflict only if
+// the conditions are changed. The other fields are only written by the agent and patched without a precondition.
+func PatchManagedClusterStatus(
+	ctx context.Context,
+	client clusterclientset.Interface,
+	spokeClusterName string,
+	updateFuncs ...UpdateManagedClusterStatusFunc) (*clusterv1.ManagedClusterStatus, bool, error) {
+	patched := false
+	var patchedManagedClusterStatus *clusterv1.ManagedClusterStatus
+
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		managedCluster, err := client.ClusterV1().ManagedClusters().Get(ctx, spokeClusterName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		oldStatus := &managedCluster.Status
+
+		newStatus := oldStatus.DeepCopy()
+		for _, update := range updateFuncs {
+			if err := update(newStatus); err != nil {
+				return err
+			}
+		}
+		if StatusEqual(oldStatus, newStatus) {
+			patchedManagedClusterStatus = newStatus
+			return nil
+		}
+
+		patch, err := managedClusterStatusPatch(managedCluster.ResourceVersion, oldStatus, newStatus)
+		if err != nil {
+			return err
+		}
+		patchedManagedCluster, err := client.ClusterV1().ManagedClusters().Patch(
+			ctx, spokeClusterName, types.MergePatchType, patch, metav1.PatchOptions{}, "status")
+		if err != nil {
+			return err
+		}
+		patchedManagedClusterStatus = &patchedManagedCluster.Status
+		patched = true
+		return nil
+	})
+
+	return patchedManagedClusterStatus, patched, err
+}
+
+// managedClusterStatusPatch returns the json merge patch from the old status to the new status, the resource
+// version is added as the precondition if the conditions are changed.
+func managedClusterStatusPatch(resourceVersion string, oldStatus, newStatus *clusterv1.ManagedClusterStatus) ([]byte, error) {
+	oldData, err := json.Marshal(oldStatus)
+	if err != nil {
+		return nil, err
+	}
+	newData, err := json.Marshal(newStatus)
+	if err != nil {
+		return nil, err
+	}
+	statusPatch, err := jsonpatch.CreateMergePatch(oldData, newData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the patch of managed cluster status: %w", err)
+	}
+
+	patch := map[string]interface{}{"status": json.RawMessage(statusPatch)}
+	if !equality.Semantic.DeepEqual(oldStatus.Conditions, newStatus.Conditions) {
+		patch["metadata"] = map[string]interface{}{"resourceVersion": resourceVersion}
+	}
+	return json.Marshal(patch)
+}
+
 func UpdateManagedClusterConditionFn(cond metav1.Condition) UpdateManagedClusterStatusFunc {
 	return func(oldStatus *clusterv1.ManagedClusterStatus) error {
 		meta.SetStatusCondition(&oldStatus.Conditions, cond)
@@ -94,6 +313,24 @@ func UpdateManagedClusterConditionFn(cond metav1.Condition) UpdateManagedCluster
 	}
 }
 
+// UpdateManagedClusterResourcesFn updates the version and the resources of a managed cluster, the capacity entries
+// which do not exist in the new capacity are kept.
+func UpdateManagedClusterResourcesFn(status clusterv1.ManagedClusterStatus) UpdateManagedClusterStatusFunc {
+	return func(oldStatus *clusterv1.ManagedClusterStatus) error {
+		capacity := clusterv1.ResourceList{}
+		for key, val := range oldStatus.Capacity {
+			capacity[key] = val
+		}
+		for key, val := range status.Capacity {
+			capacity[key] = val
+		}
+		oldStatus.Capacity = capacity
+		oldStatus.Allocatable = status.Allocatable
+		oldStatus.Version = status.Version
+		return nil
+	}
+}
+
 type UpdateManagedClusterAddOnStatusFunc func(status *addonv1alpha1.ManagedClusterAddOnStatus) error
 
 func UpdateManagedClusterAddOnStatus(
@@ -117,7 +354,7 @@ func UpdateManagedClusterAddOnStatus(
 				return err
 			}
 		}
-		if equality.Semantic.DeepEqual(oldStatus, newStatus) {
+		if StatusEqual(oldStatus, newStatus) {
 			// We return the newStatus which is a deep copy of oldStatus but with all update funcs applied.
 			updatedAddOnStatus = newStatus
 			return nil
@@ -143,6 +380,21 @@ func UpdateManagedClusterAddOnStatusFn(cond metav1.Condition) UpdateManagedClust
 	}
 }
 
+// FinalizersPatch returns the json merge patch which sets the finalizers of an object, the resource version is
+// added as the precondition, so the finalizers added or removed by others since the object was read are not lost.
+// It is used by the controllers which do not cache the whole object to update it.
+func FinalizersPatch(resourceVersion string, finalizers []string) ([]byte, error) {
+	if finalizers == nil {
+		finalizers = []string{}
+	}
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": resourceVersion,
+			"finalizers":      finalizers,
+		},
+	})
+}
+
 // Check whether a CSR is in terminal state
 func IsCSRInTerminalState(status *certificatesv1.CertificateSigningRequestStatus) bool {
 	for _, c := range status.Conditions {
@@ -220,12 +472,22 @@ func CleanUpManagedClusterManifests(
 	return errorhelpers.NewMultiLineAggregate(errs)
 }
 
+// ManagedClusterAssetFn returns the asset function rendering the manifests of the managed cluster
 func ManagedClusterAssetFn(fs embed.FS, managedClusterName string) resourceapply.AssetFunc {
+	return ManagedClusterAssetFnWithServiceAccount(fs, managedClusterName, "")
+}
+
+// ManagedClusterAssetFnWithServiceAccount returns the asset function rendering the manifests of the managed cluster
+// with the service account of its agent, which is bound along with the group of the cluster. It is only set for the
+// clusters using the token registration driver, the service account is not rendered if it is empty.
+func ManagedClusterAssetFnWithServiceAccount(fs embed.FS, managedClusterName, serviceAccountName string) resourceapply.AssetFunc {
 	return func(name string) ([]byte, error) {
 		config := struct {
 			ManagedClusterName string
+			ServiceAccountName string
 		}{
 			ManagedClusterName: managedClusterName,
+			ServiceAccountName: serviceAccountName,
 		}
 
 		template, err := fs.ReadFile(name)
@@ -294,6 +556,24 @@ func FindTaint(taints []clusterv1.Taint, taint clusterv1.Taint) *clusterv1.Taint
 	return nil
 }
 
+// IsSystemTaint returns true if the taint is owned by the registration hub controller. The system taints are
+// added/removed according to the availability of the managed cluster, and can only be changed by the users
+// who are allowed to update the managedclusters/systemtaints subresource.
+func IsSystemTaint(taint clusterv1.Taint) bool {
+	return taint.Key == clusterv1.ManagedClusterTaintUnavailable || taint.Key == clusterv1.ManagedClusterTaintUnreachable
+}
+
+// SystemTaints returns the system taints in the given taints
+func SystemTaints(taints []clusterv1.Taint) []clusterv1.Taint {
+	systemTaints := []clusterv1.Taint{}
+	for _, taint := range taints {
+		if IsSystemTaint(taint) {
+			systemTaints = append(systemTaints, taint)
+		}
+	}
+	return systemTaints
+}
+
 // IsCSRSupported checks whether the cluster supports v1 or v1beta1 csr api.
 func IsCSRSupported(nativeClient kubernetes.Interface) (bool, bool, error) {
 	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(nativeClient.Discovery()))
@@ -19,6 +19,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/diff"
@@ -120,6 +121,88 @@ func TestUpdateStatusCondition(t *testing.T) {
 	}
 }
 
+func TestPatchManagedClusterStatus(t *testing.T) {
+	transitionTime := metav1.NewTime(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	condition := testinghelpers.NewManagedClusterCondition("test", "True", "my-reason", "my-message", &transitionTime)
+	claims := []clusterv1.ManagedClusterClaim{{Name: "a", Value: "b"}}
+
+	cases := []struct {
+		name            string
+		updateFunc      UpdateManagedClusterStatusFunc
+		conflicts       int
+		expectedPatched bool
+		expectedVerbs   []string
+		expectedPatch   string
+	}{
+		{
+			name: "no change",
+			updateFunc: func(status *clusterv1.ManagedClusterStatus) error {
+				return nil
+			},
+			expectedVerbs: []string{"get"},
+		},
+		{
+			name:            "patch claims without precondition",
+			updateFunc:      func(status *clusterv1.ManagedClusterStatus) error { status.ClusterClaims = claims; return nil },
+			expectedPatched: true,
+			expectedVerbs:   []string{"get", "patch"},
+			expectedPatch:   `{"status":{"clusterClaims":[{"name":"a","value":"b"}]}}`,
+		},
+		{
+			name:            "patch conditions with resource version",
+			updateFunc:      UpdateManagedClusterConditionFn(condition),
+			expectedPatched: true,
+			expectedVerbs:   []string{"get", "patch"},
+			expectedPatch:   `{"metadata":{"resourceVersion":"1"},"status":{"conditions":[{"lastTransitionTime":"2022-01-01T00:00:00Z","message":"my-message","reason":"my-reason","status":"True","type":"test"}]}}`,
+		},
+		{
+			name:            "retry on conflict",
+			updateFunc:      UpdateManagedClusterConditionFn(condition),
+			conflicts:       1,
+			expectedPatched: true,
+			expectedVerbs:   []string{"get", "patch", "get", "patch"},
+			expectedPatch:   `{"metadata":{"resourceVersion":"1"},"status":{"conditions":[{"lastTransitionTime":"2022-01-01T00:00:00Z","message":"my-message","reason":"my-reason","status":"True","type":"test"}]}}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fakeClusterClient := clusterfake.NewSimpleClientset(&clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "testspokecluster", ResourceVersion: "1"},
+			})
+			conflicts := c.conflicts
+			fakeClusterClient.PrependReactor("patch", "managedclusters", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				if conflicts == 0 {
+					return false, nil, nil
+				}
+				conflicts--
+				return true, nil, errors.NewConflict(clusterv1.Resource("managedclusters"), "testspokecluster", fmt.Errorf("conflict"))
+			})
+
+			_, patched, err := PatchManagedClusterStatus(context.TODO(), fakeClusterClient, "testspokecluster", c.updateFunc)
+			if err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+			if patched != c.expectedPatched {
+				t.Errorf("expected %t, but %t", c.expectedPatched, patched)
+			}
+
+			actions := fakeClusterClient.Actions()
+			testinghelpers.AssertActions(t, actions, c.expectedVerbs...)
+			if len(c.expectedPatch) == 0 {
+				return
+			}
+			patch := actions[len(actions)-1].(clienttesting.PatchActionImpl)
+			if patch.GetSubresource() != "status" {
+				t.Errorf("expected to patch the status, but got %q", patch.GetSubresource())
+			}
+			if string(patch.Patch) != c.expectedPatch {
+				t.Errorf("expected patch %s, but got %s", c.expectedPatch, patch.Patch)
+			}
+		})
+	}
+}
+
 func TestUpdateManagedClusterAddOnStatus(t *testing.T) {
 	nowish := metav1.Now()
 	beforeish := metav1.Time{Time: nowish.Add(-10 * time.Second)}
@@ -252,6 +335,45 @@ func TestIsValidHTTPSURL(t *testing.T) {
 	}
 }
 
+func TestIsValidAWSIAMRoleARN(t *testing.T) {
+	cases := []struct {
+		name    string
+		arn     string
+		isValid bool
+	}{
+		{
+			name: "an empty arn",
+		},
+		{
+			name: "an arn of a user",
+			arn:  "arn:aws:iam::123456789012:user/ocm",
+		},
+		{
+			name: "an arn without account",
+			arn:  "arn:aws:iam:::role/ocm-cluster1",
+		},
+		{
+			name:    "an arn of a role",
+			arn:     "arn:aws:iam::123456789012:role/ocm/ocm-cluster1",
+			isValid: true,
+		},
+		{
+			name:    "an arn of a role in another partition",
+			arn:     "arn:aws-cn:iam::123456789012:role/ocm-cluster1",
+			isValid: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			isValid := IsValidAWSIAMRoleARN(c.arn)
+			if isValid != c.isValid {
+				t.Errorf("expected %t, but %t", c.isValid, isValid)
+			}
+		})
+	}
+}
+
 func TestCleanUpManagedClusterManifests(t *testing.T) {
 	applyFiles := map[string]runtime.Object{
 		"namespace":          testinghelpers.NewUnstructuredObj("v1", "Namespace", "", "n1"),
@@ -439,6 +561,43 @@ func TestIsTaintsEqual(t *testing.T) {
 	}
 }
 
+func TestSystemTaints(t *testing.T) {
+	userTaint := clusterv1.Taint{
+		Key:    "key1",
+		Effect: clusterv1.TaintEffectNoSelect,
+	}
+
+	cases := []struct {
+		name     string
+		taints   []clusterv1.Taint
+		expected []clusterv1.Taint
+	}{
+		{
+			name:     "no taints",
+			expected: []clusterv1.Taint{},
+		},
+		{
+			name:     "no system taints",
+			taints:   []clusterv1.Taint{userTaint},
+			expected: []clusterv1.Taint{},
+		},
+		{
+			name:     "system taints",
+			taints:   []clusterv1.Taint{UnavailableTaint, userTaint, UnreachableTaint},
+			expected: []clusterv1.Taint{UnavailableTaint, UnreachableTaint},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := SystemTaints(c.taints)
+			if !reflect.DeepEqual(actual, c.expected) {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
 func TestAddTaints(t *testing.T) {
 	cases := []struct {
 		name          string
@@ -526,3 +685,33 @@ func TestRemoveTaints(t *testing.T) {
 		})
 	}
 }
+
+func TestFinalizersPatch(t *testing.T) {
+	cases := []struct {
+		name          string
+		finalizers    []string
+		expectedPatch string
+	}{
+		{
+			name:          "remove all finalizers",
+			expectedPatch: `{"metadata":{"finalizers":[],"resourceVersion":"1"}}`,
+		},
+		{
+			name:          "set finalizers",
+			finalizers:    []string{"a", "b"},
+			expectedPatch: `{"metadata":{"finalizers":["a","b"],"resourceVersion":"1"}}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			patch, err := FinalizersPatch("1", c.finalizers)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(patch) != c.expectedPatch {
+				t.Errorf("expected patch %s, but got %s", c.expectedPatch, string(patch))
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/pem"
+	"fmt"
+)
+
+// HubCABundleConfigMapName is the name of the configmap of the hub CA bundle. The hub admin rotates the CA of the
+// hub apiserver with the configmap in the namespace of the hub controller, and the hub controller publishes the
+// CA bundle the agents should trust in the configmap with the same name in each managed cluster namespace.
+const HubCABundleConfigMapName = "hub-ca-bundle"
+
+// The keys of the data of the hub CA bundle configmap. HubCABundleKey holds the CA bundle the agents trust, and
+// HubNewCAKey holds the new CA while it is rolled out. The CA bundle published to the managed clusters includes
+// both the current CA bundle and the new CA until the old CA is retired.
+const (
+	HubCABundleKey = "ca-bundle.crt"
+	HubNewCAKey    = "new-ca.crt"
+)
+
+// HubCABundleTrustedCondition is the condition of a managed cluster reported by the registration agent, it is true
+// once the agent trusts the hub CA bundle published to the cluster, and has verified the hub with the new CA if the
+// CA is being rotated.
+const HubCABundleTrustedCondition = "HubCABundleTrusted"
+
+// HubCABundleDigest returns the digest of a CA bundle, which identifies the bundle in the conditions of the
+// managed clusters
+func HubCABundleDigest(bundle []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(bundle))[:16]
+}
+
+// HubCABundleTrustedMessage returns the message of the HubCABundleTrustedCondition of a managed cluster which
+// trusts the CA bundle with the digest. The hub compares the message with the digest of the bundle it publishes.
+func HubCABundleTrustedMessage(digest string) string {
+	return fmt.Sprintf("The hub CA bundle %s is trusted", digest)
+}
+
+// MergeCABundles returns a CA bundle with the certificates of all bundles, the duplicated certificates are dropped
+func MergeCABundles(bundles ...[]byte) []byte {
+	merged := []byte{}
+	seen := map[string]bool{}
+	for _, bundle := range bundles {
+		for {
+			var block *pem.Block
+			block, bundle = pem.Decode(bundle)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" || seen[string(block.Bytes)] {
+				continue
+			}
+			seen[string(block.Bytes)] = true
+			merged = append(merged, pem.EncodeToMemory(block)...)
+		}
+	}
+	return merged
+}
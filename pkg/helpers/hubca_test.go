@@ -0,0 +1,43 @@
+package helpers
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestMergeCABundles(t *testing.T) {
+	ca1 := testinghelpers.NewTestCert("ca1", time.Hour).Cert
+	ca2 := testinghelpers.NewTestCert("ca2", time.Hour).Cert
+
+	cases := []struct {
+		name     string
+		bundles  [][]byte
+		expected []byte
+	}{
+		{
+			name:     "no bundle",
+			expected: []byte{},
+		},
+		{
+			name:     "merge bundles",
+			bundles:  [][]byte{ca1, ca2},
+			expected: append(append([]byte{}, ca1...), ca2...),
+		},
+		{
+			name:     "drop duplicated certificates",
+			bundles:  [][]byte{append(append([]byte{}, ca1...), ca2...), ca2, []byte("invalid")},
+			expected: append(append([]byte{}, ca1...), ca2...),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			merged := MergeCABundles(c.bundles...)
+			if !bytes.Equal(merged, c.expected) {
+				t.Errorf("expected bundle %q, but got %q", string(c.expected), string(merged))
+			}
+		})
+	}
+}
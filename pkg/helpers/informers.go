@@ -0,0 +1,230 @@
+package helpers
+
+import (
+	"context"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// ListFunc lists the objects of an informer
+type ListFunc func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error)
+
+// WatchFunc watches the objects of an informer
+type WatchFunc func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error)
+
+// NewTransformingInformer returns a shared index informer whose objects are transformed before they are put into
+// the cache and passed to the event handlers. The shared informers of client-go do not support transforms yet, so
+// the transform is applied to the results of the list and watch functions. It is registered in an informer factory
+// with InformerFor, so that the controllers get the transformed informer from the factory.
+func NewTransformingInformer(listFunc ListFunc, watchFunc WatchFunc, exampleObject runtime.Object,
+	resyncPeriod time.Duration, transform cache.TransformFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				list, err := listFunc(context.TODO(), options)
+				if err != nil {
+					return nil, err
+				}
+				return list, transformList(list, transform)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				w, err := watchFunc(context.TODO(), options)
+				if err != nil {
+					return nil, err
+				}
+				return watch.Filter(w, func(in watch.Event) (watch.Event, bool) {
+					// the object of an error event is a status
+					if in.Type == watch.Error {
+						return in, true
+					}
+					if obj, err := transform(in.Object); err == nil {
+						in.Object = obj.(runtime.Object)
+					}
+					return in, true
+				}), nil
+			},
+		},
+		exampleObject,
+		resyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+}
+
+// NewFilteredCSRInformerFactory returns a kube informer factory for the csrs selected by the list options, e.g. the
+// csrs of a signer or with a label, so the csrs of the other components, e.g. kubelet or cert-manager, are not
+// cached. The csrs are transformed with TransformCSR.
+func NewFilteredCSRInformerFactory(client kubernetes.Interface, resyncPeriod time.Duration,
+	tweakListOptions func(*metav1.ListOptions)) informers.SharedInformerFactory {
+	factory := informers.NewSharedInformerFactoryWithOptions(client, resyncPeriod, informers.WithTweakListOptions(tweakListOptions))
+	// the list options of the factory are not passed to the informers registered with InformerFor
+	factory.InformerFor(&certificatesv1.CertificateSigningRequest{},
+		func(client kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			return newFilteredCSRInformer(client, resyncPeriod, tweakListOptions)
+		})
+	return factory
+}
+
+// NewLazyCSRInformerFactory returns a kube informer factory like NewFilteredCSRInformerFactory, but the csr informer
+// is a LazyInformer, which watches the csrs only while it is acquired, e.g. while a csr is pending.
+func NewLazyCSRInformerFactory(client kubernetes.Interface, resyncPeriod time.Duration,
+	tweakListOptions func(*metav1.ListOptions)) informers.SharedInformerFactory {
+	factory := informers.NewSharedInformerFactoryWithOptions(client, resyncPeriod, informers.WithTweakListOptions(tweakListOptions))
+	factory.InformerFor(&certificatesv1.CertificateSigningRequest{},
+		func(client kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			return NewLazyInformer(func() cache.SharedIndexInformer {
+				return newFilteredCSRInformer(client, resyncPeriod, tweakListOptions)
+			})
+		})
+	return factory
+}
+
+func newFilteredCSRInformer(client kubernetes.Interface, resyncPeriod time.Duration,
+	tweakListOptions func(*metav1.ListOptions)) cache.SharedIndexInformer {
+	csrs := client.CertificatesV1().CertificateSigningRequests()
+	return NewTransformingInformer(
+		func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&options)
+			return csrs.List(ctx, options)
+		},
+		func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&options)
+			return csrs.Watch(ctx, options)
+		},
+		&certificatesv1.CertificateSigningRequest{},
+		resyncPeriod,
+		TransformCSR,
+	)
+}
+
+// NewMetadataInformer returns a shared index informer which lists and watches only the metadata of a resource with
+// the metadata client, so the bodies of the objects are neither sent by the apiserver nor decoded. The objects are
+// cached as the typed objects built by newObject with the metadata only, e.g. a secret without data, so the typed
+// listers and the typed controllers keep working as long as they read the metadata only, e.g. the existence, the
+// labels or the finalizers of the objects. The managed fields are dropped as well. It is registered in an informer
+// factory with InformerFor like NewTransformingInformer.
+func NewMetadataInformer(client metadata.Interface, resource schema.GroupVersionResource, namespace string,
+	resyncPeriod time.Duration, tweakListOptions func(*metav1.ListOptions), exampleObject runtime.Object,
+	newObject func(metav1.ObjectMeta) runtime.Object) cache.SharedIndexInformer {
+	resourceClient := client.Resource(resource).Namespace(namespace)
+	if tweakListOptions == nil {
+		tweakListOptions = func(*metav1.ListOptions) {}
+	}
+	return NewTransformingInformer(
+		func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&options)
+			partialList, err := resourceClient.List(ctx, options)
+			if err != nil {
+				return nil, err
+			}
+			// the typed objects do not fit in the items of the partial object list
+			list := &metav1.List{ListMeta: partialList.ListMeta}
+			for i := range partialList.Items {
+				list.Items = append(list.Items, runtime.RawExtension{Object: &partialList.Items[i]})
+			}
+			return list, nil
+		},
+		func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&options)
+			return resourceClient.Watch(ctx, options)
+		},
+		exampleObject,
+		resyncPeriod,
+		func(obj interface{}) (interface{}, error) {
+			partial, ok := obj.(*metav1.PartialObjectMetadata)
+			if !ok {
+				return obj, nil
+			}
+			objectMeta := partial.ObjectMeta
+			objectMeta.ManagedFields = nil
+			return newObject(objectMeta), nil
+		},
+	)
+}
+
+func transformList(list runtime.Object, transform cache.TransformFunc) error {
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		obj, err := transform(items[i])
+		if err != nil {
+			return err
+		}
+		items[i] = obj.(runtime.Object)
+	}
+	return meta.SetList(list, items)
+}
+
+// StripManagedFields drops the managed fields of an object, they are never read by the controllers but usually
+// take more memory than the rest of the metadata.
+func StripManagedFields(obj interface{}) (interface{}, error) {
+	target := obj
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		target = tombstone.Obj
+	}
+	if accessor, err := meta.Accessor(target); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return obj, nil
+}
+
+// TransformCSR drops the managed fields of a csr, and the request of a csr once it is finished, that is, it is
+// denied, failed or issued. The request of a pending or an approved but not issued csr is kept, since it is
+// parsed by the approvers and the signers.
+func TransformCSR(obj interface{}) (interface{}, error) {
+	obj, _ = StripManagedFields(obj)
+	csr, ok := obj.(*certificatesv1.CertificateSigningRequest)
+	if !ok {
+		return obj, nil
+	}
+	if isCSRFinished(&csr.Status) {
+		csr.Spec.Request = nil
+	}
+	return csr, nil
+}
+
+func isCSRFinished(status *certificatesv1.CertificateSigningRequestStatus) bool {
+	if len(status.Certificate) > 0 {
+		return true
+	}
+	for _, c := range status.Conditions {
+		if c.Type == certificatesv1.CertificateDenied || c.Type == certificatesv1.CertificateFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// TransformNode drops the managed fields and the images of a node, only the capacity, allocatable and
+// schedulability of the nodes are read by the agent.
+func TransformNode(obj interface{}) (interface{}, error) {
+	obj, _ = StripManagedFields(obj)
+	if node, ok := obj.(*corev1.Node); ok {
+		node.Status.Images = nil
+	}
+	return obj, nil
+}
+
+// TransformManifestWork drops the managed fields and the manifests of a manifestwork, the hub only checks whether
+// the manifestworks in a cluster namespace are all removed.
+func TransformManifestWork(obj interface{}) (interface{}, error) {
+	obj, _ = StripManagedFields(obj)
+	if work, ok := obj.(*workv1.ManifestWork); ok {
+		work.Spec.Workload.Manifests = nil
+	}
+	return obj, nil
+}
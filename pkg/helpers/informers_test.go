@@ -0,0 +1,226 @@
+package helpers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	fakemetadata "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newCSRWithManagedFields(csr *certificatesv1.CertificateSigningRequest) *certificatesv1.CertificateSigningRequest {
+	csr.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "registration-agent", Operation: metav1.ManagedFieldsOperationUpdate}}
+	return csr
+}
+
+func TestTransformCSR(t *testing.T) {
+	holder := testinghelpers.CSRHolder{Name: "csr1", ReqBlockType: "CERTIFICATE REQUEST"}
+	issuedCSR := testinghelpers.NewApprovedCSR(holder)
+	issuedCSR.Status.Certificate = []byte("cert")
+
+	cases := []struct {
+		name            string
+		obj             interface{}
+		expectedRequest bool
+	}{
+		{
+			name:            "pending csr",
+			obj:             newCSRWithManagedFields(testinghelpers.NewCSR(holder)),
+			expectedRequest: true,
+		},
+		{
+			name:            "approved csr",
+			obj:             newCSRWithManagedFields(testinghelpers.NewApprovedCSR(holder)),
+			expectedRequest: true,
+		},
+		{
+			name:            "issued csr",
+			obj:             newCSRWithManagedFields(issuedCSR),
+			expectedRequest: false,
+		},
+		{
+			name:            "denied csr",
+			obj:             newCSRWithManagedFields(testinghelpers.NewDeniedCSR(holder)),
+			expectedRequest: false,
+		},
+		{
+			name: "tombstone",
+			obj: cache.DeletedFinalStateUnknown{
+				Key: "csr1",
+				Obj: newCSRWithManagedFields(testinghelpers.NewCSR(holder)),
+			},
+			expectedRequest: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			obj, err := TransformCSR(c.obj)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			csr := obj.(*certificatesv1.CertificateSigningRequest)
+			if len(csr.ManagedFields) != 0 {
+				t.Errorf("expected managed fields to be dropped, but got %v", csr.ManagedFields)
+			}
+			if hasRequest := len(csr.Spec.Request) > 0; hasRequest != c.expectedRequest {
+				t.Errorf("expected request kept %v, but got %v", c.expectedRequest, hasRequest)
+			}
+		})
+	}
+}
+
+func TestTransformNode(t *testing.T) {
+	node := testinghelpers.NewNode("node1", testinghelpers.NewResourceList(32, 64), testinghelpers.NewResourceList(16, 32))
+	node.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kubelet"}}
+	node.Status.Images = []corev1.ContainerImage{{Names: []string{"quay.io/open-cluster-management/registration"}, SizeBytes: 1024}}
+
+	obj, err := TransformNode(node)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	transformed := obj.(*corev1.Node)
+	if len(transformed.ManagedFields) != 0 || len(transformed.Status.Images) != 0 {
+		t.Errorf("expected managed fields and images to be dropped, but got %v", transformed)
+	}
+	if len(transformed.Status.Capacity) == 0 || len(transformed.Status.Allocatable) == 0 {
+		t.Errorf("expected capacity and allocatable to be kept, but got %v", transformed.Status)
+	}
+}
+
+func TestNewTransformingInformer(t *testing.T) {
+	holder := testinghelpers.CSRHolder{Name: "csr1", ReqBlockType: "CERTIFICATE REQUEST"}
+	kubeClient := fakekube.NewSimpleClientset(newCSRWithManagedFields(testinghelpers.NewDeniedCSR(holder)))
+	csrs := kubeClient.CertificatesV1().CertificateSigningRequests()
+
+	informer := NewTransformingInformer(
+		func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return csrs.List(ctx, options)
+		},
+		csrs.Watch,
+		&certificatesv1.CertificateSigningRequest{},
+		10*time.Minute,
+		TransformCSR,
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go informer.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		t.Fatalf("unable to sync the informer")
+	}
+
+	// the csr created after the informer is synced is received by the watch
+	holder.Name = "csr2"
+	if _, err := csrs.Create(ctx, newCSRWithManagedFields(testinghelpers.NewCSR(holder)), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		_, exists, err := informer.GetStore().GetByKey("csr2")
+		return exists, err
+	}); err != nil {
+		t.Fatalf("csr2 is not cached: %v", err)
+	}
+
+	for name, expectedRequest := range map[string]bool{"csr1": false, "csr2": true} {
+		obj, _, err := informer.GetStore().GetByKey(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		csr := obj.(*certificatesv1.CertificateSigningRequest)
+		if len(csr.ManagedFields) != 0 {
+			t.Errorf("expected managed fields of %s to be dropped, but got %v", name, csr.ManagedFields)
+		}
+		if hasRequest := len(csr.Spec.Request) > 0; hasRequest != expectedRequest {
+			t.Errorf("expected request of %s kept %v, but got %v", name, expectedRequest, hasRequest)
+		}
+	}
+}
+
+func TestNewFilteredCSRInformerFactory(t *testing.T) {
+	clusterCSR := testinghelpers.NewCSR(testinghelpers.CSRHolder{
+		Name:         "csr1",
+		Labels:       map[string]string{"open-cluster-management.io/cluster-name": "cluster1"},
+		ReqBlockType: "CERTIFICATE REQUEST",
+	})
+	kubeletCSR := testinghelpers.NewCSR(testinghelpers.CSRHolder{Name: "csr2", ReqBlockType: "CERTIFICATE REQUEST"})
+	kubeClient := fakekube.NewSimpleClientset(clusterCSR, kubeletCSR)
+
+	listOptions := []metav1.ListOptions{}
+	kubeClient.PrependReactor("list", "certificatesigningrequests", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		listOptions = append(listOptions, metav1.ListOptions{
+			LabelSelector: action.(clienttesting.ListAction).GetListRestrictions().Labels.String(),
+		})
+		return false, nil, nil
+	})
+
+	factory := NewFilteredCSRInformerFactory(kubeClient, 10*time.Minute, func(options *metav1.ListOptions) {
+		options.LabelSelector = "open-cluster-management.io/cluster-name"
+	})
+	informer := factory.Certificates().V1().CertificateSigningRequests().Informer()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		t.Fatalf("unable to sync the informer")
+	}
+
+	if len(listOptions) == 0 || listOptions[0].LabelSelector != "open-cluster-management.io/cluster-name" {
+		t.Errorf("expected the csrs to be listed with the label selector, but got %v", listOptions)
+	}
+	if keys := informer.GetStore().ListKeys(); len(keys) != 1 || keys[0] != "csr1" {
+		t.Errorf("expected only csr1 to be cached, but got %v", keys)
+	}
+}
+
+func TestNewMetadataInformer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	metav1.AddMetaToScheme(scheme)
+	secret := &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:     "ns1",
+			Name:          "secret1",
+			Labels:        map[string]string{"app": "registration"},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "registration-agent"}},
+		},
+	}
+	metadataClient := fakemetadata.NewSimpleMetadataClient(scheme, secret)
+
+	informer := NewMetadataInformer(metadataClient, corev1.SchemeGroupVersion.WithResource("secrets"), "ns1",
+		10*time.Minute, nil, &corev1.Secret{},
+		func(m metav1.ObjectMeta) runtime.Object { return &corev1.Secret{ObjectMeta: m} })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go informer.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		t.Fatalf("unable to sync the informer")
+	}
+
+	obj, exists, err := informer.GetStore().GetByKey("ns1/secret1")
+	if err != nil || !exists {
+		t.Fatalf("expected secret1 to be cached, but got %v, %v", exists, err)
+	}
+	cached, ok := obj.(*corev1.Secret)
+	if !ok {
+		t.Fatalf("expected a secret to be cached, but got %T", obj)
+	}
+	if cached.Labels["app"] != "registration" {
+		t.Errorf("expected the labels to be kept, but got %v", cached.Labels)
+	}
+	if len(cached.ManagedFields) != 0 {
+		t.Errorf("expected managed fields to be dropped, but got %v", cached.ManagedFields)
+	}
+}
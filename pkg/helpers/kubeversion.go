@@ -0,0 +1,120 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SupportedKubernetesVersionsAnnotation is the annotation of a managed cluster which advertises the range of the
+// Kubernetes minor versions supported by the hub, in the format of <min>-<max>, e.g. 1.27-1.30. Either bound may
+// be omitted, e.g. 1.27- for the versions since 1.27. It is set by the hub controller, and the registration agent
+// reports the skew of the version of the cluster in the KubernetesVersionSupportedCondition.
+const SupportedKubernetesVersionsAnnotation = "cluster.open-cluster-management.io/supported-kubernetes-versions"
+
+// KubernetesVersionSupportedCondition is the condition of a managed cluster reported by the registration agent,
+// it is true if the Kubernetes version of the cluster is in the range advertised by the hub, and false with the
+// skew from the range otherwise.
+const KubernetesVersionSupportedCondition = "KubernetesVersionSupported"
+
+// MinorVersion is the major and the minor version of Kubernetes
+type MinorVersion struct {
+	Major int
+	Minor int
+}
+
+// String returns the version in the form of <major>.<minor>
+func (v MinorVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// ParseMinorVersion parses the major and the minor version of a Kubernetes version, e.g. v1.27.3+k3s1
+func ParseMinorVersion(version string) (MinorVersion, error) {
+	parts := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".", 3)
+	if len(parts) < 2 {
+		return MinorVersion{}, fmt.Errorf("invalid Kubernetes version %q", version)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil || major < 0 {
+		return MinorVersion{}, fmt.Errorf("invalid major version of Kubernetes version %q", version)
+	}
+	// the minor version of some distributions has a suffix, e.g. 27+ of EKS and GKE
+	minor, err := strconv.Atoi(strings.TrimSuffix(parts[1], "+"))
+	if err != nil || minor < 0 {
+		return MinorVersion{}, fmt.Errorf("invalid minor version of Kubernetes version %q", version)
+	}
+	return MinorVersion{Major: major, Minor: minor}, nil
+}
+
+// KubernetesVersionRange is a range of Kubernetes minor versions, the nil bound is not limited
+type KubernetesVersionRange struct {
+	Min *MinorVersion
+	Max *MinorVersion
+}
+
+// String returns the range in the format of SupportedKubernetesVersionsAnnotation
+func (r KubernetesVersionRange) String() string {
+	bounds := []string{"", ""}
+	if r.Min != nil {
+		bounds[0] = r.Min.String()
+	}
+	if r.Max != nil {
+		bounds[1] = r.Max.String()
+	}
+	return strings.Join(bounds, "-")
+}
+
+// ParseKubernetesVersionRange parses a range in the format of SupportedKubernetesVersionsAnnotation
+func ParseKubernetesVersionRange(value string) (KubernetesVersionRange, error) {
+	r := KubernetesVersionRange{}
+	bounds := strings.Split(value, "-")
+	if len(bounds) != 2 || (len(bounds[0]) == 0 && len(bounds[1]) == 0) {
+		return r, fmt.Errorf("invalid range %q, must be in the format of <min>-<max>, e.g. 1.27-1.30", value)
+	}
+	for i, bound := range bounds {
+		if len(bound) == 0 {
+			continue
+		}
+		version, err := ParseMinorVersion(bound)
+		if err != nil {
+			return r, fmt.Errorf("invalid range %q: %w", value, err)
+		}
+		if i == 0 {
+			r.Min = &version
+		} else {
+			r.Max = &version
+		}
+	}
+	if r.Min != nil && r.Max != nil && r.Min.compare(*r.Max) > 0 {
+		return r, fmt.Errorf("invalid range %q, the min version is greater than the max version", value)
+	}
+	return r, nil
+}
+
+// Skew returns the number of the minor versions the version is older than the min version, as a negative number,
+// or newer than the max version of the range. It is zero if the version is in the range. The skew across the major
+// versions is counted as one minor version.
+func (r KubernetesVersionRange) Skew(version MinorVersion) int {
+	switch {
+	case r.Min != nil && version.compare(*r.Min) < 0:
+		return -minorSkew(*r.Min, version)
+	case r.Max != nil && version.compare(*r.Max) > 0:
+		return minorSkew(version, *r.Max)
+	}
+	return 0
+}
+
+func (v MinorVersion) compare(other MinorVersion) int {
+	if v.Major != other.Major {
+		return v.Major - other.Major
+	}
+	return v.Minor - other.Minor
+}
+
+// minorSkew returns the number of the minor versions newer is newer than older
+func minorSkew(newer, older MinorVersion) int {
+	if newer.Major != older.Major {
+		return 1
+	}
+	return newer.Minor - older.Minor
+}
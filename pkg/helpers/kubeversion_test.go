@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"testing"
+)
+
+func TestParseKubernetesVersionRange(t *testing.T) {
+	cases := []struct {
+		name          string
+		value         string
+		expectedRange string
+		expectedErr   bool
+	}{
+		{name: "min and max", value: "1.27-1.30", expectedRange: "1.27-1.30"},
+		{name: "min only", value: "v1.27-", expectedRange: "1.27-"},
+		{name: "max only", value: "-1.30", expectedRange: "-1.30"},
+		{name: "no bound", value: "-", expectedErr: true},
+		{name: "no separator", value: "1.27", expectedErr: true},
+		{name: "invalid version", value: "1-1.30", expectedErr: true},
+		{name: "min greater than max", value: "1.30-1.27", expectedErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := ParseKubernetesVersionRange(c.value)
+			if c.expectedErr != (err != nil) {
+				t.Fatalf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if err == nil && r.String() != c.expectedRange {
+				t.Errorf("expected range %q, but got %q", c.expectedRange, r.String())
+			}
+		})
+	}
+}
+
+func TestKubernetesVersionSkew(t *testing.T) {
+	r, err := ParseKubernetesVersionRange("1.27-1.30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		version      string
+		expectedSkew int
+	}{
+		{version: "v1.25.3", expectedSkew: -2},
+		{version: "v1.27.0", expectedSkew: 0},
+		{version: "v1.30.1+k3s1", expectedSkew: 0},
+		{version: "v1.31.0-eks-1234", expectedSkew: 1},
+		{version: "v2.0.0", expectedSkew: 1},
+	}
+	for _, c := range cases {
+		t.Run(c.version, func(t *testing.T) {
+			version, err := ParseMinorVersion(c.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if skew := r.Skew(version); skew != c.expectedSkew {
+				t.Errorf("expected skew %d, but got %d", c.expectedSkew, skew)
+			}
+		})
+	}
+
+	if _, err := ParseMinorVersion("v1.27+"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
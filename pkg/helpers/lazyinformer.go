@@ -0,0 +1,244 @@
+package helpers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// LazyInformer is a shared informer which watches the objects only while it is acquired by a holder, e.g. the csr
+// informer of the agent, which is idle except for the bootstrap and the certificate rotation windows. Each window
+// runs a new informer built with newInformer, and the objects are cached in a store lasting across the windows, so
+// the listers built on the informer keep working. The store is cleared once the informer is released by all of
+// the holders. While it is idle, the informer is synced with an empty store and the readers are expected to fall
+// back to the apiserver.
+type LazyInformer struct {
+	newInformer func() cache.SharedIndexInformer
+	indexer     cache.Indexer
+
+	lock     sync.Mutex
+	handlers []cache.ResourceEventHandler
+	holders  map[string]*lazyInformerHolder
+	// stopCh is the stop channel of Run, it is nil until the informer is run
+	stopCh <-chan struct{}
+	// window is the informer of the current window, it is nil while the informer is idle
+	window     cache.SharedIndexInformer
+	stopWindow chan struct{}
+
+	watchErrorHandler cache.WatchErrorHandler
+}
+
+// lazyInformerHolder is a holder of a LazyInformer, it is released once its context is done
+type lazyInformerHolder struct {
+	cancel context.CancelFunc
+}
+
+var _ cache.SharedIndexInformer = &LazyInformer{}
+
+// NewLazyInformer returns a LazyInformer running the informers built with newInformer in the windows it is acquired.
+func NewLazyInformer(newInformer func() cache.SharedIndexInformer) *LazyInformer {
+	return &LazyInformer{
+		newInformer: newInformer,
+		indexer:     cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{}),
+		holders:     map[string]*lazyInformerHolder{},
+	}
+}
+
+// Acquire starts a window of the informer if it is idle, the window lasts until all of the holders are released.
+// A holder is released by Release or once the context is done. Acquiring an acquired holder is a no-op.
+func (l *LazyInformer) Acquire(ctx context.Context, holder string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, ok := l.holders[holder]; ok {
+		return
+	}
+
+	holderCtx, cancel := context.WithCancel(ctx)
+	h := &lazyInformerHolder{cancel: cancel}
+	l.holders[holder] = h
+	go func() {
+		<-holderCtx.Done()
+		l.release(holder, h)
+	}()
+
+	if l.window == nil {
+		l.startWindow()
+	}
+}
+
+// Release releases a holder of the informer, the window is stopped if it is the last holder.
+func (l *LazyInformer) Release(holder string) {
+	l.lock.Lock()
+	h, ok := l.holders[holder]
+	l.lock.Unlock()
+	if ok {
+		l.release(holder, h)
+	}
+}
+
+func (l *LazyInformer) release(holder string, h *lazyInformerHolder) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	// the holder might be released and acquired again
+	if l.holders[holder] != h {
+		return
+	}
+	delete(l.holders, holder)
+	h.cancel()
+
+	if len(l.holders) == 0 {
+		l.stopCurrentWindow()
+	}
+}
+
+// startWindow starts an informer for a new window if the informer is running, it is called with the lock held.
+func (l *LazyInformer) startWindow() {
+	if l.stopCh == nil {
+		return
+	}
+	select {
+	case <-l.stopCh:
+		return
+	default:
+	}
+
+	window := l.newInformer()
+	if l.watchErrorHandler != nil {
+		// it fails only if the informer is started
+		_ = window.SetWatchErrorHandler(l.watchErrorHandler)
+	}
+	window.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if l.updateStore(window, func() error { return l.indexer.Add(obj) }) {
+				for _, handler := range l.eventHandlers() {
+					handler.OnAdd(obj)
+				}
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if l.updateStore(window, func() error { return l.indexer.Update(newObj) }) {
+				for _, handler := range l.eventHandlers() {
+					handler.OnUpdate(oldObj, newObj)
+				}
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if l.updateStore(window, func() error { return l.indexer.Delete(obj) }) {
+				for _, handler := range l.eventHandlers() {
+					handler.OnDelete(obj)
+				}
+			}
+		},
+	})
+
+	l.window, l.stopWindow = window, make(chan struct{})
+	go window.Run(l.stopWindow)
+}
+
+// stopCurrentWindow stops the informer of the current window and clears the store, it is called with the lock held.
+func (l *LazyInformer) stopCurrentWindow() {
+	if l.window == nil {
+		return
+	}
+	close(l.stopWindow)
+	l.window, l.stopWindow = nil, nil
+	_ = l.indexer.Replace([]interface{}{}, "")
+}
+
+// updateStore updates the store with an event of a window, the events of the stopped windows are dropped.
+func (l *LazyInformer) updateStore(window cache.SharedIndexInformer, update func() error) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.window != window {
+		return false
+	}
+	return update() == nil
+}
+
+func (l *LazyInformer) eventHandlers() []cache.ResourceEventHandler {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]cache.ResourceEventHandler{}, l.handlers...)
+}
+
+// AddEventHandler adds a handler of the events of all of the windows. Like a shared informer, the handler added
+// in a window receives the add events of the cached objects.
+func (l *LazyInformer) AddEventHandler(handler cache.ResourceEventHandler) {
+	l.lock.Lock()
+	l.handlers = append(l.handlers, handler)
+	l.lock.Unlock()
+
+	for _, obj := range l.indexer.List() {
+		handler.OnAdd(obj)
+	}
+}
+
+// AddEventHandlerWithResyncPeriod adds a handler of the events, the objects are resynced with the resync period
+// of the informers of the windows.
+func (l *LazyInformer) AddEventHandlerWithResyncPeriod(handler cache.ResourceEventHandler, _ time.Duration) {
+	l.AddEventHandler(handler)
+}
+
+// Run runs the windows of the informer until the stop channel is closed.
+func (l *LazyInformer) Run(stopCh <-chan struct{}) {
+	l.lock.Lock()
+	if l.stopCh != nil {
+		l.lock.Unlock()
+		return
+	}
+	l.stopCh = stopCh
+	if len(l.holders) > 0 {
+		l.startWindow()
+	}
+	l.lock.Unlock()
+
+	<-stopCh
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.stopCurrentWindow()
+}
+
+// HasSynced returns true if the informer of the current window is synced or the informer is idle.
+func (l *LazyInformer) HasSynced() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.window == nil {
+		return true
+	}
+	return l.window.HasSynced()
+}
+
+func (l *LazyInformer) LastSyncResourceVersion() string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.window == nil {
+		return ""
+	}
+	return l.window.LastSyncResourceVersion()
+}
+
+func (l *LazyInformer) SetWatchErrorHandler(handler cache.WatchErrorHandler) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.watchErrorHandler = handler
+	return nil
+}
+
+func (l *LazyInformer) AddIndexers(indexers cache.Indexers) error {
+	return l.indexer.AddIndexers(indexers)
+}
+
+func (l *LazyInformer) GetStore() cache.Store {
+	return l.indexer
+}
+
+func (l *LazyInformer) GetIndexer() cache.Indexer {
+	return l.indexer
+}
+
+func (l *LazyInformer) GetController() cache.Controller {
+	return l
+}
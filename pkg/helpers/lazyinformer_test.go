@@ -0,0 +1,82 @@
+package helpers
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestLazyInformer(t *testing.T) {
+	kubeClient := fakekube.NewSimpleClientset(testinghelpers.NewCSR(testinghelpers.CSRHolder{Name: "csr1", ReqBlockType: "CERTIFICATE REQUEST"}))
+	var lists int32
+	kubeClient.PrependReactor("list", "certificatesigningrequests", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		atomic.AddInt32(&lists, 1)
+		return false, nil, nil
+	})
+
+	informer := NewLazyInformer(func() cache.SharedIndexInformer {
+		return newFilteredCSRInformer(kubeClient, 10*time.Minute, func(*metav1.ListOptions) {})
+	})
+	var added int32
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			atomic.AddInt32(&added, 1)
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go informer.Run(ctx.Done())
+
+	// the informer is synced without watching the csrs while it is idle
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		t.Fatalf("unable to sync the informer")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&lists); n != 0 {
+		t.Errorf("expected no list of the idle informer, but got %d", n)
+	}
+
+	// the csrs are cached in the window of the holders
+	holderCtx, releaseHolder := context.WithCancel(ctx)
+	informer.Acquire(holderCtx, "holder1")
+	informer.Acquire(ctx, "holder2")
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		_, exists, err := informer.GetStore().GetByKey("csr1")
+		return exists && informer.HasSynced(), err
+	}); err != nil {
+		t.Fatalf("csr1 is not cached: %v", err)
+	}
+	if n := atomic.LoadInt32(&added); n != 1 {
+		t.Errorf("expected 1 add event, but got %d", n)
+	}
+
+	// the window lasts until all of the holders are released
+	releaseHolder()
+	time.Sleep(100 * time.Millisecond)
+	if len(informer.GetStore().ListKeys()) != 1 {
+		t.Errorf("expected the csrs to be cached until all of the holders are released")
+	}
+	informer.Release("holder2")
+	if keys := informer.GetStore().ListKeys(); len(keys) != 0 {
+		t.Errorf("expected the store to be cleared once the informer is idle, but got %v", keys)
+	}
+
+	// a new window lists the csrs again
+	informer.Acquire(ctx, "holder1")
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return atomic.LoadInt32(&lists) == 2, nil
+	}); err != nil {
+		t.Errorf("expected the csrs to be listed again in a new window, but got %d lists", atomic.LoadInt32(&lists))
+	}
+}
@@ -0,0 +1,62 @@
+package helpers
+
+import (
+	"context"
+	"strconv"
+
+	"k8s.io/klog/v2"
+)
+
+// The hub controller and the agent log with the contextual logger of klog. The logger is taken from the context
+// with klog.FromContext, so that it carries the name of the component and the controller, and the messages are
+// constant strings with the details in key/value pairs. The keys below are used consistently so the logs of
+// many clusters can be filtered by them:
+//   - "controller": the name of the controller, added by ControllerLogger.
+//   - "cluster": the name of the managed cluster.
+//   - "resource": the reconciled object, logged with klog.KObj or klog.KRef.
+//   - "reason": why a decision is made, e.g. why a csr is not approved.
+//   - "phase": the phase of a sync, e.g. the phase a slow sync spent most of its time in.
+//
+// The verbosity levels are:
+//   - 0: errors and the events an operator must know, e.g. the agent starts to re-register.
+//   - LogLevelChange (2): the changes the controllers make to the resources.
+//   - LogLevelDebug (4): the reconciles and the decisions of the controllers, e.g. a csr is skipped.
+const (
+	LogKeyController = "controller"
+	LogKeyCluster    = "cluster"
+	LogKeyResource   = "resource"
+	LogKeyReason     = "reason"
+	LogKeyPhase      = "phase"
+
+	LogLevelChange = 2
+	LogLevelDebug  = 4
+)
+
+// ControllerLogger returns the logger in the context with the name of a controller
+func ControllerLogger(ctx context.Context, controller string) klog.Logger {
+	return klog.LoggerWithValues(klog.FromContext(ctx), LogKeyController, controller)
+}
+
+// NewComponentContext returns a context with a logger named after a component, e.g. "registration-agent". The
+// controllers of the component log with the logger in the context.
+func NewComponentContext(ctx context.Context, component string) context.Context {
+	return klog.NewContext(ctx, klog.LoggerWithName(klog.FromContext(ctx), component))
+}
+
+// maxLogLevel is the highest verbosity LogLevel checks, the components do not log at a higher level
+const maxLogLevel = 10
+
+// LogLevel returns the current verbosity of klog, it is the highest level enabled
+func LogLevel() int32 {
+	level := int32(0)
+	for level < maxLogLevel && klog.V(klog.Level(level+1)).Enabled() {
+		level++
+	}
+	return level
+}
+
+// SetLogLevel changes the verbosity of klog while the component runs
+func SetLogLevel(level int32) error {
+	var l klog.Level
+	return l.Set(strconv.Itoa(int(level)))
+}
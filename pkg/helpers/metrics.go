@@ -0,0 +1,82 @@
+package helpers
+
+import (
+	"sync"
+
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// The metrics of a managed cluster are labeled with the name of the cluster and the clusterset it belongs to with
+// the labels below, so the dashboards are able to slice the metrics of the hub controller and the agent by them.
+const (
+	MetricLabelManagedCluster = "managed_cluster"
+	MetricLabelClusterSet     = "clusterset"
+)
+
+// OtherClustersLabelValue is the managed_cluster label value of the clusters beyond the limit of ClusterLabeler
+const OtherClustersLabelValue = "other"
+
+// DefaultMetricClusterLimit is the default number of the managed clusters labeled by their names in the metrics
+const DefaultMetricClusterLimit = 500
+
+// ClusterLabeler returns the values of the managed_cluster and clusterset labels of the metrics of the managed
+// clusters. To avoid the cardinality explosion on a large fleet, only the first clusters up to a limit observed
+// are labeled by their names, and the others share the OtherClustersLabelValue. A cluster keeps its label until
+// the process restarts, so the number of the series is bounded. The per-cluster label is opted out by setting the
+// limit to zero, then the metrics are only sliced by clustersets.
+type ClusterLabeler struct {
+	lock          sync.Mutex
+	limit         int
+	clusters      sets.String
+	clusterLister clusterv1listers.ManagedClusterLister
+}
+
+// NewClusterLabeler returns a ClusterLabeler which labels at most limit clusters by their names
+func NewClusterLabeler(limit int) *ClusterLabeler {
+	return &ClusterLabeler{
+		limit:    limit,
+		clusters: sets.NewString(),
+	}
+}
+
+// DefaultClusterLabeler is the ClusterLabeler shared by the controllers of the hub
+var DefaultClusterLabeler = NewClusterLabeler(DefaultMetricClusterLimit)
+
+// SetLimit sets the number of the clusters labeled by their names, the clusters labeled already are kept.
+func (l *ClusterLabeler) SetLimit(limit int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.limit = limit
+}
+
+// SetClusterLister sets the lister to look up the clusterset of a cluster, the clusterset label is empty if it
+// is not set.
+func (l *ClusterLabeler) SetClusterLister(clusterLister clusterv1listers.ManagedClusterLister) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.clusterLister = clusterLister
+}
+
+// LabelValues returns the values of the managed_cluster and clusterset labels of a cluster
+func (l *ClusterLabeler) LabelValues(clusterName string) []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	managedCluster := OtherClustersLabelValue
+	if l.clusters.Has(clusterName) {
+		managedCluster = clusterName
+	} else if l.clusters.Len() < l.limit {
+		l.clusters.Insert(clusterName)
+		managedCluster = clusterName
+	}
+
+	clusterSet := ""
+	if l.clusterLister != nil {
+		if cluster, err := l.clusterLister.Get(clusterName); err == nil {
+			clusterSet = cluster.Labels[ClusterSetLabel]
+		}
+	}
+	return []string{managedCluster, clusterSet}
+}
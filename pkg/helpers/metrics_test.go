@@ -0,0 +1,67 @@
+package helpers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClusterLabeler(t *testing.T) {
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterfake.NewSimpleClientset(), 10*time.Minute)
+	clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+	for _, name := range []string{"cluster1", "cluster2", "cluster3"} {
+		clusterStore.Add(&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{ClusterSetLabel: "set1"}},
+		})
+	}
+
+	cases := []struct {
+		name           string
+		limit          int
+		clusters       []string
+		expectedValues [][]string
+	}{
+		{
+			name:     "clusters within the limit",
+			limit:    2,
+			clusters: []string{"cluster1", "cluster2", "cluster1"},
+			expectedValues: [][]string{
+				{"cluster1", "set1"}, {"cluster2", "set1"}, {"cluster1", "set1"},
+			},
+		},
+		{
+			name:     "clusters beyond the limit",
+			limit:    2,
+			clusters: []string{"cluster1", "cluster2", "cluster3", "cluster1"},
+			expectedValues: [][]string{
+				{"cluster1", "set1"}, {"cluster2", "set1"}, {OtherClustersLabelValue, "set1"}, {"cluster1", "set1"},
+			},
+		},
+		{
+			name:     "per-cluster label is opted out",
+			limit:    0,
+			clusters: []string{"cluster1", "cluster4"},
+			expectedValues: [][]string{
+				{OtherClustersLabelValue, "set1"}, {OtherClustersLabelValue, ""},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			labeler := NewClusterLabeler(c.limit)
+			labeler.SetClusterLister(clusterInformerFactory.Cluster().V1().ManagedClusters().Lister())
+			for i, cluster := range c.clusters {
+				if values := labeler.LabelValues(cluster); !reflect.DeepEqual(values, c.expectedValues[i]) {
+					t.Errorf("expected label values %v of %s, but got %v", c.expectedValues[i], cluster, values)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package helpers
+
+import (
+	"sync"
+
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// lockedResourceCache is a resourceapply.ResourceCache which is safe for concurrent use
+type lockedResourceCache struct {
+	lock  sync.Mutex
+	cache resourceapply.ResourceCache
+}
+
+// NewResourceCache returns a resourceapply.ResourceCache which is safe for concurrent use. The cache of library-go
+// is a plain map, so it is wrapped with a lock for the controllers running more than one worker.
+func NewResourceCache() resourceapply.ResourceCache {
+	return &lockedResourceCache{cache: resourceapply.NewResourceCache()}
+}
+
+func (c *lockedResourceCache) UpdateCachedResourceMetadata(required runtime.Object, actual runtime.Object) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.cache.UpdateCachedResourceMetadata(required, actual)
+}
+
+func (c *lockedResourceCache) SafeToSkipApply(required runtime.Object, existing runtime.Object) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.cache.SafeToSkipApply(required, existing)
+}
@@ -0,0 +1,141 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	applycorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// SecretFieldManager is the field manager the agent applies the hub kubeconfig secret and the addon credential
+// secrets with
+const SecretFieldManager = "registration-agent"
+
+// legacySecretFieldManager is the field manager the agent updated the secrets with before they were applied, which
+// the kube-apiserver derives from the default user agent of the clients, e.g. "registration".
+var legacySecretFieldManager = strings.SplitN(rest.DefaultKubernetesUserAgent(), "/", 2)[0]
+
+var secretWritesSkipped = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Name: "open_cluster_management_registration_secret_writes_skipped_total",
+		Help: "Number of the writes of the secrets skipped by the agent since the secrets have the applied content already, partitioned by secret.",
+	},
+	[]string{"secret"},
+)
+
+func init() {
+	legacyregistry.MustRegister(secretWritesSkipped)
+}
+
+// ApplySecret applies the labels, annotations, type and data of a secret with server-side apply under the
+// SecretFieldManager. The agent only owns the fields in the given secret, so the labels and annotations added by
+// other tools, e.g. GitOps and backup tools, are kept, and a data key owned by the agent is removed once it is
+// missing in the given secret. The write is skipped if the existing secret, which is optional, has the content of
+// the given secret already.
+//
+// The fields the agent wrote with updates before are owned by the legacy update manager instead, and would be kept
+// by the apply. So the managed fields of the legacy update manager in the existing secret are migrated to the
+// SecretFieldManager before the first apply, which requires the existing secret to keep its managed fields, e.g. got
+// from the kube-apiserver rather than a cache stripping them.
+func ApplySecret(ctx context.Context, client corev1client.SecretsGetter, existing, secret *corev1.Secret) (*corev1.Secret, error) {
+	if existing != nil && hasSecretContent(existing, secret) {
+		secretWritesSkipped.WithLabelValues(secret.Namespace + "/" + secret.Name).Inc()
+		return existing, nil
+	}
+
+	if existing != nil && len(existing.UID) > 0 {
+		if err := migrateSecretManagedFields(ctx, client, existing); err != nil {
+			return nil, err
+		}
+	}
+
+	applyConfig := applycorev1.Secret(secret.Name, secret.Namespace).WithData(secret.Data)
+	if len(secret.Labels) > 0 {
+		applyConfig = applyConfig.WithLabels(secret.Labels)
+	}
+	if len(secret.Annotations) > 0 {
+		applyConfig = applyConfig.WithAnnotations(secret.Annotations)
+	}
+	if len(secret.Type) > 0 {
+		applyConfig = applyConfig.WithType(secret.Type)
+	}
+	return client.Secrets(secret.Namespace).Apply(ctx, applyConfig, metav1.ApplyOptions{FieldManager: SecretFieldManager, Force: true})
+}
+
+// migrateSecretManagedFields hands the fields of the legacy update manager over to the SecretFieldManager, if the
+// secret has not been applied by the SecretFieldManager yet. The resourceVersion of the existing secret is kept in
+// the patch, so it fails with a conflict if the secret has changed in the meantime.
+func migrateSecretManagedFields(ctx context.Context, client corev1client.SecretsGetter, existing *corev1.Secret) error {
+	managedFields := []metav1.ManagedFieldsEntry{}
+	migrated := false
+	for _, entry := range existing.ManagedFields {
+		if entry.Manager == SecretFieldManager && entry.Operation == metav1.ManagedFieldsOperationApply {
+			// the secret is applied already
+			return nil
+		}
+		if entry.Manager == legacySecretFieldManager && entry.Operation == metav1.ManagedFieldsOperationUpdate &&
+			len(entry.Subresource) == 0 && !migrated {
+			entry.Manager = SecretFieldManager
+			entry.Operation = metav1.ManagedFieldsOperationApply
+			migrated = true
+		}
+		managedFields = append(managedFields, entry)
+	}
+	if !migrated {
+		return nil
+	}
+
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "replace", "path": "/metadata/managedFields", "value": managedFields},
+		{"op": "replace", "path": "/metadata/resourceVersion", "value": existing.ResourceVersion},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.Secrets(existing.Namespace).Patch(ctx, existing.Name, types.JSONPatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// hasSecretContent returns true if the existing secret has the labels, annotations and type of the applied secret,
+// and the same data. The data keys are compared as a whole, since a key missing in the applied secret might be
+// owned by the agent and removed by the apply.
+func hasSecretContent(existing, secret *corev1.Secret) bool {
+	if len(existing.UID) == 0 {
+		// the secret does not exist
+		return false
+	}
+	if existing.Namespace != secret.Namespace || existing.Name != secret.Name {
+		return false
+	}
+	if len(secret.Type) > 0 && existing.Type != secret.Type {
+		return false
+	}
+	for k, v := range secret.Labels {
+		if value, ok := existing.Labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	for k, v := range secret.Annotations {
+		if value, ok := existing.Annotations[k]; !ok || value != v {
+			return false
+		}
+	}
+	if len(existing.Data) != len(secret.Data) {
+		return false
+	}
+	for k, v := range secret.Data {
+		if value, ok := existing.Data[k]; !ok || !bytes.Equal(value, v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package helpers
+
+import (
+	"context"
+	"testing"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newSecret(labels map[string]string, data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns1",
+			Name:      "secret1",
+			UID:       "uid1",
+			Labels:    labels,
+		},
+		Data: data,
+	}
+}
+
+func newSecretWithManagedFields(managedFields ...metav1.ManagedFieldsEntry) *corev1.Secret {
+	secret := newSecret(nil, map[string][]byte{"kubeconfig": []byte("old")})
+	secret.ResourceVersion = "1"
+	secret.ManagedFields = managedFields
+	return secret
+}
+
+func TestApplySecret(t *testing.T) {
+	applied := newSecret(map[string]string{"app": "registration"}, map[string][]byte{"kubeconfig": []byte("kubeconfig")})
+	applied.UID = ""
+
+	cases := []struct {
+		name            string
+		existing        *corev1.Secret
+		expectedActions []string
+		expectedPatches []types.PatchType
+	}{
+		{
+			name:            "no existing secret",
+			expectedActions: []string{"patch"},
+		},
+		{
+			name:            "secret not found",
+			existing:        &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "secret1"}},
+			expectedActions: []string{"patch"},
+		},
+		{
+			name: "unchanged secret",
+			existing: newSecret(map[string]string{"app": "registration", "backup": "true"},
+				map[string][]byte{"kubeconfig": []byte("kubeconfig")}),
+		},
+		{
+			name:            "data changed",
+			existing:        newSecret(map[string]string{"app": "registration"}, map[string][]byte{"kubeconfig": []byte("old")}),
+			expectedActions: []string{"patch"},
+		},
+		{
+			name: "data key to remove",
+			existing: newSecret(map[string]string{"app": "registration"},
+				map[string][]byte{"kubeconfig": []byte("kubeconfig"), "token": []byte("token")}),
+			expectedActions: []string{"patch"},
+		},
+		{
+			name:            "label missing",
+			existing:        newSecret(nil, map[string][]byte{"kubeconfig": []byte("kubeconfig")}),
+			expectedActions: []string{"patch"},
+		},
+		{
+			name: "secret updated by the agent before",
+			existing: newSecretWithManagedFields(
+				metav1.ManagedFieldsEntry{Manager: legacySecretFieldManager, Operation: metav1.ManagedFieldsOperationUpdate},
+				metav1.ManagedFieldsEntry{Manager: "gitops", Operation: metav1.ManagedFieldsOperationUpdate},
+			),
+			expectedActions: []string{"patch", "patch"},
+			expectedPatches: []types.PatchType{types.JSONPatchType, types.ApplyPatchType},
+		},
+		{
+			name: "secret applied by the agent",
+			existing: newSecretWithManagedFields(
+				metav1.ManagedFieldsEntry{Manager: legacySecretFieldManager, Operation: metav1.ManagedFieldsOperationUpdate},
+				metav1.ManagedFieldsEntry{Manager: SecretFieldManager, Operation: metav1.ManagedFieldsOperationApply},
+			),
+			expectedActions: []string{"patch"},
+			expectedPatches: []types.PatchType{types.ApplyPatchType},
+		},
+		{
+			name: "secret updated by other managers",
+			existing: newSecretWithManagedFields(
+				metav1.ManagedFieldsEntry{Manager: "gitops", Operation: metav1.ManagedFieldsOperationUpdate},
+			),
+			expectedActions: []string{"patch"},
+			expectedPatches: []types.PatchType{types.ApplyPatchType},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if len(c.expectedPatches) > 0 {
+				objects = append(objects, c.existing)
+			}
+			kubeClient := fakekube.NewSimpleClientset(objects...)
+			testinghelpers.AddSecretApplyReactor(&kubeClient.Fake, kubeClient.Tracker())
+			// the fake client fails to apply a secret which does not exist, only the actions are checked
+			_, _ = ApplySecret(context.TODO(), kubeClient.CoreV1(), c.existing, applied)
+			testinghelpers.AssertActions(t, kubeClient.Actions(), c.expectedActions...)
+			for i, patchType := range c.expectedPatches {
+				if actual := kubeClient.Actions()[i].(clienttesting.PatchActionImpl).GetPatchType(); actual != patchType {
+					t.Errorf("expected patch %q, but got %q", patchType, actual)
+				}
+			}
+
+			if len(c.expectedPatches) < 2 {
+				return
+			}
+			secret, err := kubeClient.CoreV1().Secrets("ns1").Get(context.TODO(), "secret1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if secret.ManagedFields[0].Manager != SecretFieldManager ||
+				secret.ManagedFields[0].Operation != metav1.ManagedFieldsOperationApply {
+				t.Errorf("expected the fields of the agent are migrated, but got %v", secret.ManagedFields[0])
+			}
+			if secret.ManagedFields[1].Manager != "gitops" {
+				t.Errorf("expected the fields of other managers are kept, but got %v", secret.ManagedFields[1])
+			}
+		})
+	}
+}
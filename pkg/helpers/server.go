@@ -0,0 +1,54 @@
+package helpers
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// ServeHTTP serves the handler on the given address until the context is done. The listener is created before
+// it returns, so an address in use is reported to the caller, while the errors of the server afterwards are
+// logged with the name of the server.
+func ServeHTTP(ctx context.Context, name, address string, handler http.Handler) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	serve(ctx, name, listener, handler)
+	return nil
+}
+
+// ServeHTTPS serves the handler over TLS with the given config on the given address until the context is done,
+// see ServeHTTP.
+func ServeHTTPS(ctx context.Context, name, address string, tlsConfig *tls.Config, handler http.Handler) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	serve(ctx, name, tls.NewListener(listener, tlsConfig), handler)
+	return nil
+}
+
+func serve(ctx context.Context, name string, listener net.Listener, handler http.Handler) {
+	server := &http.Server{Handler: handler}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			klog.Warningf("Unable to shutdown the %s server: %v", name, err)
+		}
+	}()
+
+	go func() {
+		klog.Infof("Serving %s on %s", name, listener.Addr())
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			klog.Errorf("The %s server exited: %v", name, err)
+		}
+	}()
+}
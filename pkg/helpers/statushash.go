@@ -0,0 +1,43 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// ClusterStatusPayloadHash returns the hash of the bulky sections of the status of a managed cluster, which are the
+// cluster claims, the capacity, the allocatable and the version. The order of the claims is ignored, and the
+// quantities are hashed in their canonical form, so two statuses equal with StatusEqual have the same hash.
+func ClusterStatusPayloadHash(status *clusterv1.ManagedClusterStatus) string {
+	data, _ := json.Marshal(struct {
+		ClusterClaims []clusterv1.ManagedClusterClaim `json:"clusterClaims"`
+		Capacity      clusterv1.ResourceList          `json:"capacity"`
+		Allocatable   clusterv1.ResourceList          `json:"allocatable"`
+		Version       clusterv1.ManagedClusterVersion `json:"version"`
+	}{
+		ClusterClaims: sortedClaims(status.ClusterClaims),
+		Capacity:      status.Capacity,
+		Allocatable:   status.Allocatable,
+		Version:       status.Version,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ClusterStatusUpToDate returns true if the update funcs change nothing of the status of a managed cluster in the
+// informer cache. The agent skips the status whose payload hash and conditions match the ones the hub already has,
+// so neither the managed cluster is fetched from the hub nor the status is patched. The cached status is not
+// modified.
+func ClusterStatusUpToDate(cached *clusterv1.ManagedClusterStatus, updateFuncs ...UpdateManagedClusterStatusFunc) (bool, error) {
+	desired := cached.DeepCopy()
+	for _, update := range updateFuncs {
+		if err := update(desired); err != nil {
+			return false, err
+		}
+	}
+	return ClusterStatusPayloadHash(desired) == ClusterStatusPayloadHash(cached) &&
+		StatusEqual(desired.Conditions, cached.Conditions), nil
+}
@@ -0,0 +1,73 @@
+package helpers
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func TestClusterStatusUpToDate(t *testing.T) {
+	cached := &clusterv1.ManagedClusterStatus{
+		Conditions: []metav1.Condition{
+			{Type: clusterv1.ManagedClusterConditionAvailable, Status: metav1.ConditionTrue, Reason: "ManagedClusterAvailable"},
+		},
+		Capacity: clusterv1.ResourceList{clusterv1.ResourceCPU: resource.MustParse("4")},
+		ClusterClaims: []clusterv1.ManagedClusterClaim{
+			{Name: "a", Value: "1"},
+			{Name: "b", Value: "2"},
+		},
+	}
+
+	cases := []struct {
+		name             string
+		update           UpdateManagedClusterStatusFunc
+		expectedUpToDate bool
+	}{
+		{
+			name: "claims in another order and quantities in another form",
+			update: func(status *clusterv1.ManagedClusterStatus) error {
+				status.Capacity = clusterv1.ResourceList{clusterv1.ResourceCPU: resource.MustParse("4000m")}
+				status.ClusterClaims = []clusterv1.ManagedClusterClaim{{Name: "b", Value: "2"}, {Name: "a", Value: "1"}}
+				return nil
+			},
+			expectedUpToDate: true,
+		},
+		{
+			name: "claim changed",
+			update: func(status *clusterv1.ManagedClusterStatus) error {
+				status.ClusterClaims = []clusterv1.ManagedClusterClaim{{Name: "a", Value: "1"}, {Name: "b", Value: "3"}}
+				return nil
+			},
+		},
+		{
+			name: "capacity changed",
+			update: func(status *clusterv1.ManagedClusterStatus) error {
+				status.Capacity = clusterv1.ResourceList{clusterv1.ResourceCPU: resource.MustParse("8")}
+				return nil
+			},
+		},
+		{
+			name: "condition changed",
+			update: UpdateManagedClusterConditionFn(metav1.Condition{
+				Type: clusterv1.ManagedClusterConditionAvailable, Status: metav1.ConditionFalse, Reason: "ManagedClusterKubeAPIServerUnavailable",
+			}),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			upToDate, err := ClusterStatusUpToDate(cached, c.update)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if upToDate != c.expectedUpToDate {
+				t.Errorf("expected up to date %v, but got %v", c.expectedUpToDate, upToDate)
+			}
+			if len(cached.ClusterClaims) != 2 || cached.ClusterClaims[1].Value != "2" {
+				t.Errorf("expected the cached status is not modified, but got %v", cached)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package testing
+
+import (
+	"encoding/json"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// AddSecretApplyReactor makes a fake client support the server-side apply of secrets, which the object tracker
+// does not support. It only approximates the apply: the applied configuration is merged into the existing secret
+// like a json merge patch, and the managed fields are not tracked, so a field missing in the applied configuration
+// is never removed. The tests relying on the field ownership have to check the apply actions instead.
+func AddSecretApplyReactor(client *clienttesting.Fake, tracker clienttesting.ObjectTracker) {
+	client.PrependReactor("patch", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patchAction := action.(clienttesting.PatchActionImpl)
+		if patchAction.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+
+		secret := &corev1.Secret{}
+		existing, err := tracker.Get(patchAction.GetResource(), patchAction.GetNamespace(), patchAction.GetName())
+		if errors.IsNotFound(err) {
+			if err := json.Unmarshal(patchAction.GetPatch(), secret); err != nil {
+				return true, nil, err
+			}
+			return true, secret, tracker.Create(patchAction.GetResource(), secret, patchAction.GetNamespace())
+		}
+		if err != nil {
+			return true, nil, err
+		}
+
+		existingData, err := json.Marshal(existing)
+		if err != nil {
+			return true, nil, err
+		}
+		mergedData, err := jsonpatch.MergePatch(existingData, patchAction.GetPatch())
+		if err != nil {
+			return true, nil, err
+		}
+		if err := json.Unmarshal(mergedData, secret); err != nil {
+			return true, nil, err
+		}
+		return true, secret, tracker.Update(patchAction.GetResource(), secret, patchAction.GetNamespace())
+	})
+}
+
+// AppliedSecret decodes the applied configuration of a server-side apply action to a secret
+func AppliedSecret(t *testing.T, action clienttesting.Action) *corev1.Secret {
+	patchAction := action.(clienttesting.PatchActionImpl)
+	if patchAction.GetPatchType() != types.ApplyPatchType {
+		t.Fatalf("expected a server-side apply, but got %s", patchAction.GetPatchType())
+	}
+	secret := &corev1.Secret{}
+	if err := json.Unmarshal(patchAction.GetPatch(), secret); err != nil {
+		t.Fatalf("unable to decode the applied secret: %v", err)
+	}
+	return secret
+}
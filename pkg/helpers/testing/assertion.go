@@ -2,6 +2,7 @@ package testing
 
 import (
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"reflect"
@@ -13,6 +14,7 @@ import (
 	authorizationv1 "k8s.io/api/authorization/v1"
 	certv1 "k8s.io/api/certificates/v1"
 	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -72,6 +74,8 @@ func AssertNoActions(t *testing.T, actualActions []clienttesting.Action) {
 }
 
 // AssertUpdateActions asserts the actions are get-then-update action
+//
+// Deprecated: the agent patches the managed cluster and the lease instead of updating them, use AssertPatchActions.
 func AssertUpdateActions(t *testing.T, actions []clienttesting.Action) {
 	for i := 0; i < len(actions); i = i + 2 {
 		if actions[i].GetVerb() != "get" {
@@ -84,6 +88,8 @@ func AssertUpdateActions(t *testing.T, actions []clienttesting.Action) {
 }
 
 // AssertNoMoreUpdates asserts only one update action in given actions
+//
+// Deprecated: the agent patches the managed cluster and the lease instead of updating them, use AssertNoMorePatches.
 func AssertNoMoreUpdates(t *testing.T, actions []clienttesting.Action) {
 	updateActions := 0
 	for _, action := range actions {
@@ -96,6 +102,49 @@ func AssertNoMoreUpdates(t *testing.T, actions []clienttesting.Action) {
 	}
 }
 
+// AssertPatchActions asserts the actions are patch actions
+func AssertPatchActions(t *testing.T, actions []clienttesting.Action) {
+	for i, action := range actions {
+		if action.GetVerb() != "patch" {
+			t.Errorf("expected action %d is patch, but %v", i, action)
+		}
+	}
+}
+
+// AssertNoMorePatches asserts only one patch action in given actions
+func AssertNoMorePatches(t *testing.T, actions []clienttesting.Action) {
+	patchActions := 0
+	for _, action := range actions {
+		if action.GetVerb() == "patch" {
+			patchActions++
+		}
+	}
+	if patchActions != 1 {
+		t.Errorf("expected there is only one patch action, but failed")
+	}
+}
+
+// PatchedManagedCluster decodes the json merge patch of a patch action to a managed cluster, which only has the
+// patched fields.
+func PatchedManagedCluster(t *testing.T, action clienttesting.Action) *clusterv1.ManagedCluster {
+	managedCluster := &clusterv1.ManagedCluster{}
+	if err := json.Unmarshal(action.(clienttesting.PatchActionImpl).Patch, managedCluster); err != nil {
+		t.Fatalf("unable to decode the patch of managed cluster: %v", err)
+	}
+	return managedCluster
+}
+
+// PatchedLease decodes the json merge patch of a patch action to a lease, which only has the patched fields.
+func PatchedLease(t *testing.T, action clienttesting.Action) *coordinationv1.Lease {
+	patchAction := action.(clienttesting.PatchActionImpl)
+	lease := &coordinationv1.Lease{}
+	if err := json.Unmarshal(patchAction.Patch, lease); err != nil {
+		t.Fatalf("unable to decode the patch of lease: %v", err)
+	}
+	lease.Namespace, lease.Name = patchAction.Namespace, patchAction.Name
+	return lease
+}
+
 // AssertFinalizers asserts the given runtime object has the expected finalizers
 func AssertFinalizers(t *testing.T, obj runtime.Object, finalizers []string) {
 	accessor, _ := meta.Accessor(obj)
@@ -146,16 +195,16 @@ func AssertManagedClusterStatus(t *testing.T, actual, expected clusterv1.Managed
 	if !reflect.DeepEqual(actual.Version, expected.Version) {
 		t.Errorf("expected version %#v but got: %#v", expected.Version, actual.Version)
 	}
-	if !reflect.DeepEqual(actual.Capacity["cpu"], expected.Capacity["cpu"]) {
+	if !equality.Semantic.DeepEqual(actual.Capacity["cpu"], expected.Capacity["cpu"]) {
 		t.Errorf("expected cpu capacity %#v but got: %#v", expected.Capacity["cpu"], actual.Capacity["cpu"])
 	}
-	if !reflect.DeepEqual(actual.Capacity["memory"], expected.Capacity["memory"]) {
+	if !equality.Semantic.DeepEqual(actual.Capacity["memory"], expected.Capacity["memory"]) {
 		t.Errorf("expected memory capacity %#v but got: %#v", expected.Capacity["memory"], actual.Capacity["memory"])
 	}
-	if !reflect.DeepEqual(actual.Allocatable["cpu"], expected.Allocatable["cpu"]) {
+	if !equality.Semantic.DeepEqual(actual.Allocatable["cpu"], expected.Allocatable["cpu"]) {
 		t.Errorf("expected cpu allocatable %#v but got: %#v", expected.Allocatable["cpu"], actual.Allocatable["cpu"])
 	}
-	if !reflect.DeepEqual(actual.Allocatable["memory"], expected.Allocatable["memory"]) {
+	if !equality.Semantic.DeepEqual(actual.Allocatable["memory"], expected.Allocatable["memory"]) {
 		t.Errorf("expected memory alocatabel %#v but got: %#v", expected.Allocatable["memory"], actual.Allocatable["memory"])
 	}
 }
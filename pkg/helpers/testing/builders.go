@@ -0,0 +1,229 @@
+package testing
+
+import (
+	"time"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	coordv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"open-cluster-management.io/registration/pkg/helpers/clusterset"
+)
+
+// ManagedClusterBuilder builds ManagedClusters for tests
+type ManagedClusterBuilder struct {
+	cluster *clusterv1.ManagedCluster
+}
+
+// NewManagedClusterBuilder returns a ManagedClusterBuilder building a ManagedCluster with the name
+func NewManagedClusterBuilder(name string) *ManagedClusterBuilder {
+	return &ManagedClusterBuilder{
+		cluster: &clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+		},
+	}
+}
+
+// WithLabels adds the labels to the ManagedCluster
+func (b *ManagedClusterBuilder) WithLabels(labels map[string]string) *ManagedClusterBuilder {
+	if b.cluster.Labels == nil {
+		b.cluster.Labels = map[string]string{}
+	}
+	for k, v := range labels {
+		b.cluster.Labels[k] = v
+	}
+	return b
+}
+
+// WithClusterSet adds the ManagedCluster to the ManagedClusterSet, see helpers.ClusterSetLabel
+func (b *ManagedClusterBuilder) WithClusterSet(clusterSetName string) *ManagedClusterBuilder {
+	return b.WithLabels(map[string]string{clusterset.Label: clusterSetName})
+}
+
+// WithFinalizers sets the finalizers of the ManagedCluster
+func (b *ManagedClusterBuilder) WithFinalizers(finalizers ...string) *ManagedClusterBuilder {
+	b.cluster.Finalizers = finalizers
+	return b
+}
+
+// WithDeletionTimestamp marks the ManagedCluster deleting
+func (b *ManagedClusterBuilder) WithDeletionTimestamp() *ManagedClusterBuilder {
+	now := metav1.Now()
+	b.cluster.DeletionTimestamp = &now
+	return b
+}
+
+// WithHubAcceptsClient sets whether the hub accepts the ManagedCluster
+func (b *ManagedClusterBuilder) WithHubAcceptsClient(accepted bool) *ManagedClusterBuilder {
+	b.cluster.Spec.HubAcceptsClient = accepted
+	return b
+}
+
+// WithLeaseDurationSeconds sets the lease duration of the ManagedCluster
+func (b *ManagedClusterBuilder) WithLeaseDurationSeconds(seconds int32) *ManagedClusterBuilder {
+	b.cluster.Spec.LeaseDurationSeconds = seconds
+	return b
+}
+
+// WithClientConfigs sets the client configs of the ManagedCluster
+func (b *ManagedClusterBuilder) WithClientConfigs(clientConfigs ...clusterv1.ClientConfig) *ManagedClusterBuilder {
+	b.cluster.Spec.ManagedClusterClientConfigs = clientConfigs
+	return b
+}
+
+// WithTaints sets the taints of the ManagedCluster
+func (b *ManagedClusterBuilder) WithTaints(taints ...clusterv1.Taint) *ManagedClusterBuilder {
+	b.cluster.Spec.Taints = taints
+	return b
+}
+
+// WithClaims sets the cluster claims in the status of the ManagedCluster
+func (b *ManagedClusterBuilder) WithClaims(claims ...clusterv1.ManagedClusterClaim) *ManagedClusterBuilder {
+	b.cluster.Status.ClusterClaims = claims
+	return b
+}
+
+// WithCondition sets the condition in the status of the ManagedCluster
+func (b *ManagedClusterBuilder) WithCondition(conditionType string, status metav1.ConditionStatus, reason, message string) *ManagedClusterBuilder {
+	b.cluster.Status.Conditions = setCondition(b.cluster.Status.Conditions,
+		NewManagedClusterCondition(conditionType, string(status), reason, message, nil))
+	return b
+}
+
+// Accepted marks the ManagedCluster accepted by the hub, like NewAcceptedManagedCluster
+func (b *ManagedClusterBuilder) Accepted() *ManagedClusterBuilder {
+	return b.WithFinalizers("cluster.open-cluster-management.io/api-resource-cleanup").
+		WithHubAcceptsClient(true).
+		WithLeaseDurationSeconds(TestLeaseDurationSeconds).
+		WithCondition(clusterv1.ManagedClusterConditionHubAccepted, metav1.ConditionTrue,
+			"HubClusterAdminAccepted", "Accepted by hub cluster admin")
+}
+
+// Joined marks the ManagedCluster accepted and joined, like NewJoinedManagedCluster
+func (b *ManagedClusterBuilder) Joined() *ManagedClusterBuilder {
+	return b.Accepted().
+		WithCondition(clusterv1.ManagedClusterConditionJoined, metav1.ConditionTrue,
+			"ManagedClusterJoined", "Managed cluster joined")
+}
+
+// Available marks the ManagedCluster accepted and available, like NewAvailableManagedCluster
+func (b *ManagedClusterBuilder) Available() *ManagedClusterBuilder {
+	return b.Accepted().
+		WithCondition(clusterv1.ManagedClusterConditionAvailable, metav1.ConditionTrue,
+			"ManagedClusterAvailable", "Managed cluster is available")
+}
+
+// Build returns a copy of the built ManagedCluster
+func (b *ManagedClusterBuilder) Build() *clusterv1.ManagedCluster {
+	return b.cluster.DeepCopy()
+}
+
+// ManagedClusterSetBuilder builds ManagedClusterSets for tests
+type ManagedClusterSetBuilder struct {
+	clusterSet *clusterv1beta1.ManagedClusterSet
+}
+
+// NewManagedClusterSetBuilder returns a ManagedClusterSetBuilder building a ManagedClusterSet with the name, which
+// selects the ManagedClusters with the clusterset label
+func NewManagedClusterSetBuilder(name string) *ManagedClusterSetBuilder {
+	return &ManagedClusterSetBuilder{
+		clusterSet: &clusterv1beta1.ManagedClusterSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: clusterv1beta1.ManagedClusterSetSpec{
+				ClusterSelector: clusterv1beta1.ManagedClusterSelector{
+					SelectorType: clusterv1beta1.LegacyClusterSetLabel,
+				},
+			},
+		},
+	}
+}
+
+// WithDeletionTimestamp marks the ManagedClusterSet deleting
+func (b *ManagedClusterSetBuilder) WithDeletionTimestamp() *ManagedClusterSetBuilder {
+	now := metav1.Now()
+	b.clusterSet.DeletionTimestamp = &now
+	return b
+}
+
+// WithCondition sets the condition in the status of the ManagedClusterSet
+func (b *ManagedClusterSetBuilder) WithCondition(conditionType string, status metav1.ConditionStatus, reason, message string) *ManagedClusterSetBuilder {
+	b.clusterSet.Status.Conditions = setCondition(b.clusterSet.Status.Conditions,
+		NewManagedClusterCondition(conditionType, string(status), reason, message, nil))
+	return b
+}
+
+// Build returns a copy of the built ManagedClusterSet
+func (b *ManagedClusterSetBuilder) Build() *clusterv1beta1.ManagedClusterSet {
+	return b.clusterSet.DeepCopy()
+}
+
+// NewManagedClusterSetBinding returns a ManagedClusterSetBinding binding the ManagedClusterSet to the namespace
+func NewManagedClusterSetBinding(namespace, clusterSetName string) *clusterv1beta1.ManagedClusterSetBinding {
+	return &clusterv1beta1.ManagedClusterSetBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      clusterSetName,
+		},
+		Spec: clusterv1beta1.ManagedClusterSetBindingSpec{
+			ClusterSet: clusterSetName,
+		},
+	}
+}
+
+// LeaseBuilder builds leases for tests
+type LeaseBuilder struct {
+	lease *coordv1.Lease
+}
+
+// NewLeaseBuilder returns a LeaseBuilder building a lease with the namespace and name
+func NewLeaseBuilder(namespace, name string) *LeaseBuilder {
+	return &LeaseBuilder{
+		lease: &coordv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+			},
+		},
+	}
+}
+
+// WithRenewTime sets the renew time of the lease
+func (b *LeaseBuilder) WithRenewTime(renewTime time.Time) *LeaseBuilder {
+	b.lease.Spec.RenewTime = &metav1.MicroTime{Time: renewTime}
+	return b
+}
+
+// WithLeaseDurationSeconds sets the lease duration of the lease
+func (b *LeaseBuilder) WithLeaseDurationSeconds(seconds int32) *LeaseBuilder {
+	b.lease.Spec.LeaseDurationSeconds = &seconds
+	return b
+}
+
+// WithHolderIdentity sets the holder of the lease
+func (b *LeaseBuilder) WithHolderIdentity(holderIdentity string) *LeaseBuilder {
+	b.lease.Spec.HolderIdentity = &holderIdentity
+	return b
+}
+
+// Build returns a copy of the built lease
+func (b *LeaseBuilder) Build() *coordv1.Lease {
+	return b.lease.DeepCopy()
+}
+
+// setCondition replaces the condition with the same type, or appends it. Unlike meta.SetStatusCondition, the last
+// transition time is not set, so that the built objects can be compared.
+func setCondition(conditions []metav1.Condition, condition metav1.Condition) []metav1.Condition {
+	for i := range conditions {
+		if conditions[i].Type == condition.Type {
+			conditions[i] = condition
+			return conditions
+		}
+	}
+	return append(conditions, condition)
+}
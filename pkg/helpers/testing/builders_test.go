@@ -0,0 +1,65 @@
+package testing
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"open-cluster-management.io/registration/pkg/helpers/clusterset"
+)
+
+func TestBuilders(t *testing.T) {
+	now := time.Now()
+
+	cases := []struct {
+		name     string
+		built    runtime.Object
+		expected runtime.Object
+	}{
+		{
+			name:     "managed cluster",
+			built:    NewManagedClusterBuilder(TestManagedClusterName).Build(),
+			expected: NewManagedCluster(),
+		},
+		{
+			name:     "accepted managed cluster",
+			built:    NewManagedClusterBuilder(TestManagedClusterName).Accepted().Build(),
+			expected: NewAcceptedManagedCluster(),
+		},
+		{
+			name:     "joined managed cluster",
+			built:    NewManagedClusterBuilder(TestManagedClusterName).Joined().Build(),
+			expected: NewJoinedManagedCluster(),
+		},
+		{
+			name:     "available managed cluster",
+			built:    NewManagedClusterBuilder(TestManagedClusterName).Available().Build(),
+			expected: NewAvailableManagedCluster(),
+		},
+		{
+			name:     "managed cluster lease",
+			built:    NewLeaseBuilder(TestManagedClusterName, "managed-cluster-lease").WithRenewTime(now).Build(),
+			expected: NewManagedClusterLease("managed-cluster-lease", now),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if !equality.Semantic.DeepEqual(c.built, c.expected) {
+				t.Errorf("expected %#v, but got %#v", c.expected, c.built)
+			}
+		})
+	}
+}
+
+func TestManagedClusterBuilderWithClusterSet(t *testing.T) {
+	cluster := NewManagedClusterBuilder("cluster1").
+		WithLabels(map[string]string{"env": "test"}).
+		WithClusterSet("clusterset1").
+		Build()
+	if cluster.Labels[clusterset.Label] != "clusterset1" || cluster.Labels["env"] != "test" {
+		t.Errorf("unexpected labels %v", cluster.Labels)
+	}
+}
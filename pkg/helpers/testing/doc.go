@@ -0,0 +1,6 @@
+// package testing provides the helpers to test the controllers working with the registration objects: fake sync
+// contexts, builders of ManagedClusters, ManagedClusterSets, leases, csrs, secrets and certificates, and the
+// assertions of the client actions. It is used by the tests of this repo, and is supported for the tests of the
+// downstream controllers, so the helpers are only added or extended compatibly. The fakehub package runs a fake hub for the
+// integration tests of the agents and the addons.
+package testing
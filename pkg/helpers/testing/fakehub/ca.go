@@ -0,0 +1,116 @@
+package fakehub
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"path/filepath"
+	"time"
+
+	"k8s.io/client-go/rest"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+// testCA is the CA the kube-apiserver of the fake hub trusts to authenticate the client certificates, it signs the
+// certificates of the users of envtest, of the bootstrap kubeconfigs and of the csrs
+type testCA struct {
+	cert   *x509.Certificate
+	key    crypto.Signer
+	caFile string
+	maxAge time.Duration
+}
+
+var _ envtest.Authn = &testCA{}
+
+func newTestCA(maxAge time.Duration) (*testCA, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := certutil.NewSelfSignedCACert(certutil.Config{CommonName: "fakehub-client-ca"}, key)
+	if err != nil {
+		return nil, err
+	}
+	return &testCA{cert: cert, key: key, maxAge: maxAge}, nil
+}
+
+// Configure makes the kube-apiserver trust the CA to authenticate the client certificates
+func (c *testCA) Configure(workDir string, args *envtest.Arguments) error {
+	c.caFile = filepath.Join(workDir, "fakehub-client-ca.crt")
+	args.Set("client-ca-file", c.caFile)
+	return nil
+}
+
+// Start writes the CA file before the kube-apiserver is started
+func (c *testCA) Start() error {
+	if len(c.caFile) == 0 {
+		return fmt.Errorf("start called before configure")
+	}
+	return ioutil.WriteFile(c.caFile, c.certPEM(), 0600)
+}
+
+// AddUser returns a copy of the config authenticated as the user with a client certificate signed by the CA
+func (c *testCA) AddUser(user envtest.User, baseCfg *rest.Config) (*rest.Config, error) {
+	certData, keyData, err := c.newClientCertKey(user.Name, user.Groups)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create client certificate for %s: %w", user.Name, err)
+	}
+	cfg := rest.CopyConfig(baseCfg)
+	cfg.CertData = certData
+	cfg.KeyData = keyData
+	return cfg, nil
+}
+
+// Stop does nothing, the CA file is removed with the work directory of envtest
+func (c *testCA) Stop() error {
+	return nil
+}
+
+func (c *testCA) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: certutil.CertificateBlockType, Bytes: c.cert.Raw})
+}
+
+// newClientCertKey returns a client certificate and its private key in PEM for the user and the groups
+func (c *testCA) newClientCertKey(user string, groups []string) ([]byte, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	certData, err := c.sign(pkix.Name{CommonName: user, Organization: groups}, key.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+	keyData := pem.EncodeToMemory(&pem.Block{Type: keyutil.RSAPrivateKeyBlockType, Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certData, keyData, nil
+}
+
+// sign returns a client certificate in PEM of the subject and the public key, which is valid for the max age of
+// the CA
+func (c *testCA) sign(subject pkix.Name, publicKey crypto.PublicKey) ([]byte, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).SetInt64(math.MaxInt64))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      subject,
+		NotBefore:    now.Add(-time.Minute).UTC(),
+		NotAfter:     now.Add(c.maxAge).UTC(),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, c.cert, publicKey, c.key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: certutil.CertificateBlockType, Bytes: der}), nil
+}
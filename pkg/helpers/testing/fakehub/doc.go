@@ -0,0 +1,20 @@
+// package fakehub runs a fake hub in the process of a test, so the agents and the addons are tested with the full
+// bootstrap flow without a real hub cluster. The fake hub is a kube-apiserver and an etcd started with envtest,
+// which requires the binaries of the kubebuilder assets, and it plays the part of the hub controller:
+//
+//   - the csrs of the kube-apiserver-client signer are approved and signed with the test CA the kube-apiserver
+//     trusts, unless Options.ManualCSRApproval is set, see ApproveCSR;
+//   - the managed clusters are accepted, their namespaces and leases are created and the HubAccepted condition is
+//     set, unless Options.ManualClusterAcceptance is set, see AcceptCluster;
+//   - the leases of the managed clusters are observed with LeaseRenewTime and WaitForLeaseRenewal.
+//
+// The bootstrap users and the managed clusters are bound to the cluster-admin role instead of the roles the hub
+// controller grants them, so the fake hub does not check the permissions of an agent. For example
+//
+//	hub, err := fakehub.Start(ctx, fakehub.Options{CRDDirectoryPaths: []string{"deploy/hub"}})
+//	...
+//	defer hub.Stop()
+//	err = hub.WriteBootstrapKubeconfig("/tmp/bootstrap/kubeconfig")
+//	...
+//	err = hub.WaitForLeaseRenewal(ctx, "cluster1", time.Now())
+package fakehub
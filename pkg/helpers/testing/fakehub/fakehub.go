@@ -0,0 +1,389 @@
+package fakehub
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/hub/user"
+)
+
+const (
+	// BootstrapUser and BootstrapGroup are the user and the group of the bootstrap kubeconfigs written by
+	// WriteBootstrapKubeconfig
+	BootstrapUser  = "system:open-cluster-management:fakehub:bootstrap"
+	BootstrapGroup = "system:bootstrappers:managedcluster"
+
+	// leaseName is the name of the lease the agent renews in the namespace of its cluster
+	leaseName = "managed-cluster-lease"
+
+	defaultCertificateDuration = 24 * time.Hour
+	defaultSyncInterval        = 200 * time.Millisecond
+)
+
+// Options are the options of the fake hub
+type Options struct {
+	// CRDDirectoryPaths are the directories of the CRDs installed on the hub, they must contain the ManagedCluster
+	// CRD, e.g. the deploy/hub directory of this repo.
+	CRDDirectoryPaths []string
+
+	// ManualCSRApproval and ManualClusterAcceptance stop the fake hub from approving the csrs and accepting the
+	// managed clusters automatically, so the test does it with ApproveCSR and AcceptCluster.
+	ManualCSRApproval       bool
+	ManualClusterAcceptance bool
+
+	// CertificateDuration is the validity of the signed certificates, it defaults to 24 hours
+	CertificateDuration time.Duration
+
+	// LeaseDurationSeconds is set on the managed clusters once they are accepted, so the agents renew their leases
+	// at a pace suitable for the test. The lease duration of the clusters is not changed if it is zero.
+	LeaseDurationSeconds int32
+
+	// SyncInterval is the interval the csrs and the managed clusters are processed, it defaults to 200ms
+	SyncInterval time.Duration
+}
+
+// FakeHub is a hub run in the process of a test, see the package doc
+type FakeHub struct {
+	// Config is the config of the cluster admin of the hub
+	Config        *rest.Config
+	KubeClient    kubernetes.Interface
+	ClusterClient clusterclientset.Interface
+
+	options Options
+	ca      *testCA
+	env     *envtest.Environment
+	cancel  context.CancelFunc
+}
+
+// Start starts the kube-apiserver and the etcd of the fake hub, installs the CRDs, and processes the csrs and the
+// managed clusters until the context is done or the hub is stopped.
+func Start(ctx context.Context, options Options) (*FakeHub, error) {
+	if options.CertificateDuration <= 0 {
+		options.CertificateDuration = defaultCertificateDuration
+	}
+	if options.SyncInterval <= 0 {
+		options.SyncInterval = defaultSyncInterval
+	}
+	ca, err := newTestCA(options.CertificateDuration)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the test CA: %w", err)
+	}
+
+	apiserver := &envtest.APIServer{}
+	apiserver.SecureServing.Authn = ca
+	env := &envtest.Environment{
+		ControlPlane:          envtest.ControlPlane{APIServer: apiserver},
+		CRDDirectoryPaths:     options.CRDDirectoryPaths,
+		ErrorIfCRDPathMissing: true,
+	}
+	config, err := env.Start()
+	if err != nil {
+		return nil, fmt.Errorf("unable to start the fake hub: %w", err)
+	}
+
+	hub, err := newFakeHub(config, options, ca)
+	if err != nil {
+		_ = env.Stop()
+		return nil, err
+	}
+	hub.env = env
+	if err := hub.grantClusterAdmin(ctx); err != nil {
+		_ = env.Stop()
+		return nil, err
+	}
+
+	ctx, hub.cancel = context.WithCancel(ctx)
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := hub.sync(ctx); err != nil {
+			klog.Warningf("The fake hub failed to sync: %v", err)
+		}
+	}, options.SyncInterval)
+	return hub, nil
+}
+
+func newFakeHub(config *rest.Config, options Options, ca *testCA) (*FakeHub, error) {
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	clusterClient, err := clusterclientset.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &FakeHub{
+		Config:        config,
+		KubeClient:    kubeClient,
+		ClusterClient: clusterClient,
+		options:       options,
+		ca:            ca,
+	}, nil
+}
+
+// Stop stops processing the csrs and the managed clusters, and stops the kube-apiserver and the etcd
+func (h *FakeHub) Stop() error {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	if h.env == nil {
+		return nil
+	}
+	return h.env.Stop()
+}
+
+// grantClusterAdmin binds the bootstrap users and the managed clusters to the cluster-admin role
+func (h *FakeHub) grantClusterAdmin(ctx context.Context) error {
+	_, err := h.KubeClient.RbacV1().ClusterRoleBindings().Create(ctx, &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "open-cluster-management:fakehub"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "cluster-admin"},
+		Subjects: []rbacv1.Subject{
+			{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: BootstrapGroup},
+			{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: user.ManagedClustersGroup},
+		},
+	}, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// BootstrapKubeconfig returns a kubeconfig of the hub authenticated as BootstrapUser
+func (h *FakeHub) BootstrapKubeconfig() (*clientcmdapi.Config, error) {
+	certData, keyData, err := h.ca.newClientCertKey(BootstrapUser, []string{BootstrapGroup})
+	if err != nil {
+		return nil, err
+	}
+	config := clientcmdapi.NewConfig()
+	config.Clusters["hub"] = &clientcmdapi.Cluster{
+		Server:                   h.Config.Host,
+		CertificateAuthorityData: h.Config.CAData,
+	}
+	config.AuthInfos["bootstrap"] = &clientcmdapi.AuthInfo{
+		ClientCertificateData: certData,
+		ClientKeyData:         keyData,
+	}
+	config.Contexts["bootstrap"] = &clientcmdapi.Context{Cluster: "hub", AuthInfo: "bootstrap"}
+	config.CurrentContext = "bootstrap"
+	return config, nil
+}
+
+// WriteBootstrapKubeconfig writes the bootstrap kubeconfig to the file, which is passed to the agent with the
+// "--bootstrap-kubeconfig" flag
+func (h *FakeHub) WriteBootstrapKubeconfig(filename string) error {
+	config, err := h.BootstrapKubeconfig()
+	if err != nil {
+		return err
+	}
+	return clientcmd.WriteToFile(*config, filename)
+}
+
+// ApproveCSR approves the csr and signs it with the test CA
+func (h *FakeHub) ApproveCSR(ctx context.Context, name string) error {
+	csr, err := h.KubeClient.CertificatesV1().CertificateSigningRequests().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return h.approveCSR(ctx, csr)
+}
+
+// AcceptCluster accepts the managed cluster, see acceptCluster
+func (h *FakeHub) AcceptCluster(ctx context.Context, name string) error {
+	cluster, err := h.ClusterClient.ClusterV1().ManagedClusters().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return h.acceptCluster(ctx, cluster)
+}
+
+// LeaseRenewTime returns the last time the agent of the managed cluster renewed its lease, it returns an error if
+// the lease is not created yet
+func (h *FakeHub) LeaseRenewTime(ctx context.Context, clusterName string) (time.Time, error) {
+	lease, err := h.KubeClient.CoordinationV1().Leases(clusterName).Get(ctx, leaseName, metav1.GetOptions{})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return leaseRenewTime(lease), nil
+}
+
+// WaitForLeaseRenewal waits until the agent of the managed cluster renews its lease after the given time, or the
+// context is done
+func (h *FakeHub) WaitForLeaseRenewal(ctx context.Context, clusterName string, after time.Time) error {
+	return wait.PollImmediateUntil(h.options.SyncInterval, func() (bool, error) {
+		renewTime, err := h.LeaseRenewTime(ctx, clusterName)
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return renewTime.After(after), nil
+	}, ctx.Done())
+}
+
+func leaseRenewTime(lease *coordinationv1.Lease) time.Time {
+	if lease.Spec.RenewTime == nil {
+		return time.Time{}
+	}
+	return lease.Spec.RenewTime.Time
+}
+
+// sync approves and signs the pending csrs, and accepts the managed clusters
+func (h *FakeHub) sync(ctx context.Context) error {
+	errs := []error{}
+
+	csrs, err := h.KubeClient.CertificatesV1().CertificateSigningRequests().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range csrs.Items {
+		csr := &csrs.Items[i]
+		if csr.Spec.SignerName != certificatesv1.KubeAPIServerClientSignerName || len(csr.Status.Certificate) > 0 ||
+			csrConditionTrue(csr, certificatesv1.CertificateDenied) || csrConditionTrue(csr, certificatesv1.CertificateFailed) {
+			continue
+		}
+		if h.options.ManualCSRApproval && !csrConditionTrue(csr, certificatesv1.CertificateApproved) {
+			continue
+		}
+		if err := h.approveCSR(ctx, csr); err != nil {
+			errs = append(errs, fmt.Errorf("unable to approve csr %s: %w", csr.Name, err))
+		}
+	}
+
+	clusters, err := h.ClusterClient.ClusterV1().ManagedClusters().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return utilerrors.NewAggregate(append(errs, err))
+	}
+	for i := range clusters.Items {
+		cluster := &clusters.Items[i]
+		if !cluster.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if h.options.ManualClusterAcceptance && !cluster.Spec.HubAcceptsClient {
+			continue
+		}
+		if err := h.acceptCluster(ctx, cluster); err != nil {
+			errs = append(errs, fmt.Errorf("unable to accept managed cluster %s: %w", cluster.Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// approveCSR approves the csr if it is not approved yet, and signs it with the test CA if it is not signed yet
+func (h *FakeHub) approveCSR(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) error {
+	var err error
+	if !csrConditionTrue(csr, certificatesv1.CertificateApproved) {
+		csr = csr.DeepCopy()
+		csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+			Type:           certificatesv1.CertificateApproved,
+			Status:         corev1.ConditionTrue,
+			Reason:         "FakeHubApproved",
+			Message:        "Approved by the fake hub",
+			LastUpdateTime: metav1.Now(),
+		})
+		csr, err = h.KubeClient.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, csr.Name, csr, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+	}
+	if len(csr.Status.Certificate) > 0 {
+		return nil
+	}
+
+	block, _ := pem.Decode(csr.Spec.Request)
+	if block == nil {
+		return fmt.Errorf("no certificate request found")
+	}
+	request, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return err
+	}
+	csr = csr.DeepCopy()
+	csr.Status.Certificate, err = h.ca.sign(request.Subject, request.PublicKey)
+	if err != nil {
+		return err
+	}
+	_, err = h.KubeClient.CertificatesV1().CertificateSigningRequests().UpdateStatus(ctx, csr, metav1.UpdateOptions{})
+	return err
+}
+
+// acceptCluster sets hubAcceptsClient and the lease duration of the managed cluster, creates the namespace and the
+// lease of the cluster, and sets the HubAccepted condition, which the agent waits for before it joins
+func (h *FakeHub) acceptCluster(ctx context.Context, cluster *clusterv1.ManagedCluster) error {
+	var err error
+	if !cluster.Spec.HubAcceptsClient ||
+		(h.options.LeaseDurationSeconds > 0 && cluster.Spec.LeaseDurationSeconds != h.options.LeaseDurationSeconds) {
+		patch := `{"spec":{"hubAcceptsClient":true}}`
+		if h.options.LeaseDurationSeconds > 0 {
+			patch = fmt.Sprintf(`{"spec":{"hubAcceptsClient":true,"leaseDurationSeconds":%d}}`, h.options.LeaseDurationSeconds)
+		}
+		cluster, err = h.ClusterClient.ClusterV1().ManagedClusters().Patch(ctx, cluster.Name, types.MergePatchType,
+			[]byte(patch), metav1.PatchOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = h.KubeClient.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: cluster.Name},
+	}, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	// the lease is created without a renew time, so the renew time observed is always set by the agent
+	_, err = h.KubeClient.CoordinationV1().Leases(cluster.Name).Create(ctx, &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      leaseName,
+			Namespace: cluster.Name,
+			Labels:    map[string]string{"open-cluster-management.io/cluster-name": cluster.Name},
+		},
+		Spec: coordinationv1.LeaseSpec{HolderIdentity: pointer.String(leaseName)},
+	}, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	if meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
+		return nil
+	}
+	cluster = cluster.DeepCopy()
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		Type:    clusterv1.ManagedClusterConditionHubAccepted,
+		Status:  metav1.ConditionTrue,
+		Reason:  "HubClusterAdminAccepted",
+		Message: "Accepted by the fake hub",
+	})
+	_, err = h.ClusterClient.ClusterV1().ManagedClusters().UpdateStatus(ctx, cluster, metav1.UpdateOptions{})
+	return err
+}
+
+func csrConditionTrue(csr *certificatesv1.CertificateSigningRequest, conditionType certificatesv1.RequestConditionType) bool {
+	for _, condition := range csr.Status.Conditions {
+		if condition.Type == conditionType && condition.Status != corev1.ConditionFalse {
+			return true
+		}
+	}
+	return false
+}
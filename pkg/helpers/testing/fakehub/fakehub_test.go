@@ -0,0 +1,189 @@
+package fakehub
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+const agentUser = "system:open-cluster-management:cluster1:agent1"
+
+func TestSync(t *testing.T) {
+	csrHolder := testinghelpers.CSRHolder{
+		Name:         "csr1",
+		SignerName:   certificatesv1.KubeAPIServerClientSignerName,
+		CN:           agentUser,
+		Orgs:         []string{"system:open-cluster-management:cluster1", "system:open-cluster-management:managed-clusters"},
+		Username:     BootstrapUser,
+		ReqBlockType: "CERTIFICATE REQUEST",
+	}
+
+	cases := []struct {
+		name                   string
+		options                Options
+		csrs                   []runtime.Object
+		clusters               []runtime.Object
+		validateKubeActions    func(t *testing.T, ca *testCA, actions []clienttesting.Action)
+		validateClusterActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name: "approve and sign the pending csr",
+			csrs: []runtime.Object{testinghelpers.NewCSR(csrHolder)},
+			validateKubeActions: func(t *testing.T, ca *testCA, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list", "update", "update")
+				approved := actions[1].(clienttesting.UpdateActionImpl).Object.(*certificatesv1.CertificateSigningRequest)
+				if !csrConditionTrue(approved, certificatesv1.CertificateApproved) {
+					t.Errorf("expected the csr approved, but got %v", approved.Status.Conditions)
+				}
+				signed := actions[2].(clienttesting.UpdateActionImpl).Object.(*certificatesv1.CertificateSigningRequest)
+				assertSignedBy(t, ca, signed.Status.Certificate, agentUser)
+			},
+			validateClusterActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+		},
+		{
+			name:    "csr approval is manual",
+			options: Options{ManualCSRApproval: true},
+			csrs:    []runtime.Object{testinghelpers.NewCSR(csrHolder)},
+			validateKubeActions: func(t *testing.T, ca *testCA, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+			validateClusterActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+		},
+		{
+			name:    "sign the manually approved csr",
+			options: Options{ManualCSRApproval: true},
+			csrs:    []runtime.Object{testinghelpers.NewApprovedCSR(csrHolder)},
+			validateKubeActions: func(t *testing.T, ca *testCA, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list", "update")
+				signed := actions[1].(clienttesting.UpdateActionImpl).Object.(*certificatesv1.CertificateSigningRequest)
+				assertSignedBy(t, ca, signed.Status.Certificate, agentUser)
+			},
+			validateClusterActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+		},
+		{
+			name: "skip the denied csr and the csr of the other signers",
+			csrs: []runtime.Object{
+				testinghelpers.NewDeniedCSR(csrHolder),
+				testinghelpers.NewCSR(testinghelpers.CSRHolder{
+					Name:         "csr2",
+					SignerName:   "example.com/signer",
+					CN:           agentUser,
+					ReqBlockType: "CERTIFICATE REQUEST",
+				}),
+			},
+			validateKubeActions: func(t *testing.T, ca *testCA, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+			validateClusterActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+		},
+		{
+			name:     "accept the cluster",
+			options:  Options{LeaseDurationSeconds: 1},
+			clusters: []runtime.Object{testinghelpers.NewManagedClusterBuilder("cluster1").Build()},
+			validateKubeActions: func(t *testing.T, ca *testCA, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list", "create", "create")
+				namespace := actions[1].(clienttesting.CreateActionImpl).Object.(*corev1.Namespace)
+				if namespace.Name != "cluster1" {
+					t.Errorf("expected namespace cluster1, but got %s", namespace.Name)
+				}
+			},
+			validateClusterActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list", "patch", "update")
+				patch := string(actions[1].(clienttesting.PatchActionImpl).Patch)
+				if patch != `{"spec":{"hubAcceptsClient":true,"leaseDurationSeconds":1}}` {
+					t.Errorf("unexpected patch %s", patch)
+				}
+				cluster := actions[2].(clienttesting.UpdateActionImpl).Object.(*clusterv1.ManagedCluster)
+				if !meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
+					t.Errorf("expected the cluster accepted, but got %v", cluster.Status.Conditions)
+				}
+			},
+		},
+		{
+			name:     "cluster acceptance is manual",
+			options:  Options{ManualClusterAcceptance: true},
+			clusters: []runtime.Object{testinghelpers.NewManagedClusterBuilder("cluster1").Build()},
+			validateKubeActions: func(t *testing.T, ca *testCA, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+			validateClusterActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+		},
+		{
+			name:     "cluster is accepted",
+			clusters: []runtime.Object{testinghelpers.NewManagedClusterBuilder("cluster1").Accepted().Build()},
+			validateKubeActions: func(t *testing.T, ca *testCA, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list", "create", "create")
+			},
+			validateClusterActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ca, err := newTestCA(time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kubeClient := kubefake.NewSimpleClientset(c.csrs...)
+			clusterClient := clusterfake.NewSimpleClientset(c.clusters...)
+			hub := &FakeHub{
+				KubeClient:    kubeClient,
+				ClusterClient: clusterClient,
+				options:       c.options,
+				ca:            ca,
+			}
+			if err := hub.sync(context.TODO()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			c.validateKubeActions(t, ca, kubeClient.Actions())
+			c.validateClusterActions(t, clusterClient.Actions())
+		})
+	}
+}
+
+func assertSignedBy(t *testing.T, ca *testCA, certData []byte, commonName string) {
+	block, _ := pem.Decode(certData)
+	if block == nil {
+		t.Fatalf("no certificate found in %q", string(certData))
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		t.Errorf("expected the certificate signed by the test CA: %v", err)
+	}
+	if cert.Subject.CommonName != commonName {
+		t.Errorf("expected common name %s, but got %s", commonName, cert.Subject.CommonName)
+	}
+}
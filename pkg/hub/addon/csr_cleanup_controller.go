@@ -0,0 +1,182 @@
+package addon
+
+import (
+	"context"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
+	addoninformerv1alpha1 "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	certificatesinformers "k8s.io/client-go/informers/certificates/v1"
+	"k8s.io/client-go/kubernetes"
+	certificateslisters "k8s.io/client-go/listers/certificates/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// addOnCSRCleanupController deletes the csrs of a managed cluster addon once the addon is deleted, so the
+// csrs of a removed addon will not be left on the hub cluster. The registration cleanup finalizer of a deleting
+// addon is removed by the controller as well if the registration agent is not able to remove it, because the
+// managed cluster is unavailable or being deleted, or the addon registration of the agent is disabled, so the addon
+// and its namespace are not stuck in deletion.
+type addOnCSRCleanupController struct {
+	kubeClient    kubernetes.Interface
+	addOnClient   addonclient.Interface
+	addOnLister   addonlisterv1alpha1.ManagedClusterAddOnLister
+	clusterLister clusterlisterv1.ManagedClusterLister
+	csrLister     certificateslisters.CertificateSigningRequestLister
+}
+
+// NewAddOnCSRCleanupController returns an instance of addOnCSRCleanupController
+func NewAddOnCSRCleanupController(
+	kubeClient kubernetes.Interface,
+	addOnClient addonclient.Interface,
+	addOnInformer addoninformerv1alpha1.ManagedClusterAddOnInformer,
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	csrInformer certificatesinformers.CertificateSigningRequestInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &addOnCSRCleanupController{
+		kubeClient:    kubeClient,
+		addOnClient:   addOnClient,
+		addOnLister:   addOnInformer.Lister(),
+		clusterLister: clusterInformer.Lister(),
+		csrLister:     csrInformer.Lister(),
+	}
+
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			key, _ := cache.MetaNamespaceKeyFunc(obj)
+			return key
+		}, addOnInformer.Informer()).
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer()).
+		WithBareInformers(csrInformer.Informer()).
+		WithSync(health.WrapSync("AddOnCSRCleanupController", c.sync)).
+		ToController("AddOnCSRCleanupController", recorder)
+}
+
+func (c *addOnCSRCleanupController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	key := syncCtx.QueueKey()
+	if key == factory.DefaultQueueKey {
+		return nil
+	}
+
+	clusterName, addOnName, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		// ignore addon whose key is not in format: namespace/name
+		return nil
+	}
+
+	// the key of a managed cluster, the deleting addons of the cluster are synced again once the cluster
+	// becomes unavailable or is being deleted
+	if len(clusterName) == 0 {
+		addOns, err := c.addOnLister.ManagedClusterAddOns(addOnName).List(labels.Everything())
+		if err != nil {
+			return err
+		}
+		for _, addOn := range addOns {
+			if !addOn.DeletionTimestamp.IsZero() {
+				syncCtx.Queue().Add(addOn.Namespace + "/" + addOn.Name)
+			}
+		}
+		return nil
+	}
+
+	addOn, err := c.addOnLister.ManagedClusterAddOns(clusterName).Get(addOnName)
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		return err
+	case addOn.DeletionTimestamp.IsZero():
+		return nil
+	}
+
+	// the addon is deleting or deleted, clean up its csrs
+	csrs, err := c.csrLister.List(labels.SelectorFromSet(labels.Set{
+		clientcert.ClusterNameLabel: clusterName,
+		clientcert.AddonNameLabel:   addOnName,
+	}))
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, csr := range csrs {
+		err := c.kubeClient.CertificatesV1().CertificateSigningRequests().Delete(ctx, csr.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+			continue
+		}
+		syncCtx.Recorder().Eventf("AddOnCSRDeleted", "csr %q of addon %q on managed cluster %q is deleted",
+			csr.Name, addOnName, clusterName)
+	}
+	if len(errs) > 0 || addOn == nil {
+		return operatorhelpers.NewMultiLineAggregate(errs)
+	}
+
+	return c.removeRegistrationCleanupFinalizer(ctx, syncCtx, addOn)
+}
+
+// removeRegistrationCleanupFinalizer removes the registration cleanup finalizer from a deleting addon if the
+// registration agent of the managed cluster is not able to clean up the addon credentials and remove it. The
+// credentials left on the unavailable cluster are no longer trusted by the hub once the csrs are deleted.
+func (c *addOnCSRCleanupController) removeRegistrationCleanupFinalizer(ctx context.Context, syncCtx factory.SyncContext,
+	addOn *addonv1alpha1.ManagedClusterAddOn) error {
+	finalizers := []string{}
+	for _, finalizer := range addOn.Finalizers {
+		if finalizer != helpers.AddOnRegistrationCleanupFinalizer {
+			finalizers = append(finalizers, finalizer)
+		}
+	}
+	if len(finalizers) == len(addOn.Finalizers) {
+		return nil
+	}
+
+	cluster, err := c.clusterLister.Get(addOn.Namespace)
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		return err
+	case !cluster.DeletionTimestamp.IsZero():
+	case cluster.Annotations[helpers.AddOnRegistrationDisabledAnnotation] == "true":
+		// the addon registration of the agent is disabled, so the finalizers added before are no longer removed by
+		// the agent
+	case meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable):
+		// the registration agent is still available to clean up the addon
+		return nil
+	}
+
+	patch, err := helpers.FinalizersPatch(addOn.ResourceVersion, finalizers)
+	if err != nil {
+		return err
+	}
+	_, err = c.addOnClient.AddonV1alpha1().ManagedClusterAddOns(addOn.Namespace).Patch(
+		ctx, addOn.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	syncCtx.Recorder().Eventf("AddOnRegistrationCleanupFinalizerRemoved",
+		"registration cleanup finalizer of addon %q is removed because the registration agent of managed cluster %q is not able to remove it",
+		addOn.Name, addOn.Namespace)
+	return nil
+}
@@ -0,0 +1,236 @@
+package addon
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestAddOnCSRCleanup(t *testing.T) {
+	now := metav1.Now()
+	addOnCSR := testinghelpers.NewCSR(testinghelpers.CSRHolder{
+		Name: "addon-csr",
+		Labels: map[string]string{
+			clientcert.ClusterNameLabel: testinghelpers.TestManagedClusterName,
+			clientcert.AddonNameLabel:   "test",
+		},
+	})
+	otherAddOnCSR := testinghelpers.NewCSR(testinghelpers.CSRHolder{
+		Name: "other-addon-csr",
+		Labels: map[string]string{
+			clientcert.ClusterNameLabel: testinghelpers.TestManagedClusterName,
+			clientcert.AddonNameLabel:   "other",
+		},
+	})
+
+	deletingAddOnWithFinalizer := &addonv1alpha1.ManagedClusterAddOn{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         testinghelpers.TestManagedClusterName,
+			Name:              "test",
+			DeletionTimestamp: &now,
+			Finalizers:        []string{"other", helpers.AddOnRegistrationCleanupFinalizer},
+		},
+	}
+
+	cases := []struct {
+		name                 string
+		queueKey             string
+		addOns               []runtime.Object
+		clusters             []runtime.Object
+		csrs                 []runtime.Object
+		validateActions      func(t *testing.T, actions []clienttesting.Action)
+		validateAddOnActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:     "addon is not deleting",
+			queueKey: testinghelpers.TestManagedClusterName + "/test",
+			addOns: []runtime.Object{&addonv1alpha1.ManagedClusterAddOn{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testinghelpers.TestManagedClusterName, Name: "test"},
+			}},
+			csrs: []runtime.Object{addOnCSR, otherAddOnCSR},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "addon is deleting",
+			queueKey: testinghelpers.TestManagedClusterName + "/test",
+			addOns: []runtime.Object{&addonv1alpha1.ManagedClusterAddOn{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         testinghelpers.TestManagedClusterName,
+					Name:              "test",
+					DeletionTimestamp: &now,
+				},
+			}},
+			csrs: []runtime.Object{addOnCSR, otherAddOnCSR},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete")
+				name := actions[0].(clienttesting.DeleteActionImpl).Name
+				if name != "addon-csr" {
+					t.Errorf("expected csr addon-csr is deleted, but got %q", name)
+				}
+			},
+		},
+		{
+			name:     "addon is deleted",
+			queueKey: testinghelpers.TestManagedClusterName + "/test",
+			addOns:   []runtime.Object{},
+			csrs:     []runtime.Object{addOnCSR, otherAddOnCSR},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete")
+			},
+		},
+		{
+			name:     "keep the finalizer of deleting addon on available cluster",
+			queueKey: testinghelpers.TestManagedClusterName + "/test",
+			addOns:   []runtime.Object{deletingAddOnWithFinalizer},
+			clusters: []runtime.Object{testinghelpers.NewAvailableManagedCluster()},
+			csrs:     []runtime.Object{addOnCSR},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete")
+			},
+			validateAddOnActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "remove the finalizer of deleting addon on unavailable cluster",
+			queueKey: testinghelpers.TestManagedClusterName + "/test",
+			addOns:   []runtime.Object{deletingAddOnWithFinalizer},
+			clusters: []runtime.Object{testinghelpers.NewUnknownManagedCluster()},
+			csrs:     []runtime.Object{addOnCSR},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete")
+			},
+			validateAddOnActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				assertFinalizersPatch(t, actions[0], `["other"]`)
+			},
+		},
+		{
+			name:     "remove the finalizer of deleting addon once the addon registration is disabled",
+			queueKey: testinghelpers.TestManagedClusterName + "/test",
+			addOns:   []runtime.Object{deletingAddOnWithFinalizer},
+			clusters: []runtime.Object{func() *clusterv1.ManagedCluster {
+				// the addon registration is turned off after the finalizer is added
+				cluster := testinghelpers.NewAvailableManagedCluster()
+				cluster.Annotations = map[string]string{helpers.AddOnRegistrationDisabledAnnotation: "true"}
+				return cluster
+			}()},
+			csrs: []runtime.Object{addOnCSR},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete")
+			},
+			validateAddOnActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				assertFinalizersPatch(t, actions[0], `["other"]`)
+			},
+		},
+		{
+			name:     "remove the finalizer of deleting addon on deleting cluster",
+			queueKey: testinghelpers.TestManagedClusterName + "/test",
+			addOns:   []runtime.Object{deletingAddOnWithFinalizer},
+			clusters: []runtime.Object{testinghelpers.NewDeletingManagedCluster()},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+			validateAddOnActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				assertFinalizersPatch(t, actions[0], `["other"]`)
+			},
+		},
+		{
+			name:     "remove the finalizer of deleting addon without cluster",
+			queueKey: testinghelpers.TestManagedClusterName + "/test",
+			addOns:   []runtime.Object{deletingAddOnWithFinalizer},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+			validateAddOnActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				assertFinalizersPatch(t, actions[0], `["other"]`)
+			},
+		},
+		{
+			name:     "sync deleting addons of cluster",
+			queueKey: testinghelpers.TestManagedClusterName,
+			addOns:   []runtime.Object{deletingAddOnWithFinalizer},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+			validateAddOnActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			addOnClient := addonfake.NewSimpleClientset(c.addOns...)
+			addOnInformerFactory := addoninformers.NewSharedInformerFactory(addOnClient, time.Minute*10)
+			addOnStore := addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer().GetStore()
+			for _, addOn := range c.addOns {
+				addOnStore.Add(addOn)
+			}
+
+			kubeClient := kubefake.NewSimpleClientset(c.csrs...)
+			kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, time.Minute*10)
+			csrStore := kubeInformerFactory.Certificates().V1().CertificateSigningRequests().Informer().GetStore()
+			for _, csr := range c.csrs {
+				csrStore.Add(csr)
+			}
+
+			clusterClient := clusterfake.NewSimpleClientset(c.clusters...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+			for _, cluster := range c.clusters {
+				clusterStore.Add(cluster)
+			}
+
+			ctrl := &addOnCSRCleanupController{
+				kubeClient:    kubeClient,
+				addOnClient:   addOnClient,
+				addOnLister:   addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				csrLister:     kubeInformerFactory.Certificates().V1().CertificateSigningRequests().Lister(),
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, c.queueKey))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateActions(t, kubeClient.Actions())
+			if c.validateAddOnActions != nil {
+				c.validateAddOnActions(t, addOnClient.Actions())
+			}
+		})
+	}
+}
+
+func assertFinalizersPatch(t *testing.T, action clienttesting.Action, expectedFinalizers string) {
+	patch := map[string]map[string]interface{}{}
+	if err := json.Unmarshal(action.(clienttesting.PatchActionImpl).Patch, &patch); err != nil {
+		t.Fatal(err)
+	}
+	finalizers, _ := json.Marshal(patch["metadata"]["finalizers"])
+	if string(finalizers) != expectedFinalizers {
+		t.Errorf("expected finalizers %s, but got %s", expectedFinalizers, finalizers)
+	}
+}
@@ -24,6 +24,9 @@ import (
 	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
 )
 
 const (
@@ -69,7 +72,7 @@ func NewAddOnFeatureDiscoveryController(
 				return key
 			},
 			addOnInformers.Informer()).
-		WithSync(c.sync).
+		WithSync(health.WrapSync("AddOnFeatureDiscoveryController", c.sync)).
 		ResyncEvery(10*time.Minute).
 		ToController("AddOnFeatureDiscoveryController", recorder)
 }
@@ -108,7 +111,9 @@ func (c *addOnFeatureDiscoveryController) sync(ctx context.Context, syncCtx fact
 }
 
 func (c *addOnFeatureDiscoveryController) syncAddOn(ctx context.Context, clusterName, addOnName string) error {
-	klog.V(4).Infof("Reconciling addOn %q", addOnName)
+	logger := helpers.ControllerLogger(ctx, "AddOnFeatureDiscoveryController")
+	logger.V(helpers.LogLevelDebug).Info("Reconciling addOn", helpers.LogKeyCluster, clusterName,
+		helpers.LogKeyResource, klog.KRef(clusterName, addOnName))
 
 	labels := map[string]string{}
 	addOn, err := c.addOnLister.ManagedClusterAddOns(clusterName).Get(addOnName)
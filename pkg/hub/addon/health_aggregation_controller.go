@@ -0,0 +1,152 @@
+package addon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
+	addoninformerv1alpha1 "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	coordinformers "k8s.io/client-go/informers/coordination/v1"
+	coordlisters "k8s.io/client-go/listers/coordination/v1"
+)
+
+// clusterLeaseName is the name of the lease of a managed cluster in its namespace
+const clusterLeaseName = "managed-cluster-lease"
+
+// addOnHealthAggregationController fans out the addon available conditions summarized by the registration agent
+// in the addon health summary annotation of a managed cluster lease to the status of each managed cluster addon.
+// The agents which update the addons one by one do not write the summary, so the controller runs for all clusters
+// and only fans out the summary of the agents running with the addon heartbeat aggregated.
+type addOnHealthAggregationController struct {
+	addOnClient   addonclient.Interface
+	addOnLister   addonlisterv1alpha1.ManagedClusterAddOnLister
+	clusterLister clusterlisterv1.ManagedClusterLister
+	leaseLister   coordlisters.LeaseLister
+}
+
+// NewAddOnHealthAggregationController returns an instance of addOnHealthAggregationController
+func NewAddOnHealthAggregationController(
+	addOnClient addonclient.Interface,
+	addOnInformer addoninformerv1alpha1.ManagedClusterAddOnInformer,
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	leaseInformer coordinformers.LeaseInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &addOnHealthAggregationController{
+		addOnClient:   addOnClient,
+		addOnLister:   addOnInformer.Lister(),
+		clusterLister: clusterInformer.Lister(),
+		leaseLister:   leaseInformer.Lister(),
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetNamespace()
+		}, func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			return accessor.GetName() == clusterLeaseName
+		}, leaseInformer.Informer()).
+		WithBareInformers(addOnInformer.Informer(), clusterInformer.Informer()).
+		WithSync(health.WrapSync("AddOnHealthAggregationController", c.sync)).
+		ToController("AddOnHealthAggregationController", recorder)
+}
+
+func (c *addOnHealthAggregationController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	clusterName := syncCtx.QueueKey()
+	if clusterName == factory.DefaultQueueKey {
+		return nil
+	}
+
+	cluster, err := c.clusterLister.Get(clusterName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// the summary is stale if the registration agent stops updating its lease, the addons status is
+	// maintained by the addon health check controller in this case.
+	availableCondition := meta.FindStatusCondition(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable)
+	if availableCondition == nil || availableCondition.Status == metav1.ConditionUnknown {
+		return nil
+	}
+
+	lease, err := c.leaseLister.Leases(clusterName).Get(clusterLeaseName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// the agent updates the addons one by one if there is no summary
+	summaryData, ok := lease.Annotations[helpers.AddOnHealthSummaryAnnotation]
+	if !ok {
+		return nil
+	}
+	summary := map[string]metav1.Condition{}
+	if err := json.Unmarshal([]byte(summaryData), &summary); err != nil {
+		return fmt.Errorf("unable to parse the addon health summary of managed cluster %q: %w", clusterName, err)
+	}
+
+	errs := []error{}
+	for addOnName, condition := range summary {
+		addOn, err := c.addOnLister.ManagedClusterAddOns(clusterName).Get(addOnName)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// "Customized" mode health check is supposed to delegate the health checking
+		// to the addon manager.
+		if addOn.Status.HealthCheck.Mode == addonv1alpha1.HealthCheckModeCustomized {
+			continue
+		}
+
+		if meta.IsStatusConditionPresentAndEqual(addOn.Status.Conditions, condition.Type, condition.Status) {
+			continue
+		}
+
+		_, updated, err := helpers.UpdateManagedClusterAddOnStatus(
+			ctx,
+			c.addOnClient,
+			clusterName,
+			addOnName,
+			helpers.UpdateManagedClusterAddOnStatusFn(condition),
+		)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if updated {
+			syncCtx.Recorder().Eventf("ManagedClusterAddOnStatusUpdated",
+				"update managed cluster addon %q available condition to %q with the addon health summary of managed cluster %q",
+				addOnName, condition.Status, clusterName)
+		}
+	}
+
+	return operatorhelpers.NewMultiLineAggregate(errs)
+}
@@ -0,0 +1,106 @@
+package addon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestAddOnHealthAggregationSync(t *testing.T) {
+	addOn := &addonv1alpha1.ManagedClusterAddOn{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testinghelpers.TestManagedClusterName, Name: "test"},
+	}
+	lease := testinghelpers.NewManagedClusterLease(clusterLeaseName, time.Now())
+	summaryLease := lease.DeepCopy()
+	summaryLease.Annotations = map[string]string{
+		helpers.AddOnHealthSummaryAnnotation: `{` +
+			`"test":{"type":"Available","status":"True","lastTransitionTime":null,"reason":"ManagedClusterAddOnLeaseUpdated","message":"test add-on is available."},` +
+			`"deleted":{"type":"Available","status":"True","lastTransitionTime":null,"reason":"ManagedClusterAddOnLeaseUpdated","message":"deleted add-on is available."}}`,
+	}
+
+	cases := []struct {
+		name            string
+		managedClusters []runtime.Object
+		leases          []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:            "no addon health summary",
+			managedClusters: []runtime.Object{testinghelpers.NewAvailableManagedCluster()},
+			leases:          []runtime.Object{lease},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:            "managed cluster is unknown",
+			managedClusters: []runtime.Object{testinghelpers.NewUnknownManagedCluster()},
+			leases:          []runtime.Object{summaryLease},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:            "fan out addon health",
+			managedClusters: []runtime.Object{testinghelpers.NewAvailableManagedCluster()},
+			leases:          []runtime.Object{summaryLease},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				addOn := actions[1].(clienttesting.UpdateActionImpl).Object.(*addonv1alpha1.ManagedClusterAddOn)
+				if !meta.IsStatusConditionTrue(addOn.Status.Conditions, addonv1alpha1.ManagedClusterAddOnConditionAvailable) {
+					t.Errorf("expected addon is available, but got %v", addOn.Status.Conditions)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.managedClusters...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+			for _, cluster := range c.managedClusters {
+				clusterStore.Add(cluster)
+			}
+
+			addOnClient := addonfake.NewSimpleClientset(addOn)
+			addOnInformerFactory := addoninformers.NewSharedInformerFactory(addOnClient, time.Minute*10)
+			addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer().GetStore().Add(addOn)
+
+			kubeClient := kubefake.NewSimpleClientset(c.leases...)
+			kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, time.Minute*10)
+			leaseStore := kubeInformerFactory.Coordination().V1().Leases().Informer().GetStore()
+			for _, lease := range c.leases {
+				leaseStore.Add(lease)
+			}
+
+			ctrl := &addOnHealthAggregationController{
+				addOnClient:   addOnClient,
+				addOnLister:   addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				leaseLister:   kubeInformerFactory.Coordination().V1().Leases().Lister(),
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateActions(t, addOnClient.Actions())
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
 	"open-cluster-management.io/registration/pkg/helpers"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
@@ -48,7 +49,7 @@ func NewManagedClusterAddOnHealthCheckController(addOnClient addonclient.Interfa
 			accessor, _ := meta.Accessor(obj)
 			return accessor.GetName()
 		}, clusterInformer.Informer()).
-		WithSync(c.sync).
+		WithSync(health.WrapSync("ManagedClusterAddonHealthCheckController", c.sync)).
 		ToController("ManagedClusterAddonHealthCheckController", recorder)
 }
 
@@ -0,0 +1,165 @@
+package addon
+
+import (
+	"context"
+	"embed"
+	"fmt"
+
+	"github.com/openshift/library-go/pkg/assets"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addoninformerv1alpha1 "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+//go:embed manifests
+var manifestFiles embed.FS
+
+var addOnRBACFiles = []string{
+	"manifests/addon-role.yaml",
+	"manifests/addon-rolebinding.yaml",
+	"manifests/addon-clusterrole-rolebinding.yaml",
+}
+
+// addOnRBACConfig is the config used to render the addon rbac templates
+type addOnRBACConfig struct {
+	ManagedClusterName string
+	AddOnName          string
+	Groups             []string
+	ServiceAccounts    []string
+}
+
+// addOnRBACController renders the role/rolebindings that an addon agent needs in the managed cluster namespace
+// from templates, according to the registrations of the managed cluster addon. The rendered rolebindings bind
+// a role with the common permissions of the addon agents and the clusterrole "open-cluster-management:addon:{addon name}",
+// in which the addon specific permissions can be declared, to the identities of the addon agent. The identities
+// are derived from the default naming on the hub, the subjects in the registrations are written by the agent of
+// the managed cluster and are never bound, so a cluster is not able to grant the addon permissions to others.
+//
+// The hub controller is only allowed to bind the clusterroles it is granted "bind" on by name, the addon
+// developer ships a clusterrole labeled "open-cluster-management.io/aggregate-to-hub-addon-bind" along with the
+// addon clusterrole to grant it, e.g.
+//
+//	rules:
+//	- apiGroups: ["rbac.authorization.k8s.io"]
+//	  resources: ["clusterroles"]
+//	  resourceNames: ["open-cluster-management:addon:{addon name}"]
+//	  verbs: ["bind"]
+type addOnRBACController struct {
+	kubeClient  kubernetes.Interface
+	addOnLister addonlisterv1alpha1.ManagedClusterAddOnLister
+	cache       resourceapply.ResourceCache
+}
+
+// NewAddOnRBACController returns an instance of addOnRBACController
+func NewAddOnRBACController(
+	kubeClient kubernetes.Interface,
+	addOnInformer addoninformerv1alpha1.ManagedClusterAddOnInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &addOnRBACController{
+		kubeClient:  kubeClient,
+		addOnLister: addOnInformer.Lister(),
+		cache:       helpers.NewResourceCache(),
+	}
+
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			key, _ := cache.MetaNamespaceKeyFunc(obj)
+			return key
+		}, addOnInformer.Informer()).
+		WithSync(health.WrapSync("AddOnRBACController", c.sync)).
+		ToController("AddOnRBACController", recorder)
+}
+
+func (c *addOnRBACController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	key := syncCtx.QueueKey()
+	if key == factory.DefaultQueueKey {
+		return nil
+	}
+
+	clusterName, addOnName, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		// ignore addon whose key is not in format: namespace/name
+		return nil
+	}
+
+	addOn, err := c.addOnLister.ManagedClusterAddOns(clusterName).Get(addOnName)
+	switch {
+	case errors.IsNotFound(err):
+		return c.cleanup(ctx, syncCtx.Recorder(), clusterName, addOnName)
+	case err != nil:
+		return err
+	}
+
+	config := newAddOnRBACConfig(addOn)
+	if !addOn.DeletionTimestamp.IsZero() ||
+		len(config.Groups)+len(config.ServiceAccounts) == 0 {
+		return c.cleanup(ctx, syncCtx.Recorder(), clusterName, addOnName)
+	}
+
+	results := resourceapply.ApplyDirectly(
+		ctx,
+		resourceapply.NewKubeClientHolder(c.kubeClient),
+		syncCtx.Recorder(),
+		c.cache,
+		addOnRBACAssetFn(config),
+		addOnRBACFiles...,
+	)
+	errs := []error{}
+	for _, result := range results {
+		if result.Error != nil {
+			errs = append(errs, fmt.Errorf("%q (%T): %v", result.File, result.Type, result.Error))
+		}
+	}
+	return operatorhelpers.NewMultiLineAggregate(errs)
+}
+
+func (c *addOnRBACController) cleanup(ctx context.Context, recorder events.Recorder, clusterName, addOnName string) error {
+	assetFn := addOnRBACAssetFn(addOnRBACConfig{ManagedClusterName: clusterName, AddOnName: addOnName})
+	return helpers.CleanUpManagedClusterManifests(ctx, c.kubeClient, recorder, assetFn, addOnRBACFiles...)
+}
+
+// newAddOnRBACConfig returns the identities of the addon agent on the hub according to the registrations of the addon.
+// Only the registrations whose credentials are used to access the hub apiserver are considered, and their identities
+// are the default group of the addon agent certificates and the service account of the addon tokens on the hub.
+func newAddOnRBACConfig(addOn *addonv1alpha1.ManagedClusterAddOn) addOnRBACConfig {
+	groups := sets.NewString()
+	serviceAccounts := sets.NewString()
+	for _, registration := range addOn.Status.Registrations {
+		switch registration.SignerName {
+		case certificatesv1.KubeAPIServerClientSignerName:
+			groups.Insert(helpers.AddOnDefaultGroup(addOn.Namespace, addOn.Name))
+		case helpers.AddOnTokenSignerName:
+			serviceAccounts.Insert(helpers.AddOnServiceAccountName(addOn.Name))
+		}
+	}
+
+	return addOnRBACConfig{
+		ManagedClusterName: addOn.Namespace,
+		AddOnName:          addOn.Name,
+		Groups:             groups.List(),
+		ServiceAccounts:    serviceAccounts.List(),
+	}
+}
+
+func addOnRBACAssetFn(config addOnRBACConfig) resourceapply.AssetFunc {
+	return func(name string) ([]byte, error) {
+		template, err := manifestFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		return assets.MustCreateAssetFromTemplate(name, template, config).Data, nil
+	}
+}
@@ -0,0 +1,123 @@
+package addon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestAddOnRBACSync(t *testing.T) {
+	now := metav1.Now()
+	newAddOn := func(deletionTimestamp *metav1.Time, registrations ...addonv1alpha1.RegistrationConfig) *addonv1alpha1.ManagedClusterAddOn {
+		return &addonv1alpha1.ManagedClusterAddOn{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         testinghelpers.TestManagedClusterName,
+				Name:              "test",
+				DeletionTimestamp: deletionTimestamp,
+			},
+			Status: addonv1alpha1.ManagedClusterAddOnStatus{
+				Registrations: registrations,
+			},
+		}
+	}
+
+	cases := []struct {
+		name            string
+		addOns          []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:   "addon with kube client and token registrations",
+			addOns: []runtime.Object{newAddOn(nil, addonv1alpha1.RegistrationConfig{SignerName: certificatesv1.KubeAPIServerClientSignerName}, addonv1alpha1.RegistrationConfig{SignerName: helpers.AddOnTokenSignerName})},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create", "get", "create", "get", "create")
+				binding := actions[3].(clienttesting.CreateActionImpl).Object.(*rbacv1.RoleBinding)
+				if len(binding.Subjects) != 2 {
+					t.Fatalf("expected 2 subjects, but got %v", binding.Subjects)
+				}
+				if binding.Subjects[0].Kind != rbacv1.GroupKind || binding.Subjects[0].Name != helpers.AddOnDefaultGroup(testinghelpers.TestManagedClusterName, "test") {
+					t.Errorf("unexpected subject %v", binding.Subjects[0])
+				}
+				if binding.Subjects[1].Kind != rbacv1.ServiceAccountKind || binding.Subjects[1].Name != helpers.AddOnServiceAccountName("test") {
+					t.Errorf("unexpected subject %v", binding.Subjects[1])
+				}
+			},
+		},
+		{
+			name:   "addon with customized subject",
+			addOns: []runtime.Object{newAddOn(nil, addonv1alpha1.RegistrationConfig{SignerName: certificatesv1.KubeAPIServerClientSignerName, Subject: addonv1alpha1.Subject{User: "user1", Groups: []string{"group1"}}})},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create", "get", "create", "get", "create")
+				binding := actions[5].(clienttesting.CreateActionImpl).Object.(*rbacv1.RoleBinding)
+				if binding.RoleRef.Kind != "ClusterRole" || binding.RoleRef.Name != "open-cluster-management:addon:test" {
+					t.Errorf("unexpected role ref %v", binding.RoleRef)
+				}
+				// the subject in the registration written by the agent is never bound
+				if len(binding.Subjects) != 1 || binding.Subjects[0].Kind != rbacv1.GroupKind ||
+					binding.Subjects[0].Name != helpers.AddOnDefaultGroup(testinghelpers.TestManagedClusterName, "test") {
+					t.Errorf("unexpected subjects %v", binding.Subjects)
+				}
+			},
+		},
+		{
+			name:   "addon with customized signer only",
+			addOns: []runtime.Object{newAddOn(nil, addonv1alpha1.RegistrationConfig{SignerName: "mysigner"})},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete", "delete", "delete")
+			},
+		},
+		{
+			name:   "addon is deleting",
+			addOns: []runtime.Object{newAddOn(&now, addonv1alpha1.RegistrationConfig{SignerName: certificatesv1.KubeAPIServerClientSignerName})},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete", "delete", "delete")
+			},
+		},
+		{
+			name:   "addon is deleted",
+			addOns: []runtime.Object{},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete", "delete", "delete")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			addOnClient := addonfake.NewSimpleClientset(c.addOns...)
+			addOnInformerFactory := addoninformers.NewSharedInformerFactory(addOnClient, time.Minute*10)
+			addOnStore := addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer().GetStore()
+			for _, addOn := range c.addOns {
+				addOnStore.Add(addOn)
+			}
+
+			kubeClient := kubefake.NewSimpleClientset()
+
+			ctrl := &addOnRBACController{
+				kubeClient:  kubeClient,
+				addOnLister: addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				cache:       resourceapply.NewResourceCache(),
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName+"/test"))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateActions(t, kubeClient.Actions())
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package addon
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift/library-go/pkg/assets"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addoninformerv1alpha1 "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// addOnTokenRBACFiles are applied in order, the hub controller is bound in the namespace first since a role only
+// grants the permissions the hub controller holds in the namespace
+var addOnTokenRBACFiles = []string{
+	"manifests/addon-token-hub-rolebinding.yaml",
+	"manifests/addon-token-role.yaml",
+	"manifests/addon-token-rolebinding.yaml",
+}
+
+// addOnTokenRBACConfig is the config used to render the rbac templates of the addon tokens
+type addOnTokenRBACConfig struct {
+	ManagedClusterName string
+	AddOnName          string
+	ServiceAccountName string
+
+	HubServiceAccountNamespace string
+	HubServiceAccountName      string
+}
+
+// addOnTokenServiceAccountController maintains a service account in the managed cluster namespace for each
+// managed cluster addon which uses token based registration. The registration agent requests the tokens of
+// this service account for the addon, with a role which only allows it to request the tokens of this service
+// account, so the agent is not able to request the tokens of the other service accounts in the namespace. The hub
+// controller is only allowed to request the tokens in the namespaces with such addons, where it binds itself to the
+// clusterrole open-cluster-management:hub:addon-token.
+type addOnTokenServiceAccountController struct {
+	kubeClient                 kubernetes.Interface
+	addOnLister                addonlisterv1alpha1.ManagedClusterAddOnLister
+	hubServiceAccountNamespace string
+	hubServiceAccountName      string
+	cache                      resourceapply.ResourceCache
+}
+
+// NewAddOnTokenServiceAccountController returns an instance of addOnTokenServiceAccountController
+func NewAddOnTokenServiceAccountController(
+	kubeClient kubernetes.Interface,
+	addOnInformer addoninformerv1alpha1.ManagedClusterAddOnInformer,
+	hubServiceAccountNamespace, hubServiceAccountName string,
+	recorder events.Recorder) factory.Controller {
+	c := &addOnTokenServiceAccountController{
+		kubeClient:                 kubeClient,
+		addOnLister:                addOnInformer.Lister(),
+		hubServiceAccountNamespace: hubServiceAccountNamespace,
+		hubServiceAccountName:      hubServiceAccountName,
+		cache:                      helpers.NewResourceCache(),
+	}
+
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			key, _ := cache.MetaNamespaceKeyFunc(obj)
+			return key
+		}, addOnInformer.Informer()).
+		WithSync(health.WrapSync("AddOnTokenServiceAccountController", c.sync)).
+		ToController("AddOnTokenServiceAccountController", recorder)
+}
+
+func (c *addOnTokenServiceAccountController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	key := syncCtx.QueueKey()
+	if key == factory.DefaultQueueKey {
+		return nil
+	}
+
+	clusterName, addOnName, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		// ignore addon whose key is not in format: namespace/name
+		return nil
+	}
+
+	addOn, err := c.addOnLister.ManagedClusterAddOns(clusterName).Get(addOnName)
+	switch {
+	case errors.IsNotFound(err):
+		return c.removeServiceAccount(ctx, syncCtx.Recorder(), clusterName, addOnName)
+	case err != nil:
+		return err
+	}
+
+	if !addOn.DeletionTimestamp.IsZero() || !hasTokenRegistration(addOn) {
+		return c.removeServiceAccount(ctx, syncCtx.Recorder(), clusterName, addOnName)
+	}
+
+	_, _, err = resourceapply.ApplyServiceAccount(ctx, c.kubeClient.CoreV1(), syncCtx.Recorder(), &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: clusterName,
+			Name:      helpers.AddOnServiceAccountName(addOnName),
+			Labels: map[string]string{
+				clientcert.ClusterNameLabel: clusterName,
+				clientcert.AddonNameLabel:   addOnName,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	results := resourceapply.ApplyDirectly(
+		ctx,
+		resourceapply.NewKubeClientHolder(c.kubeClient),
+		syncCtx.Recorder(),
+		c.cache,
+		c.rbacAssetFn(clusterName, addOnName),
+		addOnTokenRBACFiles...,
+	)
+	errs := []error{}
+	for _, result := range results {
+		if result.Error != nil {
+			errs = append(errs, fmt.Errorf("%q (%T): %v", result.File, result.Type, result.Error))
+		}
+	}
+	return operatorhelpers.NewMultiLineAggregate(errs)
+}
+
+func (c *addOnTokenServiceAccountController) removeServiceAccount(ctx context.Context, recorder events.Recorder, clusterName, addOnName string) error {
+	// revoke the permission to request the tokens before the service account is removed
+	if err := helpers.CleanUpManagedClusterManifests(ctx, c.kubeClient, recorder,
+		c.rbacAssetFn(clusterName, addOnName), addOnTokenRBACFiles...); err != nil {
+		return err
+	}
+
+	name := helpers.AddOnServiceAccountName(addOnName)
+	err := c.kubeClient.CoreV1().ServiceAccounts(clusterName).Delete(ctx, name, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	recorder.Eventf("AddOnServiceAccountDeleted", "service account %q of addon %q on managed cluster %q is deleted",
+		name, addOnName, clusterName)
+	return nil
+}
+
+func (c *addOnTokenServiceAccountController) rbacAssetFn(clusterName, addOnName string) resourceapply.AssetFunc {
+	config := addOnTokenRBACConfig{
+		ManagedClusterName:         clusterName,
+		AddOnName:                  addOnName,
+		ServiceAccountName:         helpers.AddOnServiceAccountName(addOnName),
+		HubServiceAccountNamespace: c.hubServiceAccountNamespace,
+		HubServiceAccountName:      c.hubServiceAccountName,
+	}
+	return func(name string) ([]byte, error) {
+		template, err := manifestFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		return assets.MustCreateAssetFromTemplate(name, template, config).Data, nil
+	}
+}
+
+func hasTokenRegistration(addOn *addonv1alpha1.ManagedClusterAddOn) bool {
+	for _, registration := range addOn.Status.Registrations {
+		if registration.SignerName == helpers.AddOnTokenSignerName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package addon
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestAddOnTokenServiceAccountSync(t *testing.T) {
+	now := metav1.Now()
+	newAddOn := func(signerName string, deletionTimestamp *metav1.Time) *addonv1alpha1.ManagedClusterAddOn {
+		return &addonv1alpha1.ManagedClusterAddOn{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         testinghelpers.TestManagedClusterName,
+				Name:              "test",
+				DeletionTimestamp: deletionTimestamp,
+			},
+			Status: addonv1alpha1.ManagedClusterAddOnStatus{
+				Registrations: []addonv1alpha1.RegistrationConfig{{SignerName: signerName}},
+			},
+		}
+	}
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testinghelpers.TestManagedClusterName,
+			Name:      helpers.AddOnServiceAccountName("test"),
+		},
+	}
+
+	cases := []struct {
+		name            string
+		addOns          []runtime.Object
+		serviceAccounts []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:   "addon with token registration",
+			addOns: []runtime.Object{newAddOn(helpers.AddOnTokenSignerName, nil)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create", "get", "create", "get", "create", "get", "create")
+				hubBinding := actions[3].(clienttesting.CreateActionImpl).Object.(*rbacv1.RoleBinding)
+				if hubBinding.Namespace != testinghelpers.TestManagedClusterName ||
+					hubBinding.RoleRef.Name != "open-cluster-management:hub:addon-token" ||
+					!reflect.DeepEqual(hubBinding.Subjects, []rbacv1.Subject{{Kind: "ServiceAccount", Namespace: "open-cluster-management-hub", Name: "hub-sa"}}) {
+					t.Errorf("expected the hub is bound in the cluster namespace before the role is created, but got %v", hubBinding)
+				}
+				role := actions[5].(clienttesting.CreateActionImpl).Object.(*rbacv1.Role)
+				if len(role.Rules) != 1 || !reflect.DeepEqual(role.Rules[0].ResourceNames, []string{helpers.AddOnServiceAccountName("test")}) {
+					t.Errorf("expected the role only allows to request the token of the addon service account, but got %v", role.Rules)
+				}
+			},
+		},
+		{
+			name:   "addon without token registration",
+			addOns: []runtime.Object{newAddOn("kubernetes.io/kube-apiserver-client", nil)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete", "delete", "delete", "delete")
+			},
+		},
+		{
+			name:            "addon is deleting",
+			addOns:          []runtime.Object{newAddOn(helpers.AddOnTokenSignerName, &now)},
+			serviceAccounts: []runtime.Object{serviceAccount},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete", "delete", "delete", "delete")
+			},
+		},
+		{
+			name:            "addon is deleted",
+			serviceAccounts: []runtime.Object{serviceAccount},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete", "delete", "delete", "delete")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			addOnClient := addonfake.NewSimpleClientset(c.addOns...)
+			addOnInformerFactory := addoninformers.NewSharedInformerFactory(addOnClient, time.Minute*10)
+			addOnStore := addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer().GetStore()
+			for _, addOn := range c.addOns {
+				addOnStore.Add(addOn)
+			}
+
+			kubeClient := kubefake.NewSimpleClientset(c.serviceAccounts...)
+
+			ctrl := &addOnTokenServiceAccountController{
+				kubeClient:                 kubeClient,
+				addOnLister:                addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				hubServiceAccountNamespace: "open-cluster-management-hub",
+				hubServiceAccountName:      "hub-sa",
+				cache:                      helpers.NewResourceCache(),
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName+"/test"))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateActions(t, kubeClient.Actions())
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package agentconfig
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const controllerName = "AgentConfigController"
+
+// agentConfigController publishes the agent settings of each accepted managed cluster in the agent config configmap
+// in the cluster namespace, they are the fleet-wide settings in the agent config configmap in the namespace of the
+// hub controller overridden with the agent config annotations of the cluster. The lease duration is set on the spec
+// of the cluster instead, which the agent renews its lease with and the hub checks the lease against. The settings
+// of a cluster failed to parse are not published, the agent keeps the settings it runs with.
+type agentConfigController struct {
+	kubeClient      kubernetes.Interface
+	clusterClient   clusterv1client.Interface
+	clusterLister   clusterv1listers.ManagedClusterLister
+	configMapLister corev1listers.ConfigMapLister
+	namespace       string
+	cache           resourceapply.ResourceCache
+}
+
+// NewAgentConfigController returns an instance of agentConfigController. The configmap informer should watch the
+// namespace of the hub controller.
+func NewAgentConfigController(
+	kubeClient kubernetes.Interface,
+	clusterClient clusterv1client.Interface,
+	clusterInformer clusterv1informer.ManagedClusterInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	namespace string,
+	recorder events.Recorder) factory.Controller {
+	c := &agentConfigController{
+		kubeClient:      kubeClient,
+		clusterClient:   clusterClient,
+		clusterLister:   clusterInformer.Lister(),
+		configMapLister: configMapInformer.Lister(),
+		namespace:       namespace,
+		cache:           helpers.NewResourceCache(),
+	}
+
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer()).
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			return accessor.GetNamespace() == namespace && accessor.GetName() == helpers.AgentConfigConfigMapName
+		}, configMapInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ToController(controllerName, recorder)
+}
+
+func (c *agentConfigController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	fleet := map[string]string{}
+	source, err := c.configMapLister.ConfigMaps(c.namespace).Get(helpers.AgentConfigConfigMapName)
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		return err
+	default:
+		fleet = source.Data
+	}
+
+	// the fleet-wide settings are changed, all of the clusters are synced
+	if syncCtx.QueueKey() == factory.DefaultQueueKey {
+		clusters, err := c.clusterLister.List(labels.Everything())
+		if err != nil {
+			return err
+		}
+		errs := []error{}
+		for _, cluster := range clusters {
+			if err := c.syncCluster(ctx, syncCtx.Recorder(), cluster, fleet); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return operatorhelpers.NewMultiLineAggregate(errs)
+	}
+
+	cluster, err := c.clusterLister.Get(syncCtx.QueueKey())
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return c.syncCluster(ctx, syncCtx.Recorder(), cluster, fleet)
+}
+
+func (c *agentConfigController) syncCluster(ctx context.Context, recorder events.Recorder,
+	cluster *clusterv1.ManagedCluster, fleet map[string]string) error {
+	if !cluster.Spec.HubAcceptsClient || !cluster.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	data := helpers.AgentConfigData(fleet, cluster.Annotations)
+	settings, err := helpers.ParseAgentSettings(data)
+	if err != nil {
+		recorder.Warningf("AgentConfigInvalid", "The agent settings of managed cluster %q are not published: %v",
+			cluster.Name, err)
+		return nil
+	}
+
+	if settings.LeaseDurationSeconds != nil && *settings.LeaseDurationSeconds != cluster.Spec.LeaseDurationSeconds {
+		patch, err := json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{"leaseDurationSeconds": *settings.LeaseDurationSeconds},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = c.clusterClient.ClusterV1().ManagedClusters().Patch(ctx, cluster.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	// the configmap is published even if there is no settings, so the agent resets the settings removed
+	required := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Name,
+			Name:      helpers.AgentConfigConfigMapName,
+		},
+		Data: data,
+	}
+	_, _, err = resourceapply.ApplyConfigMapImproved(ctx, c.kubeClient.CoreV1(), recorder, required, c.cache)
+	if errors.IsNotFound(err) {
+		// the namespace of the cluster is not created yet, the cluster is synced again once it is changed
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+package agentconfig
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+const testNamespace = "open-cluster-management-hub"
+
+func TestSync(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: helpers.AgentConfigConfigMapName},
+		Data: map[string]string{
+			helpers.AgentConfigLogLevelKey:             "2",
+			helpers.AgentConfigLeaseDurationSecondsKey: "60",
+		},
+	}
+	annotatedCluster := func(name string, annotations map[string]string) *clusterv1.ManagedCluster {
+		cluster := testinghelpers.NewManagedClusterBuilder(name).Accepted().WithLeaseDurationSeconds(60).Build()
+		cluster.Annotations = annotations
+		return cluster
+	}
+
+	cases := []struct {
+		name                   string
+		key                    string
+		source                 *corev1.ConfigMap
+		clusters               []*clusterv1.ManagedCluster
+		validateKubeActions    func(t *testing.T, actions []clienttesting.Action)
+		validateClusterActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:   "publish the fleet-wide settings to the accepted clusters",
+			key:    factory.DefaultQueueKey,
+			source: source,
+			clusters: []*clusterv1.ManagedCluster{
+				annotatedCluster("cluster1", nil),
+				testinghelpers.NewManagedClusterBuilder("cluster2").Build(),
+			},
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create")
+				published := actions[1].(clienttesting.CreateActionImpl).Object.(*corev1.ConfigMap)
+				if published.Namespace != "cluster1" || published.Data[helpers.AgentConfigLogLevelKey] != "2" {
+					t.Errorf("unexpected published configmap %v", published)
+				}
+			},
+			validateClusterActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:   "override the settings with the annotations of the cluster",
+			key:    "cluster1",
+			source: source,
+			clusters: []*clusterv1.ManagedCluster{
+				annotatedCluster("cluster1", map[string]string{
+					helpers.AgentConfigAnnotationPrefix + helpers.AgentConfigLogLevelKey:             "6",
+					helpers.AgentConfigAnnotationPrefix + helpers.AgentConfigLeaseDurationSecondsKey: "120",
+				}),
+			},
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create")
+				published := actions[1].(clienttesting.CreateActionImpl).Object.(*corev1.ConfigMap)
+				if published.Data[helpers.AgentConfigLogLevelKey] != "6" {
+					t.Errorf("unexpected published configmap %v", published)
+				}
+			},
+			validateClusterActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				patch := string(actions[0].(clienttesting.PatchActionImpl).Patch)
+				if patch != `{"spec":{"leaseDurationSeconds":120}}` {
+					t.Errorf("unexpected patch %s", patch)
+				}
+			},
+		},
+		{
+			name: "publish no settings",
+			key:  "cluster1",
+			clusters: []*clusterv1.ManagedCluster{
+				annotatedCluster("cluster1", nil),
+			},
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create")
+				published := actions[1].(clienttesting.CreateActionImpl).Object.(*corev1.ConfigMap)
+				if len(published.Data) != 0 {
+					t.Errorf("expected no settings, but got %v", published.Data)
+				}
+			},
+			validateClusterActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:   "invalid settings",
+			key:    "cluster1",
+			source: source,
+			clusters: []*clusterv1.ManagedCluster{
+				annotatedCluster("cluster1", map[string]string{
+					helpers.AgentConfigAnnotationPrefix + helpers.AgentConfigLogLevelKey: "debug",
+				}),
+			},
+			validateKubeActions:    testinghelpers.AssertNoActions,
+			validateClusterActions: testinghelpers.AssertNoActions,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if c.source != nil {
+				objects = append(objects, c.source)
+			}
+			kubeClient := kubefake.NewSimpleClientset(objects...)
+			kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, time.Minute*10)
+			if c.source != nil {
+				if err := kubeInformerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(c.source); err != nil {
+					t.Fatal(err)
+				}
+			}
+			clusterObjects := []runtime.Object{}
+			for _, cluster := range c.clusters {
+				clusterObjects = append(clusterObjects, cluster)
+			}
+			clusterClient := clusterfake.NewSimpleClientset(clusterObjects...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			for _, cluster := range c.clusters {
+				if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := &agentConfigController{
+				kubeClient:      kubeClient,
+				clusterClient:   clusterClient,
+				clusterLister:   clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				configMapLister: kubeInformerFactory.Core().V1().ConfigMaps().Lister(),
+				namespace:       testNamespace,
+				cache:           helpers.NewResourceCache(),
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, c.key))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+			c.validateKubeActions(t, kubeClient.Actions())
+			c.validateClusterActions(t, clusterClient.Actions())
+		})
+	}
+}
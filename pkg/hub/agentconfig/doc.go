@@ -0,0 +1,5 @@
+// package agentconfig contains the hub-side controller which propagates the agent settings to the managed
+// clusters. The hub admin tunes the agents of the fleet with the agent config configmap in the namespace of the hub
+// controller, and overrides the settings of a cluster with its annotations, so the agents are tuned without being
+// redeployed.
+package agentconfig
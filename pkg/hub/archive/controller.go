@@ -0,0 +1,212 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const (
+	controllerName = "ClusterArchiveController"
+
+	// ArchiveFinalizer is the finalizer of the managed clusters which holds the deletion of a cluster until it is
+	// archived
+	ArchiveFinalizer = "cluster.open-cluster-management.io/archive"
+	// ArchiveLabel is the label of the ConfigMaps of the archival records, its value is the name of the cluster
+	ArchiveLabel = "cluster.open-cluster-management.io/archive"
+	// RecordKey is the key of the archival record in the ConfigMap, the record is in JSON
+	RecordKey = "record.json"
+
+	// pruneInterval is the interval the expired records are pruned in
+	pruneInterval = time.Hour
+)
+
+// archiveController archives the managed clusters once they are deleted. The deletion of a cluster is held with
+// ArchiveFinalizer until its record is written, so a cluster deleted while the hub controller is down is archived
+// as well. The records older than the retention are pruned. If the archival is disabled, the controller only
+// removes the finalizer from the clusters, so their deletion is not blocked.
+type archiveController struct {
+	kubeClient    kubernetes.Interface
+	clusterClient clusterv1client.Interface
+	clusterLister clusterv1listers.ManagedClusterLister
+	namespace     string
+	archive       bool
+	retention     time.Duration
+	now           func() time.Time
+}
+
+// NewClusterArchiveController returns an instance of archiveController. The records are kept in the namespace,
+// and they are kept forever if the retention is zero.
+func NewClusterArchiveController(
+	kubeClient kubernetes.Interface,
+	clusterClient clusterv1client.Interface,
+	clusterInformer clusterv1informer.ManagedClusterInformer,
+	namespace string,
+	archive bool,
+	retention time.Duration,
+	recorder events.Recorder) factory.Controller {
+	c := &archiveController{
+		kubeClient:    kubeClient,
+		clusterClient: clusterClient,
+		clusterLister: clusterInformer.Lister(),
+		namespace:     namespace,
+		archive:       archive,
+		retention:     retention,
+		now:           time.Now,
+	}
+
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ResyncEvery(pruneInterval).
+		ToController(controllerName, recorder)
+}
+
+func (c *archiveController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	clusterName := syncCtx.QueueKey()
+	if clusterName == factory.DefaultQueueKey {
+		return c.prune(ctx)
+	}
+
+	cluster, err := c.clusterLister.Get(clusterName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !c.archive:
+		return c.removeFinalizer(ctx, cluster)
+	case cluster.DeletionTimestamp.IsZero():
+		return c.addFinalizer(ctx, cluster)
+	case !hasFinalizer(cluster):
+		return nil
+	}
+
+	created, err := c.writeRecord(ctx, NewRecord(cluster))
+	if err != nil {
+		return err
+	}
+	if created {
+		syncCtx.Recorder().Eventf("ManagedClusterArchived", "The managed cluster %q is archived in %s/%s",
+			cluster.Name, c.namespace, recordName(cluster.Name, cluster.UID))
+	}
+	return c.removeFinalizer(ctx, cluster)
+}
+
+// writeRecord creates the ConfigMap of the record, it returns false if the record is written already
+func (c *archiveController) writeRecord(ctx context.Context, record *Record) (bool, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return false, err
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: c.namespace,
+			Name:      recordName(record.Name, record.UID),
+			Labels:    map[string]string{ArchiveLabel: record.Name},
+		},
+		Data: map[string]string{RecordKey: string(data)},
+	}
+	_, err = c.kubeClient.CoreV1().ConfigMaps(c.namespace).Create(ctx, configMap, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to archive the managed cluster %q: %w", record.Name, err)
+	}
+	return true, nil
+}
+
+// prune deletes the records which are kept longer than the retention
+func (c *archiveController) prune(ctx context.Context) error {
+	if !c.archive || c.retention == 0 {
+		return nil
+	}
+
+	configMaps, err := c.kubeClient.CoreV1().ConfigMaps(c.namespace).List(ctx, metav1.ListOptions{LabelSelector: ArchiveLabel})
+	if err != nil {
+		return err
+	}
+	for _, configMap := range configMaps.Items {
+		if c.now().Sub(configMap.CreationTimestamp.Time) < c.retention {
+			continue
+		}
+		err := c.kubeClient.CoreV1().ConfigMaps(c.namespace).Delete(ctx, configMap.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		helpers.ControllerLogger(ctx, controllerName).Info("Pruned the expired archival record",
+			helpers.LogKeyCluster, configMap.Labels[ArchiveLabel], helpers.LogKeyResource, configMap.Name)
+	}
+	return nil
+}
+
+func (c *archiveController) addFinalizer(ctx context.Context, cluster *clusterv1.ManagedCluster) error {
+	if hasFinalizer(cluster) {
+		return nil
+	}
+	return c.patchFinalizers(ctx, cluster, append(append([]string{}, cluster.Finalizers...), ArchiveFinalizer))
+}
+
+func (c *archiveController) removeFinalizer(ctx context.Context, cluster *clusterv1.ManagedCluster) error {
+	if !hasFinalizer(cluster) {
+		return nil
+	}
+	finalizers := []string{}
+	for _, finalizer := range cluster.Finalizers {
+		if finalizer != ArchiveFinalizer {
+			finalizers = append(finalizers, finalizer)
+		}
+	}
+	return c.patchFinalizers(ctx, cluster, finalizers)
+}
+
+// patchFinalizers patches the finalizers of the cluster with its resource version, so the finalizers added or
+// removed by the other controllers in the meantime are not overwritten
+func (c *archiveController) patchFinalizers(ctx context.Context, cluster *clusterv1.ManagedCluster, finalizers []string) error {
+	patch, err := helpers.FinalizersPatch(cluster.ResourceVersion, finalizers)
+	if err != nil {
+		return err
+	}
+	_, err = c.clusterClient.ClusterV1().ManagedClusters().Patch(ctx, cluster.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func hasFinalizer(cluster *clusterv1.ManagedCluster) bool {
+	for _, finalizer := range cluster.Finalizers {
+		if finalizer == ArchiveFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// recordName returns the name of the ConfigMap of the record, the uid tells the records of the clusters with the
+// same name apart
+func recordName(clusterName string, uid types.UID) string {
+	return fmt.Sprintf("archive.%s.%s", clusterName, uid)
+}
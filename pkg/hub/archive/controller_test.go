@@ -0,0 +1,179 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+const testNamespace = "open-cluster-management-hub"
+
+func newArchivingCluster() *clusterv1.ManagedCluster {
+	cluster := testinghelpers.NewAvailableManagedCluster()
+	cluster.UID = "uid1"
+	cluster.Finalizers = []string{"cluster.open-cluster-management.io/api-resource-cleanup", ArchiveFinalizer}
+	cluster.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	cluster.Status.ClusterClaims = []clusterv1.ManagedClusterClaim{{Name: "id.k8s.io", Value: "cluster1"}}
+	cluster.Status.Conditions = append(cluster.Status.Conditions, metav1.Condition{
+		Type:               clusterv1.ManagedClusterConditionJoined,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Hour)),
+	})
+	return cluster
+}
+
+func TestSync(t *testing.T) {
+	cases := []struct {
+		name               string
+		cluster            *clusterv1.ManagedCluster
+		archive            bool
+		validateActions    func(t *testing.T, actions []clienttesting.Action)
+		expectedFinalizers string
+		expectedRecord     bool
+	}{
+		{
+			name:            "archival is disabled",
+			cluster:         testinghelpers.NewAvailableManagedCluster(),
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:               "remove the finalizer once the archival is disabled",
+			cluster:            newArchivingCluster(),
+			expectedFinalizers: `["cluster.open-cluster-management.io/api-resource-cleanup"]`,
+		},
+		{
+			name:               "add the finalizer",
+			cluster:            testinghelpers.NewAvailableManagedCluster(),
+			archive:            true,
+			expectedFinalizers: `["cluster.open-cluster-management.io/api-resource-cleanup","cluster.open-cluster-management.io/archive"]`,
+		},
+		{
+			name:               "archive the deleted cluster",
+			cluster:            newArchivingCluster(),
+			archive:            true,
+			expectedFinalizers: `["cluster.open-cluster-management.io/api-resource-cleanup"]`,
+			expectedRecord:     true,
+		},
+		{
+			name:            "the deleted cluster is archived",
+			cluster:         testinghelpers.NewDeletingManagedCluster(),
+			archive:         true,
+			validateActions: testinghelpers.AssertNoActions,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			clusterClient := clusterfake.NewSimpleClientset(c.cluster)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, 10*time.Minute)
+			if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(c.cluster); err != nil {
+				t.Fatal(err)
+			}
+
+			ctrl := &archiveController{
+				kubeClient:    kubeClient,
+				clusterClient: clusterClient,
+				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				namespace:     testNamespace,
+				archive:       c.archive,
+				now:           time.Now,
+			}
+			if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, c.cluster.Name)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if c.validateActions != nil {
+				c.validateActions(t, clusterClient.Actions())
+			}
+			if len(c.expectedFinalizers) > 0 {
+				testinghelpers.AssertActions(t, clusterClient.Actions(), "patch")
+				patch := map[string]map[string]interface{}{}
+				if err := json.Unmarshal(clusterClient.Actions()[0].(clienttesting.PatchActionImpl).Patch, &patch); err != nil {
+					t.Fatal(err)
+				}
+				finalizers, _ := json.Marshal(patch["metadata"]["finalizers"])
+				if string(finalizers) != c.expectedFinalizers {
+					t.Errorf("expected finalizers %s, but got %s", c.expectedFinalizers, finalizers)
+				}
+			}
+
+			configMaps, err := kubeClient.CoreV1().ConfigMaps(testNamespace).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !c.expectedRecord {
+				if len(configMaps.Items) != 0 {
+					t.Errorf("expected no record, but got %d", len(configMaps.Items))
+				}
+				return
+			}
+			if len(configMaps.Items) != 1 {
+				t.Fatalf("expected a record, but got %d", len(configMaps.Items))
+			}
+			configMap := configMaps.Items[0]
+			if configMap.Name != "archive.testmanagedcluster.uid1" || configMap.Labels[ArchiveLabel] != c.cluster.Name {
+				t.Errorf("unexpected record %s with labels %v", configMap.Name, configMap.Labels)
+			}
+			record := &Record{}
+			if err := json.Unmarshal([]byte(configMap.Data[RecordKey]), record); err != nil {
+				t.Fatal(err)
+			}
+			if record.Name != c.cluster.Name || record.Claims["id.k8s.io"] != "cluster1" || record.JoinedTime == nil ||
+				record.LastAvailability == nil || record.LastAvailability.Status != metav1.ConditionTrue ||
+				record.DetachedTime.IsZero() {
+				t.Errorf("unexpected record %s", configMap.Data[RecordKey])
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Now()
+	newRecord := func(name string, age time.Duration) runtime.Object {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         testNamespace,
+				Name:              name,
+				Labels:            map[string]string{ArchiveLabel: "cluster1"},
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+		}
+	}
+	kubeClient := kubefake.NewSimpleClientset(
+		newRecord("archive.cluster1.uid1", 48*time.Hour),
+		newRecord("archive.cluster1.uid2", time.Hour),
+	)
+
+	ctrl := &archiveController{
+		kubeClient: kubeClient,
+		namespace:  testNamespace,
+		archive:    true,
+		retention:  24 * time.Hour,
+		now:        func() time.Time { return now },
+	}
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	configMaps, err := kubeClient.CoreV1().ConfigMaps(testNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configMaps.Items) != 1 || configMaps.Items[0].Name != "archive.cluster1.uid2" {
+		t.Errorf("expected only the record within the retention is kept, but got %v", configMaps.Items)
+	}
+}
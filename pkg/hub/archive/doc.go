@@ -0,0 +1,5 @@
+// package archive contains the hub-side controller which archives the managed clusters once they are detached
+// from the hub. A compact record of a deleted managed cluster, with its final claims, the time it joined and was
+// detached and its last availability, is kept in a ConfigMap in the namespace of the hub controller, so the
+// history of the fleet survives the detaches for auditing.
+package archive
@@ -0,0 +1,63 @@
+package archive
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// Record is the archival record of a detached managed cluster
+type Record struct {
+	Name       string            `json:"name"`
+	UID        types.UID         `json:"uid"`
+	ClusterSet string            `json:"clusterSet,omitempty"`
+	Claims     map[string]string `json:"claims,omitempty"`
+	// JoinedTime is the time the managed cluster joined the hub, it is nil if the cluster never joined
+	JoinedTime *metav1.Time `json:"joinedTime,omitempty"`
+	// DetachedTime is the time the managed cluster was deleted
+	DetachedTime metav1.Time `json:"detachedTime"`
+	// LastAvailability is the last Available condition of the managed cluster, it is nil if the condition is not set
+	LastAvailability *Availability `json:"lastAvailability,omitempty"`
+}
+
+// Availability is the Available condition of a managed cluster
+type Availability struct {
+	Status metav1.ConditionStatus `json:"status"`
+	Reason string                 `json:"reason,omitempty"`
+	// Time is the last time the status transitioned
+	Time metav1.Time `json:"time"`
+}
+
+// NewRecord returns the archival record of a managed cluster being deleted
+func NewRecord(cluster *clusterv1.ManagedCluster) *Record {
+	record := &Record{
+		Name:       cluster.Name,
+		UID:        cluster.UID,
+		ClusterSet: cluster.Labels[helpers.ClusterSetLabel],
+	}
+	if cluster.DeletionTimestamp != nil {
+		record.DetachedTime = *cluster.DeletionTimestamp
+	}
+	if condition := meta.FindStatusCondition(cluster.Status.Conditions, clusterv1.ManagedClusterConditionJoined); condition != nil &&
+		condition.Status == metav1.ConditionTrue {
+		joinedTime := condition.LastTransitionTime
+		record.JoinedTime = &joinedTime
+	}
+	if condition := meta.FindStatusCondition(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable); condition != nil {
+		record.LastAvailability = &Availability{
+			Status: condition.Status,
+			Reason: condition.Reason,
+			Time:   condition.LastTransitionTime,
+		}
+	}
+	if len(cluster.Status.ClusterClaims) > 0 {
+		record.Claims = map[string]string{}
+		for _, claim := range cluster.Status.ClusterClaims {
+			record.Claims[claim.Name] = claim.Value
+		}
+	}
+	return record
+}
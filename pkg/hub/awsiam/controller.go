@@ -0,0 +1,237 @@
+package awsiam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/hub/user"
+)
+
+const controllerName = "AWSIAMRoleMappingController"
+
+const (
+	// AWSAuthConfigMapNamespace and AWSAuthConfigMapName are the namespace and name of the configmap which the EKS
+	// api server maps the IAM roles to kubernetes users and groups with
+	AWSAuthConfigMapNamespace = "kube-system"
+	AWSAuthConfigMapName      = "aws-auth"
+
+	// mapRolesKey is the key of the role mappings in the data of the aws-auth configmap
+	mapRolesKey = "mapRoles"
+
+	// the keys of an entry of the role mappings in the aws-auth configmap
+	roleARNKey  = "rolearn"
+	usernameKey = "username"
+	groupsKey   = "groups"
+
+	// resyncInterval is the interval the mappings of all clusters are checked against the aws-auth configmap in.
+	// The configmap is not watched since the hub controller is only allowed to get and update it.
+	resyncInterval = 10 * time.Minute
+)
+
+// awsIAMRoleMappingController maps the IAM role of each accepted managed cluster using the aws iam registration
+// driver to the identity of its agent in the aws-auth configmap. The username of the agent is
+// system:open-cluster-management:<cluster name>:<session name>, where the agent assumes the role with its agent name
+// as the session name, and its groups are the same as the subject of its client certificate, so the agent is
+// granted the same permissions without the client certificate. The mapping is removed once the cluster is denied,
+// deleted or switched to another registration driver.
+type awsIAMRoleMappingController struct {
+	kubeClient    kubernetes.Interface
+	clusterClient clientset.Interface
+	clusterLister listerv1.ManagedClusterLister
+	eventRecorder events.Recorder
+}
+
+// NewAWSIAMRoleMappingController returns an instance of awsIAMRoleMappingController
+func NewAWSIAMRoleMappingController(
+	kubeClient kubernetes.Interface,
+	clusterClient clientset.Interface,
+	clusterInformer informerv1.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &awsIAMRoleMappingController{
+		kubeClient:    kubeClient,
+		clusterClient: clusterClient,
+		clusterLister: clusterInformer.Lister(),
+		eventRecorder: recorder.WithComponentSuffix("aws-iam-role-mapping-controller"),
+	}
+
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer()).
+		// the mappings of all clusters are checked again periodically in case the aws-auth configmap is changed
+		ResyncEvery(resyncInterval).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ToController(controllerName, recorder)
+}
+
+func (c *awsIAMRoleMappingController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	clusterName := syncCtx.QueueKey()
+	if clusterName == factory.DefaultQueueKey {
+		clusters, err := c.clusterLister.List(labels.Everything())
+		if err != nil {
+			return err
+		}
+		for _, cluster := range clusters {
+			if usesAWSIAMRegistration(cluster) || len(cluster.Annotations[helpers.AWSIAMRoleMappedAnnotation]) > 0 {
+				syncCtx.Queue().Add(cluster.Name)
+			}
+		}
+		return nil
+	}
+
+	cluster, err := c.clusterLister.Get(clusterName)
+	switch {
+	case errors.IsNotFound(err):
+		cluster = nil
+	case err != nil:
+		return err
+	}
+
+	roleARN := mappedRoleARN(cluster)
+	if err := c.applyRoleMapping(ctx, clusterName, roleARN); err != nil {
+		return err
+	}
+	if cluster == nil || cluster.Annotations[helpers.AWSIAMRoleMappedAnnotation] == roleARN {
+		return nil
+	}
+
+	// tell the agent the role is mapped, so it starts to authenticate with the role
+	var mapped interface{}
+	if len(roleARN) > 0 {
+		mapped = roleARN
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{helpers.AWSIAMRoleMappedAnnotation: mapped},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.clusterClient.ClusterV1().ManagedClusters().Patch(ctx, clusterName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// applyRoleMapping makes sure the role is the only one mapped to the agent of the cluster in the aws-auth
+// configmap, and removes the mapping of the cluster if the role is empty. The configmap is never created by the
+// hub controller, it is expected to be created with the EKS cluster. The entries are edited as generic maps and
+// only the entry of the agent is changed, so the keys of the other entries unknown to the hub controller, which
+// are written by the other tools, are kept.
+func (c *awsIAMRoleMappingController) applyRoleMapping(ctx context.Context, clusterName, roleARN string) error {
+	configMap, err := c.kubeClient.CoreV1().ConfigMaps(AWSAuthConfigMapNamespace).Get(ctx, AWSAuthConfigMapName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err) && len(roleARN) == 0:
+		return nil
+	case errors.IsNotFound(err):
+		return fmt.Errorf("unable to map IAM role %q of managed cluster %q: configmap %q is not found", roleARN,
+			clusterName, AWSAuthConfigMapNamespace+"/"+AWSAuthConfigMapName)
+	case err != nil:
+		return err
+	}
+
+	mappings := []map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(configMap.Data[mapRolesKey]), &mappings); err != nil {
+		return fmt.Errorf("unable to parse %q of configmap %q: %w", mapRolesKey,
+			AWSAuthConfigMapNamespace+"/"+AWSAuthConfigMapName, err)
+	}
+
+	username := agentUsername(clusterName)
+	var agentMapping map[string]interface{}
+	if len(roleARN) > 0 {
+		agentMapping = map[string]interface{}{
+			roleARNKey:  roleARN,
+			usernameKey: username,
+			groupsKey:   []interface{}{user.SubjectPrefix + clusterName, user.ManagedClustersGroup},
+		}
+	}
+
+	desired := []map[string]interface{}{}
+	for _, mapping := range mappings {
+		if mapping[usernameKey] == username {
+			// the entry of the agent is replaced in place, and the duplicated ones are removed
+			if agentMapping != nil {
+				desired = append(desired, agentMapping)
+				agentMapping = nil
+			}
+			continue
+		}
+		// the role of another cluster or user is never mapped to the agent, otherwise the agent of the other
+		// cluster would be granted the permissions of the cluster
+		if len(roleARN) > 0 && mapping[roleARNKey] == roleARN {
+			return fmt.Errorf("IAM role %q of managed cluster %q is already mapped to user %q", roleARN, clusterName,
+				fmt.Sprint(mapping[usernameKey]))
+		}
+		desired = append(desired, mapping)
+	}
+	if agentMapping != nil {
+		desired = append(desired, agentMapping)
+	}
+	if equality.Semantic.DeepEqual(desired, mappings) {
+		return nil
+	}
+
+	data, err := yaml.Marshal(desired)
+	if err != nil {
+		return err
+	}
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[mapRolesKey] = string(data)
+	if _, err := c.kubeClient.CoreV1().ConfigMaps(AWSAuthConfigMapNamespace).Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	if len(roleARN) > 0 {
+		c.eventRecorder.Eventf("AWSIAMRoleMapped", "The IAM role %q is mapped to the agent of managed cluster %q", roleARN, clusterName)
+	} else {
+		c.eventRecorder.Eventf("AWSIAMRoleUnmapped", "The IAM role of the agent of managed cluster %q is unmapped", clusterName)
+	}
+	return nil
+}
+
+// mappedRoleARN returns the IAM role should be mapped to the agent of the cluster, which is empty if the cluster
+// is not found, not accepted, deleting, or not using the aws iam registration driver
+func mappedRoleARN(cluster *clusterv1.ManagedCluster) string {
+	if cluster == nil || !usesAWSIAMRegistration(cluster) || !cluster.DeletionTimestamp.IsZero() ||
+		!cluster.Spec.HubAcceptsClient ||
+		!meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
+		return ""
+	}
+	roleARN := cluster.Annotations[helpers.AWSIAMRoleARNAnnotation]
+	if !helpers.IsValidAWSIAMRoleARN(roleARN) {
+		return ""
+	}
+	return roleARN
+}
+
+// usesAWSIAMRegistration returns true if the agent of the cluster uses the aws iam registration driver
+func usesAWSIAMRegistration(cluster *clusterv1.ManagedCluster) bool {
+	return cluster.Annotations[helpers.RegistrationDriverAnnotation] == helpers.AWSIAMRegistrationDriver
+}
+
+// agentUsername returns the username the IAM role of the cluster is mapped to. The session name is the agent name.
+func agentUsername(clusterName string) string {
+	return fmt.Sprintf("%s%s:{{SessionName}}", user.SubjectPrefix, clusterName)
+}
@@ -0,0 +1,278 @@
+package awsiam
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+const testRoleARN = "arn:aws:iam::123456789012:role/ocm-testmanagedcluster"
+
+const nodeRoleMapping = `- groups:
+  - system:bootstrappers
+  - system:nodes
+  rolearn: arn:aws:iam::123456789012:role/eks-node
+  username: system:node:{{EC2PrivateDNSName}}
+`
+
+// adminRoleMapping carries the keys unknown to the hub controller, which are written by the other tools
+const adminRoleMapping = `- groups:
+  - system:masters
+  rolearn: arn:aws:iam::123456789012:role/admin
+  sso:
+    permissionSetName: AdministratorAccess
+  username: admin:{{SessionName}}
+  x-managed-by: eksctl
+`
+
+const agentRoleMapping = `- groups:
+  - system:open-cluster-management:testmanagedcluster
+  - system:open-cluster-management:managed-clusters
+  rolearn: arn:aws:iam::123456789012:role/ocm-testmanagedcluster
+  username: system:open-cluster-management:testmanagedcluster:{{SessionName}}
+`
+
+func newAWSIAMCluster(cluster *clusterv1.ManagedCluster, mapped bool) *clusterv1.ManagedCluster {
+	cluster.Annotations = map[string]string{
+		helpers.RegistrationDriverAnnotation: helpers.AWSIAMRegistrationDriver,
+		helpers.AWSIAMRoleARNAnnotation:      testRoleARN,
+	}
+	if mapped {
+		cluster.Annotations[helpers.AWSIAMRoleMappedAnnotation] = testRoleARN
+	}
+	return cluster
+}
+
+func newAWSAuthConfigMap(mapRoles string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       AWSAuthConfigMapNamespace,
+			Name:            AWSAuthConfigMapName,
+			ResourceVersion: "1",
+		},
+		Data: map[string]string{mapRolesKey: mapRoles},
+	}
+}
+
+// updatedMapRoles returns the role mappings in the aws-auth configmap updated by the action
+func updatedMapRoles(t *testing.T, action clienttesting.Action) string {
+	if action.GetVerb() != "update" || action.GetResource().Resource != "configmaps" {
+		t.Fatalf("expected the configmap to be updated, but got %v", action)
+	}
+	return action.(clienttesting.UpdateActionImpl).Object.(*corev1.ConfigMap).Data[mapRolesKey]
+}
+
+// patchedAnnotation returns the role mapped annotation in the patch of the managed cluster
+func patchedAnnotation(t *testing.T, action clienttesting.Action) interface{} {
+	patch := map[string]map[string]map[string]interface{}{}
+	if err := json.Unmarshal(action.(clienttesting.PatchActionImpl).Patch, &patch); err != nil {
+		t.Fatal(err)
+	}
+	return patch["metadata"]["annotations"][helpers.AWSIAMRoleMappedAnnotation]
+}
+
+func TestSync(t *testing.T) {
+	cases := []struct {
+		name                  string
+		cluster               *clusterv1.ManagedCluster
+		configMap             *corev1.ConfigMap
+		expectedErr           string
+		validateKubeActions   func(t *testing.T, actions []clienttesting.Action)
+		validateClusterAction func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:      "cluster using client certificates",
+			cluster:   testinghelpers.NewAcceptedManagedCluster(),
+			configMap: newAWSAuthConfigMap(nodeRoleMapping),
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+			validateClusterAction: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:      "accepted cluster",
+			cluster:   newAWSIAMCluster(testinghelpers.NewAcceptedManagedCluster(), false),
+			configMap: newAWSAuthConfigMap(nodeRoleMapping),
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				if mapRoles := updatedMapRoles(t, actions[1]); mapRoles != nodeRoleMapping+agentRoleMapping {
+					t.Errorf("expected the role to be mapped, but got\n%s", mapRoles)
+				}
+			},
+			validateClusterAction: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				if mapped := patchedAnnotation(t, actions[0]); mapped != testRoleARN {
+					t.Errorf("expected the cluster to be annotated with the mapped role, but got %v", mapped)
+				}
+			},
+		},
+		{
+			name:      "accepted cluster with the other entries carrying unknown keys",
+			cluster:   newAWSIAMCluster(testinghelpers.NewAcceptedManagedCluster(), false),
+			configMap: newAWSAuthConfigMap(adminRoleMapping + nodeRoleMapping),
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				if mapRoles := updatedMapRoles(t, actions[1]); mapRoles != adminRoleMapping+nodeRoleMapping+agentRoleMapping {
+					t.Errorf("expected the role to be mapped and the other entries kept, but got\n%s", mapRoles)
+				}
+			},
+			validateClusterAction: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+			},
+		},
+		{
+			name:        "no aws-auth configmap",
+			cluster:     newAWSIAMCluster(testinghelpers.NewAcceptedManagedCluster(), false),
+			expectedErr: "unable to map IAM role \"arn:aws:iam::123456789012:role/ocm-testmanagedcluster\" of managed cluster \"testmanagedcluster\": configmap \"kube-system/aws-auth\" is not found",
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+			validateClusterAction: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:      "mapped role",
+			cluster:   newAWSIAMCluster(testinghelpers.NewJoinedManagedCluster(), true),
+			configMap: newAWSAuthConfigMap(nodeRoleMapping + agentRoleMapping),
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+			validateClusterAction: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:        "role mapped to another user",
+			cluster:     newAWSIAMCluster(testinghelpers.NewAcceptedManagedCluster(), false),
+			configMap:   newAWSAuthConfigMap(strings.ReplaceAll(nodeRoleMapping, "eks-node", "ocm-testmanagedcluster")),
+			expectedErr: "IAM role \"arn:aws:iam::123456789012:role/ocm-testmanagedcluster\" of managed cluster \"testmanagedcluster\" is already mapped to user \"system:node:{{EC2PrivateDNSName}}\"",
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+			validateClusterAction: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:      "denied cluster",
+			cluster:   newAWSIAMCluster(testinghelpers.NewDeniedManagedCluster(), true),
+			configMap: newAWSAuthConfigMap(nodeRoleMapping + agentRoleMapping),
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				if mapRoles := updatedMapRoles(t, actions[1]); mapRoles != nodeRoleMapping {
+					t.Errorf("expected the role to be unmapped, but got\n%s", mapRoles)
+				}
+			},
+			validateClusterAction: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				if mapped := patchedAnnotation(t, actions[0]); mapped != nil {
+					t.Errorf("expected the mapped role annotation to be removed, but got %v", mapped)
+				}
+			},
+		},
+		{
+			name:      "deleted cluster with the other entries carrying unknown keys",
+			configMap: newAWSAuthConfigMap(adminRoleMapping + agentRoleMapping + nodeRoleMapping),
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				if mapRoles := updatedMapRoles(t, actions[1]); mapRoles != adminRoleMapping+nodeRoleMapping {
+					t.Errorf("expected the role to be unmapped and the other entries kept, but got\n%s", mapRoles)
+				}
+			},
+			validateClusterAction: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:      "deleted cluster",
+			configMap: newAWSAuthConfigMap(nodeRoleMapping + agentRoleMapping),
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				if mapRoles := updatedMapRoles(t, actions[1]); mapRoles != nodeRoleMapping {
+					t.Errorf("expected the role to be unmapped, but got\n%s", mapRoles)
+				}
+			},
+			validateClusterAction: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeObjects := []runtime.Object{}
+			if c.configMap != nil {
+				kubeObjects = append(kubeObjects, c.configMap)
+			}
+			kubeClient := kubefake.NewSimpleClientset(kubeObjects...)
+
+			clusterObjects := []runtime.Object{}
+			if c.cluster != nil {
+				clusterObjects = append(clusterObjects, c.cluster)
+			}
+			clusterClient := clusterfake.NewSimpleClientset(clusterObjects...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			for _, obj := range clusterObjects {
+				if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(obj); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := &awsIAMRoleMappingController{
+				kubeClient:    kubeClient,
+				clusterClient: clusterClient,
+				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				eventRecorder: eventstesting.NewTestingEventRecorder(t),
+			}
+			err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			c.validateKubeActions(t, kubeClient.Actions())
+			c.validateClusterAction(t, clusterClient.Actions())
+		})
+	}
+}
+
+func TestSyncAll(t *testing.T) {
+	clusters := []runtime.Object{
+		testinghelpers.NewAcceptedManagedCluster(),
+		newAWSIAMCluster(testinghelpers.NewJoinedManagedCluster(), true),
+	}
+	clusters[0].(*clusterv1.ManagedCluster).Name = "cluster1"
+	clusterClient := clusterfake.NewSimpleClientset(clusters...)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+	for _, obj := range clusters {
+		if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctrl := &awsIAMRoleMappingController{
+		clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+	}
+	syncCtx := testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey)
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if syncCtx.Queue().Len() != 1 {
+		t.Errorf("expected only the cluster using the aws iam registration driver to be requeued, but got %d", syncCtx.Queue().Len())
+	}
+}
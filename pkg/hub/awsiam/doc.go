@@ -0,0 +1,6 @@
+// package awsiam contains the hub-side controller of the aws iam registration driver, for the EKS hubs. The agents
+// of the clusters annotated with the aws iam registration driver authenticate to the hub with the IAM role of the
+// cluster instead of client certificates. The hub maps the role to the identity of the agent in the aws-auth
+// configmap once the cluster is accepted, and removes the mapping once the cluster is denied or deleted. The hub
+// controller is only allowed to get and update the aws-auth configmap, see deploy/hub/aws-iam.
+package awsiam
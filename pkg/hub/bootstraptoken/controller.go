@@ -0,0 +1,341 @@
+package bootstraptoken
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/hub/importer"
+)
+
+const controllerName = "BootstrapTokenController"
+
+const (
+	// DefaultGenerations is the default number of the generations of the bootstrap token kept valid
+	DefaultGenerations = 2
+	// MinRotationInterval is the min interval the bootstrap token is rotated in, the tokens shorter-lived are
+	// not issued by the apiserver
+	MinRotationInterval = 10 * time.Minute
+)
+
+const (
+	// BootstrapServiceAccountName is the name of the bootstrap service account in the namespace of the hub
+	// controller, which the bootstrap tokens are issued for
+	BootstrapServiceAccountName = "cluster-bootstrap"
+	// BootstrapKubeconfigSecretName is the name of the secret in the namespace of the hub controller, which holds
+	// the bootstrap kubeconfig of the latest generation with the key importer.BootstrapKubeconfigKey
+	BootstrapKubeconfigSecretName = "bootstrap-hub-kubeconfig"
+
+	// GenerationLabel is the label of the secrets of the generations of the bootstrap token
+	GenerationLabel = "open-cluster-management.io/bootstrap-token-generation"
+	// issuedAtAnnotation records the time a generation of the bootstrap token is issued
+	issuedAtAnnotation = "open-cluster-management.io/bootstrap-token-issued-at"
+	// tokenKey is the key of the token in the secret of a generation
+	tokenKey = "token"
+
+	// bootstrapClusterRoleName is the name of the clusterrole and the clusterrolebinding of the bootstrap service
+	// account
+	bootstrapClusterRoleName = "open-cluster-management:bootstrap-token"
+	// rootCAConfigMapName is the configmap of the CA of the hub apiserver published in each namespace
+	rootCAConfigMapName = "kube-root-ca.crt"
+
+	// pendingPeriod is the period a generation is issued in. A generation without a kubeconfig is still being
+	// issued within the period, otherwise it is left by a failed rotation and pruned.
+	pendingPeriod = time.Minute
+)
+
+// bootstrapTokenController rotates the bootstrap token of the bootstrap service account every rotationInterval.
+// Each generation of the token is requested bound to a secret labeled with GenerationLabel which holds the token
+// and its bootstrap kubeconfig, so deleting the secret invalidates the token. The latest generations are kept, and
+// the bootstrap kubeconfig of the latest one is published in the secret BootstrapKubeconfigSecretName. The hub is
+// verified with the hub CA bundle if the hub CA is rotated, or with the CA in the kube-root-ca.crt configmap.
+type bootstrapTokenController struct {
+	kubeClient       kubernetes.Interface
+	secretLister     corev1listers.SecretLister
+	configMapLister  corev1listers.ConfigMapLister
+	namespace        string
+	hubServer        string
+	rotationInterval time.Duration
+	generations      int
+	now              func() time.Time
+}
+
+// NewBootstrapTokenController returns an instance of bootstrapTokenController. The secret and configmap informers
+// should watch the namespace of the hub controller.
+func NewBootstrapTokenController(
+	kubeClient kubernetes.Interface,
+	secretInformer corev1informers.SecretInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	namespace, hubServer string,
+	rotationInterval time.Duration,
+	generations int,
+	recorder events.Recorder) factory.Controller {
+	c := &bootstrapTokenController{
+		kubeClient:       kubeClient,
+		secretLister:     secretInformer.Lister(),
+		configMapLister:  configMapInformer.Lister(),
+		namespace:        namespace,
+		hubServer:        hubServer,
+		rotationInterval: rotationInterval,
+		generations:      generations,
+		now:              time.Now,
+	}
+
+	return factory.New().
+		WithBareInformers(configMapInformer.Informer()).
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			_, ok := accessor.GetLabels()[GenerationLabel]
+			return accessor.GetNamespace() == namespace && (ok || accessor.GetName() == BootstrapKubeconfigSecretName)
+		}, secretInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ResyncEvery(10*time.Minute).
+		ToController(controllerName, recorder)
+}
+
+func (c *bootstrapTokenController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	health.EnterPhase(ctx, "apply resources")
+	if err := c.applyBootstrapServiceAccount(ctx, syncCtx.Recorder()); err != nil {
+		return err
+	}
+
+	now := c.now()
+	generations, incomplete, pending, err := c.listGenerations(now)
+	if err != nil {
+		return err
+	}
+	if pending {
+		syncCtx.Queue().AddAfter(factory.DefaultQueueKey, pendingPeriod)
+		return nil
+	}
+
+	health.EnterPhase(ctx, "rotate token")
+	if len(generations) == 0 || !now.Before(issuedAt(generations[0]).Add(c.rotationInterval)) {
+		latest, err := c.issueGeneration(ctx, now)
+		if err != nil {
+			return err
+		}
+		syncCtx.Recorder().Eventf("BootstrapTokenRotated", "The bootstrap token is rotated with the generation %q", latest.Name)
+		generations = append([]*corev1.Secret{latest}, generations...)
+	}
+
+	// the tokens of the old generations are invalidated along with their secrets
+	pruned := incomplete
+	if len(generations) > c.generations {
+		pruned = append(pruned, generations[c.generations:]...)
+	}
+	errs := []error{}
+	for _, generation := range pruned {
+		err := c.kubeClient.CoreV1().Secrets(c.namespace).Delete(ctx, generation.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+			continue
+		}
+		syncCtx.Recorder().Eventf("BootstrapTokenRevoked", "The bootstrap token of the generation %q is revoked", generation.Name)
+	}
+	if len(errs) > 0 {
+		return operatorhelpers.NewMultiLineAggregate(errs)
+	}
+
+	health.EnterPhase(ctx, "publish kubeconfig")
+	if _, _, err := resourceapply.ApplySecret(ctx, c.kubeClient.CoreV1(), syncCtx.Recorder(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   c.namespace,
+			Name:        BootstrapKubeconfigSecretName,
+			Annotations: map[string]string{issuedAtAnnotation: generations[0].Annotations[issuedAtAnnotation]},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			importer.BootstrapKubeconfigKey: generations[0].Data[importer.BootstrapKubeconfigKey],
+		},
+	}); err != nil {
+		return err
+	}
+
+	// sync again once the latest generation should be rotated
+	syncCtx.Queue().AddAfter(factory.DefaultQueueKey, issuedAt(generations[0]).Add(c.rotationInterval).Sub(now))
+	return nil
+}
+
+// listGenerations returns the generations of the bootstrap token from the latest to the oldest, the incomplete
+// generations left by the failed rotations, and whether any generation is still being issued
+func (c *bootstrapTokenController) listGenerations(now time.Time) ([]*corev1.Secret, []*corev1.Secret, bool, error) {
+	requirement, err := labels.NewRequirement(GenerationLabel, selection.Exists, nil)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	secrets, err := c.secretLister.Secrets(c.namespace).List(labels.NewSelector().Add(*requirement))
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	generations, incomplete, pending := []*corev1.Secret{}, []*corev1.Secret{}, false
+	for _, secret := range secrets {
+		if len(secret.Data[importer.BootstrapKubeconfigKey]) == 0 {
+			if now.Before(issuedAt(secret).Add(pendingPeriod)) {
+				pending = true
+				continue
+			}
+			incomplete = append(incomplete, secret)
+			continue
+		}
+		generations = append(generations, secret)
+	}
+	sort.SliceStable(generations, func(i, j int) bool {
+		return issuedAt(generations[i]).After(issuedAt(generations[j]))
+	})
+	return generations, incomplete, pending, nil
+}
+
+// issueGeneration creates the secret of a new generation, and requests a token bound to it. The token expires
+// once the generation would be pruned.
+func (c *bootstrapTokenController) issueGeneration(ctx context.Context, now time.Time) (*corev1.Secret, error) {
+	caData, err := c.hubCAData()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := c.kubeClient.CoreV1().Secrets(c.namespace).Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   c.namespace,
+			Name:        fmt.Sprintf("bootstrap-token-%d", now.Unix()),
+			Labels:      map[string]string{GenerationLabel: ""},
+			Annotations: map[string]string{issuedAtAnnotation: now.UTC().Format(time.RFC3339)},
+		},
+		Type: corev1.SecretTypeOpaque,
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	expirationSeconds := int64((c.rotationInterval * time.Duration(c.generations)).Seconds())
+	tokenRequest, err := c.kubeClient.CoreV1().ServiceAccounts(c.namespace).CreateToken(ctx, BootstrapServiceAccountName,
+		&authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				ExpirationSeconds: &expirationSeconds,
+				BoundObjectRef: &authenticationv1.BoundObjectReference{
+					APIVersion: "v1",
+					Kind:       "Secret",
+					Name:       secret.Name,
+					UID:        secret.UID,
+				},
+			},
+		}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to request token for service account %q: %w",
+			c.namespace+"/"+BootstrapServiceAccountName, err)
+	}
+	kubeconfig, err := importer.BuildBootstrapKubeconfig(c.hubServer, caData, tokenRequest.Status.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	secret = secret.DeepCopy()
+	secret.Data = map[string][]byte{
+		tokenKey:                        []byte(tokenRequest.Status.Token),
+		importer.BootstrapKubeconfigKey: kubeconfig,
+	}
+	return c.kubeClient.CoreV1().Secrets(c.namespace).Update(ctx, secret, metav1.UpdateOptions{})
+}
+
+// hubCAData returns the CA bundle to verify the hub, which is the hub CA bundle while the hub CA is rotated
+func (c *bootstrapTokenController) hubCAData() ([]byte, error) {
+	hubCABundle, err := c.configMapLister.ConfigMaps(c.namespace).Get(helpers.HubCABundleConfigMapName)
+	switch {
+	case err == nil:
+		bundle := helpers.MergeCABundles([]byte(hubCABundle.Data[helpers.HubCABundleKey]),
+			[]byte(hubCABundle.Data[helpers.HubNewCAKey]))
+		if len(bundle) > 0 {
+			return bundle, nil
+		}
+	case !errors.IsNotFound(err):
+		return nil, err
+	}
+
+	rootCA, err := c.configMapLister.ConfigMaps(c.namespace).Get(rootCAConfigMapName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the CA of the hub: %w", err)
+	}
+	return []byte(rootCA.Data["ca.crt"]), nil
+}
+
+// applyBootstrapServiceAccount applies the bootstrap service account with its permissions, which are to create
+// the csrs and the managed clusters
+func (c *bootstrapTokenController) applyBootstrapServiceAccount(ctx context.Context, recorder events.Recorder) error {
+	errs := []error{}
+	if _, _, err := resourceapply.ApplyServiceAccount(ctx, c.kubeClient.CoreV1(), recorder, &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: c.namespace,
+			Name:      BootstrapServiceAccountName,
+		},
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	if _, _, err := resourceapply.ApplyClusterRole(ctx, c.kubeClient.RbacV1(), recorder, &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: bootstrapClusterRoleName},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{"certificates.k8s.io"},
+				Resources: []string{"certificatesigningrequests"},
+				Verbs:     []string{"create", "get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{"cluster.open-cluster-management.io"},
+				Resources: []string{"managedclusters"},
+				Verbs:     []string{"create", "get"},
+			},
+		},
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	if _, _, err := resourceapply.ApplyClusterRoleBinding(ctx, c.kubeClient.RbacV1(), recorder, &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: bootstrapClusterRoleName},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     bootstrapClusterRoleName,
+		},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Namespace: c.namespace,
+			Name:      BootstrapServiceAccountName,
+		}},
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	return operatorhelpers.NewMultiLineAggregate(errs)
+}
+
+// issuedAt returns the time a generation is issued, the generations with an invalid time are the oldest
+func issuedAt(secret *corev1.Secret) time.Time {
+	t, err := time.Parse(time.RFC3339, secret.Annotations[issuedAtAnnotation])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
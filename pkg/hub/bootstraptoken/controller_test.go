@@ -0,0 +1,189 @@
+package bootstraptoken
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+	"open-cluster-management.io/registration/pkg/hub/importer"
+)
+
+const testNamespace = "open-cluster-management-hub"
+
+func newGeneration(issued time.Time, complete bool) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   testNamespace,
+			Name:        fmt.Sprintf("bootstrap-token-%d", issued.Unix()),
+			Labels:      map[string]string{GenerationLabel: ""},
+			Annotations: map[string]string{issuedAtAnnotation: issued.UTC().Format(time.RFC3339)},
+		},
+	}
+	if complete {
+		secret.Data = map[string][]byte{importer.BootstrapKubeconfigKey: []byte(secret.Name)}
+	}
+	return secret
+}
+
+func TestSync(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	rootCA := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: rootCAConfigMapName},
+		Data:       map[string]string{"ca.crt": "root-ca"},
+	}
+
+	cases := []struct {
+		name                string
+		generations         []*corev1.Secret
+		expectedGenerations []string
+		expectedPublished   string
+		expectedPending     bool
+	}{
+		{
+			name:                "issue the first generation",
+			expectedGenerations: []string{newGeneration(now, true).Name},
+		},
+		{
+			name:                "latest generation is valid",
+			generations:         []*corev1.Secret{newGeneration(now.Add(-30*time.Minute), true)},
+			expectedGenerations: []string{newGeneration(now.Add(-30*time.Minute), true).Name},
+			expectedPublished:   newGeneration(now.Add(-30*time.Minute), true).Name,
+		},
+		{
+			name: "rotate and revoke the oldest generation",
+			generations: []*corev1.Secret{
+				newGeneration(now.Add(-2*time.Hour), true),
+				newGeneration(now.Add(-time.Hour), true),
+			},
+			expectedGenerations: []string{newGeneration(now.Add(-time.Hour), true).Name, newGeneration(now, true).Name},
+		},
+		{
+			name: "prune an incomplete generation",
+			generations: []*corev1.Secret{
+				newGeneration(now.Add(-10*time.Minute), true),
+				newGeneration(now.Add(-5*time.Minute), false),
+			},
+			expectedGenerations: []string{newGeneration(now.Add(-10*time.Minute), true).Name},
+			expectedPublished:   newGeneration(now.Add(-10*time.Minute), true).Name,
+		},
+		{
+			name:                "generation is being issued",
+			generations:         []*corev1.Secret{newGeneration(now.Add(-10*time.Second), false)},
+			expectedGenerations: []string{newGeneration(now.Add(-10*time.Second), false).Name},
+			expectedPending:     true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objects := []runtime.Object{rootCA}
+			for _, generation := range c.generations {
+				objects = append(objects, generation)
+			}
+			kubeClient := kubefake.NewSimpleClientset(objects...)
+			kubeClient.PrependReactor("create", "serviceaccounts",
+				func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+					if action.GetSubresource() != "token" {
+						return false, nil, nil
+					}
+					tokenRequest := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenRequest)
+					if ref := tokenRequest.Spec.BoundObjectRef; ref == nil || ref.Kind != "Secret" {
+						t.Errorf("expected the token to be bound to a secret, but got %v", ref)
+					}
+					return true, &authenticationv1.TokenRequest{
+						Status: authenticationv1.TokenRequestStatus{Token: "new-token"},
+					}, nil
+				})
+
+			informerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, time.Minute*10)
+			for _, obj := range objects {
+				switch obj.(type) {
+				case *corev1.Secret:
+					informerFactory.Core().V1().Secrets().Informer().GetStore().Add(obj)
+				case *corev1.ConfigMap:
+					informerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(obj)
+				}
+			}
+
+			ctrl := &bootstrapTokenController{
+				kubeClient:       kubeClient,
+				secretLister:     informerFactory.Core().V1().Secrets().Lister(),
+				configMapLister:  informerFactory.Core().V1().ConfigMaps().Lister(),
+				namespace:        testNamespace,
+				hubServer:        "https://hub.example.com:6443",
+				rotationInterval: time.Hour,
+				generations:      DefaultGenerations,
+				now:              func() time.Time { return now },
+			}
+			syncCtx := testinghelpers.NewFakeSyncContext(t, "")
+			if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			secrets, err := kubeClient.CoreV1().Secrets(testNamespace).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			generations := sets.NewString()
+			var published *corev1.Secret
+			for i := range secrets.Items {
+				if _, ok := secrets.Items[i].Labels[GenerationLabel]; ok {
+					generations.Insert(secrets.Items[i].Name)
+				}
+				if secrets.Items[i].Name == BootstrapKubeconfigSecretName {
+					published = &secrets.Items[i]
+				}
+			}
+			if !generations.Equal(sets.NewString(c.expectedGenerations...)) {
+				t.Errorf("expected generations %v, but got %v", c.expectedGenerations, generations.List())
+			}
+
+			if c.expectedPending {
+				if published != nil {
+					t.Errorf("expected no kubeconfig is published while a generation is being issued")
+				}
+			} else {
+				if published == nil {
+					t.Fatalf("expected the kubeconfig is published")
+				}
+				kubeconfig := published.Data[importer.BootstrapKubeconfigKey]
+				if len(c.expectedPublished) > 0 {
+					if string(kubeconfig) != c.expectedPublished {
+						t.Errorf("expected the kubeconfig of %q is published, but got %q", c.expectedPublished, kubeconfig)
+					}
+				} else {
+					config, err := clientcmd.Load(kubeconfig)
+					if err != nil {
+						t.Fatalf("invalid kubeconfig: %v", err)
+					}
+					expectedAuthInfo := map[string]string{"bootstrap": "new-token"}
+					authInfos := map[string]string{}
+					for name, authInfo := range config.AuthInfos {
+						authInfos[name] = authInfo.Token
+					}
+					if !reflect.DeepEqual(authInfos, expectedAuthInfo) || string(config.Clusters["hub"].CertificateAuthorityData) != "root-ca" {
+						t.Errorf("unexpected kubeconfig %s", kubeconfig)
+					}
+				}
+			}
+
+			// the controller is requeued once the latest generation should be rotated
+			if syncCtx.Queue().Len() > 0 {
+				t.Errorf("expected the controller not to be requeued immediately")
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+// package bootstraptoken contains the hub-side controller which rotates the shared bootstrap token used to import
+// the managed clusters. Each generation of the token is bound to a secret in the namespace of the hub controller,
+// so the token is invalidated once its secret is deleted. The controller issues a new generation on schedule,
+// keeps a number of the latest generations valid for the clusters being imported, and publishes the bootstrap
+// kubeconfig of the latest generation, which the import tooling renders the import artifacts with.
+package bootstraptoken
@@ -0,0 +1,5 @@
+// package certmanager contains the hub-side controller which signs the client certificates of the registration
+// agents with a cert-manager issuer, for the installations which centralize their PKI in cert-manager. The agents
+// request their client certificates with the signer open-cluster-management.io/cert-manager, and the approved csrs
+// are converted into cert-manager CertificateRequests.
+package certmanager
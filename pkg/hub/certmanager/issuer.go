@@ -0,0 +1,51 @@
+package certmanager
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The group and the kinds of the issuers of cert-manager
+const (
+	DefaultIssuerGroup = "cert-manager.io"
+	IssuerKind         = "Issuer"
+	ClusterIssuerKind  = "ClusterIssuer"
+)
+
+// IssuerRef refers to the cert-manager issuer which signs the client certificates of the registration agents. An
+// Issuer is looked up in the namespace of the hub controller, where the CertificateRequests are created.
+type IssuerRef struct {
+	Group string
+	Kind  string
+	Name  string
+}
+
+// ParseIssuerRef parses an issuer reference in the format of <kind>[.<group>]/<name>, e.g. ClusterIssuer/ocm-ca or
+// Issuer.example.com/ocm-ca for an external issuer. The group is cert-manager.io if it is omitted, and the kind of
+// the issuers in the group cert-manager.io is either Issuer or ClusterIssuer.
+func ParseIssuerRef(ref string) (IssuerRef, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return IssuerRef{}, fmt.Errorf("%q is not in the format of <kind>[.<group>]/<name>", ref)
+	}
+	name := parts[1]
+
+	kindGroup := strings.SplitN(parts[0], ".", 2)
+	kind, group := kindGroup[0], DefaultIssuerGroup
+	if len(kindGroup) == 2 {
+		group = kindGroup[1]
+	}
+	if len(kind) == 0 || len(group) == 0 {
+		return IssuerRef{}, fmt.Errorf("%q is not in the format of <kind>[.<group>]/<name>", ref)
+	}
+	if group == DefaultIssuerGroup && kind != IssuerKind && kind != ClusterIssuerKind {
+		return IssuerRef{}, fmt.Errorf("kind %q is not supported, it must be %s or %s", kind, IssuerKind, ClusterIssuerKind)
+	}
+
+	return IssuerRef{Group: group, Kind: kind, Name: name}, nil
+}
+
+// String returns the issuer reference in the format of <kind>.<group>/<name>
+func (r IssuerRef) String() string {
+	return fmt.Sprintf("%s.%s/%s", r.Kind, r.Group, r.Name)
+}
@@ -0,0 +1,68 @@
+package certmanager
+
+import (
+	"testing"
+)
+
+func TestParseIssuerRef(t *testing.T) {
+	cases := []struct {
+		name          string
+		ref           string
+		expectedRef   IssuerRef
+		expectedError bool
+	}{
+		{
+			name:        "cluster issuer",
+			ref:         "ClusterIssuer/ocm-ca",
+			expectedRef: IssuerRef{Group: DefaultIssuerGroup, Kind: ClusterIssuerKind, Name: "ocm-ca"},
+		},
+		{
+			name:        "issuer with the group",
+			ref:         "Issuer.cert-manager.io/ocm-ca",
+			expectedRef: IssuerRef{Group: DefaultIssuerGroup, Kind: IssuerKind, Name: "ocm-ca"},
+		},
+		{
+			name:        "external issuer",
+			ref:         "StepClusterIssuer.certmanager.step.sm/ocm-ca",
+			expectedRef: IssuerRef{Group: "certmanager.step.sm", Kind: "StepClusterIssuer", Name: "ocm-ca"},
+		},
+		{
+			name:          "no kind",
+			ref:           "ocm-ca",
+			expectedError: true,
+		},
+		{
+			name:          "empty name",
+			ref:           "ClusterIssuer/",
+			expectedError: true,
+		},
+		{
+			name:          "empty group",
+			ref:           "ClusterIssuer./ocm-ca",
+			expectedError: true,
+		},
+		{
+			name:          "unsupported kind",
+			ref:           "Certificate/ocm-ca",
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ref, err := ParseIssuerRef(c.ref)
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected an error, but got %v", ref)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ref != c.expectedRef {
+				t.Errorf("expected %v, but got %v", c.expectedRef, ref)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package certmanager
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	certificatesinformers "k8s.io/client-go/informers/certificates/v1"
+	"k8s.io/client-go/kubernetes"
+	certificateslisters "k8s.io/client-go/listers/certificates/v1"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const controllerName = "CertManagerSignerController"
+
+// CertificateRequestGVR is the resource of the cert-manager CertificateRequests
+var CertificateRequestGVR = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificaterequests"}
+
+// PollInterval is the interval the pending CertificateRequests are checked in. The CertificateRequests are not
+// watched, so the hub does not depend on the CRDs of cert-manager unless the controller is enabled. It is exposed
+// so that integration tests can shorten it.
+var PollInterval = 5 * time.Second
+
+// allowedUsages are the key usages of the client certificates of the registration agents
+var allowedUsages = sets.NewString(
+	string(certificatesv1.UsageDigitalSignature),
+	string(certificatesv1.UsageKeyEncipherment),
+	string(certificatesv1.UsageClientAuth),
+)
+
+// certManagerSignerController signs the approved csrs of the cert-manager signer with a cert-manager issuer. A
+// CertificateRequest named after the csr is created in the namespace of the hub controller, and the issued
+// certificate is copied to the csr once the CertificateRequest is ready. The csr fails if the CertificateRequest is
+// denied or fails. The csrs are approved as the csrs of the kube-apiserver-client signer, by the cluster admin when a
+// cluster joins and by the csr approving controller when a certificate is renewed.
+//
+// The CertificateRequests are approved by the approvers of cert-manager, e.g. approver-policy, unless approve is
+// true. The controller approves them itself then, which requires the approve permission on the signer of the
+// issuer, e.g. clusterissuers.cert-manager.io/<name>.
+type certManagerSignerController struct {
+	kubeClient    kubernetes.Interface
+	dynamicClient dynamic.Interface
+	csrLister     certificateslisters.CertificateSigningRequestLister
+	issuer        IssuerRef
+	namespace     string
+	approve       bool
+	eventRecorder events.Recorder
+}
+
+// NewCertManagerSignerController returns an instance of certManagerSignerController
+func NewCertManagerSignerController(
+	kubeClient kubernetes.Interface,
+	dynamicClient dynamic.Interface,
+	csrInformer certificatesinformers.CertificateSigningRequestInformer,
+	issuer IssuerRef,
+	namespace string,
+	approve bool,
+	recorder events.Recorder) factory.Controller {
+	c := &certManagerSignerController{
+		kubeClient:    kubeClient,
+		dynamicClient: dynamicClient,
+		csrLister:     csrInformer.Lister(),
+		issuer:        issuer,
+		namespace:     namespace,
+		approve:       approve,
+		eventRecorder: recorder.WithComponentSuffix("cert-manager-signer-controller"),
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, func(obj interface{}) bool {
+			csr, ok := obj.(*certificatesv1.CertificateSigningRequest)
+			return ok && csr.Spec.SignerName == helpers.CertManagerSignerName
+		}, csrInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ToController(controllerName, recorder)
+}
+
+func (c *certManagerSignerController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	csrName := syncCtx.QueueKey()
+	logger := helpers.ControllerLogger(ctx, controllerName)
+	logger.V(helpers.LogLevelDebug).Info("Reconciling CertificateSigningRequest", helpers.LogKeyResource, klog.KRef("", csrName))
+
+	csr, err := c.csrLister.Get(csrName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if csr.Spec.SignerName != helpers.CertManagerSignerName || len(csr.Status.Certificate) > 0 {
+		return nil
+	}
+
+	approved := false
+	for _, condition := range csr.Status.Conditions {
+		switch condition.Type {
+		case certificatesv1.CertificateDenied, certificatesv1.CertificateFailed:
+			return nil
+		case certificatesv1.CertificateApproved:
+			approved = true
+		}
+	}
+	// the csr is signed once it is approved
+	if !approved {
+		return nil
+	}
+
+	for _, usage := range csr.Spec.Usages {
+		if !allowedUsages.Has(string(usage)) {
+			return c.fail(ctx, csr, "UnsupportedUsage", fmt.Sprintf("usage %q is not allowed", usage))
+		}
+	}
+
+	certificateRequests := c.dynamicClient.Resource(CertificateRequestGVR).Namespace(c.namespace)
+	certificateRequest, err := certificateRequests.Get(ctx, csr.Name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		certificateRequest, err = certificateRequests.Create(ctx, c.newCertificateRequest(csr), metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		c.eventRecorder.Eventf("CertificateRequestCreated", "CertificateRequest %q is created with issuer %q for csr %q",
+			c.namespace+"/"+csr.Name, c.issuer.String(), csr.Name)
+	case err != nil:
+		return err
+	}
+
+	status := getCertificateRequestStatus(certificateRequest)
+	switch {
+	case len(status.failure) > 0:
+		return c.fail(ctx, csr, "CertificateRequestFailed", status.failure)
+	case len(status.certificate) > 0:
+		csr = csr.DeepCopy()
+		csr.Status.Certificate = status.certificate
+		if _, err := c.kubeClient.CertificatesV1().CertificateSigningRequests().UpdateStatus(ctx, csr, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		c.eventRecorder.Eventf("ClusterClientCertSigned", "csr %q is signed with issuer %q", csr.Name, c.issuer.String())
+		return nil
+	case !status.approved && c.approve:
+		if err := c.approveCertificateRequest(ctx, certificateRequest); err != nil {
+			return err
+		}
+	}
+
+	logger.V(helpers.LogLevelDebug).Info("Waiting for CertificateRequest to be ready",
+		helpers.LogKeyResource, klog.KRef(c.namespace, csr.Name))
+	syncCtx.Queue().AddAfter(csrName, PollInterval)
+	return nil
+}
+
+// newCertificateRequest returns the CertificateRequest of the csr, which is owned by the csr and is removed with it
+func (c *certManagerSignerController) newCertificateRequest(csr *certificatesv1.CertificateSigningRequest) *unstructured.Unstructured {
+	usages := []interface{}{}
+	for _, usage := range csr.Spec.Usages {
+		usages = append(usages, string(usage))
+	}
+	spec := map[string]interface{}{
+		"request": base64.StdEncoding.EncodeToString(csr.Spec.Request),
+		"issuerRef": map[string]interface{}{
+			"group": c.issuer.Group,
+			"kind":  c.issuer.Kind,
+			"name":  c.issuer.Name,
+		},
+		"usages": usages,
+	}
+	if csr.Spec.ExpirationSeconds != nil {
+		spec["duration"] = (time.Duration(*csr.Spec.ExpirationSeconds) * time.Second).String()
+	}
+
+	certificateRequest := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	certificateRequest.SetAPIVersion(CertificateRequestGVR.GroupVersion().String())
+	certificateRequest.SetKind("CertificateRequest")
+	certificateRequest.SetNamespace(c.namespace)
+	certificateRequest.SetName(csr.Name)
+	if clusterName, ok := csr.Labels[clientcert.ClusterNameLabel]; ok {
+		certificateRequest.SetLabels(map[string]string{clientcert.ClusterNameLabel: clusterName})
+	}
+	certificateRequest.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(csr, certificatesv1.SchemeGroupVersion.WithKind("CertificateSigningRequest")),
+	})
+	return certificateRequest
+}
+
+// approveCertificateRequest approves the CertificateRequest, the csr it is created for is approved already
+func (c *certManagerSignerController) approveCertificateRequest(ctx context.Context, certificateRequest *unstructured.Unstructured) error {
+	certificateRequest = certificateRequest.DeepCopy()
+	conditions, _, err := unstructured.NestedSlice(certificateRequest.Object, "status", "conditions")
+	if err != nil {
+		return err
+	}
+	conditions = append(conditions, map[string]interface{}{
+		"type":               "Approved",
+		"status":             string(metav1.ConditionTrue),
+		"reason":             "ApprovedByRegistrationController",
+		"message":            "The CertificateSigningRequest of the managed cluster is approved.",
+		"lastTransitionTime": metav1.Now().UTC().Format(time.RFC3339),
+	})
+	if err := unstructured.SetNestedSlice(certificateRequest.Object, conditions, "status", "conditions"); err != nil {
+		return err
+	}
+
+	_, err = c.dynamicClient.Resource(CertificateRequestGVR).Namespace(c.namespace).
+		UpdateStatus(ctx, certificateRequest, metav1.UpdateOptions{})
+	return err
+}
+
+// fail adds the failed condition to the csr, so the agent creates another csr
+func (c *certManagerSignerController) fail(ctx context.Context, csr *certificatesv1.CertificateSigningRequest, reason, message string) error {
+	csr = csr.DeepCopy()
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:           certificatesv1.CertificateFailed,
+		Status:         corev1.ConditionTrue,
+		Reason:         reason,
+		Message:        message,
+		LastUpdateTime: metav1.Now(),
+	})
+	if _, err := c.kubeClient.CertificatesV1().CertificateSigningRequests().UpdateStatus(ctx, csr, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	c.eventRecorder.Warningf("ClusterClientCertFailed", "csr %q is failed to be signed with issuer %q: %s",
+		csr.Name, c.issuer.String(), message)
+	return nil
+}
+
+// certificateRequestStatus is the status of a CertificateRequest
+type certificateRequestStatus struct {
+	approved bool
+	// failure is the message of the condition with which the CertificateRequest is denied or fails
+	failure string
+	// certificate is the issued certificate once the CertificateRequest is ready
+	certificate []byte
+}
+
+// getCertificateRequestStatus reads the status of a CertificateRequest from its conditions. A CertificateRequest
+// is finished once it is ready, or its Ready condition is false with the reason Failed or Denied. It is invalid if
+// its InvalidRequest condition is true.
+func getCertificateRequestStatus(certificateRequest *unstructured.Unstructured) certificateRequestStatus {
+	status := certificateRequestStatus{}
+	conditions, _, _ := unstructured.NestedSlice(certificateRequest.Object, "status", "conditions")
+	ready := false
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		conditionType, _, _ := unstructured.NestedString(condition, "type")
+		conditionStatus, _, _ := unstructured.NestedString(condition, "status")
+		reason, _, _ := unstructured.NestedString(condition, "reason")
+		message, _, _ := unstructured.NestedString(condition, "message")
+
+		switch {
+		case conditionType == "Approved" && conditionStatus == string(metav1.ConditionTrue):
+			status.approved = true
+		case conditionType == "Denied" && conditionStatus == string(metav1.ConditionTrue),
+			conditionType == "InvalidRequest" && conditionStatus == string(metav1.ConditionTrue):
+			status.failure = fmt.Sprintf("%s: %s", reason, message)
+		case conditionType == "Ready" && conditionStatus == string(metav1.ConditionTrue):
+			ready = true
+		case conditionType == "Ready" && (reason == "Failed" || reason == "Denied"):
+			status.failure = fmt.Sprintf("%s: %s", reason, message)
+		}
+	}
+	if len(status.failure) > 0 || !ready {
+		return status
+	}
+
+	certificate, _, _ := unstructured.NestedString(certificateRequest.Object, "status", "certificate")
+	// the certificate is base64 encoded in the json of the CertificateRequest
+	status.certificate, _ = base64.StdEncoding.DecodeString(certificate)
+	return status
+}
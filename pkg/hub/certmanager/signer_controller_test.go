@@ -0,0 +1,257 @@
+package certmanager
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/hub/user"
+)
+
+const testNamespace = "open-cluster-management-hub"
+
+var (
+	testIssuer = IssuerRef{Group: DefaultIssuerGroup, Kind: ClusterIssuerKind, Name: "ocm-ca"}
+
+	clusterCSR = testinghelpers.CSRHolder{
+		Name:         "managedcluster1-abcde",
+		Labels:       map[string]string{"open-cluster-management.io/cluster-name": "managedcluster1"},
+		SignerName:   helpers.CertManagerSignerName,
+		CN:           user.SubjectPrefix + "managedcluster1:spokeagent1",
+		Orgs:         []string{user.SubjectPrefix + "managedcluster1", user.ManagedClustersGroup},
+		Username:     user.SubjectPrefix + "managedcluster1:spokeagent1",
+		ReqBlockType: "CERTIFICATE REQUEST",
+	}
+)
+
+func newClusterCSR(approved bool, usages ...certificatesv1.KeyUsage) *certificatesv1.CertificateSigningRequest {
+	csr := testinghelpers.NewCSR(clusterCSR)
+	if approved {
+		csr = testinghelpers.NewApprovedCSR(clusterCSR)
+	}
+	if len(usages) == 0 {
+		usages = []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageKeyEncipherment,
+			certificatesv1.UsageClientAuth}
+	}
+	csr.Spec.Usages = usages
+	expirationSeconds := int32(3600)
+	csr.Spec.ExpirationSeconds = &expirationSeconds
+	return csr
+}
+
+func newCertificateRequest(certificate []byte, conditions ...map[string]interface{}) *unstructured.Unstructured {
+	certificateRequest := testinghelpers.NewUnstructuredObj("cert-manager.io/v1", "CertificateRequest",
+		testNamespace, clusterCSR.Name)
+	status := map[string]interface{}{}
+	if len(conditions) > 0 {
+		items := []interface{}{}
+		for _, condition := range conditions {
+			items = append(items, condition)
+		}
+		status["conditions"] = items
+	}
+	if len(certificate) > 0 {
+		status["certificate"] = base64.StdEncoding.EncodeToString(certificate)
+	}
+	certificateRequest.Object["status"] = status
+	return certificateRequest
+}
+
+func newCondition(conditionType, status, reason string) map[string]interface{} {
+	return map[string]interface{}{"type": conditionType, "status": status, "reason": reason, "message": "test"}
+}
+
+func TestSignerSync(t *testing.T) {
+	cert := testinghelpers.NewTestCert("system:open-cluster-management:managedcluster1:spokeagent1", time.Hour)
+
+	cases := []struct {
+		name                   string
+		csrs                   []runtime.Object
+		certificateRequests    []runtime.Object
+		approve                bool
+		expectedRequeue        bool
+		validateActions        func(t *testing.T, actions []clienttesting.Action)
+		validateDynamicActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name: "sync a deleted csr",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name: "sync a pending csr",
+			csrs: []runtime.Object{newClusterCSR(false)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name: "fail a csr with an unsupported usage",
+			csrs: []runtime.Object{newClusterCSR(true, certificatesv1.UsageServerAuth)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				csr := actions[0].(clienttesting.UpdateActionImpl).Object.(*certificatesv1.CertificateSigningRequest)
+				assertFailed(t, csr, "UnsupportedUsage")
+			},
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:            "create a certificate request",
+			csrs:            []runtime.Object{newClusterCSR(true)},
+			expectedRequeue: true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				// the certificate request is left to the approvers of cert-manager
+				testinghelpers.AssertActions(t, actions, "get", "create")
+			},
+		},
+		{
+			name:            "create and approve a certificate request",
+			csrs:            []runtime.Object{newClusterCSR(true)},
+			approve:         true,
+			expectedRequeue: true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create", "update")
+				certificateRequest := actions[1].(clienttesting.CreateActionImpl).Object.(*unstructured.Unstructured)
+				if certificateRequest.GetNamespace() != testNamespace || certificateRequest.GetName() != clusterCSR.Name {
+					t.Errorf("unexpected certificate request %s/%s", certificateRequest.GetNamespace(), certificateRequest.GetName())
+				}
+				if issuer, _, _ := unstructured.NestedString(certificateRequest.Object, "spec", "issuerRef", "name"); issuer != "ocm-ca" {
+					t.Errorf("expected issuer ocm-ca, but got %q", issuer)
+				}
+				if duration, _, _ := unstructured.NestedString(certificateRequest.Object, "spec", "duration"); duration != "1h0m0s" {
+					t.Errorf("expected duration 1h0m0s, but got %q", duration)
+				}
+				if len(certificateRequest.GetOwnerReferences()) != 1 {
+					t.Errorf("expected the certificate request to be owned by the csr")
+				}
+				if actions[2].GetSubresource() != "status" {
+					t.Errorf("expected status to be updated, but got %q", actions[2].GetSubresource())
+				}
+				approved := actions[2].(clienttesting.UpdateActionImpl).Object.(*unstructured.Unstructured)
+				if !getCertificateRequestStatus(approved).approved {
+					t.Errorf("expected the certificate request to be approved")
+				}
+			},
+		},
+		{
+			name:                "wait for a pending certificate request",
+			csrs:                []runtime.Object{newClusterCSR(true)},
+			certificateRequests: []runtime.Object{newCertificateRequest(nil, newCondition("Approved", "True", "cert-manager.io"))},
+			expectedRequeue:     true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name: "copy the issued certificate",
+			csrs: []runtime.Object{newClusterCSR(true)},
+			certificateRequests: []runtime.Object{newCertificateRequest(cert.Cert,
+				newCondition("Approved", "True", "cert-manager.io"), newCondition("Ready", "True", "Issued"))},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				csr := actions[0].(clienttesting.UpdateActionImpl).Object.(*certificatesv1.CertificateSigningRequest)
+				if string(csr.Status.Certificate) != string(cert.Cert) {
+					t.Errorf("expected the issued certificate to be copied to the csr")
+				}
+			},
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name: "fail a csr whose certificate request is denied",
+			csrs: []runtime.Object{newClusterCSR(true)},
+			certificateRequests: []runtime.Object{newCertificateRequest(nil,
+				newCondition("Denied", "True", "policy"), newCondition("Ready", "False", "Denied"))},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				csr := actions[0].(clienttesting.UpdateActionImpl).Object.(*certificatesv1.CertificateSigningRequest)
+				assertFailed(t, csr, "CertificateRequestFailed")
+			},
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(c.csrs...)
+			dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{CertificateRequestGVR: "CertificateRequestList"}, c.certificateRequests...)
+
+			kubeInformers := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+			csrStore := kubeInformers.Certificates().V1().CertificateSigningRequests().Informer().GetStore()
+			for _, csr := range c.csrs {
+				if err := csrStore.Add(csr); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := &certManagerSignerController{
+				kubeClient:    kubeClient,
+				dynamicClient: dynamicClient,
+				csrLister:     kubeInformers.Certificates().V1().CertificateSigningRequests().Lister(),
+				issuer:        testIssuer,
+				namespace:     testNamespace,
+				approve:       c.approve,
+				eventRecorder: eventstesting.NewTestingEventRecorder(t),
+			}
+
+			defer func(interval time.Duration) { PollInterval = interval }(PollInterval)
+			PollInterval = 0
+			syncCtx := testinghelpers.NewFakeSyncContext(t, clusterCSR.Name)
+			if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+
+			if requeued := syncCtx.Queue().Len() > 0; requeued != c.expectedRequeue {
+				t.Errorf("expected requeue %t, but got %t", c.expectedRequeue, requeued)
+			}
+			c.validateActions(t, kubeClient.Actions())
+			c.validateDynamicActions(t, dynamicClient.Actions())
+		})
+	}
+}
+
+func assertFailed(t *testing.T, csr *certificatesv1.CertificateSigningRequest, reason string) {
+	for _, condition := range csr.Status.Conditions {
+		if condition.Type == certificatesv1.CertificateFailed && condition.Reason == reason {
+			return
+		}
+	}
+	t.Errorf("expected the csr to fail with reason %q, but got %v", reason, csr.Status.Conditions)
+}
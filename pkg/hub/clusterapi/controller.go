@@ -0,0 +1,201 @@
+package clusterapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const controllerName = "ClusterAPIImportController"
+
+// ClusterGVR is the resource of the Clusters of Cluster API
+var ClusterGVR = schema.GroupVersionResource{Group: "cluster.x-k8s.io", Version: "v1beta1", Resource: "clusters"}
+
+const (
+	// ImportedFromAnnotation is the annotation of a ManagedCluster imported from a Cluster of Cluster API, its value
+	// is the <namespace>/<name> of the Cluster.
+	ImportedFromAnnotation = "cluster.open-cluster-management.io/imported-from-capi-cluster"
+
+	// PhaseLabel is the label of a ManagedCluster imported from a Cluster of Cluster API, its value is the phase of
+	// the Cluster, e.g. Provisioning, Provisioned or Deleting.
+	PhaseLabel = "cluster.open-cluster-management.io/capi-cluster-phase"
+
+	// BootstrapDeliveredAnnotation is the annotation of a ManagedCluster imported from a Cluster of Cluster API
+	// whose bootstrap material is delivered, the bootstrap material is not delivered again once it is set.
+	BootstrapDeliveredAnnotation = "cluster.open-cluster-management.io/capi-bootstrap-delivered"
+
+	// phaseProvisioned is the phase of a Cluster whose control plane is reachable
+	phaseProvisioned = "Provisioned"
+)
+
+// PollInterval is the interval the Clusters of Cluster API are read in. The Clusters are not watched, so the hub
+// does not depend on the CRD of Cluster API unless the controller is enabled. It is exposed so that integration
+// tests can shorten it.
+var PollInterval = time.Minute
+
+// clusterAPIImportController creates an accepted ManagedCluster named after each Cluster of Cluster API, and keeps
+// the phase of the Cluster in PhaseLabel of the ManagedCluster. The bootstrap material of the registration agent is
+// delivered with the delivery once the Cluster is provisioned, and the ManagedCluster is deleted once the Cluster is
+// deleted. The ManagedClusters which are not imported from the Clusters are never updated or deleted, even if they
+// are named after a Cluster.
+type clusterAPIImportController struct {
+	clusterClient clientset.Interface
+	dynamicClient dynamic.Interface
+	clusterLister listerv1.ManagedClusterLister
+	delivery      BootstrapDelivery
+	eventRecorder events.Recorder
+}
+
+// NewClusterAPIImportController returns an instance of clusterAPIImportController. The bootstrap material of the
+// registration agent is not delivered if delivery is nil, the agent should be deployed on the provisioned clusters
+// in another way then.
+func NewClusterAPIImportController(
+	clusterClient clientset.Interface,
+	dynamicClient dynamic.Interface,
+	clusterInformer informerv1.ManagedClusterInformer,
+	delivery BootstrapDelivery,
+	recorder events.Recorder) factory.Controller {
+	c := &clusterAPIImportController{
+		clusterClient: clusterClient,
+		dynamicClient: dynamicClient,
+		clusterLister: clusterInformer.Lister(),
+		delivery:      delivery,
+		eventRecorder: recorder.WithComponentSuffix("cluster-api-import-controller"),
+	}
+
+	return factory.New().
+		WithInformers(clusterInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ResyncEvery(PollInterval).
+		ToController(controllerName, recorder)
+}
+
+func (c *clusterAPIImportController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	helpers.ControllerLogger(ctx, controllerName).V(helpers.LogLevelDebug).Info("Reconciling Clusters of Cluster API")
+
+	capiClusterList, err := c.dynamicClient.Resource(ClusterGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	capiClusters := map[string]*unstructured.Unstructured{}
+	for i := range capiClusterList.Items {
+		capiCluster := &capiClusterList.Items[i]
+		// the Clusters being deleted are not imported, and the ManagedClusters imported from them are deleted
+		if capiCluster.GetDeletionTimestamp() != nil {
+			continue
+		}
+		capiClusters[capiCluster.GetNamespace()+"/"+capiCluster.GetName()] = capiCluster
+	}
+
+	errs := []error{}
+	for _, key := range sets.StringKeySet(capiClusters).List() {
+		if err := c.importCluster(ctx, capiClusters[key]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	clusters, err := c.clusterLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, cluster := range clusters {
+		importedFrom, ok := cluster.Annotations[ImportedFromAnnotation]
+		if !ok {
+			continue
+		}
+		if _, ok := capiClusters[importedFrom]; ok {
+			continue
+		}
+		err := c.clusterClient.ClusterV1().ManagedClusters().Delete(ctx, cluster.Name, metav1.DeleteOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c.eventRecorder.Eventf("ClusterAPIClusterDeleted", "managed cluster %q of the deleted Cluster %q is deleted",
+			cluster.Name, importedFrom)
+	}
+	return operatorhelpers.NewMultiLineAggregate(errs)
+}
+
+// importCluster creates or updates the ManagedCluster of the Cluster, and delivers the bootstrap material once the
+// Cluster is provisioned
+func (c *clusterAPIImportController) importCluster(ctx context.Context, capiCluster *unstructured.Unstructured) error {
+	logger := helpers.ControllerLogger(ctx, controllerName)
+	importedFrom := capiCluster.GetNamespace() + "/" + capiCluster.GetName()
+	phase, _, _ := unstructured.NestedString(capiCluster.Object, "status", "phase")
+
+	cluster, err := c.clusterLister.Get(capiCluster.GetName())
+	switch {
+	case errors.IsNotFound(err):
+		cluster = &clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        capiCluster.GetName(),
+				Labels:      map[string]string{PhaseLabel: phase},
+				Annotations: map[string]string{ImportedFromAnnotation: importedFrom},
+			},
+			Spec: clusterv1.ManagedClusterSpec{
+				// the Clusters are provisioned by the hub admin, so their ManagedClusters are accepted
+				HubAcceptsClient: true,
+			},
+		}
+		cluster, err = c.clusterClient.ClusterV1().ManagedClusters().Create(ctx, cluster, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		c.eventRecorder.Eventf("ClusterAPIClusterImported", "managed cluster %q is created for Cluster %q",
+			cluster.Name, importedFrom)
+		logger.Info("Imported Cluster of Cluster API",
+			helpers.LogKeyCluster, cluster.Name, helpers.LogKeyResource, klog.KObj(capiCluster))
+	case err != nil:
+		return err
+	}
+
+	if cluster.Annotations[ImportedFromAnnotation] != importedFrom {
+		logger.V(helpers.LogLevelDebug).Info("ManagedCluster is not imported from the Cluster of Cluster API",
+			helpers.LogKeyCluster, cluster.Name, helpers.LogKeyResource, klog.KObj(capiCluster))
+		return nil
+	}
+
+	updated := cluster.DeepCopy()
+	if updated.Labels == nil {
+		updated.Labels = map[string]string{}
+	}
+	updated.Labels[PhaseLabel] = phase
+
+	if phase == phaseProvisioned && c.delivery != nil && updated.Annotations[BootstrapDeliveredAnnotation] != "true" {
+		if err := c.delivery.Deliver(ctx, capiCluster, cluster); err != nil {
+			return err
+		}
+		updated.Annotations[BootstrapDeliveredAnnotation] = "true"
+		c.eventRecorder.Eventf("ClusterAPIBootstrapDelivered", "bootstrap material of managed cluster %q is delivered to Cluster %q",
+			cluster.Name, importedFrom)
+	}
+
+	if cluster.Labels[PhaseLabel] == phase &&
+		cluster.Annotations[BootstrapDeliveredAnnotation] == updated.Annotations[BootstrapDeliveredAnnotation] {
+		return nil
+	}
+	_, err = c.clusterClient.ClusterV1().ManagedClusters().Update(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,182 @@
+package clusterapi
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+const testNamespace = "capi-clusters"
+
+func newCAPICluster(phase string, deleting bool) *unstructured.Unstructured {
+	capiCluster := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{"phase": phase},
+	}}
+	capiCluster.SetAPIVersion(ClusterGVR.GroupVersion().String())
+	capiCluster.SetKind("Cluster")
+	capiCluster.SetNamespace(testNamespace)
+	capiCluster.SetName(testinghelpers.TestManagedClusterName)
+	if deleting {
+		now := metav1.Now()
+		capiCluster.SetDeletionTimestamp(&now)
+	}
+	return capiCluster
+}
+
+func newImportedCluster(phase string, delivered bool) *clusterv1.ManagedCluster {
+	cluster := testinghelpers.NewAcceptedManagedCluster()
+	cluster.Labels = map[string]string{PhaseLabel: phase}
+	cluster.Annotations = map[string]string{ImportedFromAnnotation: testNamespace + "/" + testinghelpers.TestManagedClusterName}
+	if delivered {
+		cluster.Annotations[BootstrapDeliveredAnnotation] = "true"
+	}
+	return cluster
+}
+
+func TestSync(t *testing.T) {
+	cases := []struct {
+		name            string
+		capiClusters    []runtime.Object
+		cluster         *clusterv1.ManagedCluster
+		deliveryErr     error
+		expectedErr     string
+		expectDelivered bool
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:         "import Cluster",
+			capiClusters: []runtime.Object{newCAPICluster("Provisioning", false)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "create")
+				cluster := actions[0].(clienttesting.CreateActionImpl).Object.(*clusterv1.ManagedCluster)
+				if !cluster.Spec.HubAcceptsClient || cluster.Labels[PhaseLabel] != "Provisioning" ||
+					cluster.Annotations[ImportedFromAnnotation] != testNamespace+"/"+testinghelpers.TestManagedClusterName {
+					t.Errorf("expected the Cluster to be imported, but got %v", cluster)
+				}
+			},
+		},
+		{
+			name:         "phase changed",
+			capiClusters: []runtime.Object{newCAPICluster("Provisioning", false)},
+			cluster:      newImportedCluster("Pending", false),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				cluster := actions[0].(clienttesting.UpdateActionImpl).Object.(*clusterv1.ManagedCluster)
+				if cluster.Labels[PhaseLabel] != "Provisioning" {
+					t.Errorf("expected the phase label to be updated, but got %v", cluster.Labels)
+				}
+			},
+		},
+		{
+			name:            "deliver bootstrap material",
+			capiClusters:    []runtime.Object{newCAPICluster("Provisioned", false)},
+			cluster:         newImportedCluster("Provisioning", false),
+			expectDelivered: true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				cluster := actions[0].(clienttesting.UpdateActionImpl).Object.(*clusterv1.ManagedCluster)
+				if cluster.Labels[PhaseLabel] != "Provisioned" || cluster.Annotations[BootstrapDeliveredAnnotation] != "true" {
+					t.Errorf("expected the bootstrap material to be delivered, but got %v", cluster)
+				}
+			},
+		},
+		{
+			name:            "bootstrap material delivered",
+			capiClusters:    []runtime.Object{newCAPICluster("Provisioned", false)},
+			cluster:         newImportedCluster("Provisioned", true),
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "delivery failed",
+			capiClusters:    []runtime.Object{newCAPICluster("Provisioned", false)},
+			cluster:         newImportedCluster("Provisioned", false),
+			deliveryErr:     fmt.Errorf("workload cluster unreachable"),
+			expectedErr:     "workload cluster unreachable",
+			expectDelivered: true,
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "cluster not imported",
+			capiClusters:    []runtime.Object{newCAPICluster("Provisioned", false)},
+			cluster:         testinghelpers.NewAcceptedManagedCluster(),
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:    "Cluster deleted",
+			cluster: newImportedCluster("Provisioned", true),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete")
+			},
+		},
+		{
+			name:         "Cluster deleting",
+			capiClusters: []runtime.Object{newCAPICluster("Deleting", true)},
+			cluster:      newImportedCluster("Provisioned", true),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete")
+			},
+		},
+		{
+			name:            "user cluster without Cluster",
+			cluster:         testinghelpers.NewAcceptedManagedCluster(),
+			validateActions: testinghelpers.AssertNoActions,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterObjects := []runtime.Object{}
+			if c.cluster != nil {
+				clusterObjects = append(clusterObjects, c.cluster)
+			}
+			clusterClient := clusterfake.NewSimpleClientset(clusterObjects...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			if c.cluster != nil {
+				if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(c.cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{ClusterGVR: "ClusterList"}, c.capiClusters...)
+
+			delivered := false
+			ctrl := &clusterAPIImportController{
+				clusterClient: clusterClient,
+				dynamicClient: dynamicClient,
+				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				delivery: BootstrapDeliveryFunc(func(ctx context.Context, capiCluster *unstructured.Unstructured, cluster *clusterv1.ManagedCluster) error {
+					delivered = true
+					return c.deliveryErr
+				}),
+				eventRecorder: eventstesting.NewTestingEventRecorder(t),
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey))
+			switch {
+			case len(c.expectedErr) == 0 && syncErr != nil:
+				t.Errorf("unexpected err: %v", syncErr)
+			case len(c.expectedErr) > 0 && (syncErr == nil || syncErr.Error() != c.expectedErr):
+				t.Errorf("expected err %q, but got %v", c.expectedErr, syncErr)
+			}
+			if delivered != c.expectDelivered {
+				t.Errorf("expected delivered %v, but got %v", c.expectDelivered, delivered)
+			}
+
+			c.validateActions(t, clusterClient.Actions())
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package clusterapi
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// BootstrapDelivery delivers the bootstrap material of the registration agent, e.g. the bootstrap kubeconfig and the
+// manifests of the agent, to a Cluster provisioned by Cluster API. The workload cluster is reachable with the
+// kubeconfig in the secret "<name>-kubeconfig" in the namespace of the Cluster. Deliver is called once the Cluster is
+// provisioned, and it is called again later if it returns an error, so it should be idempotent.
+type BootstrapDelivery interface {
+	Deliver(ctx context.Context, capiCluster *unstructured.Unstructured, cluster *clusterv1.ManagedCluster) error
+}
+
+// BootstrapDeliveryFunc is a function which implements BootstrapDelivery
+type BootstrapDeliveryFunc func(ctx context.Context, capiCluster *unstructured.Unstructured, cluster *clusterv1.ManagedCluster) error
+
+// Deliver calls f(ctx, capiCluster, cluster)
+func (f BootstrapDeliveryFunc) Deliver(ctx context.Context, capiCluster *unstructured.Unstructured, cluster *clusterv1.ManagedCluster) error {
+	return f(ctx, capiCluster, cluster)
+}
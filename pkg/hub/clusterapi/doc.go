@@ -0,0 +1,6 @@
+// package clusterapi contains the hub-side controller which imports the Clusters provisioned by Cluster API
+// (cluster.x-k8s.io) on the hub as managed clusters, so the clusters register themselves once they are provisioned.
+// An accepted ManagedCluster is created for each Cluster, the phase of the Cluster is kept in a label of the
+// ManagedCluster, and the bootstrap material of the registration agent is handed to a pluggable BootstrapDelivery
+// once the Cluster is provisioned.
+package clusterapi
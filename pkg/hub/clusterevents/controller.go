@@ -0,0 +1,271 @@
+package clusterevents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/cloudevents"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const (
+	controllerName = "ClusterEventsConsumerController"
+
+	// leaseName is the name of the lease of a managed cluster in the cluster namespace
+	leaseName = "managed-cluster-lease"
+)
+
+// hubConditionTypes are the conditions of the managed clusters owned by the hub, the agents are not allowed to
+// report them
+var hubConditionTypes = map[string]bool{
+	clusterv1.ManagedClusterConditionHubAccepted: true,
+	clusterv1.ManagedClusterConditionHubDenied:   true,
+	clusterv1.ManagedClusterConditionJoined:      true,
+}
+
+// receivedEvents are the latest events of a cluster which are not reconciled yet
+type receivedEvents struct {
+	status    *cloudevents.Event
+	heartbeat *cloudevents.Event
+}
+
+// consumerController consumes the events of the managed clusters from the broker. The latest events of each
+// accepted cluster are kept until they are reconciled, the older events of a cluster received meanwhile are
+// discarded. The events of the clusters which are not accepted are dropped once they are received, and the
+// events of a cluster are forgotten once the cluster is deleted, so the received events are bounded by the
+// accepted clusters.
+type consumerController struct {
+	kubeClient    kubernetes.Interface
+	clusterClient clientset.Interface
+	clusterLister listerv1.ManagedClusterLister
+	subscriber    cloudevents.Subscriber
+	now           func() time.Time
+
+	lock     sync.Mutex
+	received map[string]*receivedEvents
+}
+
+// NewConsumerController returns a controller which subscribes to the events of all of the clusters with the
+// subscriber, and reconciles the events of the accepted clusters. The events of the clusters which are not
+// accepted are dropped.
+func NewConsumerController(
+	kubeClient kubernetes.Interface,
+	clusterClient clientset.Interface,
+	clusterInformer informerv1.ManagedClusterInformer,
+	subscriber cloudevents.Subscriber,
+	recorder events.Recorder) factory.Controller {
+	c := &consumerController{
+		kubeClient:    kubeClient,
+		clusterClient: clusterClient,
+		clusterLister: clusterInformer.Lister(),
+		subscriber:    subscriber,
+		now:           time.Now,
+		received:      map[string]*receivedEvents{},
+	}
+	clusterInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: c.forget,
+	})
+
+	return factory.New().
+		WithBareInformers(clusterInformer.Informer()).
+		WithPostStartHooks(c.consume).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ToController(controllerName, recorder)
+}
+
+// consume subscribes to the events of the clusters until the context is done, and queues the clusters of the
+// received events
+func (c *consumerController) consume(ctx context.Context, syncCtx factory.SyncContext) error {
+	logger := helpers.ControllerLogger(ctx, controllerName)
+	c.subscriber.Subscribe(ctx, cloudevents.ClusterEventsTopicFilter, func(topic string, payload []byte) {
+		event, err := cloudevents.ParseEvent(topic, payload)
+		if err != nil {
+			logger.Error(err, "Dropped an invalid event", "topic", topic)
+			return
+		}
+		if c.receive(event) {
+			syncCtx.Queue().Add(cloudevents.ClusterName(event))
+		}
+	})
+	return nil
+}
+
+// receive keeps the event if the cluster is accepted and the event is newer than the received event of the same
+// type of the cluster, and returns whether it is kept
+func (c *consumerController) receive(event *cloudevents.Event) bool {
+	clusterName := cloudevents.ClusterName(event)
+	cluster, err := c.clusterLister.Get(clusterName)
+	if err != nil || !meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
+		return false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	received, ok := c.received[clusterName]
+	if !ok {
+		received = &receivedEvents{}
+		c.received[clusterName] = received
+	}
+	latest := &received.heartbeat
+	if event.Type() == cloudevents.ClusterStatusEventType {
+		latest = &received.status
+	}
+	if *latest != nil && (*latest).Time().After(event.Time()) {
+		return false
+	}
+	*latest = event
+	return true
+}
+
+// forget drops the received events of the deleted cluster
+func (c *consumerController) forget(obj interface{}) {
+	clusterName, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.received, clusterName)
+}
+
+// reconciled forgets the events of the cluster once they are reconciled, unless newer events are received
+// meanwhile
+func (c *consumerController) reconciled(clusterName string, events receivedEvents) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	received, ok := c.received[clusterName]
+	if !ok {
+		return
+	}
+	if received.status == events.status {
+		received.status = nil
+	}
+	if received.heartbeat == events.heartbeat {
+		received.heartbeat = nil
+	}
+	if received.status == nil && received.heartbeat == nil {
+		delete(c.received, clusterName)
+	}
+}
+
+func (c *consumerController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	clusterName := syncCtx.QueueKey()
+	logger := helpers.ControllerLogger(ctx, controllerName)
+
+	c.lock.Lock()
+	var events receivedEvents
+	if received, ok := c.received[clusterName]; ok {
+		events = *received
+	}
+	c.lock.Unlock()
+	if events.status == nil && events.heartbeat == nil {
+		return nil
+	}
+
+	cluster, err := c.clusterLister.Get(clusterName)
+	switch {
+	case errors.IsNotFound(err):
+		logger.V(helpers.LogLevelDebug).Info("Dropped the events of a cluster not found",
+			helpers.LogKeyCluster, clusterName)
+		c.reconciled(clusterName, events)
+		return nil
+	case err != nil:
+		return err
+	}
+	if !meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
+		logger.V(helpers.LogLevelDebug).Info("Dropped the events of a cluster not accepted",
+			helpers.LogKeyCluster, clusterName)
+		c.reconciled(clusterName, events)
+		return nil
+	}
+
+	if events.heartbeat != nil {
+		health.EnterPhase(ctx, "renew lease")
+		if err := c.renewLease(ctx, clusterName, events.heartbeat.Time()); err != nil {
+			return err
+		}
+	}
+	if events.status != nil {
+		health.EnterPhase(ctx, "update status")
+		if err := c.updateStatus(ctx, syncCtx, clusterName, events.status); err != nil {
+			return err
+		}
+	}
+
+	c.reconciled(clusterName, events)
+	return nil
+}
+
+// renewLease renews the lease of the cluster at the time of the heartbeat, the time of a heartbeat from the future
+// is not trusted
+func (c *consumerController) renewLease(ctx context.Context, clusterName string, heartbeatTime time.Time) error {
+	renewTime := c.now()
+	if heartbeatTime.Before(renewTime) {
+		renewTime = heartbeatTime
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"renewTime": metav1.NewMicroTime(renewTime)},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.kubeClient.CoordinationV1().Leases(clusterName).Patch(ctx, leaseName, types.MergePatchType, patch,
+		metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to renew the lease of managed cluster %q: %w", clusterName, err)
+	}
+	return nil
+}
+
+// updateStatus updates the conditions reported by the agent, and the version and the resources of the cluster
+func (c *consumerController) updateStatus(ctx context.Context, syncCtx factory.SyncContext, clusterName string,
+	event *cloudevents.Event) error {
+	status := clusterv1.ManagedClusterStatus{}
+	if err := cloudevents.DecodeData(event, &status); err != nil {
+		// the event is not retried
+		syncCtx.Recorder().Warningf("ClusterStatusEventInvalid", "Unable to decode the status of managed cluster %q in event %q: %v",
+			clusterName, event.ID(), err)
+		return nil
+	}
+
+	updateStatusFuncs := []helpers.UpdateManagedClusterStatusFunc{}
+	if len(status.Version.Kubernetes) > 0 {
+		updateStatusFuncs = append(updateStatusFuncs, helpers.UpdateManagedClusterResourcesFn(status))
+	}
+	for _, condition := range status.Conditions {
+		if hubConditionTypes[condition.Type] {
+			continue
+		}
+		updateStatusFuncs = append(updateStatusFuncs, helpers.UpdateManagedClusterConditionFn(condition))
+	}
+
+	_, updated, err := helpers.PatchManagedClusterStatus(ctx, c.clusterClient, clusterName, updateStatusFuncs...)
+	if err != nil {
+		return fmt.Errorf("unable to update status of managed cluster %q: %w", clusterName, err)
+	}
+	if updated {
+		syncCtx.Recorder().Eventf("ManagedClusterStatusUpdated", "The status of managed cluster %q is updated with event %q",
+			clusterName, event.ID())
+	}
+	return nil
+}
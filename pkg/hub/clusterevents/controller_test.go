@@ -0,0 +1,166 @@
+package clusterevents
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/cloudevents"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func newTestEvent(t *testing.T, eventType string, eventTime time.Time, data interface{}) *cloudevents.Event {
+	event, err := cloudevents.NewEvent(eventType, testinghelpers.TestManagedClusterName, "agent1", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	event.SetTime(eventTime)
+	return event
+}
+
+func TestSync(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	status := &clusterv1.ManagedClusterStatus{
+		Conditions: []metav1.Condition{
+			{Type: clusterv1.ManagedClusterConditionAvailable, Status: metav1.ConditionTrue, Reason: "ManagedClusterAvailable"},
+			// the conditions of the hub are ignored
+			{Type: clusterv1.ManagedClusterConditionHubAccepted, Status: metav1.ConditionFalse, Reason: "Forged"},
+		},
+		Version: clusterv1.ManagedClusterVersion{Kubernetes: "v1.23.0"},
+	}
+
+	cases := []struct {
+		name                string
+		clusters            []runtime.Object
+		events              func(t *testing.T) []*cloudevents.Event
+		validateKubeActions func(t *testing.T, actions []clienttesting.Action)
+		validateActions     func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:     "no events",
+			clusters: []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			events: func(t *testing.T) []*cloudevents.Event {
+				return nil
+			},
+			validateKubeActions: testinghelpers.AssertNoActions,
+			validateActions:     testinghelpers.AssertNoActions,
+		},
+		{
+			name: "cluster not found",
+			events: func(t *testing.T) []*cloudevents.Event {
+				return []*cloudevents.Event{newTestEvent(t, cloudevents.ClusterHeartbeatEventType, now, nil)}
+			},
+			validateKubeActions: testinghelpers.AssertNoActions,
+			validateActions:     testinghelpers.AssertNoActions,
+		},
+		{
+			name:     "cluster not accepted",
+			clusters: []runtime.Object{testinghelpers.NewManagedCluster()},
+			events: func(t *testing.T) []*cloudevents.Event {
+				return []*cloudevents.Event{newTestEvent(t, cloudevents.ClusterStatusEventType, now, status)}
+			},
+			validateKubeActions: testinghelpers.AssertNoActions,
+			validateActions:     testinghelpers.AssertNoActions,
+		},
+		{
+			name:     "heartbeat",
+			clusters: []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			events: func(t *testing.T) []*cloudevents.Event {
+				return []*cloudevents.Event{
+					newTestEvent(t, cloudevents.ClusterHeartbeatEventType, now.Add(-time.Minute), nil),
+					// the older heartbeat is discarded
+					newTestEvent(t, cloudevents.ClusterHeartbeatEventType, now.Add(-2*time.Minute), nil),
+				}
+			},
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				lease := testinghelpers.PatchedLease(t, actions[0])
+				if !lease.Spec.RenewTime.Time.Equal(now.Add(-time.Minute)) {
+					t.Errorf("expected the lease renewed at %v, but got %v", now.Add(-time.Minute), lease.Spec.RenewTime)
+				}
+			},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:     "heartbeat from the future",
+			clusters: []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			events: func(t *testing.T) []*cloudevents.Event {
+				return []*cloudevents.Event{newTestEvent(t, cloudevents.ClusterHeartbeatEventType, now.Add(time.Hour), nil)}
+			},
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				lease := testinghelpers.PatchedLease(t, actions[0])
+				if !lease.Spec.RenewTime.Time.Equal(now) {
+					t.Errorf("expected the lease renewed at %v, but got %v", now, lease.Spec.RenewTime)
+				}
+			},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:     "status",
+			clusters: []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			events: func(t *testing.T) []*cloudevents.Event {
+				return []*cloudevents.Event{newTestEvent(t, cloudevents.ClusterStatusEventType, now, status)}
+			},
+			validateKubeActions: testinghelpers.AssertNoActions,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				cluster := testinghelpers.PatchedManagedCluster(t, actions[1])
+				if !meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable) {
+					t.Errorf("expected the cluster available, but got %v", cluster.Status.Conditions)
+				}
+				if meta.IsStatusConditionFalse(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
+					t.Errorf("expected the hub accepted condition not changed, but got %v", cluster.Status.Conditions)
+				}
+				if cluster.Status.Version.Kubernetes != "v1.23.0" {
+					t.Errorf("expected the version updated, but got %v", cluster.Status.Version)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(testinghelpers.NewManagedClusterLease(leaseName, now.Add(-time.Hour)))
+			clusterClient := clusterfake.NewSimpleClientset(c.clusters...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+			for _, cluster := range c.clusters {
+				if err := clusterStore.Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+			clusterClient.ClearActions()
+
+			ctrl := &consumerController{
+				kubeClient:    kubeClient,
+				clusterClient: clusterClient,
+				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				now:           func() time.Time { return now },
+				received:      map[string]*receivedEvents{},
+			}
+			for _, event := range c.events(t) {
+				ctrl.receive(event)
+			}
+
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+			c.validateKubeActions(t, kubeClient.Actions())
+			c.validateActions(t, clusterClient.Actions())
+			if len(ctrl.received) != 0 {
+				t.Errorf("expected the events to be forgotten once they are reconciled, but got %v", ctrl.received)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+// package clusterevents contains the hub-side consumer of the status and the heartbeats of the managed clusters
+// published as CloudEvents over MQTT by the agents, see the cloudevents package. The consumer reconciles the events
+// into the status of the ManagedClusters and the renew time of their leases, so the other hub controllers handle
+// the clusters the same as the clusters whose agents update them on the hub kube-apiserver.
+package clusterevents
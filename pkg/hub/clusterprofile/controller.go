@@ -0,0 +1,340 @@
+package clusterprofile
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const controllerName = "ClusterProfileController"
+
+// ClusterProfileGVR is the resource of the ClusterProfiles of the cluster inventory API
+var ClusterProfileGVR = schema.GroupVersionResource{Group: "multicluster.x-k8s.io", Version: "v1alpha1", Resource: "clusterprofiles"}
+
+const (
+	// ClusterManagerLabel is the label of a ClusterProfile which specifies the cluster manager maintaining it, the
+	// ClusterProfiles maintained by the hub are labeled with ClusterManagerName.
+	ClusterManagerLabel = "x-k8s.io/cluster-manager"
+	ClusterManagerName  = "open-cluster-management"
+
+	// ClusterSetLabel is the label of a ClusterProfile which specifies the clusterset it belongs to, it is mapped
+	// from the clusterset label of the ManagedCluster.
+	ClusterSetLabel = "multicluster.x-k8s.io/clusterset"
+
+	// ImportedFromAnnotation is the annotation of a ManagedCluster imported from the ClusterProfile of another
+	// cluster manager, its value is the <namespace>/<name> of the ClusterProfile.
+	ImportedFromAnnotation = "cluster.open-cluster-management.io/imported-from-clusterprofile"
+
+	// The condition types of a ClusterProfile mapped from the conditions of the ManagedCluster
+	ConditionControlPlaneHealthy = "ControlPlaneHealthy"
+	ConditionJoined              = "Joined"
+)
+
+// DefaultNamespace is the default namespace the ClusterProfiles of the managed clusters are maintained in
+const DefaultNamespace = "open-cluster-management"
+
+// PollInterval is the interval the ClusterProfiles are checked in for the ones orphaned by the deleted
+// ManagedClusters and the ones to import. The ClusterProfiles are not watched, so the hub does not depend on the
+// CRD of the cluster inventory API unless the controller is enabled. It is exposed so that integration tests can
+// shorten it.
+var PollInterval = time.Minute
+
+// conditionTypes maps the condition types of a ManagedCluster to the condition types of its ClusterProfile
+var conditionTypes = map[string]string{
+	clusterv1.ManagedClusterConditionAvailable: ConditionControlPlaneHealthy,
+	clusterv1.ManagedClusterConditionJoined:    ConditionJoined,
+}
+
+// clusterProfileStatus is the part of the status of a ClusterProfile maintained by the hub
+type clusterProfileStatus struct {
+	Conditions []metav1.Condition       `json:"conditions,omitempty"`
+	Version    clusterProfileVersion    `json:"version,omitempty"`
+	Properties []clusterProfileProperty `json:"properties,omitempty"`
+}
+
+type clusterProfileVersion struct {
+	Kubernetes string `json:"kubernetes,omitempty"`
+}
+
+type clusterProfileProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// clusterProfileController maintains a ClusterProfile named after each ManagedCluster in the namespace of the
+// inventory. The display name and the cluster manager are set in its spec, and the Available and Joined conditions,
+// the kubernetes version and the cluster claims of the ManagedCluster are mapped to its status. The ClusterProfile is
+// deleted with the ManagedCluster. The ClusterProfiles of the other cluster managers are left untouched, and a
+// ManagedCluster which is not accepted yet is created for each of them if importProfiles is true, so the cluster
+// admin accepts the clusters to manage them with open-cluster-management as well.
+type clusterProfileController struct {
+	clusterClient  clientset.Interface
+	dynamicClient  dynamic.Interface
+	clusterLister  listerv1.ManagedClusterLister
+	namespace      string
+	importProfiles bool
+	eventRecorder  events.Recorder
+}
+
+// NewClusterProfileController returns an instance of clusterProfileController
+func NewClusterProfileController(
+	clusterClient clientset.Interface,
+	dynamicClient dynamic.Interface,
+	clusterInformer informerv1.ManagedClusterInformer,
+	namespace string,
+	importProfiles bool,
+	recorder events.Recorder) factory.Controller {
+	c := &clusterProfileController{
+		clusterClient:  clusterClient,
+		dynamicClient:  dynamicClient,
+		clusterLister:  clusterInformer.Lister(),
+		namespace:      namespace,
+		importProfiles: importProfiles,
+		eventRecorder:  recorder.WithComponentSuffix("cluster-profile-controller"),
+	}
+
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ResyncEvery(PollInterval).
+		ToController(controllerName, recorder)
+}
+
+func (c *clusterProfileController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	clusterName := syncCtx.QueueKey()
+	if clusterName == factory.DefaultQueueKey {
+		return c.syncClusterProfiles(ctx)
+	}
+
+	logger := helpers.ControllerLogger(ctx, controllerName)
+	logger.V(helpers.LogLevelDebug).Info("Reconciling ClusterProfile", helpers.LogKeyCluster, clusterName)
+
+	clusterProfiles := c.dynamicClient.Resource(ClusterProfileGVR).Namespace(c.namespace)
+	clusterProfile, err := clusterProfiles.Get(ctx, clusterName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		clusterProfile = nil
+	case err != nil:
+		return err
+	}
+
+	cluster, err := c.clusterLister.Get(clusterName)
+	switch {
+	case errors.IsNotFound(err):
+		return c.deleteClusterProfile(ctx, clusterProfile)
+	case err != nil:
+		return err
+	}
+
+	if clusterProfile == nil {
+		clusterProfile = &unstructured.Unstructured{Object: map[string]interface{}{}}
+		clusterProfile.SetAPIVersion(ClusterProfileGVR.GroupVersion().String())
+		clusterProfile.SetKind("ClusterProfile")
+		clusterProfile.SetNamespace(c.namespace)
+		clusterProfile.SetName(clusterName)
+		if err := applyClusterProfileSpec(clusterProfile, cluster); err != nil {
+			return err
+		}
+		clusterProfile, err = clusterProfiles.Create(ctx, clusterProfile, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		c.eventRecorder.Eventf("ClusterProfileCreated", "ClusterProfile %q is created for managed cluster %q",
+			c.namespace+"/"+clusterName, clusterName)
+	}
+
+	// the ClusterProfiles of the other cluster managers are never overwritten
+	if clusterProfile.GetLabels()[ClusterManagerLabel] != ClusterManagerName {
+		logger.V(helpers.LogLevelDebug).Info("ClusterProfile is managed by another cluster manager",
+			helpers.LogKeyCluster, clusterName, "clusterManager", clusterProfile.GetLabels()[ClusterManagerLabel])
+		return nil
+	}
+
+	updated := clusterProfile.DeepCopy()
+	if err := applyClusterProfileSpec(updated, cluster); err != nil {
+		return err
+	}
+	if !equality.Semantic.DeepEqual(updated.Object, clusterProfile.Object) {
+		clusterProfile, err = clusterProfiles.Update(ctx, updated, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	status := clusterProfileStatus{}
+	if statusObj, ok, _ := unstructured.NestedMap(clusterProfile.Object, "status"); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(statusObj, &status); err != nil {
+			return err
+		}
+	}
+	desiredStatus := newClusterProfileStatus(status, cluster)
+	if equality.Semantic.DeepEqual(desiredStatus, status) {
+		return nil
+	}
+	statusObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&desiredStatus)
+	if err != nil {
+		return err
+	}
+	updated = clusterProfile.DeepCopy()
+	if err := unstructured.SetNestedMap(updated.Object, statusObj, "status"); err != nil {
+		return err
+	}
+	_, err = clusterProfiles.UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+// syncClusterProfiles deletes the ClusterProfiles maintained by the hub whose ManagedClusters are deleted while the
+// hub is not watching, and imports the ClusterProfiles of the other cluster managers if importProfiles is true
+func (c *clusterProfileController) syncClusterProfiles(ctx context.Context) error {
+	clusterProfiles, err := c.dynamicClient.Resource(ClusterProfileGVR).Namespace(c.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for i := range clusterProfiles.Items {
+		clusterProfile := &clusterProfiles.Items[i]
+		_, err := c.clusterLister.Get(clusterProfile.GetName())
+		switch {
+		case err == nil:
+			continue
+		case !errors.IsNotFound(err):
+			errs = append(errs, err)
+			continue
+		}
+
+		if clusterProfile.GetLabels()[ClusterManagerLabel] == ClusterManagerName {
+			if err := c.deleteClusterProfile(ctx, clusterProfile); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if c.importProfiles {
+			if err := c.importClusterProfile(ctx, clusterProfile); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return operatorhelpers.NewMultiLineAggregate(errs)
+}
+
+// deleteClusterProfile deletes the ClusterProfile if it is maintained by the hub
+func (c *clusterProfileController) deleteClusterProfile(ctx context.Context, clusterProfile *unstructured.Unstructured) error {
+	if clusterProfile == nil || clusterProfile.GetLabels()[ClusterManagerLabel] != ClusterManagerName {
+		return nil
+	}
+	err := c.dynamicClient.Resource(ClusterProfileGVR).Namespace(c.namespace).
+		Delete(ctx, clusterProfile.GetName(), metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.eventRecorder.Eventf("ClusterProfileDeleted", "ClusterProfile %q of the deleted managed cluster is deleted",
+		c.namespace+"/"+clusterProfile.GetName())
+	return nil
+}
+
+// importClusterProfile creates a ManagedCluster for the ClusterProfile of another cluster manager. The ManagedCluster
+// is not accepted until the cluster admin accepts it, and its agent joins it as any other cluster.
+func (c *clusterProfileController) importClusterProfile(ctx context.Context, clusterProfile *unstructured.Unstructured) error {
+	cluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: clusterProfile.GetName(),
+			Annotations: map[string]string{
+				ImportedFromAnnotation: clusterProfile.GetNamespace() + "/" + clusterProfile.GetName(),
+			},
+		},
+	}
+	if clusterSet, ok := clusterProfile.GetLabels()[ClusterSetLabel]; ok {
+		cluster.Labels = map[string]string{helpers.ClusterSetLabel: clusterSet}
+	}
+
+	_, err := c.clusterClient.ClusterV1().ManagedClusters().Create(ctx, cluster, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.eventRecorder.Eventf("ClusterProfileImported", "managed cluster %q is created for ClusterProfile %q of cluster manager %q",
+		cluster.Name, clusterProfile.GetNamespace()+"/"+clusterProfile.GetName(), clusterProfile.GetLabels()[ClusterManagerLabel])
+	helpers.ControllerLogger(ctx, controllerName).Info("Imported ClusterProfile",
+		helpers.LogKeyCluster, cluster.Name, helpers.LogKeyResource, klog.KObj(clusterProfile))
+	return nil
+}
+
+// applyClusterProfileSpec sets the labels and the spec of the ClusterProfile of the ManagedCluster, the other
+// labels and fields of the ClusterProfile are kept
+func applyClusterProfileSpec(clusterProfile *unstructured.Unstructured, cluster *clusterv1.ManagedCluster) error {
+	labels := clusterProfile.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[ClusterManagerLabel] = ClusterManagerName
+	if clusterSet, ok := cluster.Labels[helpers.ClusterSetLabel]; ok {
+		labels[ClusterSetLabel] = clusterSet
+	} else {
+		delete(labels, ClusterSetLabel)
+	}
+	clusterProfile.SetLabels(labels)
+
+	if err := unstructured.SetNestedField(clusterProfile.Object, cluster.Name, "spec", "displayName"); err != nil {
+		return err
+	}
+	return unstructured.SetNestedField(clusterProfile.Object, ClusterManagerName, "spec", "clusterManager", "name")
+}
+
+// newClusterProfileStatus returns the status of the ClusterProfile mapped from the ManagedCluster. The conditions
+// which are not mapped from the ManagedCluster are kept.
+func newClusterProfileStatus(status clusterProfileStatus, cluster *clusterv1.ManagedCluster) clusterProfileStatus {
+	desired := clusterProfileStatus{
+		Version: clusterProfileVersion{Kubernetes: cluster.Status.Version.Kubernetes},
+	}
+	for _, condition := range status.Conditions {
+		if condition.Type != ConditionControlPlaneHealthy && condition.Type != ConditionJoined {
+			desired.Conditions = append(desired.Conditions, condition)
+		}
+	}
+	for _, condition := range cluster.Status.Conditions {
+		conditionType, ok := conditionTypes[condition.Type]
+		if !ok {
+			continue
+		}
+		desired.Conditions = append(desired.Conditions, metav1.Condition{
+			Type:    conditionType,
+			Status:  condition.Status,
+			Reason:  condition.Reason,
+			Message: condition.Message,
+			// the time is serialized in seconds, it is truncated so that the status is compared with the one read back
+			LastTransitionTime: condition.LastTransitionTime.Rfc3339Copy(),
+		})
+	}
+	for _, claim := range cluster.Status.ClusterClaims {
+		desired.Properties = append(desired.Properties, clusterProfileProperty{Name: claim.Name, Value: claim.Value})
+	}
+	return desired
+}
@@ -0,0 +1,271 @@
+package clusterprofile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+const testNamespace = "open-cluster-management"
+
+func newClusterProfile(name, clusterManager string, labels map[string]string, status *clusterProfileStatus) *unstructured.Unstructured {
+	clusterProfile := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"displayName":    name,
+			"clusterManager": map[string]interface{}{"name": clusterManager},
+		},
+	}}
+	clusterProfile.SetAPIVersion(ClusterProfileGVR.GroupVersion().String())
+	clusterProfile.SetKind("ClusterProfile")
+	clusterProfile.SetNamespace(testNamespace)
+	clusterProfile.SetName(name)
+	profileLabels := map[string]string{ClusterManagerLabel: clusterManager}
+	for key, value := range labels {
+		profileLabels[key] = value
+	}
+	clusterProfile.SetLabels(profileLabels)
+	if status != nil {
+		statusObj, _ := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+		clusterProfile.Object["status"] = statusObj
+	}
+	return clusterProfile
+}
+
+// newAvailableCluster returns an available cluster in clusterset "dev" with a claim
+func newAvailableCluster() *clusterv1.ManagedCluster {
+	cluster := testinghelpers.NewManagedClusterBuilder(testinghelpers.TestManagedClusterName).
+		WithLabels(map[string]string{helpers.ClusterSetLabel: "dev"}).
+		WithClaims(clusterv1.ManagedClusterClaim{Name: "platform.open-cluster-management.io", Value: "AWS"}).
+		Joined().
+		Available().
+		Build()
+	cluster.Status.Version.Kubernetes = "v1.23.0"
+	return cluster
+}
+
+// availableClusterProfileStatus is the status of the ClusterProfile of newAvailableCluster
+func availableClusterProfileStatus() *clusterProfileStatus {
+	return &clusterProfileStatus{
+		Conditions: []metav1.Condition{
+			{Type: ConditionJoined, Status: metav1.ConditionTrue, Reason: "ManagedClusterJoined", Message: "Managed cluster joined"},
+			{Type: ConditionControlPlaneHealthy, Status: metav1.ConditionTrue, Reason: "ManagedClusterAvailable", Message: "Managed cluster is available"},
+		},
+		Version:    clusterProfileVersion{Kubernetes: "v1.23.0"},
+		Properties: []clusterProfileProperty{{Name: "platform.open-cluster-management.io", Value: "AWS"}},
+	}
+}
+
+// updatedStatus returns the status of the ClusterProfile updated by the action
+func updatedStatus(t *testing.T, action clienttesting.Action) clusterProfileStatus {
+	if action.GetVerb() != "update" || action.GetSubresource() != "status" {
+		t.Fatalf("expected the status of the ClusterProfile to be updated, but got %v", action)
+	}
+	obj := action.(clienttesting.UpdateActionImpl).Object.(*unstructured.Unstructured)
+	statusObj, _, _ := unstructured.NestedMap(obj.Object, "status")
+	status := clusterProfileStatus{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(statusObj, &status); err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func TestSync(t *testing.T) {
+	cases := []struct {
+		name                  string
+		queueKey              string
+		importProfiles        bool
+		cluster               *clusterv1.ManagedCluster
+		clusterProfiles       []runtime.Object
+		validateActions       func(t *testing.T, actions []clienttesting.Action)
+		validateClusterAction func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:     "create ClusterProfile",
+			queueKey: testinghelpers.TestManagedClusterName,
+			cluster:  newAvailableCluster(),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create", "update")
+				created := actions[1].(clienttesting.CreateActionImpl).Object.(*unstructured.Unstructured)
+				if created.GetLabels()[ClusterManagerLabel] != ClusterManagerName || created.GetLabels()[ClusterSetLabel] != "dev" {
+					t.Errorf("unexpected labels %v", created.GetLabels())
+				}
+				if name, _, _ := unstructured.NestedString(created.Object, "spec", "clusterManager", "name"); name != ClusterManagerName {
+					t.Errorf("unexpected cluster manager %q", name)
+				}
+				status := updatedStatus(t, actions[2])
+				if expected := availableClusterProfileStatus(); !equalStatus(status, *expected) {
+					t.Errorf("expected status %v, but got %v", expected, status)
+				}
+			},
+		},
+		{
+			name:            "ClusterProfile is up to date",
+			queueKey:        testinghelpers.TestManagedClusterName,
+			cluster:         newAvailableCluster(),
+			clusterProfiles: []runtime.Object{newClusterProfile(testinghelpers.TestManagedClusterName, ClusterManagerName, map[string]string{ClusterSetLabel: "dev"}, availableClusterProfileStatus())},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name:     "cluster becomes unavailable",
+			queueKey: testinghelpers.TestManagedClusterName,
+			cluster: testinghelpers.NewManagedClusterBuilder(testinghelpers.TestManagedClusterName).
+				WithLabels(map[string]string{helpers.ClusterSetLabel: "dev"}).
+				Joined().
+				WithCondition(clusterv1.ManagedClusterConditionAvailable, metav1.ConditionUnknown, "ManagedClusterLeaseUpdateStopped", "lost").
+				Build(),
+			clusterProfiles: []runtime.Object{newClusterProfile(testinghelpers.TestManagedClusterName, ClusterManagerName, map[string]string{ClusterSetLabel: "dev"}, availableClusterProfileStatus())},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				status := updatedStatus(t, actions[1])
+				if len(status.Conditions) != 2 || status.Conditions[1].Type != ConditionControlPlaneHealthy ||
+					status.Conditions[1].Status != metav1.ConditionUnknown {
+					t.Errorf("expected the control plane to be unknown, but got %v", status.Conditions)
+				}
+				if len(status.Version.Kubernetes) != 0 || len(status.Properties) != 0 {
+					t.Errorf("expected no version and properties, but got %v", status)
+				}
+			},
+		},
+		{
+			name:            "cluster moved to another clusterset",
+			queueKey:        testinghelpers.TestManagedClusterName,
+			cluster:         newAvailableCluster(),
+			clusterProfiles: []runtime.Object{newClusterProfile(testinghelpers.TestManagedClusterName, ClusterManagerName, map[string]string{ClusterSetLabel: "prod"}, availableClusterProfileStatus())},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				updated := actions[1].(clienttesting.UpdateActionImpl).Object.(*unstructured.Unstructured)
+				if updated.GetLabels()[ClusterSetLabel] != "dev" {
+					t.Errorf("expected the clusterset label to be updated, but got %v", updated.GetLabels())
+				}
+			},
+		},
+		{
+			name:            "ClusterProfile of another cluster manager",
+			queueKey:        testinghelpers.TestManagedClusterName,
+			cluster:         newAvailableCluster(),
+			clusterProfiles: []runtime.Object{newClusterProfile(testinghelpers.TestManagedClusterName, "fleet", nil, nil)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name:            "cluster deleted",
+			queueKey:        testinghelpers.TestManagedClusterName,
+			clusterProfiles: []runtime.Object{newClusterProfile(testinghelpers.TestManagedClusterName, ClusterManagerName, nil, nil)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "delete")
+			},
+		},
+		{
+			name:            "cluster of another cluster manager deleted",
+			queueKey:        testinghelpers.TestManagedClusterName,
+			clusterProfiles: []runtime.Object{newClusterProfile(testinghelpers.TestManagedClusterName, "fleet", nil, nil)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name:     "orphaned ClusterProfile",
+			queueKey: factory.DefaultQueueKey,
+			clusterProfiles: []runtime.Object{
+				newClusterProfile(testinghelpers.TestManagedClusterName, ClusterManagerName, nil, nil),
+				newClusterProfile("cluster2", "fleet", nil, nil),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list", "delete")
+			},
+			validateClusterAction: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:           "import ClusterProfiles",
+			queueKey:       factory.DefaultQueueKey,
+			importProfiles: true,
+			cluster:        newAvailableCluster(),
+			clusterProfiles: []runtime.Object{
+				newClusterProfile(testinghelpers.TestManagedClusterName, ClusterManagerName, nil, nil),
+				newClusterProfile("cluster2", "fleet", map[string]string{ClusterSetLabel: "edge"}, nil),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+			validateClusterAction: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "create")
+				cluster := actions[0].(clienttesting.CreateActionImpl).Object.(*clusterv1.ManagedCluster)
+				if cluster.Name != "cluster2" || cluster.Spec.HubAcceptsClient {
+					t.Errorf("expected cluster2 to be created and not accepted, but got %v", cluster)
+				}
+				if cluster.Annotations[ImportedFromAnnotation] != testNamespace+"/cluster2" || cluster.Labels[helpers.ClusterSetLabel] != "edge" {
+					t.Errorf("unexpected metadata of the imported cluster %v", cluster.ObjectMeta)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterObjects := []runtime.Object{}
+			if c.cluster != nil {
+				clusterObjects = append(clusterObjects, c.cluster)
+			}
+			clusterClient := clusterfake.NewSimpleClientset(clusterObjects...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			for _, obj := range clusterObjects {
+				if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(obj); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{ClusterProfileGVR: "ClusterProfileList"}, c.clusterProfiles...)
+
+			ctrl := &clusterProfileController{
+				clusterClient:  clusterClient,
+				dynamicClient:  dynamicClient,
+				clusterLister:  clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				namespace:      testNamespace,
+				importProfiles: c.importProfiles,
+				eventRecorder:  eventstesting.NewTestingEventRecorder(t),
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, c.queueKey))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateActions(t, dynamicClient.Actions())
+			if c.validateClusterAction != nil {
+				c.validateClusterAction(t, clusterClient.Actions())
+			}
+		})
+	}
+}
+
+// equalStatus compares the statuses ignoring the transition times of the conditions
+func equalStatus(actual, expected clusterProfileStatus) bool {
+	for i := range actual.Conditions {
+		actual.Conditions[i].LastTransitionTime = metav1.Time{}
+	}
+	for i := range expected.Conditions {
+		expected.Conditions[i].LastTransitionTime = metav1.Time{}
+	}
+	return equality.Semantic.DeepEqual(actual, expected)
+}
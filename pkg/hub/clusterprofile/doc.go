@@ -0,0 +1,6 @@
+// package clusterprofile contains the hub-side controller which synchronizes the ManagedClusters with the
+// ClusterProfiles of the SIG-Multicluster cluster inventory API (multicluster.x-k8s.io/v1alpha1), so the tools
+// built on the inventory API discover the clusters registered with open-cluster-management. A ClusterProfile is
+// maintained for each ManagedCluster, and the ClusterProfiles of the other cluster managers are optionally imported
+// as ManagedClusters waiting to be accepted.
+package clusterprofile
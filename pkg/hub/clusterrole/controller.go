@@ -7,6 +7,7 @@ import (
 
 	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
 	"open-cluster-management.io/registration/pkg/helpers"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
@@ -22,12 +23,14 @@ import (
 )
 
 const (
-	registrationClusterRole = "open-cluster-management:managedcluster:registration"
-	workClusterRole         = "open-cluster-management:managedcluster:work"
+	registrationClusterRole      = "open-cluster-management:managedcluster:registration"
+	registrationAddOnClusterRole = "open-cluster-management:managedcluster:registration:addon"
+	workClusterRole              = "open-cluster-management:managedcluster:work"
 )
 
 var clusterRoleFiles = []string{
 	"manifests/managedcluster-registration-clusterrole.yaml",
+	"manifests/managedcluster-registration-addon-clusterrole.yaml",
 	"manifests/managedcluster-work-clusterrole.yaml",
 }
 
@@ -51,13 +54,13 @@ func NewManagedClusterClusterroleController(
 	c := &clusterroleController{
 		kubeClient:    kubeClient,
 		clusterLister: clusterInformer.Lister(),
-		cache:         resourceapply.NewResourceCache(),
+		cache:         helpers.NewResourceCache(),
 		eventRecorder: recorder.WithComponentSuffix("managed-cluster-clusterrole-controller"),
 	}
 	return factory.New().
 		WithFilteredEventsInformers(
 			func(obj interface{}) bool {
-				clusterRoles := sets.NewString(registrationClusterRole, workClusterRole)
+				clusterRoles := sets.NewString(registrationClusterRole, registrationAddOnClusterRole, workClusterRole)
 				metaObj := obj.(metav1.Object)
 				if clusterRoles.Has(metaObj.GetName()) {
 					return true
@@ -65,7 +68,7 @@ func NewManagedClusterClusterroleController(
 				return false
 			}, clusterRoleInformer.Informer()).
 		WithInformers(clusterInformer.Informer()).
-		WithSync(c.sync).
+		WithSync(health.WrapSync("ManagedClusterClusterRoleController", c.sync)).
 		ToController("ManagedClusterClusterRoleController", recorder)
 }
 
@@ -31,12 +31,16 @@ func TestSyncManagedClusterClusterRole(t *testing.T) {
 			clusters:     []runtime.Object{testinghelpers.NewManagedCluster()},
 			clusterroles: []runtime.Object{},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "get", "create", "get", "create")
+				testinghelpers.AssertActions(t, actions, "get", "create", "get", "create", "get", "create")
 				registrationClusterRole := (actions[1].(clienttesting.CreateActionImpl).Object).(*rbacv1.ClusterRole)
 				if registrationClusterRole.Name != "open-cluster-management:managedcluster:registration" {
 					t.Errorf("expected registration clusterrole, but failed")
 				}
-				workClusterRole := (actions[3].(clienttesting.CreateActionImpl).Object).(*rbacv1.ClusterRole)
+				registrationAddOnClusterRole := (actions[3].(clienttesting.CreateActionImpl).Object).(*rbacv1.ClusterRole)
+				if registrationAddOnClusterRole.Name != "open-cluster-management:managedcluster:registration:addon" {
+					t.Errorf("expected registration addon clusterrole, but failed")
+				}
+				workClusterRole := (actions[5].(clienttesting.CreateActionImpl).Object).(*rbacv1.ClusterRole)
 				if workClusterRole.Name != "open-cluster-management:managedcluster:work" {
 					t.Errorf("expected work clusterrole, but failed")
 				}
@@ -47,14 +51,18 @@ func TestSyncManagedClusterClusterRole(t *testing.T) {
 			clusters: []runtime.Object{},
 			clusterroles: []runtime.Object{
 				&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "open-cluster-management:managedcluster:registration"}},
+				&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "open-cluster-management:managedcluster:registration:addon"}},
 				&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "open-cluster-management:managedcluster:work"}},
 			},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "delete", "delete")
+				testinghelpers.AssertActions(t, actions, "delete", "delete", "delete")
 				if actions[0].(clienttesting.DeleteActionImpl).Name != "open-cluster-management:managedcluster:registration" {
 					t.Errorf("expected registration clusterrole, but failed")
 				}
-				if actions[1].(clienttesting.DeleteActionImpl).Name != "open-cluster-management:managedcluster:work" {
+				if actions[1].(clienttesting.DeleteActionImpl).Name != "open-cluster-management:managedcluster:registration:addon" {
+					t.Errorf("expected registration addon clusterrole, but failed")
+				}
+				if actions[2].(clienttesting.DeleteActionImpl).Name != "open-cluster-management:managedcluster:work" {
 					t.Errorf("expected work clusterrole, but failed")
 				}
 			},
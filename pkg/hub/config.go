@@ -0,0 +1,45 @@
+package hub
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	configv1alpha1 "open-cluster-management.io/registration/pkg/apis/config/v1alpha1"
+	"open-cluster-management.io/registration/pkg/features"
+)
+
+// applyComponentConfig applies the HubConfiguration in the file passed with --config to the options. The flags
+// set on the command line take precedence over the configuration file. The file is ignored if it is not a
+// HubConfiguration, e.g. a GenericOperatorConfig of library-go.
+func (m *HubManagerOptions) applyComponentConfig(componentConfig *unstructured.Unstructured) error {
+	if componentConfig == nil || componentConfig.GroupVersionKind().GroupVersion() != configv1alpha1.SchemeGroupVersion {
+		return nil
+	}
+
+	data, err := componentConfig.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	config, err := configv1alpha1.DecodeHubConfiguration(data)
+	if err != nil {
+		return err
+	}
+	if errs := configv1alpha1.ValidateHubConfiguration(config); len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+
+	changed := func(name string) bool {
+		return m.flags != nil && m.flags.Changed(name)
+	}
+	if !changed("feature-gates") && len(config.FeatureGates) > 0 {
+		if err := features.DefaultHubMutableFeatureGate.SetFromMap(config.FeatureGates); err != nil {
+			return err
+		}
+	}
+	if !changed("webhook-failure-policy") {
+		m.WebhookFailurePolicy = config.WebhookFailurePolicy
+	}
+	if !changed("webhook-excluded-namespaces") && len(config.WebhookExcludedNamespaces) > 0 {
+		m.WebhookExcludedNamespaces = config.WebhookExcludedNamespaces
+	}
+	return nil
+}
@@ -0,0 +1,556 @@
+package hub
+
+import (
+	"open-cluster-management.io/registration/pkg/cloudevents"
+	"open-cluster-management.io/registration/pkg/features"
+	"open-cluster-management.io/registration/pkg/hub/addon"
+	"open-cluster-management.io/registration/pkg/hub/agentconfig"
+	"open-cluster-management.io/registration/pkg/hub/archive"
+	"open-cluster-management.io/registration/pkg/hub/awsiam"
+	"open-cluster-management.io/registration/pkg/hub/bootstraptoken"
+	"open-cluster-management.io/registration/pkg/hub/certmanager"
+	"open-cluster-management.io/registration/pkg/hub/clusterapi"
+	"open-cluster-management.io/registration/pkg/hub/clusterevents"
+	"open-cluster-management.io/registration/pkg/hub/clusterprofile"
+	"open-cluster-management.io/registration/pkg/hub/clusterrole"
+	"open-cluster-management.io/registration/pkg/hub/csr"
+	"open-cluster-management.io/registration/pkg/hub/hubca"
+	"open-cluster-management.io/registration/pkg/hub/importer"
+	"open-cluster-management.io/registration/pkg/hub/inventory"
+	"open-cluster-management.io/registration/pkg/hub/lease"
+	"open-cluster-management.io/registration/pkg/hub/managedcluster"
+	"open-cluster-management.io/registration/pkg/hub/managedclusterset"
+	"open-cluster-management.io/registration/pkg/hub/rbacfinalizerdeletion"
+	"open-cluster-management.io/registration/pkg/hub/registrationtoken"
+	"open-cluster-management.io/registration/pkg/hub/rename"
+	"open-cluster-management.io/registration/pkg/hub/taint"
+	"open-cluster-management.io/registration/pkg/hub/webhookcert"
+	"open-cluster-management.io/registration/pkg/hub/webhookconfig"
+
+	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	workv1informers "open-cluster-management.io/api/client/work/informers/externalversions"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	apiregistrationclient "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset/typed/apiregistration/v1"
+)
+
+// hubControllerContext holds the options, the clients and the shared informer factories the controllers on hub
+// are created with
+type hubControllerContext struct {
+	*EmbeddedOptions
+
+	kubeConfig    *rest.Config
+	kubeClient    kubernetes.Interface
+	clusterClient clusterv1client.Interface
+	addOnClient   addonclient.Interface
+	recorder      events.Recorder
+
+	clusterInformers clusterv1informers.SharedInformerFactory
+	// scopedClusterInformers only watch the clusters in clusterScope, they are the same with clusterInformers if
+	// the hub controller is not scoped
+	scopedClusterInformers clusterv1informers.SharedInformerFactory
+	clusterScope           labels.Selector
+	workInformers          workv1informers.SharedInformerFactory
+	kubeInformers          kubeinformers.SharedInformerFactory
+	addOnInformers         addoninformers.SharedInformerFactory
+	// clusterCSRInformers only watch the csrs of the managed clusters and their addons
+	clusterCSRInformers kubeinformers.SharedInformerFactory
+	// webhookCSRInformers only watch the csrs of the webhook serving certificate
+	webhookCSRInformers kubeinformers.SharedInformerFactory
+	// namespacedKubeInformers only watch the namespace of the hub controller
+	namespacedKubeInformers kubeinformers.SharedInformerFactory
+}
+
+// newControllersFunc creates the controllers on hub with a name, which share the workers configured by the name.
+// It returns no controller if the controller is not required by the options or the feature gates.
+type newControllersFunc func(c *hubControllerContext) ([]factory.Controller, error)
+
+// hubControllers are the functions creating the controllers on hub, keyed by ControllerNames
+var hubControllers = map[string]newControllersFunc{
+	ManagedClusterControllerName:            newManagedClusterController,
+	TaintControllerName:                     newTaintController,
+	CSRApprovingControllerName:              newCSRApprovingController,
+	LeaseControllerName:                     newLeaseController,
+	RBACFinalizerControllerName:             newRBACFinalizerController,
+	ManagedClusterSetControllerName:         newManagedClusterSetController,
+	ClusterRoleControllerName:               newClusterRoleController,
+	AddOnHealthCheckControllerName:          newAddOnHealthCheckController,
+	AddOnFeatureDiscoveryControllerName:     newAddOnFeatureDiscoveryController,
+	AddOnCSRCleanupControllerName:           newAddOnCSRCleanupController,
+	AddOnTokenServiceAccountControllerName:  newAddOnTokenServiceAccountController,
+	AddOnRBACControllerName:                 newAddOnRBACController,
+	AddOnHealthAggregationControllerName:    newAddOnHealthAggregationController,
+	DefaultManagedClusterSetControllerName:  newDefaultManagedClusterSetControllers,
+	WebhookConfigurationControllerName:      newWebhookConfigurationController,
+	WebhookServingCertificateControllerName: newWebhookServingCertificateControllers,
+	CertManagerSignerControllerName:         newCertManagerSignerController,
+	RegistrationTokenControllerName:         newRegistrationTokenController,
+	AWSIAMRoleMappingControllerName:         newAWSIAMRoleMappingController,
+	ClusterEventsConsumerControllerName:     newClusterEventsConsumerController,
+	ClusterProfileControllerName:            newClusterProfileController,
+	ClusterAPIImportControllerName:          newClusterAPIImportController,
+	InventoryExportControllerName:           newInventoryExportController,
+	HubCARotationControllerName:             newHubCARotationController,
+	BootstrapTokenControllerName:            newBootstrapTokenController,
+	ClusterArchiveControllerName:            newClusterArchiveController,
+	ClusterRenameControllerName:             newClusterRenameController,
+	AgentConfigControllerName:               newAgentConfigController,
+	BootstrapCleanupControllerName:          newBootstrapCleanupController,
+}
+
+// newControllers creates the controllers on hub which are enabled in the options, grouped by their names
+func (c *hubControllerContext) newControllers() (map[string][]factory.Controller, error) {
+	controllers := map[string][]factory.Controller{}
+	for _, name := range ControllerNames.List() {
+		if !c.enabled(name) {
+			continue
+		}
+		ctrls, err := hubControllers[name](c)
+		if err != nil {
+			return nil, err
+		}
+		if len(ctrls) > 0 {
+			controllers[name] = ctrls
+		}
+	}
+	return controllers, nil
+}
+
+func newManagedClusterController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{managedcluster.NewManagedClusterController(
+		c.kubeClient,
+		c.clusterClient,
+		c.scopedClusterInformers.Cluster().V1().ManagedClusters(),
+		c.SupportedKubernetesVersions,
+		c.recorder,
+	)}, nil
+}
+
+func newTaintController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{taint.NewTaintController(
+		c.clusterClient,
+		c.scopedClusterInformers.Cluster().V1().ManagedClusters(),
+		c.recorder,
+	)}, nil
+}
+
+func newCSRApprovingController(c *hubControllerContext) ([]factory.Controller, error) {
+	approvers := []csr.Approver{}
+	// the csrs with the keys which are not approved are denied before any approver approves them
+	if c.FIPSMode {
+		approvers = append(approvers, csr.NewFIPSCSRApprover())
+	}
+	approvers = append(approvers, c.CSRApprovers...)
+	if features.DefaultHubMutableFeatureGate.Enabled(features.ReverseTunnelBootstrap) {
+		approvers = append(approvers, csr.NewReverseTunnelCSRApprover(c.kubeClient, c.ReverseTunnelSignerName))
+	}
+	// the csrs of the clusters out of the scope are left to the other hub controllers
+	var csrClusterInformer clusterv1informer.ManagedClusterInformer
+	if c.clusterScope != nil {
+		csrClusterInformer = c.scopedClusterInformers.Cluster().V1().ManagedClusters()
+	}
+	return []factory.Controller{csr.NewScopedCSRApprovingController(
+		c.kubeClient,
+		c.clusterCSRInformers.Certificates().V1().CertificateSigningRequests(),
+		csrClusterInformer,
+		c.addOnInformers.Addon().V1alpha1().ClusterManagementAddOns(),
+		csr.ApprovalPolicy(c.CSRApprovalPolicy),
+		c.recorder,
+		approvers...,
+	)}, nil
+}
+
+func newLeaseController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{lease.NewClusterLeaseController(
+		c.kubeClient,
+		c.clusterClient,
+		c.scopedClusterInformers.Cluster().V1().ManagedClusters(),
+		c.kubeInformers.Coordination().V1().Leases(),
+		ResyncInterval, //TODO: this interval time should be allowed to change from outside
+		c.recorder,
+	)}, nil
+}
+
+func newRBACFinalizerController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{rbacfinalizerdeletion.NewFinalizeController(
+		c.kubeInformers.Rbac().V1().Roles(),
+		c.kubeInformers.Rbac().V1().RoleBindings(),
+		c.kubeInformers.Core().V1().Namespaces().Lister(),
+		c.clusterInformers.Cluster().V1().ManagedClusters().Lister(),
+		c.workInformers.Work().V1().ManifestWorks().Lister(),
+		c.kubeClient.RbacV1(),
+		c.recorder,
+	)}, nil
+}
+
+func newManagedClusterSetController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{managedclusterset.NewManagedClusterSetController(
+		c.clusterClient,
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		c.clusterInformers.Cluster().V1beta1().ManagedClusterSets(),
+		c.recorder,
+	)}, nil
+}
+
+func newClusterRoleController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{clusterrole.NewManagedClusterClusterroleController(
+		c.kubeClient,
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		c.kubeInformers.Rbac().V1().ClusterRoles(),
+		c.recorder,
+	)}, nil
+}
+
+func newAddOnHealthCheckController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{addon.NewManagedClusterAddOnHealthCheckController(
+		c.addOnClient,
+		c.addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
+		c.scopedClusterInformers.Cluster().V1().ManagedClusters(),
+		c.recorder,
+	)}, nil
+}
+
+func newAddOnFeatureDiscoveryController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{addon.NewAddOnFeatureDiscoveryController(
+		c.clusterClient,
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		c.addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
+		c.recorder,
+	)}, nil
+}
+
+func newAddOnCSRCleanupController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{addon.NewAddOnCSRCleanupController(
+		c.kubeClient,
+		c.addOnClient,
+		c.addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		c.clusterCSRInformers.Certificates().V1().CertificateSigningRequests(),
+		c.recorder,
+	)}, nil
+}
+
+func newAddOnTokenServiceAccountController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{addon.NewAddOnTokenServiceAccountController(
+		c.kubeClient,
+		c.addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
+		c.OperatorNamespace,
+		c.serviceAccountName(),
+		c.recorder,
+	)}, nil
+}
+
+func newAddOnRBACController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{addon.NewAddOnRBACController(
+		c.kubeClient,
+		c.addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
+		c.recorder,
+	)}, nil
+}
+
+func newAddOnHealthAggregationController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{addon.NewAddOnHealthAggregationController(
+		c.addOnClient,
+		c.addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
+		c.scopedClusterInformers.Cluster().V1().ManagedClusters(),
+		c.kubeInformers.Coordination().V1().Leases(),
+		c.recorder,
+	)}, nil
+}
+
+func newDefaultManagedClusterSetControllers(c *hubControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultHubMutableFeatureGate.Enabled(features.DefaultClusterSet) {
+		return nil, nil
+	}
+	return []factory.Controller{
+		managedclusterset.NewDefaultManagedClusterSetController(
+			c.clusterClient.ClusterV1beta1(),
+			c.clusterInformers.Cluster().V1beta1().ManagedClusterSets(),
+			c.recorder,
+		),
+		managedclusterset.NewDefaultManagedClusterSetLabelController(
+			c.clusterClient,
+			c.scopedClusterInformers.Cluster().V1().ManagedClusters(),
+			c.recorder,
+		),
+	}, nil
+}
+
+func newWebhookConfigurationController(c *hubControllerContext) ([]factory.Controller, error) {
+	webhookPolicy := c.webhookPolicy()
+	if !webhookPolicy.IsSet() {
+		return nil, nil
+	}
+	return []factory.Controller{webhookconfig.NewWebhookConfigurationController(
+		c.kubeClient,
+		c.kubeInformers.Admissionregistration().V1().ValidatingWebhookConfigurations(),
+		c.kubeInformers.Admissionregistration().V1().MutatingWebhookConfigurations(),
+		webhookPolicy,
+		c.recorder,
+	)}, nil
+}
+
+func newWebhookServingCertificateControllers(c *hubControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultHubMutableFeatureGate.Enabled(features.WebhookServingCertRotation) {
+		return nil, nil
+	}
+	apiServiceClient, err := apiregistrationclient.NewForConfig(c.kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	crdClient, err := apiextensionsclient.NewForConfig(c.kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookServingCertController, err := webhookcert.NewWebhookServingCertController(
+		c.kubeClient,
+		c.webhookCSRInformers.Certificates(),
+		c.namespacedKubeInformers.Core().V1().Secrets(),
+		c.OperatorNamespace,
+		c.recorder,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return []factory.Controller{
+		webhookcert.NewWebhookServingSignerController(
+			c.kubeClient,
+			c.webhookCSRInformers.Certificates().V1().CertificateSigningRequests(),
+			c.namespacedKubeInformers.Core().V1().Secrets(),
+			c.OperatorNamespace,
+			c.recorder,
+		),
+		webhookServingCertController,
+		webhookcert.NewWebhookCABundleController(
+			c.kubeClient,
+			apiServiceClient,
+			crdClient,
+			c.namespacedKubeInformers.Core().V1().Secrets(),
+			c.OperatorNamespace,
+			c.recorder,
+		),
+	}, nil
+}
+
+func newCertManagerSignerController(c *hubControllerContext) ([]factory.Controller, error) {
+	if len(c.CertManagerIssuer) == 0 {
+		return nil, nil
+	}
+	issuer, err := certmanager.ParseIssuerRef(c.CertManagerIssuer)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(c.kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return []factory.Controller{certmanager.NewCertManagerSignerController(
+		c.kubeClient,
+		dynamicClient,
+		c.clusterCSRInformers.Certificates().V1().CertificateSigningRequests(),
+		issuer,
+		c.OperatorNamespace,
+		c.CertManagerApproveRequests,
+		c.recorder,
+	)}, nil
+}
+
+func newRegistrationTokenController(c *hubControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultHubMutableFeatureGate.Enabled(features.TokenRegistration) {
+		return nil, nil
+	}
+	return []factory.Controller{registrationtoken.NewRegistrationTokenController(
+		c.kubeClient,
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		c.RegistrationTokenBootstrapGroups,
+		c.OperatorNamespace,
+		c.serviceAccountName(),
+		c.recorder,
+	)}, nil
+}
+
+func newAWSIAMRoleMappingController(c *hubControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultHubMutableFeatureGate.Enabled(features.AWSIAMRegistration) {
+		return nil, nil
+	}
+	return []factory.Controller{awsiam.NewAWSIAMRoleMappingController(
+		c.kubeClient,
+		c.clusterClient,
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		c.recorder,
+	)}, nil
+}
+
+func newClusterEventsConsumerController(c *hubControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultHubMutableFeatureGate.Enabled(features.CloudEventsTransport) || len(c.CloudEventsBrokerAddress) == 0 {
+		return nil, nil
+	}
+	tlsConfig, err := cloudevents.NewMQTTTLSConfig(c.CloudEventsCAFile, c.CloudEventsClientCertFile, c.CloudEventsClientKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return []factory.Controller{clusterevents.NewConsumerController(
+		c.kubeClient,
+		c.clusterClient,
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		cloudevents.NewMQTTClient(cloudevents.MQTTConfig{
+			BrokerAddress: c.CloudEventsBrokerAddress,
+			ClientID:      "open-cluster-management-registration-hub",
+			TLSConfig:     tlsConfig,
+		}),
+		c.recorder,
+	)}, nil
+}
+
+func newClusterProfileController(c *hubControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultHubMutableFeatureGate.Enabled(features.ClusterProfile) {
+		return nil, nil
+	}
+	dynamicClient, err := dynamic.NewForConfig(c.kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return []factory.Controller{clusterprofile.NewClusterProfileController(
+		c.clusterClient,
+		dynamicClient,
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		c.ClusterProfileNamespace,
+		c.ImportClusterProfiles,
+		c.recorder,
+	)}, nil
+}
+
+func newClusterAPIImportController(c *hubControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultHubMutableFeatureGate.Enabled(features.ClusterAPIImport) {
+		return nil, nil
+	}
+	dynamicClient, err := dynamic.NewForConfig(c.kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return []factory.Controller{clusterapi.NewClusterAPIImportController(
+		c.clusterClient,
+		dynamicClient,
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		c.ClusterAPIBootstrapDelivery,
+		c.recorder,
+	)}, nil
+}
+
+func newInventoryExportController(c *hubControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultHubMutableFeatureGate.Enabled(features.InventoryExport) ||
+		(c.InventorySink == nil && len(c.InventoryExportSink) == 0) {
+		return nil, nil
+	}
+	sink := c.InventorySink
+	if sink == nil {
+		var err error
+		sink, err = inventory.NewSink(inventory.SinkConfig{
+			URL:       c.InventoryExportSink,
+			CAFile:    c.InventoryExportCAFile,
+			TokenFile: c.InventoryExportTokenFile,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return []factory.Controller{inventory.NewInventoryExportController(
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		sink,
+		c.InventoryExportInterval,
+		c.recorder,
+	)}, nil
+}
+
+func newHubCARotationController(c *hubControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultHubMutableFeatureGate.Enabled(features.HubCARotation) {
+		return nil, nil
+	}
+	return []factory.Controller{hubca.NewHubCARotationController(
+		c.kubeClient,
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		c.namespacedKubeInformers.Core().V1().ConfigMaps(),
+		c.OperatorNamespace,
+		c.recorder,
+	)}, nil
+}
+
+func newBootstrapTokenController(c *hubControllerContext) ([]factory.Controller, error) {
+	if c.BootstrapTokenRotationInterval <= 0 {
+		return nil, nil
+	}
+	return []factory.Controller{bootstraptoken.NewBootstrapTokenController(
+		c.kubeClient,
+		c.namespacedKubeInformers.Core().V1().Secrets(),
+		c.namespacedKubeInformers.Core().V1().ConfigMaps(),
+		c.OperatorNamespace,
+		c.BootstrapHubServer,
+		c.BootstrapTokenRotationInterval,
+		c.BootstrapTokenGenerations,
+		c.recorder,
+	)}, nil
+}
+
+// newClusterArchiveController creates the controller even if the archival is disabled, so the finalizer is
+// removed from the clusters archived before and their deletion is not blocked. It manages the whole fleet, so the
+// clusters outside the scope of the hub controller are archived and released as well.
+func newClusterArchiveController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{archive.NewClusterArchiveController(
+		c.kubeClient,
+		c.clusterClient,
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		c.OperatorNamespace,
+		c.ArchiveDetachedClusters,
+		c.ClusterArchiveRetention,
+		c.recorder,
+	)}, nil
+}
+
+// newClusterRenameController creates the controller renaming the clusters of the whole fleet
+func newClusterRenameController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{rename.NewClusterRenameController(
+		c.clusterClient,
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		c.recorder,
+	)}, nil
+}
+
+// newAgentConfigController creates the controller propagating the agent config to the clusters of the whole fleet
+func newAgentConfigController(c *hubControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultHubMutableFeatureGate.Enabled(features.AgentConfigPropagation) {
+		return nil, nil
+	}
+	return []factory.Controller{agentconfig.NewAgentConfigController(
+		c.kubeClient,
+		c.clusterClient,
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		c.namespacedKubeInformers.Core().V1().ConfigMaps(),
+		c.OperatorNamespace,
+		c.recorder,
+	)}, nil
+}
+
+func newBootstrapCleanupController(c *hubControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{importer.NewBootstrapCleanupController(
+		c.kubeClient,
+		c.clusterInformers.Cluster().V1().ManagedClusters(),
+		c.recorder,
+	)}, nil
+}
@@ -0,0 +1,326 @@
+package csr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+
+	"open-cluster-management.io/registration/pkg/fips"
+	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/hub/user"
+)
+
+// Decision is the decision an Approver makes on a csr
+type Decision string
+
+const (
+	// DecisionApprove approves the csr
+	DecisionApprove Decision = "Approve"
+	// DecisionDeny denies the csr
+	DecisionDeny Decision = "Deny"
+	// DecisionSkip leaves the csr to the next approver
+	DecisionSkip Decision = "Skip"
+)
+
+// ApprovalResult is the result of an Approver. The Reason and Message are set on the approved/denied condition
+// of the csr, and they are ignored if the decision is DecisionSkip.
+type ApprovalResult struct {
+	Decision Decision
+	Reason   string
+	Message  string
+}
+
+// Skip is the result of an Approver which has no opinion on the csr
+var Skip = ApprovalResult{Decision: DecisionSkip}
+
+// Approver makes the approval decision on a pending csr. The approvers of the csr approving controller are
+// evaluated in order, and the first decision other than DecisionSkip is applied to the csr. The csr is left
+// pending if all of the approvers skip it. An error fails the evaluation, and the csr is evaluated again later.
+type Approver interface {
+	Approve(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (ApprovalResult, error)
+}
+
+// ApproverFunc is a function which implements Approver
+type ApproverFunc func(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (ApprovalResult, error)
+
+// Approve calls f(ctx, csr)
+func (f ApproverFunc) Approve(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (ApprovalResult, error) {
+	return f(ctx, csr)
+}
+
+// ApprovalPolicy is the policy of a csr approving controller on the csrs which are not decided by the given
+// approvers
+type ApprovalPolicy string
+
+const (
+	// ApprovalPolicyAuto approves the csrs with the built-in approvers
+	ApprovalPolicyAuto ApprovalPolicy = "Auto"
+	// ApprovalPolicyManual leaves the csrs pending, so they are approved or denied by the cluster admin
+	ApprovalPolicyManual ApprovalPolicy = "Manual"
+)
+
+// ApprovalPolicies are the supported approval policies
+var ApprovalPolicies = sets.NewString(string(ApprovalPolicyAuto), string(ApprovalPolicyManual))
+
+// defaultApprovers returns the built-in approvers, which approve the addon csrs allowed by the hub cluster admin,
+// and the renewal csrs of the accepted managed clusters.
+func defaultApprovers(kubeClient kubernetes.Interface, clusterManagementAddOnLister addonlisterv1alpha1.ClusterManagementAddOnLister) []Approver {
+	return []Approver{
+		&addOnCSRApprover{clusterManagementAddOnLister: clusterManagementAddOnLister},
+		&renewalCSRApprover{kubeClient: kubeClient},
+	}
+}
+
+// addOnCSRApprover approves the csrs of the addons whose ClusterManagementAddOns are annotated with
+// helpers.AddOnRegistrationAutoApproveAnnotation by the hub cluster admin. No addon csr is approved if the lister
+// is nil.
+type addOnCSRApprover struct {
+	clusterManagementAddOnLister addonlisterv1alpha1.ClusterManagementAddOnLister
+}
+
+func (a *addOnCSRApprover) Approve(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (ApprovalResult, error) {
+	if _, ok := csr.Labels[addOnNameLabel]; !ok || a.clusterManagementAddOnLister == nil {
+		return Skip, nil
+	}
+
+	allowed, err := a.isAutoApprovedAddOnCSR(ctx, csr)
+	if err != nil {
+		return Skip, err
+	}
+	if !allowed {
+		klog.FromContext(ctx).V(helpers.LogLevelDebug).Info("Addon csr cannot be auto approved",
+			helpers.LogKeyCluster, csr.Labels[spokeClusterNameLabel], helpers.LogKeyResource, klog.KObj(csr))
+		return Skip, nil
+	}
+	return ApprovalResult{
+		Decision: DecisionApprove,
+		Reason:   "AutoApprovedByHubCSRApprovingController",
+		Message:  "Auto approving addon agent certificate allowed by the cluster management addon.",
+	}, nil
+}
+
+// isAutoApprovedAddOnCSR checks whether an addon csr can be auto approved. An addon csr is auto approved if
+// 1. the ClusterManagementAddOn of the addon is annotated to auto approve its csrs.
+// 2. the csr is created by the registration agent of the managed cluster with the kube-apiserver-client signer.
+// 3. the common name and all of the organizations in the csr request are the identities of the addon on the
+// managed cluster, which are the default group of the addon or prefixed with it.
+func (a *addOnCSRApprover) isAutoApprovedAddOnCSR(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (bool, error) {
+	clusterName := csr.Labels[spokeClusterNameLabel]
+	addOnName := csr.Labels[addOnNameLabel]
+	if len(clusterName) == 0 || len(addOnName) == 0 {
+		return false, nil
+	}
+
+	if csr.Spec.SignerName != certificatesv1.KubeAPIServerClientSignerName {
+		return false, nil
+	}
+
+	// the csr must be created by the registration agent of the managed cluster
+	if !strings.HasPrefix(csr.Spec.Username, fmt.Sprintf("%s%s:", user.SubjectPrefix, clusterName)) {
+		return false, nil
+	}
+
+	clusterManagementAddOn, err := a.clusterManagementAddOnLister.Get(addOnName)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if clusterManagementAddOn.Annotations[helpers.AddOnRegistrationAutoApproveAnnotation] != "true" {
+		return false, nil
+	}
+
+	x509cr, err := parseCSRRequest(csr)
+	if err != nil {
+		klog.FromContext(ctx).V(helpers.LogLevelDebug).Info("CSR was not recognized",
+			helpers.LogKeyResource, klog.KObj(csr), helpers.LogKeyReason, err.Error())
+		return false, nil
+	}
+
+	defaultGroup := helpers.AddOnDefaultGroup(clusterName, addOnName)
+	if !isAddOnIdentity(x509cr.Subject.CommonName, defaultGroup) || len(x509cr.Subject.Organization) == 0 {
+		return false, nil
+	}
+	for _, group := range x509cr.Subject.Organization {
+		if !isAddOnIdentity(group, defaultGroup) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isAddOnIdentity returns true if the name is the default group of an addon or prefixed with it
+func isAddOnIdentity(name, defaultGroup string) bool {
+	return name == defaultGroup || strings.HasPrefix(name, defaultGroup+":")
+}
+
+// renewalCSRApprover approves the renewal csrs of the accepted managed clusters.
+type renewalCSRApprover struct {
+	kubeClient kubernetes.Interface
+}
+
+func (a *renewalCSRApprover) Approve(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (ApprovalResult, error) {
+	// the addon csrs are handled by the addOnCSRApprover
+	if _, ok := csr.Labels[addOnNameLabel]; ok {
+		return Skip, nil
+	}
+
+	// Check whether current csr is a renewal spoker cluster csr.
+	if !isSpokeClusterClientCertRenewal(ctx, csr) {
+		klog.FromContext(ctx).V(helpers.LogLevelDebug).Info("CSR was not recognized as a renewal csr of a managed cluster",
+			helpers.LogKeyResource, klog.KObj(csr))
+		return Skip, nil
+	}
+
+	// Authorize whether the current spoke agent has been authorized to renew its csr.
+	allowed, err := a.authorize(ctx, csr)
+	if err != nil {
+		return Skip, err
+	}
+	if !allowed {
+		//TODO find a way to avoid looking at this CSR again.
+		klog.FromContext(ctx).V(helpers.LogLevelDebug).Info("Managed cluster csr cannot be auto approved",
+			helpers.LogKeyCluster, csr.Labels[spokeClusterNameLabel], helpers.LogKeyResource, klog.KObj(csr),
+			helpers.LogKeyReason, "subject access review was not approved")
+		return Skip, nil
+	}
+
+	return ApprovalResult{
+		Decision: DecisionApprove,
+		Reason:   "AutoApprovedByHubCSRApprovingController",
+		Message:  "Auto approving Managed cluster agent certificate after SubjectAccessReview.",
+	}, nil
+}
+
+// Using SubjectAccessReview API to check whether a spoke agent has been authorized to renew its csr,
+// a spoke agent is authorized after its spoke cluster is accepted by hub cluster admin.
+func (a *renewalCSRApprover) authorize(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (bool, error) {
+	return authorizeRenewal(ctx, a.kubeClient, csr)
+}
+
+// authorizeRenewal checks whether the requester of the csr is allowed to renew the client certificates of its
+// managed cluster with a SubjectAccessReview
+func authorizeRenewal(ctx context.Context, kubeClient kubernetes.Interface, csr *certificatesv1.CertificateSigningRequest) (bool, error) {
+	extra := make(map[string]authorizationv1.ExtraValue)
+	for k, v := range csr.Spec.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   csr.Spec.Username,
+			UID:    csr.Spec.UID,
+			Groups: csr.Spec.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Group:       "register.open-cluster-management.io",
+				Resource:    "managedclusters",
+				Verb:        "renew",
+				Subresource: "clientcertificates",
+			},
+		},
+	}
+	sar, err := kubeClient.AuthorizationV1().SubjectAccessReviews().Create(ctx, sar, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return sar.Status.Allowed, nil
+}
+
+// NewReverseTunnelCSRApprover returns an Approver which approves the csrs of the reverse tunnel agent credential
+// requested by the registration agents of the accepted managed clusters with the signer. The csrs are signed by the
+// signer of the reverse tunnel, e.g. cluster-proxy, instead of the hub kube-apiserver.
+func NewReverseTunnelCSRApprover(kubeClient kubernetes.Interface, signerName string) Approver {
+	return &reverseTunnelCSRApprover{kubeClient: kubeClient, signerName: signerName}
+}
+
+// reverseTunnelCSRApprover approves the csrs of the reverse tunnel agent credential. A csr is approved if
+// 1. it is created by the registration agent of the managed cluster with the signer of the reverse tunnel.
+// 2. the subject in the csr request is the reverse tunnel user and group of the managed cluster.
+// 3. the registration agent is allowed to renew its client certificate, which means the cluster is accepted.
+type reverseTunnelCSRApprover struct {
+	kubeClient kubernetes.Interface
+	signerName string
+}
+
+func (a *reverseTunnelCSRApprover) Approve(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (ApprovalResult, error) {
+	if _, ok := csr.Labels[helpers.ReverseTunnelLabel]; !ok {
+		return Skip, nil
+	}
+
+	clusterName := csr.Labels[spokeClusterNameLabel]
+	if len(clusterName) == 0 || csr.Spec.SignerName != a.signerName {
+		return Skip, nil
+	}
+
+	// the csr must be created by the registration agent of the managed cluster
+	if !strings.HasPrefix(csr.Spec.Username, fmt.Sprintf("%s%s:", user.SubjectPrefix, clusterName)) {
+		return Skip, nil
+	}
+
+	x509cr, err := parseCSRRequest(csr)
+	if err != nil {
+		klog.FromContext(ctx).V(helpers.LogLevelDebug).Info("CSR was not recognized",
+			helpers.LogKeyResource, klog.KObj(csr), helpers.LogKeyReason, err.Error())
+		return Skip, nil
+	}
+	group := helpers.ReverseTunnelGroup(clusterName)
+	if !strings.HasPrefix(x509cr.Subject.CommonName, group+":agent:") ||
+		!sets.NewString(group).Equal(sets.NewString(x509cr.Subject.Organization...)) {
+		return Skip, nil
+	}
+
+	allowed, err := authorizeRenewal(ctx, a.kubeClient, csr)
+	if err != nil {
+		return Skip, err
+	}
+	if !allowed {
+		klog.FromContext(ctx).V(helpers.LogLevelDebug).Info("Reverse tunnel csr cannot be auto approved",
+			helpers.LogKeyCluster, clusterName, helpers.LogKeyResource, klog.KObj(csr),
+			helpers.LogKeyReason, "subject access review was not approved")
+		return Skip, nil
+	}
+
+	return ApprovalResult{
+		Decision: DecisionApprove,
+		Reason:   "AutoApprovedByHubCSRApprovingController",
+		Message:  "Auto approving reverse tunnel agent certificate of the accepted managed cluster.",
+	}, nil
+}
+
+// NewFIPSCSRApprover returns an Approver which denies the csrs of the managed clusters whose keys are not
+// FIPS-approved, e.g. ed25519 keys or short RSA keys, so that no such client certificate is issued in the FIPS
+// mode. The other csrs are left to the next approvers.
+func NewFIPSCSRApprover() Approver {
+	return ApproverFunc(func(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (ApprovalResult, error) {
+		if len(csr.Labels[spokeClusterNameLabel]) == 0 {
+			return Skip, nil
+		}
+
+		x509cr, err := parseCSRRequest(csr)
+		if err != nil {
+			klog.FromContext(ctx).V(helpers.LogLevelDebug).Info("CSR was not recognized",
+				helpers.LogKeyResource, klog.KObj(csr), helpers.LogKeyReason, err.Error())
+			return Skip, nil
+		}
+		if err := fips.ValidatePublicKey(x509cr.PublicKey); err != nil {
+			return ApprovalResult{
+				Decision: DecisionDeny,
+				Reason:   "FIPSNonCompliantKey",
+				Message:  fmt.Sprintf("The key of the csr is not allowed in the FIPS mode: %v.", err),
+			}, nil
+		}
+		return Skip, nil
+	})
+}
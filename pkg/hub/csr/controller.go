@@ -6,10 +6,11 @@ import (
 	"encoding/pem"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
-	authorizationv1 "k8s.io/api/authorization/v1"
 	certificatesv1 "k8s.io/api/certificates/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -22,139 +23,256 @@ import (
 	certificateslisters "k8s.io/client-go/listers/certificates/v1"
 	"k8s.io/klog/v2"
 
+	addoninformerv1alpha1 "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+
+	"open-cluster-management.io/registration/pkg/debug"
+	"open-cluster-management.io/registration/pkg/health"
 	"open-cluster-management.io/registration/pkg/helpers"
 	"open-cluster-management.io/registration/pkg/hub/user"
 )
 
+const controllerName = "CSRApprovingController"
+
 const (
 	spokeClusterNameLabel = "open-cluster-management.io/cluster-name"
+	addOnNameLabel        = "open-cluster-management.io/addon-name"
 )
 
 // csrApprovingController auto approve the renewal CertificateSigningRequests for an accepted spoke cluster on the hub.
+// It also auto approves the CertificateSigningRequests of addons whose ClusterManagementAddOns allow auto approving.
+// The decisions are made by a list of approvers, which are evaluated in order.
 type csrApprovingController struct {
 	kubeClient    kubernetes.Interface
 	csrLister     certificateslisters.CertificateSigningRequestLister
+	approvers     []Approver
 	eventRecorder events.Recorder
+
+	// clusterLister lists the managed clusters in the scope of the controller, the csrs of the other clusters are
+	// ignored. The csrs of all of the clusters are decided if it is nil.
+	clusterLister clusterv1listers.ManagedClusterLister
+
+	// lock guards the decisions and the csr phases, which are shared by the workers of the controller
+	lock sync.Mutex
+	// decisions caches the approval decisions made by the controller on the existing csrs for debugging
+	decisions map[string]ApprovalResult
+	// csrPhases are the last observed phases of the csrs of the managed clusters, they are used to record the
+	// lifecycle durations of the csrs
+	csrPhases map[string]csrPhase
+}
+
+// NewCSRApprovingController creates a new csr approving controller. The given approvers are evaluated in order
+// before the built-in approvers, so they are able to deny or approve the csrs which would be left pending or
+// approved by the built-in ones.
+func NewCSRApprovingController(
+	kubeClient kubernetes.Interface,
+	csrInformer certificatesinformers.CertificateSigningRequestInformer,
+	recorder events.Recorder,
+	approvers ...Approver) factory.Controller {
+	return NewScopedCSRApprovingController(kubeClient, csrInformer, nil, nil, ApprovalPolicyAuto, recorder, approvers...)
 }
 
-// NewCSRApprovingController creates a new csr approving controller
-func NewCSRApprovingController(kubeClient kubernetes.Interface, csrInformer certificatesinformers.CertificateSigningRequestInformer, recorder events.Recorder) factory.Controller {
+// NewScopedCSRApprovingController creates a csr approving controller which only decides on the csrs of the managed
+// clusters in the cluster informer, so that several hub controllers managing their own slices of the fleet approve
+// the csrs with their own policies. The csrs of all of the clusters are decided if the cluster informer is nil. The
+// built-in approvers are evaluated after the given approvers only if the policy is ApprovalPolicyAuto. The addon csrs
+// are approved by the built-in approvers with the ClusterManagementAddOns in the informer, and none of them is
+// approved if it is nil.
+func NewScopedCSRApprovingController(
+	kubeClient kubernetes.Interface,
+	csrInformer certificatesinformers.CertificateSigningRequestInformer,
+	clusterInformer clusterv1informer.ManagedClusterInformer,
+	clusterManagementAddOnInformer addoninformerv1alpha1.ClusterManagementAddOnInformer,
+	policy ApprovalPolicy,
+	recorder events.Recorder,
+	approvers ...Approver) factory.Controller {
+	informers := []factory.Informer{}
+	var clusterLister clusterv1listers.ManagedClusterLister
+	if clusterInformer != nil {
+		clusterLister = clusterInformer.Lister()
+		informers = append(informers, clusterInformer.Informer())
+	}
+	var clusterManagementAddOnLister addonlisterv1alpha1.ClusterManagementAddOnLister
+	if clusterManagementAddOnInformer != nil {
+		clusterManagementAddOnLister = clusterManagementAddOnInformer.Lister()
+		informers = append(informers, clusterManagementAddOnInformer.Informer())
+	}
+	c := newCSRApprovingController(kubeClient, csrInformer.Lister(), clusterLister, clusterManagementAddOnLister,
+		policy, recorder, approvers...)
+	// the pending csrs are synced with a priority queue, so the csrs of the joining clusters are not delayed behind
+	// the approved csrs listed after the hub restarts
+	return health.NewPriorityController(controllerName, recorder, isPending,
+		func(f *factory.Factory, filter factory.EventFilterFunc) *factory.Factory {
+			return f.WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+				accessor, _ := meta.Accessor(obj)
+				return accessor.GetName()
+			}, filter, csrInformer.Informer()).WithBareInformers(informers...)
+		}, c.sync, nil)
+}
+
+func newCSRApprovingController(
+	kubeClient kubernetes.Interface,
+	csrLister certificateslisters.CertificateSigningRequestLister,
+	clusterLister clusterv1listers.ManagedClusterLister,
+	clusterManagementAddOnLister addonlisterv1alpha1.ClusterManagementAddOnLister,
+	policy ApprovalPolicy,
+	recorder events.Recorder,
+	approvers ...Approver) *csrApprovingController {
 	c := &csrApprovingController{
 		kubeClient:    kubeClient,
-		csrLister:     csrInformer.Lister(),
+		csrLister:     csrLister,
+		clusterLister: clusterLister,
+		approvers:     append([]Approver{}, approvers...),
 		eventRecorder: recorder.WithComponentSuffix("csr-approving-controller"),
+		decisions:     map[string]ApprovalResult{},
+		csrPhases:     map[string]csrPhase{},
+	}
+	if policy != ApprovalPolicyManual {
+		c.approvers = append(c.approvers, defaultApprovers(kubeClient, clusterManagementAddOnLister)...)
 	}
-	return factory.New().
-		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
-			accessor, _ := meta.Accessor(obj)
-			return accessor.GetName()
-		}, csrInformer.Informer()).
-		WithSync(c.sync).
-		ToController("CSRApprovingController", recorder)
+	return c
+}
+
+// isPending returns true if the csr is neither approved nor denied
+func isPending(obj interface{}) bool {
+	csr, ok := obj.(*certificatesv1.CertificateSigningRequest)
+	if !ok {
+		return false
+	}
+	return !helpers.IsCSRInTerminalState(&csr.Status)
 }
 
 func (c *csrApprovingController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
 	csrName := syncCtx.QueueKey()
-	klog.V(4).Infof("Reconciling CertificateSigningRequests %q", csrName)
+	logger := helpers.ControllerLogger(ctx, controllerName)
+	logger.V(helpers.LogLevelDebug).Info("Reconciling CertificateSigningRequest", helpers.LogKeyResource, klog.KRef("", csrName))
+	// the approvers log with the logger of the controller
+	ctx = klog.NewContext(ctx, logger)
+
 	csr, err := c.csrLister.Get(csrName)
 	if errors.IsNotFound(err) {
+		c.forgetDecision(csrName)
 		return nil
 	}
 	if err != nil {
 		return err
 	}
+	if !c.inScope(csr) {
+		logger.V(helpers.LogLevelDebug).Info("CSR of the managed cluster out of the scope is ignored",
+			helpers.LogKeyCluster, csr.Labels[spokeClusterNameLabel], helpers.LogKeyResource, klog.KObj(csr))
+		return nil
+	}
 
+	c.observeCSRPhase(csr, time.Now())
 	csr = csr.DeepCopy()
 	// Current csr is in terminal state, do nothing.
 	if helpers.IsCSRInTerminalState(&csr.Status) {
 		return nil
 	}
 
-	// Check whether current csr is a renewal spoker cluster csr.
-	isRenewal := isSpokeClusterClientCertRenewal(csr)
-	if !isRenewal {
-		klog.V(4).Infof("CSR %q was not recognized", csr.Name)
-		return nil
-	}
+	health.EnterPhase(ctx, "approve")
+	for _, approver := range c.approvers {
+		result, err := approver.Approve(ctx, csr)
+		if err != nil {
+			return err
+		}
 
-	// Authorize whether the current spoke agent has been authorized to renew its csr.
-	allowed, err := c.authorize(ctx, csr)
-	if err != nil {
-		return err
+		switch result.Decision {
+		case DecisionApprove:
+			return c.updateApproval(ctx, csr, certificatesv1.CertificateApproved, result,
+				"CSRAutoApproved", "csr %q is auto approved by hub csr controller")
+		case DecisionDeny:
+			return c.updateApproval(ctx, csr, certificatesv1.CertificateDenied, result,
+				"CSRDenied", "csr %q is denied by hub csr controller")
+		case DecisionSkip, "":
+			continue
+		default:
+			return fmt.Errorf("unknown decision %q on csr %q", result.Decision, csr.Name)
+		}
 	}
-	if !allowed {
-		//TODO find a way to avoid looking at this CSR again.
-		klog.V(4).Infof("Managed cluster csr %q cannont be auto approved due to subject access review was not approved", csr.Name)
-		return nil
+
+	logger.V(helpers.LogLevelDebug).Info("CSR is not approved or denied by any approver", helpers.LogKeyResource, klog.KObj(csr))
+	return nil
+}
+
+// inScope returns true if the managed cluster of the csr is in the scope of the controller
+func (c *csrApprovingController) inScope(csr *certificatesv1.CertificateSigningRequest) bool {
+	if c.clusterLister == nil {
+		return true
 	}
+	_, err := c.clusterLister.Get(csr.Labels[spokeClusterNameLabel])
+	return err == nil
+}
 
-	// Auto approve the spoke cluster csr
+func (c *csrApprovingController) updateApproval(ctx context.Context, csr *certificatesv1.CertificateSigningRequest,
+	conditionType certificatesv1.RequestConditionType, result ApprovalResult, eventReason, eventMessageFmt string) error {
+	health.EnterPhase(ctx, "update approval")
 	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
-		Type:    certificatesv1.CertificateApproved,
+		Type:    conditionType,
 		Status:  corev1.ConditionTrue,
-		Reason:  "AutoApprovedByHubCSRApprovingController",
-		Message: "Auto approving Managed cluster agent certificate after SubjectAccessReview.",
+		Reason:  result.Reason,
+		Message: result.Message,
+		// the approval time is used to record the lifecycle durations of the csr
+		LastUpdateTime: metav1.Now(),
 	})
-	_, err = c.kubeClient.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, csr.Name, csr, metav1.UpdateOptions{})
+	_, err := c.kubeClient.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, csr.Name, csr, metav1.UpdateOptions{})
 	if err != nil {
 		return err
 	}
-	c.eventRecorder.Eventf("ManagedClusterCSRAutoApproved", "spoke cluster csr %q is auto approved by hub csr controller", csr.Name)
+	c.eventRecorder.Eventf(eventReason, eventMessageFmt, csr.Name)
+	c.cacheDecision(csr.Name, result)
 	return nil
 }
 
-// Using SubjectAccessReview API to check whether a spoke agent has been authorized to renew its csr,
-// a spoke agent is authorized after its spoke cluster is accepted by hub cluster admin.
-func (c *csrApprovingController) authorize(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (bool, error) {
-	extra := make(map[string]authorizationv1.ExtraValue)
-	for k, v := range csr.Spec.Extra {
-		extra[k] = authorizationv1.ExtraValue(v)
-	}
-
-	sar := &authorizationv1.SubjectAccessReview{
-		Spec: authorizationv1.SubjectAccessReviewSpec{
-			User:   csr.Spec.Username,
-			UID:    csr.Spec.UID,
-			Groups: csr.Spec.Groups,
-			Extra:  extra,
-			ResourceAttributes: &authorizationv1.ResourceAttributes{
-				Group:       "register.open-cluster-management.io",
-				Resource:    "managedclusters",
-				Verb:        "renew",
-				Subresource: "clientcertificates",
-			},
-		},
-	}
-	sar, err := c.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(ctx, sar, metav1.CreateOptions{})
-	if err != nil {
-		return false, err
+// cacheDecision caches the decision on a csr and records a snapshot of the cached decisions for debugging
+func (c *csrApprovingController) cacheDecision(csrName string, result ApprovalResult) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.decisions[csrName] = result
+	c.recordDecisions()
+}
+
+// forgetDecision removes the decision and the observed phase of a deleted csr from the cache
+func (c *csrApprovingController) forgetDecision(csrName string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.csrPhases, csrName)
+	if _, ok := c.decisions[csrName]; !ok {
+		return
+	}
+	delete(c.decisions, csrName)
+	c.recordDecisions()
+}
+
+// recordDecisions records a snapshot of the cached decisions, it is called with the lock held
+func (c *csrApprovingController) recordDecisions() {
+	decisions := make(map[string]ApprovalResult, len(c.decisions))
+	for name, result := range c.decisions {
+		decisions[name] = result
 	}
-	return sar.Status.Allowed, nil
+	debug.RecordState(controllerName, decisions)
 }
 
 // To check a renewal managed cluster csr, we check
-// 1. if the signer name in csr request is valid.
+// 1. if the signer name in csr request is valid, it is either the kube-apiserver-client signer or the cert-manager signer.
 // 2. if organization field and commonName field in csr request is valid.
 // 3. if user name in csr is the same as commonName field in csr request.
-func isSpokeClusterClientCertRenewal(csr *certificatesv1.CertificateSigningRequest) bool {
+func isSpokeClusterClientCertRenewal(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) bool {
 	spokeClusterName, existed := csr.Labels[spokeClusterNameLabel]
 	if !existed {
 		return false
 	}
 
-	if csr.Spec.SignerName != certificatesv1.KubeAPIServerClientSignerName {
-		return false
-	}
-
-	block, _ := pem.Decode(csr.Spec.Request)
-	if block == nil || block.Type != "CERTIFICATE REQUEST" {
-		klog.V(4).Infof("csr %q was not recognized: PEM block type is not CERTIFICATE REQUEST", csr.Name)
+	if !helpers.IsClusterClientSignerName(csr.Spec.SignerName) {
 		return false
 	}
 
-	x509cr, err := x509.ParseCertificateRequest(block.Bytes)
+	x509cr, err := parseCSRRequest(csr)
 	if err != nil {
-		klog.V(4).Infof("csr %q was not recognized: %v", csr.Name, err)
+		klog.FromContext(ctx).V(helpers.LogLevelDebug).Info("CSR was not recognized",
+			helpers.LogKeyResource, klog.KObj(csr), helpers.LogKeyReason, err.Error())
 		return false
 	}
 
@@ -177,3 +295,11 @@ func isSpokeClusterClientCertRenewal(csr *certificatesv1.CertificateSigningReque
 
 	return csr.Spec.Username == x509cr.Subject.CommonName
 }
+
+func parseCSRRequest(csr *certificatesv1.CertificateSigningRequest) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(csr.Spec.Request)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("PEM block type is not CERTIFICATE REQUEST")
+	}
+	return x509.ParseCertificateRequest(block.Bytes)
+}
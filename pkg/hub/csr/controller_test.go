@@ -2,17 +2,33 @@ package csr
 
 import (
 	"context"
+	"crypto/ed25519"
+	cryptorand "crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
 	"testing"
 	"time"
 
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 	"open-cluster-management.io/registration/pkg/hub/user"
 
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+
 	authorizationv1 "k8s.io/api/authorization/v1"
 	certificatesv1 "k8s.io/api/certificates/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/informers"
@@ -30,13 +46,47 @@ var (
 		Username:     user.SubjectPrefix + "managedcluster1:spokeagent1",
 		ReqBlockType: "CERTIFICATE REQUEST",
 	}
+
+	addOnCSR = testinghelpers.CSRHolder{
+		Name: "testcsr",
+		Labels: map[string]string{
+			"open-cluster-management.io/cluster-name": "managedcluster1",
+			"open-cluster-management.io/addon-name":   "addon1",
+		},
+		SignerName:   certificatesv1.KubeAPIServerClientSignerName,
+		CN:           helpers.AddOnDefaultGroup("managedcluster1", "addon1") + ":agent:addon1",
+		Orgs:         []string{helpers.AddOnDefaultGroup("managedcluster1", "addon1")},
+		Username:     user.SubjectPrefix + "managedcluster1:spokeagent1",
+		ReqBlockType: "CERTIFICATE REQUEST",
+	}
+
+	autoApprovedAddOn = &addonv1alpha1.ClusterManagementAddOn{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "addon1",
+			Annotations: map[string]string{helpers.AddOnRegistrationAutoApproveAnnotation: "true"},
+		},
+	}
 )
 
+func newClusterManagementAddOnLister(t *testing.T, addOns ...runtime.Object) addonlisterv1alpha1.ClusterManagementAddOnLister {
+	addOnInformerFactory := addoninformers.NewSharedInformerFactory(addonfake.NewSimpleClientset(), 3*time.Minute)
+	addOnStore := addOnInformerFactory.Addon().V1alpha1().ClusterManagementAddOns().Informer().GetStore()
+	for _, addOn := range addOns {
+		if err := addOnStore.Add(addOn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return addOnInformerFactory.Addon().V1alpha1().ClusterManagementAddOns().Lister()
+}
+
 func TestSync(t *testing.T) {
 	cases := []struct {
 		name                 string
 		startingCSRs         []runtime.Object
+		addOns               []runtime.Object
 		autoApprovingAllowed bool
+		approvers            []Approver
+		expectedErr          string
 		validateActions      func(t *testing.T, actions []clienttesting.Action)
 	}{
 		{
@@ -123,6 +173,131 @@ func TestSync(t *testing.T) {
 				testinghelpers.AssertCSRCondition(t, actual.(*certificatesv1.CertificateSigningRequest).Status.Conditions, expectedCondition)
 			},
 		},
+		{
+			name:         "addon csr not allowed by the cluster management addon",
+			startingCSRs: []runtime.Object{testinghelpers.NewCSR(addOnCSR)},
+			addOns:       []runtime.Object{&addonv1alpha1.ClusterManagementAddOn{ObjectMeta: metav1.ObjectMeta{Name: "addon1"}}},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name: "addon csr with unexpected subject",
+			startingCSRs: []runtime.Object{testinghelpers.NewCSR(testinghelpers.CSRHolder{
+				Name:         addOnCSR.Name,
+				Labels:       addOnCSR.Labels,
+				SignerName:   addOnCSR.SignerName,
+				CN:           addOnCSR.CN,
+				Orgs:         []string{helpers.AddOnDefaultGroup("managedcluster1", "addon1"), "system:masters"},
+				Username:     addOnCSR.Username,
+				ReqBlockType: addOnCSR.ReqBlockType,
+			})},
+			addOns: []runtime.Object{autoApprovedAddOn},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name: "addon csr with the identity of another addon",
+			startingCSRs: []runtime.Object{testinghelpers.NewCSR(testinghelpers.CSRHolder{
+				Name:         addOnCSR.Name,
+				Labels:       addOnCSR.Labels,
+				SignerName:   addOnCSR.SignerName,
+				CN:           helpers.AddOnDefaultGroup("managedcluster1", "addon10") + ":agent:addon10",
+				Orgs:         []string{helpers.AddOnDefaultGroup("managedcluster1", "addon10")},
+				Username:     addOnCSR.Username,
+				ReqBlockType: addOnCSR.ReqBlockType,
+			})},
+			addOns: []runtime.Object{autoApprovedAddOn},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:         "auto approve addon csr",
+			startingCSRs: []runtime.Object{testinghelpers.NewCSR(addOnCSR)},
+			addOns:       []runtime.Object{autoApprovedAddOn},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				expectedCondition := certificatesv1.CertificateSigningRequestCondition{
+					Type:    certificatesv1.CertificateApproved,
+					Status:  corev1.ConditionTrue,
+					Reason:  "AutoApprovedByHubCSRApprovingController",
+					Message: "Auto approving addon agent certificate allowed by the cluster management addon.",
+				}
+				testinghelpers.AssertActions(t, actions, "update")
+				actual := actions[0].(clienttesting.UpdateActionImpl).Object
+				testinghelpers.AssertCSRCondition(t, actual.(*certificatesv1.CertificateSigningRequest).Status.Conditions, expectedCondition)
+			},
+		},
+		{
+			name:         "deny a csr by a custom approver",
+			startingCSRs: []runtime.Object{testinghelpers.NewCSR(validCSR)},
+			approvers: []Approver{
+				newDecisionApprover(Skip),
+				newDecisionApprover(ApprovalResult{Decision: DecisionDeny, Reason: "Custom", Message: "denied"}),
+				newDecisionApprover(ApprovalResult{Decision: DecisionApprove, Reason: "Custom", Message: "approved"}),
+			},
+			autoApprovingAllowed: true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				expectedCondition := certificatesv1.CertificateSigningRequestCondition{
+					Type:    certificatesv1.CertificateDenied,
+					Status:  corev1.ConditionTrue,
+					Reason:  "Custom",
+					Message: "denied",
+				}
+				testinghelpers.AssertActions(t, actions, "update")
+				actual := actions[0].(clienttesting.UpdateActionImpl).Object
+				testinghelpers.AssertCSRCondition(t, actual.(*certificatesv1.CertificateSigningRequest).Status.Conditions, expectedCondition)
+			},
+		},
+		{
+			name:         "approve a csr by a custom approver",
+			startingCSRs: []runtime.Object{testinghelpers.NewCSR(addOnCSR)},
+			approvers: []Approver{
+				newDecisionApprover(ApprovalResult{Decision: DecisionApprove, Reason: "Custom", Message: "approved"}),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				expectedCondition := certificatesv1.CertificateSigningRequestCondition{
+					Type:    certificatesv1.CertificateApproved,
+					Status:  corev1.ConditionTrue,
+					Reason:  "Custom",
+					Message: "approved",
+				}
+				testinghelpers.AssertActions(t, actions, "update")
+				actual := actions[0].(clienttesting.UpdateActionImpl).Object
+				testinghelpers.AssertCSRCondition(t, actual.(*certificatesv1.CertificateSigningRequest).Status.Conditions, expectedCondition)
+			},
+		},
+		{
+			name:                 "fall through to the built-in approvers",
+			startingCSRs:         []runtime.Object{testinghelpers.NewCSR(validCSR)},
+			approvers:            []Approver{newDecisionApprover(Skip)},
+			autoApprovingAllowed: true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "create", "update")
+			},
+		},
+		{
+			name:         "custom approver fails",
+			startingCSRs: []runtime.Object{testinghelpers.NewCSR(validCSR)},
+			approvers: []Approver{ApproverFunc(func(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (ApprovalResult, error) {
+				return Skip, fmt.Errorf("failed")
+			})},
+			autoApprovingAllowed: true,
+			expectedErr:          "failed",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:         "unknown decision",
+			startingCSRs: []runtime.Object{testinghelpers.NewCSR(validCSR)},
+			approvers:    []Approver{newDecisionApprover(ApprovalResult{Decision: "Maybe"})},
+			expectedErr:  "unknown decision \"Maybe\" on csr \"testcsr\"",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -145,17 +320,89 @@ func TestSync(t *testing.T) {
 				csrStore.Add(csr)
 			}
 
-			ctrl := &csrApprovingController{kubeClient, informerFactory.Certificates().V1().CertificateSigningRequests().Lister(), eventstesting.NewTestingEventRecorder(t)}
-			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, validCSR.Name))
-			if syncErr != nil {
-				t.Errorf("unexpected err: %v", syncErr)
+			ctrl := &csrApprovingController{
+				kubeClient:    kubeClient,
+				csrLister:     informerFactory.Certificates().V1().CertificateSigningRequests().Lister(),
+				approvers:     append(append([]Approver{}, c.approvers...), defaultApprovers(kubeClient, newClusterManagementAddOnLister(t, c.addOns...))...),
+				eventRecorder: eventstesting.NewTestingEventRecorder(t),
+				decisions:     map[string]ApprovalResult{},
+				csrPhases:     map[string]csrPhase{},
 			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, validCSR.Name))
+			testinghelpers.AssertError(t, syncErr, c.expectedErr)
 
 			c.validateActions(t, kubeClient.Actions())
 		})
 	}
 }
 
+func TestSyncScoped(t *testing.T) {
+	cases := []struct {
+		name            string
+		scoped          bool
+		clusters        []runtime.Object
+		policy          ApprovalPolicy
+		expectedActions []string
+	}{
+		{
+			name:            "not scoped",
+			policy:          ApprovalPolicyAuto,
+			expectedActions: []string{"update"},
+		},
+		{
+			name:            "cluster in the scope",
+			scoped:          true,
+			clusters:        []runtime.Object{testinghelpers.NewManagedClusterBuilder("managedcluster1").Build()},
+			policy:          ApprovalPolicyAuto,
+			expectedActions: []string{"update"},
+		},
+		{
+			name:     "cluster out of the scope",
+			scoped:   true,
+			clusters: []runtime.Object{testinghelpers.NewManagedClusterBuilder("managedcluster2").Build()},
+			policy:   ApprovalPolicyAuto,
+		},
+		{
+			name:     "manual approval policy",
+			scoped:   true,
+			clusters: []runtime.Object{testinghelpers.NewManagedClusterBuilder("managedcluster1").Build()},
+			policy:   ApprovalPolicyManual,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			csr := testinghelpers.NewCSR(addOnCSR)
+			kubeClient := kubefake.NewSimpleClientset(csr)
+			informerFactory := informers.NewSharedInformerFactory(kubeClient, 3*time.Minute)
+			csrStore := informerFactory.Certificates().V1().CertificateSigningRequests().Informer().GetStore()
+			if err := csrStore.Add(csr); err != nil {
+				t.Fatal(err)
+			}
+
+			var clusterLister clusterv1listers.ManagedClusterLister
+			if c.scoped {
+				clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterfake.NewSimpleClientset(), 3*time.Minute)
+				clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+				for _, cluster := range c.clusters {
+					if err := clusterStore.Add(cluster); err != nil {
+						t.Fatal(err)
+					}
+				}
+				clusterLister = clusterInformerFactory.Cluster().V1().ManagedClusters().Lister()
+			}
+
+			ctrl := newCSRApprovingController(kubeClient, informerFactory.Certificates().V1().CertificateSigningRequests().Lister(),
+				clusterLister, newClusterManagementAddOnLister(t, autoApprovedAddOn), c.policy, eventstesting.NewTestingEventRecorder(t))
+			if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, addOnCSR.Name)); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			testinghelpers.AssertActions(t, kubeClient.Actions(), c.expectedActions...)
+		})
+	}
+}
+
 func TestIsSpokeClusterClientCertRenewal(t *testing.T) {
 	invalidSignerName := "invalidsigner"
 
@@ -246,14 +493,194 @@ func TestIsSpokeClusterClientCertRenewal(t *testing.T) {
 			csr:       validCSR,
 			isRenewal: true,
 		},
+		{
+			name: "a renewal csr of the cert-manager signer",
+			csr: testinghelpers.CSRHolder{
+				Name:         validCSR.Name,
+				Labels:       validCSR.Labels,
+				SignerName:   helpers.CertManagerSignerName,
+				CN:           validCSR.CN,
+				Orgs:         validCSR.Orgs,
+				Username:     validCSR.Username,
+				ReqBlockType: validCSR.ReqBlockType,
+			},
+			isRenewal: true,
+		},
 	}
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			isRenewal := isSpokeClusterClientCertRenewal(testinghelpers.NewCSR(c.csr))
+			isRenewal := isSpokeClusterClientCertRenewal(context.TODO(), testinghelpers.NewCSR(c.csr))
 			if isRenewal != c.isRenewal {
 				t.Errorf("expected %t, but failed", c.isRenewal)
 			}
 		})
 	}
 }
+
+func TestReverseTunnelCSRApprover(t *testing.T) {
+	tunnelCSR := testinghelpers.CSRHolder{
+		Name: "testcsr",
+		Labels: map[string]string{
+			"open-cluster-management.io/cluster-name": "managedcluster1",
+			helpers.ReverseTunnelLabel:                "true",
+		},
+		SignerName:   helpers.DefaultReverseTunnelSignerName,
+		CN:           helpers.ReverseTunnelUser("managedcluster1", "spokeagent1"),
+		Orgs:         []string{helpers.ReverseTunnelGroup("managedcluster1")},
+		Username:     user.SubjectPrefix + "managedcluster1:spokeagent1",
+		ReqBlockType: "CERTIFICATE REQUEST",
+	}
+
+	cases := []struct {
+		name             string
+		csr              testinghelpers.CSRHolder
+		allowed          bool
+		expectedDecision Decision
+		expectedActions  []string
+	}{
+		{
+			name:             "approve reverse tunnel csr",
+			csr:              tunnelCSR,
+			allowed:          true,
+			expectedDecision: DecisionApprove,
+			expectedActions:  []string{"create"},
+		},
+		{
+			name:             "cluster not accepted",
+			csr:              tunnelCSR,
+			expectedDecision: DecisionSkip,
+			expectedActions:  []string{"create"},
+		},
+		{
+			name:             "not a reverse tunnel csr",
+			csr:              validCSR,
+			allowed:          true,
+			expectedDecision: DecisionSkip,
+		},
+		{
+			name: "unexpected signer",
+			csr: testinghelpers.CSRHolder{
+				Name:         tunnelCSR.Name,
+				Labels:       tunnelCSR.Labels,
+				SignerName:   certificatesv1.KubeAPIServerClientSignerName,
+				CN:           tunnelCSR.CN,
+				Orgs:         tunnelCSR.Orgs,
+				Username:     tunnelCSR.Username,
+				ReqBlockType: tunnelCSR.ReqBlockType,
+			},
+			allowed:          true,
+			expectedDecision: DecisionSkip,
+		},
+		{
+			name: "requested by another cluster",
+			csr: testinghelpers.CSRHolder{
+				Name:         tunnelCSR.Name,
+				Labels:       tunnelCSR.Labels,
+				SignerName:   tunnelCSR.SignerName,
+				CN:           tunnelCSR.CN,
+				Orgs:         tunnelCSR.Orgs,
+				Username:     user.SubjectPrefix + "managedcluster2:spokeagent1",
+				ReqBlockType: tunnelCSR.ReqBlockType,
+			},
+			allowed:          true,
+			expectedDecision: DecisionSkip,
+		},
+		{
+			name: "unexpected subject",
+			csr: testinghelpers.CSRHolder{
+				Name:         tunnelCSR.Name,
+				Labels:       tunnelCSR.Labels,
+				SignerName:   tunnelCSR.SignerName,
+				CN:           tunnelCSR.CN,
+				Orgs:         []string{"system:masters"},
+				Username:     tunnelCSR.Username,
+				ReqBlockType: tunnelCSR.ReqBlockType,
+			},
+			allowed:          true,
+			expectedDecision: DecisionSkip,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeClient.PrependReactor(
+				"create",
+				"subjectaccessreviews",
+				func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &authorizationv1.SubjectAccessReview{
+						Status: authorizationv1.SubjectAccessReviewStatus{Allowed: c.allowed},
+					}, nil
+				},
+			)
+
+			approver := NewReverseTunnelCSRApprover(kubeClient, helpers.DefaultReverseTunnelSignerName)
+			result, err := approver.Approve(context.TODO(), testinghelpers.NewCSR(c.csr))
+			if err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+			if result.Decision != c.expectedDecision {
+				t.Errorf("expected decision %q, but got %q", c.expectedDecision, result.Decision)
+			}
+			testinghelpers.AssertActions(t, kubeClient.Actions(), c.expectedActions...)
+		})
+	}
+}
+
+func newDecisionApprover(result ApprovalResult) Approver {
+	return ApproverFunc(func(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (ApprovalResult, error) {
+		return result, nil
+	})
+}
+
+func TestFIPSCSRApprover(t *testing.T) {
+	_, ed25519Key, err := ed25519.GenerateKey(cryptorand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err := x509.CreateCertificateRequest(cryptorand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: validCSR.CN, Organization: validCSR.Orgs},
+	}, ed25519Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519CSR := testinghelpers.NewCSR(validCSR)
+	ed25519CSR.Spec.Request = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: request})
+
+	otherCSR := ed25519CSR.DeepCopy()
+	otherCSR.Labels = nil
+
+	cases := []struct {
+		name             string
+		csr              *certificatesv1.CertificateSigningRequest
+		expectedDecision Decision
+	}{
+		{
+			name:             "approved key",
+			csr:              testinghelpers.NewCSR(validCSR),
+			expectedDecision: DecisionSkip,
+		},
+		{
+			name:             "ed25519 key",
+			csr:              ed25519CSR,
+			expectedDecision: DecisionDeny,
+		},
+		{
+			name:             "not a csr of managed clusters",
+			csr:              otherCSR,
+			expectedDecision: DecisionSkip,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, err := NewFIPSCSRApprover().Approve(context.TODO(), c.csr)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if result.Decision != c.expectedDecision {
+				t.Errorf("expected decision %q, but got %q", c.expectedDecision, result.Decision)
+			}
+		})
+	}
+}
@@ -1,3 +1,4 @@
 // package csr contains the hub-side reconciler for auto approving the renewal CertificateSigningRequests
-// for an accepted managed cluster
+// for an accepted managed cluster. The approval decisions are made by a chain of approvers, and the custom
+// approvers can be registered ahead of the built-in ones, see Approver.
 package csr
@@ -0,0 +1,100 @@
+package csr
+
+import (
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// csrLifecycleBuckets are the buckets of the csr lifecycle durations, from a second to an hour
+var csrLifecycleBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+
+var (
+	csrApprovalDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Name:    "open_cluster_management_registration_csr_approval_duration_seconds",
+			Help:    "Duration from the creation of a csr of a managed cluster to its approval, partitioned by the type of the csr, cluster or addon, the managed cluster and the clusterset.",
+			Buckets: csrLifecycleBuckets,
+		},
+		[]string{"type", helpers.MetricLabelManagedCluster, helpers.MetricLabelClusterSet},
+	)
+
+	csrIssuanceDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Name:    "open_cluster_management_registration_csr_issuance_duration_seconds",
+			Help:    "Duration from the approval of a csr of a managed cluster to the certificate is issued by the signer, partitioned by the type of the csr, cluster or addon, the managed cluster and the clusterset.",
+			Buckets: csrLifecycleBuckets,
+		},
+		[]string{"type", helpers.MetricLabelManagedCluster, helpers.MetricLabelClusterSet},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(csrApprovalDuration, csrIssuanceDuration)
+}
+
+// csrPhase is the phase of a csr in its lifecycle
+type csrPhase string
+
+const (
+	csrPhasePending  csrPhase = "Pending"
+	csrPhaseApproved csrPhase = "Approved"
+	csrPhaseIssued   csrPhase = "Issued"
+	csrPhaseDenied   csrPhase = "Denied"
+)
+
+// getCSRPhase returns the phase of a csr and the time it is approved
+func getCSRPhase(csr *certificatesv1.CertificateSigningRequest) (csrPhase, time.Time) {
+	phase, approvedTime := csrPhasePending, time.Time{}
+	for _, cond := range csr.Status.Conditions {
+		switch cond.Type {
+		case certificatesv1.CertificateDenied, certificatesv1.CertificateFailed:
+			return csrPhaseDenied, time.Time{}
+		case certificatesv1.CertificateApproved:
+			phase, approvedTime = csrPhaseApproved, cond.LastUpdateTime.Time
+		}
+	}
+	if phase == csrPhaseApproved && len(csr.Status.Certificate) > 0 {
+		phase = csrPhaseIssued
+	}
+	return phase, approvedTime
+}
+
+// observeCSRPhase records the lifecycle durations of a csr of a managed cluster once it moves to the next phase.
+// A duration is recorded only if the controller observed the csr in the previous phase, so the csrs observed the
+// first time after the controller restarts are not recorded again.
+func (c *csrApprovingController) observeCSRPhase(csr *certificatesv1.CertificateSigningRequest, now time.Time) {
+	clusterName, ok := csr.Labels[spokeClusterNameLabel]
+	if !ok {
+		return
+	}
+	csrType := "cluster"
+	if _, ok := csr.Labels[addOnNameLabel]; ok {
+		csrType = "addon"
+	}
+
+	phase, approvedTime := getCSRPhase(csr)
+	if approvedTime.IsZero() {
+		approvedTime = now
+	}
+	c.lock.Lock()
+	lastPhase, observed := c.csrPhases[csr.Name]
+	c.csrPhases[csr.Name] = phase
+	c.lock.Unlock()
+	if !observed || lastPhase == phase {
+		return
+	}
+
+	labelValues := append([]string{csrType}, helpers.DefaultClusterLabeler.LabelValues(clusterName)...)
+
+	if lastPhase == csrPhasePending && (phase == csrPhaseApproved || phase == csrPhaseIssued) {
+		csrApprovalDuration.WithLabelValues(labelValues...).Observe(approvedTime.Sub(csr.CreationTimestamp.Time).Seconds())
+	}
+	if (lastPhase == csrPhasePending || lastPhase == csrPhaseApproved) && phase == csrPhaseIssued {
+		csrIssuanceDuration.WithLabelValues(labelValues...).Observe(now.Sub(approvedTime).Seconds())
+	}
+}
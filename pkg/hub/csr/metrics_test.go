@@ -0,0 +1,119 @@
+package csr
+
+import (
+	"testing"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics/testutil"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestObserveCSRPhase(t *testing.T) {
+	now := time.Now()
+	holder := testinghelpers.CSRHolder{
+		Name:   "csr1",
+		Labels: map[string]string{spokeClusterNameLabel: "cluster1"},
+	}
+
+	newCSR := func(approved, issued bool) *certificatesv1.CertificateSigningRequest {
+		csr := testinghelpers.NewCSR(holder)
+		if approved {
+			csr = testinghelpers.NewApprovedCSR(holder)
+			csr.Status.Conditions[0].LastUpdateTime = metav1.NewTime(now.Add(-time.Minute))
+		}
+		csr.CreationTimestamp = metav1.NewTime(now.Add(-10 * time.Minute))
+		if issued {
+			csr.Status.Certificate = []byte("cert")
+		}
+		return csr
+	}
+
+	cases := []struct {
+		name              string
+		csrs              []*certificatesv1.CertificateSigningRequest
+		expectedApprovals uint64
+		expectedIssuances uint64
+	}{
+		{
+			name: "csr is observed in each phase",
+			csrs: []*certificatesv1.CertificateSigningRequest{
+				newCSR(false, false), newCSR(true, false), newCSR(true, true),
+			},
+			expectedApprovals: 1,
+			expectedIssuances: 1,
+		},
+		{
+			name: "csr is approved and issued at once",
+			csrs: []*certificatesv1.CertificateSigningRequest{
+				newCSR(false, false), newCSR(true, true),
+			},
+			expectedApprovals: 1,
+			expectedIssuances: 1,
+		},
+		{
+			name: "csr is observed the first time after approved",
+			csrs: []*certificatesv1.CertificateSigningRequest{
+				newCSR(true, false), newCSR(true, true), newCSR(true, true),
+			},
+			expectedIssuances: 1,
+		},
+		{
+			name: "csr is observed the first time after issued",
+			csrs: []*certificatesv1.CertificateSigningRequest{
+				newCSR(true, true), newCSR(true, true),
+			},
+		},
+		{
+			name: "csr is denied",
+			csrs: []*certificatesv1.CertificateSigningRequest{
+				newCSR(false, false), testinghelpers.NewDeniedCSR(holder),
+			},
+		},
+		{
+			name: "csr of other components",
+			csrs: []*certificatesv1.CertificateSigningRequest{
+				testinghelpers.NewCSR(testinghelpers.CSRHolder{Name: "csr1"}),
+				testinghelpers.NewApprovedCSR(testinghelpers.CSRHolder{Name: "csr1"}),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			csrApprovalDuration.Reset()
+			csrIssuanceDuration.Reset()
+
+			ctrl := &csrApprovingController{csrPhases: map[string]csrPhase{}}
+			for _, csr := range c.csrs {
+				ctrl.observeCSRPhase(csr, now)
+			}
+
+			approvals, err := testutil.GetHistogramMetricCount(csrApprovalDuration.WithLabelValues("cluster", "cluster1", ""))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if approvals != c.expectedApprovals {
+				t.Errorf("expected %d approvals, but got %d", c.expectedApprovals, approvals)
+			}
+			issuances, err := testutil.GetHistogramMetricCount(csrIssuanceDuration.WithLabelValues("cluster", "cluster1", ""))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if issuances != c.expectedIssuances {
+				t.Errorf("expected %d issuances, but got %d", c.expectedIssuances, issuances)
+			}
+			if c.expectedApprovals > 0 {
+				sum, err := testutil.GetHistogramMetricValue(csrApprovalDuration.WithLabelValues("cluster", "cluster1", ""))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if sum != 540 {
+					t.Errorf("expected approval duration 540s, but got %v", sum)
+				}
+			}
+		})
+	}
+}
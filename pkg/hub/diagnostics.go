@@ -0,0 +1,69 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/diagnostics"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// DiagnosticsSources returns the sources of the objects in the diagnostics bundle of the hub controller, which are
+// the managed clusters and their recent conditions, the clustersets, the csrs and the leases of the clusters.
+func (m *HubManagerOptions) DiagnosticsSources(kubeConfig *rest.Config) ([]diagnostics.Source, error) {
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	clusterClient, err := clusterv1client.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return []diagnostics.Source{
+		func(ctx context.Context, bundle *diagnostics.Bundle) error {
+			clusters, err := clusterClient.ClusterV1().ManagedClusters().List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("unable to list managed clusters: %w", err)
+			}
+			conditions := map[string][]metav1.Condition{}
+			for _, cluster := range clusters.Items {
+				conditions["managedcluster/"+cluster.Name] = cluster.Status.Conditions
+			}
+			if err := bundle.AddYAML("conditions.yaml", diagnostics.RecentConditions(conditions, diagnostics.DefaultConditionLimit)); err != nil {
+				return err
+			}
+			return bundle.AddObject("managedclusters.yaml", clusters)
+		},
+		func(ctx context.Context, bundle *diagnostics.Bundle) error {
+			clusterSets, err := clusterClient.ClusterV1beta1().ManagedClusterSets().List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("unable to list managed clustersets: %w", err)
+			}
+			return bundle.AddObject("managedclustersets.yaml", clusterSets)
+		},
+		func(ctx context.Context, bundle *diagnostics.Bundle) error {
+			csrs, err := kubeClient.CertificatesV1().CertificateSigningRequests().List(ctx, metav1.ListOptions{
+				LabelSelector: clientcert.ClusterNameLabel,
+			})
+			if err != nil {
+				return fmt.Errorf("unable to list csrs: %w", err)
+			}
+			return bundle.AddObject("certificatesigningrequests.yaml", csrs)
+		},
+		func(ctx context.Context, bundle *diagnostics.Bundle) error {
+			leases, err := kubeClient.CoordinationV1().Leases(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+				LabelSelector: clientcert.ClusterNameLabel,
+			})
+			if err != nil {
+				return fmt.Errorf("unable to list leases: %w", err)
+			}
+			return bundle.AddObject("leases.yaml", leases)
+		},
+	}, nil
+}
@@ -0,0 +1,182 @@
+package hubca
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const controllerName = "HubCARotationController"
+
+// RotationProgressAnnotation is the annotation on the hub CA bundle configmap in the namespace of the hub
+// controller, which shows the number of the accepted clusters trusting the new CA out of all accepted clusters
+// while the CA is being rotated, e.g. "3/5".
+const RotationProgressAnnotation = "open-cluster-management.io/hub-ca-rotation-progress"
+
+// hubCARotationController publishes the hub CA bundle to the namespaces of the accepted managed clusters. While
+// a new CA is rolled out, the published bundle includes both the current CA bundle and the new CA, and the agents
+// report whether they trust it in the HubCABundleTrusted condition of their clusters. Once all accepted clusters
+// trust the published bundle and have verified the hub with the new CA, the old CA is retired by replacing the
+// current CA bundle with the new CA in the configmap of the hub controller. The clusters are not watched, since
+// their heartbeats would trigger the controller all the time, the progress is checked in each resync instead.
+type hubCARotationController struct {
+	kubeClient      kubernetes.Interface
+	clusterLister   listerv1.ManagedClusterLister
+	configMapLister corev1listers.ConfigMapLister
+	namespace       string
+	cache           resourceapply.ResourceCache
+}
+
+// NewHubCARotationController returns an instance of hubCARotationController. The configmap informer should
+// watch the namespace of the hub controller.
+func NewHubCARotationController(
+	kubeClient kubernetes.Interface,
+	clusterInformer informerv1.ManagedClusterInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	namespace string,
+	recorder events.Recorder) factory.Controller {
+	c := &hubCARotationController{
+		kubeClient:      kubeClient,
+		clusterLister:   clusterInformer.Lister(),
+		configMapLister: configMapInformer.Lister(),
+		namespace:       namespace,
+		cache:           helpers.NewResourceCache(),
+	}
+
+	return factory.New().
+		WithBareInformers(clusterInformer.Informer()).
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			return accessor.GetNamespace() == namespace && accessor.GetName() == helpers.HubCABundleConfigMapName
+		}, configMapInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ResyncEvery(time.Minute).
+		ToController(controllerName, recorder)
+}
+
+func (c *hubCARotationController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	source, err := c.configMapLister.ConfigMaps(c.namespace).Get(helpers.HubCABundleConfigMapName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	caBundle := []byte(source.Data[helpers.HubCABundleKey])
+	newCA := []byte(source.Data[helpers.HubNewCAKey])
+	bundle := helpers.MergeCABundles(caBundle, newCA)
+	if len(bundle) == 0 {
+		return nil
+	}
+	digest := helpers.HubCABundleDigest(bundle)
+
+	clusters, err := c.clusterLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	accepted, trusted := 0, 0
+	errs := []error{}
+	for _, cluster := range clusters {
+		if !cluster.Spec.HubAcceptsClient || !cluster.DeletionTimestamp.IsZero() {
+			continue
+		}
+		accepted++
+		// the cluster trusting the bundle has got the bundle already
+		if trustsBundle(cluster, digest) {
+			trusted++
+			continue
+		}
+		if err := c.publish(ctx, syncCtx.Recorder(), cluster.Name, bundle, newCA); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return operatorhelpers.NewMultiLineAggregate(errs)
+	}
+
+	if len(newCA) == 0 {
+		return nil
+	}
+
+	source = source.DeepCopy()
+	if trusted < accepted {
+		progress := fmt.Sprintf("%d/%d", trusted, accepted)
+		if source.Annotations[RotationProgressAnnotation] == progress {
+			return nil
+		}
+		if source.Annotations == nil {
+			source.Annotations = map[string]string{}
+		}
+		source.Annotations[RotationProgressAnnotation] = progress
+		_, err := c.kubeClient.CoreV1().ConfigMaps(c.namespace).Update(ctx, source, metav1.UpdateOptions{})
+		return err
+	}
+
+	// all accepted clusters have verified the hub with the new CA, the old CA is retired
+	source.Data[helpers.HubCABundleKey] = string(helpers.MergeCABundles(newCA))
+	delete(source.Data, helpers.HubNewCAKey)
+	delete(source.Annotations, RotationProgressAnnotation)
+	if _, err := c.kubeClient.CoreV1().ConfigMaps(c.namespace).Update(ctx, source, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	syncCtx.Recorder().Eventf("HubCARetired",
+		"The old hub CA is retired, the new CA is trusted by all of the %d accepted managed clusters", accepted)
+	return nil
+}
+
+// publish applies the hub CA bundle configmap in the namespace of a managed cluster. The clusters whose namespaces
+// are not created yet are skipped, they are synced again in the next resync.
+func (c *hubCARotationController) publish(ctx context.Context, recorder events.Recorder, clusterName string,
+	bundle, newCA []byte) error {
+	required := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: clusterName,
+			Name:      helpers.HubCABundleConfigMapName,
+		},
+		Data: map[string]string{
+			helpers.HubCABundleKey: string(bundle),
+		},
+	}
+	if len(newCA) > 0 {
+		required.Data[helpers.HubNewCAKey] = string(newCA)
+	}
+
+	_, _, err := resourceapply.ApplyConfigMapImproved(ctx, c.kubeClient.CoreV1(), recorder, required, c.cache)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// trustsBundle returns true if the agent of the cluster trusts the hub CA bundle with the digest
+func trustsBundle(cluster *clusterv1.ManagedCluster, digest string) bool {
+	cond := meta.FindStatusCondition(cluster.Status.Conditions, helpers.HubCABundleTrustedCondition)
+	return cond != nil && cond.Status == metav1.ConditionTrue && cond.Message == helpers.HubCABundleTrustedMessage(digest)
+}
@@ -0,0 +1,164 @@
+package hubca
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+const testNamespace = "open-cluster-management-hub"
+
+func TestSync(t *testing.T) {
+	oldCA := testinghelpers.NewTestCert("old-ca", time.Hour).Cert
+	newCA := testinghelpers.NewTestCert("new-ca", time.Hour).Cert
+	combinedDigest := helpers.HubCABundleDigest(helpers.MergeCABundles(oldCA, newCA))
+
+	newSource := func(data map[string][]byte) *corev1.ConfigMap {
+		source := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: helpers.HubCABundleConfigMapName},
+			Data:       map[string]string{},
+		}
+		for key, value := range data {
+			source.Data[key] = string(value)
+		}
+		return source
+	}
+	trustingCluster := func(name, digest string) *clusterv1.ManagedCluster {
+		return testinghelpers.NewManagedClusterBuilder(name).Accepted().
+			WithCondition(helpers.HubCABundleTrustedCondition, metav1.ConditionTrue, "HubCABundleTrusted",
+				helpers.HubCABundleTrustedMessage(digest)).
+			Build()
+	}
+
+	cases := []struct {
+		name            string
+		source          *corev1.ConfigMap
+		clusters        []*clusterv1.ManagedCluster
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:     "no hub ca bundle",
+			clusters: []*clusterv1.ManagedCluster{testinghelpers.NewManagedClusterBuilder("cluster1").Accepted().Build()},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:   "publish the hub ca bundle to the accepted clusters",
+			source: newSource(map[string][]byte{helpers.HubCABundleKey: oldCA}),
+			clusters: []*clusterv1.ManagedCluster{
+				testinghelpers.NewManagedClusterBuilder("cluster1").Accepted().Build(),
+				testinghelpers.NewManagedClusterBuilder("cluster2").Build(),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create")
+				published := actions[1].(clienttesting.CreateActionImpl).Object.(*corev1.ConfigMap)
+				if published.Namespace != "cluster1" || published.Data[helpers.HubCABundleKey] != string(oldCA) {
+					t.Errorf("unexpected published configmap %v", published)
+				}
+				if _, ok := published.Data[helpers.HubNewCAKey]; ok {
+					t.Errorf("expected no new ca, but got %v", published.Data)
+				}
+			},
+		},
+		{
+			name:   "publish the combined bundle while rotating",
+			source: newSource(map[string][]byte{helpers.HubCABundleKey: oldCA, helpers.HubNewCAKey: newCA}),
+			clusters: []*clusterv1.ManagedCluster{
+				testinghelpers.NewManagedClusterBuilder("cluster1").Accepted().Build(),
+				trustingCluster("cluster2", combinedDigest),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create", "update")
+				published := actions[1].(clienttesting.CreateActionImpl).Object.(*corev1.ConfigMap)
+				if published.Namespace != "cluster1" ||
+					published.Data[helpers.HubCABundleKey] != string(helpers.MergeCABundles(oldCA, newCA)) ||
+					published.Data[helpers.HubNewCAKey] != string(newCA) {
+					t.Errorf("unexpected published configmap %v", published)
+				}
+				source := actions[2].(clienttesting.UpdateActionImpl).Object.(*corev1.ConfigMap)
+				if source.Annotations[RotationProgressAnnotation] != "1/2" {
+					t.Errorf("expected progress 1/2, but got %q", source.Annotations[RotationProgressAnnotation])
+				}
+			},
+		},
+		{
+			name:   "clusters trusting a stale bundle",
+			source: newSource(map[string][]byte{helpers.HubCABundleKey: oldCA, helpers.HubNewCAKey: newCA}),
+			clusters: []*clusterv1.ManagedCluster{
+				trustingCluster("cluster1", helpers.HubCABundleDigest(oldCA)),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create", "update")
+			},
+		},
+		{
+			name:   "retire the old ca",
+			source: newSource(map[string][]byte{helpers.HubCABundleKey: oldCA, helpers.HubNewCAKey: newCA}),
+			clusters: []*clusterv1.ManagedCluster{
+				trustingCluster("cluster1", combinedDigest),
+				trustingCluster("cluster2", combinedDigest),
+				testinghelpers.NewManagedClusterBuilder("cluster3").Build(),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				source := actions[0].(clienttesting.UpdateActionImpl).Object.(*corev1.ConfigMap)
+				if source.Data[helpers.HubCABundleKey] != string(newCA) {
+					t.Errorf("expected the new ca to be the ca bundle, but got %q", source.Data[helpers.HubCABundleKey])
+				}
+				if _, ok := source.Data[helpers.HubNewCAKey]; ok {
+					t.Errorf("expected the new ca to be removed, but got %v", source.Data)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if c.source != nil {
+				objects = append(objects, c.source)
+			}
+			kubeClient := kubefake.NewSimpleClientset(objects...)
+			kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, time.Minute*10)
+			if c.source != nil {
+				if err := kubeInformerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(c.source); err != nil {
+					t.Fatal(err)
+				}
+			}
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterfake.NewSimpleClientset(), time.Minute*10)
+			for _, cluster := range c.clusters {
+				if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := &hubCARotationController{
+				kubeClient:      kubeClient,
+				clusterLister:   clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				configMapLister: kubeInformerFactory.Core().V1().ConfigMaps().Lister(),
+				namespace:       testNamespace,
+				cache:           helpers.NewResourceCache(),
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+			c.validateActions(t, kubeClient.Actions())
+		})
+	}
+}
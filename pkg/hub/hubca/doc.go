@@ -0,0 +1,5 @@
+// package hubca contains the hub-side controller which coordinates the rotation of the CA of the hub apiserver.
+// The hub admin adds the new CA to the hub CA bundle configmap in the namespace of the hub controller, the
+// controller publishes the combined bundle of the old and the new CA to the managed clusters, and retires the old
+// CA once the agents of all accepted clusters have verified the hub with the new CA.
+package hubca
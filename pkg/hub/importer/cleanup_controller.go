@@ -0,0 +1,110 @@
+package importer
+
+import (
+	"context"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+)
+
+const cleanupControllerName = "BootstrapCleanupController"
+
+// bootstrapCleanupController deletes the bootstrap service account of a cluster applied by Render with its
+// clusterrole and clusterrolebinding once the cluster joins or is deleted, so the bootstrap token is invalidated
+// and the resources do not leak. The resources are not owned by the ManagedCluster since it is created by the agent
+// after the artifacts are rendered.
+type bootstrapCleanupController struct {
+	kubeClient    kubernetes.Interface
+	clusterLister listerv1.ManagedClusterLister
+}
+
+// NewBootstrapCleanupController returns an instance of bootstrapCleanupController
+func NewBootstrapCleanupController(
+	kubeClient kubernetes.Interface,
+	clusterInformer informerv1.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &bootstrapCleanupController{
+		kubeClient:    kubeClient,
+		clusterLister: clusterInformer.Lister(),
+	}
+
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer()).
+		WithSync(health.WrapSync(cleanupControllerName, c.sync)).
+		ToController(cleanupControllerName, recorder)
+}
+
+func (c *bootstrapCleanupController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	clusterName := syncCtx.QueueKey()
+	if clusterName == factory.DefaultQueueKey {
+		return nil
+	}
+
+	// the cluster is only synced once it is created, so a cluster which is not found is deleted
+	cluster, err := c.clusterLister.Get(clusterName)
+	switch {
+	case errors.IsNotFound(err):
+		return c.removeBootstrapServiceAccount(ctx, syncCtx.Recorder(), clusterName)
+	case err != nil:
+		return err
+	}
+
+	if !cluster.DeletionTimestamp.IsZero() ||
+		meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionJoined) {
+		return c.removeBootstrapServiceAccount(ctx, syncCtx.Recorder(), clusterName)
+	}
+	return nil
+}
+
+// removeBootstrapServiceAccount removes the bootstrap service account of the cluster with its clusterrole and
+// clusterrolebinding. The namespace of the service account is read from the subject of the clusterrolebinding,
+// which is deleted last, so the removal is retried until everything is removed.
+func (c *bootstrapCleanupController) removeBootstrapServiceAccount(ctx context.Context, recorder events.Recorder, clusterName string) error {
+	name := bootstrapClusterRoleName(clusterName)
+	binding, err := c.kubeClient.RbacV1().ClusterRoleBindings().Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	errs := []error{}
+	ignoreNotFound := func(err error) {
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	for _, subject := range binding.Subjects {
+		if subject.Kind != rbacv1.ServiceAccountKind || subject.Name != bootstrapName(clusterName) {
+			continue
+		}
+		ignoreNotFound(c.kubeClient.CoreV1().ServiceAccounts(subject.Namespace).Delete(ctx, subject.Name, metav1.DeleteOptions{}))
+	}
+	ignoreNotFound(c.kubeClient.RbacV1().ClusterRoles().Delete(ctx, name, metav1.DeleteOptions{}))
+	if len(errs) > 0 {
+		return operatorhelpers.NewMultiLineAggregate(errs)
+	}
+
+	ignoreNotFound(c.kubeClient.RbacV1().ClusterRoleBindings().Delete(ctx, name, metav1.DeleteOptions{}))
+	if len(errs) > 0 {
+		return operatorhelpers.NewMultiLineAggregate(errs)
+	}
+	recorder.Eventf("BootstrapServiceAccountDeleted", "The bootstrap service account of managed cluster %q is deleted", clusterName)
+	return nil
+}
@@ -0,0 +1,119 @@
+package importer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func newBootstrapObjects(namespace string) []runtime.Object {
+	name := bootstrapClusterRoleName(testinghelpers.TestManagedClusterName)
+	return []runtime.Object{
+		&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      bootstrapName(testinghelpers.TestManagedClusterName),
+			},
+		},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Subjects: []rbacv1.Subject{{
+				Kind:      rbacv1.ServiceAccountKind,
+				Namespace: namespace,
+				Name:      bootstrapName(testinghelpers.TestManagedClusterName),
+			}},
+		},
+	}
+}
+
+func TestCleanupSync(t *testing.T) {
+	cases := []struct {
+		name            string
+		cluster         *clusterv1.ManagedCluster
+		objects         []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:    "joining cluster",
+			cluster: testinghelpers.NewAcceptedManagedCluster(),
+			objects: newBootstrapObjects(DefaultBootstrapNamespace),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:    "joined cluster without bootstrap service account",
+			cluster: testinghelpers.NewJoinedManagedCluster(),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name:    "joined cluster",
+			cluster: testinghelpers.NewJoinedManagedCluster(),
+			objects: newBootstrapObjects("bootstrap"),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "delete", "delete", "delete")
+				if ns := actions[1].GetNamespace(); actions[1].GetResource().Resource != "serviceaccounts" || ns != "bootstrap" {
+					t.Errorf("expected the service account in namespace bootstrap to be deleted, but got %v", actions[1])
+				}
+				if actions[3].GetResource().Resource != "clusterrolebindings" {
+					t.Errorf("expected the clusterrolebinding to be deleted last, but got %v", actions[3])
+				}
+			},
+		},
+		{
+			name:    "deleting cluster",
+			cluster: testinghelpers.NewDeletingManagedCluster(),
+			objects: newBootstrapObjects(DefaultBootstrapNamespace),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "delete", "delete", "delete")
+			},
+		},
+		{
+			name:    "deleted cluster",
+			objects: newBootstrapObjects(DefaultBootstrapNamespace),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "delete", "delete", "delete")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(c.objects...)
+
+			clusterClient := clusterfake.NewSimpleClientset()
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			if c.cluster != nil {
+				if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(c.cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := &bootstrapCleanupController{
+				kubeClient:    kubeClient,
+				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+			}
+			syncCtx := testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName)
+			if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			c.validateActions(t, kubeClient.Actions())
+		})
+	}
+}
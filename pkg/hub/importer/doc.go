@@ -0,0 +1,6 @@
+// package importer renders the artifacts to import a cluster to the hub: a bootstrap kubeconfig with a token scoped
+// to the registration of the cluster, and the manifests of the registration agent with the flags it expects. The
+// import tooling, e.g. CLIs and UIs, renders the artifacts with this package instead of re-implementing the
+// contract between the hub and the agent. The bootstrap service account of a cluster is deleted by the bootstrap
+// cleanup controller on the hub once the cluster joins or is deleted.
+package importer
@@ -0,0 +1,394 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/yaml"
+
+	"open-cluster-management.io/registration/deploy"
+	"open-cluster-management.io/registration/pkg/clientcert"
+)
+
+const (
+	// DefaultImage is the image of the registration agent
+	DefaultImage = "quay.io/open-cluster-management/registration:latest"
+	// DefaultAgentNamespace is the namespace the registration agent is deployed in on the managed cluster
+	DefaultAgentNamespace = "open-cluster-management-agent"
+	// DefaultBootstrapNamespace is the namespace of the bootstrap service accounts on the hub
+	DefaultBootstrapNamespace = "open-cluster-management-hub"
+	// DefaultTokenExpirationSeconds is the default lifetime of the bootstrap token. The token is only used until the
+	// cluster joins, so it is short-lived.
+	DefaultTokenExpirationSeconds int64 = 24 * 60 * 60
+
+	// BootstrapSecretName is the name of the secret of the bootstrap kubeconfig mounted by the registration agent
+	BootstrapSecretName = "bootstrap-secret"
+	// BootstrapKubeconfigKey is the key of the bootstrap kubeconfig in the bootstrap secret
+	BootstrapKubeconfigKey = "kubeconfig"
+
+	// manifestDir is the directory of the manifests of the registration agent in deploy.SpokeManifestFiles
+	manifestDir = "spoke"
+	// agentContainerName is the name of the container of the registration agent in its deployment
+	agentContainerName = "spoke-agent"
+)
+
+// Options are the options to render the import artifacts of a cluster
+type Options struct {
+	// ClusterName is the name of the managed cluster to import
+	ClusterName string
+	// HubServer is the URL of the hub apiserver reachable from the managed cluster
+	HubServer string
+	// HubCAData is the CA bundle to verify the serving certificate of the hub apiserver
+	HubCAData []byte
+	// Image is the image of the registration agent, DefaultImage is used if it is empty
+	Image string
+	// AgentNamespace is the namespace the registration agent is deployed in, DefaultAgentNamespace is used if it
+	// is empty
+	AgentNamespace string
+	// BootstrapNamespace is the namespace of the bootstrap service account of the cluster on the hub,
+	// DefaultBootstrapNamespace is used if it is empty
+	BootstrapNamespace string
+	// TokenExpirationSeconds is the requested lifetime of the bootstrap token, DefaultTokenExpirationSeconds is
+	// used if it is zero
+	TokenExpirationSeconds int64
+	// AgentArgs are the additional flags of the registration agent, e.g. --feature-gates=ClusterClaim=true. A flag
+	// which is already set in the manifests is overridden.
+	AgentArgs []string
+}
+
+// Artifacts are the artifacts to import a cluster
+type Artifacts struct {
+	// BootstrapKubeconfig is the bootstrap kubeconfig of the registration agent
+	BootstrapKubeconfig []byte
+	// Manifests are the YAML manifests to apply on the managed cluster in order, including the bootstrap secret
+	Manifests [][]byte
+}
+
+// Render renders the import artifacts of a cluster. A bootstrap service account of the cluster is created on the
+// hub, which is only allowed to create the csrs and to create and get the ManagedCluster of the cluster, and a
+// token of it is requested for the bootstrap kubeconfig. The service account is deleted by the bootstrap cleanup
+// controller once the cluster joins or is deleted, see NewBootstrapCleanupController. The manifests of the registration agent are rendered from
+// deploy.SpokeManifestFiles, so they are in line with the flags the agent expects.
+func Render(ctx context.Context, kubeClient kubernetes.Interface, recorder events.Recorder, options Options) (*Artifacts, error) {
+	options = withDefaults(options)
+	if err := validate(options); err != nil {
+		return nil, err
+	}
+
+	token, err := requestBootstrapToken(ctx, kubeClient, recorder, options)
+	if err != nil {
+		return nil, err
+	}
+	kubeconfig, err := BuildBootstrapKubeconfig(options.HubServer, options.HubCAData, token)
+	if err != nil {
+		return nil, err
+	}
+	manifests, err := RenderAgentManifests(options, kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return &Artifacts{BootstrapKubeconfig: kubeconfig, Manifests: manifests}, nil
+}
+
+// BuildBootstrapKubeconfig builds a bootstrap kubeconfig which authenticates to the hub with the token
+func BuildBootstrapKubeconfig(server string, caData []byte, token string) ([]byte, error) {
+	return clientcmd.Write(clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{"hub": {
+			Server:                   server,
+			CertificateAuthorityData: caData,
+		}},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{"bootstrap": {
+			Token: token,
+		}},
+		Contexts: map[string]*clientcmdapi.Context{"bootstrap": {
+			Cluster:  "hub",
+			AuthInfo: "bootstrap",
+		}},
+		CurrentContext: "bootstrap",
+	})
+}
+
+// RenderAgentManifests renders the manifests of the registration agent of the cluster with the bootstrap
+// kubeconfig. The resources listed in the kustomization of the manifests are rendered in order, with the namespace
+// of the agent, the image and the flags set, and the bootstrap secret is rendered before the deployment.
+func RenderAgentManifests(options Options, bootstrapKubeconfig []byte) ([][]byte, error) {
+	options = withDefaults(options)
+
+	kustomization := struct {
+		Resources []string `json:"resources"`
+	}{}
+	data, err := deploy.SpokeManifestFiles.ReadFile(path.Join(manifestDir, "kustomization.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &kustomization); err != nil {
+		return nil, err
+	}
+
+	manifests := [][]byte{}
+	for _, resource := range kustomization.Resources {
+		data, err := deploy.SpokeManifestFiles.ReadFile(path.Join(manifestDir, resource))
+		if err != nil {
+			return nil, err
+		}
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(data, &obj.Object); err != nil {
+			return nil, fmt.Errorf("unable to decode %q: %w", resource, err)
+		}
+
+		if obj.GetKind() == "Deployment" {
+			secret, err := yaml.Marshal(bootstrapSecret(options.AgentNamespace, bootstrapKubeconfig))
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, secret)
+		}
+
+		if err := renderAgentResource(obj, options); err != nil {
+			return nil, fmt.Errorf("unable to render %q: %w", resource, err)
+		}
+		manifest, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests, nil
+}
+
+// renderAgentResource sets the namespace of the agent to a resource of the agent, and the image and the flags to
+// the deployment of the agent
+func renderAgentResource(obj *unstructured.Unstructured, options Options) error {
+	switch obj.GetKind() {
+	case "CustomResourceDefinition", "ClusterRole":
+	case "Namespace":
+		obj.SetName(options.AgentNamespace)
+	case "ClusterRoleBinding":
+		return setSubjectsNamespace(obj, options.AgentNamespace)
+	case "RoleBinding":
+		obj.SetNamespace(options.AgentNamespace)
+		return setSubjectsNamespace(obj, options.AgentNamespace)
+	case "Deployment":
+		obj.SetNamespace(options.AgentNamespace)
+		containers, _, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok || container["name"] != agentContainerName {
+				continue
+			}
+			args, _, err := unstructured.NestedStringSlice(container, "args")
+			if err != nil {
+				return err
+			}
+			container["image"] = options.Image
+			container["args"] = toInterfaces(mergeArgs(args,
+				append([]string{"--cluster-name=" + options.ClusterName}, options.AgentArgs...)))
+			found = true
+		}
+		if !found {
+			return fmt.Errorf("the container %q is not found", agentContainerName)
+		}
+		return unstructured.SetNestedSlice(obj.Object, containers, "spec", "template", "spec", "containers")
+	default:
+		obj.SetNamespace(options.AgentNamespace)
+	}
+	return nil
+}
+
+// requestBootstrapToken applies the bootstrap service account of the cluster with its permissions, and requests a
+// token of it
+func requestBootstrapToken(ctx context.Context, kubeClient kubernetes.Interface, recorder events.Recorder, options Options) (string, error) {
+	name := bootstrapName(options.ClusterName)
+	labels := map[string]string{clientcert.ClusterNameLabel: options.ClusterName}
+
+	errs := []error{}
+	if _, _, err := resourceapply.ApplyServiceAccount(ctx, kubeClient.CoreV1(), recorder, &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: options.BootstrapNamespace,
+			Name:      name,
+			Labels:    labels,
+		},
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	if _, _, err := resourceapply.ApplyClusterRole(ctx, kubeClient.RbacV1(), recorder, &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   bootstrapClusterRoleName(options.ClusterName),
+			Labels: labels,
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{"certificates.k8s.io"},
+				Resources: []string{"certificatesigningrequests"},
+				Verbs:     []string{"create", "get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{"cluster.open-cluster-management.io"},
+				Resources: []string{"managedclusters"},
+				Verbs:     []string{"create"},
+			},
+			{
+				APIGroups:     []string{"cluster.open-cluster-management.io"},
+				Resources:     []string{"managedclusters"},
+				ResourceNames: []string{options.ClusterName},
+				Verbs:         []string{"get"},
+			},
+		},
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	if _, _, err := resourceapply.ApplyClusterRoleBinding(ctx, kubeClient.RbacV1(), recorder, &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   bootstrapClusterRoleName(options.ClusterName),
+			Labels: labels,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     bootstrapClusterRoleName(options.ClusterName),
+		},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Namespace: options.BootstrapNamespace,
+			Name:      name,
+		}},
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return "", operatorhelpers.NewMultiLineAggregate(errs)
+	}
+
+	expirationSeconds := options.TokenExpirationSeconds
+	tokenRequest, err := kubeClient.CoreV1().ServiceAccounts(options.BootstrapNamespace).CreateToken(ctx, name,
+		&authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				ExpirationSeconds: &expirationSeconds,
+			},
+		}, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("unable to request token for service account %q: %w",
+			options.BootstrapNamespace+"/"+name, err)
+	}
+	return tokenRequest.Status.Token, nil
+}
+
+func bootstrapSecret(namespace string, kubeconfig []byte) *corev1.Secret {
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      BootstrapSecretName,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{BootstrapKubeconfigKey: kubeconfig},
+	}
+}
+
+// setSubjectsNamespace sets the namespace of the service account subjects of a binding
+func setSubjectsNamespace(obj *unstructured.Unstructured, namespace string) error {
+	subjects, _, err := unstructured.NestedSlice(obj.Object, "subjects")
+	if err != nil {
+		return err
+	}
+	for _, s := range subjects {
+		if subject, ok := s.(map[string]interface{}); ok && subject["kind"] == rbacv1.ServiceAccountKind {
+			subject["namespace"] = namespace
+		}
+	}
+	return unstructured.SetNestedSlice(obj.Object, subjects, "subjects")
+}
+
+// mergeArgs returns the args with the overrides, a flag in the overrides replaces the flag of the same name in the
+// args, and the others are appended
+func mergeArgs(args, overrides []string) []string {
+	merged := append([]string{}, args...)
+	for _, override := range overrides {
+		name := strings.SplitN(override, "=", 2)[0]
+		replaced := false
+		for i, arg := range merged {
+			if strings.HasPrefix(arg, "--") && strings.SplitN(arg, "=", 2)[0] == name {
+				merged[i] = override
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, override)
+		}
+	}
+	return merged
+}
+
+func toInterfaces(values []string) []interface{} {
+	ret := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		ret = append(ret, value)
+	}
+	return ret
+}
+
+func withDefaults(options Options) Options {
+	if len(options.Image) == 0 {
+		options.Image = DefaultImage
+	}
+	if len(options.AgentNamespace) == 0 {
+		options.AgentNamespace = DefaultAgentNamespace
+	}
+	if len(options.BootstrapNamespace) == 0 {
+		options.BootstrapNamespace = DefaultBootstrapNamespace
+	}
+	if options.TokenExpirationSeconds == 0 {
+		options.TokenExpirationSeconds = DefaultTokenExpirationSeconds
+	}
+	return options
+}
+
+func validate(options Options) error {
+	errs := field.ErrorList{}
+	for _, msg := range validation.IsDNS1123Label(options.ClusterName) {
+		errs = append(errs, field.Invalid(field.NewPath("clusterName"), options.ClusterName, msg))
+	}
+	if len(options.HubServer) == 0 {
+		errs = append(errs, field.Required(field.NewPath("hubServer"), ""))
+	}
+	if options.TokenExpirationSeconds < 600 {
+		errs = append(errs, field.Invalid(field.NewPath("tokenExpirationSeconds"), options.TokenExpirationSeconds,
+			"must be at least 600"))
+	}
+	for i, arg := range options.AgentArgs {
+		if !strings.HasPrefix(arg, "--") {
+			errs = append(errs, field.Invalid(field.NewPath("agentArgs").Index(i), arg, "must be in the format of --<name>=<value>"))
+		}
+	}
+	return errs.ToAggregate()
+}
+
+// bootstrapName returns the name of the bootstrap service account of the cluster
+func bootstrapName(clusterName string) string {
+	return fmt.Sprintf("%s-bootstrap-sa", clusterName)
+}
+
+// bootstrapClusterRoleName returns the name of the clusterrole and the clusterrolebinding of the bootstrap service
+// account of the cluster
+func bootstrapClusterRoleName(clusterName string) string {
+	return fmt.Sprintf("open-cluster-management:bootstrap:%s", clusterName)
+}
@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		name        string
+		options     Options
+		expectedErr string
+		validate    func(t *testing.T, artifacts *Artifacts, kubeClient *kubefake.Clientset)
+	}{
+		{
+			name:    "invalid options",
+			options: Options{ClusterName: "Cluster1", AgentArgs: []string{"-v=4"}},
+			expectedErr: "[clusterName: Invalid value: \"Cluster1\": a lowercase RFC 1123 label must consist of lower " +
+				"case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. " +
+				"'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?'), hubServer: " +
+				"Required value, agentArgs[0]: Invalid value: \"-v=4\": must be in the format of --<name>=<value>]",
+		},
+		{
+			name: "render artifacts",
+			options: Options{
+				ClusterName:    testinghelpers.TestManagedClusterName,
+				HubServer:      "https://hub.example.com:6443",
+				HubCAData:      []byte("ca"),
+				Image:          "quay.io/open-cluster-management/registration:v0.7.0",
+				AgentNamespace: "ocm-agent",
+				AgentArgs:      []string{"--feature-gates=ClusterClaim=true", "--disable-leader-election=false"},
+			},
+			validate: func(t *testing.T, artifacts *Artifacts, kubeClient *kubefake.Clientset) {
+				kubeconfig, err := clientcmd.Load(artifacts.BootstrapKubeconfig)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if kubeconfig.AuthInfos["bootstrap"].Token != "bootstrap-token" ||
+					kubeconfig.Clusters["hub"].Server != "https://hub.example.com:6443" {
+					t.Errorf("unexpected bootstrap kubeconfig %s", string(artifacts.BootstrapKubeconfig))
+				}
+
+				for _, action := range kubeClient.Actions() {
+					if action.GetVerb() == "create" && action.GetSubresource() == "token" &&
+						action.GetNamespace() != DefaultBootstrapNamespace {
+						t.Errorf("expected the token of the bootstrap service account in %q, but got %q",
+							DefaultBootstrapNamespace, action.GetNamespace())
+					}
+				}
+				if _, err := kubeClient.RbacV1().ClusterRoleBindings().Get(context.TODO(),
+					"open-cluster-management:bootstrap:testmanagedcluster", metav1.GetOptions{}); err != nil {
+					t.Errorf("expected the bootstrap clusterrolebinding to be created, but got %v", err)
+				}
+
+				kinds := []string{}
+				for _, manifest := range artifacts.Manifests {
+					obj := &unstructured.Unstructured{}
+					if err := yaml.Unmarshal(manifest, &obj.Object); err != nil {
+						t.Fatal(err)
+					}
+					kinds = append(kinds, obj.GetKind())
+					switch obj.GetKind() {
+					case "Namespace":
+						if obj.GetName() != "ocm-agent" {
+							t.Errorf("expected namespace ocm-agent, but got %q", obj.GetName())
+						}
+					case "ClusterRoleBinding", "RoleBinding":
+						subjects, _, _ := unstructured.NestedSlice(obj.Object, "subjects")
+						if namespace := subjects[0].(map[string]interface{})["namespace"]; namespace != "ocm-agent" {
+							t.Errorf("expected the subject in namespace ocm-agent, but got %v", namespace)
+						}
+					case "Secret":
+						if obj.GetNamespace() != "ocm-agent" || obj.GetName() != BootstrapSecretName {
+							t.Errorf("unexpected bootstrap secret %s/%s", obj.GetNamespace(), obj.GetName())
+						}
+					case "Deployment":
+						containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+						container := containers[0].(map[string]interface{})
+						if container["image"] != "quay.io/open-cluster-management/registration:v0.7.0" {
+							t.Errorf("unexpected image %v", container["image"])
+						}
+						args, _, _ := unstructured.NestedStringSlice(container, "args")
+						expectedArgs := []string{
+							"/registration",
+							"agent",
+							"--cluster-name=testmanagedcluster",
+							"--bootstrap-kubeconfig=/spoke/bootstrap/kubeconfig",
+							"--disable-leader-election=false",
+							"--feature-gates=ClusterClaim=true",
+						}
+						if !reflect.DeepEqual(args, expectedArgs) {
+							t.Errorf("expected args %v, but got %v", expectedArgs, args)
+						}
+					}
+				}
+				expectedKinds := []string{"CustomResourceDefinition", "Namespace", "ServiceAccount", "ClusterRole",
+					"ClusterRoleBinding", "ClusterRole", "ClusterRoleBinding", "Role", "RoleBinding", "Secret", "Deployment"}
+				if !reflect.DeepEqual(kinds, expectedKinds) {
+					t.Errorf("expected manifests %v, but got %v", expectedKinds, kinds)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeClient.PrependReactor("create", "serviceaccounts",
+				func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+					if action.GetSubresource() != "token" {
+						return false, nil, nil
+					}
+					return true, &authenticationv1.TokenRequest{
+						Status: authenticationv1.TokenRequestStatus{
+							Token:               "bootstrap-token",
+							ExpirationTimestamp: metav1.NewTime(time.Now().Add(time.Hour)),
+						},
+					}, nil
+				})
+
+			artifacts, err := Render(context.TODO(), kubeClient, eventstesting.NewTestingEventRecorder(t), c.options)
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if c.validate != nil {
+				c.validate(t, artifacts, kubeClient)
+			}
+		})
+	}
+}
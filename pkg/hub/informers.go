@@ -0,0 +1,116 @@
+package hub
+
+import (
+	"context"
+	"time"
+
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	workv1client "open-cluster-management.io/api/client/work/clientset/versioned"
+	workv1informers "open-cluster-management.io/api/client/work/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+)
+
+// The informers below are registered with cache transforms in the informer factories created by the hub manager,
+// which drop the managed fields and the data the controllers never read, to reduce the memory of a hub caching
+// thousands of clusters. The injected informer factories are left as they are, since their informers may be shared
+// with other controllers reading the dropped data. The csrs are watched with the filtered csr informer factories, and the objects
+// the controllers read the metadata only of are watched with the metadata informers.
+
+// installClusterInformerTransforms registers the informer of the managed clusters, which only watches the clusters
+// matching the selector if it is not nil.
+func installClusterInformerTransforms(informers clusterv1informers.SharedInformerFactory, selector labels.Selector) {
+	tweakListOptions := func(options *metav1.ListOptions) {
+		if selector != nil {
+			options.LabelSelector = selector.String()
+		}
+	}
+	informers.InformerFor(&clusterv1.ManagedCluster{},
+		func(client clusterv1client.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			clusters := client.ClusterV1().ManagedClusters()
+			return helpers.NewTransformingInformer(
+				func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+					tweakListOptions(&options)
+					return clusters.List(ctx, options)
+				},
+				func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+					tweakListOptions(&options)
+					return clusters.Watch(ctx, options)
+				},
+				&clusterv1.ManagedCluster{},
+				resyncPeriod,
+				helpers.StripManagedFields,
+			)
+		})
+}
+
+func installWorkInformerTransforms(informers workv1informers.SharedInformerFactory) {
+	informers.InformerFor(&workv1.ManifestWork{},
+		func(client workv1client.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			works := client.WorkV1().ManifestWorks(metav1.NamespaceAll)
+			return helpers.NewTransformingInformer(
+				func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+					return works.List(ctx, options)
+				},
+				works.Watch,
+				&workv1.ManifestWork{},
+				resyncPeriod,
+				helpers.TransformManifestWork,
+			)
+		})
+}
+
+func installAddOnInformerTransforms(informers addoninformers.SharedInformerFactory) {
+	informers.InformerFor(&addonv1alpha1.ManagedClusterAddOn{},
+		func(client addonclient.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			addOns := client.AddonV1alpha1().ManagedClusterAddOns(metav1.NamespaceAll)
+			return helpers.NewTransformingInformer(
+				func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+					return addOns.List(ctx, options)
+				},
+				addOns.Watch,
+				&addonv1alpha1.ManagedClusterAddOn{},
+				resyncPeriod,
+				helpers.StripManagedFields,
+			)
+		})
+}
+
+// installKubeInformerMetadata registers the metadata informers of the namespaces and the rbac objects, the controllers
+// only read the existence, the deletion timestamps and the finalizers of them.
+func installKubeInformerMetadata(informers kubeinformers.SharedInformerFactory, client metadata.Interface) {
+	informers.InformerFor(&corev1.Namespace{},
+		func(_ kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			return helpers.NewMetadataInformer(client, corev1.SchemeGroupVersion.WithResource("namespaces"),
+				metav1.NamespaceAll, resyncPeriod, nil, &corev1.Namespace{},
+				func(m metav1.ObjectMeta) runtime.Object { return &corev1.Namespace{ObjectMeta: m} })
+		})
+	informers.InformerFor(&rbacv1.Role{},
+		func(_ kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			return helpers.NewMetadataInformer(client, rbacv1.SchemeGroupVersion.WithResource("roles"),
+				metav1.NamespaceAll, resyncPeriod, nil, &rbacv1.Role{},
+				func(m metav1.ObjectMeta) runtime.Object { return &rbacv1.Role{ObjectMeta: m} })
+		})
+	informers.InformerFor(&rbacv1.RoleBinding{},
+		func(_ kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			return helpers.NewMetadataInformer(client, rbacv1.SchemeGroupVersion.WithResource("rolebindings"),
+				metav1.NamespaceAll, resyncPeriod, nil, &rbacv1.RoleBinding{},
+				func(m metav1.ObjectMeta) runtime.Object { return &rbacv1.RoleBinding{ObjectMeta: m} })
+		})
+}
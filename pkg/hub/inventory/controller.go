@@ -0,0 +1,61 @@
+package inventory
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/labels"
+
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const controllerName = "InventoryExportController"
+
+// DefaultExportInterval is the default interval the inventory is exported in
+const DefaultExportInterval = 10 * time.Minute
+
+// inventoryExportController exports the inventory of the managed clusters to the sink in each interval. The
+// inventory is exported periodically instead of on each change of the managed clusters, since the heartbeats of the
+// clusters would flood the sink otherwise. A failed export is retried with the backoff of the controller.
+type inventoryExportController struct {
+	clusterLister listerv1.ManagedClusterLister
+	sink          Sink
+}
+
+// NewInventoryExportController returns an instance of inventoryExportController
+func NewInventoryExportController(
+	clusterInformer informerv1.ManagedClusterInformer,
+	sink Sink,
+	interval time.Duration,
+	recorder events.Recorder) factory.Controller {
+	c := &inventoryExportController{
+		clusterLister: clusterInformer.Lister(),
+		sink:          sink,
+	}
+
+	return factory.New().
+		WithBareInformers(clusterInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ResyncEvery(interval).
+		ToController(controllerName, recorder)
+}
+
+func (c *inventoryExportController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	clusters, err := c.clusterLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	inventory := NewInventory(clusters, time.Now())
+	if err := c.sink.Export(ctx, inventory); err != nil {
+		return err
+	}
+	helpers.ControllerLogger(ctx, controllerName).V(helpers.LogLevelDebug).Info("Exported inventory",
+		"clusters", len(inventory.Clusters))
+	return nil
+}
@@ -0,0 +1,93 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestSync(t *testing.T) {
+	cases := []struct {
+		name             string
+		clusters         []*clusterv1.ManagedCluster
+		sinkErr          error
+		expectedErr      string
+		expectedClusters []Cluster
+	}{
+		{
+			name:             "no cluster",
+			expectedClusters: []Cluster{},
+		},
+		{
+			name: "export clusters",
+			clusters: []*clusterv1.ManagedCluster{
+				testinghelpers.NewManagedClusterBuilder("cluster2").Build(),
+				testinghelpers.NewManagedClusterBuilder("cluster1").
+					WithLabels(map[string]string{helpers.ClusterSetLabel: "dev"}).
+					WithClaims(clusterv1.ManagedClusterClaim{Name: "platform.open-cluster-management.io", Value: "AWS"}).
+					Available().
+					Build(),
+			},
+			expectedClusters: []Cluster{
+				{
+					Name:       "cluster1",
+					ClusterSet: "dev",
+					Claims:     map[string]string{"platform.open-cluster-management.io": "AWS"},
+					Available:  metav1.ConditionTrue,
+				},
+				{
+					Name:      "cluster2",
+					Available: metav1.ConditionUnknown,
+				},
+			},
+		},
+		{
+			name:             "export failed",
+			clusters:         []*clusterv1.ManagedCluster{testinghelpers.NewManagedClusterBuilder("cluster1").Build()},
+			sinkErr:          fmt.Errorf("503 Service Unavailable"),
+			expectedErr:      "503 Service Unavailable",
+			expectedClusters: []Cluster{{Name: "cluster1", Available: metav1.ConditionUnknown}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset()
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			for _, cluster := range c.clusters {
+				if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var exported *Inventory
+			ctrl := &inventoryExportController{
+				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				sink: SinkFunc(func(ctx context.Context, inventory *Inventory) error {
+					exported = inventory
+					return c.sinkErr
+				}),
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey))
+			testinghelpers.AssertError(t, syncErr, c.expectedErr)
+
+			if exported == nil {
+				t.Fatalf("expected the inventory to be exported")
+			}
+			if !reflect.DeepEqual(exported.Clusters, c.expectedClusters) {
+				t.Errorf("expected clusters %v, but got %v", c.expectedClusters, exported.Clusters)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+// package inventory contains the hub-side controller which exports the inventory of the managed clusters to a
+// sink outside the hub, e.g. an S3 object, an HTTP endpoint or a Kafka topic, for the organizations which keep the
+// inventory of their fleet in an external CMDB. The inventory is exported periodically as a JSON snapshot of the
+// names, the clustersets, the claims and the availability of the managed clusters.
+package inventory
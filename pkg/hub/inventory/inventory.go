@@ -0,0 +1,58 @@
+package inventory
+
+import (
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// Inventory is the snapshot of the managed clusters exported to a sink
+type Inventory struct {
+	// Time is the time the snapshot is taken
+	Time metav1.Time `json:"time"`
+	// Clusters are the managed clusters sorted by their names
+	Clusters []Cluster `json:"clusters"`
+}
+
+// Cluster is a managed cluster in the inventory
+type Cluster struct {
+	Name       string            `json:"name"`
+	ClusterSet string            `json:"clusterSet,omitempty"`
+	Claims     map[string]string `json:"claims,omitempty"`
+	// Available is the status of the Available condition of the managed cluster, True, False or Unknown
+	Available metav1.ConditionStatus `json:"available"`
+}
+
+// NewInventory returns the inventory of the managed clusters at the time
+func NewInventory(clusters []*clusterv1.ManagedCluster, now time.Time) *Inventory {
+	inventory := &Inventory{
+		Time:     metav1.NewTime(now),
+		Clusters: make([]Cluster, 0, len(clusters)),
+	}
+	for _, cluster := range clusters {
+		record := Cluster{
+			Name:       cluster.Name,
+			ClusterSet: cluster.Labels[helpers.ClusterSetLabel],
+			Available:  metav1.ConditionUnknown,
+		}
+		if condition := meta.FindStatusCondition(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable); condition != nil {
+			record.Available = condition.Status
+		}
+		if len(cluster.Status.ClusterClaims) > 0 {
+			record.Claims = map[string]string{}
+			for _, claim := range cluster.Status.ClusterClaims {
+				record.Claims[claim.Name] = claim.Value
+			}
+		}
+		inventory.Clusters = append(inventory.Clusters, record)
+	}
+	sort.Slice(inventory.Clusters, func(i, j int) bool {
+		return inventory.Clusters[i].Name < inventory.Clusters[j].Name
+	})
+	return inventory
+}
@@ -0,0 +1,272 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	certutil "k8s.io/client-go/util/cert"
+
+	"open-cluster-management.io/registration/pkg/fips"
+)
+
+// Sink receives the inventory exported by the hub
+type Sink interface {
+	Export(ctx context.Context, inventory *Inventory) error
+}
+
+// SinkFunc is a function which implements Sink
+type SinkFunc func(ctx context.Context, inventory *Inventory) error
+
+// Export calls f(ctx, inventory)
+func (f SinkFunc) Export(ctx context.Context, inventory *Inventory) error {
+	return f(ctx, inventory)
+}
+
+// SinkConfig configures a built-in Sink
+type SinkConfig struct {
+	// URL is the destination of the inventory, which selects the sink by its scheme
+	//   - http:// or https:// posts the inventory to the endpoint;
+	//   - s3://<bucket>/<key>?region=<region> puts the inventory as the object, the endpoint of an S3 compatible
+	//     storage is set with the query parameter endpoint. The credentials are read from the environment variables
+	//     AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN;
+	//   - kafka+http://<host>/<topic> or kafka+https://<host>/<topic> produces the inventory as a record of the topic
+	//     through the Kafka REST proxy at the host.
+	URL string
+	// CAFile is the CA bundle to verify the serving certificate of the sink, the system roots are used if it is empty
+	CAFile string
+	// TokenFile is the bearer token the hub authenticates to the http and kafka sinks with, it is read on each
+	// export since the tokens may be rotated. No token is sent if it is empty.
+	TokenFile string
+}
+
+// NewSink returns the built-in Sink selected by the scheme of the URL
+func NewSink(config SinkConfig) (Sink, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// the TLS config is restricted to the approved settings in the FIPS mode
+	transport.TLSClientConfig = fips.RestrictTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12})
+	if len(config.CAFile) > 0 {
+		pool, err := certutil.NewPool(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the CA bundle of the inventory sink: %w", err)
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	client := &http.Client{Transport: transport, Timeout: 30 * time.Second}
+
+	switch u.Scheme {
+	case "http", "https":
+		return &httpSink{client: client, url: u.String(), tokenFile: config.TokenFile}, nil
+	case "kafka+http", "kafka+https":
+		topic := strings.Trim(u.Path, "/")
+		if len(u.Host) == 0 || len(topic) == 0 || strings.Contains(topic, "/") {
+			return nil, fmt.Errorf("the kafka sink must be in the format of %s://<host>/<topic>", u.Scheme)
+		}
+		proxy := url.URL{Scheme: strings.TrimPrefix(u.Scheme, "kafka+"), Host: u.Host, Path: "/topics/" + topic}
+		return &kafkaSink{client: client, url: proxy.String(), tokenFile: config.TokenFile}, nil
+	case "s3":
+		key := strings.TrimPrefix(u.Path, "/")
+		region := u.Query().Get("region")
+		if len(u.Host) == 0 || len(key) == 0 || len(region) == 0 {
+			return nil, fmt.Errorf("the s3 sink must be in the format of s3://<bucket>/<key>?region=<region>")
+		}
+		endpoint := u.Query().Get("endpoint")
+		if len(endpoint) == 0 {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+		}
+		return &s3Sink{client: client, endpoint: strings.TrimSuffix(endpoint, "/"), bucket: u.Host, key: key, region: region}, nil
+	default:
+		return nil, fmt.Errorf("unsupported inventory sink %q, the scheme must be one of http, https, s3, kafka+http or kafka+https",
+			config.URL)
+	}
+}
+
+// httpSink posts the inventory to an HTTP endpoint
+type httpSink struct {
+	client    *http.Client
+	url       string
+	tokenFile string
+}
+
+func (s *httpSink) Export(ctx context.Context, inventory *Inventory) error {
+	body, err := json.Marshal(inventory)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := setBearerToken(req, s.tokenFile); err != nil {
+		return err
+	}
+	return do(s.client, req)
+}
+
+// kafkaSink produces the inventory as a record of a topic through the Kafka REST proxy, the record is keyed with
+// "inventory" so the snapshots of the hub are kept in order in a partition
+type kafkaSink struct {
+	client    *http.Client
+	url       string
+	tokenFile string
+}
+
+func (s *kafkaSink) Export(ctx context.Context, inventory *Inventory) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"records": []interface{}{
+			map[string]interface{}{"key": "inventory", "value": inventory},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	if err := setBearerToken(req, s.tokenFile); err != nil {
+		return err
+	}
+	return do(s.client, req)
+}
+
+// s3Sink puts the inventory as an object of an S3 bucket, the request is signed with the AWS signature version 4
+type s3Sink struct {
+	client   *http.Client
+	endpoint string
+	bucket   string
+	key      string
+	region   string
+}
+
+func (s *s3Sink) Export(ctx context.Context, inventory *Inventory) error {
+	body, err := json.Marshal(inventory)
+	if err != nil {
+		return err
+	}
+	// the path-style url is used, so it works with the S3 compatible storages as well
+	objectURL := fmt.Sprintf("%s/%s/%s", s.endpoint, s.bucket, s.key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	accessKeyID, secretAccessKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if len(accessKeyID) == 0 || len(secretAccessKey) == 0 {
+		return fmt.Errorf("the environment variables AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required by the s3 sink")
+	}
+	if sessionToken := os.Getenv("AWS_SESSION_TOKEN"); len(sessionToken) > 0 {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+	signV4(req, body, accessKeyID, secretAccessKey, s.region, "s3", time.Now())
+	return do(s.client, req)
+}
+
+// signV4 signs the request with the AWS signature version 4, all of the headers of the request and the host are
+// signed
+func signV4(req *http.Request, body []byte, accessKeyID, secretAccessKey, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	for _, data := range []string{region, service, "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, data)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, hex.EncodeToString(hmacSHA256(signingKey, stringToSign))))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// setBearerToken sets the token in the file as the bearer token of the request if the file is set
+func setBearerToken(req *http.Request, tokenFile string) error {
+	if len(tokenFile) == 0 {
+		return nil
+	}
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	return nil
+}
+
+// do sends the request and returns an error if the response is not successful
+func do(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if len(data) > 0 {
+			return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+		}
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
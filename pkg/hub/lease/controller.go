@@ -2,22 +2,28 @@ package lease
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/debug"
+	"open-cluster-management.io/registration/pkg/health"
 	"open-cluster-management.io/registration/pkg/helpers"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 
 	coordv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	coordinformers "k8s.io/client-go/informers/coordination/v1"
 	"k8s.io/client-go/kubernetes"
 	coordlisters "k8s.io/client-go/listers/coordination/v1"
@@ -25,7 +31,12 @@ import (
 )
 
 const leaseDurationTimes = 5
+
+// a renewal of the lease is missed if the lease is not renewed within missedRenewalTimes of the lease duration
+const missedRenewalTimes = 2
+
 const leaseName = "managed-cluster-lease"
+const controllerName = "ManagedClusterLeaseController"
 
 var (
 	// LeaseDurationSeconds is lease update time interval
@@ -33,11 +44,25 @@ var (
 )
 
 // leaseController checks the lease of managed clusters on hub cluster to determine whether a managed cluster is available.
+// The clusters are synced by their names, so the leases of different clusters are checked in parallel by the workers
+// of the controller. The periodic resync enqueues all of the clusters to check the leases which are not renewed.
 type leaseController struct {
 	kubeClient    kubernetes.Interface
 	clusterClient clientset.Interface
 	clusterLister clusterv1listers.ManagedClusterLister
 	leaseLister   coordlisters.LeaseLister
+
+	// lock guards the observed leases, which are shared by the workers of the controller
+	lock sync.Mutex
+	// observedLeases are the last observed leases of the clusters, they are used to report the missed renewals
+	observedLeases map[string]*observedLease
+}
+
+// observedLease is the last observed renew time of a cluster lease
+type observedLease struct {
+	renewTime time.Time
+	// missReported is true if the missed renewal after the renew time is reported
+	missReported bool
 }
 
 // NewClusterLeaseController creates a cluster lease controller on hub cluster.
@@ -53,10 +78,27 @@ func NewClusterLeaseController(
 		clusterClient: clusterClient,
 		clusterLister: clusterInformer.Lister(),
 		leaseLister:   leaseInformer.Lister(),
+
+		observedLeases: map[string]*observedLease{},
 	}
+	// the clusters are synced in batches after the hub restarts, the clusters whose leases have not been renewed
+	// for the longest time first
+	warmUp := health.NewWarmUp(func() ([]runtime.Object, error) {
+		clusters, err := c.clusterLister.List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		objs := []runtime.Object{}
+		for _, cluster := range clusters {
+			objs = append(objs, cluster)
+		}
+		return objs, nil
+	}, queueKey, c.lastRenewTime)
+
 	return factory.New().
-		WithFilteredEventsInformers(
-			func(obj interface{}) bool {
+		WithFilteredEventsInformersQueueKeyFunc(
+			queueKey,
+			warmUp.Filter(func(obj interface{}) bool {
 				metaObj, ok := obj.(metav1.ObjectMetaAccessor)
 				if !ok {
 					return false
@@ -65,86 +107,215 @@ func NewClusterLeaseController(
 				// TODO instead of this by adding label filter in the SharedInformerFactory
 				// see https://github.com/open-cluster-management-io/registration/issues/225
 				return metaObj.GetObjectMeta().GetName() == leaseName
-			},
+			}),
 			leaseInformer.Informer(),
 		).
-		WithInformers(clusterInformer.Informer()).
-		WithSync(c.sync).
+		WithFilteredEventsInformersQueueKeyFunc(queueKey, warmUp.Filter(nil), clusterInformer.Informer()).
+		WithPostStartHooks(warmUp.Run).
+		WithSync(health.WrapSync(controllerName, c.sync)).
 		ResyncEvery(resyncInterval).
-		ToController("ManagedClusterLeaseController", recorder)
+		ToController(controllerName, recorder)
+}
+
+// queueKey returns the name of the cluster of a cluster or a cluster lease, the cluster lease is in the cluster
+// namespace
+func queueKey(obj runtime.Object) string {
+	accessor, _ := meta.Accessor(obj)
+	if _, ok := obj.(*coordv1.Lease); ok {
+		return accessor.GetNamespace()
+	}
+	return accessor.GetName()
+}
+
+// lastRenewTime returns the last renew time of the lease of a cluster, it is zero if the lease is not found
+func (c *leaseController) lastRenewTime(obj runtime.Object) time.Time {
+	lease, err := c.leaseLister.Leases(queueKey(obj)).Get(leaseName)
+	if err != nil || lease.Spec.RenewTime == nil {
+		return time.Time{}
+	}
+	return lease.Spec.RenewTime.Time
 }
 
-// sync checks the lease of each accepted cluster on hub to determine whether a managed cluster is available.
+// sync checks the lease of an accepted cluster on hub to determine whether the managed cluster is available.
 func (c *leaseController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
-	clusters, err := c.clusterLister.List(labels.Everything())
+	clusterName := syncCtx.QueueKey()
+	if clusterName == factory.DefaultQueueKey {
+		return c.resync(syncCtx)
+	}
+
+	cluster, err := c.clusterLister.Get(clusterName)
+	if errors.IsNotFound(err) {
+		c.forgetObservedLease(clusterName)
+		return nil
+	}
 	if err != nil {
+		return err
+	}
+
+	// cluster is not accepted, skip it.
+	if !meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
+		c.forgetObservedLease(clusterName)
 		return nil
 	}
-	for _, cluster := range clusters {
-		// cluster is not accepted, skip it.
-		if !meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
-			continue
+
+	// get the lease of a cluster, if the lease is not found, create it
+	observedLease, err := c.leaseLister.Leases(cluster.Name).Get(leaseName)
+	switch {
+	case errors.IsNotFound(err):
+		c.forgetObservedLease(clusterName)
+		if !cluster.DeletionTimestamp.IsZero() {
+			// the cluster is deleting, do nothing
+			break
+		}
+		lease := &coordv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      leaseName,
+				Namespace: cluster.Name,
+				Labels:    map[string]string{"open-cluster-management.io/cluster-name": cluster.Name},
+			},
+			Spec: coordv1.LeaseSpec{
+				HolderIdentity: pointer.StringPtr(leaseName),
+				RenewTime:      &metav1.MicroTime{Time: time.Now()},
+			},
+		}
+		_, err := c.kubeClient.CoordinationV1().Leases(cluster.Name).Create(ctx, lease, metav1.CreateOptions{})
+		return err
+	case err != nil:
+		return err
+	case err == nil:
+		gracePeriod := time.Duration(leaseDurationTimes*cluster.Spec.LeaseDurationSeconds) * time.Second
+		// FIX: #183 avoid gracePeriod is zero, will non-stop update ManagedClusterLeaseUpdateStopped condition.
+		if gracePeriod == 0 {
+			gracePeriod = time.Duration(leaseDurationTimes*LeaseDurationSeconds) * time.Second
 		}
+		now := time.Now()
+		c.reportMissedRenewal(syncCtx.Recorder().ComponentName(), cluster, observedLease, now)
 
-		// get the lease of a cluster, if the lease is not found, create it
-		observedLease, err := c.leaseLister.Leases(cluster.Name).Get(leaseName)
-		switch {
-		case errors.IsNotFound(err):
-			if !cluster.DeletionTimestamp.IsZero() {
-				// the cluster is deleting, do nothing
-				break
-			}
-			lease := &coordv1.Lease{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      leaseName,
-					Namespace: cluster.Name,
-					Labels:    map[string]string{"open-cluster-management.io/cluster-name": cluster.Name},
-				},
-				Spec: coordv1.LeaseSpec{
-					HolderIdentity: pointer.StringPtr(leaseName),
-					RenewTime:      &metav1.MicroTime{Time: time.Now()},
-				},
-			}
-			if _, err := c.kubeClient.CoordinationV1().Leases(cluster.Name).Create(ctx, lease, metav1.CreateOptions{}); err != nil {
-				return err
-			}
-			continue
-		case err != nil:
-			return err
-		case err == nil:
-			gracePeriod := time.Duration(leaseDurationTimes*cluster.Spec.LeaseDurationSeconds) * time.Second
-			// FIX: #183 avoid gracePeriod is zero, will non-stop update ManagedClusterLeaseUpdateStopped condition.
-			if gracePeriod == 0 {
-				gracePeriod = time.Duration(leaseDurationTimes*LeaseDurationSeconds) * time.Second
-			}
-			// the lease is constantly updated, do nothing
-			now := time.Now()
-			if now.Before(observedLease.Spec.RenewTime.Add(gracePeriod)) {
-				continue
-			}
+		// the lease is constantly updated, do nothing
+		if now.Before(observedLease.Spec.RenewTime.Add(gracePeriod)) {
+			return nil
 		}
+	}
 
-		if meta.IsStatusConditionPresentAndEqual(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable, metav1.ConditionUnknown) {
-			// the managed cluster available condition alreay is unknown, do nothing
+	if meta.IsStatusConditionPresentAndEqual(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable, metav1.ConditionUnknown) {
+		// the managed cluster available condition alreay is unknown, do nothing
+		return nil
+	}
+
+	// the lease is not constantly updated, update it to unknown
+	conditionUpdateFn := helpers.UpdateManagedClusterConditionFn(metav1.Condition{
+		Type:    clusterv1.ManagedClusterConditionAvailable,
+		Status:  metav1.ConditionUnknown,
+		Reason:  "ManagedClusterLeaseUpdateStopped",
+		Message: "Registration agent stopped updating its lease.",
+	})
+	_, updated, err := helpers.UpdateManagedClusterStatus(ctx, c.clusterClient, cluster.Name, conditionUpdateFn)
+	if err != nil {
+		return err
+	}
+	if updated {
+		syncCtx.Recorder().Eventf("ManagedClusterAvailableConditionUpdated",
+			"update managed cluster %q available condition to unknown, due to its lease is not updated constantly",
+			cluster.Name)
+	}
+	return nil
+}
+
+// resync enqueues all of the clusters, so the leases which are not renewed are checked without any event. The last
+// renew times of the observed cluster leases are recorded for debugging.
+func (c *leaseController) resync(syncCtx factory.SyncContext) error {
+	clusters, err := c.clusterLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	clusterNames := sets.NewString()
+	for _, cluster := range clusters {
+		clusterNames.Insert(cluster.Name)
+		syncCtx.Queue().Add(cluster.Name)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	observedRenewTimes := map[string]time.Time{}
+	for name, observed := range c.observedLeases {
+		if !clusterNames.Has(name) {
+			delete(c.observedLeases, name)
 			continue
 		}
+		observedRenewTimes[name] = observed.renewTime
+	}
+	debug.RecordState(controllerName, observedRenewTimes)
+	return nil
+}
 
-		// the lease is not constantly updated, update it to unknown
-		conditionUpdateFn := helpers.UpdateManagedClusterConditionFn(metav1.Condition{
-			Type:    clusterv1.ManagedClusterConditionAvailable,
-			Status:  metav1.ConditionUnknown,
-			Reason:  "ManagedClusterLeaseUpdateStopped",
-			Message: "Registration agent stopped updating its lease.",
-		})
-		_, updated, err := helpers.UpdateManagedClusterStatus(ctx, c.clusterClient, cluster.Name, conditionUpdateFn)
-		if err != nil {
-			return err
+// reportMissedRenewal records an event on the cluster lease in the cluster namespace if the lease missed its
+// renewal, i.e. it is renewed late or it is still not renewed missedRenewalTimes of the lease duration after the
+// last renewal. Each missed renewal is reported once.
+func (c *leaseController) reportMissedRenewal(component string, cluster *clusterv1.ManagedCluster, lease *coordv1.Lease, now time.Time) {
+	leaseDuration := time.Duration(cluster.Spec.LeaseDurationSeconds) * time.Second
+	if leaseDuration == 0 {
+		leaseDuration = time.Duration(LeaseDurationSeconds) * time.Second
+	}
+	renewTime := lease.Spec.RenewTime.Time
+
+	lastRenewTime, missed := c.observeRenewal(cluster.Name, renewTime, leaseDuration, now)
+	if !missed {
+		return
+	}
+
+	leaseRenewalMissed.WithLabelValues(helpers.DefaultClusterLabeler.LabelValues(cluster.Name)...).Inc()
+	recorder := events.NewRecorder(c.kubeClient.CoreV1().Events(cluster.Name), component, &corev1.ObjectReference{
+		Kind:       "Lease",
+		APIVersion: "coordination.k8s.io/v1",
+		Namespace:  lease.Namespace,
+		Name:       lease.Name,
+		UID:        lease.UID,
+	})
+	recorder.Warningf("ManagedClusterLeaseRenewalMissed",
+		"Lease of managed cluster %q missed its renewal, the renew time is expected before %s, but the observed renew time is %s",
+		cluster.Name, lastRenewTime.Add(leaseDuration).UTC().Format(time.RFC3339), renewTime.UTC().Format(time.RFC3339))
+}
+
+// observeRenewal records the observed renew time of a cluster lease, and returns the last renewal before the renew
+// time and whether the renewal after it is missed and not reported yet.
+func (c *leaseController) observeRenewal(clusterName string, renewTime time.Time, leaseDuration time.Duration, now time.Time) (time.Time, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// the last renewal is the one before the observed renew time if the lease is renewed since the last sync
+	lastRenewTime := renewTime
+	observed, ok := c.observedLeases[clusterName]
+	switch {
+	case !ok:
+		observed = &observedLease{renewTime: renewTime}
+		c.observedLeases[clusterName] = observed
+	case !renewTime.Equal(observed.renewTime):
+		if !observed.missReported {
+			lastRenewTime = observed.renewTime
 		}
-		if updated {
-			syncCtx.Recorder().Eventf("ManagedClusterAvailableConditionUpdated",
-				"update managed cluster %q available condition to unknown, due to its lease is not updated constantly",
-				cluster.Name)
+		observed.renewTime, observed.missReported = renewTime, false
+	}
+	if observed.missReported {
+		return lastRenewTime, false
+	}
+
+	if lastRenewTime.Equal(renewTime) {
+		// the lease is not renewed since the last renewal
+		if now.Sub(renewTime) <= missedRenewalTimes*leaseDuration {
+			return lastRenewTime, false
 		}
+		observed.missReported = true
+	} else if renewTime.Sub(lastRenewTime) <= missedRenewalTimes*leaseDuration {
+		// the lease is renewed in time
+		return lastRenewTime, false
 	}
-	return nil
+	return lastRenewTime, true
+}
+
+// forgetObservedLease removes the observed lease of a cluster whose lease is not checked any more
+func (c *leaseController) forgetObservedLease(clusterName string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.observedLeases, clusterName)
 }
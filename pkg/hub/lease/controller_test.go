@@ -11,6 +11,9 @@ import (
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 
+	"github.com/openshift/library-go/pkg/controller/factory"
+
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	kubeinformers "k8s.io/client-go/informers"
@@ -53,6 +56,11 @@ func TestSync(t *testing.T) {
 				testinghelpers.NewManagedClusterLease(fmt.Sprintf("cluster-lease-%s", testinghelpers.TestManagedClusterName), now.Add(-5*time.Minute)),
 			},
 			validateActions: func(t *testing.T, leaseActions, clusterActions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, leaseActions, "create")
+				event := leaseActions[0].(clienttesting.CreateActionImpl).Object.(*corev1.Event)
+				if event.Namespace != testinghelpers.TestManagedClusterName || event.Reason != "ManagedClusterLeaseRenewalMissed" {
+					t.Errorf("expected lease renewal missed event in the cluster namespace, but got %s/%s", event.Namespace, event.Reason)
+				}
 				expected := metav1.Condition{
 					Type:    clusterv1.ManagedClusterConditionAvailable,
 					Status:  metav1.ConditionUnknown,
@@ -118,8 +126,10 @@ func TestSync(t *testing.T) {
 				clusterClient: clusterClient,
 				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
 				leaseLister:   leaseInformerFactory.Coordination().V1().Leases().Lister(),
+
+				observedLeases: map[string]*observedLease{},
 			}
-			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
 			if syncErr != nil {
 				t.Errorf("unexpected err: %v", syncErr)
 			}
@@ -128,9 +138,112 @@ func TestSync(t *testing.T) {
 	}
 }
 
+func TestResync(t *testing.T) {
+	clusterClient := clusterfake.NewSimpleClientset()
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+	clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+	clusterStore.Add(testinghelpers.NewAvailableManagedCluster())
+
+	ctrl := &leaseController{
+		clusterClient: clusterClient,
+		clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+		observedLeases: map[string]*observedLease{
+			testinghelpers.TestManagedClusterName: {renewTime: now},
+			"removed-cluster":                     {renewTime: now},
+		},
+	}
+	syncCtx := testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey)
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Errorf("unexpected err: %v", err)
+	}
+
+	if syncCtx.Queue().Len() != 1 {
+		t.Errorf("expected the cluster to be enqueued, but got %d keys", syncCtx.Queue().Len())
+	}
+	if key, _ := syncCtx.Queue().Get(); key != testinghelpers.TestManagedClusterName {
+		t.Errorf("expected key %q, but got %v", testinghelpers.TestManagedClusterName, key)
+	}
+	if _, ok := ctrl.observedLeases["removed-cluster"]; ok {
+		t.Errorf("expected the observed lease of the removed cluster to be forgotten")
+	}
+	testinghelpers.AssertNoActions(t, clusterClient.Actions())
+}
+
 func newDeletingManagedCluster() *clusterv1.ManagedCluster {
 	now := metav1.Now()
 	cluster := testinghelpers.NewAcceptedManagedCluster()
 	cluster.DeletionTimestamp = &now
 	return cluster
 }
+
+func TestReportMissedRenewal(t *testing.T) {
+	cluster := testinghelpers.NewAvailableManagedCluster()
+	cluster.Spec.LeaseDurationSeconds = 60
+
+	cases := []struct {
+		name           string
+		observedLease  *observedLease
+		renewTime      time.Time
+		expectedEvent  bool
+		expectedReport bool
+	}{
+		{
+			name:      "first observation of a renewed lease",
+			renewTime: now.Add(-30 * time.Second),
+		},
+		{
+			name:           "first observation of an expired lease",
+			renewTime:      now.Add(-5 * time.Minute),
+			expectedEvent:  true,
+			expectedReport: true,
+		},
+		{
+			name:          "lease is renewed in time",
+			observedLease: &observedLease{renewTime: now.Add(-70 * time.Second)},
+			renewTime:     now.Add(-10 * time.Second),
+		},
+		{
+			name:          "lease is renewed late",
+			observedLease: &observedLease{renewTime: now.Add(-5 * time.Minute)},
+			renewTime:     now.Add(-10 * time.Second),
+			expectedEvent: true,
+		},
+		{
+			name:          "lease is renewed after the missed renewal is reported",
+			observedLease: &observedLease{renewTime: now.Add(-5 * time.Minute), missReported: true},
+			renewTime:     now.Add(-10 * time.Second),
+		},
+		{
+			name:           "missed renewal is reported once",
+			observedLease:  &observedLease{renewTime: now.Add(-5 * time.Minute), missReported: true},
+			renewTime:      now.Add(-5 * time.Minute),
+			expectedReport: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			ctrl := &leaseController{
+				kubeClient:     kubeClient,
+				observedLeases: map[string]*observedLease{},
+			}
+			if c.observedLease != nil {
+				ctrl.observedLeases[cluster.Name] = c.observedLease
+			}
+
+			lease := testinghelpers.NewManagedClusterLease(leaseName, c.renewTime)
+			ctrl.reportMissedRenewal("test", cluster, lease, now)
+
+			if c.expectedEvent {
+				testinghelpers.AssertActions(t, kubeClient.Actions(), "create")
+			} else {
+				testinghelpers.AssertNoActions(t, kubeClient.Actions())
+			}
+			observed := ctrl.observedLeases[cluster.Name]
+			if !observed.renewTime.Equal(c.renewTime) || observed.missReported != c.expectedReport {
+				t.Errorf("expected observed lease %v/%v, but got %v/%v", c.renewTime, c.expectedReport, observed.renewTime, observed.missReported)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package lease
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+var leaseRenewalMissed = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Name: "open_cluster_management_registration_lease_renewal_missed_total",
+		Help: "Number of the missed renewals of the leases of the managed clusters, partitioned by the managed cluster and the clusterset.",
+	},
+	[]string{helpers.MetricLabelManagedCluster, helpers.MetricLabelClusterSet},
+)
+
+func init() {
+	legacyregistry.MustRegister(leaseRenewalMissed)
+}
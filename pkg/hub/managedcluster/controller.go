@@ -4,11 +4,13 @@ import (
 	"context"
 	"embed"
 	"fmt"
+	"time"
 
 	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	v1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
 	"open-cluster-management.io/registration/pkg/helpers"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
@@ -19,9 +21,9 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/klog/v2"
 )
 
 const (
@@ -39,6 +41,11 @@ var staticFiles = []string{
 	"manifests/managedcluster-work-rolebinding.yaml",
 }
 
+// addOnFiles are applied unless the agent of the cluster disables the addon registration
+var addOnFiles = []string{
+	"manifests/managedcluster-registration-addon-rolebinding.yaml",
+}
+
 // managedClusterController reconciles instances of ManagedCluster on the hub.
 type managedClusterController struct {
 	kubeClient    kubernetes.Interface
@@ -46,6 +53,8 @@ type managedClusterController struct {
 	clusterLister listerv1.ManagedClusterLister
 	cache         resourceapply.ResourceCache
 	eventRecorder events.Recorder
+	// supportedKubernetesVersions is advertised to the agents of the accepted clusters if it is not empty
+	supportedKubernetesVersions string
 }
 
 // NewManagedClusterController creates a new managed cluster controller
@@ -53,26 +62,73 @@ func NewManagedClusterController(
 	kubeClient kubernetes.Interface,
 	clusterClient clientset.Interface,
 	clusterInformer informerv1.ManagedClusterInformer,
+	supportedKubernetesVersions string,
 	recorder events.Recorder) factory.Controller {
 	c := &managedClusterController{
-		kubeClient:    kubeClient,
-		clusterClient: clusterClient,
-		clusterLister: clusterInformer.Lister(),
-		cache:         resourceapply.NewResourceCache(),
-		eventRecorder: recorder.WithComponentSuffix("managed-cluster-controller"),
-	}
-	return factory.New().
-		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
-			accessor, _ := meta.Accessor(obj)
-			return accessor.GetName()
-		}, clusterInformer.Informer()).
-		WithSync(c.sync).
-		ToController("ManagedClusterController", recorder)
+		kubeClient:                  kubeClient,
+		clusterClient:               clusterClient,
+		clusterLister:               clusterInformer.Lister(),
+		cache:                       helpers.NewResourceCache(),
+		eventRecorder:               recorder.WithComponentSuffix("managed-cluster-controller"),
+		supportedKubernetesVersions: supportedKubernetesVersions,
+	}
+	queueKeyFunc := func(obj runtime.Object) string {
+		accessor, _ := meta.Accessor(obj)
+		return accessor.GetName()
+	}
+	// the joined clusters are synced in batches after the hub restarts, the clusters whose conditions have not
+	// changed for the longest time first
+	warmUp := health.NewWarmUp(func() ([]runtime.Object, error) {
+		clusters, err := c.clusterLister.List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		objs := []runtime.Object{}
+		for _, cluster := range clusters {
+			if !isJoining(cluster) {
+				objs = append(objs, cluster)
+			}
+		}
+		return objs, nil
+	}, queueKeyFunc, lastConditionTransitionTime)
+
+	// the clusters which have not joined are synced with a priority queue, so the clusters joining the hub are not
+	// delayed behind the joined clusters
+	return health.NewPriorityController("ManagedClusterController", recorder, isJoining,
+		func(f *factory.Factory, filter factory.EventFilterFunc) *factory.Factory {
+			return f.WithFilteredEventsInformersQueueKeyFunc(queueKeyFunc, filter, clusterInformer.Informer())
+		}, c.sync, warmUp)
+}
+
+// lastConditionTransitionTime returns the last transition time of the conditions of a cluster
+func lastConditionTransitionTime(obj runtime.Object) time.Time {
+	last := time.Time{}
+	cluster, ok := obj.(*v1.ManagedCluster)
+	if !ok {
+		return last
+	}
+	for _, cond := range cluster.Status.Conditions {
+		if cond.LastTransitionTime.After(last) {
+			last = cond.LastTransitionTime.Time
+		}
+	}
+	return last
+}
+
+// isJoining returns true if the cluster is not deleting and has not joined the hub yet
+func isJoining(obj interface{}) bool {
+	cluster, ok := obj.(*v1.ManagedCluster)
+	if !ok {
+		return false
+	}
+	return cluster.DeletionTimestamp.IsZero() &&
+		!meta.IsStatusConditionTrue(cluster.Status.Conditions, v1.ManagedClusterConditionJoined)
 }
 
 func (c *managedClusterController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
 	managedClusterName := syncCtx.QueueKey()
-	klog.V(4).Infof("Reconciling ManagedCluster %s", managedClusterName)
+	logger := helpers.ControllerLogger(ctx, "ManagedClusterController")
+	logger.V(helpers.LogLevelDebug).Info("Reconciling ManagedCluster", helpers.LogKeyCluster, managedClusterName)
 	managedCluster, err := c.clusterLister.Get(managedClusterName)
 	if errors.IsNotFound(err) {
 		// Spoke cluster not found, could have been deleted, do nothing.
@@ -100,6 +156,7 @@ func (c *managedClusterController) sync(ctx context.Context, syncCtx factory.Syn
 
 	// Spoke cluster is deleting, we remove its related resources
 	if !managedCluster.DeletionTimestamp.IsZero() {
+		health.EnterPhase(ctx, "remove resources")
 		if err := c.removeManagedClusterResources(ctx, managedClusterName); err != nil {
 			return err
 		}
@@ -115,6 +172,7 @@ func (c *managedClusterController) sync(ctx context.Context, syncCtx factory.Syn
 		// Hub cluster-admin denies the current spoke cluster, we remove its related resources and update its condition.
 		c.eventRecorder.Eventf("ManagedClusterDenied", "managed cluster %s is denied by hub cluster admin", managedClusterName)
 
+		health.EnterPhase(ctx, "remove resources")
 		if err := c.removeManagedClusterResources(ctx, managedClusterName); err != nil {
 			return err
 		}
@@ -133,25 +191,46 @@ func (c *managedClusterController) sync(ctx context.Context, syncCtx factory.Syn
 		return err
 	}
 
+	// advertise the supported versions of Kubernetes to the agent, which reports the skew of its cluster
+	if len(c.supportedKubernetesVersions) > 0 &&
+		managedCluster.Annotations[helpers.SupportedKubernetesVersionsAnnotation] != c.supportedKubernetesVersions {
+		if managedCluster.Annotations == nil {
+			managedCluster.Annotations = map[string]string{}
+		}
+		managedCluster.Annotations[helpers.SupportedKubernetesVersionsAnnotation] = c.supportedKubernetesVersions
+		_, err := c.clusterClient.ClusterV1().ManagedClusters().Update(ctx, managedCluster, metav1.UpdateOptions{})
+		return err
+	}
+
 	// TODO: we will add the managedcluster-namespace.yaml back to staticFiles
 	// in next release, currently, we need keep the namespace after the managed
 	// cluster is deleted.
 	applyFiles := []string{"manifests/managedcluster-namespace.yaml"}
 	applyFiles = append(applyFiles, staticFiles...)
 
+	errs := []error{}
+	if addOnRegistrationDisabled(managedCluster) {
+		assetFn := helpers.ManagedClusterAssetFn(manifestFiles, managedClusterName)
+		if err := helpers.CleanUpManagedClusterManifests(ctx, c.kubeClient, c.eventRecorder, assetFn, addOnFiles...); err != nil {
+			errs = append(errs, err)
+		}
+	} else {
+		applyFiles = append(applyFiles, addOnFiles...)
+	}
+
 	// Hub cluster-admin accepts the spoke cluster, we apply
 	// 1. clusterrole and clusterrolebinding for this spoke cluster.
 	// 2. namespace for this spoke cluster.
 	// 3. role and rolebinding for this spoke cluster on its namespace.
+	health.EnterPhase(ctx, "apply resources")
 	resourceResults := resourceapply.ApplyDirectly(
 		ctx,
 		resourceapply.NewKubeClientHolder(c.kubeClient),
 		syncCtx.Recorder(),
 		c.cache,
-		helpers.ManagedClusterAssetFn(manifestFiles, managedClusterName),
+		helpers.ManagedClusterAssetFnWithServiceAccount(manifestFiles, managedClusterName, agentServiceAccountName(managedCluster)),
 		applyFiles...,
 	)
-	errs := []error{}
 	for _, result := range resourceResults {
 		if result.Error != nil {
 			errs = append(errs, fmt.Errorf("%q (%T): %v", result.File, result.Type, result.Error))
@@ -172,6 +251,7 @@ func (c *managedClusterController) sync(ctx context.Context, syncCtx factory.Syn
 		acceptedCondition.Message = applyErrors.Error()
 	}
 
+	health.EnterPhase(ctx, "update status")
 	_, updated, updatedErr := helpers.UpdateManagedClusterStatus(
 		ctx,
 		c.clusterClient,
@@ -187,11 +267,27 @@ func (c *managedClusterController) sync(ctx context.Context, syncCtx factory.Syn
 	return operatorhelpers.NewMultiLineAggregate(errs)
 }
 
+// agentServiceAccountName returns the service account of the registration agent bound with the roles of the
+// cluster, it is only bound if the agent uses the token registration driver
+func agentServiceAccountName(managedCluster *v1.ManagedCluster) string {
+	if managedCluster.Annotations[helpers.RegistrationDriverAnnotation] != helpers.TokenRegistrationDriver {
+		return ""
+	}
+	return helpers.RegistrationAgentServiceAccountName
+}
+
+// addOnRegistrationDisabled returns true if the agent of the cluster disables the addon registration, the addon
+// role is not bound for the agent then
+func addOnRegistrationDisabled(managedCluster *v1.ManagedCluster) bool {
+	return managedCluster.Annotations[helpers.AddOnRegistrationDisabledAnnotation] == "true"
+}
+
 func (c *managedClusterController) removeManagedClusterResources(ctx context.Context, managedClusterName string) error {
 	errs := []error{}
 	// Clean up managed cluster manifests
 	assetFn := helpers.ManagedClusterAssetFn(manifestFiles, managedClusterName)
-	if err := helpers.CleanUpManagedClusterManifests(ctx, c.kubeClient, c.eventRecorder, assetFn, staticFiles...); err != nil {
+	files := append(append([]string{}, staticFiles...), addOnFiles...)
+	if err := helpers.CleanUpManagedClusterManifests(ctx, c.kubeClient, c.eventRecorder, assetFn, files...); err != nil {
 		errs = append(errs, err)
 	}
 	return operatorhelpers.NewMultiLineAggregate(errs)
@@ -2,6 +2,7 @@ package managedcluster
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -9,10 +10,12 @@ import (
 	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	v1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	kubefake "k8s.io/client-go/kubernetes/fake"
@@ -21,9 +24,10 @@ import (
 
 func TestSyncManagedCluster(t *testing.T) {
 	cases := []struct {
-		name            string
-		startingObjects []runtime.Object
-		validateActions func(t *testing.T, actions []clienttesting.Action)
+		name                        string
+		startingObjects             []runtime.Object
+		supportedKubernetesVersions string
+		validateActions             func(t *testing.T, actions []clienttesting.Action)
 	}{
 		{
 			name:            "sync a deleted spoke cluster",
@@ -64,6 +68,18 @@ func TestSyncManagedCluster(t *testing.T) {
 				testinghelpers.AssertActions(t, actions, "get")
 			},
 		},
+		{
+			name:                        "advertise the supported kubernetes versions",
+			startingObjects:             []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			supportedKubernetesVersions: "1.27-1.30",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				managedCluster := (actions[0].(clienttesting.UpdateActionImpl).Object).(*v1.ManagedCluster)
+				if managedCluster.Annotations[helpers.SupportedKubernetesVersionsAnnotation] != "1.27-1.30" {
+					t.Errorf("expected the supported kubernetes versions are advertised, but got %v", managedCluster.Annotations)
+				}
+			},
+		},
 		{
 			name:            "deny an accepted spoke cluster",
 			startingObjects: []runtime.Object{testinghelpers.NewDeniedManagedCluster()},
@@ -101,7 +117,7 @@ func TestSyncManagedCluster(t *testing.T) {
 				clusterStore.Add(cluster)
 			}
 
-			ctrl := managedClusterController{kubeClient, clusterClient, clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(), resourceapply.NewResourceCache(), eventstesting.NewTestingEventRecorder(t)}
+			ctrl := managedClusterController{kubeClient, clusterClient, clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(), resourceapply.NewResourceCache(), eventstesting.NewTestingEventRecorder(t), c.supportedKubernetesVersions}
 			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
 			if syncErr != nil {
 				t.Errorf("unexpected err: %v", syncErr)
@@ -111,3 +127,128 @@ func TestSyncManagedCluster(t *testing.T) {
 		})
 	}
 }
+
+func TestAgentServiceAccountBindings(t *testing.T) {
+	cases := []struct {
+		name                   string
+		registrationDriver     string
+		expectedServiceAccount bool
+	}{
+		{
+			name: "csr registration",
+		},
+		{
+			name:                   "token registration",
+			registrationDriver:     helpers.TokenRegistrationDriver,
+			expectedServiceAccount: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := testinghelpers.NewAcceptingManagedCluster()
+			if len(c.registrationDriver) > 0 {
+				managedCluster.Annotations = map[string]string{helpers.RegistrationDriverAnnotation: c.registrationDriver}
+			}
+			clusterClient := clusterfake.NewSimpleClientset(managedCluster)
+			kubeClient := kubefake.NewSimpleClientset()
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(managedCluster)
+
+			ctrl := managedClusterController{kubeClient, clusterClient, clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(), resourceapply.NewResourceCache(), eventstesting.NewTestingEventRecorder(t), ""}
+			if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName)); err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+
+			bindings := 0
+			for _, action := range kubeClient.Actions() {
+				if action.GetVerb() != "create" {
+					continue
+				}
+				var subjects []rbacv1.Subject
+				switch obj := action.(clienttesting.CreateActionImpl).Object.(type) {
+				case *rbacv1.ClusterRoleBinding:
+					subjects = obj.Subjects
+				case *rbacv1.RoleBinding:
+					subjects = obj.Subjects
+				default:
+					continue
+				}
+				bindings++
+				hasServiceAccount := false
+				for _, subject := range subjects {
+					if subject.Kind == rbacv1.ServiceAccountKind && subject.Name == helpers.RegistrationAgentServiceAccountName {
+						hasServiceAccount = true
+					}
+				}
+				if hasServiceAccount != c.expectedServiceAccount {
+					t.Errorf("expected the service account bound %v, but got subjects %v", c.expectedServiceAccount, subjects)
+				}
+			}
+			if bindings != 4 {
+				t.Errorf("expected 4 bindings created, but got %d", bindings)
+			}
+		})
+	}
+}
+
+func TestAddOnRoleBinding(t *testing.T) {
+	addOnRoleBinding := fmt.Sprintf("open-cluster-management:managedcluster:%s:registration:addon", testinghelpers.TestManagedClusterName)
+	cases := []struct {
+		name                      string
+		addOnRegistrationDisabled bool
+		expectedVerb              string
+	}{
+		{
+			name:         "addon registration enabled",
+			expectedVerb: "update",
+		},
+		{
+			name:                      "addon registration disabled",
+			addOnRegistrationDisabled: true,
+			expectedVerb:              "delete",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := testinghelpers.NewAcceptingManagedCluster()
+			if c.addOnRegistrationDisabled {
+				managedCluster.Annotations = map[string]string{helpers.AddOnRegistrationDisabledAnnotation: "true"}
+			}
+			clusterClient := clusterfake.NewSimpleClientset(managedCluster)
+			// the addon rolebinding bound before the addon registration is disabled is removed
+			kubeClient := kubefake.NewSimpleClientset(&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{
+				Namespace: testinghelpers.TestManagedClusterName, Name: addOnRoleBinding}})
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(managedCluster)
+
+			ctrl := managedClusterController{kubeClient, clusterClient, clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(), resourceapply.NewResourceCache(), eventstesting.NewTestingEventRecorder(t), ""}
+			if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName)); err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+
+			verbs := []string{}
+			for _, action := range kubeClient.Actions() {
+				if action.GetResource().Resource != "rolebindings" {
+					continue
+				}
+				var name string
+				switch a := action.(type) {
+				case clienttesting.UpdateActionImpl:
+					name = a.Object.(*rbacv1.RoleBinding).Name
+				case clienttesting.DeleteActionImpl:
+					name = a.Name
+				default:
+					continue
+				}
+				if name == addOnRoleBinding {
+					verbs = append(verbs, action.GetVerb())
+				}
+			}
+			if len(verbs) != 1 || verbs[0] != c.expectedVerb {
+				t.Errorf("expected the addon rolebinding to be %s, but got %v", c.expectedVerb, verbs)
+			}
+		})
+	}
+}
@@ -3,7 +3,6 @@ package managedclusterset
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"sync"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
@@ -22,10 +21,8 @@ import (
 	clusterlisterv1beta1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
-)
-
-const (
-	clusterSetLabel = "cluster.open-cluster-management.io/clusterset"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
 )
 
 // managedClusterSetController reconciles instances of ManagedClusterSet on the hub.
@@ -74,7 +71,7 @@ func NewManagedClusterSetController(
 		// registering event handler. And then refactor the logic here.
 		WithInformersQueueKeyFunc(c.originalClusterSetQueueKeyFunc, clusterInformer.Informer()).
 		WithInformersQueueKeyFunc(c.currentClusterSetQueueKeyFunc, clusterInformer.Informer()).
-		WithSync(c.sync).
+		WithSync(health.WrapSync("ManagedClusterSetController", c.sync)).
 		ToController("ManagedClusterSetController", recorder)
 }
 
@@ -90,7 +87,7 @@ func (c *managedClusterSetController) originalClusterSetQueueKeyFunc(obj runtime
 
 	var currentClusterSetName string
 	if clusterLabels := accessor.GetLabels(); clusterLabels != nil {
-		currentClusterSetName = clusterLabels[clusterSetLabel]
+		currentClusterSetName = clusterLabels[helpers.ClusterSetLabel]
 	}
 
 	// return the name of clusterset it previously belonged to only if the parent clusterset
@@ -108,7 +105,7 @@ func (c *managedClusterSetController) currentClusterSetQueueKeyFunc(obj runtime.
 
 	// always return the name of clusterset it currently belongs to
 	if clusterLabels := accessor.GetLabels(); clusterLabels != nil {
-		return clusterLabels[clusterSetLabel]
+		return clusterLabels[helpers.ClusterSetLabel]
 	}
 
 	return ""
@@ -119,7 +116,8 @@ func (c *managedClusterSetController) sync(ctx context.Context, syncCtx factory.
 	if len(clusterSetName) == 0 {
 		return nil
 	}
-	klog.Infof("Reconciling ManagedClusterSet %s", clusterSetName)
+	logger := helpers.ControllerLogger(ctx, "ManagedClusterSetController")
+	logger.V(helpers.LogLevelDebug).Info("Reconciling ManagedClusterSet", helpers.LogKeyResource, klog.KRef("", clusterSetName))
 
 	clusterSet, err := c.clusterSetLister.Get(clusterSetName)
 	if errors.IsNotFound(err) {
@@ -154,7 +152,7 @@ func (c *managedClusterSetController) syncClusterSet(ctx context.Context, origin
 
 	// find out the containing clusters of clusterset
 	selector := labels.SelectorFromSet(labels.Set{
-		clusterSetLabel: clusterSet.Name,
+		helpers.ClusterSetLabel: clusterSet.Name,
 	})
 	clusters, err := c.clusterLister.List(selector)
 	if err != nil {
@@ -180,7 +178,7 @@ func (c *managedClusterSetController) syncClusterSet(ctx context.Context, origin
 	meta.SetStatusCondition(&clusterSet.Status.Conditions, emptyCondition)
 
 	// skip update if cluster set status does not change
-	if reflect.DeepEqual(clusterSet.Status.Conditions, originalClusterSet.Status.Conditions) {
+	if helpers.StatusEqual(clusterSet.Status, originalClusterSet.Status) {
 		return nil
 	}
 
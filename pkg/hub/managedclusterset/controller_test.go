@@ -14,6 +14,7 @@ import (
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 )
 
@@ -175,7 +176,7 @@ func newManagedCluster(name, clusterSet string) *clusterv1.ManagedCluster {
 
 	if len(clusterSet) > 0 {
 		cluster.Labels = map[string]string{
-			clusterSetLabel: clusterSet,
+			helpers.ClusterSetLabel: clusterSet,
 		}
 	}
 
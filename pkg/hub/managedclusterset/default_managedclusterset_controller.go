@@ -17,6 +17,8 @@ import (
 	clusterinformerv1beta1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1beta1"
 	clusterlisterv1beta1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta1"
 	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
 )
 
 const (
@@ -70,7 +72,7 @@ func NewDefaultManagedClusterSetController(
 			},
 			clusterSetInformer.Informer(),
 		).
-		WithSync(c.sync).
+		WithSync(health.WrapSync("DefaultManagedClusterSetController", c.sync)).
 		// use ResyncEvery to make sure:
 		// 1. create the default clusterset once controller is launched
 		// 2. the default clusterset be recreated once it is deleted for some reason
@@ -80,7 +82,9 @@ func NewDefaultManagedClusterSetController(
 
 func (c *defaultManagedClusterSetController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
 	defaultClusterSetName := defaultManagedClusterSetName
-	klog.V(4).Infof("Reconciling DefaultManagedClusterSet")
+	logger := helpers.ControllerLogger(ctx, "DefaultManagedClusterSetController")
+	logger.V(helpers.LogLevelDebug).Info("Reconciling DefaultManagedClusterSet", helpers.LogKeyResource, klog.KRef("", defaultClusterSetName))
+	ctx = klog.NewContext(ctx, logger)
 
 	defaultClusterSet, err := c.clusterSetLister.Get(defaultClusterSetName)
 
@@ -115,7 +119,8 @@ func (c *defaultManagedClusterSetController) syncDefaultClusterSet(ctx context.C
 
 	// if the annotation has set to disable, default clusterset controller will not work.
 	if hasAnnotation(defaultClusterSet, autoUpdateAnnotation, "false") {
-		klog.V(4).Info("DefaultManagedClusterSetDisabled", "The DefaultManagedClusterSet is disabled by user")
+		klog.FromContext(ctx).V(helpers.LogLevelDebug).Info("The DefaultManagedClusterSet is disabled by user",
+			helpers.LogKeyResource, klog.KObj(defaultClusterSet), helpers.LogKeyReason, "DefaultManagedClusterSetDisabled")
 		return nil
 	}
 
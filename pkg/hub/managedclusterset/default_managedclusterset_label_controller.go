@@ -11,11 +11,12 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/klog/v2"
 	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
 )
 
 const (
@@ -51,14 +52,15 @@ func NewDefaultManagedClusterSetLabelController(
 			accessor, _ := meta.Accessor(obj)
 			return accessor.GetName()
 		}, clusterInformer.Informer()).
-		WithSync(c.sync).
+		WithSync(health.WrapSync("DefaultManagedClusterSetLabelController", c.sync)).
 		ToController("DefaultManagedClusterSetLabelController", recorder)
 }
 
 func (c *defaultManagedClusterSetLabelController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
 	managedClusterName := syncCtx.QueueKey()
 
-	klog.V(4).Infof("Reconciling ManagedClusterSetLabel of ManagedCluster %s", managedClusterName)
+	logger := helpers.ControllerLogger(ctx, "DefaultManagedClusterSetLabelController")
+	logger.V(helpers.LogLevelDebug).Info("Reconciling ManagedClusterSetLabel of ManagedCluster", helpers.LogKeyCluster, managedClusterName)
 
 	managedCluster, err := c.clusterLister.Get(managedClusterName)
 	if errors.IsNotFound(err) {
@@ -83,12 +85,12 @@ func (c *defaultManagedClusterSetLabelController) sync(ctx context.Context, sync
 func (c *defaultManagedClusterSetLabelController) syncClusterSetLabel(ctx context.Context, managedCluster *clusterv1.ManagedCluster) error {
 	cluster := managedCluster.DeepCopy()
 
-	if v, ok := cluster.Labels[clusterSetLabel]; !ok || v == "" {
+	if v, ok := cluster.Labels[helpers.ClusterSetLabel]; !ok || v == "" {
 		modified := false
 
 		clusterSetLabels := map[string]string{}
-		clusterSetLabels[clusterSetLabel] = defaultManagedClusterSetValue
-		// merge clusterSetLabel into ManagedCluster.Labels
+		clusterSetLabels[helpers.ClusterSetLabel] = defaultManagedClusterSetValue
+		// merge helpers.ClusterSetLabel into ManagedCluster.Labels
 		resourcemerge.MergeMap(&modified, &cluster.Labels, clusterSetLabels)
 
 		// no work if the cluster labels have no change
@@ -101,6 +103,6 @@ func (c *defaultManagedClusterSetLabelController) syncClusterSetLabel(ctx contex
 		return err
 	}
 
-	// if clusterSetLabel already set, do nothing
+	// if helpers.ClusterSetLabel already set, do nothing
 	return nil
 }
@@ -2,10 +2,17 @@ package hub
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/cloudevents"
+	"open-cluster-management.io/registration/pkg/debug"
 	"open-cluster-management.io/registration/pkg/features"
-	"open-cluster-management.io/registration/pkg/hub/taint"
+	"open-cluster-management.io/registration/pkg/fips"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
 
 	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
 	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
@@ -13,30 +20,808 @@ import (
 	clusterv1informers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	workv1client "open-cluster-management.io/api/client/work/clientset/versioned"
 	workv1informers "open-cluster-management.io/api/client/work/informers/externalversions"
-	"open-cluster-management.io/registration/pkg/hub/addon"
-	"open-cluster-management.io/registration/pkg/hub/clusterrole"
+	"open-cluster-management.io/registration/pkg/hub/archive"
+	"open-cluster-management.io/registration/pkg/hub/bootstraptoken"
+	"open-cluster-management.io/registration/pkg/hub/certmanager"
+	"open-cluster-management.io/registration/pkg/hub/clusterapi"
+	"open-cluster-management.io/registration/pkg/hub/clusterprofile"
 	"open-cluster-management.io/registration/pkg/hub/csr"
-	"open-cluster-management.io/registration/pkg/hub/lease"
-	"open-cluster-management.io/registration/pkg/hub/managedcluster"
-	"open-cluster-management.io/registration/pkg/hub/managedclusterset"
-	"open-cluster-management.io/registration/pkg/hub/rbacfinalizerdeletion"
+	"open-cluster-management.io/registration/pkg/hub/inventory"
+	"open-cluster-management.io/registration/pkg/hub/registrationtoken"
+	"open-cluster-management.io/registration/pkg/hub/webhookcert"
+	"open-cluster-management.io/registration/pkg/hub/webhookconfig"
+	"open-cluster-management.io/registration/pkg/secureserving"
 
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
-	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/spf13/pflag"
 
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 )
 
 var ResyncInterval = 5 * time.Minute
 
-// RunControllerManager starts the controllers on hub to manage spoke cluster registration.
+// DefaultControllerWorkers is the default number of the workers of each controller on hub
+const DefaultControllerWorkers = 1
+
+// DefaultServiceAccountName is the default name of the service account of the hub controller
+const DefaultServiceAccountName = "hub-sa"
+
+// HubManagerOptions holds configuration for hub controller manager
+type HubManagerOptions struct {
+	// WebhookFailurePolicy and WebhookExcludedNamespaces are applied to the webhook configurations of the
+	// registration webhook if they are set, the webhook configurations are left as they are deployed otherwise.
+	WebhookFailurePolicy      string
+	WebhookExcludedNamespaces []string
+
+	// CSRApprovers are evaluated in order before the built-in approvers of the csr approving controller, so that
+	// the distributions embedding the hub controller manager are able to add their own approval logic. They are
+	// called concurrently if the csr approving controller runs more than one worker.
+	CSRApprovers []csr.Approver
+
+	// DebugBindAddress is the address the internal state of the controllers is served on for troubleshooting, the
+	// state is not served if it is empty.
+	DebugBindAddress string
+
+	// SecureBindAddress is the address the metrics and the internal state of the controllers are served on over
+	// TLS to the users authorized by the kube apiserver, they are not served securely if it is empty. The serving
+	// certificate is loaded from SecureServingCertFile and SecureServingKeyFile, or self-signed if they are empty.
+	SecureBindAddress     string
+	SecureServingCertFile string
+	SecureServingKeyFile  string
+
+	// HealthProbeBindAddress is the address the health checks of the controllers are served on, a check fails if
+	// the controller processes no key in ControllerProgressDeadline while it has pending work.
+	HealthProbeBindAddress     string
+	ControllerProgressDeadline time.Duration
+
+	// SlowSyncThreshold is the duration after which a sync of a controller is logged and counted as slow
+	SlowSyncThreshold time.Duration
+
+	// RetryBaseDelay, RetryMaxDelay and RetryQPS limit the retries of the failed keys of the controllers. A failed
+	// key is retried after a delay growing exponentially from RetryBaseDelay up to RetryMaxDelay, and the retries of
+	// a controller are limited to RetryQPS.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	RetryQPS       float64
+
+	// ControllerWorkers is the number of the workers of each controller, which process the keys of the controller
+	// in parallel. PerControllerWorkers overrides it for the controllers by their names, see ControllerNames.
+	ControllerWorkers    int
+	PerControllerWorkers map[string]int
+
+	// Controllers selects the controllers started by the hub controller by their names, see ControllerNames. A
+	// controller prefixed with "-" is not started, and "*" starts the controllers which are not listed, so that the
+	// controllers owned by another component are turned off, e.g. "*,-taint". All of the controllers are started if
+	// it is empty.
+	Controllers []string
+
+	// WarmUpBatchSize and WarmUpBatchInterval spread the initial sync of the managed clusters over time after the hub
+	// controller starts, the clusters are synced in batches of WarmUpBatchSize every WarmUpBatchInterval, the
+	// clusters which have not been synced for the longest time first.
+	WarmUpBatchSize     int
+	WarmUpBatchInterval time.Duration
+
+	// ClusterSelector and ClusterSets scope the hub controller to a slice of the fleet, e.g. to isolate a noisy
+	// tenant or to roll out a change of the hub controller in stages. The controllers in ScopedControllerNames only
+	// manage the clusters matching the label selector ClusterSelector in any of ClusterSets, the other controllers
+	// always manage the whole fleet and are not started if DisableFleetControllers is true, so that they are run by
+	// only one of the hub controllers managing the slices. The hub controller is not scoped if both are empty.
+	ClusterSelector         string
+	ClusterSets             []string
+	DisableFleetControllers bool
+
+	// InstanceName names an independent instance of the hub controller pinned to ClusterSets, so that the platform
+	// teams are able to operate the hub controllers of their own clustersets. An instance holds its own leader
+	// election lease, see ComponentName, and only starts the controllers in ScopedControllerNames. The csrs of the
+	// clusters in the scope of a hub controller are approved with its CSRApprovalPolicy, see csr.ApprovalPolicy, which is Auto if it is empty.
+	InstanceName      string
+	CSRApprovalPolicy string
+
+	// SupportedKubernetesVersions is the range of the minor versions of Kubernetes supported on the managed
+	// clusters, in the format of <min>-<max>, e.g. 1.27-1.30. It is advertised to the agents with the annotation
+	// helpers.SupportedKubernetesVersionsAnnotation on the accepted clusters, and the agents report the skew of
+	// their clusters with the condition helpers.KubernetesVersionSupportedCondition. Nothing is advertised if it
+	// is empty.
+	SupportedKubernetesVersions string
+
+	// CertManagerIssuer refers to the cert-manager issuer which signs the client certificates of the registration
+	// agents requested with the cert-manager signer, in the format of <kind>[.<group>]/<name>. The agents request
+	// their client certificates with the kube-apiserver-client signer unless they are configured with the
+	// cert-manager signer. The csrs of the cert-manager signer are not signed if it is empty.
+	CertManagerIssuer string
+	// CertManagerApproveRequests is true if the hub controller approves the cert-manager CertificateRequests of the
+	// approved csrs itself, instead of leaving them to the approvers of cert-manager. The hub controller has to be
+	// granted the approve permission on the signer of the issuer then, e.g. clusterissuers.cert-manager.io/<name>
+	// or issuers.cert-manager.io/<namespace>.<name>.
+	CertManagerApproveRequests bool
+
+	// RegistrationTokenBootstrapGroups are the groups of the bootstrap kubeconfigs of the clusters using the token
+	// registration driver. The group suffixed with the cluster name, e.g. system:bootstrappers:managedcluster:cluster1,
+	// is allowed to read the token of the registration agent of the cluster until the cluster joins, so the agent
+	// gets its first token with a bootstrap kubeconfig issued for the cluster only. It is used when the feature
+	// TokenRegistration is enabled.
+	RegistrationTokenBootstrapGroups []string
+	// ServiceAccountName is the name of the service account of the hub controller in its namespace. The hub
+	// controller binds it to the clusterroles shipped with it in the namespaces of the managed clusters, so it
+	// requests the tokens and maintains the secrets there without a cluster wide permission. The default name is
+	// used if it is empty.
+	ServiceAccountName string
+
+	// BootstrapTokenRotationInterval is the interval the shared bootstrap token used to import the clusters is
+	// rotated in, see bootstraptoken. BootstrapTokenGenerations latest generations of the token are kept valid, and
+	// the bootstrap kubeconfig of the latest one connects to the hub with BootstrapHubServer. The bootstrap token
+	// is not maintained if the interval is zero.
+	BootstrapTokenRotationInterval time.Duration
+	BootstrapTokenGenerations      int
+	BootstrapHubServer             string
+
+	// ArchiveDetachedClusters keeps an archival record of each deleted managed cluster in the namespace of the hub
+	// controller, see archive. The records are pruned once they are kept longer than ClusterArchiveRetention, or
+	// kept forever if it is zero.
+	ArchiveDetachedClusters bool
+	ClusterArchiveRetention time.Duration
+
+	// MetricsClusterLimit is the number of the managed clusters labeled by their names in the metrics, the others
+	// share a single label value. The per-cluster label is opted out if it is zero.
+	MetricsClusterLimit int
+
+	// CloudEventsBrokerAddress is the address of the MQTT broker the status and the heartbeats of the managed
+	// clusters are consumed from, it is used when the feature CloudEventsTransport is enabled. The serving
+	// certificate of the broker is verified with CloudEventsCAFile, and the hub authenticates with the client
+	// certificate in CloudEventsClientCertFile and CloudEventsClientKeyFile.
+	CloudEventsBrokerAddress  string
+	CloudEventsCAFile         string
+	CloudEventsClientCertFile string
+	CloudEventsClientKeyFile  string
+
+	// ClusterProfileNamespace is the namespace the ClusterProfiles of the managed clusters are maintained in, and
+	// the ClusterProfiles of the other cluster managers in it are imported as managed clusters if
+	// ImportClusterProfiles is true. They are used when the feature ClusterProfile is enabled.
+	ClusterProfileNamespace string
+	ImportClusterProfiles   bool
+
+	// ClusterAPIBootstrapDelivery delivers the bootstrap material of the registration agent to the Clusters of
+	// Cluster API imported as managed clusters, so that the distributions embedding the hub controller manager are
+	// able to deploy the agent on the provisioned clusters. It is used when the feature ClusterAPIImport is enabled,
+	// and the agent should be deployed in another way if it is nil.
+	ClusterAPIBootstrapDelivery clusterapi.BootstrapDelivery
+
+	// ReverseTunnelSignerName is the signer of the client certificates of the reverse tunnel agents requested by the
+	// registration agents, the csrs of the accepted managed clusters with the signer are approved. It is used when
+	// the feature ReverseTunnelBootstrap is enabled.
+	ReverseTunnelSignerName string
+
+	// InventoryExportSink is the url of the sink the inventory of the managed clusters is exported to every
+	// InventoryExportInterval, see inventory.SinkConfig. The serving certificate of the sink is verified with
+	// InventoryExportCAFile, and the hub authenticates with the bearer token in InventoryExportTokenFile.
+	// InventorySink takes precedence over the built-in sinks if it is set, so that the distributions embedding the
+	// hub controller manager are able to export the inventory to their own sinks. They are used when the feature
+	// InventoryExport is enabled.
+	InventoryExportSink      string
+	InventoryExportCAFile    string
+	InventoryExportTokenFile string
+	InventoryExportInterval  time.Duration
+	InventorySink            inventory.Sink
+
+	// FIPSMode restricts the crypto of the hub controller to the FIPS-approved algorithms, see fips. The csrs of the
+	// managed clusters with the keys which are not approved are denied, and the plaintext connections to the broker
+	// and the inventory sinks are not allowed. It is always enabled in a binary built with boringcrypto.
+	FIPSMode bool
+
+	// flags are the command line flags registered by AddFlags, they take precedence over the configuration file
+	flags *pflag.FlagSet
+}
+
+// NewHubManagerOptions returns a HubManagerOptions
+func NewHubManagerOptions() *HubManagerOptions {
+	return &HubManagerOptions{
+		ControllerProgressDeadline: health.DefaultProgressDeadline,
+		SlowSyncThreshold:          health.DefaultSlowSyncThreshold,
+		RetryBaseDelay:             health.DefaultRetryBaseDelay,
+		RetryMaxDelay:              health.DefaultRetryMaxDelay,
+		RetryQPS:                   health.DefaultRetryQPS,
+		ControllerWorkers:          DefaultControllerWorkers,
+		Controllers:                []string{"*"},
+		CSRApprovalPolicy:          string(csr.ApprovalPolicyAuto),
+		WarmUpBatchSize:            health.DefaultWarmUpBatchSize,
+		WarmUpBatchInterval:        health.DefaultWarmUpBatchInterval,
+		MetricsClusterLimit:        helpers.DefaultMetricClusterLimit,
+		ClusterProfileNamespace:    clusterprofile.DefaultNamespace,
+		ReverseTunnelSignerName:    helpers.DefaultReverseTunnelSignerName,
+		InventoryExportInterval:    inventory.DefaultExportInterval,
+		FIPSMode:                   fips.BuiltIn(),
+
+		RegistrationTokenBootstrapGroups: []string{registrationtoken.DefaultBootstrapGroup},
+		ServiceAccountName:               DefaultServiceAccountName,
+		BootstrapTokenGenerations:        bootstraptoken.DefaultGenerations,
+	}
+}
+
+// AddFlags registers flags for manager
+func (m *HubManagerOptions) AddFlags(fs *pflag.FlagSet) {
+	m.flags = fs
+	features.DefaultHubMutableFeatureGate.AddFlag(fs)
+	fs.StringVar(&m.WebhookFailurePolicy, "webhook-failure-policy", m.WebhookFailurePolicy,
+		"The failure policy of the registration webhooks, Fail or Ignore. The failure policies of the webhooks "+
+			"are not changed if it is empty.")
+	fs.StringSliceVar(&m.WebhookExcludedNamespaces, "webhook-excluded-namespaces", m.WebhookExcludedNamespaces,
+		"The namespaces whose objects are not validated or mutated by the registration webhooks. The namespace "+
+			"selectors of the webhooks are not changed if it is empty.")
+	fs.StringVar(&m.DebugBindAddress, "debug-bind-address", m.DebugBindAddress,
+		"The address to serve the internal state of the controllers on /debug/registration without authentication, "+
+			"e.g. 127.0.0.1:8000. The state is not served if it is empty.")
+	fs.StringVar(&m.SecureBindAddress, "secure-bind-address", m.SecureBindAddress,
+		"The address to serve the metrics on /metrics and the internal state of the controllers on "+
+			"/debug/registration over TLS, e.g. :8443. The requests are authenticated with TokenReviews and authorized "+
+			"with SubjectAccessReviews. They are not served securely if it is empty.")
+	fs.StringVar(&m.SecureServingCertFile, "secure-serving-cert-file", m.SecureServingCertFile,
+		"The serving certificate of secure-bind-address. A self-signed certificate is generated if it is empty.")
+	fs.StringVar(&m.SecureServingKeyFile, "secure-serving-key-file", m.SecureServingKeyFile,
+		"The private key of secure-serving-cert-file.")
+	fs.StringVar(&m.HealthProbeBindAddress, "health-probe-bind-address", m.HealthProbeBindAddress,
+		"The address to serve the health checks of the controllers on /healthz without authentication, e.g. :8000. "+
+			"The checks are not served if it is empty.")
+	fs.DurationVar(&m.ControllerProgressDeadline, "controller-progress-deadline", m.ControllerProgressDeadline,
+		"The period a controller with pending work is allowed to process no key before its health check fails. "+
+			"The default deadline is used if it is zero.")
+	fs.DurationVar(&m.SlowSyncThreshold, "slow-sync-threshold", m.SlowSyncThreshold,
+		"The duration after which a sync of a controller is logged and counted as slow with the key and the phase "+
+			"it spent most of its time in. The default threshold is used if it is zero.")
+	fs.DurationVar(&m.RetryBaseDelay, "retry-base-delay", m.RetryBaseDelay,
+		"The delay before the first retry of a key failed to sync, it doubles on each failure up to retry-max-delay. "+
+			"The default delay is used if it is zero.")
+	fs.DurationVar(&m.RetryMaxDelay, "retry-max-delay", m.RetryMaxDelay,
+		"The max delay before a retry of a key failed to sync. The default delay is used if it is zero.")
+	fs.Float64Var(&m.RetryQPS, "retry-qps", m.RetryQPS,
+		"The max number of the retries of the failed keys per second of a controller. The default qps is used if it is zero.")
+	fs.IntVar(&m.ControllerWorkers, "controller-workers", m.ControllerWorkers,
+		"The number of the workers of each controller, which process the keys of the controller in parallel. "+
+			"The default number is used if it is zero.")
+	fs.StringToIntVar(&m.PerControllerWorkers, "per-controller-workers", m.PerControllerWorkers,
+		"The number of the workers of the controllers by their names, which overrides controller-workers, "+
+			"e.g. managed-cluster=4,lease=4,csr-approving=4.")
+	fs.StringSliceVar(&m.Controllers, "controllers", m.Controllers,
+		"The controllers to start by their names, \"*\" starts the controllers which are not listed and \"-foo\" "+
+			"does not start the controller foo, e.g. *,-taint,-managed-cluster-set. The controllers behind a feature "+
+			"gate are started only if the feature gate is enabled as well. All controllers: "+
+			strings.Join(ControllerNames.List(), ", ")+".")
+	fs.IntVar(&m.WarmUpBatchSize, "warm-up-batch-size", m.WarmUpBatchSize,
+		"The number of the managed clusters synced in a batch after the hub controller starts, so a large fleet is "+
+			"not synced all at once. The default size is used if it is zero.")
+	fs.DurationVar(&m.WarmUpBatchInterval, "warm-up-batch-interval", m.WarmUpBatchInterval,
+		"The interval between the batches of the managed clusters synced after the hub controller starts. "+
+			"The default interval is used if it is zero.")
+	fs.StringVar(&m.ClusterSelector, "cluster-selector", m.ClusterSelector,
+		"The label selector of the managed clusters managed by the hub controller, e.g. stage=canary. The controllers "+
+			"of the whole fleet, e.g. clusterrole, are not scoped. All clusters are managed if it is empty.")
+	fs.StringSliceVar(&m.ClusterSets, "cluster-sets", m.ClusterSets,
+		"The clustersets of the managed clusters managed by the hub controller, along with cluster-selector.")
+	fs.BoolVar(&m.DisableFleetControllers, "disable-fleet-controllers", m.DisableFleetControllers,
+		"Start only the controllers managing the clusters in the scope of cluster-selector and cluster-sets, the "+
+			"controllers of the whole fleet should be started in only one of the hub controllers managing the slices.")
+	fs.StringVar(&m.InstanceName, "instance-name", m.InstanceName,
+		"The name of an independent instance of the hub controller pinned to cluster-sets, which holds the leader "+
+			"election lease registration-controller-<name>-lock and only starts the controllers managing the clusters "+
+			"in its clustersets. The other clustersets should be excluded from the hub controller of the whole fleet "+
+			"with cluster-selector, e.g. cluster.open-cluster-management.io/clusterset notin (team-a).")
+	fs.StringVar(&m.CSRApprovalPolicy, "csr-approval-policy", m.CSRApprovalPolicy,
+		"The policy on the csrs of the managed clusters managed by the hub controller, Auto to approve the renewal "+
+			"csrs of the accepted clusters and the auto approved addon csrs, or Manual to leave them to the cluster admin.")
+	fs.StringVar(&m.SupportedKubernetesVersions, "supported-kubernetes-versions", m.SupportedKubernetesVersions,
+		"The range of the minor versions of Kubernetes supported on the managed clusters, in the format of "+
+			"<min>-<max>, e.g. 1.27-1.30, either bound may be omitted. The agents report whether the version of "+
+			"their clusters is in the range with the condition "+helpers.KubernetesVersionSupportedCondition+".")
+	fs.StringVar(&m.CertManagerIssuer, "cert-manager-issuer", m.CertManagerIssuer,
+		"The cert-manager issuer which signs the client certificates of the agents registering with the signer "+
+			helpers.CertManagerSignerName+", in the format of <kind>[.<group>]/<name>, e.g. ClusterIssuer/ocm-ca. "+
+			"An Issuer is looked up in the namespace of the hub controller.")
+	fs.BoolVar(&m.CertManagerApproveRequests, "cert-manager-approve-requests", m.CertManagerApproveRequests,
+		"Approve the cert-manager CertificateRequests of the approved csrs by the hub controller, instead of the "+
+			"approvers of cert-manager. It requires the approve permission on the signer of the issuer, e.g. "+
+			"clusterissuers.cert-manager.io/<name> or issuers.cert-manager.io/<namespace>.<name>.")
+	fs.StringSliceVar(&m.RegistrationTokenBootstrapGroups, "registration-token-bootstrap-groups", m.RegistrationTokenBootstrapGroups,
+		"The groups of the bootstrap kubeconfigs of the clusters using the token registration driver. The group "+
+			"suffixed with the cluster name, e.g. system:bootstrappers:managedcluster:<cluster name>, is allowed to "+
+			"read the token of the registration agent of the accepted cluster until it joins. It is used when the "+
+			"feature TokenRegistration is enabled.")
+	fs.StringVar(&m.ServiceAccountName, "service-account-name", m.ServiceAccountName,
+		"The name of the service account of the hub controller in its namespace, which is bound to the clusterroles "+
+			"of the hub controller in the namespaces of the managed clusters. The default name is used if it is empty.")
+	fs.DurationVar(&m.BootstrapTokenRotationInterval, "bootstrap-token-rotation-interval", m.BootstrapTokenRotationInterval,
+		"The interval the shared bootstrap token of the service account "+bootstraptoken.BootstrapServiceAccountName+
+			" in the namespace of the hub controller is rotated in, the bootstrap kubeconfig of the latest token is "+
+			"published in the secret "+bootstraptoken.BootstrapKubeconfigSecretName+". The token is not maintained if it is zero.")
+	fs.IntVar(&m.BootstrapTokenGenerations, "bootstrap-token-generations", m.BootstrapTokenGenerations,
+		"The number of the latest generations of the bootstrap token which are kept valid, the older ones are revoked.")
+	fs.StringVar(&m.BootstrapHubServer, "bootstrap-hub-server", m.BootstrapHubServer,
+		"The URL of the hub apiserver reachable from the managed clusters in the published bootstrap kubeconfig, "+
+			"it is required by bootstrap-token-rotation-interval.")
+	fs.BoolVar(&m.ArchiveDetachedClusters, "archive-detached-clusters", m.ArchiveDetachedClusters,
+		"Keep an archival record with the final claims, the joined and detached time and the last availability of "+
+			"each deleted managed cluster in a ConfigMap labeled "+archive.ArchiveLabel+" in the namespace of the hub controller.")
+	fs.DurationVar(&m.ClusterArchiveRetention, "cluster-archive-retention", m.ClusterArchiveRetention,
+		"The duration the archival records of the deleted managed clusters are kept, they are kept forever if it is zero.")
+	fs.IntVar(&m.MetricsClusterLimit, "metrics-cluster-limit", m.MetricsClusterLimit,
+		"The number of the managed clusters labeled by their names in the metrics, the others are labeled as \"other\". "+
+			"Set it to 0 to opt out the per-cluster label, the metrics are still labeled by clustersets.")
+	fs.StringVar(&m.CloudEventsBrokerAddress, "cloudevents-broker-address", m.CloudEventsBrokerAddress,
+		"The address of the MQTT broker the status and the heartbeats of the managed clusters are consumed from, "+
+			"e.g. tls://broker.example.com:8883. It is used when the feature CloudEventsTransport is enabled.")
+	fs.StringVar(&m.CloudEventsCAFile, "cloudevents-ca-file", m.CloudEventsCAFile,
+		"The CA bundle to verify the serving certificate of the MQTT broker. The system roots are used if it is empty.")
+	fs.StringVar(&m.CloudEventsClientCertFile, "cloudevents-client-cert-file", m.CloudEventsClientCertFile,
+		"The client certificate the hub authenticates to the MQTT broker with.")
+	fs.StringVar(&m.CloudEventsClientKeyFile, "cloudevents-client-key-file", m.CloudEventsClientKeyFile,
+		"The key of the client certificate in cloudevents-client-cert-file.")
+	fs.StringVar(&m.ClusterProfileNamespace, "cluster-profile-namespace", m.ClusterProfileNamespace,
+		"The namespace the ClusterProfiles of the managed clusters are maintained in. It is used when the feature "+
+			"ClusterProfile is enabled.")
+	fs.BoolVar(&m.ImportClusterProfiles, "import-cluster-profiles", m.ImportClusterProfiles,
+		"Create a managed cluster, which is not accepted until the cluster admin accepts it, for each ClusterProfile "+
+			"of the other cluster managers in cluster-profile-namespace. It is used when the feature ClusterProfile is enabled.")
+	fs.StringVar(&m.ReverseTunnelSignerName, "reverse-tunnel-signer-name", m.ReverseTunnelSignerName,
+		"The signer of the client certificates of the reverse tunnel agents, the csrs of the accepted managed clusters "+
+			"with the signer are approved. The hub controller should be allowed to approve the csrs of the signer. "+
+			"It is used when the feature ReverseTunnelBootstrap is enabled.")
+	fs.StringVar(&m.InventoryExportSink, "inventory-export-sink", m.InventoryExportSink,
+		"The url of the sink the inventory of the managed clusters is exported to, https://<endpoint> to post it, "+
+			"s3://<bucket>/<key>?region=<region> to put it as an object, or kafka+https://<rest proxy>/<topic> to produce "+
+			"it to a topic. It is used when the feature InventoryExport is enabled.")
+	fs.StringVar(&m.InventoryExportCAFile, "inventory-export-ca-file", m.InventoryExportCAFile,
+		"The CA bundle to verify the serving certificate of the inventory sink. The system roots are used if it is empty.")
+	fs.StringVar(&m.InventoryExportTokenFile, "inventory-export-token-file", m.InventoryExportTokenFile,
+		"The bearer token the hub authenticates to the https and kafka inventory sinks with.")
+	fs.DurationVar(&m.InventoryExportInterval, "inventory-export-interval", m.InventoryExportInterval,
+		"The interval the inventory of the managed clusters is exported in.")
+	fs.BoolVar(&m.FIPSMode, "fips-mode", m.FIPSMode,
+		"Restrict the crypto to the FIPS-approved algorithms. The csrs of the managed clusters with the keys which are "+
+			"not approved are denied. It is always enabled in a binary built with boringcrypto.")
+}
+
+// Validate verifies the options. The errors of all of the invalid flags are aggregated, see ValidateFields.
+func (m *HubManagerOptions) Validate() error {
+	return m.ValidateFields().ToAggregate()
+}
+
+// ValidateFields verifies the options and returns an error for each invalid option. The field paths of the errors
+// are named after the flags, so the misconfigured flags are reported precisely.
+func (m *HubManagerOptions) ValidateFields() field.ErrorList {
+	errs := field.ErrorList{}
+	if len(m.WebhookFailurePolicy) > 0 {
+		errs = append(errs, webhookconfig.ValidateFailurePolicy(field.NewPath("webhook-failure-policy"),
+			admissionregistrationv1.FailurePolicyType(m.WebhookFailurePolicy))...)
+	}
+	errs = append(errs, secureserving.ValidateFields(m.SecureBindAddress, m.SecureServingCertFile, m.SecureServingKeyFile)...)
+	if m.ControllerProgressDeadline < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("controller-progress-deadline"), m.ControllerProgressDeadline.String(),
+			"must not be negative"))
+	}
+	if m.MetricsClusterLimit < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("metrics-cluster-limit"), m.MetricsClusterLimit,
+			"must not be negative"))
+	}
+	if m.SlowSyncThreshold < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("slow-sync-threshold"), m.SlowSyncThreshold.String(),
+			"must not be negative"))
+	}
+	if m.RetryBaseDelay < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("retry-base-delay"), m.RetryBaseDelay.String(),
+			"must not be negative"))
+	}
+	if m.RetryMaxDelay < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("retry-max-delay"), m.RetryMaxDelay.String(),
+			"must not be negative"))
+	}
+	if m.RetryBaseDelay > 0 && m.RetryMaxDelay > 0 && m.RetryMaxDelay < m.RetryBaseDelay {
+		errs = append(errs, field.Invalid(field.NewPath("retry-max-delay"), m.RetryMaxDelay.String(),
+			"must not be less than retry-base-delay"))
+	}
+	if m.RetryQPS < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("retry-qps"), m.RetryQPS,
+			"must not be negative"))
+	}
+	if m.ControllerWorkers < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("controller-workers"), m.ControllerWorkers,
+			"must not be negative"))
+	}
+	if m.WarmUpBatchSize < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("warm-up-batch-size"), m.WarmUpBatchSize,
+			"must not be negative"))
+	}
+	if m.WarmUpBatchInterval < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("warm-up-batch-interval"), m.WarmUpBatchInterval.String(),
+			"must not be negative"))
+	}
+	if len(m.ClusterSelector) > 0 {
+		if _, err := labels.Parse(m.ClusterSelector); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("cluster-selector"), m.ClusterSelector, err.Error()))
+		}
+	}
+	for i, clusterSet := range m.ClusterSets {
+		for _, msg := range validation.IsValidLabelValue(clusterSet) {
+			errs = append(errs, field.Invalid(field.NewPath("cluster-sets").Index(i), clusterSet, msg))
+		}
+		if len(clusterSet) == 0 {
+			errs = append(errs, field.Invalid(field.NewPath("cluster-sets").Index(i), clusterSet, "must not be empty"))
+		}
+	}
+	if len(m.InstanceName) > 0 {
+		for _, msg := range validation.IsDNS1123Label(m.InstanceName) {
+			errs = append(errs, field.Invalid(field.NewPath("instance-name"), m.InstanceName, msg))
+		}
+		if len(m.ClusterSets) == 0 {
+			errs = append(errs, field.Required(field.NewPath("cluster-sets"), "required by instance-name"))
+		}
+	}
+	if len(m.CSRApprovalPolicy) > 0 && !csr.ApprovalPolicies.Has(m.CSRApprovalPolicy) {
+		errs = append(errs, field.NotSupported(field.NewPath("csr-approval-policy"), m.CSRApprovalPolicy,
+			csr.ApprovalPolicies.List()))
+	}
+	if len(m.SupportedKubernetesVersions) > 0 {
+		if _, err := helpers.ParseKubernetesVersionRange(m.SupportedKubernetesVersions); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("supported-kubernetes-versions"),
+				m.SupportedKubernetesVersions, err.Error()))
+		}
+	}
+	if len(m.CertManagerIssuer) > 0 {
+		if _, err := certmanager.ParseIssuerRef(m.CertManagerIssuer); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("cert-manager-issuer"), m.CertManagerIssuer, err.Error()))
+		}
+	}
+	if m.CertManagerApproveRequests && len(m.CertManagerIssuer) == 0 {
+		errs = append(errs, field.Invalid(field.NewPath("cert-manager-approve-requests"), m.CertManagerApproveRequests,
+			"requires cert-manager-issuer"))
+	}
+	if len(m.CloudEventsBrokerAddress) > 0 && !cloudevents.IsValidBrokerAddress(m.CloudEventsBrokerAddress) {
+		errs = append(errs, field.Invalid(field.NewPath("cloudevents-broker-address"), m.CloudEventsBrokerAddress,
+			"must be a tls:// or tcp:// address with a port, e.g. tls://broker.example.com:8883"))
+	}
+	if (len(m.CloudEventsClientCertFile) == 0) != (len(m.CloudEventsClientKeyFile) == 0) {
+		errs = append(errs, field.Required(field.NewPath("cloudevents-client-key-file"), "must be set with cloudevents-client-cert-file"))
+	}
+	if features.DefaultHubMutableFeatureGate.Enabled(features.ClusterProfile) {
+		for _, msg := range validation.IsDNS1123Label(m.ClusterProfileNamespace) {
+			errs = append(errs, field.Invalid(field.NewPath("cluster-profile-namespace"), m.ClusterProfileNamespace, msg))
+		}
+	}
+	if features.DefaultHubMutableFeatureGate.Enabled(features.ReverseTunnelBootstrap) {
+		for _, msg := range validation.IsQualifiedName(m.ReverseTunnelSignerName) {
+			errs = append(errs, field.Invalid(field.NewPath("reverse-tunnel-signer-name"), m.ReverseTunnelSignerName, msg))
+		}
+		// the reverse tunnel credentials must not authenticate to the hub kube-apiserver
+		if helpers.IsClusterClientSignerName(m.ReverseTunnelSignerName) {
+			errs = append(errs, field.Invalid(field.NewPath("reverse-tunnel-signer-name"), m.ReverseTunnelSignerName,
+				"must not be a signer of the client certificates of the hub"))
+		}
+	}
+	if m.BootstrapTokenRotationInterval != 0 {
+		if m.BootstrapTokenRotationInterval < bootstraptoken.MinRotationInterval {
+			errs = append(errs, field.Invalid(field.NewPath("bootstrap-token-rotation-interval"),
+				m.BootstrapTokenRotationInterval.String(),
+				fmt.Sprintf("must be zero or at least %v", bootstraptoken.MinRotationInterval)))
+		}
+		if m.BootstrapTokenGenerations < 1 {
+			errs = append(errs, field.Invalid(field.NewPath("bootstrap-token-generations"), m.BootstrapTokenGenerations,
+				"must be at least 1"))
+		}
+		if len(m.BootstrapHubServer) == 0 {
+			errs = append(errs, field.Required(field.NewPath("bootstrap-hub-server"),
+				"required by bootstrap-token-rotation-interval"))
+		}
+	}
+	if m.ClusterArchiveRetention < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("cluster-archive-retention"), m.ClusterArchiveRetention.String(),
+			"must not be negative"))
+	}
+	if features.DefaultHubMutableFeatureGate.Enabled(features.InventoryExport) {
+		if len(m.InventoryExportSink) > 0 {
+			if _, err := inventory.NewSink(inventory.SinkConfig{URL: m.InventoryExportSink}); err != nil {
+				errs = append(errs, field.Invalid(field.NewPath("inventory-export-sink"), m.InventoryExportSink, err.Error()))
+			}
+		}
+		if m.InventoryExportInterval <= 0 {
+			errs = append(errs, field.Invalid(field.NewPath("inventory-export-interval"), m.InventoryExportInterval.String(),
+				"must be greater than zero"))
+		}
+	}
+	if !m.FIPSMode && fips.BuiltIn() {
+		errs = append(errs, field.Invalid(field.NewPath("fips-mode"), m.FIPSMode,
+			"may not be disabled in a binary built with boringcrypto"))
+	}
+	if m.FIPSMode {
+		if strings.HasPrefix(m.CloudEventsBrokerAddress, "tcp://") {
+			errs = append(errs, field.Forbidden(field.NewPath("cloudevents-broker-address"),
+				"must be a tls:// address in the FIPS mode"))
+		}
+		if strings.HasPrefix(m.InventoryExportSink, "http://") || strings.HasPrefix(m.InventoryExportSink, "kafka+http://") {
+			errs = append(errs, field.Forbidden(field.NewPath("inventory-export-sink"),
+				"must not be a plaintext http sink in the FIPS mode"))
+		}
+	}
+	for i, controller := range m.Controllers {
+		name := strings.TrimPrefix(controller, "-")
+		if controller != "*" && !ControllerNames.Has(name) {
+			errs = append(errs, field.NotSupported(field.NewPath("controllers").Index(i), controller,
+				append([]string{"*"}, ControllerNames.List()...)))
+		}
+	}
+	for _, name := range sets.StringKeySet(m.PerControllerWorkers).List() {
+		switch {
+		case !ControllerNames.Has(name):
+			errs = append(errs, field.NotSupported(field.NewPath("per-controller-workers").Key(name), name, ControllerNames.List()))
+		case m.PerControllerWorkers[name] <= 0:
+			errs = append(errs, field.Invalid(field.NewPath("per-controller-workers").Key(name), m.PerControllerWorkers[name],
+				"must be positive"))
+		}
+	}
+	return errs
+}
+
+// controllerWorkers returns the number of the workers of a controller configured by the options
+func (m *HubManagerOptions) controllerWorkers(name string) int {
+	if workers, ok := m.PerControllerWorkers[name]; ok && workers > 0 {
+		return workers
+	}
+	if m.ControllerWorkers > 0 {
+		return m.ControllerWorkers
+	}
+	return DefaultControllerWorkers
+}
+
+// serviceAccountName returns the name of the service account of the hub controller
+func (m *HubManagerOptions) serviceAccountName() string {
+	if len(m.ServiceAccountName) > 0 {
+		return m.ServiceAccountName
+	}
+	return DefaultServiceAccountName
+}
+
+// controllerSelected returns true if the controller is selected by Controllers
+func (m *HubManagerOptions) controllerSelected(name string) bool {
+	if len(m.Controllers) == 0 {
+		return true
+	}
+	selected := false
+	for _, controller := range m.Controllers {
+		switch controller {
+		case name:
+			return true
+		case "-" + name:
+			return false
+		case "*":
+			selected = true
+		}
+	}
+	return selected
+}
+
+// webhookPolicy returns the policy of the registration webhooks configured by the options
+func (m *HubManagerOptions) webhookPolicy() webhookconfig.WebhookPolicy {
+	return webhookconfig.WebhookPolicy{
+		FailurePolicy:      admissionregistrationv1.FailurePolicyType(m.WebhookFailurePolicy),
+		ExcludedNamespaces: m.WebhookExcludedNamespaces,
+	}
+}
+
+// RunControllerManager starts the controllers on hub with the default options.
 func RunControllerManager(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
+	return NewHubManagerOptions().RunControllerManager(ctx, controllerContext)
+}
+
+// RunControllerManager starts the controllers on hub to manage spoke cluster registration.
+func (m *HubManagerOptions) RunControllerManager(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
+	if err := m.applyComponentConfig(controllerContext.ComponentConfig); err != nil {
+		return err
+	}
+
+	return RunHubManager(ctx, &EmbeddedOptions{
+		HubManagerOptions: m,
+		KubeConfig:        controllerContext.KubeConfig,
+		OperatorNamespace: controllerContext.OperatorNamespace,
+		EventRecorder:     controllerContext.EventRecorder,
+	})
+}
+
+// The names of the controllers on hub, which are used to disable the controllers in EmbeddedOptions.
+const (
+	ManagedClusterControllerName            = "managed-cluster"
+	TaintControllerName                     = "taint"
+	CSRApprovingControllerName              = "csr-approving"
+	LeaseControllerName                     = "lease"
+	RBACFinalizerControllerName             = "rbac-finalizer"
+	ManagedClusterSetControllerName         = "managed-cluster-set"
+	ClusterRoleControllerName               = "clusterrole"
+	AddOnHealthCheckControllerName          = "addon-health-check"
+	AddOnFeatureDiscoveryControllerName     = "addon-feature-discovery"
+	AddOnCSRCleanupControllerName           = "addon-csr-cleanup"
+	AddOnTokenServiceAccountControllerName  = "addon-token-service-account"
+	AddOnRBACControllerName                 = "addon-rbac"
+	AddOnHealthAggregationControllerName    = "addon-health-aggregation"
+	DefaultManagedClusterSetControllerName  = "default-managed-cluster-set"
+	WebhookConfigurationControllerName      = "webhook-configuration"
+	WebhookServingCertificateControllerName = "webhook-serving-certificate"
+	CertManagerSignerControllerName         = "cert-manager-signer"
+	RegistrationTokenControllerName         = "registration-token"
+	AWSIAMRoleMappingControllerName         = "aws-iam-role-mapping"
+	ClusterEventsConsumerControllerName     = "cluster-events-consumer"
+	ClusterProfileControllerName            = "cluster-profile"
+	ClusterAPIImportControllerName          = "cluster-api-import"
+	InventoryExportControllerName           = "inventory-export"
+	HubCARotationControllerName             = "hub-ca-rotation"
+	BootstrapTokenControllerName            = "bootstrap-token"
+	ClusterArchiveControllerName            = "cluster-archive"
+	ClusterRenameControllerName             = "cluster-rename"
+	AgentConfigControllerName               = "agent-config"
+	BootstrapCleanupControllerName          = "bootstrap-cleanup"
+)
+
+// ControllerNames are the names of all of the controllers on hub, each of them is created by hubControllers
+var ControllerNames = sets.NewString(
+	ManagedClusterControllerName,
+	TaintControllerName,
+	CSRApprovingControllerName,
+	LeaseControllerName,
+	RBACFinalizerControllerName,
+	ManagedClusterSetControllerName,
+	ClusterRoleControllerName,
+	AddOnHealthCheckControllerName,
+	AddOnFeatureDiscoveryControllerName,
+	AddOnCSRCleanupControllerName,
+	AddOnTokenServiceAccountControllerName,
+	AddOnRBACControllerName,
+	AddOnHealthAggregationControllerName,
+	DefaultManagedClusterSetControllerName,
+	WebhookConfigurationControllerName,
+	WebhookServingCertificateControllerName,
+	CertManagerSignerControllerName,
+	RegistrationTokenControllerName,
+	AWSIAMRoleMappingControllerName,
+	ClusterEventsConsumerControllerName,
+	ClusterProfileControllerName,
+	ClusterAPIImportControllerName,
+	InventoryExportControllerName,
+	HubCARotationControllerName,
+	BootstrapTokenControllerName,
+	ClusterArchiveControllerName,
+	ClusterRenameControllerName,
+	AgentConfigControllerName,
+	BootstrapCleanupControllerName,
+)
+
+// EmbeddedOptions holds the configuration to run the hub controllers in another binary, e.g. an aggregated
+// control plane which runs the controllers of several projects in one process.
+type EmbeddedOptions struct {
+	*HubManagerOptions
+
+	// KubeConfig is the client config of the hub apiserver, it is required.
+	KubeConfig *rest.Config
+	// OperatorNamespace is the namespace of the hub controller, it is required by the webhook serving certificate
+	// controller, the cert-manager signer controller, the hub CA rotation controller, the bootstrap token controller,
+	// the cluster archive controller, the registration token controller and the agent config controller.
+	OperatorNamespace string
+	// EventRecorder records the events of the controllers, it is required.
+	EventRecorder events.Recorder
+
+	// DisabledControllers are the names of the controllers which are not started, see ControllerNames. The
+	// controllers behind a feature gate are started only if the feature gate is enabled as well.
+	DisabledControllers []string
+
+	// The shared informer factories used by the controllers. They are created from KubeConfig if not set. The
+	// injected factories are started by RunHubManager as well, which is a no-op for the informers started already.
+	// The csrs are always watched with the filtered informer factories created by RunHubManager.
+	KubeInformers    kubeinformers.SharedInformerFactory
+	ClusterInformers clusterv1informers.SharedInformerFactory
+	WorkInformers    workv1informers.SharedInformerFactory
+	AddOnInformers   addoninformers.SharedInformerFactory
+}
+
+// Validate verifies the options. The errors of all of the invalid options are aggregated, see ValidateFields.
+func (o *EmbeddedOptions) Validate() error {
+	return o.ValidateFields().ToAggregate()
+}
+
+// ValidateFields verifies the options and returns an error for each invalid option
+func (o *EmbeddedOptions) ValidateFields() field.ErrorList {
+	errs := field.ErrorList{}
+	if o.KubeConfig == nil {
+		errs = append(errs, field.Required(field.NewPath("kubeConfig"), ""))
+	}
+	if o.EventRecorder == nil {
+		errs = append(errs, field.Required(field.NewPath("eventRecorder"), ""))
+	}
+	if len(o.OperatorNamespace) == 0 && o.enabled(AddOnTokenServiceAccountControllerName) {
+		errs = append(errs, field.Required(field.NewPath("operatorNamespace"),
+			fmt.Sprintf("required by the %s controller", AddOnTokenServiceAccountControllerName)))
+	}
+	if len(o.OperatorNamespace) == 0 && o.enabled(WebhookServingCertificateControllerName) &&
+		features.DefaultHubMutableFeatureGate.Enabled(features.WebhookServingCertRotation) {
+		errs = append(errs, field.Required(field.NewPath("operatorNamespace"),
+			fmt.Sprintf("required by the %s controller", WebhookServingCertificateControllerName)))
+	}
+	if len(o.OperatorNamespace) == 0 && o.HubManagerOptions != nil && len(o.CertManagerIssuer) > 0 &&
+		o.enabled(CertManagerSignerControllerName) {
+		errs = append(errs, field.Required(field.NewPath("operatorNamespace"),
+			fmt.Sprintf("required by the %s controller", CertManagerSignerControllerName)))
+	}
+	if len(o.OperatorNamespace) == 0 && o.enabled(HubCARotationControllerName) &&
+		features.DefaultHubMutableFeatureGate.Enabled(features.HubCARotation) {
+		errs = append(errs, field.Required(field.NewPath("operatorNamespace"),
+			fmt.Sprintf("required by the %s controller", HubCARotationControllerName)))
+	}
+	if len(o.OperatorNamespace) == 0 && o.HubManagerOptions != nil && o.BootstrapTokenRotationInterval > 0 &&
+		o.enabled(BootstrapTokenControllerName) {
+		errs = append(errs, field.Required(field.NewPath("operatorNamespace"),
+			fmt.Sprintf("required by the %s controller", BootstrapTokenControllerName)))
+	}
+	if len(o.OperatorNamespace) == 0 && o.HubManagerOptions != nil && o.ArchiveDetachedClusters &&
+		o.enabled(ClusterArchiveControllerName) {
+		errs = append(errs, field.Required(field.NewPath("operatorNamespace"),
+			fmt.Sprintf("required by the %s controller", ClusterArchiveControllerName)))
+	}
+	if len(o.OperatorNamespace) == 0 && o.enabled(RegistrationTokenControllerName) &&
+		features.DefaultHubMutableFeatureGate.Enabled(features.TokenRegistration) {
+		errs = append(errs, field.Required(field.NewPath("operatorNamespace"),
+			fmt.Sprintf("required by the %s controller", RegistrationTokenControllerName)))
+	}
+	if len(o.OperatorNamespace) == 0 && o.enabled(AgentConfigControllerName) &&
+		features.DefaultHubMutableFeatureGate.Enabled(features.AgentConfigPropagation) {
+		errs = append(errs, field.Required(field.NewPath("operatorNamespace"),
+			fmt.Sprintf("required by the %s controller", AgentConfigControllerName)))
+	}
+	if o.HubManagerOptions != nil && o.ClusterInformers != nil &&
+		(len(o.ClusterSelector) > 0 || len(o.ClusterSets) > 0) {
+		errs = append(errs, field.Forbidden(field.NewPath("clusterInformers"),
+			"may not be injected when the hub controller is scoped by cluster-selector or cluster-sets"))
+	}
+	for i, name := range o.DisabledControllers {
+		if !ControllerNames.Has(name) {
+			errs = append(errs, field.NotSupported(field.NewPath("disabledControllers").Index(i), name, ControllerNames.List()))
+		}
+	}
+	if o.HubManagerOptions != nil {
+		errs = append(errs, o.HubManagerOptions.ValidateFields()...)
+	}
+	return errs
+}
+
+// enabled returns true if the controller is not disabled
+func (o *EmbeddedOptions) enabled(name string) bool {
+	return !sets.NewString(o.DisabledControllers...).Has(name) &&
+		(o.HubManagerOptions == nil || (o.controllerSelected(name) && o.controllerStarted(name)))
+}
+
+// RunHubManager starts the hub controllers which are not disabled in the options, and blocks until the context
+// is done.
+func RunHubManager(ctx context.Context, o *EmbeddedOptions) error {
+	if o.HubManagerOptions == nil {
+		o.HubManagerOptions = NewHubManagerOptions()
+	}
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	features.ReportFeatureGates(features.Hub)
+	if o.FIPSMode {
+		fips.Enable()
+	}
+
+	// the controllers log with the logger of the hub controller in the context
+	ctx = helpers.NewComponentContext(ctx, componentName)
+
 	// If qps in kubconfig is not set, increase the qps and burst to enhance the ability of kube client to handle
 	// requests in concurrent
 	// TODO: Use ClientConnectionOverrides flags to change qps/burst when library-go exposes them in the future
-	kubeConfig := rest.CopyConfig(controllerContext.KubeConfig)
+	kubeConfig := rest.CopyConfig(o.KubeConfig)
 	if kubeConfig.QPS == 0.0 {
 		kubeConfig.QPS = 100.0
 		kubeConfig.Burst = 200
@@ -62,109 +847,119 @@ func RunControllerManager(ctx context.Context, controllerContext *controllercmd.
 		return err
 	}
 
-	clusterInformers := clusterv1informers.NewSharedInformerFactory(clusterClient, 10*time.Minute)
-	workInformers := workv1informers.NewSharedInformerFactory(workClient, 10*time.Minute)
-	kubeInfomers := kubeinformers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
-	addOnInformers := addoninformers.NewSharedInformerFactory(addOnClient, 10*time.Minute)
-
-	managedClusterController := managedcluster.NewManagedClusterController(
-		kubeClient,
-		clusterClient,
-		clusterInformers.Cluster().V1().ManagedClusters(),
-		controllerContext.EventRecorder,
-	)
-
-	taintController := taint.NewTaintController(
-		clusterClient,
-		clusterInformers.Cluster().V1().ManagedClusters(),
-		controllerContext.EventRecorder,
-	)
-
-	csrController := csr.NewCSRApprovingController(
-		kubeClient,
-		kubeInfomers.Certificates().V1().CertificateSigningRequests(),
-		controllerContext.EventRecorder,
-	)
-
-	leaseController := lease.NewClusterLeaseController(
-		kubeClient,
-		clusterClient,
-		clusterInformers.Cluster().V1().ManagedClusters(),
-		kubeInfomers.Coordination().V1().Leases(),
-		ResyncInterval, //TODO: this interval time should be allowed to change from outside
-		controllerContext.EventRecorder,
-	)
-
-	rbacFinalizerController := rbacfinalizerdeletion.NewFinalizeController(
-		kubeInfomers.Rbac().V1().Roles(),
-		kubeInfomers.Rbac().V1().RoleBindings(),
-		kubeInfomers.Core().V1().Namespaces().Lister(),
-		clusterInformers.Cluster().V1().ManagedClusters().Lister(),
-		workInformers.Work().V1().ManifestWorks().Lister(),
-		kubeClient.RbacV1(),
-		controllerContext.EventRecorder,
-	)
+	clusterInformers := o.ClusterInformers
+	if clusterInformers == nil {
+		clusterInformers = clusterv1informers.NewSharedInformerFactory(clusterClient, 10*time.Minute)
+		installClusterInformerTransforms(clusterInformers, nil)
+	}
+	// the controllers in ScopedControllerNames only watch the clusters in the scope of the hub controller
+	scopedClusterInformers := clusterInformers
+	clusterScope, err := o.clusterScope()
+	if err != nil {
+		return err
+	}
+	if clusterScope != nil {
+		scopedClusterInformers = clusterv1informers.NewSharedInformerFactory(clusterClient, 10*time.Minute)
+		installClusterInformerTransforms(scopedClusterInformers, clusterScope)
+	}
+	workInformers := o.WorkInformers
+	if workInformers == nil {
+		workInformers = workv1informers.NewSharedInformerFactory(workClient, 10*time.Minute)
+		installWorkInformerTransforms(workInformers)
+	}
+	kubeInfomers := o.KubeInformers
+	if kubeInfomers == nil {
+		metadataClient, err := metadata.NewForConfig(kubeConfig)
+		if err != nil {
+			return err
+		}
+		kubeInfomers = kubeinformers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+		installKubeInformerMetadata(kubeInfomers, metadataClient)
+	}
+	addOnInformers := o.AddOnInformers
+	if addOnInformers == nil {
+		addOnInformers = addoninformers.NewSharedInformerFactory(addOnClient, 10*time.Minute)
+		installAddOnInformerTransforms(addOnInformers)
+	}
 
-	managedClusterSetController := managedclusterset.NewManagedClusterSetController(
-		clusterClient,
-		clusterInformers.Cluster().V1().ManagedClusters(),
-		clusterInformers.Cluster().V1beta1().ManagedClusterSets(),
-		controllerContext.EventRecorder,
-	)
+	// only watch the csrs of the managed clusters and their addons, which are labeled with the cluster name
+	clusterCSRInformers := helpers.NewFilteredCSRInformerFactory(kubeClient, 10*time.Minute, func(listOptions *metav1.ListOptions) {
+		listOptions.LabelSelector = clientcert.ClusterNameLabel
+	})
+	// only watch the csrs of the webhook serving certificate
+	webhookCSRInformers := helpers.NewFilteredCSRInformerFactory(kubeClient, 10*time.Minute, func(listOptions *metav1.ListOptions) {
+		listOptions.FieldSelector = fields.OneTermEqualSelector("spec.signerName", webhookcert.SignerName).String()
+	})
 
-	clusterroleController := clusterrole.NewManagedClusterClusterroleController(
+	// only watch the namespace of the hub controller for the secrets of the webhook serving certificate and the
+	// hub CA bundle configmap
+	namespacedKubeInformers := kubeinformers.NewSharedInformerFactoryWithOptions(
 		kubeClient,
-		clusterInformers.Cluster().V1().ManagedClusters(),
-		kubeInfomers.Rbac().V1().ClusterRoles(),
-		controllerContext.EventRecorder,
+		10*time.Minute,
+		kubeinformers.WithNamespace(o.OperatorNamespace),
 	)
 
-	addOnHealthCheckController := addon.NewManagedClusterAddOnHealthCheckController(
-		addOnClient,
-		addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
-		clusterInformers.Cluster().V1().ManagedClusters(),
-		controllerContext.EventRecorder,
-	)
-
-	addOnFeatureDiscoveryController := addon.NewAddOnFeatureDiscoveryController(
-		clusterClient,
-		clusterInformers.Cluster().V1().ManagedClusters(),
-		addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
-		controllerContext.EventRecorder,
-	)
+	// the controllers are grouped by their names on hub, which the number of workers is configured by
+	controllers, err := (&hubControllerContext{
+		EmbeddedOptions:         o,
+		kubeConfig:              kubeConfig,
+		kubeClient:              kubeClient,
+		clusterClient:           clusterClient,
+		addOnClient:             addOnClient,
+		recorder:                o.EventRecorder,
+		clusterInformers:        clusterInformers,
+		scopedClusterInformers:  scopedClusterInformers,
+		clusterScope:            clusterScope,
+		workInformers:           workInformers,
+		kubeInformers:           kubeInfomers,
+		addOnInformers:          addOnInformers,
+		clusterCSRInformers:     clusterCSRInformers,
+		webhookCSRInformers:     webhookCSRInformers,
+		namespacedKubeInformers: namespacedKubeInformers,
+	}).newControllers()
+	if err != nil {
+		return err
+	}
 
-	var defaultManagedClusterSetController, defaultManagedClusterSetLabelController factory.Controller
-	if features.DefaultHubMutableFeatureGate.Enabled(features.DefaultClusterSet) {
-		defaultManagedClusterSetController = managedclusterset.NewDefaultManagedClusterSetController(
-			clusterClient.ClusterV1beta1(),
-			clusterInformers.Cluster().V1beta1().ManagedClusterSets(),
-			controllerContext.EventRecorder,
-		)
+	if len(o.DebugBindAddress) > 0 {
+		if err := debug.Serve(ctx, o.DebugBindAddress); err != nil {
+			return err
+		}
+	}
+	if len(o.SecureBindAddress) > 0 {
+		if err := secureserving.Serve(ctx, o.SecureBindAddress, o.SecureServingCertFile, o.SecureServingKeyFile,
+			kubeClient); err != nil {
+			return err
+		}
+	}
 
-		defaultManagedClusterSetLabelController = managedclusterset.NewDefaultManagedClusterSetLabelController(
-			clusterClient,
-			clusterInformers.Cluster().V1().ManagedClusters(),
-			controllerContext.EventRecorder,
-		)
+	health.SetProgressDeadline(o.ControllerProgressDeadline)
+	health.SetSlowSyncThreshold(o.SlowSyncThreshold)
+	health.SetRetryLimits(o.RetryBaseDelay, o.RetryMaxDelay, o.RetryQPS)
+	health.SetWarmUpBatches(o.WarmUpBatchSize, o.WarmUpBatchInterval)
+	if len(o.HealthProbeBindAddress) > 0 {
+		if err := health.Serve(ctx, o.HealthProbeBindAddress); err != nil {
+			return err
+		}
 	}
 
+	helpers.DefaultClusterLabeler.SetLimit(o.MetricsClusterLimit)
+	helpers.DefaultClusterLabeler.SetClusterLister(scopedClusterInformers.Cluster().V1().ManagedClusters().Lister())
+
+	// the factories only start the informers requested by the enabled controllers
 	go clusterInformers.Start(ctx.Done())
+	go scopedClusterInformers.Start(ctx.Done())
 	go workInformers.Start(ctx.Done())
 	go kubeInfomers.Start(ctx.Done())
 	go addOnInformers.Start(ctx.Done())
+	go namespacedKubeInformers.Start(ctx.Done())
+	go clusterCSRInformers.Start(ctx.Done())
+	go webhookCSRInformers.Start(ctx.Done())
 
-	go managedClusterController.Run(ctx, 1)
-	go taintController.Run(ctx, 1)
-	go csrController.Run(ctx, 1)
-	go leaseController.Run(ctx, 1)
-	go rbacFinalizerController.Run(ctx, 1)
-	go managedClusterSetController.Run(ctx, 1)
-	go clusterroleController.Run(ctx, 1)
-	go addOnHealthCheckController.Run(ctx, 1)
-	go addOnFeatureDiscoveryController.Run(ctx, 1)
-	if features.DefaultHubMutableFeatureGate.Enabled(features.DefaultClusterSet) {
-		go defaultManagedClusterSetController.Run(ctx, 1)
-		go defaultManagedClusterSetLabelController.Run(ctx, 1)
+	for name, ctrls := range controllers {
+		for _, controller := range ctrls {
+			go health.RunController(ctx, controller, o.controllerWorkers(name))
+		}
 	}
 
 	<-ctx.Done()
@@ -0,0 +1,327 @@
+package hub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestEmbeddedOptionsValidate(t *testing.T) {
+	cases := []struct {
+		name        string
+		options     *EmbeddedOptions
+		expectedErr string
+	}{
+		{
+			name:        "errors are aggregated",
+			options:     &EmbeddedOptions{OperatorNamespace: "open-cluster-management-hub"},
+			expectedErr: "[kubeConfig: Required value, eventRecorder: Required value]",
+		},
+		{
+			name:        "no kubeconfig",
+			options:     &EmbeddedOptions{EventRecorder: eventstesting.NewTestingEventRecorder(t), OperatorNamespace: "open-cluster-management-hub"},
+			expectedErr: "kubeConfig: Required value",
+		},
+		{
+			name:        "no event recorder",
+			options:     &EmbeddedOptions{KubeConfig: &rest.Config{}, OperatorNamespace: "open-cluster-management-hub"},
+			expectedErr: "eventRecorder: Required value",
+		},
+		{
+			name: "unknown controller",
+			options: &EmbeddedOptions{
+				KubeConfig:          &rest.Config{},
+				EventRecorder:       eventstesting.NewTestingEventRecorder(t),
+				DisabledControllers: []string{LeaseControllerName, "foo"},
+				OperatorNamespace:   "open-cluster-management-hub",
+			},
+			expectedErr: "disabledControllers[1]: Unsupported value: \"foo\"",
+		},
+		{
+			name: "invalid webhook failure policy",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{WebhookFailurePolicy: "Retry"},
+				KubeConfig:        &rest.Config{},
+				EventRecorder:     eventstesting.NewTestingEventRecorder(t),
+				OperatorNamespace: "open-cluster-management-hub",
+			},
+			expectedErr: "webhook-failure-policy: Unsupported value: \"Retry\": supported values: \"Fail\", \"Ignore\"",
+		},
+		{
+			name: "negative controller workers",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{WebhookFailurePolicy: "Fail", ControllerWorkers: -1},
+				KubeConfig:        &rest.Config{},
+				EventRecorder:     eventstesting.NewTestingEventRecorder(t),
+				OperatorNamespace: "open-cluster-management-hub",
+			},
+			expectedErr: "controller-workers: Invalid value: -1: must not be negative",
+		},
+		{
+			name: "negative warm up batch size",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{WebhookFailurePolicy: "Fail", WarmUpBatchSize: -1},
+				KubeConfig:        &rest.Config{},
+				EventRecorder:     eventstesting.NewTestingEventRecorder(t),
+				OperatorNamespace: "open-cluster-management-hub",
+			},
+			expectedErr: "warm-up-batch-size: Invalid value: -1: must not be negative",
+		},
+		{
+			name: "invalid cert-manager issuer",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{WebhookFailurePolicy: "Fail", CertManagerIssuer: "ocm-ca"},
+				KubeConfig:        &rest.Config{},
+				OperatorNamespace: "open-cluster-management-hub",
+				EventRecorder:     eventstesting.NewTestingEventRecorder(t),
+			},
+			expectedErr: "cert-manager-issuer: Invalid value: \"ocm-ca\"",
+		},
+		{
+			name: "approve cert-manager requests without issuer",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{WebhookFailurePolicy: "Fail", CertManagerApproveRequests: true},
+				KubeConfig:        &rest.Config{},
+				OperatorNamespace: "open-cluster-management-hub",
+				EventRecorder:     eventstesting.NewTestingEventRecorder(t),
+			},
+			expectedErr: "cert-manager-approve-requests: Invalid value: true: requires cert-manager-issuer",
+		},
+		{
+			name: "cert-manager issuer without operator namespace",
+			options: &EmbeddedOptions{
+				HubManagerOptions:   &HubManagerOptions{WebhookFailurePolicy: "Fail", CertManagerIssuer: "ClusterIssuer/ocm-ca"},
+				KubeConfig:          &rest.Config{},
+				EventRecorder:       eventstesting.NewTestingEventRecorder(t),
+				DisabledControllers: []string{WebhookServingCertificateControllerName},
+			},
+			expectedErr: "[operatorNamespace: Required value: required by the addon-token-service-account controller, " +
+				"operatorNamespace: Required value: required by the cert-manager-signer controller]",
+		},
+		{
+			name: "invalid per controller workers",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{
+					WebhookFailurePolicy: "Fail",
+					PerControllerWorkers: map[string]int{LeaseControllerName: 0, "foo": 2},
+				},
+				KubeConfig:        &rest.Config{},
+				EventRecorder:     eventstesting.NewTestingEventRecorder(t),
+				OperatorNamespace: "open-cluster-management-hub",
+			},
+			expectedErr: "[per-controller-workers[foo]: Unsupported value: \"foo\"",
+		},
+		{
+			name: "invalid cloudevents broker address",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{
+					WebhookFailurePolicy:      "Fail",
+					CloudEventsBrokerAddress:  "broker.example.com:8883",
+					CloudEventsClientCertFile: "/hub/mqtt/tls.crt",
+				},
+				KubeConfig:        &rest.Config{},
+				EventRecorder:     eventstesting.NewTestingEventRecorder(t),
+				OperatorNamespace: "open-cluster-management-hub",
+			},
+			expectedErr: "[cloudevents-broker-address: Invalid value: \"broker.example.com:8883\": must be a tls:// or tcp:// address with a port, e.g. tls://broker.example.com:8883, cloudevents-client-key-file: Required value: must be set with cloudevents-client-cert-file]",
+		},
+		{
+			name: "plaintext connections in fips mode",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{
+					WebhookFailurePolicy:     "Fail",
+					CloudEventsBrokerAddress: "tcp://broker.example.com:1883",
+					InventoryExportSink:      "kafka+http://kafka-rest.example.com:8082/fleet-inventory",
+					FIPSMode:                 true,
+				},
+				KubeConfig:        &rest.Config{},
+				EventRecorder:     eventstesting.NewTestingEventRecorder(t),
+				OperatorNamespace: "open-cluster-management-hub",
+			},
+			expectedErr: "[cloudevents-broker-address: Forbidden: must be a tls:// address in the FIPS mode, " +
+				"inventory-export-sink: Forbidden: must not be a plaintext http sink in the FIPS mode]",
+		},
+		{
+			name: "invalid cluster scope",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{
+					WebhookFailurePolicy: "Fail",
+					ClusterSelector:      "stage in canary",
+					ClusterSets:          []string{"tenant-a", ""},
+				},
+				KubeConfig:        &rest.Config{},
+				EventRecorder:     eventstesting.NewTestingEventRecorder(t),
+				ClusterInformers:  clusterinformers.NewSharedInformerFactory(clusterfake.NewSimpleClientset(), time.Minute),
+				OperatorNamespace: "open-cluster-management-hub",
+			},
+			expectedErr: "[clusterInformers: Forbidden: may not be injected when the hub controller is scoped by " +
+				"cluster-selector or cluster-sets, cluster-selector: Invalid value: \"stage in canary\"",
+		},
+		{
+			name: "invalid instance",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{
+					WebhookFailurePolicy: "Fail",
+					InstanceName:         "Team-A",
+					CSRApprovalPolicy:    "Deny",
+				},
+				KubeConfig:        &rest.Config{},
+				EventRecorder:     eventstesting.NewTestingEventRecorder(t),
+				OperatorNamespace: "open-cluster-management-hub",
+			},
+			expectedErr: "[instance-name: Invalid value: \"Team-A\"",
+		},
+		{
+			name: "invalid supported kubernetes versions",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{WebhookFailurePolicy: "Fail", SupportedKubernetesVersions: "1.30-1.27"},
+				KubeConfig:        &rest.Config{},
+				EventRecorder:     eventstesting.NewTestingEventRecorder(t),
+				OperatorNamespace: "open-cluster-management-hub",
+			},
+			expectedErr: "supported-kubernetes-versions: Invalid value: \"1.30-1.27\"",
+		},
+		{
+			name: "invalid bootstrap token rotation",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{
+					WebhookFailurePolicy:           "Fail",
+					BootstrapTokenRotationInterval: time.Minute,
+				},
+				KubeConfig:    &rest.Config{},
+				EventRecorder: eventstesting.NewTestingEventRecorder(t),
+			},
+			expectedErr: "[operatorNamespace: Required value: required by the addon-token-service-account controller, " +
+				"operatorNamespace: Required value: required by the bootstrap-token controller, " +
+				"bootstrap-token-rotation-interval: Invalid value: \"1m0s\": must be zero or at least 10m0s, " +
+				"bootstrap-token-generations: Invalid value: 0: must be at least 1, " +
+				"bootstrap-hub-server: Required value: required by bootstrap-token-rotation-interval]",
+		},
+		{
+			name: "invalid cluster archive",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{
+					WebhookFailurePolicy:    "Fail",
+					ArchiveDetachedClusters: true,
+					ClusterArchiveRetention: -time.Hour,
+				},
+				KubeConfig:    &rest.Config{},
+				EventRecorder: eventstesting.NewTestingEventRecorder(t),
+			},
+			expectedErr: "[operatorNamespace: Required value: required by the addon-token-service-account controller, " +
+				"operatorNamespace: Required value: required by the cluster-archive controller, " +
+				"cluster-archive-retention: Invalid value: \"-1h0m0s\": must not be negative]",
+		},
+		{
+			name: "invalid secure serving",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{WebhookFailurePolicy: "Fail", SecureServingCertFile: "/serving-cert/tls.crt"},
+				KubeConfig:        &rest.Config{},
+				EventRecorder:     eventstesting.NewTestingEventRecorder(t),
+				OperatorNamespace: "open-cluster-management-hub",
+			},
+			expectedErr: "[secure-serving-key-file: Required value: must be set with secure-serving-cert-file, " +
+				"secure-serving-cert-file: Forbidden: may only be set with secure-bind-address]",
+		},
+		{
+			name: "unknown controllers",
+			options: &EmbeddedOptions{
+				HubManagerOptions: &HubManagerOptions{WebhookFailurePolicy: "Fail", Controllers: []string{"*", "-foo"}},
+				KubeConfig:        &rest.Config{},
+				EventRecorder:     eventstesting.NewTestingEventRecorder(t),
+				OperatorNamespace: "open-cluster-management-hub",
+			},
+			expectedErr: "controllers[1]: Unsupported value: \"-foo\"",
+		},
+		{
+			name: "valid options",
+			options: &EmbeddedOptions{
+				HubManagerOptions:   NewHubManagerOptions(),
+				KubeConfig:          &rest.Config{},
+				EventRecorder:       eventstesting.NewTestingEventRecorder(t),
+				DisabledControllers: []string{LeaseControllerName, WebhookServingCertificateControllerName},
+				OperatorNamespace:   "open-cluster-management-hub",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			testinghelpers.AssertErrorWithPrefix(t, c.options.Validate(), c.expectedErr)
+		})
+	}
+}
+
+func TestControllerWorkers(t *testing.T) {
+	options := NewHubManagerOptions()
+	if workers := options.controllerWorkers(LeaseControllerName); workers != DefaultControllerWorkers {
+		t.Errorf("expected %d workers by default, but got %d", DefaultControllerWorkers, workers)
+	}
+
+	options.ControllerWorkers = 2
+	options.PerControllerWorkers = map[string]int{LeaseControllerName: 8}
+	if workers := options.controllerWorkers(LeaseControllerName); workers != 8 {
+		t.Errorf("expected 8 workers of the lease controller, but got %d", workers)
+	}
+	if workers := options.controllerWorkers(ManagedClusterControllerName); workers != 2 {
+		t.Errorf("expected 2 workers of the managed cluster controller, but got %d", workers)
+	}
+}
+
+func TestControllerSelected(t *testing.T) {
+	cases := []struct {
+		name        string
+		controllers []string
+		expected    map[string]bool
+	}{
+		{
+			name:     "all controllers by default",
+			expected: map[string]bool{TaintControllerName: true, LeaseControllerName: true},
+		},
+		{
+			name:        "disable a controller",
+			controllers: []string{"*", "-" + TaintControllerName},
+			expected:    map[string]bool{TaintControllerName: false, LeaseControllerName: true},
+		},
+		{
+			name:        "only the listed controllers",
+			controllers: []string{TaintControllerName},
+			expected:    map[string]bool{TaintControllerName: true, LeaseControllerName: false},
+		},
+		{
+			name:        "disabled controller is not listed",
+			controllers: []string{"-" + TaintControllerName},
+			expected:    map[string]bool{TaintControllerName: false, LeaseControllerName: false},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			options := &EmbeddedOptions{HubManagerOptions: NewHubManagerOptions()}
+			if c.controllers != nil {
+				options.Controllers = c.controllers
+			}
+			for name, expected := range c.expected {
+				if enabled := options.enabled(name); enabled != expected {
+					t.Errorf("expected the controller %s enabled %v, but got %v", name, expected, enabled)
+				}
+			}
+		})
+	}
+}
+
+func TestHubControllers(t *testing.T) {
+	names := sets.NewString()
+	for name := range hubControllers {
+		names.Insert(name)
+	}
+	if !names.Equal(ControllerNames) {
+		t.Errorf("expected the controllers %v, but got %v", ControllerNames.List(), names.List())
+	}
+}
@@ -0,0 +1,136 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+
+	configv1alpha1 "open-cluster-management.io/registration/pkg/apis/config/v1alpha1"
+	"open-cluster-management.io/registration/pkg/features"
+	"open-cluster-management.io/registration/pkg/hub/preflight"
+	"open-cluster-management.io/registration/pkg/hub/webhookcert"
+)
+
+// requiredResources are the resources of the crds the hub controllers require
+var requiredResources = []schema.GroupVersionResource{
+	{Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"},
+	{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "managedclustersets"},
+	{Group: "addon.open-cluster-management.io", Version: "v1alpha1", Resource: "managedclusteraddons"},
+	{Group: "work.open-cluster-management.io", Version: "v1", Resource: "manifestworks"},
+}
+
+// requiredAccess are the permissions on the hub the hub controllers require
+var requiredAccess = []authorizationv1.ResourceAttributes{
+	{Group: "cluster.open-cluster-management.io", Resource: "managedclusters", Verb: "list"},
+	{Group: "cluster.open-cluster-management.io", Resource: "managedclusters", Verb: "watch"},
+	{Group: "cluster.open-cluster-management.io", Resource: "managedclusters", Verb: "update"},
+	{Group: "cluster.open-cluster-management.io", Resource: "managedclusters", Subresource: "status", Verb: "patch"},
+	{Group: "cluster.open-cluster-management.io", Resource: "managedclustersets", Verb: "list"},
+	{Group: "addon.open-cluster-management.io", Resource: "managedclusteraddons", Verb: "list"},
+	{Group: "work.open-cluster-management.io", Resource: "manifestworks", Verb: "list"},
+	{Group: "certificates.k8s.io", Resource: "certificatesigningrequests", Verb: "list"},
+	{Group: "certificates.k8s.io", Resource: "certificatesigningrequests", Subresource: "approval", Verb: "update"},
+	{Group: "rbac.authorization.k8s.io", Resource: "clusterroles", Verb: "create"},
+	{Group: "rbac.authorization.k8s.io", Resource: "rolebindings", Verb: "create"},
+	{Resource: "namespaces", Verb: "list"},
+	{Group: "coordination.k8s.io", Resource: "leases", Verb: "list"},
+}
+
+// PreflightChecks returns the checks of the hub controller run with --validate-only. The configuration file is
+// applied to the options by the first check, so the options are validated with the settings in the file. The
+// namespace is the namespace the hub controller runs in.
+func (m *HubManagerOptions) PreflightChecks(kubeConfig *rest.Config, configFile, namespace string) ([]preflight.Check, error) {
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return []preflight.Check{
+		{
+			Name: "configuration file",
+			Run: func(ctx context.Context) (string, error) {
+				return m.checkConfigFile(configFile)
+			},
+		},
+		{
+			Name: "options",
+			Run: func(ctx context.Context) (string, error) {
+				options := &EmbeddedOptions{
+					HubManagerOptions: m,
+					KubeConfig:        kubeConfig,
+					OperatorNamespace: namespace,
+					EventRecorder:     events.NewInMemoryRecorder("preflight"),
+				}
+				if err := options.Validate(); err != nil {
+					return "", err
+				}
+				return "the options are valid", nil
+			},
+		},
+		{
+			Name: "files",
+			Run: func(ctx context.Context) (string, error) {
+				return preflight.CheckFiles(m.CloudEventsCAFile, m.CloudEventsClientCertFile, m.CloudEventsClientKeyFile,
+					m.InventoryExportCAFile, m.InventoryExportTokenFile)
+			},
+		},
+		{
+			Name: "webhook serving certificate",
+			Run: func(ctx context.Context) (string, error) {
+				if !features.DefaultHubMutableFeatureGate.Enabled(features.WebhookServingCertRotation) {
+					return "", preflight.Skip(fmt.Sprintf("the serving certificate is not issued by the hub controller "+
+						"unless the feature gate %s is enabled", features.WebhookServingCertRotation))
+				}
+				return preflight.CheckServingCertSecret(ctx, kubeClient, namespace, webhookcert.ServingCertSecretName, time.Now())
+			},
+		},
+		{
+			Name: "crds",
+			Run: func(ctx context.Context) (string, error) {
+				return preflight.CheckResources(kubeClient, requiredResources)
+			},
+		},
+		{
+			Name: "rbac",
+			Run: func(ctx context.Context) (string, error) {
+				return preflight.CheckAccess(ctx, kubeClient, requiredAccess)
+			},
+		},
+	}, nil
+}
+
+// checkConfigFile validates the configuration file passed with --config, and applies it to the options if it is a
+// HubConfiguration. The other files, e.g. a GenericOperatorConfig of library-go, are only parsed.
+func (m *HubManagerOptions) checkConfigFile(configFile string) (string, error) {
+	if len(configFile) == 0 {
+		return "", preflight.Skip("no configuration file is specified")
+	}
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the configuration file %q: %w", configFile, err)
+	}
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse the configuration file %q: %w", configFile, err)
+	}
+	componentConfig := &unstructured.Unstructured{}
+	if err := componentConfig.UnmarshalJSON(jsonData); err != nil {
+		return "", fmt.Errorf("unable to parse the configuration file %q: %w", configFile, err)
+	}
+	if componentConfig.GroupVersionKind().GroupVersion() != configv1alpha1.SchemeGroupVersion {
+		return fmt.Sprintf("the %s in %q is parsed", componentConfig.GetKind(), configFile), nil
+	}
+	if err := m.applyComponentConfig(componentConfig); err != nil {
+		return "", fmt.Errorf("invalid configuration file %q: %w", configFile, err)
+	}
+	return fmt.Sprintf("the %s in %q is valid", componentConfig.GetKind(), configFile), nil
+}
@@ -0,0 +1,219 @@
+// package preflight validates the hub controller without starting it, e.g. in an init container which gates an
+// upgrade of the hub controller. It checks the flags and the configuration file, the files the hub controller
+// reads, the serving certificate of the registration webhook, and the crds and the rbac the controllers require
+// on the hub.
+package preflight
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+// Status is the status of a check
+type Status string
+
+const (
+	StatusPass Status = "PASS"
+	StatusFail Status = "FAIL"
+	// StatusSkip is the status of a check which does not apply to the configuration of the hub controller
+	StatusSkip Status = "SKIP"
+)
+
+// Result is the result of a check
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+}
+
+// Report is the structured report of the checks printed by PrintReport
+type Report struct {
+	Passed  bool     `json:"passed"`
+	Failed  int      `json:"failed"`
+	Results []Result `json:"results"`
+}
+
+// Check is a check of the hub controller. It returns a message describing what is verified, or an error if the
+// check fails. The check is skipped if the error is returned by Skip.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (string, error)
+}
+
+// skipError is returned by the checks which do not apply
+type skipError struct {
+	reason string
+}
+
+func (e *skipError) Error() string {
+	return e.reason
+}
+
+// Skip returns an error which skips a check with the reason
+func Skip(reason string) error {
+	return &skipError{reason: reason}
+}
+
+// Run runs the checks in order
+func Run(ctx context.Context, checks []Check) []Result {
+	results := []Result{}
+	for _, check := range checks {
+		message, err := check.Run(ctx)
+		var skipErr *skipError
+		switch {
+		case errors.As(err, &skipErr):
+			results = append(results, Result{Name: check.Name, Status: StatusSkip, Message: skipErr.reason})
+		case err != nil:
+			results = append(results, Result{Name: check.Name, Status: StatusFail, Message: err.Error()})
+		default:
+			results = append(results, Result{Name: check.Name, Status: StatusPass, Message: message})
+		}
+	}
+	return results
+}
+
+// PrintReport prints the results of the checks as a json Report, and returns the number of the failed checks
+func PrintReport(w io.Writer, results []Result) (int, error) {
+	report := Report{Results: results}
+	for _, result := range results {
+		if result.Status == StatusFail {
+			report.Failed++
+		}
+	}
+	report.Passed = report.Failed == 0
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return report.Failed, err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return report.Failed, err
+}
+
+// CheckFiles checks the files exist and are readable, the empty names are ignored
+func CheckFiles(files ...string) (string, error) {
+	read := []string{}
+	for _, file := range files {
+		if len(file) == 0 {
+			continue
+		}
+		if _, err := ioutil.ReadFile(file); err != nil {
+			return "", fmt.Errorf("unable to read the file %q: %w", file, err)
+		}
+		read = append(read, file)
+	}
+	if len(read) == 0 {
+		return "", Skip("no file is configured")
+	}
+	return fmt.Sprintf("%d files are readable", len(read)), nil
+}
+
+// CheckServingCertSecret checks the secret holds a serving certificate and its key which are valid at now
+func CheckServingCertSecret(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string, now time.Time) (string, error) {
+	secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", fmt.Errorf("the secret %s/%s of the serving certificate is not found", namespace, name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	certData, keyData := secret.Data["tls.crt"], secret.Data["tls.key"]
+	if _, err := tls.X509KeyPair(certData, keyData); err != nil {
+		return "", fmt.Errorf("invalid serving certificate in secret %s/%s: %w", namespace, name, err)
+	}
+	certs, err := certutil.ParseCertsPEM(certData)
+	if err != nil {
+		return "", fmt.Errorf("invalid serving certificate in secret %s/%s: %w", namespace, name, err)
+	}
+	notAfter := certs[0].NotAfter.UTC().Format(time.RFC3339)
+	switch {
+	case now.Before(certs[0].NotBefore):
+		return "", fmt.Errorf("the serving certificate in secret %s/%s is not valid until %s", namespace, name,
+			certs[0].NotBefore.UTC().Format(time.RFC3339))
+	case now.After(certs[0].NotAfter):
+		return "", fmt.Errorf("the serving certificate in secret %s/%s expired at %s", namespace, name, notAfter)
+	}
+	return fmt.Sprintf("the serving certificate in secret %s/%s expires at %s", namespace, name, notAfter), nil
+}
+
+// CheckResources checks the resources are served by the hub apiserver, e.g. the crds of the apis
+func CheckResources(kubeClient kubernetes.Interface, resources []schema.GroupVersionResource) (string, error) {
+	missing := []string{}
+	served := map[schema.GroupVersion]map[string]bool{}
+	for _, resource := range resources {
+		groupVersion := resource.GroupVersion()
+		if _, ok := served[groupVersion]; !ok {
+			served[groupVersion] = map[string]bool{}
+			resourceList, err := kubeClient.Discovery().ServerResourcesForGroupVersion(groupVersion.String())
+			if err != nil && !apierrors.IsNotFound(err) {
+				return "", fmt.Errorf("unable to discover the resources of %s: %w", groupVersion, err)
+			}
+			if resourceList != nil {
+				for _, apiResource := range resourceList.APIResources {
+					served[groupVersion][apiResource.Name] = true
+				}
+			}
+		}
+		if !served[groupVersion][resource.Resource] {
+			missing = append(missing, formatResource(resource.Group, resource.Version, resource.Resource))
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("the resources are not served: %s", strings.Join(missing, ", "))
+	}
+	return fmt.Sprintf("%d resources are served", len(resources)), nil
+}
+
+// CheckAccess checks the current user is allowed to access the resources with self subject access reviews
+func CheckAccess(ctx context.Context, kubeClient kubernetes.Interface, attributes []authorizationv1.ResourceAttributes) (string, error) {
+	denied := []string{}
+	for i := range attributes {
+		sar := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attributes[i]},
+		}
+		sar, err := kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, sar, metav1.CreateOptions{})
+		if err != nil {
+			return "", fmt.Errorf("unable to review the access: %w", err)
+		}
+		if !sar.Status.Allowed {
+			denied = append(denied, formatAttributes(attributes[i]))
+		}
+	}
+	if len(denied) > 0 {
+		return "", fmt.Errorf("not allowed to %s", strings.Join(denied, ", "))
+	}
+	return fmt.Sprintf("%d permissions are granted", len(attributes)), nil
+}
+
+func formatResource(group, version, resource string) string {
+	if len(group) == 0 {
+		return fmt.Sprintf("%s/%s", version, resource)
+	}
+	return fmt.Sprintf("%s.%s/%s", resource, group, version)
+}
+
+func formatAttributes(attributes authorizationv1.ResourceAttributes) string {
+	resource := attributes.Resource
+	if len(attributes.Group) > 0 {
+		resource = resource + "." + attributes.Group
+	}
+	if len(attributes.Namespace) > 0 {
+		return fmt.Sprintf("%s %s in %s", attributes.Verb, resource, attributes.Namespace)
+	}
+	return fmt.Sprintf("%s %s", attributes.Verb, resource)
+}
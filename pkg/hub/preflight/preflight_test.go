@@ -0,0 +1,209 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+const (
+	testNamespace  = "open-cluster-management-hub"
+	testSecretName = "managedcluster-admission-serving-cert"
+)
+
+var testResources = []schema.GroupVersionResource{
+	{Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"},
+	{Group: "work.open-cluster-management.io", Version: "v1", Resource: "manifestworks"},
+}
+
+// newKubeClient returns a fake client serving the crd of managed clusters, which denies the access to the given
+// resources
+func newKubeClient(objects []runtime.Object, deniedResources ...string) *kubefake.Clientset {
+	kubeClient := kubefake.NewSimpleClientset(objects...)
+	kubeClient.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "cluster.open-cluster-management.io/v1",
+			APIResources: []metav1.APIResource{{Name: "managedclusters"}},
+		},
+	}
+	kubeClient.PrependReactor("create", "selfsubjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		sar := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		allowed := true
+		for _, resource := range deniedResources {
+			allowed = allowed && sar.Spec.ResourceAttributes.Resource != resource
+		}
+		sar.Status.Allowed = allowed
+		return true, sar, nil
+	})
+	return kubeClient
+}
+
+func newServingCertSecret(cert *testinghelpers.TestCert) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testSecretName},
+		Data:       map[string][]byte{"tls.crt": cert.Cert, "tls.key": cert.Key},
+	}
+}
+
+func TestRun(t *testing.T) {
+	testDir := t.TempDir()
+	tokenFile := path.Join(testDir, "token")
+	testinghelpers.WriteFile(tokenFile, []byte("token"))
+
+	validCert := testinghelpers.NewTestCert("managedcluster-admission.open-cluster-management-hub.svc", time.Hour)
+	expiredCert := testinghelpers.NewTestCert("managedcluster-admission.open-cluster-management-hub.svc", -time.Hour)
+	mismatchedKey := &testinghelpers.TestCert{
+		Cert: validCert.Cert,
+		Key:  testinghelpers.NewTestCert("other", time.Hour).Key,
+	}
+
+	cases := []struct {
+		name             string
+		objects          []runtime.Object
+		deniedResources  []string
+		files            []string
+		resources        []schema.GroupVersionResource
+		expectedStatuses []Status
+		expectedMessage  string
+	}{
+		{
+			name:             "all checks pass",
+			objects:          []runtime.Object{newServingCertSecret(validCert)},
+			files:            []string{tokenFile, ""},
+			resources:        testResources[:1],
+			expectedStatuses: []Status{StatusPass, StatusPass, StatusPass, StatusPass, StatusPass},
+		},
+		{
+			name:             "no file is configured",
+			objects:          []runtime.Object{newServingCertSecret(validCert)},
+			resources:        testResources[:1],
+			expectedStatuses: []Status{StatusPass, StatusSkip, StatusPass, StatusPass, StatusPass},
+			expectedMessage:  `"message": "no file is configured"`,
+		},
+		{
+			name:             "missing file",
+			objects:          []runtime.Object{newServingCertSecret(validCert)},
+			files:            []string{path.Join(testDir, "missing")},
+			resources:        testResources[:1],
+			expectedStatuses: []Status{StatusPass, StatusFail, StatusPass, StatusPass, StatusPass},
+			expectedMessage:  "unable to read the file",
+		},
+		{
+			name:             "no serving certificate",
+			resources:        testResources[:1],
+			expectedStatuses: []Status{StatusPass, StatusSkip, StatusFail, StatusPass, StatusPass},
+			expectedMessage: "the secret open-cluster-management-hub/managedcluster-admission-serving-cert of the " +
+				"serving certificate is not found",
+		},
+		{
+			name:             "expired serving certificate",
+			objects:          []runtime.Object{newServingCertSecret(expiredCert)},
+			resources:        testResources[:1],
+			expectedStatuses: []Status{StatusPass, StatusSkip, StatusFail, StatusPass, StatusPass},
+			expectedMessage:  "the serving certificate in secret open-cluster-management-hub/managedcluster-admission-serving-cert expired at",
+		},
+		{
+			name:             "mismatched serving key",
+			objects:          []runtime.Object{newServingCertSecret(mismatchedKey)},
+			resources:        testResources[:1],
+			expectedStatuses: []Status{StatusPass, StatusSkip, StatusFail, StatusPass, StatusPass},
+			expectedMessage:  "invalid serving certificate in secret open-cluster-management-hub/managedcluster-admission-serving-cert",
+		},
+		{
+			name:             "missing crd",
+			objects:          []runtime.Object{newServingCertSecret(validCert)},
+			resources:        testResources,
+			expectedStatuses: []Status{StatusPass, StatusSkip, StatusPass, StatusFail, StatusPass},
+			expectedMessage:  "the resources are not served: manifestworks.work.open-cluster-management.io/v1",
+		},
+		{
+			name:             "denied",
+			objects:          []runtime.Object{newServingCertSecret(validCert)},
+			deniedResources:  []string{"managedclusters"},
+			resources:        testResources[:1],
+			expectedStatuses: []Status{StatusPass, StatusSkip, StatusPass, StatusPass, StatusFail},
+			expectedMessage:  "not allowed to update managedclusters.cluster.open-cluster-management.io",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := newKubeClient(c.objects, c.deniedResources...)
+			checks := []Check{
+				{
+					Name: "options",
+					Run: func(ctx context.Context) (string, error) {
+						return "the options are valid", nil
+					},
+				},
+				{
+					Name: "files",
+					Run: func(ctx context.Context) (string, error) {
+						return CheckFiles(c.files...)
+					},
+				},
+				{
+					Name: "webhook serving certificate",
+					Run: func(ctx context.Context) (string, error) {
+						return CheckServingCertSecret(ctx, kubeClient, testNamespace, testSecretName, time.Now())
+					},
+				},
+				{
+					Name: "crds",
+					Run: func(ctx context.Context) (string, error) {
+						return CheckResources(kubeClient, c.resources)
+					},
+				},
+				{
+					Name: "rbac",
+					Run: func(ctx context.Context) (string, error) {
+						return CheckAccess(ctx, kubeClient, []authorizationv1.ResourceAttributes{
+							{Group: "cluster.open-cluster-management.io", Resource: "managedclusters", Verb: "update"},
+							{Group: "certificates.k8s.io", Resource: "certificatesigningrequests", Verb: "list"},
+						})
+					},
+				},
+			}
+
+			results := Run(context.TODO(), checks)
+			statuses := []Status{}
+			for _, result := range results {
+				statuses = append(statuses, result.Status)
+			}
+			if !reflect.DeepEqual(statuses, c.expectedStatuses) {
+				t.Errorf("expected statuses %v, but got %v", c.expectedStatuses, results)
+			}
+
+			output := &bytes.Buffer{}
+			failed, err := PrintReport(output, results)
+			if err != nil {
+				t.Fatal(err)
+			}
+			report := &Report{}
+			if err := json.Unmarshal(output.Bytes(), report); err != nil {
+				t.Fatalf("unable to parse the report: %v", err)
+			}
+			if report.Failed != failed || report.Passed != (failed == 0) || !reflect.DeepEqual(report.Results, results) {
+				t.Errorf("unexpected report %v", report)
+			}
+			if !strings.Contains(output.String(), c.expectedMessage) {
+				t.Errorf("expected %q in the report:\n%s", c.expectedMessage, output.String())
+			}
+		})
+	}
+}
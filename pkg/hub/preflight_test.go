@@ -0,0 +1,82 @@
+package hub
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	"open-cluster-management.io/registration/pkg/hub/preflight"
+)
+
+func TestCheckConfigFile(t *testing.T) {
+	cases := []struct {
+		name                  string
+		data                  string
+		expectedStatus        preflight.Status
+		expectedMessage       string
+		expectedFailurePolicy string
+	}{
+		{
+			name:            "no configuration file",
+			expectedStatus:  preflight.StatusSkip,
+			expectedMessage: "no configuration file is specified",
+		},
+		{
+			name: "hub configuration",
+			data: "apiVersion: registration.config.open-cluster-management.io/v1alpha1\n" +
+				"kind: HubConfiguration\n" +
+				"webhookFailurePolicy: Ignore\n",
+			expectedStatus:        preflight.StatusPass,
+			expectedMessage:       "the HubConfiguration in",
+			expectedFailurePolicy: "Ignore",
+		},
+		{
+			name: "invalid hub configuration",
+			data: "apiVersion: registration.config.open-cluster-management.io/v1alpha1\n" +
+				"kind: HubConfiguration\n" +
+				"webhookFailurePolicy: Never\n",
+			expectedStatus:  preflight.StatusFail,
+			expectedMessage: "webhookFailurePolicy: Unsupported value: \"Never\"",
+		},
+		{
+			name: "configuration of library-go",
+			data: "apiVersion: operator.openshift.io/v1alpha1\n" +
+				"kind: GenericOperatorConfig\n",
+			expectedStatus:  preflight.StatusPass,
+			expectedMessage: "the GenericOperatorConfig in",
+		},
+		{
+			name:            "malformed configuration file",
+			data:            "apiVersion: [",
+			expectedStatus:  preflight.StatusFail,
+			expectedMessage: "unable to parse the configuration file",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			configFile := ""
+			if len(c.data) > 0 {
+				configFile = path.Join(t.TempDir(), "config.yaml")
+				if err := ioutil.WriteFile(configFile, []byte(c.data), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			options := NewHubManagerOptions()
+			results := preflight.Run(context.TODO(), []preflight.Check{{
+				Name: "configuration file",
+				Run: func(ctx context.Context) (string, error) {
+					return options.checkConfigFile(configFile)
+				},
+			}})
+			if results[0].Status != c.expectedStatus || !strings.Contains(results[0].Message, c.expectedMessage) {
+				t.Errorf("expected %s with %q, but got %v", c.expectedStatus, c.expectedMessage, results[0])
+			}
+			if options.WebhookFailurePolicy != c.expectedFailurePolicy {
+				t.Errorf("expected failure policy %q, but got %q", c.expectedFailurePolicy, options.WebhookFailurePolicy)
+			}
+		})
+	}
+}
@@ -8,6 +8,8 @@ import (
 	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	worklister "open-cluster-management.io/api/client/work/listers/work/v1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -19,12 +21,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	rbacv1informers "k8s.io/client-go/informers/rbac/v1"
 	rbacv1client "k8s.io/client-go/kubernetes/typed/rbac/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	rbacv1listers "k8s.io/client-go/listers/rbac/v1"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/klog/v2"
 )
 
 const (
@@ -68,7 +70,7 @@ func NewFinalizeController(
 			key, _ := cache.MetaNamespaceKeyFunc(obj)
 			return key
 		}, roleInformer.Informer(), roleBindingInformer.Informer()).
-		WithSync(controller.sync).ToController("FinalizeController", eventRecorder)
+		WithSync(health.WrapSync("FinalizeController", controller.sync)).ToController("FinalizeController", eventRecorder)
 }
 
 func (m *finalizeController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
@@ -97,7 +99,8 @@ func (m *finalizeController) sync(ctx context.Context, controllerContext factory
 	err = m.syncRoleAndRoleBinding(ctx, controllerContext, role, rolebinding, ns, cluster)
 
 	if err != nil {
-		klog.Errorf("Reconcile role/rolebinding %s fails with err: %v", key, err)
+		helpers.ControllerLogger(ctx, "FinalizeController").Error(err, "Unable to reconcile role/rolebinding",
+			helpers.LogKeyCluster, namespace, helpers.LogKeyResource, key)
 	}
 	return err
 }
@@ -164,7 +167,12 @@ func (m *finalizeController) removeFinalizerFromRole(ctx context.Context, role *
 		return nil
 	}
 
-	_, err := m.rbacClient.Roles(role.Namespace).Update(ctx, role, metav1.UpdateOptions{})
+	// the roles are cached with the metadata only, so the finalizers are patched instead of updating the role
+	patch, err := helpers.FinalizersPatch(role.ResourceVersion, role.Finalizers)
+	if err != nil {
+		return err
+	}
+	_, err = m.rbacClient.Roles(role.Namespace).Patch(ctx, role.Name, types.MergePatchType, patch, metav1.PatchOptions{})
 	return err
 }
 
@@ -179,7 +187,12 @@ func (m *finalizeController) removeFinalizerFromRoleBinding(ctx context.Context,
 		return nil
 	}
 
-	_, err := m.rbacClient.RoleBindings(rolebinding.Namespace).Update(ctx, rolebinding, metav1.UpdateOptions{})
+	// the rolebindings are cached with the metadata only, so the finalizers are patched instead of updating the rolebinding
+	patch, err := helpers.FinalizersPatch(rolebinding.ResourceVersion, rolebinding.Finalizers)
+	if err != nil {
+		return err
+	}
+	_, err = m.rbacClient.RoleBindings(rolebinding.Namespace).Patch(ctx, rolebinding.Name, types.MergePatchType, patch, metav1.PatchOptions{})
 	return err
 }
 
@@ -141,7 +141,7 @@ func TestSyncRoleAndRoleBinding(t *testing.T) {
 			expectedRoleBindingFinalizers: []string{manifestWorkFinalizer},
 			expectedWorkFinalizers:        []string{manifestWorkFinalizer},
 			validateRbacActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "update")
+				testinghelpers.AssertActions(t, actions, "patch")
 			},
 		},
 		{
@@ -151,7 +151,7 @@ func TestSyncRoleAndRoleBinding(t *testing.T) {
 			cluster:     testinghelpers.NewDeletingManagedCluster(),
 			namespace:   testinghelpers.NewNamespace(testinghelpers.TestManagedClusterName, false),
 			validateRbacActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "update", "update")
+				testinghelpers.AssertActions(t, actions, "patch", "patch")
 			},
 		},
 		{
@@ -160,7 +160,7 @@ func TestSyncRoleAndRoleBinding(t *testing.T) {
 			roleBinding: testinghelpers.NewRoleBinding(testinghelpers.TestManagedClusterName, roleName, []string{manifestWorkFinalizer}, true),
 			namespace:   testinghelpers.NewNamespace(testinghelpers.TestManagedClusterName, true),
 			validateRbacActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "update", "update")
+				testinghelpers.AssertActions(t, actions, "patch", "patch")
 			},
 		},
 	}
@@ -0,0 +1,317 @@
+package registrationtoken
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const controllerName = "RegistrationTokenController"
+
+const (
+	// tokenExpirationAnnotation records the expiration time of the token saved in the secret
+	tokenExpirationAnnotation = "open-cluster-management.io/token-expiration"
+	// tokenIssuedAnnotation records the time the token saved in the secret was issued at
+	tokenIssuedAnnotation = "open-cluster-management.io/token-issued"
+
+	// tokenRefreshRatio is the ratio of the lifetime of a token remaining at which the token is requested again
+	tokenRefreshRatio = 0.2
+)
+
+// DefaultBootstrapGroup is the group of the bootstrap tokens of the managed clusters. The token secret of a cluster
+// is only readable by the group suffixed with the cluster name, see BootstrapGroup.
+const DefaultBootstrapGroup = "system:bootstrappers:managedcluster"
+
+// HubClusterRoleName is the name of the clusterrole which allows the hub controller to maintain the token secret
+// in the namespace of a managed cluster. It is bound to the hub controller in the namespace of each managed cluster
+// using the token registration driver, so the hub controller holds no cluster wide permission on the secrets.
+const HubClusterRoleName = "open-cluster-management:hub:registration-token"
+
+// TokenExpirationSeconds is the requested lifetime of the tokens of the registration agents. The token is
+// requested again once it has less than 20% of its life remaining. The lifetime is the one of the issued token,
+// which may be shorter than the requested one if the apiserver caps the lifetime of the tokens. It is exposed so
+// that integration tests can shorten it.
+var TokenExpirationSeconds int64 = 24 * 60 * 60
+
+// registrationTokenController maintains the registration agent service account, its token secret and the role
+// to read the secret in the namespace of each accepted managed cluster using the token registration driver. The
+// bootstrap groups of the cluster are allowed to read the secret until the cluster joins, so the agent gets its
+// first token with a bootstrap kubeconfig issued for the cluster. Once the cluster is denied, deleted or switched to another registration driver, the
+// service account is deleted and its tokens are invalidated.
+type registrationTokenController struct {
+	kubeClient      kubernetes.Interface
+	clusterLister   listerv1.ManagedClusterLister
+	bootstrapGroups []string
+	// hubServiceAccount is the service account of the hub controller, which is bound to HubClusterRoleName in
+	// the namespace of the cluster
+	hubServiceAccount rbacv1.Subject
+}
+
+// NewRegistrationTokenController returns an instance of registrationTokenController
+func NewRegistrationTokenController(
+	kubeClient kubernetes.Interface,
+	clusterInformer informerv1.ManagedClusterInformer,
+	bootstrapGroups []string,
+	hubServiceAccountNamespace, hubServiceAccountName string,
+	recorder events.Recorder) factory.Controller {
+	c := &registrationTokenController{
+		kubeClient:      kubeClient,
+		clusterLister:   clusterInformer.Lister(),
+		bootstrapGroups: bootstrapGroups,
+		hubServiceAccount: rbacv1.Subject{
+			Kind:      rbacv1.ServiceAccountKind,
+			Namespace: hubServiceAccountNamespace,
+			Name:      hubServiceAccountName,
+		},
+	}
+
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ToController(controllerName, recorder)
+}
+
+func (c *registrationTokenController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	clusterName := syncCtx.QueueKey()
+	if clusterName == factory.DefaultQueueKey {
+		return nil
+	}
+
+	cluster, err := c.clusterLister.Get(clusterName)
+	switch {
+	case errors.IsNotFound(err):
+		return c.removeAgentServiceAccount(ctx, syncCtx.Recorder(), clusterName)
+	case err != nil:
+		return err
+	}
+
+	if !usesTokenRegistration(cluster) || !cluster.DeletionTimestamp.IsZero() || !cluster.Spec.HubAcceptsClient ||
+		!meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
+		return c.removeAgentServiceAccount(ctx, syncCtx.Recorder(), clusterName)
+	}
+
+	health.EnterPhase(ctx, "apply resources")
+	errs := []error{}
+	labels := map[string]string{clientcert.ClusterNameLabel: clusterName}
+	// the hub controller is bound in the namespace first, it is required to maintain the token secret and to grant
+	// the permission to read the token secret
+	if _, _, err := resourceapply.ApplyRoleBinding(ctx, c.kubeClient.RbacV1(), syncCtx.Recorder(), &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: clusterName,
+			Name:      HubClusterRoleName,
+			Labels:    labels,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     HubClusterRoleName,
+		},
+		Subjects: []rbacv1.Subject{c.hubServiceAccount},
+	}); err != nil {
+		return err
+	}
+	if _, _, err := resourceapply.ApplyServiceAccount(ctx, c.kubeClient.CoreV1(), syncCtx.Recorder(), &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: clusterName,
+			Name:      helpers.RegistrationAgentServiceAccountName,
+			Labels:    labels,
+		},
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	if _, _, err := resourceapply.ApplyRole(ctx, c.kubeClient.RbacV1(), syncCtx.Recorder(), &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: clusterName,
+			Name:      roleName(clusterName),
+			Labels:    labels,
+		},
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups:     []string{""},
+			Resources:     []string{"secrets"},
+			ResourceNames: []string{helpers.RegistrationTokenSecretName},
+			Verbs:         []string{"get"},
+		}},
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	if _, _, err := resourceapply.ApplyRoleBinding(ctx, c.kubeClient.RbacV1(), syncCtx.Recorder(), &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: clusterName,
+			Name:      roleName(clusterName),
+			Labels:    labels,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     roleName(clusterName),
+		},
+		Subjects: c.subjects(cluster),
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return operatorhelpers.NewMultiLineAggregate(errs)
+	}
+
+	health.EnterPhase(ctx, "rotate token")
+	refresh, err := c.rotateToken(ctx, syncCtx.Recorder(), clusterName)
+	if err != nil {
+		return err
+	}
+
+	// sync the cluster again once the token should be rotated
+	syncCtx.Queue().AddAfter(clusterName, time.Until(refresh))
+	return nil
+}
+
+// subjects returns the subjects allowed to read the token secret of the cluster. The bootstrap groups of the
+// cluster are only allowed until the cluster joins, the bootstrap groups shared by all clusters are never bound
+// since any bootstrap kubeconfig would be able to read the token of an accepted cluster then.
+func (c *registrationTokenController) subjects(cluster *clusterv1.ManagedCluster) []rbacv1.Subject {
+	subjects := []rbacv1.Subject{{
+		Kind:      rbacv1.ServiceAccountKind,
+		Namespace: cluster.Name,
+		Name:      helpers.RegistrationAgentServiceAccountName,
+	}}
+	if meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionJoined) {
+		return subjects
+	}
+	for _, group := range c.bootstrapGroups {
+		subjects = append(subjects, rbacv1.Subject{
+			APIGroup: rbacv1.GroupName,
+			Kind:     rbacv1.GroupKind,
+			Name:     BootstrapGroup(group, cluster.Name),
+		})
+	}
+	return subjects
+}
+
+// rotateToken requests a new token of the registration agent service account if the token in the secret is
+// missing or expiring, and returns the time the token in the secret should be rotated
+func (c *registrationTokenController) rotateToken(ctx context.Context, recorder events.Recorder, clusterName string) (time.Time, error) {
+	secret, err := c.kubeClient.CoreV1().Secrets(clusterName).Get(ctx, helpers.RegistrationTokenSecretName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		return time.Time{}, err
+	case len(secret.Data[helpers.RegistrationTokenKey]) > 0:
+		expiration, expirationErr := time.Parse(time.RFC3339, secret.Annotations[tokenExpirationAnnotation])
+		issued, issuedErr := time.Parse(time.RFC3339, secret.Annotations[tokenIssuedAnnotation])
+		if expirationErr == nil && issuedErr == nil && time.Now().Before(refreshTime(issued, expiration)) {
+			return refreshTime(issued, expiration), nil
+		}
+	}
+
+	issued := time.Now()
+	expirationSeconds := TokenExpirationSeconds
+	tokenRequest, err := c.kubeClient.CoreV1().ServiceAccounts(clusterName).CreateToken(ctx,
+		helpers.RegistrationAgentServiceAccountName, &authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				ExpirationSeconds: &expirationSeconds,
+			},
+		}, metav1.CreateOptions{})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to request token for service account %q: %w",
+			clusterName+"/"+helpers.RegistrationAgentServiceAccountName, err)
+	}
+
+	expiration := tokenRequest.Status.ExpirationTimestamp.Time
+	if _, _, err := resourceapply.ApplySecret(ctx, c.kubeClient.CoreV1(), recorder, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: clusterName,
+			Name:      helpers.RegistrationTokenSecretName,
+			Labels:    map[string]string{clientcert.ClusterNameLabel: clusterName},
+			Annotations: map[string]string{
+				tokenExpirationAnnotation: expiration.UTC().Format(time.RFC3339),
+				tokenIssuedAnnotation:     issued.UTC().Format(time.RFC3339),
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			helpers.RegistrationTokenKey: []byte(tokenRequest.Status.Token),
+		},
+	}); err != nil {
+		return time.Time{}, err
+	}
+	recorder.Eventf("RegistrationTokenRotated", "The token of the registration agent of managed cluster %q is rotated", clusterName)
+	return refreshTime(issued, expiration), nil
+}
+
+// removeAgentServiceAccount removes the registration agent service account of the cluster with its token secret
+// and role. The binding of the hub controller is created first and deleted last since it is required to delete the
+// token secret, so the removal is retried until everything is removed.
+func (c *registrationTokenController) removeAgentServiceAccount(ctx context.Context, recorder events.Recorder, clusterName string) error {
+	_, err := c.kubeClient.RbacV1().RoleBindings(clusterName).Get(ctx, HubClusterRoleName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	errs := []error{}
+	ignoreNotFound := func(err error) {
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	ignoreNotFound(c.kubeClient.RbacV1().RoleBindings(clusterName).Delete(ctx, roleName(clusterName), metav1.DeleteOptions{}))
+	ignoreNotFound(c.kubeClient.RbacV1().Roles(clusterName).Delete(ctx, roleName(clusterName), metav1.DeleteOptions{}))
+	ignoreNotFound(c.kubeClient.CoreV1().Secrets(clusterName).Delete(ctx, helpers.RegistrationTokenSecretName, metav1.DeleteOptions{}))
+	ignoreNotFound(c.kubeClient.CoreV1().ServiceAccounts(clusterName).Delete(ctx, helpers.RegistrationAgentServiceAccountName, metav1.DeleteOptions{}))
+	if len(errs) > 0 {
+		return operatorhelpers.NewMultiLineAggregate(errs)
+	}
+
+	ignoreNotFound(c.kubeClient.RbacV1().RoleBindings(clusterName).Delete(ctx, HubClusterRoleName, metav1.DeleteOptions{}))
+	if len(errs) > 0 {
+		return operatorhelpers.NewMultiLineAggregate(errs)
+	}
+	recorder.Eventf("RegistrationAgentServiceAccountDeleted", "The registration agent service account of managed cluster %q is deleted", clusterName)
+	return nil
+}
+
+// BootstrapGroup returns the group of the bootstrap kubeconfigs issued for the cluster, which is allowed to read
+// the token secret of the cluster until the cluster joins, e.g. system:bootstrappers:managedcluster:cluster1.
+func BootstrapGroup(bootstrapGroup, clusterName string) string {
+	return fmt.Sprintf("%s:%s", bootstrapGroup, clusterName)
+}
+
+// usesTokenRegistration returns true if the agent of the cluster uses the token registration driver
+func usesTokenRegistration(cluster *clusterv1.ManagedCluster) bool {
+	return cluster.Annotations[helpers.RegistrationDriverAnnotation] == helpers.TokenRegistrationDriver
+}
+
+// refreshTime returns the time a token issued at the issued time and expiring at the expiration time should be
+// rotated, which is when it has less than tokenRefreshRatio of its issued lifetime remaining
+func refreshTime(issued, expiration time.Time) time.Time {
+	return expiration.Add(-time.Duration(float64(expiration.Sub(issued)) * tokenRefreshRatio))
+}
+
+// roleName returns the name of the role to read the token secret of the cluster
+func roleName(clusterName string) string {
+	return fmt.Sprintf("open-cluster-management:managedcluster:%s:registration-token", clusterName)
+}
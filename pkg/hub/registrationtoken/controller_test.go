@@ -0,0 +1,247 @@
+package registrationtoken
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func newTokenCluster(cluster *clusterv1.ManagedCluster) *clusterv1.ManagedCluster {
+	cluster.Annotations = map[string]string{helpers.RegistrationDriverAnnotation: helpers.TokenRegistrationDriver}
+	return cluster
+}
+
+func newServiceAccount() *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testinghelpers.TestManagedClusterName,
+			Name:      helpers.RegistrationAgentServiceAccountName,
+		},
+	}
+}
+
+func newHubRoleBinding() *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testinghelpers.TestManagedClusterName,
+			Name:      HubClusterRoleName,
+		},
+	}
+}
+
+func newTokenSecret(issued, expiration time.Time) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testinghelpers.TestManagedClusterName,
+			Name:      helpers.RegistrationTokenSecretName,
+			Annotations: map[string]string{
+				tokenExpirationAnnotation: expiration.UTC().Format(time.RFC3339),
+				tokenIssuedAnnotation:     issued.UTC().Format(time.RFC3339),
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			helpers.RegistrationTokenKey: []byte("token"),
+		},
+	}
+}
+
+// createdObject returns the object created by the first create action of the resource
+func createdObject(t *testing.T, actions []clienttesting.Action, resource string) runtime.Object {
+	return createdObjects(t, actions, resource)[0]
+}
+
+// createdObjects returns the objects created by the create actions of the resource
+func createdObjects(t *testing.T, actions []clienttesting.Action, resource string) []runtime.Object {
+	objects := []runtime.Object{}
+	for _, action := range actions {
+		if action.GetVerb() == "create" && action.GetResource().Resource == resource && len(action.GetSubresource()) == 0 {
+			objects = append(objects, action.(clienttesting.CreateActionImpl).Object)
+		}
+	}
+	if len(objects) == 0 {
+		t.Fatalf("expected %s to be created, but got %v", resource, actions)
+	}
+	return objects
+}
+
+func TestSync(t *testing.T) {
+	cases := []struct {
+		name            string
+		cluster         *clusterv1.ManagedCluster
+		objects         []runtime.Object
+		expectedRequeue bool
+		// issuedLifetime is the lifetime of the issued tokens, the requested one is used if it is 0
+		issuedLifetime  time.Duration
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:    "cluster using client certificates",
+			cluster: testinghelpers.NewAcceptedManagedCluster(),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name:    "cluster not accepted",
+			cluster: newTokenCluster(testinghelpers.NewAcceptingManagedCluster()),
+			objects: []runtime.Object{newHubRoleBinding(), newServiceAccount(), newTokenSecret(time.Now(), time.Now().Add(time.Hour))},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "delete", "delete", "delete", "delete", "delete")
+				if actions[4].GetResource().Resource != "serviceaccounts" {
+					t.Errorf("expected the service account to be deleted, but got %q", actions[4].GetResource().Resource)
+				}
+				if name := actions[5].(clienttesting.DeleteActionImpl).Name; name != HubClusterRoleName {
+					t.Errorf("expected the binding of the hub to be deleted last, but got %q", name)
+				}
+			},
+		},
+		{
+			name:            "joining cluster",
+			cluster:         newTokenCluster(testinghelpers.NewAcceptedManagedCluster()),
+			expectedRequeue: true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				hubBinding := createdObject(t, actions, "rolebindings").(*rbacv1.RoleBinding)
+				if hubBinding.RoleRef.Name != HubClusterRoleName || len(hubBinding.Subjects) != 1 ||
+					hubBinding.Subjects[0].Name != "hub-sa" || hubBinding.Subjects[0].Namespace != "open-cluster-management-hub" {
+					t.Errorf("expected the hub to be bound first, but got %v", hubBinding)
+				}
+				binding := createdObjects(t, actions, "rolebindings")[1].(*rbacv1.RoleBinding)
+				if len(binding.Subjects) != 2 || binding.Subjects[1].Name != DefaultBootstrapGroup+":"+testinghelpers.TestManagedClusterName {
+					t.Errorf("expected the bootstrap group of the cluster to read the token, but got %v", binding.Subjects)
+				}
+				secret := createdObject(t, actions, "secrets").(*corev1.Secret)
+				if string(secret.Data[helpers.RegistrationTokenKey]) != "new-token" {
+					t.Errorf("expected the new token to be saved, but got %q", string(secret.Data[helpers.RegistrationTokenKey]))
+				}
+			},
+		},
+		{
+			name:    "joined cluster",
+			cluster: newTokenCluster(testinghelpers.NewJoinedManagedCluster()),
+			objects: []runtime.Object{newServiceAccount(),
+				newTokenSecret(time.Now(), time.Now().Add(time.Duration(TokenExpirationSeconds)*time.Second))},
+			expectedRequeue: true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				binding := createdObjects(t, actions, "rolebindings")[1].(*rbacv1.RoleBinding)
+				if len(binding.Subjects) != 1 || binding.Subjects[0].Kind != rbacv1.ServiceAccountKind {
+					t.Errorf("expected only the service account to read the token, but got %v", binding.Subjects)
+				}
+				for _, action := range actions {
+					if action.GetSubresource() == "token" {
+						t.Errorf("expected the valid token not to be rotated")
+					}
+				}
+			},
+		},
+		{
+			name:            "expiring token",
+			cluster:         newTokenCluster(testinghelpers.NewJoinedManagedCluster()),
+			objects:         []runtime.Object{newServiceAccount(), newTokenSecret(time.Now().Add(-time.Hour), time.Now().Add(time.Minute))},
+			expectedRequeue: true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				rotated := false
+				for _, action := range actions {
+					if action.GetVerb() == "update" && action.GetResource().Resource == "secrets" {
+						secret := action.(clienttesting.UpdateActionImpl).Object.(*corev1.Secret)
+						rotated = string(secret.Data[helpers.RegistrationTokenKey]) == "new-token"
+					}
+				}
+				if !rotated {
+					t.Errorf("expected the token to be rotated")
+				}
+			},
+		},
+		{
+			name:    "valid token with a short issued lifetime",
+			cluster: newTokenCluster(testinghelpers.NewJoinedManagedCluster()),
+			objects: []runtime.Object{newServiceAccount(),
+				newTokenSecret(time.Now().Add(-10*time.Minute), time.Now().Add(50*time.Minute))},
+			expectedRequeue: true,
+			issuedLifetime:  time.Hour,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				for _, action := range actions {
+					if action.GetSubresource() == "token" {
+						t.Errorf("expected the valid token not to be rotated")
+					}
+				}
+			},
+		},
+		{
+			name:            "token issued with a short lifetime",
+			cluster:         newTokenCluster(testinghelpers.NewJoinedManagedCluster()),
+			objects:         []runtime.Object{newServiceAccount()},
+			expectedRequeue: true,
+			issuedLifetime:  time.Hour,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				secret := createdObject(t, actions, "secrets").(*corev1.Secret)
+				if len(secret.Annotations[tokenIssuedAnnotation]) == 0 {
+					t.Errorf("expected the issued time of the token to be recorded, but got %v", secret.Annotations)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			issuedLifetime := c.issuedLifetime
+			if issuedLifetime == 0 {
+				issuedLifetime = time.Duration(TokenExpirationSeconds) * time.Second
+			}
+			kubeClient := kubefake.NewSimpleClientset(c.objects...)
+			kubeClient.PrependReactor("create", "serviceaccounts",
+				func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+					if action.GetSubresource() != "token" {
+						return false, nil, nil
+					}
+					return true, &authenticationv1.TokenRequest{
+						Status: authenticationv1.TokenRequestStatus{
+							Token:               "new-token",
+							ExpirationTimestamp: metav1.NewTime(time.Now().Add(issuedLifetime)),
+						},
+					}, nil
+				})
+
+			clusterClient := clusterfake.NewSimpleClientset(c.cluster)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(c.cluster); err != nil {
+				t.Fatal(err)
+			}
+
+			ctrl := &registrationTokenController{
+				kubeClient:      kubeClient,
+				clusterLister:   clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				bootstrapGroups: []string{DefaultBootstrapGroup},
+				hubServiceAccount: rbacv1.Subject{
+					Kind:      rbacv1.ServiceAccountKind,
+					Namespace: "open-cluster-management-hub",
+					Name:      "hub-sa",
+				},
+			}
+			syncCtx := testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName)
+			if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			c.validateActions(t, kubeClient.Actions())
+			// the requeued cluster is not added until its token should be rotated
+			if requeued := syncCtx.Queue().Len() > 0; requeued {
+				t.Errorf("expected the cluster not to be requeued immediately")
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+// package registrationtoken contains the hub-side controller of the token registration driver, for the hubs
+// fronted by OIDC-only API servers on which the client certificate authentication is disabled. The agents of the
+// clusters annotated with the token registration driver authenticate with the tokens of a service account in the
+// managed cluster namespace instead of client certificates. The hub provisions the service account once the
+// cluster is accepted, and rotates its token in a secret the agent reads. Until the cluster joins, the secret is also
+// readable by the bootstrap group of the cluster, so the agent has to bootstrap with a kubeconfig issued for the
+// cluster, e.g. a bootstrap token in the group system:bootstrappers:managedcluster:<cluster name>.
+package registrationtoken
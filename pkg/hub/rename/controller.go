@@ -0,0 +1,200 @@
+package rename
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const controllerName = "ClusterRenameController"
+
+// systemTaints are the taints maintained by the hub according to the status of a cluster, they are not copied to
+// the renamed cluster
+var systemTaints = sets.NewString(clusterv1.ManagedClusterTaintUnavailable, clusterv1.ManagedClusterTaintUnreachable)
+
+// ignoredAnnotations are the annotations of the old cluster which are not copied to the renamed cluster
+var ignoredAnnotations = sets.NewString(
+	helpers.RenameAnnotation,
+	helpers.RenameTargetAnnotation,
+	helpers.RenamedFromAnnotation,
+	"kubectl.kubernetes.io/last-applied-configuration",
+)
+
+// renameController renames the managed clusters annotated with helpers.RenameAnnotation in the following steps:
+//  1. the ManagedCluster of the new name is created as a copy of the old one, annotated with
+//     helpers.RenamedFromAnnotation;
+//  2. the old ManagedCluster is annotated with helpers.RenameTargetAnnotation, the registration agent re-registers
+//     with the new name once it observes the annotation;
+//  3. the old ManagedCluster is deleted once the new one is available.
+type renameController struct {
+	clusterClient clusterv1client.Interface
+	clusterLister clusterv1listers.ManagedClusterLister
+}
+
+// NewClusterRenameController returns an instance of renameController
+func NewClusterRenameController(
+	clusterClient clusterv1client.Interface,
+	clusterInformer clusterv1informer.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &renameController{
+		clusterClient: clusterClient,
+		clusterLister: clusterInformer.Lister(),
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(
+			func(obj runtime.Object) string {
+				accessor, _ := meta.Accessor(obj)
+				return accessor.GetName()
+			},
+			func(obj interface{}) bool {
+				accessor, err := meta.Accessor(obj)
+				if err != nil {
+					return false
+				}
+				annotations := accessor.GetAnnotations()
+				return len(annotations[helpers.RenameAnnotation]) > 0 || len(annotations[helpers.RenamedFromAnnotation]) > 0
+			}, clusterInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ToController(controllerName, recorder)
+}
+
+func (c *renameController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	cluster, err := c.clusterLister.Get(syncCtx.QueueKey())
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if oldName := cluster.Annotations[helpers.RenamedFromAnnotation]; len(oldName) > 0 {
+		if err := c.cleanUp(ctx, syncCtx, cluster, oldName); err != nil {
+			return err
+		}
+	}
+	if newName := cluster.Annotations[helpers.RenameAnnotation]; len(newName) > 0 && cluster.DeletionTimestamp.IsZero() {
+		return c.rename(ctx, syncCtx, cluster, newName)
+	}
+	return nil
+}
+
+// rename creates the ManagedCluster of the new name and asks the agent to re-register with the new name
+func (c *renameController) rename(ctx context.Context, syncCtx factory.SyncContext, cluster *clusterv1.ManagedCluster, newName string) error {
+	if newName == cluster.Name {
+		return nil
+	}
+	if errs := validation.IsDNS1123Label(newName); len(errs) > 0 {
+		syncCtx.Recorder().Warningf("ManagedClusterRenameInvalid", "The managed cluster %q cannot be renamed to %q: %s",
+			cluster.Name, newName, strings.Join(errs, ", "))
+		return nil
+	}
+
+	renamed, err := c.clusterLister.Get(newName)
+	switch {
+	case errors.IsNotFound(err):
+		_, err := c.clusterClient.ClusterV1().ManagedClusters().Create(ctx, newRenamedCluster(cluster, newName), metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+		syncCtx.Recorder().Eventf("ManagedClusterRenaming", "The managed cluster %q is being renamed to %q", cluster.Name, newName)
+	case err != nil:
+		return err
+	case renamed.Annotations[helpers.RenamedFromAnnotation] != cluster.Name:
+		syncCtx.Recorder().Warningf("ManagedClusterRenameConflict",
+			"The managed cluster %q cannot be renamed to %q, which is the name of another managed cluster", cluster.Name, newName)
+		return nil
+	}
+
+	if cluster.Annotations[helpers.RenameTargetAnnotation] == newName {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{helpers.RenameTargetAnnotation: newName},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.clusterClient.ClusterV1().ManagedClusters().Patch(ctx, cluster.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// cleanUp deletes the ManagedCluster of the old name once the renamed cluster is available
+func (c *renameController) cleanUp(ctx context.Context, syncCtx factory.SyncContext, renamed *clusterv1.ManagedCluster, oldName string) error {
+	if !meta.IsStatusConditionTrue(renamed.Status.Conditions, clusterv1.ManagedClusterConditionAvailable) {
+		return nil
+	}
+
+	cluster, err := c.clusterLister.Get(oldName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// the old cluster is deleted only if it is still being renamed to the renamed cluster
+	if cluster.Annotations[helpers.RenameTargetAnnotation] != renamed.Name || !cluster.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	uid := cluster.UID
+	err = c.clusterClient.ClusterV1().ManagedClusters().Delete(ctx, oldName, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &uid},
+	})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	syncCtx.Recorder().Eventf("ManagedClusterRenamed", "The managed cluster %q is renamed to %q", oldName, renamed.Name)
+	return nil
+}
+
+// newRenamedCluster returns the ManagedCluster of the new name with the labels, the annotations and the spec of the
+// cluster, the clusterset membership is kept with the clusterset label
+func newRenamedCluster(cluster *clusterv1.ManagedCluster, name string) *clusterv1.ManagedCluster {
+	renamed := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{helpers.RenamedFromAnnotation: cluster.Name},
+		},
+		Spec: *cluster.Spec.DeepCopy(),
+	}
+	for key, value := range cluster.Labels {
+		renamed.Labels[key] = value
+	}
+	for key, value := range cluster.Annotations {
+		if !ignoredAnnotations.Has(key) {
+			renamed.Annotations[key] = value
+		}
+	}
+
+	renamed.Spec.Taints = nil
+	for _, taint := range cluster.Spec.Taints {
+		if !systemTaints.Has(taint.Key) {
+			renamed.Spec.Taints = append(renamed.Spec.Taints, taint)
+		}
+	}
+	return renamed
+}
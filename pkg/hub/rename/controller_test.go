@@ -0,0 +1,152 @@
+package rename
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+const newClusterName = "cluster2"
+
+func newRenamingCluster(renameTo, target string) *clusterv1.ManagedCluster {
+	cluster := testinghelpers.NewAvailableManagedCluster()
+	cluster.UID = "uid1"
+	cluster.Labels = map[string]string{helpers.ClusterSetLabel: "dev"}
+	cluster.Annotations = map[string]string{helpers.RenameAnnotation: renameTo}
+	if len(target) > 0 {
+		cluster.Annotations[helpers.RenameTargetAnnotation] = target
+	}
+	cluster.Spec.Taints = []clusterv1.Taint{
+		{Key: "team", Value: "a", Effect: clusterv1.TaintEffectNoSelect},
+		{Key: clusterv1.ManagedClusterTaintUnreachable, Effect: clusterv1.TaintEffectNoSelect},
+	}
+	return cluster
+}
+
+func newTargetCluster(from string, available bool) *clusterv1.ManagedCluster {
+	cluster := testinghelpers.NewAcceptedManagedCluster()
+	if available {
+		cluster = testinghelpers.NewAvailableManagedCluster()
+	}
+	cluster.Name = newClusterName
+	cluster.Annotations = map[string]string{helpers.RenamedFromAnnotation: from}
+	return cluster
+}
+
+func TestSync(t *testing.T) {
+	cases := []struct {
+		name            string
+		key             string
+		clusters        []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:            "invalid new name",
+			key:             testinghelpers.TestManagedClusterName,
+			clusters:        []runtime.Object{newRenamingCluster("Cluster_2", "")},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:     "create the renamed cluster",
+			key:      testinghelpers.TestManagedClusterName,
+			clusters: []runtime.Object{newRenamingCluster(newClusterName, "")},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "create", "patch")
+				renamed := actions[0].(clienttesting.CreateActionImpl).Object.(*clusterv1.ManagedCluster)
+				if renamed.Name != newClusterName || renamed.Annotations[helpers.RenamedFromAnnotation] != testinghelpers.TestManagedClusterName {
+					t.Errorf("unexpected renamed cluster %v", renamed.ObjectMeta)
+				}
+				if renamed.Labels[helpers.ClusterSetLabel] != "dev" || !renamed.Spec.HubAcceptsClient {
+					t.Errorf("expected the clusterset and the acceptance are migrated, but got %v", renamed)
+				}
+				if len(renamed.Spec.Taints) != 1 || renamed.Spec.Taints[0].Key != "team" {
+					t.Errorf("expected only the taints of the users are migrated, but got %v", renamed.Spec.Taints)
+				}
+				if _, ok := renamed.Annotations[helpers.RenameAnnotation]; ok {
+					t.Errorf("expected the rename annotation is not migrated")
+				}
+				patch := string(actions[1].(clienttesting.PatchActionImpl).Patch)
+				expectedPatch := `{"metadata":{"annotations":{"cluster.open-cluster-management.io/rename-target":"cluster2"}}}`
+				if patch != expectedPatch {
+					t.Errorf("expected patch %s, but got %s", expectedPatch, patch)
+				}
+			},
+		},
+		{
+			name: "name is taken by another cluster",
+			key:  testinghelpers.TestManagedClusterName,
+			clusters: []runtime.Object{
+				newRenamingCluster(newClusterName, ""),
+				newTargetCluster("cluster3", true),
+			},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name: "wait for the renamed cluster to be available",
+			key:  newClusterName,
+			clusters: []runtime.Object{
+				newRenamingCluster(newClusterName, newClusterName),
+				newTargetCluster(testinghelpers.TestManagedClusterName, false),
+			},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name: "delete the old cluster",
+			key:  newClusterName,
+			clusters: []runtime.Object{
+				newRenamingCluster(newClusterName, newClusterName),
+				newTargetCluster(testinghelpers.TestManagedClusterName, true),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete")
+				if name := actions[0].(clienttesting.DeleteActionImpl).Name; name != testinghelpers.TestManagedClusterName {
+					t.Errorf("expected the old cluster is deleted, but got %q", name)
+				}
+			},
+		},
+		{
+			name: "old cluster is being deleted",
+			key:  newClusterName,
+			clusters: []runtime.Object{
+				func() *clusterv1.ManagedCluster {
+					cluster := newRenamingCluster(newClusterName, newClusterName)
+					cluster.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+					return cluster
+				}(),
+				newTargetCluster(testinghelpers.TestManagedClusterName, true),
+			},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.clusters...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, 10*time.Minute)
+			for _, cluster := range c.clusters {
+				if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := &renameController{
+				clusterClient: clusterClient,
+				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+			}
+			if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, c.key)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			c.validateActions(t, clusterClient.Actions())
+		})
+	}
+}
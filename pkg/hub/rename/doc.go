@@ -0,0 +1,6 @@
+// package rename contains the hub-side controller which renames the managed clusters. A managed cluster is renamed
+// by annotating it with the new name, the controller creates the ManagedCluster of the new name with the labels,
+// the clusterset membership and the spec of the old one, and asks the registration agent to re-register with the
+// new name. The old ManagedCluster is deleted only after the new one is available, so the cluster is never left
+// without a record on the hub.
+package rename
@@ -0,0 +1,64 @@
+package hub
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// componentName is the name of the hub controller
+const componentName = "registration-controller"
+
+// ScopedControllerNames are the names of the controllers which manage only the managed clusters in the scope of
+// the hub controller, see HubManagerOptions.ClusterSelector. They manage each cluster on its own and ignore the
+// clusters they do not see. The other controllers manage the whole fleet or the objects shared by the clusters,
+// e.g. the clustersets, the clusterroles and the aws-auth configmap, so they always see all of the clusters, and
+// they should be started in only one of the hub controllers managing the slices of the fleet, see
+// DisableFleetControllers.
+var ScopedControllerNames = sets.NewString(
+	ManagedClusterControllerName,
+	TaintControllerName,
+	CSRApprovingControllerName,
+	LeaseControllerName,
+	AddOnHealthCheckControllerName,
+	AddOnHealthAggregationControllerName,
+	DefaultManagedClusterSetControllerName,
+)
+
+// clusterScope returns the selector of the managed clusters in the scope of the hub controller, which selects the
+// clusters matching ClusterSelector in any of ClusterSets. It returns nil if the hub controller is not scoped.
+func (m *HubManagerOptions) clusterScope() (labels.Selector, error) {
+	if len(m.ClusterSelector) == 0 && len(m.ClusterSets) == 0 {
+		return nil, nil
+	}
+
+	selector, err := labels.Parse(m.ClusterSelector)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.ClusterSets) > 0 {
+		requirement, err := labels.NewRequirement(helpers.ClusterSetLabel, selection.In, m.ClusterSets)
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*requirement)
+	}
+	return selector, nil
+}
+
+// controllerStarted returns true if the controller is started by the hub controller, the controllers of the whole
+// fleet are not started if DisableFleetControllers is true or the hub controller is an instance of InstanceName.
+func (m *HubManagerOptions) controllerStarted(name string) bool {
+	return !(m.DisableFleetControllers || len(m.InstanceName) > 0) || ScopedControllerNames.Has(name)
+}
+
+// ComponentName returns the name of the hub controller, which its leader election lease is named after, so that
+// each instance of InstanceName holds its own lease.
+func (m *HubManagerOptions) ComponentName() string {
+	if len(m.InstanceName) == 0 {
+		return componentName
+	}
+	return componentName + "-" + m.InstanceName
+}
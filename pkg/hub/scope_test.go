@@ -0,0 +1,105 @@
+package hub
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+func TestClusterScope(t *testing.T) {
+	cases := []struct {
+		name            string
+		clusterSelector string
+		clusterSets     []string
+		clusterLabels   map[string]string
+		expectedScoped  bool
+		expectedMatched bool
+	}{
+		{
+			name:            "not scoped",
+			clusterLabels:   map[string]string{"stage": "canary"},
+			expectedMatched: true,
+		},
+		{
+			name:            "matched by the selector",
+			clusterSelector: "stage=canary",
+			clusterLabels:   map[string]string{"stage": "canary"},
+			expectedScoped:  true,
+			expectedMatched: true,
+		},
+		{
+			name:            "not matched by the selector",
+			clusterSelector: "stage!=canary",
+			clusterLabels:   map[string]string{"stage": "canary"},
+			expectedScoped:  true,
+		},
+		{
+			name:            "in the clustersets",
+			clusterSets:     []string{"tenant-a", "tenant-b"},
+			clusterLabels:   map[string]string{helpers.ClusterSetLabel: "tenant-b"},
+			expectedScoped:  true,
+			expectedMatched: true,
+		},
+		{
+			name:            "in the clustersets but not matched by the selector",
+			clusterSelector: "stage=canary",
+			clusterSets:     []string{"tenant-a"},
+			clusterLabels:   map[string]string{helpers.ClusterSetLabel: "tenant-a", "stage": "stable"},
+			expectedScoped:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			options := NewHubManagerOptions()
+			options.ClusterSelector = c.clusterSelector
+			options.ClusterSets = c.clusterSets
+			selector, err := options.clusterScope()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (selector != nil) != c.expectedScoped {
+				t.Fatalf("expected scoped %v, but got %v", c.expectedScoped, selector)
+			}
+			if selector == nil {
+				selector = labels.Everything()
+			}
+			if matched := selector.Matches(labels.Set(c.clusterLabels)); matched != c.expectedMatched {
+				t.Errorf("expected matched %v, but got %v", c.expectedMatched, matched)
+			}
+		})
+	}
+}
+
+func TestControllerStarted(t *testing.T) {
+	options := &EmbeddedOptions{HubManagerOptions: NewHubManagerOptions()}
+	if !options.enabled(LeaseControllerName) || !options.enabled(ClusterRoleControllerName) {
+		t.Errorf("expected all of the controllers to be started by default")
+	}
+
+	options.DisableFleetControllers = true
+	if !options.enabled(LeaseControllerName) {
+		t.Errorf("expected the scoped controller %s to be started", LeaseControllerName)
+	}
+	if options.enabled(ClusterRoleControllerName) {
+		t.Errorf("expected the fleet controller %s not to be started", ClusterRoleControllerName)
+	}
+
+	options.DisableFleetControllers = false
+	options.InstanceName = "team-a"
+	if !options.enabled(CSRApprovingControllerName) || options.enabled(ClusterRoleControllerName) {
+		t.Errorf("expected only the scoped controllers to be started by the instance %s", options.InstanceName)
+	}
+}
+
+func TestComponentName(t *testing.T) {
+	options := NewHubManagerOptions()
+	if name := options.ComponentName(); name != "registration-controller" {
+		t.Errorf("unexpected component name %q", name)
+	}
+	options.InstanceName = "team-a"
+	if name := options.ComponentName(); name != "registration-controller-team-a" {
+		t.Errorf("unexpected component name %q of the instance", name)
+	}
+}
@@ -0,0 +1,237 @@
+package webhookcert
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	apiregistrationclient "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset/typed/apiregistration/v1"
+
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/hub/webhookconfig"
+)
+
+const apiServiceName = "v1.admission.cluster.open-cluster-management.io"
+
+// managedClusterSetCRDName is the name of the CRD of ManagedClusterSet, whose versions are converted by the
+// conversion webhook of the registration webhook server
+const managedClusterSetCRDName = "managedclustersets.cluster.open-cluster-management.io"
+
+// webhookCABundleController injects the CA of the webhook serving signer into the APIService, the webhook
+// configurations and the conversion webhook of the ManagedClusterSet CRD which refer to the webhook service, so
+// that the kube apiserver verifies the serving certificate of the registration webhook.
+type webhookCABundleController struct {
+	kubeClient       kubernetes.Interface
+	apiServiceClient apiregistrationclient.APIServicesGetter
+	crdClient        apiextensionsclient.CustomResourceDefinitionsGetter
+	secretLister     corev1listers.SecretLister
+	namespace        string
+}
+
+// NewWebhookCABundleController returns an instance of webhookCABundleController
+func NewWebhookCABundleController(
+	kubeClient kubernetes.Interface,
+	apiServiceClient apiregistrationclient.APIServicesGetter,
+	crdClient apiextensionsclient.CustomResourceDefinitionsGetter,
+	secretInformer corev1informers.SecretInformer,
+	namespace string,
+	recorder events.Recorder) factory.Controller {
+	c := &webhookCABundleController{
+		kubeClient:       kubeClient,
+		apiServiceClient: apiServiceClient,
+		crdClient:        crdClient,
+		secretLister:     secretInformer.Lister(),
+		namespace:        namespace,
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			return accessor.GetNamespace() == namespace && accessor.GetName() == SignerSecretName
+		}, secretInformer.Informer()).
+		WithSync(health.WrapSync("WebhookCABundleController", c.sync)).
+		ResyncEvery(10*time.Minute).
+		ToController("WebhookCABundleController", recorder)
+}
+
+func (c *webhookCABundleController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	secret, err := c.secretLister.Secrets(c.namespace).Get(SignerSecretName)
+	if errors.IsNotFound(err) {
+		// wait until the signer creates its CA
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	caBundle := secret.Data[corev1.TLSCertKey]
+	if len(caBundle) == 0 {
+		return nil
+	}
+
+	errs := []error{}
+	if err := c.injectAPIService(ctx, syncCtx.Recorder(), caBundle); err != nil {
+		errs = append(errs, err)
+	}
+	for _, name := range webhookconfig.ValidatingWebhookConfigurationNames {
+		if err := c.injectValidatingWebhookConfiguration(ctx, syncCtx.Recorder(), name, caBundle); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, name := range webhookconfig.MutatingWebhookConfigurationNames {
+		if err := c.injectMutatingWebhookConfiguration(ctx, syncCtx.Recorder(), name, caBundle); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := c.injectConversionWebhook(ctx, syncCtx.Recorder(), managedClusterSetCRDName, caBundle); err != nil {
+		errs = append(errs, err)
+	}
+	return operatorhelpers.NewMultiLineAggregate(errs)
+}
+
+func (c *webhookCABundleController) injectAPIService(ctx context.Context, recorder events.Recorder, caBundle []byte) error {
+	apiService, err := c.apiServiceClient.APIServices().Get(ctx, apiServiceName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !c.isWebhookService(apiService.Spec.Service.Namespace, apiService.Spec.Service.Name) {
+		return nil
+	}
+	if bytes.Equal(apiService.Spec.CABundle, caBundle) && !apiService.Spec.InsecureSkipTLSVerify {
+		return nil
+	}
+
+	apiService = apiService.DeepCopy()
+	apiService.Spec.CABundle = caBundle
+	// the CA bundle is not allowed to be set with insecureSkipTLSVerify
+	apiService.Spec.InsecureSkipTLSVerify = false
+	if _, err := c.apiServiceClient.APIServices().Update(ctx, apiService, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	recorder.Eventf("CABundleInjected", "the CA bundle is injected into apiservice %q", apiServiceName)
+	return nil
+}
+
+func (c *webhookCABundleController) injectValidatingWebhookConfiguration(ctx context.Context, recorder events.Recorder, name string, caBundle []byte) error {
+	config, err := c.kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	config = config.DeepCopy()
+	modified := false
+	for i := range config.Webhooks {
+		modified = c.injectWebhookClientConfig(&config.Webhooks[i].ClientConfig, caBundle) || modified
+	}
+	if !modified {
+		return nil
+	}
+
+	if _, err := c.kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().Update(ctx, config, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	recorder.Eventf("CABundleInjected", "the CA bundle is injected into validatingwebhookconfiguration %q", name)
+	return nil
+}
+
+func (c *webhookCABundleController) injectMutatingWebhookConfiguration(ctx context.Context, recorder events.Recorder, name string, caBundle []byte) error {
+	config, err := c.kubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	config = config.DeepCopy()
+	modified := false
+	for i := range config.Webhooks {
+		modified = c.injectWebhookClientConfig(&config.Webhooks[i].ClientConfig, caBundle) || modified
+	}
+	if !modified {
+		return nil
+	}
+
+	if _, err := c.kubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations().Update(ctx, config, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	recorder.Eventf("CABundleInjected", "the CA bundle is injected into mutatingwebhookconfiguration %q", name)
+	return nil
+}
+
+// injectConversionWebhook sets the CA bundle of the conversion webhook of the CRD if it refers to the webhook
+// service directly
+func (c *webhookCABundleController) injectConversionWebhook(ctx context.Context, recorder events.Recorder, name string, caBundle []byte) error {
+	crd, err := c.crdClient.CustomResourceDefinitions().Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	conversion := crd.Spec.Conversion
+	if conversion == nil || conversion.Strategy != apiextensionsv1.WebhookConverter || conversion.Webhook == nil ||
+		conversion.Webhook.ClientConfig == nil {
+		return nil
+	}
+	service := conversion.Webhook.ClientConfig.Service
+	if service == nil || !c.isWebhookService(service.Namespace, service.Name) {
+		return nil
+	}
+	if bytes.Equal(conversion.Webhook.ClientConfig.CABundle, caBundle) {
+		return nil
+	}
+
+	crd = crd.DeepCopy()
+	crd.Spec.Conversion.Webhook.ClientConfig.CABundle = caBundle
+	if _, err := c.crdClient.CustomResourceDefinitions().Update(ctx, crd, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	recorder.Eventf("CABundleInjected", "the CA bundle is injected into the conversion webhook of crd %q", name)
+	return nil
+}
+
+// injectWebhookClientConfig sets the CA bundle of the client config if it refers to the webhook service directly.
+// The webhooks reaching the registration webhook via the aggregated API are verified with the CA of the kube
+// apiserver, so they are not changed.
+func (c *webhookCABundleController) injectWebhookClientConfig(clientConfig *admissionregistrationv1.WebhookClientConfig, caBundle []byte) bool {
+	if clientConfig.Service == nil || !c.isWebhookService(clientConfig.Service.Namespace, clientConfig.Service.Name) {
+		return false
+	}
+	if bytes.Equal(clientConfig.CABundle, caBundle) {
+		return false
+	}
+	clientConfig.CABundle = caBundle
+	return true
+}
+
+func (c *webhookCABundleController) isWebhookService(namespace, name string) bool {
+	return namespace == c.namespace && name == ServiceName
+}
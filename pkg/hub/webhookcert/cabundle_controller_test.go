@@ -0,0 +1,269 @@
+package webhookcert
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+	"open-cluster-management.io/registration/pkg/hub/webhookconfig"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+	apiregistrationclient "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset/typed/apiregistration/v1"
+)
+
+// fakeAPIServices is an in-memory implementation of the apiservice client since the fake clientset of
+// kube-aggregator is not vendored.
+type fakeAPIServices struct {
+	apiregistrationclient.APIServiceInterface
+	apiService *apiregistrationv1.APIService
+	updated    *apiregistrationv1.APIService
+}
+
+func (f *fakeAPIServices) APIServices() apiregistrationclient.APIServiceInterface {
+	return f
+}
+
+func (f *fakeAPIServices) Get(ctx context.Context, name string, opts metav1.GetOptions) (*apiregistrationv1.APIService, error) {
+	if f.apiService == nil || f.apiService.Name != name {
+		return nil, errors.NewNotFound(apiregistrationv1.Resource("apiservices"), name)
+	}
+	return f.apiService, nil
+}
+
+func (f *fakeAPIServices) Update(ctx context.Context, apiService *apiregistrationv1.APIService, opts metav1.UpdateOptions) (*apiregistrationv1.APIService, error) {
+	f.updated = apiService
+	return apiService, nil
+}
+
+// fakeCRDs is an in-memory implementation of the crd client since the fake clientset of apiextensions-apiserver
+// is not vendored.
+type fakeCRDs struct {
+	apiextensionsclient.CustomResourceDefinitionInterface
+	crd     *apiextensionsv1.CustomResourceDefinition
+	updated *apiextensionsv1.CustomResourceDefinition
+}
+
+func (f *fakeCRDs) CustomResourceDefinitions() apiextensionsclient.CustomResourceDefinitionInterface {
+	return f
+}
+
+func (f *fakeCRDs) Get(ctx context.Context, name string, opts metav1.GetOptions) (*apiextensionsv1.CustomResourceDefinition, error) {
+	if f.crd == nil || f.crd.Name != name {
+		return nil, errors.NewNotFound(apiextensionsv1.Resource("customresourcedefinitions"), name)
+	}
+	return f.crd, nil
+}
+
+func (f *fakeCRDs) Update(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition, opts metav1.UpdateOptions) (*apiextensionsv1.CustomResourceDefinition, error) {
+	f.updated = crd
+	return crd, nil
+}
+
+func TestCABundleSync(t *testing.T) {
+	signerSecret := newSignerSecret(t)
+	caBundle := signerSecret.Data[corev1.TLSCertKey]
+
+	cases := []struct {
+		name               string
+		secrets            []runtime.Object
+		apiService         *apiregistrationv1.APIService
+		crd                *apiextensionsv1.CustomResourceDefinition
+		webhookConfigs     []runtime.Object
+		expectedAPIService bool
+		expectedCRD        bool
+		validateActions    func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:       "no signer",
+			apiService: newAPIService(testNamespace, nil),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:    "no apiservice and webhook configurations",
+			secrets: []runtime.Object{signerSecret},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "get", "get")
+			},
+		},
+		{
+			name:               "inject the ca bundle",
+			secrets:            []runtime.Object{signerSecret},
+			apiService:         newAPIService(testNamespace, nil),
+			crd:                newClusterSetCRD(testNamespace, nil),
+			expectedAPIService: true,
+			expectedCRD:        true,
+			webhookConfigs: []runtime.Object{
+				newValidatingWebhookConfiguration(webhookconfig.ValidatingWebhookConfigurationNames[0], testNamespace, nil),
+				newMutatingWebhookConfiguration(webhookconfig.MutatingWebhookConfigurationNames[0], testNamespace, nil),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update", "get", "get", "update")
+				validating := actions[1].(clienttesting.UpdateActionImpl).Object.(*admissionregistrationv1.ValidatingWebhookConfiguration)
+				if !bytes.Equal(validating.Webhooks[0].ClientConfig.CABundle, caBundle) {
+					t.Errorf("expected ca bundle is injected into validating webhook")
+				}
+				mutating := actions[4].(clienttesting.UpdateActionImpl).Object.(*admissionregistrationv1.MutatingWebhookConfiguration)
+				if !bytes.Equal(mutating.Webhooks[0].ClientConfig.CABundle, caBundle) {
+					t.Errorf("expected ca bundle is injected into mutating webhook")
+				}
+			},
+		},
+		{
+			name:       "ca bundle is injected already",
+			secrets:    []runtime.Object{signerSecret},
+			apiService: newAPIService(testNamespace, caBundle),
+			crd:        newClusterSetCRD(testNamespace, caBundle),
+			webhookConfigs: []runtime.Object{
+				newValidatingWebhookConfiguration(webhookconfig.ValidatingWebhookConfigurationNames[0], testNamespace, caBundle),
+				newMutatingWebhookConfiguration(webhookconfig.MutatingWebhookConfigurationNames[0], testNamespace, caBundle),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "get", "get")
+			},
+		},
+		{
+			name:       "webhooks refer to other services",
+			secrets:    []runtime.Object{signerSecret},
+			apiService: newAPIService("default", nil),
+			crd:        newClusterSetCRD("default", nil),
+			webhookConfigs: []runtime.Object{
+				newValidatingWebhookConfiguration(webhookconfig.ValidatingWebhookConfigurationNames[0], "default", nil),
+				newMutatingWebhookConfiguration(webhookconfig.MutatingWebhookConfigurationNames[0], "default", nil),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "get", "get")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(c.webhookConfigs...)
+			apiServiceClient := &fakeAPIServices{apiService: c.apiService}
+			crdClient := &fakeCRDs{crd: c.crd}
+
+			informerFactory := informers.NewSharedInformerFactory(kubeClient, 3*time.Minute)
+			for _, secret := range c.secrets {
+				informerFactory.Core().V1().Secrets().Informer().GetStore().Add(secret)
+			}
+
+			ctrl := &webhookCABundleController{
+				kubeClient:       kubeClient,
+				apiServiceClient: apiServiceClient,
+				crdClient:        crdClient,
+				secretLister:     informerFactory.Core().V1().Secrets().Lister(),
+				namespace:        testNamespace,
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "key"))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateActions(t, kubeClient.Actions())
+
+			if !c.expectedAPIService && apiServiceClient.updated != nil {
+				t.Errorf("expected apiservice is not updated")
+			}
+			if c.expectedAPIService {
+				if apiServiceClient.updated == nil {
+					t.Fatalf("expected apiservice is updated")
+				}
+				if !bytes.Equal(apiServiceClient.updated.Spec.CABundle, caBundle) || apiServiceClient.updated.Spec.InsecureSkipTLSVerify {
+					t.Errorf("expected ca bundle is injected into apiservice, but got %#v", apiServiceClient.updated.Spec)
+				}
+			}
+
+			if !c.expectedCRD && crdClient.updated != nil {
+				t.Errorf("expected crd is not updated")
+			}
+			if c.expectedCRD {
+				if crdClient.updated == nil {
+					t.Fatalf("expected crd is updated")
+				}
+				if !bytes.Equal(crdClient.updated.Spec.Conversion.Webhook.ClientConfig.CABundle, caBundle) {
+					t.Errorf("expected ca bundle is injected into the conversion webhook of crd")
+				}
+			}
+		})
+	}
+}
+
+func newAPIService(namespace string, caBundle []byte) *apiregistrationv1.APIService {
+	return &apiregistrationv1.APIService{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: apiServiceName,
+		},
+		Spec: apiregistrationv1.APIServiceSpec{
+			Service: &apiregistrationv1.ServiceReference{
+				Namespace: namespace,
+				Name:      ServiceName,
+			},
+			CABundle:              caBundle,
+			InsecureSkipTLSVerify: len(caBundle) == 0,
+		},
+	}
+}
+
+func newClusterSetCRD(namespace string, caBundle []byte) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: managedClusterSetCRDName,
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Conversion: &apiextensionsv1.CustomResourceConversion{
+				Strategy: apiextensionsv1.WebhookConverter,
+				Webhook: &apiextensionsv1.WebhookConversion{
+					ClientConfig: &apiextensionsv1.WebhookClientConfig{
+						Service: &apiextensionsv1.ServiceReference{
+							Namespace: namespace,
+							Name:      ServiceName,
+						},
+						CABundle: caBundle,
+					},
+				},
+			},
+		},
+	}
+}
+
+func newWebhookClientConfig(namespace string, caBundle []byte) admissionregistrationv1.WebhookClientConfig {
+	return admissionregistrationv1.WebhookClientConfig{
+		Service: &admissionregistrationv1.ServiceReference{
+			Namespace: namespace,
+			Name:      ServiceName,
+		},
+		CABundle: caBundle,
+	}
+}
+
+func newValidatingWebhookConfiguration(name, namespace string, caBundle []byte) *admissionregistrationv1.ValidatingWebhookConfiguration {
+	return &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: name, ClientConfig: newWebhookClientConfig(namespace, caBundle)},
+		},
+	}
+}
+
+func newMutatingWebhookConfiguration(name, namespace string, caBundle []byte) *admissionregistrationv1.MutatingWebhookConfiguration {
+	return &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{Name: name, ClientConfig: newWebhookClientConfig(namespace, caBundle)},
+		},
+	}
+}
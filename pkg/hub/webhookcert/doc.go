@@ -0,0 +1,3 @@
+// package webhookcert contains the hub-side controllers which issue and rotate the serving certificate of the
+// registration webhook, and inject the CA bundle of the serving certificate into the webhook configurations.
+package webhookcert
@@ -0,0 +1,65 @@
+package webhookcert
+
+import (
+	"crypto/x509/pkix"
+	"fmt"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	certificatesinformers "k8s.io/client-go/informers/certificates"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+)
+
+// servingCertLabel is added on the csrs of the webhook serving certificate
+const servingCertLabel = "open-cluster-management.io/webhook-serving-cert"
+
+// NewWebhookServingCertController returns a clientcert controller which creates the serving certificate of the
+// registration webhook in secret ServingCertSecretName with csrs, and rotates it before it expires. The csrs are
+// signed by the controller returned by NewWebhookServingSignerController.
+func NewWebhookServingCertController(
+	kubeClient kubernetes.Interface,
+	csrInformer certificatesinformers.Interface,
+	secretInformer corev1informers.SecretInformer,
+	namespace string,
+	recorder events.Recorder) (factory.Controller, error) {
+	return clientcert.NewController(
+		namespace,
+		ServingCertSecretName,
+		csrInformer,
+		secretInformer,
+		kubeClient,
+		kubeClient,
+		recorder,
+		"WebhookServingCertController",
+		clientcert.WithSubject(&pkix.Name{
+			CommonName: fmt.Sprintf("%s.%s.svc", ServiceName, namespace),
+		}),
+		clientcert.WithDNSNames(ServingCertDNSNames(namespace)...),
+		clientcert.WithSigner(SignerName),
+		clientcert.WithUsages(
+			certificatesv1.UsageDigitalSignature,
+			certificatesv1.UsageKeyEncipherment,
+			certificatesv1.UsageServerAuth,
+		),
+		clientcert.WithCSRObjectMeta(metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-", ServiceName),
+			Labels: map[string]string{
+				servingCertLabel: "",
+			},
+		}, func(obj interface{}) bool {
+			csr, ok := obj.(*certificatesv1.CertificateSigningRequest)
+			if !ok {
+				return false
+			}
+			if _, ok := csr.Labels[servingCertLabel]; !ok {
+				return false
+			}
+			return csr.Spec.SignerName == SignerName
+		}),
+	)
+}
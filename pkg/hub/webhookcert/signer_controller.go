@@ -0,0 +1,286 @@
+package webhookcert
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/events"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	certificatesinformers "k8s.io/client-go/informers/certificates/v1"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	certificateslisters "k8s.io/client-go/listers/certificates/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/fips"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const (
+	// SignerName is the name of the signer which issues the serving certificate of the registration webhook
+	SignerName = "open-cluster-management.io/webhook-serving"
+	// SignerSecretName is the name of the secret containing the CA of the signer
+	SignerSecretName = "managedcluster-admission-signer"
+	// ServingCertSecretName is the name of the secret containing the serving certificate of the registration webhook
+	ServingCertSecretName = "managedcluster-admission-serving-cert"
+	// ServiceName is the name of the service of the registration webhook
+	ServiceName = "managedcluster-admission"
+)
+
+var (
+	// SignerLifetime is the lifetime of the CA of the signer
+	SignerLifetime = 5 * 365 * 24 * time.Hour
+	// ServingCertLifetime is the lifetime of the serving certificates issued by the signer. It is exposed so
+	// that integration tests can shorten it.
+	ServingCertLifetime = 30 * 24 * time.Hour
+)
+
+// ServingCertDNSNames returns the DNS names of the webhook service in the given namespace
+func ServingCertDNSNames(namespace string) []string {
+	return []string{
+		fmt.Sprintf("%s.%s.svc", ServiceName, namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", ServiceName, namespace),
+	}
+}
+
+// webhookServingSignerController maintains a self-signed CA in the namespace of the hub controller, and approves
+// and signs the csrs of the webhook serving certificate with it. Only the csrs requested by the service accounts
+// in the same namespace for the DNS names of the webhook service are signed.
+type webhookServingSignerController struct {
+	kubeClient    kubernetes.Interface
+	csrLister     certificateslisters.CertificateSigningRequestLister
+	secretLister  corev1listers.SecretLister
+	namespace     string
+	eventRecorder events.Recorder
+}
+
+// NewWebhookServingSignerController returns an instance of webhookServingSignerController
+func NewWebhookServingSignerController(
+	kubeClient kubernetes.Interface,
+	csrInformer certificatesinformers.CertificateSigningRequestInformer,
+	secretInformer corev1informers.SecretInformer,
+	namespace string,
+	recorder events.Recorder) factory.Controller {
+	c := &webhookServingSignerController{
+		kubeClient:    kubeClient,
+		csrLister:     csrInformer.Lister(),
+		secretLister:  secretInformer.Lister(),
+		namespace:     namespace,
+		eventRecorder: recorder.WithComponentSuffix("webhook-serving-signer-controller"),
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, func(obj interface{}) bool {
+			csr, ok := obj.(*certificatesv1.CertificateSigningRequest)
+			return ok && csr.Spec.SignerName == SignerName
+		}, csrInformer.Informer()).
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			return accessor.GetNamespace() == namespace && accessor.GetName() == SignerSecretName
+		}, secretInformer.Informer()).
+		WithSync(health.WrapSync("WebhookServingSignerController", c.sync)).
+		ToController("WebhookServingSignerController", recorder)
+}
+
+func (c *webhookServingSignerController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	ca, err := c.ensureCA(ctx)
+	if err != nil {
+		return err
+	}
+
+	csrName := syncCtx.QueueKey()
+	if csrName == factory.DefaultQueueKey {
+		return nil
+	}
+	logger := helpers.ControllerLogger(ctx, "WebhookServingSignerController")
+	logger.V(helpers.LogLevelDebug).Info("Reconciling CertificateSigningRequest", helpers.LogKeyResource, klog.KRef("", csrName))
+
+	csr, err := c.csrLister.Get(csrName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if csr.Spec.SignerName != SignerName || len(csr.Status.Certificate) > 0 {
+		return nil
+	}
+
+	approved := false
+	for _, condition := range csr.Status.Conditions {
+		switch condition.Type {
+		case certificatesv1.CertificateDenied, certificatesv1.CertificateFailed:
+			return nil
+		case certificatesv1.CertificateApproved:
+			approved = true
+		}
+	}
+
+	x509cr, err := c.validate(csr)
+	if err != nil {
+		logger.V(helpers.LogLevelDebug).Info("Webhook serving csr cannot be signed",
+			helpers.LogKeyResource, klog.KObj(csr), helpers.LogKeyReason, err.Error())
+		return nil
+	}
+
+	csr = csr.DeepCopy()
+	if !approved {
+		csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+			Type:    certificatesv1.CertificateApproved,
+			Status:  corev1.ConditionTrue,
+			Reason:  "AutoApprovedByWebhookServingSigner",
+			Message: "Auto approving the serving certificate of the registration webhook.",
+		})
+		csr, err = c.kubeClient.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, csr.Name, csr, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	certData, err := sign(ca, x509cr, ServingCertLifetime)
+	if err != nil {
+		return err
+	}
+	csr.Status.Certificate = certData
+	if _, err := c.kubeClient.CertificatesV1().CertificateSigningRequests().UpdateStatus(ctx, csr, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	c.eventRecorder.Eventf("WebhookServingCertSigned", "webhook serving csr %q is signed", csr.Name)
+	return nil
+}
+
+// ensureCA returns the CA of the signer, and creates it if it does not exist
+func (c *webhookServingSignerController) ensureCA(ctx context.Context) (*crypto.CA, error) {
+	secret, err := c.secretLister.Secrets(c.namespace).Get(SignerSecretName)
+	switch {
+	case errors.IsNotFound(err):
+		caConfig, err := crypto.MakeSelfSignedCAConfigForDuration(fmt.Sprintf("%s@%d", SignerName, time.Now().Unix()), SignerLifetime)
+		if err != nil {
+			return nil, err
+		}
+		certData, keyData, err := caConfig.GetPEMBytes()
+		if err != nil {
+			return nil, err
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: c.namespace,
+				Name:      SignerSecretName,
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       certData,
+				corev1.TLSPrivateKeyKey: keyData,
+			},
+		}
+		if _, err := c.kubeClient.CoreV1().Secrets(c.namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+			return nil, err
+		}
+		c.eventRecorder.Eventf("WebhookServingSignerCreated", "the CA of the webhook serving signer is created in secret %q",
+			c.namespace+"/"+SignerSecretName)
+	case err != nil:
+		return nil, err
+	}
+
+	return crypto.GetCAFromBytes(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+}
+
+// validate checks the csr is requested by a service account in the namespace of the hub controller, for the
+// server usages and the DNS names of the webhook service.
+func (c *webhookServingSignerController) validate(csr *certificatesv1.CertificateSigningRequest) (*x509.CertificateRequest, error) {
+	if !strings.HasPrefix(csr.Spec.Username, fmt.Sprintf("system:serviceaccount:%s:", c.namespace)) {
+		return nil, fmt.Errorf("requester %q is not a service account in namespace %q", csr.Spec.Username, c.namespace)
+	}
+
+	allowedUsages := sets.NewString(
+		string(certificatesv1.UsageDigitalSignature),
+		string(certificatesv1.UsageKeyEncipherment),
+		string(certificatesv1.UsageServerAuth),
+	)
+	for _, usage := range csr.Spec.Usages {
+		if !allowedUsages.Has(string(usage)) {
+			return nil, fmt.Errorf("usage %q is not allowed", usage)
+		}
+	}
+
+	block, _ := pem.Decode(csr.Spec.Request)
+	if block == nil || block.Type != certutil.CertificateRequestBlockType {
+		return nil, fmt.Errorf("PEM block type is not CERTIFICATE REQUEST")
+	}
+	x509cr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := x509cr.CheckSignature(); err != nil {
+		return nil, err
+	}
+	if fips.Enabled() {
+		if err := fips.ValidatePublicKey(x509cr.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(x509cr.IPAddresses) > 0 || len(x509cr.EmailAddresses) > 0 || len(x509cr.URIs) > 0 {
+		return nil, fmt.Errorf("only DNS names are allowed in subject alternative names")
+	}
+	allowedDNSNames := sets.NewString(ServingCertDNSNames(c.namespace)...)
+	for _, dnsName := range x509cr.DNSNames {
+		if !allowedDNSNames.Has(dnsName) {
+			return nil, fmt.Errorf("DNS name %q is not allowed", dnsName)
+		}
+	}
+
+	return x509cr, nil
+}
+
+// sign issues a serving certificate for the certificate request with the CA
+func sign(ca *crypto.CA, x509cr *x509.CertificateRequest, lifetime time.Duration) ([]byte, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               x509cr.Subject,
+		DNSNames:              x509cr.DNSNames,
+		NotBefore:             now.Add(-1 * time.Minute),
+		NotAfter:              now.Add(lifetime),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Config.Certs[0], x509cr.PublicKey, ca.Config.Key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: certutil.CertificateBlockType, Bytes: der}), nil
+}
@@ -0,0 +1,229 @@
+package webhookcert
+
+import (
+	"context"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+)
+
+const testNamespace = "open-cluster-management-hub"
+
+var servingUsages = []certificatesv1.KeyUsage{
+	certificatesv1.UsageDigitalSignature,
+	certificatesv1.UsageKeyEncipherment,
+	certificatesv1.UsageServerAuth,
+}
+
+func TestSignerSync(t *testing.T) {
+	signerSecret := newSignerSecret(t)
+
+	cases := []struct {
+		name            string
+		queueKey        string
+		secrets         []runtime.Object
+		csrs            []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:     "create the signer",
+			queueKey: "key",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "create")
+				secret := actions[0].(clienttesting.CreateActionImpl).Object.(*corev1.Secret)
+				if secret.Namespace != testNamespace || secret.Name != SignerSecretName {
+					t.Errorf("unexpected secret %s/%s", secret.Namespace, secret.Name)
+				}
+				if _, err := crypto.GetCAFromBytes(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name:     "sync a deleted csr",
+			queueKey: "csr1",
+			secrets:  []runtime.Object{signerSecret},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "approve and sign a serving csr",
+			queueKey: "csr1",
+			secrets:  []runtime.Object{signerSecret},
+			csrs: []runtime.Object{
+				newServingCSR(t, "system:serviceaccount:"+testNamespace+":hub-sa", ServingCertDNSNames(testNamespace), servingUsages),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update", "update")
+				if actions[0].GetSubresource() != "approval" {
+					t.Errorf("expected approval to be updated, but got %q", actions[0].GetSubresource())
+				}
+				csr := actions[1].(clienttesting.UpdateActionImpl).Object.(*certificatesv1.CertificateSigningRequest)
+				certs, err := certutil.ParseCertsPEM(csr.Status.Certificate)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !sets.NewString(certs[0].DNSNames...).Equal(sets.NewString(ServingCertDNSNames(testNamespace)...)) {
+					t.Errorf("unexpected dns names %v", certs[0].DNSNames)
+				}
+			},
+		},
+		{
+			name:     "sync a csr of another signer",
+			queueKey: "csr1",
+			secrets:  []runtime.Object{signerSecret},
+			csrs: []runtime.Object{func() runtime.Object {
+				csr := newServingCSR(t, "system:serviceaccount:"+testNamespace+":hub-sa", ServingCertDNSNames(testNamespace), servingUsages)
+				csr.Spec.SignerName = certificatesv1.KubeletServingSignerName
+				return csr
+			}()},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "sync a csr requested by a service account in another namespace",
+			queueKey: "csr1",
+			secrets:  []runtime.Object{signerSecret},
+			csrs: []runtime.Object{
+				newServingCSR(t, "system:serviceaccount:default:sa", ServingCertDNSNames(testNamespace), servingUsages),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "sync a csr with disallowed dns names",
+			queueKey: "csr1",
+			secrets:  []runtime.Object{signerSecret},
+			csrs: []runtime.Object{
+				newServingCSR(t, "system:serviceaccount:"+testNamespace+":hub-sa", []string{"kubernetes.default.svc"}, servingUsages),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "sync a csr with client auth usage",
+			queueKey: "csr1",
+			secrets:  []runtime.Object{signerSecret},
+			csrs: []runtime.Object{
+				newServingCSR(t, "system:serviceaccount:"+testNamespace+":hub-sa", ServingCertDNSNames(testNamespace),
+					append(servingUsages, certificatesv1.UsageClientAuth)),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "sync a denied csr",
+			queueKey: "csr1",
+			secrets:  []runtime.Object{signerSecret},
+			csrs: []runtime.Object{func() runtime.Object {
+				csr := newServingCSR(t, "system:serviceaccount:"+testNamespace+":hub-sa", ServingCertDNSNames(testNamespace), servingUsages)
+				csr.Status.Conditions = []certificatesv1.CertificateSigningRequestCondition{
+					{Type: certificatesv1.CertificateDenied, Status: corev1.ConditionTrue},
+				}
+				return csr
+			}()},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objects := append([]runtime.Object{}, c.secrets...)
+			objects = append(objects, c.csrs...)
+			kubeClient := kubefake.NewSimpleClientset(objects...)
+
+			informerFactory := informers.NewSharedInformerFactory(kubeClient, 3*time.Minute)
+			for _, secret := range c.secrets {
+				informerFactory.Core().V1().Secrets().Informer().GetStore().Add(secret)
+			}
+			for _, csr := range c.csrs {
+				informerFactory.Certificates().V1().CertificateSigningRequests().Informer().GetStore().Add(csr)
+			}
+
+			ctrl := &webhookServingSignerController{
+				kubeClient:    kubeClient,
+				csrLister:     informerFactory.Certificates().V1().CertificateSigningRequests().Lister(),
+				secretLister:  informerFactory.Core().V1().Secrets().Lister(),
+				namespace:     testNamespace,
+				eventRecorder: eventstesting.NewTestingEventRecorder(t),
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, c.queueKey))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateActions(t, kubeClient.Actions())
+		})
+	}
+}
+
+func newSignerSecret(t *testing.T) *corev1.Secret {
+	caConfig, err := crypto.MakeSelfSignedCAConfigForDuration("test-signer", time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	certData, keyData, err := caConfig.GetPEMBytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace,
+			Name:      SignerSecretName,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certData,
+			corev1.TLSPrivateKeyKey: keyData,
+		},
+	}
+}
+
+func newServingCSR(t *testing.T, username string, dnsNames []string, usages []certificatesv1.KeyUsage) *certificatesv1.CertificateSigningRequest {
+	keyData, err := keyutil.MakeEllipticPrivateKeyPEM()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	privateKey, err := keyutil.ParsePrivateKeyPEM(keyData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	csrData, err := certutil.MakeCSR(privateKey, &pkix.Name{CommonName: ServiceName}, dnsNames, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "csr1",
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Username:   username,
+			Usages:     usages,
+			SignerName: SignerName,
+			Request:    csrData,
+		},
+	}
+}
@@ -0,0 +1,216 @@
+package webhookconfig
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	admissionregistrationinformers "k8s.io/client-go/informers/admissionregistration/v1"
+	"k8s.io/client-go/kubernetes"
+	admissionregistrationlisters "k8s.io/client-go/listers/admissionregistration/v1"
+
+	"open-cluster-management.io/registration/pkg/health"
+)
+
+// namespaceNameLabel is the label set on each namespace with its name by the kube apiserver
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+var (
+	// ValidatingWebhookConfigurationNames are the names of the validating webhook configurations of the
+	// registration webhook
+	ValidatingWebhookConfigurationNames = []string{
+		"managedclustervalidators.admission.cluster.open-cluster-management.io",
+		"managedclustersetbindingvalidators.admission.cluster.open-cluster-management.io",
+	}
+	// MutatingWebhookConfigurationNames are the names of the mutating webhook configurations of the
+	// registration webhook
+	MutatingWebhookConfigurationNames = []string{
+		"managedclustermutators.admission.cluster.open-cluster-management.io",
+	}
+)
+
+// WebhookPolicy is the policy configured by the hub command flags for the webhooks of the registration webhook.
+// Only the fields which are set are applied, the others are left as they are deployed.
+type WebhookPolicy struct {
+	// FailurePolicy is the failure policy of the webhooks, Fail or Ignore. The failure policies of the webhooks
+	// are not changed if it is empty.
+	FailurePolicy admissionregistrationv1.FailurePolicyType
+	// ExcludedNamespaces are the namespaces whose objects are not sent to the webhooks. The cluster scoped
+	// objects, like ManagedClusters, are always sent to the webhooks. The namespace selectors of the webhooks are
+	// not changed if it is empty.
+	ExcludedNamespaces []string
+}
+
+// IsSet returns true if any field of the policy is set, otherwise the webhooks are left as they are deployed
+func (p WebhookPolicy) IsSet() bool {
+	return len(p.FailurePolicy) > 0 || len(p.ExcludedNamespaces) > 0
+}
+
+// Validate returns an error if the policy is invalid
+func (p WebhookPolicy) Validate() error {
+	if len(p.FailurePolicy) == 0 {
+		return nil
+	}
+	return ValidateFailurePolicy(field.NewPath("failurePolicy"), p.FailurePolicy).ToAggregate()
+}
+
+// ValidateFailurePolicy returns an error if the failure policy is not Fail or Ignore
+func ValidateFailurePolicy(fldPath *field.Path, failurePolicy admissionregistrationv1.FailurePolicyType) field.ErrorList {
+	switch failurePolicy {
+	case admissionregistrationv1.Fail, admissionregistrationv1.Ignore:
+		return nil
+	default:
+		return field.ErrorList{field.NotSupported(fldPath, failurePolicy,
+			[]string{string(admissionregistrationv1.Fail), string(admissionregistrationv1.Ignore)})}
+	}
+}
+
+// namespaceSelector returns the namespace selector of the webhooks which excludes the excluded namespaces
+func (p WebhookPolicy) namespaceSelector() *metav1.LabelSelector {
+	if len(p.ExcludedNamespaces) == 0 {
+		return nil
+	}
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{
+				Key:      namespaceNameLabel,
+				Operator: metav1.LabelSelectorOpNotIn,
+				Values:   sets.NewString(p.ExcludedNamespaces...).List(),
+			},
+		},
+	}
+}
+
+// webhookConfigurationController applies the failure policy and the excluded namespaces configured on the hub
+// to the webhook configurations of the registration webhook, so that each environment can choose whether the
+// requests fail open or closed when the webhook is unavailable.
+type webhookConfigurationController struct {
+	kubeClient              kubernetes.Interface
+	validatingWebhookLister admissionregistrationlisters.ValidatingWebhookConfigurationLister
+	mutatingWebhookLister   admissionregistrationlisters.MutatingWebhookConfigurationLister
+	policy                  WebhookPolicy
+}
+
+// NewWebhookConfigurationController returns an instance of webhookConfigurationController
+func NewWebhookConfigurationController(
+	kubeClient kubernetes.Interface,
+	validatingWebhookInformer admissionregistrationinformers.ValidatingWebhookConfigurationInformer,
+	mutatingWebhookInformer admissionregistrationinformers.MutatingWebhookConfigurationInformer,
+	policy WebhookPolicy,
+	recorder events.Recorder) factory.Controller {
+	c := &webhookConfigurationController{
+		kubeClient:              kubeClient,
+		validatingWebhookLister: validatingWebhookInformer.Lister(),
+		mutatingWebhookLister:   mutatingWebhookInformer.Lister(),
+		policy:                  policy,
+	}
+
+	names := sets.NewString(ValidatingWebhookConfigurationNames...).Insert(MutatingWebhookConfigurationNames...)
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			return names.Has(accessor.GetName())
+		}, validatingWebhookInformer.Informer(), mutatingWebhookInformer.Informer()).
+		WithSync(health.WrapSync("WebhookConfigurationController", c.sync)).
+		ResyncEvery(10*time.Minute).
+		ToController("WebhookConfigurationController", recorder)
+}
+
+func (c *webhookConfigurationController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	errs := []error{}
+	for _, name := range ValidatingWebhookConfigurationNames {
+		if err := c.applyValidatingWebhookConfiguration(ctx, syncCtx.Recorder(), name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, name := range MutatingWebhookConfigurationNames {
+		if err := c.applyMutatingWebhookConfiguration(ctx, syncCtx.Recorder(), name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return operatorhelpers.NewMultiLineAggregate(errs)
+}
+
+func (c *webhookConfigurationController) applyValidatingWebhookConfiguration(ctx context.Context, recorder events.Recorder, name string) error {
+	config, err := c.validatingWebhookLister.Get(name)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	config = config.DeepCopy()
+	modified := false
+	for i := range config.Webhooks {
+		modified = c.applyPolicy(&config.Webhooks[i].FailurePolicy, &config.Webhooks[i].NamespaceSelector) || modified
+	}
+	if !modified {
+		return nil
+	}
+
+	if _, err := c.kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().Update(ctx, config, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	recorder.Eventf("WebhookPolicyApplied", "the webhook policy is applied to validatingwebhookconfiguration %q", name)
+	return nil
+}
+
+func (c *webhookConfigurationController) applyMutatingWebhookConfiguration(ctx context.Context, recorder events.Recorder, name string) error {
+	config, err := c.mutatingWebhookLister.Get(name)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	config = config.DeepCopy()
+	modified := false
+	for i := range config.Webhooks {
+		modified = c.applyPolicy(&config.Webhooks[i].FailurePolicy, &config.Webhooks[i].NamespaceSelector) || modified
+	}
+	if !modified {
+		return nil
+	}
+
+	if _, err := c.kubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations().Update(ctx, config, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	recorder.Eventf("WebhookPolicyApplied", "the webhook policy is applied to mutatingwebhookconfiguration %q", name)
+	return nil
+}
+
+// applyPolicy sets the failure policy and the namespace selector of a webhook if they are set in the policy, and
+// returns true if any of them is changed
+func (c *webhookConfigurationController) applyPolicy(failurePolicy **admissionregistrationv1.FailurePolicyType,
+	namespaceSelector **metav1.LabelSelector) bool {
+	modified := false
+
+	if len(c.policy.FailurePolicy) > 0 && (*failurePolicy == nil || **failurePolicy != c.policy.FailurePolicy) {
+		policy := c.policy.FailurePolicy
+		*failurePolicy = &policy
+		modified = true
+	}
+
+	if required := c.policy.namespaceSelector(); required != nil && !equality.Semantic.DeepEqual(*namespaceSelector, required) {
+		*namespaceSelector = required
+		modified = true
+	}
+	return modified
+}
@@ -0,0 +1,207 @@
+package webhookconfig
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestSync(t *testing.T) {
+	fail := admissionregistrationv1.Fail
+	ignore := admissionregistrationv1.Ignore
+	excludingSelector := &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{
+				Key:      namespaceNameLabel,
+				Operator: metav1.LabelSelectorOpNotIn,
+				Values:   []string{"ns1", "ns2"},
+			},
+		},
+	}
+
+	cases := []struct {
+		name            string
+		policy          WebhookPolicy
+		webhookConfigs  []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:   "no webhook configurations",
+			policy: WebhookPolicy{FailurePolicy: admissionregistrationv1.Fail},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:   "apply the failure policy and the excluded namespaces",
+			policy: WebhookPolicy{FailurePolicy: admissionregistrationv1.Ignore, ExcludedNamespaces: []string{"ns2", "ns1"}},
+			webhookConfigs: []runtime.Object{
+				newValidatingWebhookConfiguration(ValidatingWebhookConfigurationNames[0], &fail, nil),
+				newMutatingWebhookConfiguration(MutatingWebhookConfigurationNames[0], &fail, nil),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update", "update")
+				validating := actions[0].(clienttesting.UpdateActionImpl).Object.(*admissionregistrationv1.ValidatingWebhookConfiguration)
+				if *validating.Webhooks[0].FailurePolicy != admissionregistrationv1.Ignore {
+					t.Errorf("expected failure policy Ignore, but got %v", *validating.Webhooks[0].FailurePolicy)
+				}
+				if !reflect.DeepEqual(validating.Webhooks[0].NamespaceSelector, excludingSelector) {
+					t.Errorf("expected namespace selector %v, but got %v", excludingSelector, validating.Webhooks[0].NamespaceSelector)
+				}
+				mutating := actions[1].(clienttesting.UpdateActionImpl).Object.(*admissionregistrationv1.MutatingWebhookConfiguration)
+				if *mutating.Webhooks[0].FailurePolicy != admissionregistrationv1.Ignore {
+					t.Errorf("expected failure policy Ignore, but got %v", *mutating.Webhooks[0].FailurePolicy)
+				}
+				if !reflect.DeepEqual(mutating.Webhooks[0].NamespaceSelector, excludingSelector) {
+					t.Errorf("expected namespace selector %v, but got %v", excludingSelector, mutating.Webhooks[0].NamespaceSelector)
+				}
+			},
+		},
+		{
+			name:   "the policy is applied already",
+			policy: WebhookPolicy{FailurePolicy: admissionregistrationv1.Ignore, ExcludedNamespaces: []string{"ns1", "ns2"}},
+			webhookConfigs: []runtime.Object{
+				newValidatingWebhookConfiguration(ValidatingWebhookConfigurationNames[0], &ignore, excludingSelector),
+				newMutatingWebhookConfiguration(MutatingWebhookConfigurationNames[0], &ignore, excludingSelector),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:   "keep the namespace selectors if no namespace is excluded",
+			policy: WebhookPolicy{FailurePolicy: admissionregistrationv1.Ignore},
+			webhookConfigs: []runtime.Object{
+				newValidatingWebhookConfiguration(ValidatingWebhookConfigurationNames[0], &ignore, excludingSelector),
+				newMutatingWebhookConfiguration(MutatingWebhookConfigurationNames[0], &ignore, &metav1.LabelSelector{}),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:   "keep the failure policies if it is not set",
+			policy: WebhookPolicy{ExcludedNamespaces: []string{"ns1", "ns2"}},
+			webhookConfigs: []runtime.Object{
+				newValidatingWebhookConfiguration(ValidatingWebhookConfigurationNames[0], &ignore, nil),
+				newMutatingWebhookConfiguration(MutatingWebhookConfigurationNames[0], &fail, excludingSelector),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				validating := actions[0].(clienttesting.UpdateActionImpl).Object.(*admissionregistrationv1.ValidatingWebhookConfiguration)
+				if *validating.Webhooks[0].FailurePolicy != admissionregistrationv1.Ignore {
+					t.Errorf("expected failure policy Ignore is kept, but got %v", *validating.Webhooks[0].FailurePolicy)
+				}
+				if !reflect.DeepEqual(validating.Webhooks[0].NamespaceSelector, excludingSelector) {
+					t.Errorf("expected namespace selector %v, but got %v", excludingSelector, validating.Webhooks[0].NamespaceSelector)
+				}
+			},
+		},
+		{
+			name:   "ignore other webhook configurations",
+			policy: WebhookPolicy{FailurePolicy: admissionregistrationv1.Ignore},
+			webhookConfigs: []runtime.Object{
+				newValidatingWebhookConfiguration("other", &fail, nil),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(c.webhookConfigs...)
+			informerFactory := informers.NewSharedInformerFactory(kubeClient, 3*time.Minute)
+			for _, config := range c.webhookConfigs {
+				switch config.(type) {
+				case *admissionregistrationv1.ValidatingWebhookConfiguration:
+					informerFactory.Admissionregistration().V1().ValidatingWebhookConfigurations().Informer().GetStore().Add(config)
+				case *admissionregistrationv1.MutatingWebhookConfiguration:
+					informerFactory.Admissionregistration().V1().MutatingWebhookConfigurations().Informer().GetStore().Add(config)
+				}
+			}
+
+			ctrl := &webhookConfigurationController{
+				kubeClient:              kubeClient,
+				validatingWebhookLister: informerFactory.Admissionregistration().V1().ValidatingWebhookConfigurations().Lister(),
+				mutatingWebhookLister:   informerFactory.Admissionregistration().V1().MutatingWebhookConfigurations().Lister(),
+				policy:                  c.policy,
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "key"))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateActions(t, kubeClient.Actions())
+		})
+	}
+}
+
+func TestValidatePolicy(t *testing.T) {
+	cases := []struct {
+		name        string
+		policy      WebhookPolicy
+		expectedErr bool
+	}{
+		{
+			name:   "fail",
+			policy: WebhookPolicy{FailurePolicy: admissionregistrationv1.Fail},
+		},
+		{
+			name:   "ignore",
+			policy: WebhookPolicy{FailurePolicy: admissionregistrationv1.Ignore},
+		},
+		{
+			name:   "unset",
+			policy: WebhookPolicy{},
+		},
+		{
+			name:        "unsupported",
+			policy:      WebhookPolicy{FailurePolicy: "Retry"},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.policy.Validate()
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but got nil")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func newValidatingWebhookConfiguration(name string, failurePolicy *admissionregistrationv1.FailurePolicyType,
+	namespaceSelector *metav1.LabelSelector) *admissionregistrationv1.ValidatingWebhookConfiguration {
+	return &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: name, FailurePolicy: failurePolicy, NamespaceSelector: namespaceSelector},
+		},
+	}
+}
+
+func newMutatingWebhookConfiguration(name string, failurePolicy *admissionregistrationv1.FailurePolicyType,
+	namespaceSelector *metav1.LabelSelector) *admissionregistrationv1.MutatingWebhookConfiguration {
+	return &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{Name: name, FailurePolicy: failurePolicy, NamespaceSelector: namespaceSelector},
+		},
+	}
+}
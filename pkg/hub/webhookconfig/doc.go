@@ -0,0 +1,3 @@
+// package webhookconfig contains the hub-side controller which applies the failure policy and the namespace
+// exclusions configured on the hub to the webhook configurations of the registration webhook.
+package webhookconfig
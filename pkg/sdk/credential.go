@@ -0,0 +1,81 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/util/wait"
+	certificatesinformers "k8s.io/client-go/informers/certificates"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/spoke/managedcluster"
+)
+
+// NewCredentialController returns a controller which requests a client certificate for the identity from the hub
+// with csrs, and rotates it before it expires. The certificate is stored in the secret secretNamespace/secretName
+// together with the identity and a kubeconfig, which refers to the certificate files and connects to the hub
+// with hubClientConfig. The csrs are requested with the signerName, which is kubernetes.io/kube-apiserver-client
+// if it is empty, or open-cluster-management.io/cert-manager if the hub signs the client certificates with a
+// cert-manager issuer. The opts are applied to the client certificate controller, e.g. clientcert.WithKey.
+//
+// An agent bootstraps with a hubClientConfig built from its bootstrap kubeconfig, and the returned controller can
+// be stopped once WaitForHubKubeconfig returns. The agent then runs another one with a hubClientConfig built from
+// the hub kubeconfig to rotate the certificate.
+func NewCredentialController(
+	identity Identity,
+	signerName string,
+	secretNamespace, secretName string,
+	hubClientConfig *rest.Config,
+	secretInformer corev1informers.SecretInformer,
+	hubCSRInformer certificatesinformers.Interface,
+	secretKubeClient kubernetes.Interface,
+	hubKubeClient kubernetes.Interface,
+	recorder events.Recorder,
+	controllerName string,
+	opts ...clientcert.Option,
+) (factory.Controller, error) {
+	// create a kubeconfig with references to the key/cert files in the same secret
+	kubeconfig := clientcert.BuildKubeconfig(hubClientConfig, clientcert.TLSCertFile, clientcert.TLSKeyFile)
+	kubeconfigData, err := clientcmd.Write(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return managedcluster.NewClientCertForHubController(
+		identity.ClusterName, identity.AgentName, signerName, secretNamespace, secretName,
+		kubeconfigData,
+		secretInformer,
+		hubCSRInformer,
+		secretKubeClient,
+		hubKubeClient,
+		recorder,
+		controllerName,
+		opts...,
+	)
+}
+
+// WaitForHubKubeconfig waits until there is a valid hub kubeconfig for the identity in hubKubeconfigDir, which
+// is usually the mount path of the secret of the credential controller.
+func WaitForHubKubeconfig(ctx context.Context, hubKubeconfigDir string, identity Identity) error {
+	return wait.PollImmediateUntil(1*time.Second, func() (bool, error) {
+		return HasValidHubKubeconfig(hubKubeconfigDir, identity)
+	}, ctx.Done())
+}
+
+// LoadHubClientConfig returns the client config built from the hub kubeconfig in hubKubeconfigDir
+func LoadHubClientConfig(hubKubeconfigDir string) (*rest.Config, error) {
+	kubeconfigPath := path.Join(hubKubeconfigDir, clientcert.KubeconfigFile)
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load hub kubeconfig from file %q: %w", kubeconfigPath, err)
+	}
+	return config, nil
+}
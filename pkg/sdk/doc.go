@@ -0,0 +1,10 @@
+// package sdk provides the primitives of the registration agent lifecycle for the agents which register to an
+// open-cluster-management hub on their own:
+//  1. identity: resolve the names of the cluster and the agent, see ResolveIdentity;
+//  2. credential: request a client certificate for the identity with csrs, and rotate it before it expires,
+//     see NewCredentialController and WaitForHubKubeconfig;
+//  3. heartbeat: maintain the lease of the managed cluster on the hub, see NewHeartbeatController;
+//  4. re-registration: ask the agent to discard its hub credentials and bootstrap again, see TriggerReregistration.
+//
+// The registration agent itself is built on these primitives, so the custom agents get the same lifecycle.
+package sdk
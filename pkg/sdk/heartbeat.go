@@ -0,0 +1,23 @@
+package sdk
+
+import (
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+
+	"open-cluster-management.io/registration/pkg/spoke/lease"
+	"open-cluster-management.io/registration/pkg/spoke/managedcluster"
+)
+
+// NewHeartbeatController returns a controller which renews the lease of the managed cluster on the hub with the
+// lease duration of the managed cluster, once the managed cluster is accepted by the hub. The hub marks the
+// managed cluster unavailable if the lease is not renewed in time. The lease is renewed by the leaseRenewer, see
+// lease.NewRenewer, which may be shared with the other leases of the agent to renew them in batched passes.
+func NewHeartbeatController(
+	clusterName string,
+	leaseRenewer *lease.Renewer,
+	hubClusterInformer clusterv1informer.ManagedClusterInformer,
+	recorder events.Recorder,
+) factory.Controller {
+	return managedcluster.NewManagedClusterLeaseController(clusterName, leaseRenewer, hubClusterInformer, recorder)
+}
@@ -0,0 +1,171 @@
+package sdk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/spoke/managedcluster"
+)
+
+// agentNameLength is the length of the agent name which is generated automatically
+const agentNameLength = 5
+
+// derivedAgentNameLength is the length of the agent name derived from the identity of the service account
+const derivedAgentNameLength = 10
+
+// Identity is the identity of an agent on the hub. The client certificate of the agent is issued for it.
+type Identity struct {
+	// ClusterName is the name of the managed cluster
+	ClusterName string
+	// AgentName is the name of the agent, which distinguishes the agents of the same managed cluster
+	AgentName string
+}
+
+// String returns the identity in the form of <cluster name>:<agent name>
+func (i Identity) String() string {
+	return fmt.Sprintf("%s:%s", i.ClusterName, i.AgentName)
+}
+
+// ResolveIdentity returns the identity of the agent with the hub kubeconfig files in hubKubeconfigDir.
+// Rules for picking up cluster name:
+//  1. Use clusterName if it is specified;
+//  2. Parse cluster name from the common name of the certification subject if the certification exists;
+//  3. Fallback to cluster name in the mounted secret if it exists;
+//  4. Generate a random cluster name then;
+//
+// Rules for picking up agent name:
+//  1. Parse agent name from the common name of the certification subject if the certification exists;
+//  2. Fallback to agent name in the mounted secret if it exists;
+//  3. Generate a random agent name then;
+func ResolveIdentity(clusterName, hubKubeconfigDir string) Identity {
+	return ResolveIdentityWithAgentName(clusterName, hubKubeconfigDir, "")
+}
+
+// ResolveIdentityWithAgentName returns the identity of the agent as ResolveIdentity, except that agentName is used
+// instead of a random agent name if it is not empty, e.g. an agent name derived with DeriveAgentName. The agent name
+// in the certificate or in the mounted secret still takes precedence, so the existing agents keep their names.
+func ResolveIdentityWithAgentName(clusterName, hubKubeconfigDir, agentName string) Identity {
+	// try to load cluster/agent name from tls certification
+	var clusterNameInCert, agentNameInCert string
+	certPath := path.Join(hubKubeconfigDir, clientcert.TLSCertFile)
+	certData, certErr := ioutil.ReadFile(path.Clean(certPath))
+	if certErr == nil {
+		clusterNameInCert, agentNameInCert, _ = managedcluster.GetClusterAgentNamesFromCertificate(certData)
+	}
+
+	// if cluster name is not specified, try to load it from file
+	if clusterName == "" {
+		// TODO, read cluster name from openshift struct if the agent is running in an openshift cluster
+
+		// and then load the cluster name from the mounted secret
+		clusterNameFilePath := path.Join(hubKubeconfigDir, clientcert.ClusterNameFile)
+		clusterNameBytes, err := ioutil.ReadFile(path.Clean(clusterNameFilePath))
+		switch {
+		case len(clusterNameInCert) > 0:
+			// use cluster name loaded from the tls certification
+			clusterName = clusterNameInCert
+			if clusterNameInCert != string(clusterNameBytes) {
+				klog.Warningf("Use cluster name %q in certification instead of %q in the mounted secret", clusterNameInCert, string(clusterNameBytes))
+			}
+		case err == nil:
+			// use cluster name load from the mounted secret
+			clusterName = string(clusterNameBytes)
+		default:
+			// generate random cluster name
+			clusterName = GenerateClusterName()
+		}
+	}
+
+	// try to load agent name from the mounted secret
+	agentNameFilePath := path.Join(hubKubeconfigDir, clientcert.AgentNameFile)
+	agentNameBytes, err := ioutil.ReadFile(path.Clean(agentNameFilePath))
+	switch {
+	case len(agentNameInCert) > 0:
+		// use agent name loaded from the tls certification
+		agentName = agentNameInCert
+		if agentNameInCert != string(agentNameBytes) {
+			klog.Warningf("Use agent name %q in certification instead of %q in the mounted secret", agentNameInCert, string(agentNameBytes))
+		}
+	case err == nil:
+		// use agent name loaded from the mounted secret
+		agentName = string(agentNameBytes)
+	case len(agentName) > 0:
+		// use the agent name specified
+	default:
+		// generate random agent name
+		agentName = GenerateAgentName()
+	}
+
+	return Identity{ClusterName: clusterName, AgentName: agentName}
+}
+
+// GenerateClusterName generates a random name for a managed cluster
+func GenerateClusterName() string {
+	return string(uuid.NewUUID())
+}
+
+// GenerateAgentName generates a random name for an agent
+func GenerateAgentName() string {
+	return utilrand.String(agentNameLength)
+}
+
+// DeriveAgentName returns a stable agent name derived from the identity of the service account the agent runs
+// with, so the agent keeps its name once the hub kubeconfig secret is re-created, and the name maps to exactly one
+// installation of the agent.
+func DeriveAgentName(namespace, serviceAccountUID string) string {
+	hash := sha256.Sum256([]byte(namespace + "/" + serviceAccountUID))
+	return hex.EncodeToString(hash[:])[:derivedAgentNameLength]
+}
+
+// HasValidHubKubeconfig returns ture if all the conditions below are met:
+//  1. KubeconfigFile exists in hubKubeconfigDir;
+//  2. TLSKeyFile exists;
+//  3. TLSCertFile exists;
+//  4. Certificate in TLSCertFile is issued for the identity;
+//  5. Certificate in TLSCertFile is not expired;
+//
+// Normally, KubeconfigFile/TLSKeyFile/TLSCertFile will be created once the bootstrap process
+// completes. Changing the name of the cluster will make the existing hub kubeconfig invalid,
+// because certificate in TLSCertFile is issued to a specific cluster/agent.
+func HasValidHubKubeconfig(hubKubeconfigDir string, identity Identity) (bool, error) {
+	kubeconfigPath := path.Join(hubKubeconfigDir, clientcert.KubeconfigFile)
+	if _, err := os.Stat(kubeconfigPath); os.IsNotExist(err) {
+		klog.V(4).Infof("Kubeconfig file %q not found", kubeconfigPath)
+		return false, nil
+	}
+
+	keyPath := path.Join(hubKubeconfigDir, clientcert.TLSKeyFile)
+	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
+		klog.V(4).Infof("TLS key file %q not found", keyPath)
+		return false, nil
+	}
+
+	certPath := path.Join(hubKubeconfigDir, clientcert.TLSCertFile)
+	certData, err := ioutil.ReadFile(path.Clean(certPath))
+	if err != nil {
+		klog.V(4).Infof("Unable to load TLS cert file %q", certPath)
+		return false, nil
+	}
+
+	// check if the tls certificate is issued for the identity
+	clusterName, agentName, err := managedcluster.GetClusterAgentNamesFromCertificate(certData)
+	if err != nil {
+		return false, nil
+	}
+	if clusterName != identity.ClusterName || agentName != identity.AgentName {
+		klog.V(4).Infof("Certificate in file %q is issued for agent %q instead of %q",
+			certPath, fmt.Sprintf("%s:%s", clusterName, agentName), identity.String())
+		return false, nil
+	}
+
+	return clientcert.IsCertificateValid(certData, nil)
+}
@@ -0,0 +1,128 @@
+package sdk
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestResolveIdentity(t *testing.T) {
+	cert := testinghelpers.NewTestCert("system:open-cluster-management:cluster1:agent1", 60*time.Second)
+
+	cases := []struct {
+		name             string
+		clusterName      string
+		files            map[string][]byte
+		expectedIdentity Identity
+	}{
+		{
+			name:        "cluster name is specified",
+			clusterName: "cluster0",
+			files: map[string][]byte{
+				clientcert.AgentNameFile: []byte("agent0"),
+			},
+			expectedIdentity: Identity{ClusterName: "cluster0", AgentName: "agent0"},
+		},
+		{
+			name: "identity in the certificate is preferred",
+			files: map[string][]byte{
+				clientcert.TLSCertFile:     cert.Cert,
+				clientcert.ClusterNameFile: []byte("cluster2"),
+				clientcert.AgentNameFile:   []byte("agent2"),
+			},
+			expectedIdentity: Identity{ClusterName: "cluster1", AgentName: "agent1"},
+		},
+		{
+			name: "identity in the files",
+			files: map[string][]byte{
+				clientcert.ClusterNameFile: []byte("cluster2"),
+				clientcert.AgentNameFile:   []byte("agent2"),
+			},
+			expectedIdentity: Identity{ClusterName: "cluster2", AgentName: "agent2"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tempDir, err := ioutil.TempDir("", "testresolveidentity")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			for name, data := range c.files {
+				testinghelpers.WriteFile(path.Join(tempDir, name), data)
+			}
+
+			identity := ResolveIdentity(c.clusterName, tempDir)
+			if identity != c.expectedIdentity {
+				t.Errorf("expected identity %q, but got %q", c.expectedIdentity, identity)
+			}
+		})
+	}
+}
+
+func TestResolveGeneratedIdentity(t *testing.T) {
+	identity := ResolveIdentity("", "/nonexistent")
+	if len(identity.ClusterName) == 0 {
+		t.Errorf("expected cluster name is generated")
+	}
+	if len(identity.AgentName) != agentNameLength {
+		t.Errorf("expected agent name with length %d is generated, but got %q", agentNameLength, identity.AgentName)
+	}
+}
+
+func TestResolveIdentityWithAgentName(t *testing.T) {
+	agentName := DeriveAgentName("open-cluster-management-agent", "0f6a2b7e-1c3d-4e5f-8a9b-0c1d2e3f4a5b")
+	if len(agentName) != derivedAgentNameLength {
+		t.Errorf("expected agent name with length %d is derived, but got %q", derivedAgentNameLength, agentName)
+	}
+	if derived := DeriveAgentName("open-cluster-management-agent", "0f6a2b7e-1c3d-4e5f-8a9b-0c1d2e3f4a5b"); derived != agentName {
+		t.Errorf("expected the same agent name %q is derived, but got %q", agentName, derived)
+	}
+	if derived := DeriveAgentName("open-cluster-management-agent", "9d8c7b6a-5f4e-3d2c-1b0a-f9e8d7c6b5a4"); derived == agentName {
+		t.Errorf("expected another agent name is derived for another service account")
+	}
+
+	identity := ResolveIdentityWithAgentName("cluster1", "/nonexistent", agentName)
+	if identity != (Identity{ClusterName: "cluster1", AgentName: agentName}) {
+		t.Errorf("expected the derived agent name is used, but got %q", identity)
+	}
+
+	tempDir := t.TempDir()
+	testinghelpers.WriteFile(path.Join(tempDir, clientcert.AgentNameFile), []byte("agent0"))
+	identity = ResolveIdentityWithAgentName("cluster1", tempDir, agentName)
+	if identity != (Identity{ClusterName: "cluster1", AgentName: "agent0"}) {
+		t.Errorf("expected the agent name in the mounted secret is used, but got %q", identity)
+	}
+}
+
+func TestWaitForHubKubeconfig(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "testwaitforhubkubeconfig")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	identity := Identity{ClusterName: "cluster1", AgentName: "agent1"}
+	cert := testinghelpers.NewTestCert("system:open-cluster-management:cluster1:agent1", 60*time.Second)
+
+	// no hub kubeconfig
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := WaitForHubKubeconfig(ctx, tempDir, identity); err == nil {
+		t.Errorf("expected timeout error, but got nil")
+	}
+
+	testinghelpers.WriteFile(path.Join(tempDir, clientcert.KubeconfigFile), testinghelpers.NewKubeconfig(nil, nil))
+	testinghelpers.WriteFile(path.Join(tempDir, clientcert.TLSKeyFile), cert.Key)
+	testinghelpers.WriteFile(path.Join(tempDir, clientcert.TLSCertFile), cert.Cert)
+	if err := WaitForHubKubeconfig(context.Background(), tempDir, identity); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
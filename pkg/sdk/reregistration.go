@@ -0,0 +1,44 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"open-cluster-management.io/registration/pkg/spoke/managedcluster"
+)
+
+// ReregistrationAnnotation is the annotation on the hub kubeconfig secret which asks the agent to discard its
+// current hub credentials and re-run bootstrap.
+const ReregistrationAnnotation = managedcluster.ReregistrationAnnotation
+
+// TriggerReregistration annotates the hub kubeconfig secret secretNamespace/secretName with
+// ReregistrationAnnotation. The agent then discards the hub credentials in the secret and restarts to bootstrap
+// again with its bootstrap kubeconfig, which is useful to move a cluster to another hub. The identity of the
+// agent is kept. It is a no-op if the secret does not exist, since the agent bootstraps anyway in that case.
+func TriggerReregistration(ctx context.Context, kubeClient kubernetes.Interface, secretNamespace, secretName string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				ReregistrationAnnotation: "true",
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = kubeClient.CoreV1().Secrets(secretNamespace).Patch(ctx, secretName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to trigger re-registration on secret %s/%s: %w", secretNamespace, secretName, err)
+	}
+	return nil
+}
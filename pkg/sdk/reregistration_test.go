@@ -0,0 +1,60 @@
+package sdk
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestTriggerReregistration(t *testing.T) {
+	cases := []struct {
+		name   string
+		secret *corev1.Secret
+	}{
+		{
+			name: "no secret",
+		},
+		{
+			name: "annotate secret",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "ns1",
+					Name:        "hub-kubeconfig-secret",
+					Annotations: map[string]string{"foo": "bar"},
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if c.secret != nil {
+				objects = append(objects, c.secret)
+			}
+			kubeClient := kubefake.NewSimpleClientset(objects...)
+
+			if err := TriggerReregistration(context.TODO(), kubeClient, "ns1", "hub-kubeconfig-secret"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.secret == nil {
+				return
+			}
+
+			secret, err := kubeClient.CoreV1().Secrets("ns1").Get(context.TODO(), "hub-kubeconfig-secret", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if secret.Annotations[ReregistrationAnnotation] != "true" {
+				t.Errorf("expected secret to be annotated, but got %v", secret.Annotations)
+			}
+			if secret.Annotations["foo"] != "bar" {
+				t.Errorf("expected existing annotations to be kept, but got %v", secret.Annotations)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+// package secureserving serves the metrics and the internal state of the controllers of the hub controller and
+// the agent over TLS when the "--secure-bind-address" flag is set, so they are reachable from outside of the pod
+// without a kube-rbac-proxy sidecar. The bearer token of a request is authenticated with a TokenReview, and the
+// user is authorized with a SubjectAccessReview to get the non-resource url of the request, so a scraper needs a
+// cluster role granting the "get" verb on the nonResourceURLs "/metrics" and "/debug/registration".
+//
+// The serving certificate is read from the "--secure-serving-cert-file" and "--secure-serving-key-file" flags,
+// or a self-signed certificate is generated if they are not set.
+package secureserving
@@ -0,0 +1,171 @@
+package secureserving
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/authenticatorfactory"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/authorization/authorizerfactory"
+	"k8s.io/client-go/kubernetes"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/debug"
+	"open-cluster-management.io/registration/pkg/fips"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// MetricsPath is the path of the endpoint which serves the metrics
+const MetricsPath = "/metrics"
+
+// authCacheTTL is the length of time the results of the token and the access reviews are cached, so a scraper is
+// not reviewed on every request
+const authCacheTTL = 10 * time.Second
+
+// webhookRetryBackoff is the backoff of the retries of the token and the access reviews
+var webhookRetryBackoff = wait.Backoff{Duration: 500 * time.Millisecond, Factor: 1.5, Jitter: 0.2, Steps: 5}
+
+// NewDelegatingAuth returns the authenticator which authenticates the bearer tokens of the requests with
+// TokenReviews, and the authorizer which authorizes the users with SubjectAccessReviews, both are created with the
+// given kube client.
+func NewDelegatingAuth(kubeClient kubernetes.Interface) (authenticator.Request, authorizer.Authorizer, error) {
+	authn, _, err := authenticatorfactory.DelegatingAuthenticatorConfig{
+		TokenAccessReviewClient: kubeClient.AuthenticationV1(),
+		WebhookRetryBackoff:     &webhookRetryBackoff,
+		CacheTTL:                authCacheTTL,
+	}.New()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create the delegating authenticator: %w", err)
+	}
+	authz, err := authorizerfactory.DelegatingAuthorizerConfig{
+		SubjectAccessReviewClient: kubeClient.AuthorizationV1(),
+		WebhookRetryBackoff:       &webhookRetryBackoff,
+		AllowCacheTTL:             authCacheTTL,
+		DenyCacheTTL:              authCacheTTL,
+	}.New()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create the delegating authorizer: %w", err)
+	}
+	return authn, authz, nil
+}
+
+// WithAuth returns a handler which serves the requests with the given handler only if they are authenticated, and
+// the users are authorized to access the path of the requests. The verb of the access is "get" for the GET and
+// HEAD requests, and the lowercase method for the others.
+func WithAuth(handler http.Handler, authn authenticator.Request, authz authorizer.Authorizer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, ok, err := authn.AuthenticateRequest(r)
+		if err != nil {
+			klog.V(4).Infof("Unable to authenticate the request to %s: %v", r.URL.Path, err)
+		}
+		if err != nil || !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		attributes := authorizer.AttributesRecord{
+			User: resp.User,
+			Verb: requestVerb(r),
+			Path: r.URL.Path,
+		}
+		decision, reason, err := authz.Authorize(r.Context(), attributes)
+		if err != nil {
+			klog.Errorf("Unable to authorize %q to %s %s: %v", resp.User.GetName(), attributes.Verb, attributes.Path, err)
+			http.Error(w, "Authorization error", http.StatusInternalServerError)
+			return
+		}
+		if decision != authorizer.DecisionAllow {
+			klog.V(4).Infof("Forbidden %q to %s %s: %s", resp.User.GetName(), attributes.Verb, attributes.Path, reason)
+			http.Error(w, fmt.Sprintf("Forbidden (user=%s, verb=%s, path=%s)", resp.User.GetName(), attributes.Verb,
+				attributes.Path), http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func requestVerb(r *http.Request) string {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return "get"
+	}
+	return strings.ToLower(r.Method)
+}
+
+// NewTLSConfig returns the tls config of the secure endpoints. The serving certificate is loaded from certFile and
+// keyFile, or a self-signed certificate is generated if they are empty. The certificate is loaded once, so the
+// files should be watched with the flags which restart the process once they are changed. The config is
+// restricted to the FIPS-approved settings in the FIPS mode.
+func NewTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	if len(certFile) > 0 || len(keyFile) > 0 {
+		cert, err = tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load serving certificate: %w", err)
+		}
+	} else {
+		certData, keyData, err := certutil.GenerateSelfSignedCertKey("localhost", nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate self-signed serving certificate: %w", err)
+		}
+		cert, err = tls.X509KeyPair(certData, keyData)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load self-signed serving certificate: %w", err)
+		}
+	}
+	if err := fips.ValidateKeyPair(cert); err != nil {
+		return nil, fmt.Errorf("invalid serving certificate: %w", err)
+	}
+	return fips.RestrictTLSConfig(&tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}), nil
+}
+
+// Handler serves the metrics on MetricsPath and the internal state of the controllers on debug.Path to the
+// authenticated and authorized users
+func Handler(authn authenticator.Request, authz authorizer.Authorizer) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, legacyregistry.Handler())
+	mux.Handle(debug.Path, debug.Handler())
+	return WithAuth(mux, authn, authz)
+}
+
+// Serve serves the metrics and the internal state of the controllers over TLS on the given address until the
+// context is done. The requests are authenticated and authorized by the kube apiserver the kube client connects
+// to, see NewDelegatingAuth.
+func Serve(ctx context.Context, address, certFile, keyFile string, kubeClient kubernetes.Interface) error {
+	tlsConfig, err := NewTLSConfig(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	authn, authz, err := NewDelegatingAuth(kubeClient)
+	if err != nil {
+		return err
+	}
+	return helpers.ServeHTTPS(ctx, "secure metrics and debug endpoints", address, tlsConfig, Handler(authn, authz))
+}
+
+// ValidateFields verifies the flags of the secure endpoints, the field paths of the errors are named after the
+// flags
+func ValidateFields(address, certFile, keyFile string) field.ErrorList {
+	errs := field.ErrorList{}
+	if (len(certFile) == 0) != (len(keyFile) == 0) {
+		errs = append(errs, field.Required(field.NewPath("secure-serving-key-file"),
+			"must be set with secure-serving-cert-file"))
+	}
+	if len(address) == 0 && len(certFile) > 0 {
+		errs = append(errs, field.Forbidden(field.NewPath("secure-serving-cert-file"),
+			"may only be set with secure-bind-address"))
+	}
+	return errs
+}
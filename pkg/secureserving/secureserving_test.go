@@ -0,0 +1,145 @@
+package secureserving
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+func TestWithAuth(t *testing.T) {
+	authenticated := authenticator.RequestFunc(func(req *http.Request) (*authenticator.Response, bool, error) {
+		return &authenticator.Response{User: &user.DefaultInfo{Name: "system:serviceaccount:monitoring:prometheus"}}, true, nil
+	})
+
+	cases := []struct {
+		name         string
+		authn        authenticator.Request
+		authz        authorizer.Authorizer
+		expectedCode int
+	}{
+		{
+			name: "unauthenticated",
+			authn: authenticator.RequestFunc(func(req *http.Request) (*authenticator.Response, bool, error) {
+				return nil, false, nil
+			}),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "failed to authenticate",
+			authn: authenticator.RequestFunc(func(req *http.Request) (*authenticator.Response, bool, error) {
+				return nil, false, fmt.Errorf("token review failed")
+			}),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:  "forbidden",
+			authn: authenticated,
+			authz: authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+				return authorizer.DecisionNoOpinion, "no rule", nil
+			}),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:  "failed to authorize",
+			authn: authenticated,
+			authz: authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+				return authorizer.DecisionNoOpinion, "", fmt.Errorf("access review failed")
+			}),
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:  "allowed",
+			authn: authenticated,
+			authz: authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+				if a.IsResourceRequest() || a.GetVerb() != "get" || a.GetPath() != MetricsPath {
+					return authorizer.DecisionDeny, "unexpected attributes", nil
+				}
+				return authorizer.DecisionAllow, "", nil
+			}),
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			handler := WithAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}), c.authn, c.authz)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, MetricsPath, nil))
+			if recorder.Code != c.expectedCode {
+				t.Errorf("expected code %d, but got %d", c.expectedCode, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secureserving")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certData, keyData, err := certutil.GenerateSelfSignedCertKey("registration", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := path.Join(dir, "tls.crt"), path.Join(dir, "tls.key")
+	if err := ioutil.WriteFile(certFile, certData, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyData, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name        string
+		certFile    string
+		keyFile     string
+		expectedErr bool
+	}{
+		{
+			name: "self-signed certificate",
+		},
+		{
+			name:     "certificate files",
+			certFile: certFile,
+			keyFile:  keyFile,
+		},
+		{
+			name:        "missing key file",
+			certFile:    certFile,
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tlsConfig, err := NewTLSConfig(c.certFile, c.keyFile)
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tlsConfig.Certificates) != 1 {
+				t.Errorf("expected one serving certificate, but got %d", len(tlsConfig.Certificates))
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	certificatesv1 "k8s.io/api/certificates/v1"
 	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	"open-cluster-management.io/registration/pkg/helpers"
 )
 
 const defaultAddOnInstallationNamespace = "open-cluster-management-agent-addon"
@@ -22,10 +23,18 @@ type registrationConfig struct {
 	registration          addonv1alpha1.RegistrationConfig
 
 	// secretName is the name of secret containing client certificate. If the SignerName is "kubernetes.io/kube-apiserver-client",
-	// the secret name will be "{addon name}-hub-kubeconfig". Otherwise, the secret name will be "{addon name}-{signer name}-client-cert".
+	// the secret name will be "{addon name}-hub-kubeconfig". If the SignerName is "open-cluster-management.io/addon-token", the
+	// secret name will be "{addon name}-hub-token". Otherwise, the secret name will be "{addon name}-{signer name}-client-cert".
 	secretName string
-	hash       string
-	stopFunc   context.CancelFunc
+	// rotationThreshold is the ratio of the certificate lifetime remaining at which the client certificate is rotated
+	rotationThreshold float64
+	hash              string
+	stopFunc          context.CancelFunc
+}
+
+// key returns the key of the registration, which is unique among the registrations of all addons
+func (c *registrationConfig) key() string {
+	return fmt.Sprintf("%s/%s", c.addOnName, c.hash)
 }
 
 func (c *registrationConfig) x509Subject(clusterName, agentName string) *pkix.Name {
@@ -61,10 +70,18 @@ func getAddOnInstallationNamespace(addOn *addonv1alpha1.ManagedClusterAddOn) str
 }
 
 // getRegistrationConfigs reads annotations of a addon and returns a map of registrationConfig whose
-// key is the hash of the registrationConfig
-func getRegistrationConfigs(addOn *addonv1alpha1.ManagedClusterAddOn) (map[string]registrationConfig, error) {
+// key is the hash of the registrationConfig. If the registration configuration of the addon is set on
+// the hub, it takes precedence over the registrations in the status of the addon.
+func getRegistrationConfigs(addOn *addonv1alpha1.ManagedClusterAddOn, addOnConfig *helpers.AddOnRegistrationConfig) (map[string]registrationConfig, error) {
+	registrations := addOn.Status.Registrations
+	if addOnConfig != nil {
+		registrations = []addonv1alpha1.RegistrationConfig{addOnConfig.Registration}
+	}
+
 	configs := map[string]registrationConfig{}
-	for _, registration := range addOn.Status.Registrations {
+	// an addon requesting credentials from multiple signers declares one registration per signer, and each of
+	// them has its own secret
+	for _, registration := range registrations {
 		config := registrationConfig{
 			addOnName:             addOn.Name,
 			installationNamespace: getAddOnInstallationNamespace(addOn),
@@ -75,6 +92,8 @@ func getRegistrationConfigs(addOn *addonv1alpha1.ManagedClusterAddOn) (map[strin
 		switch registration.SignerName {
 		case certificatesv1.KubeAPIServerClientSignerName:
 			config.secretName = fmt.Sprintf("%s-hub-kubeconfig", addOn.Name)
+		case helpers.AddOnTokenSignerName:
+			config.secretName = fmt.Sprintf("%s-hub-token", addOn.Name)
 		default:
 			config.secretName = fmt.Sprintf("%s-%s-client-cert", addOn.Name, strings.ReplaceAll(registration.SignerName, "/", "-"))
 		}
@@ -87,6 +106,17 @@ func getRegistrationConfigs(addOn *addonv1alpha1.ManagedClusterAddOn) (map[strin
 		}
 		h := sha256.New()
 		h.Write(data)
+
+		// override the secret name and the rotation threshold with the configuration on the hub, and take them
+		// into account in the hash, so the registration is restarted once they change
+		if addOnConfig != nil {
+			if len(addOnConfig.SecretName) > 0 {
+				config.secretName = addOnConfig.SecretName
+			}
+			config.rotationThreshold = addOnConfig.RotationThreshold
+			h.Write([]byte(fmt.Sprintf("%s/%v", addOnConfig.SecretName, addOnConfig.RotationThreshold)))
+		}
+
 		config.hash = fmt.Sprintf("%x", h.Sum(nil))
 		configs[config.hash] = config
 	}
@@ -99,5 +129,5 @@ func defaultCommonName(clusterName, agentName, addonName string) string {
 }
 
 func defaultOrganization(clusterName, addonName string) string {
-	return fmt.Sprintf("system:open-cluster-management:cluster:%s:addon:%s", clusterName, addonName)
+	return helpers.AddOnDefaultGroup(clusterName, addonName)
 }
@@ -77,11 +77,37 @@ func TestGetRegistrationConfigs(t *testing.T) {
 				newRegistrationConfig(addOnName, addOnNamespace, "mysigner", "", nil),
 			},
 		},
+		{
+			name: "with multiple signers",
+			addon: &addonv1alpha1.ManagedClusterAddOn{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: testinghelpers.TestManagedClusterName,
+					Name:      addOnName,
+				},
+				Spec: addonv1alpha1.ManagedClusterAddOnSpec{
+					InstallNamespace: addOnNamespace,
+				},
+				Status: addonv1alpha1.ManagedClusterAddOnStatus{
+					Registrations: []addonv1alpha1.RegistrationConfig{
+						{
+							SignerName: "kubernetes.io/kube-apiserver-client",
+						},
+						{
+							SignerName: "mysigner",
+						},
+					},
+				},
+			},
+			configs: []registrationConfig{
+				newRegistrationConfig(addOnName, addOnNamespace, "kubernetes.io/kube-apiserver-client", "", nil),
+				newRegistrationConfig(addOnName, addOnNamespace, "mysigner", "", nil),
+			},
+		},
 	}
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			configs, err := getRegistrationConfigs(c.addon)
+			configs, err := getRegistrationConfigs(c.addon, nil)
 			if err != nil {
 				t.Errorf("unexpected error: %v", err)
 			}
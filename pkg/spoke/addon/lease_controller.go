@@ -2,6 +2,7 @@ package addon
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -9,7 +10,9 @@ import (
 	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
 	addoninformerv1alpha1 "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
 	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	"open-cluster-management.io/registration/pkg/health"
 	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/spoke/lease"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -19,12 +22,18 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/clock"
 	coordv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
 	"k8s.io/client-go/tools/cache"
 )
 
-const leaseDurationTimes = 5
+const (
+	leaseDurationTimes = 5
+
+	// managedClusterLeaseName is the name of the lease of the managed cluster on the hub
+	managedClusterLeaseName = "managed-cluster-lease"
+)
 
 // AddOnLeaseControllerLeaseDurationSeconds is exposed so that integration tests can crank up the lease update speed.
 // TODO: we may add this to ManagedClusterAddOn API to allow addon to adjust its own lease duration seconds
@@ -39,6 +48,15 @@ type managedClusterAddOnLeaseController struct {
 	addOnLister    addonlisterv1alpha1.ManagedClusterAddOnLister
 	hubLeaseClient coordv1client.CoordinationV1Interface
 	leaseClient    coordv1client.CoordinationV1Interface
+
+	// aggregated is true indicates the available conditions of all addons are summarized into an annotation
+	// of the managed cluster lease on the hub instead of being updated on each addon. The summary is written by
+	// the leaseRenewer along with the renewal of the managed cluster lease.
+	aggregated   bool
+	leaseRenewer *lease.Renewer
+	// summaryRemoved is true once the summary of a previous aggregated run is removed from the managed cluster
+	// lease, so the hub stops fanning it out when the addons are updated one by one.
+	summaryRemoved bool
 }
 
 // NewManagedClusterAddOnLeaseController returns an instance of managedClusterAddOnLeaseController
@@ -47,6 +65,8 @@ func NewManagedClusterAddOnLeaseController(clusterName string,
 	addOnInformer addoninformerv1alpha1.ManagedClusterAddOnInformer,
 	hubLeaseClient coordv1client.CoordinationV1Interface,
 	leaseClient coordv1client.CoordinationV1Interface,
+	aggregated bool,
+	leaseRenewer *lease.Renewer,
 	resyncInterval time.Duration,
 	recorder events.Recorder) factory.Controller {
 	c := &managedClusterAddOnLeaseController{
@@ -56,6 +76,8 @@ func NewManagedClusterAddOnLeaseController(clusterName string,
 		addOnLister:    addOnInformer.Lister(),
 		hubLeaseClient: hubLeaseClient,
 		leaseClient:    leaseClient,
+		aggregated:     aggregated,
+		leaseRenewer:   leaseRenewer,
 	}
 
 	// TODO We do not add leaser informer to support kubernetes version lower than 1.17. Lease v1 api
@@ -63,7 +85,7 @@ func NewManagedClusterAddOnLeaseController(clusterName string,
 	// informer cache sync and result in fatal exit of this controller. The code will be factored
 	// when we no longer support kubernetes version lower than 1.17.
 	return factory.New().
-		WithSync(c.sync).
+		WithSync(health.WrapSync("ManagedClusterAddOnLeaseController", c.sync)).
 		ResyncEvery(resyncInterval).
 		ToController("ManagedClusterAddOnLeaseController", recorder)
 }
@@ -75,6 +97,12 @@ func (c *managedClusterAddOnLeaseController) sync(ctx context.Context, syncCtx f
 		if err != nil {
 			return err
 		}
+		if c.aggregated {
+			return c.syncAggregated(ctx, addOns, syncCtx.Recorder())
+		}
+		if err := c.removeSummary(ctx); err != nil {
+			return err
+		}
 		for _, addOn := range addOns {
 			// enqueue the addon to reconcile
 			syncCtx.Queue().Add(fmt.Sprintf("%s/%s", getAddOnInstallationNamespace(addOn), addOn.Name))
@@ -110,6 +138,118 @@ func (c *managedClusterAddOnLeaseController) syncSingle(ctx context.Context,
 	leaseNamespace string,
 	addOn *addonv1alpha1.ManagedClusterAddOn,
 	recorder events.Recorder) error {
+	condition, err := c.getAvailableCondition(ctx, leaseNamespace, addOn)
+	if err != nil {
+		return err
+	}
+
+	if meta.IsStatusConditionPresentAndEqual(addOn.Status.Conditions, condition.Type, condition.Status) {
+		// addon status is not changed, do nothing
+		return nil
+	}
+
+	_, updated, err := helpers.UpdateManagedClusterAddOnStatus(
+		ctx,
+		c.addOnClient,
+		c.clusterName,
+		addOn.Name,
+		helpers.UpdateManagedClusterAddOnStatusFn(condition),
+	)
+	if err != nil {
+		return err
+	}
+	if updated {
+		recorder.Eventf("ManagedClusterAddOnStatusUpdated",
+			"update managed cluster addon %q available condition to %q with its lease %q/%q status",
+			addOn.Name, condition.Status, leaseNamespace, addOn.Name)
+	}
+
+	return nil
+}
+
+// syncAggregated summarizes the available conditions of the addons into the addon health summary annotation of
+// the managed cluster lease on the hub
+func (c *managedClusterAddOnLeaseController) syncAggregated(ctx context.Context,
+	addOns []*addonv1alpha1.ManagedClusterAddOn,
+	recorder events.Recorder) error {
+	summary := map[string]metav1.Condition{}
+	for _, addOn := range addOns {
+		// "Customized" mode health check is supposed to delegate the health checking
+		// to the addon manager.
+		if addOn.Status.HealthCheck.Mode == addonv1alpha1.HealthCheckModeCustomized {
+			continue
+		}
+
+		condition, err := c.getAvailableCondition(ctx, getAddOnInstallationNamespace(addOn), addOn)
+		if err != nil {
+			return err
+		}
+		summary[addOn.Name] = condition
+	}
+	summaryData, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	lease, err := c.hubLeaseClient.Leases(c.clusterName).Get(ctx, managedClusterLeaseName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		// the lease is created by the hub once the cluster is accepted
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if lease.Annotations[helpers.AddOnHealthSummaryAnnotation] == string(summaryData) {
+		return nil
+	}
+
+	// the summary is written in the next pass of the lease renewer, which renews the managed cluster lease in the
+	// same request
+	c.leaseRenewer.Annotate(c.clusterName, managedClusterLeaseName, helpers.AddOnHealthSummaryAnnotation, string(summaryData))
+	recorder.Eventf("AddOnHealthSummaryUpdated", "addon health summary of managed cluster %q is updated", c.clusterName)
+	return nil
+}
+
+// removeSummary removes the addon health summary written by a previous aggregated run from the managed cluster
+// lease on the hub. The lease is checked only once after the agent starts.
+func (c *managedClusterAddOnLeaseController) removeSummary(ctx context.Context) error {
+	if c.summaryRemoved {
+		return nil
+	}
+
+	lease, err := c.hubLeaseClient.Leases(c.clusterName).Get(ctx, managedClusterLeaseName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		c.summaryRemoved = true
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if _, ok := lease.Annotations[helpers.AddOnHealthSummaryAnnotation]; ok {
+		// remove the annotation with a json merge patch, so the renew time updated by the lease renewer is not
+		// stomped
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{helpers.AddOnHealthSummaryAnnotation: nil},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := c.hubLeaseClient.Leases(c.clusterName).Patch(ctx, managedClusterLeaseName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return err
+		}
+	}
+	c.summaryRemoved = true
+	return nil
+}
+
+// getAvailableCondition returns the available condition of an addon according to its lease
+func (c *managedClusterAddOnLeaseController) getAvailableCondition(ctx context.Context,
+	leaseNamespace string,
+	addOn *addonv1alpha1.ManagedClusterAddOn) (metav1.Condition, error) {
 	now := c.clock.Now()
 	gracePeriod := time.Duration(leaseDurationTimes*AddOnLeaseControllerLeaseDurationSeconds) * time.Second
 	// addon lease name should be same with the addon name.
@@ -150,7 +290,7 @@ func (c *managedClusterAddOnLeaseController) syncSingle(ctx context.Context,
 			Message: fmt.Sprintf("The status of %s add-on is unknown.", addOn.Name),
 		}
 	case err != nil:
-		return err
+		return condition, err
 	case err == nil:
 		if now.Before(observedLease.Spec.RenewTime.Add(gracePeriod)) {
 			// the lease is constantly updated, update its addon status to available
@@ -172,28 +312,7 @@ func (c *managedClusterAddOnLeaseController) syncSingle(ctx context.Context,
 		}
 	}
 
-	if meta.IsStatusConditionPresentAndEqual(addOn.Status.Conditions, condition.Type, condition.Status) {
-		// addon status is not changed, do nothing
-		return nil
-	}
-
-	_, updated, err := helpers.UpdateManagedClusterAddOnStatus(
-		ctx,
-		c.addOnClient,
-		c.clusterName,
-		addOn.Name,
-		helpers.UpdateManagedClusterAddOnStatusFn(condition),
-	)
-	if err != nil {
-		return err
-	}
-	if updated {
-		recorder.Eventf("ManagedClusterAddOnStatusUpdated",
-			"update managed cluster addon %q available condition to %q with its lease %q/%q status",
-			addOn.Name, condition.Status, leaseNamespace, addOn.Name)
-	}
-
-	return nil
+	return condition, nil
 }
 
 func (c *managedClusterAddOnLeaseController) queueKeyFunc(lease runtime.Object) string {
@@ -2,14 +2,20 @@ package addon
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
 	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
 	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
 	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+	"open-cluster-management.io/registration/pkg/spoke/lease"
 
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	coordv1 "k8s.io/api/coordination/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -343,3 +349,165 @@ func TestSync(t *testing.T) {
 		})
 	}
 }
+
+func TestSyncAggregated(t *testing.T) {
+	newAddOn := func(name string) *addonv1alpha1.ManagedClusterAddOn {
+		return &addonv1alpha1.ManagedClusterAddOn{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testinghelpers.TestManagedClusterName,
+				Name:      name,
+			},
+			Spec: addonv1alpha1.ManagedClusterAddOnSpec{
+				InstallNamespace: "test",
+			},
+		}
+	}
+
+	newClusterLease := func(summary string) *coordv1.Lease {
+		lease := testinghelpers.NewManagedClusterLease(managedClusterLeaseName, now)
+		if len(summary) > 0 {
+			lease.Annotations = map[string]string{helpers.AddOnHealthSummaryAnnotation: summary}
+		}
+		return lease
+	}
+
+	cases := []struct {
+		name            string
+		aggregated      bool
+		renewLease      bool
+		addOns          []runtime.Object
+		leases          []runtime.Object
+		hubLeases       []runtime.Object
+		validateActions func(t *testing.T, addOnActions, hubActions []clienttesting.Action)
+	}{
+		{
+			name:       "summarize addon health",
+			aggregated: true,
+			addOns:     []runtime.Object{newAddOn("addon1"), newAddOn("addon2")},
+			leases:     []runtime.Object{testinghelpers.NewAddOnLease("test", "addon1", now)},
+			hubLeases:  []runtime.Object{newClusterLease("")},
+			validateActions: func(t *testing.T, addOnActions, hubActions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, addOnActions)
+				testinghelpers.AssertActions(t, hubActions, "get", "get", "patch")
+				patch := &coordv1.Lease{}
+				if err := json.Unmarshal(hubActions[2].(clienttesting.PatchActionImpl).Patch, patch); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				summary := map[string]metav1.Condition{}
+				if err := json.Unmarshal([]byte(patch.Annotations[helpers.AddOnHealthSummaryAnnotation]), &summary); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(summary) != 2 {
+					t.Fatalf("expected 2 addons in summary, but got %v", summary)
+				}
+				if summary["addon1"].Status != metav1.ConditionTrue {
+					t.Errorf("expected addon1 is available, but got %v", summary["addon1"])
+				}
+			},
+		},
+		{
+			name:       "summarize addon health with the renewal of the cluster lease",
+			aggregated: true,
+			renewLease: true,
+			addOns:     []runtime.Object{newAddOn("addon1")},
+			leases:     []runtime.Object{testinghelpers.NewAddOnLease("test", "addon1", now)},
+			hubLeases:  []runtime.Object{newClusterLease("")},
+			validateActions: func(t *testing.T, addOnActions, hubActions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, addOnActions)
+				// the heartbeats of the cluster and its addons are written in one batch
+				testinghelpers.AssertActions(t, hubActions, "get", "patch")
+				patch := &coordv1.Lease{}
+				if err := json.Unmarshal(hubActions[1].(clienttesting.PatchActionImpl).Patch, patch); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(patch.Annotations[helpers.AddOnHealthSummaryAnnotation]) == 0 || patch.Spec.RenewTime == nil {
+					t.Errorf("expected the cluster lease to be renewed with the summary, but got %v", patch)
+				}
+			},
+		},
+		{
+			name:       "addon health is not changed",
+			aggregated: true,
+			addOns:     []runtime.Object{newAddOn("addon1")},
+			leases:     []runtime.Object{testinghelpers.NewAddOnLease("test", "addon1", now)},
+			hubLeases: []runtime.Object{newClusterLease(
+				`{"addon1":{"type":"Available","status":"True","lastTransitionTime":null,"reason":"ManagedClusterAddOnLeaseUpdated","message":"addon1 add-on is available."}}`)},
+			validateActions: func(t *testing.T, addOnActions, hubActions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, addOnActions)
+				testinghelpers.AssertActions(t, hubActions, "get")
+			},
+		},
+		{
+			name:       "cluster lease is not created",
+			aggregated: true,
+			addOns:     []runtime.Object{newAddOn("addon1")},
+			leases:     []runtime.Object{testinghelpers.NewAddOnLease("test", "addon1", now)},
+			validateActions: func(t *testing.T, addOnActions, hubActions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, addOnActions)
+				testinghelpers.AssertActions(t, hubActions, "get")
+			},
+		},
+		{
+			name:      "remove summary when the heartbeat is not aggregated",
+			addOns:    []runtime.Object{newAddOn("addon1")},
+			hubLeases: []runtime.Object{newClusterLease(`{}`)},
+			validateActions: func(t *testing.T, addOnActions, hubActions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, hubActions, "get", "patch")
+				patch := string(hubActions[1].(clienttesting.PatchActionImpl).Patch)
+				if patch != `{"metadata":{"annotations":{"addon.open-cluster-management.io/health-summary":null}}}` {
+					t.Errorf("unexpected patch %s", patch)
+				}
+			},
+		},
+		{
+			name:      "no summary when the heartbeat is not aggregated",
+			addOns:    []runtime.Object{newAddOn("addon1")},
+			hubLeases: []runtime.Object{newClusterLease("")},
+			validateActions: func(t *testing.T, addOnActions, hubActions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, hubActions, "get")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			addOnClient := addonfake.NewSimpleClientset(c.addOns...)
+			addOnInformerFactory := addoninformers.NewSharedInformerFactory(addOnClient, time.Minute*10)
+			addOnStore := addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer().GetStore()
+			for _, addOn := range c.addOns {
+				addOnStore.Add(addOn)
+			}
+
+			hubClient := kubefake.NewSimpleClientset(c.hubLeases...)
+			leaseClient := kubefake.NewSimpleClientset(c.leases...)
+
+			leaseRenewer := lease.NewRenewer(hubClient, eventstesting.NewTestingEventRecorder(t))
+			if c.renewLease {
+				leaseRenewer.Renew(testinghelpers.TestManagedClusterName, managedClusterLeaseName, time.Minute)
+			}
+
+			ctrl := &managedClusterAddOnLeaseController{
+				clusterName:    testinghelpers.TestManagedClusterName,
+				clock:          clock.NewFakeClock(time.Now()),
+				hubLeaseClient: hubClient.CoordinationV1(),
+				addOnClient:    addOnClient,
+				addOnLister:    addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				leaseClient:    leaseClient.CoordinationV1(),
+				aggregated:     c.aggregated,
+				leaseRenewer:   leaseRenewer,
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			// wait for the first pass of the lease renewer
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			leaseRenewer.Start(ctx)
+			time.Sleep(200 * time.Millisecond)
+
+			c.validateActions(t, addOnClient.Actions(), hubClient.Actions())
+		})
+	}
+}
@@ -3,6 +3,7 @@ package addon
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
@@ -13,58 +14,96 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	certificatesinformers "k8s.io/client-go/informers/certificates"
+	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
 	addoninformerv1alpha1 "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
 	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
 	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/debug"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
 )
 
 // addOnRegistrationController monitors ManagedClusterAddOns on hub and starts addOn registration
 // according to the registrationConfigs read from annotations of ManagedClusterAddOns. Echo addOn
-// may have multiple registrationConfigs. A clientcert.NewClientCertificateController will be started
-// for each of them.
+// may have multiple registrationConfigs. A controller returned by clientcert.NewController will be started
+// for each of them, except the ones using the addon token signer, for which an addon token controller
+// will be started.
 type addOnRegistrationController struct {
 	clusterName     string
 	agentName       string
 	kubeconfigData  []byte
 	spokeKubeClient kubernetes.Interface
+	hubAddOnClient  addonclient.Interface
 	hubAddOnLister  addonlisterv1alpha1.ManagedClusterAddOnLister
 	hubCSRInformer  certificatesinformers.Interface
-	hubKubeClient   kubernetes.Interface
-	recorder        events.Recorder
-
-	startRegistrationFunc func(ctx context.Context, config registrationConfig) context.CancelFunc
+	// hubConfigMapLister lists the configmaps in the managed cluster namespace on the hub, which contain
+	// the registration configuration of addons
+	hubConfigMapLister corev1listers.ConfigMapLister
+	hubKubeClient      kubernetes.Interface
+	recorder           events.Recorder
+	// signerChecker verifies the signers of the registrations before the csrs are created, all signers are
+	// accepted if it is nil
+	signerChecker clientcert.SignerChecker
+	// clientCertOptions are applied to the client certificate controllers of the registrations, e.g. the type of
+	// the private keys
+	clientCertOptions []clientcert.Option
+
+	startRegistrationFunc func(ctx context.Context, config registrationConfig) (context.CancelFunc, error)
+
+	// registrationSlots limits the registrations in flight, so that enabling many addons at once does not
+	// flood the hub with csrs. The registrations which are not allowed to start yet are retried after a
+	// jittered staggerInterval.
+	registrationSlots *registrationSlots
+	staggerInterval   time.Duration
 
 	// registrationConfigs maps the addon name to a map of registrationConfigs whose key is the hash of
 	// the registrationConfig
 	addOnRegistrationConfigs map[string]map[string]registrationConfig
 }
 
-// NewAddOnRegistrationController returns an instance of addOnRegistrationController
+// NewAddOnRegistrationController returns an instance of addOnRegistrationController. The clientCertOptions are
+// applied to the client certificate controllers of the registrations.
 func NewAddOnRegistrationController(
 	clusterName string,
 	agentName string,
 	kubeconfigData []byte,
 	kubeClient kubernetes.Interface,
 	hubCSRInformer certificatesinformers.Interface,
+	hubAddOnClient addonclient.Interface,
 	hubAddOnInformers addoninformerv1alpha1.ManagedClusterAddOnInformer,
+	hubConfigMapInformer corev1informers.ConfigMapInformer,
 	hubCSRClient kubernetes.Interface,
+	maxConcurrentRegistrations int,
+	staggerInterval time.Duration,
+	signerChecker clientcert.SignerChecker,
 	recorder events.Recorder,
+	clientCertOptions ...clientcert.Option,
 ) factory.Controller {
 	c := &addOnRegistrationController{
 		clusterName:              clusterName,
 		agentName:                agentName,
 		kubeconfigData:           kubeconfigData,
 		spokeKubeClient:          kubeClient,
+		hubAddOnClient:           hubAddOnClient,
 		hubAddOnLister:           hubAddOnInformers.Lister(),
 		hubCSRInformer:           hubCSRInformer,
+		hubConfigMapLister:       hubConfigMapInformer.Lister(),
 		hubKubeClient:            hubCSRClient,
 		recorder:                 recorder,
 		addOnRegistrationConfigs: map[string]map[string]registrationConfig{},
+		registrationSlots:        newRegistrationSlots(maxConcurrentRegistrations),
+		staggerInterval:          staggerInterval,
+		signerChecker:            signerChecker,
+		clientCertOptions:        clientCertOptions,
 	}
 
 	c.startRegistrationFunc = c.startRegistration
@@ -76,7 +115,21 @@ func NewAddOnRegistrationController(
 				return accessor.GetName()
 			},
 			hubAddOnInformers.Informer()).
-		WithSync(c.sync).
+		WithFilteredEventsInformersQueueKeyFunc(
+			func(obj runtime.Object) string {
+				accessor, _ := meta.Accessor(obj)
+				return strings.TrimSuffix(accessor.GetName(), helpers.AddOnRegistrationConfigName(""))
+			},
+			func(obj interface{}) bool {
+				accessor, err := meta.Accessor(obj)
+				if err != nil {
+					return false
+				}
+				// only enqueue the addon whose registration configuration changes
+				return strings.HasSuffix(accessor.GetName(), helpers.AddOnRegistrationConfigName(""))
+			},
+			hubConfigMapInformer.Informer()).
+		WithSync(health.WrapSync("AddOnRegistrationController", c.sync)).
 		ResyncEvery(10*time.Minute).
 		ToController("AddOnRegistrationController", recorder)
 }
@@ -109,7 +162,9 @@ func (c *addOnRegistrationController) sync(ctx context.Context, syncCtx factory.
 }
 
 func (c *addOnRegistrationController) syncAddOn(ctx context.Context, syncCtx factory.SyncContext, addOnName string) error {
-	klog.V(4).Infof("Reconciling addOn %q", addOnName)
+	logger := helpers.ControllerLogger(ctx, "AddOnRegistrationController")
+	logger.V(helpers.LogLevelDebug).Info("Reconciling addOn", helpers.LogKeyCluster, c.clusterName,
+		helpers.LogKeyResource, klog.KRef(c.clusterName, addOnName))
 
 	addOn, err := c.hubAddOnLister.ManagedClusterAddOns(c.clusterName).Get(addOnName)
 	if errors.IsNotFound(err) {
@@ -120,17 +175,33 @@ func (c *addOnRegistrationController) syncAddOn(ctx context.Context, syncCtx fac
 		return err
 	}
 
-	// addon is deleting
+	// addon is deleting, clean up the registrations and the secrets of the addon before removing the finalizer
 	if !addOn.DeletionTimestamp.IsZero() {
-		return c.cleanup(ctx, addOnName)
+		return c.cleanupDeletingAddOn(ctx, addOn)
 	}
 
-	cachedConfigs := c.addOnRegistrationConfigs[addOnName]
-	configs, err := getRegistrationConfigs(addOn)
+	addOnConfig, err := c.getAddOnRegistrationConfig(addOnName)
+	if err != nil {
+		// the addon is synced again once the configuration is updated, so it is reported instead of retried, and the
+		// registrations started keep running
+		c.recorder.Warningf("AddOnRegistrationConfigInvalid", "The registration configuration of addon %s/%s is invalid: %v",
+			c.clusterName, addOnName, err)
+		return c.updateRegistrationAppliedCondition(ctx, addOn, []error{err})
+	}
+
+	configs, err := getRegistrationConfigs(addOn, addOnConfig)
 	if err != nil {
 		return err
 	}
 
+	// make sure the credentials of the addon will be cleaned up once the addon is deleted
+	if len(configs) > 0 && !hasRegistrationCleanupFinalizer(addOn) {
+		return c.patchFinalizers(ctx, addOn,
+			append(append([]string{}, addOn.Finalizers...), helpers.AddOnRegistrationCleanupFinalizer))
+	}
+
+	cachedConfigs := c.addOnRegistrationConfigs[addOnName]
+
 	// stop registration for the stale registration configs
 	errs := []error{}
 	for hash, cachedConfig := range cachedConfigs {
@@ -147,6 +218,7 @@ func (c *addOnRegistrationController) syncAddOn(ctx context.Context, syncCtx fac
 	}
 
 	syncedConfigs := map[string]registrationConfig{}
+	deferred := false
 	for hash, config := range configs {
 		// keep the unchanged configs
 		if cachedConfig, ok := cachedConfigs[hash]; ok {
@@ -154,71 +226,255 @@ func (c *addOnRegistrationController) syncAddOn(ctx context.Context, syncCtx fac
 			continue
 		}
 
-		// start registration for the new added configs
-		config.stopFunc = c.startRegistrationFunc(ctx, config)
+		// defer the registration if too many registrations are in flight
+		if c.registrationSlots != nil && c.requiresSlot(ctx, config) && !c.registrationSlots.acquire(config.key()) {
+			deferred = true
+			continue
+		}
+
+		// start registration for the new added configs, the configs failed to start are not recorded so they
+		// are retried on the next sync
+		stopFunc, err := c.startRegistrationFunc(ctx, config)
+		if err != nil {
+			c.registrationSlots.release(config.key())
+			errs = append(errs, fmt.Errorf("failed to start the registration of addon %q with signer %q: %w",
+				addOnName, config.registration.SignerName, err))
+			continue
+		}
+		config.stopFunc = stopFunc
 		syncedConfigs[hash] = config
 	}
 
+	if deferred {
+		logger.V(helpers.LogLevelDebug).Info("Registration of addOn is deferred", helpers.LogKeyCluster, c.clusterName,
+			helpers.LogKeyResource, klog.KRef(c.clusterName, addOnName), "retryAfter", c.staggerInterval)
+		syncCtx.Queue().AddAfter(addOnName, wait.Jitter(c.staggerInterval, 0.5))
+	}
+
 	if len(syncedConfigs) == 0 {
 		delete(c.addOnRegistrationConfigs, addOnName)
+	} else {
+		c.addOnRegistrationConfigs[addOnName] = syncedConfigs
+	}
+
+	// the addon no longer has registrations, its credentials are cleaned up already
+	if len(configs) == 0 {
+		return c.removeFinalizer(ctx, addOn)
+	}
+
+	if err := c.updateRegistrationAppliedCondition(ctx, addOn, errs); err != nil {
+		errs = append(errs, err)
+	}
+	return operatorhelpers.NewMultiLineAggregate(errs)
+}
+
+// cleanupDeletingAddOn stops the registrations of the deleting addon, deletes their secrets and then removes the
+// finalizer of the addon. The secrets of the registrations which are not started, e.g. once the agent restarts,
+// are deleted too, unless the registration configuration on the hub is invalid, which must not block the deletion.
+func (c *addOnRegistrationController) cleanupDeletingAddOn(ctx context.Context, addOn *addonv1alpha1.ManagedClusterAddOn) error {
+	if err := c.cleanup(ctx, addOn.Name); err != nil {
+		return err
+	}
+
+	if addOnConfig, err := c.getAddOnRegistrationConfig(addOn.Name); err == nil {
+		configs, err := getRegistrationConfigs(addOn, addOnConfig)
+		if err != nil {
+			return err
+		}
+		for _, config := range configs {
+			if err := c.stopRegistration(ctx, config); err != nil {
+				return err
+			}
+		}
+	}
+
+	return c.removeFinalizer(ctx, addOn)
+}
+
+// updateRegistrationAppliedCondition reports whether the registrations of the addon are started in the
+// RegistrationApplied condition of the addon
+func (c *addOnRegistrationController) updateRegistrationAppliedCondition(ctx context.Context,
+	addOn *addonv1alpha1.ManagedClusterAddOn, errs []error) error {
+	condition := metav1.Condition{
+		Type:    helpers.AddOnRegistrationAppliedCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  "RegistrationApplied",
+		Message: "The registrations of the addon are started",
+	}
+	if len(errs) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "RegistrationFailed"
+		condition.Message = operatorhelpers.NewMultiLineAggregate(errs).Error()
+	}
+
+	existing := meta.FindStatusCondition(addOn.Status.Conditions, condition.Type)
+	if existing == nil && condition.Status == metav1.ConditionTrue {
+		// the condition is only reported once a registration fails
 		return nil
 	}
-	c.addOnRegistrationConfigs[addOnName] = syncedConfigs
-	return nil
+	if existing != nil && existing.Status == condition.Status && existing.Message == condition.Message {
+		return nil
+	}
+
+	_, _, err := helpers.UpdateManagedClusterAddOnStatus(ctx, c.hubAddOnClient, c.clusterName, addOn.Name,
+		helpers.UpdateManagedClusterAddOnStatusFn(condition))
+	return err
+}
+
+// removeFinalizer removes the registration cleanup finalizer from the addon
+func (c *addOnRegistrationController) removeFinalizer(ctx context.Context, addOn *addonv1alpha1.ManagedClusterAddOn) error {
+	if !hasRegistrationCleanupFinalizer(addOn) {
+		return nil
+	}
+
+	finalizers := []string{}
+	for _, finalizer := range addOn.Finalizers {
+		if finalizer != helpers.AddOnRegistrationCleanupFinalizer {
+			finalizers = append(finalizers, finalizer)
+		}
+	}
+	err := c.patchFinalizers(ctx, addOn, finalizers)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// patchFinalizers patches the finalizers of the addon with its resource version, so the finalizers added or
+// removed by the others in the meantime are not overwritten
+func (c *addOnRegistrationController) patchFinalizers(ctx context.Context, addOn *addonv1alpha1.ManagedClusterAddOn,
+	finalizers []string) error {
+	patch, err := helpers.FinalizersPatch(addOn.ResourceVersion, finalizers)
+	if err != nil {
+		return err
+	}
+	_, err = c.hubAddOnClient.AddonV1alpha1().ManagedClusterAddOns(addOn.Namespace).Patch(
+		ctx, addOn.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func hasRegistrationCleanupFinalizer(addOn *addonv1alpha1.ManagedClusterAddOn) bool {
+	for _, finalizer := range addOn.Finalizers {
+		if finalizer == helpers.AddOnRegistrationCleanupFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// getAddOnRegistrationConfig returns the registration configuration of the addon on the hub. A nil value is
+// returned if the addon has no registration configuration.
+func (c *addOnRegistrationController) getAddOnRegistrationConfig(addOnName string) (*helpers.AddOnRegistrationConfig, error) {
+	configMap, err := c.hubConfigMapLister.ConfigMaps(c.clusterName).Get(helpers.AddOnRegistrationConfigName(addOnName))
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return helpers.ParseAddOnRegistrationConfig(configMap)
 }
 
-// startRegistration starts a client certificate controller with the given config
-func (c *addOnRegistrationController) startRegistration(ctx context.Context, config registrationConfig) context.CancelFunc {
+// startRegistration starts a client certificate controller with the given config. If the config uses the
+// addon token signer, an addon token controller is started instead. An error is returned if the controller
+// cannot be built, e.g. the renewal of the registration is invalid.
+func (c *addOnRegistrationController) startRegistration(ctx context.Context, config registrationConfig) (context.CancelFunc, error) {
 	ctx, stopFunc := context.WithCancel(ctx)
 	kubeInformerFactory := informers.NewSharedInformerFactoryWithOptions(c.spokeKubeClient, 10*time.Minute, informers.WithNamespace(config.installationNamespace))
 
+	// the labels are used to find the secrets of the addons which no longer exist
+	secretLabels := map[string]string{
+		clientcert.ClusterNameLabel: c.clusterName,
+		clientcert.AddonNameLabel:   config.addOnName,
+	}
+
+	if config.registration.SignerName == helpers.AddOnTokenSignerName {
+		controllerName := fmt.Sprintf("TokenController@addon:%s", config.addOnName)
+		tokenController, err := NewAddOnTokenController(
+			c.clusterName,
+			helpers.AddOnServiceAccountName(config.addOnName),
+			config.installationNamespace,
+			config.secretName,
+			secretLabels,
+			c.kubeconfigData,
+			kubeInformerFactory.Core().V1().Secrets(),
+			c.spokeKubeClient.CoreV1(),
+			c.hubKubeClient.CoreV1(),
+			c.recorder,
+			controllerName,
+		)
+		if err != nil {
+			stopFunc()
+			return nil, err
+		}
+
+		go kubeInformerFactory.Start(ctx.Done())
+		go health.RunController(ctx, tokenController, 1)
+
+		return stopFunc, nil
+	}
+
 	additonalSecretData := map[string][]byte{}
 	if config.registration.SignerName == certificatesv1.KubeAPIServerClientSignerName {
 		additonalSecretData[clientcert.KubeconfigFile] = c.kubeconfigData
 	}
 
-	// build and start a client cert controller
-	clientCertOption := clientcert.ClientCertOption{
-		SecretNamespace:               config.installationNamespace,
-		SecretName:                    config.secretName,
-		AdditionalSecretData:          additonalSecretData,
-		AdditionalSecretDataSensitive: true,
-	}
-
-	csrOption := clientcert.CSROption{
-		ObjectMeta: metav1.ObjectMeta{
+	// build and start a client cert controller, the state of the certificate is reported in the conditions of the
+	// addon, e.g. the signer of the registration is not allowed
+	controllerName := fmt.Sprintf("ClientCertController@addon:%s:signer:%s", config.addOnName, config.registration.SignerName)
+	opts := []clientcert.Option{
+		clientcert.WithSigner(config.registration.SignerName),
+		clientcert.WithSubject(config.x509Subject(c.clusterName, c.agentName)),
+		clientcert.WithDNSNames(fmt.Sprintf("%s.addon.open-cluster-management.io", config.addOnName)),
+		clientcert.WithCSRObjectMeta(metav1.ObjectMeta{
 			GenerateName: fmt.Sprintf("addon-%s-%s-", c.clusterName, config.addOnName),
 			Labels: map[string]string{
 				// the labels are only hints. Anyone could set/modify them.
 				clientcert.ClusterNameLabel: c.clusterName,
 				clientcert.AddonNameLabel:   config.addOnName,
 			},
-		},
-		Subject:         config.x509Subject(c.clusterName, c.agentName),
-		DNSNames:        []string{fmt.Sprintf("%s.addon.open-cluster-management.io", config.addOnName)},
-		SignerName:      config.registration.SignerName,
-		EventFilterFunc: createCSREventFilterFunc(c.clusterName, config.addOnName, config.registration.SignerName),
+		}, createCSREventFilterFunc(c.clusterName, config.addOnName, config.registration.SignerName)),
+		clientcert.WithSecretData(additonalSecretData, true),
+		clientcert.WithSecretLabels(secretLabels),
+		clientcert.WithRenewalThreshold(config.rotationThreshold),
+		clientcert.WithStatusUpdater(func(ctx context.Context, cond metav1.Condition) error {
+			// the registration is no longer in flight once its certificate is issued or fails
+			if cond.Type == clientcert.ClientCertificateRotatedCondition {
+				c.registrationSlots.release(config.key())
+			}
+			_, _, err := helpers.UpdateManagedClusterAddOnStatus(ctx, c.hubAddOnClient, c.clusterName, config.addOnName,
+				helpers.UpdateManagedClusterAddOnStatusFn(cond))
+			return err
+		}),
 	}
-
-	controllerName := fmt.Sprintf("ClientCertController@addon:%s:signer:%s", config.addOnName, config.registration.SignerName)
-	clientCertController, err := clientcert.NewClientCertificateController(
-		clientCertOption,
-		csrOption,
+	if c.signerChecker != nil {
+		opts = append(opts, clientcert.WithSignerChecker(c.signerChecker))
+	}
+	opts = append(opts, c.clientCertOptions...)
+	clientCertController, err := clientcert.NewController(
+		config.installationNamespace,
+		config.secretName,
 		c.hubCSRInformer,
 		kubeInformerFactory.Core().V1().Secrets(),
 		c.spokeKubeClient,
 		c.hubKubeClient,
 		c.recorder,
 		controllerName,
+		opts...,
 	)
 	if err != nil {
-		utilruntime.HandleError(err)
+		stopFunc()
+		return nil, err
 	}
 
 	go kubeInformerFactory.Start(ctx.Done())
-	go clientCertController.Run(ctx, 1)
+	go health.RunController(ctx, clientCertController, 1)
 
-	return stopFunc
+	return func() {
+		stopFunc()
+		debug.ForgetState(controllerName)
+		clientcert.ForgetMetrics(controllerName)
+	}, nil
 }
 
 // stopRegistration stops the client certificate controller for the given config
@@ -226,6 +482,7 @@ func (c *addOnRegistrationController) stopRegistration(ctx context.Context, conf
 	if config.stopFunc != nil {
 		config.stopFunc()
 	}
+	c.registrationSlots.release(config.key())
 
 	// delete the secret generated
 	err := c.spokeKubeClient.CoreV1().Secrets(config.installationNamespace).Delete(ctx, config.secretName, metav1.DeleteOptions{})
@@ -253,6 +510,21 @@ func (c *addOnRegistrationController) cleanup(ctx context.Context, addOnName str
 	return nil
 }
 
+// requiresSlot returns true if the registration requests a client certificate which is not issued yet. The
+// registrations served with tokens and the ones whose secrets hold a certificate, e.g. once the agent restarts,
+// do not wait for the csrs to be approved, so they are not limited.
+func (c *addOnRegistrationController) requiresSlot(ctx context.Context, config registrationConfig) bool {
+	if config.registration.SignerName == helpers.AddOnTokenSignerName {
+		return false
+	}
+	secret, err := c.spokeKubeClient.CoreV1().Secrets(config.installationNamespace).Get(ctx, config.secretName,
+		metav1.GetOptions{})
+	if err != nil {
+		return true
+	}
+	return len(secret.Data[clientcert.TLSCertFile]) == 0
+}
+
 func createCSREventFilterFunc(clusterName, addOnName, signerName string) factory.EventFilterFunc {
 	return func(obj interface{}) bool {
 		accessor, err := meta.Accessor(obj)
@@ -10,8 +10,13 @@ import (
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	certificates "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
 
@@ -20,6 +25,7 @@ import (
 	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
 	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
 	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 )
 
@@ -95,8 +101,13 @@ func TestRegistrationSync(t *testing.T) {
 		queueKey                             string
 		addOn                                *addonv1alpha1.ManagedClusterAddOn
 		addOnRegistrationConfigs             map[string]map[string]registrationConfig
+		configMap                            *corev1.ConfigMap
+		throttled                            bool
+		startErr                             error
+		expectedErr                          bool
 		expectedAddOnRegistrationConfigHashs map[string][]string
 		validateActions                      func(t *testing.T, actions []clienttesting.Action)
+		validateAddOnActions                 func(t *testing.T, actions []clienttesting.Action)
 	}{
 		{
 			name:     "addon registration not enabled",
@@ -121,6 +132,153 @@ func TestRegistrationSync(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:     "addon registration configured on hub",
+			queueKey: addonName,
+			addOn:    newManagedClusterAddOn(clusterName, addonName, []addonv1alpha1.RegistrationConfig{config1}),
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: clusterName,
+					Name:      helpers.AddOnRegistrationConfigName(addonName),
+				},
+				Data: map[string]string{
+					helpers.AddOnRegistrationConfigUser:              "user1",
+					helpers.AddOnRegistrationConfigGroups:            "group1",
+					helpers.AddOnRegistrationConfigSecretName:        "secret1",
+					helpers.AddOnRegistrationConfigRotationThreshold: "0.5",
+				},
+			},
+			expectedAddOnRegistrationConfigHashs: map[string][]string{
+				addonName: {hashWithOverrides(addonv1alpha1.RegistrationConfig{
+					SignerName: certificates.KubeAPIServerClientSignerName,
+					Subject: addonv1alpha1.Subject{
+						User:   "user1",
+						Groups: []string{"group1"},
+					},
+				}, "secret1/0.5")},
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "add finalizer to addon with registrations",
+			queueKey: addonName,
+			addOn: func() *addonv1alpha1.ManagedClusterAddOn {
+				addOn := newManagedClusterAddOn(clusterName, addonName, []addonv1alpha1.RegistrationConfig{config1})
+				addOn.Finalizers = nil
+				return addOn
+			}(),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+			validateAddOnActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				assertFinalizersPatch(t, actions[0], `["`+helpers.AddOnRegistrationCleanupFinalizer+`"]`)
+			},
+		},
+		{
+			name:     "addon is deleting",
+			queueKey: addonName,
+			addOn: func() *addonv1alpha1.ManagedClusterAddOn {
+				addOn := newManagedClusterAddOn(clusterName, addonName, []addonv1alpha1.RegistrationConfig{config1})
+				now := metav1.Now()
+				addOn.DeletionTimestamp = &now
+				return addOn
+			}(),
+			addOnRegistrationConfigs: map[string]map[string]registrationConfig{
+				addonName: {
+					hash(config1): {
+						secretName:            "secret1",
+						installationNamespace: addonName,
+					},
+				},
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete", "delete")
+			},
+			validateAddOnActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				assertFinalizersPatch(t, actions[0], `[]`)
+			},
+		},
+		{
+			name:     "addon is deleting with an invalid registration configuration on hub",
+			queueKey: addonName,
+			addOn: func() *addonv1alpha1.ManagedClusterAddOn {
+				addOn := newManagedClusterAddOn(clusterName, addonName, []addonv1alpha1.RegistrationConfig{config1})
+				now := metav1.Now()
+				addOn.DeletionTimestamp = &now
+				return addOn
+			}(),
+			configMap: newInvalidRegistrationConfigMap(clusterName, addonName),
+			addOnRegistrationConfigs: map[string]map[string]registrationConfig{
+				addonName: {
+					hash(config1): {
+						secretName:            "secret1",
+						installationNamespace: addonName,
+					},
+				},
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete")
+			},
+			validateAddOnActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				assertFinalizersPatch(t, actions[0], `[]`)
+			},
+		},
+		{
+			name:      "invalid registration configuration on hub",
+			queueKey:  addonName,
+			addOn:     newManagedClusterAddOn(clusterName, addonName, []addonv1alpha1.RegistrationConfig{config1}),
+			configMap: newInvalidRegistrationConfigMap(clusterName, addonName),
+			addOnRegistrationConfigs: map[string]map[string]registrationConfig{
+				addonName: {
+					hash(config1): {
+						secretName:            "secret1",
+						installationNamespace: addonName,
+					},
+				},
+			},
+			expectedAddOnRegistrationConfigHashs: map[string][]string{
+				addonName: {hash(config1)},
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+			validateAddOnActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				addOn := actions[1].(clienttesting.UpdateActionImpl).Object.(*addonv1alpha1.ManagedClusterAddOn)
+				if !meta.IsStatusConditionFalse(addOn.Status.Conditions, helpers.AddOnRegistrationAppliedCondition) {
+					t.Errorf("expected the registration applied condition false, but got %v", addOn.Status.Conditions)
+				}
+			},
+		},
+		{
+			name:      "addon registration throttled",
+			queueKey:  addonName,
+			addOn:     newManagedClusterAddOn(clusterName, addonName, []addonv1alpha1.RegistrationConfig{config1}),
+			throttled: true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				// the secret is checked for an issued certificate
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name:        "addon registration failed to start",
+			queueKey:    addonName,
+			addOn:       newManagedClusterAddOn(clusterName, addonName, []addonv1alpha1.RegistrationConfig{config1}),
+			startErr:    fmt.Errorf("invalid renewal"),
+			expectedErr: true,
+			validateAddOnActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				addOn := actions[1].(clienttesting.UpdateActionImpl).Object.(*addonv1alpha1.ManagedClusterAddOn)
+				if !meta.IsStatusConditionFalse(addOn.Status.Conditions, helpers.AddOnRegistrationAppliedCondition) {
+					t.Errorf("expected the registration applied condition false, but got %v", addOn.Status.Conditions)
+				}
+			},
+		},
 		{
 			name:     "addon registration updated",
 			queueKey: addonName,
@@ -205,24 +363,43 @@ func TestRegistrationSync(t *testing.T) {
 				addonStore.Add(c.addOn)
 			}
 
+			hubKubeInformerFactory := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), time.Minute*10)
+			if c.configMap != nil {
+				hubKubeInformerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(c.configMap)
+			}
+
 			if c.addOnRegistrationConfigs == nil {
 				c.addOnRegistrationConfigs = map[string]map[string]registrationConfig{}
 			}
 
 			controller := addOnRegistrationController{
-				clusterName:     clusterName,
-				spokeKubeClient: kubeClient,
-				hubAddOnLister:  addonInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
-				recorder:        eventstesting.NewTestingEventRecorder(t),
-				startRegistrationFunc: func(ctx context.Context, config registrationConfig) context.CancelFunc {
+				clusterName:        clusterName,
+				spokeKubeClient:    kubeClient,
+				hubAddOnClient:     addonClient,
+				hubAddOnLister:     addonInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				hubConfigMapLister: hubKubeInformerFactory.Core().V1().ConfigMaps().Lister(),
+				recorder:           eventstesting.NewTestingEventRecorder(t),
+				startRegistrationFunc: func(ctx context.Context, config registrationConfig) (context.CancelFunc, error) {
+					if c.startErr != nil {
+						return nil, c.startErr
+					}
 					_, cancel := context.WithCancel(context.Background())
-					return cancel
+					return cancel, nil
 				},
 				addOnRegistrationConfigs: c.addOnRegistrationConfigs,
+				staggerInterval:          time.Second,
+			}
+			if c.throttled {
+				// all slots are taken by the registrations of another addon
+				controller.registrationSlots = newRegistrationSlots(1)
+				controller.registrationSlots.acquire("addon2/hash")
 			}
 
 			err := controller.sync(context.Background(), testinghelpers.NewFakeSyncContext(t, c.queueKey))
-			if err != nil {
+			if c.expectedErr && err == nil {
+				t.Errorf("expected an error, but got none")
+			}
+			if !c.expectedErr && err != nil {
 				t.Errorf("unexpected error: %v", err)
 			}
 
@@ -245,15 +422,24 @@ func TestRegistrationSync(t *testing.T) {
 			if c.validateActions != nil {
 				c.validateActions(t, kubeClient.Actions())
 			}
+
+			if c.validateAddOnActions != nil {
+				c.validateAddOnActions(t, addonClient.Actions())
+			}
 		})
 	}
 }
 
 func newManagedClusterAddOn(namespace, name string, registrations []addonv1alpha1.RegistrationConfig) *addonv1alpha1.ManagedClusterAddOn {
+	var finalizers []string
+	if len(registrations) > 0 {
+		finalizers = []string{helpers.AddOnRegistrationCleanupFinalizer}
+	}
 	return &addonv1alpha1.ManagedClusterAddOn{
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: namespace,
-			Name:      name,
+			Namespace:  namespace,
+			Name:       name,
+			Finalizers: finalizers,
 		},
 		Status: addonv1alpha1.ManagedClusterAddOnStatus{
 			Registrations: registrations,
@@ -261,9 +447,212 @@ func newManagedClusterAddOn(namespace, name string, registrations []addonv1alpha
 	}
 }
 
+func newInvalidRegistrationConfigMap(namespace, addOnName string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      helpers.AddOnRegistrationConfigName(addOnName),
+		},
+		Data: map[string]string{
+			helpers.AddOnRegistrationConfigRotationThreshold: "1.5",
+		},
+	}
+}
+
 func hash(registration addonv1alpha1.RegistrationConfig) string {
 	data, _ := json.Marshal(registration)
 	h := sha256.New()
 	h.Write(data)
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
+
+func hashWithOverrides(registration addonv1alpha1.RegistrationConfig, overrides string) string {
+	data, _ := json.Marshal(registration)
+	h := sha256.New()
+	h.Write(data)
+	h.Write([]byte(overrides))
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func assertFinalizersPatch(t *testing.T, action clienttesting.Action, expectedFinalizers string) {
+	patch := map[string]map[string]interface{}{}
+	if err := json.Unmarshal(action.(clienttesting.PatchActionImpl).Patch, &patch); err != nil {
+		t.Fatal(err)
+	}
+	finalizers, _ := json.Marshal(patch["metadata"]["finalizers"])
+	if string(finalizers) != expectedFinalizers {
+		t.Errorf("expected finalizers %s, but got %s", expectedFinalizers, finalizers)
+	}
+}
+
+func TestStartRegistration(t *testing.T) {
+	cases := []struct {
+		name              string
+		signerName        string
+		rotationThreshold float64
+		expectedErr       bool
+		expectedCSR       bool
+	}{
+		{
+			name:              "high rotation threshold with the renewal jitter",
+			signerName:        certificates.KubeAPIServerClientSignerName,
+			rotationThreshold: 0.85,
+			expectedCSR:       true,
+		},
+		{
+			name:        "invalid registration",
+			expectedErr: true,
+		},
+		{
+			name:              "renewal beyond the lifetime of the certificate",
+			signerName:        certificates.KubeAPIServerClientSignerName,
+			rotationThreshold: 0.95,
+			expectedErr:       true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			hubKubeClient := kubefake.NewSimpleClientset()
+			hubKubeInformerFactory := informers.NewSharedInformerFactory(hubKubeClient, time.Minute*10)
+			controller := addOnRegistrationController{
+				clusterName:       testinghelpers.TestManagedClusterName,
+				agentName:         "agent1",
+				spokeKubeClient:   kubeClient,
+				hubAddOnClient:    addonfake.NewSimpleClientset(),
+				hubCSRInformer:    hubKubeInformerFactory.Certificates(),
+				hubKubeClient:     hubKubeClient,
+				recorder:          eventstesting.NewTestingEventRecorder(t),
+				clientCertOptions: []clientcert.Option{clientcert.WithRenewalJitter(0.1)},
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go hubKubeInformerFactory.Start(ctx.Done())
+
+			stopFunc, err := controller.startRegistration(ctx, registrationConfig{
+				addOnName:             "addon1",
+				installationNamespace: defaultAddOnInstallationNamespace,
+				registration:          addonv1alpha1.RegistrationConfig{SignerName: c.signerName},
+				secretName:            "addon1-hub-kubeconfig",
+				rotationThreshold:     c.rotationThreshold,
+			})
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected an error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer stopFunc()
+
+			err = wait.PollImmediate(100*time.Millisecond, 3*time.Second, func() (bool, error) {
+				for _, action := range hubKubeClient.Actions() {
+					if action.GetVerb() == "create" && action.GetResource().Resource == "certificatesigningrequests" {
+						return true, nil
+					}
+				}
+				return false, nil
+			})
+			if c.expectedCSR && err != nil {
+				t.Errorf("expected a csr created, but got none")
+			}
+			if !c.expectedCSR && err == nil {
+				t.Errorf("expected no csr created, but got one")
+			}
+		})
+	}
+}
+
+func TestRegistrationSlots(t *testing.T) {
+	slots := newRegistrationSlots(2)
+	if !slots.acquire("addon1/a") || !slots.acquire("addon2/b") {
+		t.Fatalf("expected the slots acquired")
+	}
+	if !slots.acquire("addon1/a") {
+		t.Errorf("expected the registration in flight keeps its slot")
+	}
+	if slots.acquire("addon3/c") {
+		t.Errorf("expected no slot once all slots are taken")
+	}
+	slots.release("addon1/a")
+	if !slots.acquire("addon3/c") {
+		t.Errorf("expected the released slot acquired")
+	}
+
+	// the csr of addon2 is denied, so its slot is given back once it times out
+	fakeClock := clock.NewFakeClock(time.Now())
+	slots.clock = fakeClock
+	slots.inFlight["addon2/b"] = fakeClock.Now()
+	slots.inFlight["addon3/c"] = fakeClock.Now().Add(time.Minute)
+	if slots.acquire("addon4/d") {
+		t.Errorf("expected no slot before the denied registration times out")
+	}
+	fakeClock.Step(registrationSlotTimeout)
+	if !slots.acquire("addon4/d") {
+		t.Errorf("expected the slot of the denied registration acquired once it times out")
+	}
+	if _, ok := slots.inFlight["addon3/c"]; !ok {
+		t.Errorf("expected the registration which does not time out keeps its slot")
+	}
+
+	// the registrations are not limited without slots
+	var unlimited *registrationSlots
+	if !unlimited.acquire("addon1/a") {
+		t.Errorf("expected no limit without slots")
+	}
+	unlimited.release("addon1/a")
+}
+
+func TestRequiresSlot(t *testing.T) {
+	cases := []struct {
+		name     string
+		config   registrationConfig
+		secret   *corev1.Secret
+		expected bool
+	}{
+		{
+			name: "token registration",
+			config: registrationConfig{
+				registration: addonv1alpha1.RegistrationConfig{SignerName: helpers.AddOnTokenSignerName},
+			},
+		},
+		{
+			name: "no secret",
+			config: registrationConfig{
+				installationNamespace: defaultAddOnInstallationNamespace,
+				secretName:            "addon1-hub-kubeconfig",
+				registration:          addonv1alpha1.RegistrationConfig{SignerName: certificates.KubeAPIServerClientSignerName},
+			},
+			expected: true,
+		},
+		{
+			name: "certificate issued",
+			config: registrationConfig{
+				installationNamespace: defaultAddOnInstallationNamespace,
+				secretName:            "addon1-hub-kubeconfig",
+				registration:          addonv1alpha1.RegistrationConfig{SignerName: certificates.KubeAPIServerClientSignerName},
+			},
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: defaultAddOnInstallationNamespace, Name: "addon1-hub-kubeconfig"},
+				Data:       map[string][]byte{clientcert.TLSCertFile: []byte("cert")},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if c.secret != nil {
+				objects = append(objects, c.secret)
+			}
+			controller := addOnRegistrationController{spokeKubeClient: kubefake.NewSimpleClientset(objects...)}
+			if actual := controller.requiresSlot(context.Background(), c.config); actual != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package addon
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+// registrationSlotTimeout is how long a registration holds its slot at most. The csr of a registration may be
+// denied or never approved, e.g. its ClusterManagementAddOn does not allow the csrs to be approved automatically,
+// and the registration keeps running without any certificate issued, so its slot is given to the others once it
+// times out.
+const registrationSlotTimeout = 10 * time.Minute
+
+// registrationSlots limits the addon registrations in flight, i.e. the registrations which are started but whose
+// client certificates are not issued yet. A registration takes a slot before it starts, and gives it back once its
+// certificate is issued or fails, once it is stopped, or once the slot times out, so the csrs waiting for the
+// approval on the hub are bounded however slowly they are approved.
+type registrationSlots struct {
+	lock    sync.Mutex
+	max     int
+	timeout time.Duration
+	clock   clock.Clock
+	// inFlight maps the keys of the registrations in flight to the time they took their slots
+	inFlight map[string]time.Time
+}
+
+// newRegistrationSlots returns the slots of at most max registrations in flight. A nil value is returned if max
+// is not positive, which means the registrations are not limited.
+func newRegistrationSlots(max int) *registrationSlots {
+	if max <= 0 {
+		return nil
+	}
+	return &registrationSlots{
+		max:      max,
+		timeout:  registrationSlotTimeout,
+		clock:    clock.RealClock{},
+		inFlight: map[string]time.Time{},
+	}
+}
+
+// acquire takes a slot for the registration with the key, and returns false if all slots are taken. A
+// registration holding a slot already keeps it.
+func (s *registrationSlots) acquire(key string) bool {
+	if s == nil {
+		return true
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.inFlight[key]; ok {
+		return true
+	}
+
+	// give back the slots of the registrations which are in flight for too long
+	now := s.clock.Now()
+	for inFlightKey, acquired := range s.inFlight {
+		if now.Sub(acquired) >= s.timeout {
+			delete(s.inFlight, inFlightKey)
+		}
+	}
+
+	if len(s.inFlight) >= s.max {
+		return false
+	}
+	s.inFlight[key] = now
+	return true
+}
+
+// release gives back the slot of the registration with the key if it holds one
+func (s *registrationSlots) release(key string) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.inFlight, key)
+}
@@ -0,0 +1,91 @@
+package addon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/kubernetes"
+	addoninformerv1alpha1 "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/health"
+)
+
+// AddOnSecretJanitorSyncInterval is exposed so that integration tests can crank up the controller sync speed.
+var AddOnSecretJanitorSyncInterval = 10 * time.Minute
+
+// addOnSecretJanitorController periodically deletes the addon credential secrets managed by the registration
+// agent whose ManagedClusterAddOns no longer exist on the hub. The secrets may be left behind if the deletion
+// of the addons is missed, e.g. when the agent is down.
+type addOnSecretJanitorController struct {
+	clusterName     string
+	spokeKubeClient kubernetes.Interface
+	hubAddOnLister  addonlisterv1alpha1.ManagedClusterAddOnLister
+}
+
+// NewAddOnSecretJanitorController returns an instance of addOnSecretJanitorController
+func NewAddOnSecretJanitorController(
+	clusterName string,
+	spokeKubeClient kubernetes.Interface,
+	hubAddOnInformers addoninformerv1alpha1.ManagedClusterAddOnInformer,
+	recorder events.Recorder,
+) factory.Controller {
+	c := &addOnSecretJanitorController{
+		clusterName:     clusterName,
+		spokeKubeClient: spokeKubeClient,
+		hubAddOnLister:  hubAddOnInformers.Lister(),
+	}
+
+	return factory.New().
+		WithBareInformers(hubAddOnInformers.Informer()).
+		WithSync(health.WrapSync("AddOnSecretJanitorController", c.sync)).
+		ResyncEvery(AddOnSecretJanitorSyncInterval).
+		ToController("AddOnSecretJanitorController", recorder)
+}
+
+func (c *addOnSecretJanitorController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	selector := labels.SelectorFromSet(labels.Set{clientcert.ClusterNameLabel: c.clusterName})
+	requirement, err := labels.NewRequirement(clientcert.AddonNameLabel, selection.Exists, nil)
+	if err != nil {
+		return err
+	}
+	selector = selector.Add(*requirement)
+
+	secrets, err := c.spokeKubeClient.CoreV1().Secrets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, secret := range secrets.Items {
+		addOnName := secret.Labels[clientcert.AddonNameLabel]
+		_, err := c.hubAddOnLister.ManagedClusterAddOns(c.clusterName).Get(addOnName)
+		switch {
+		case err == nil:
+			continue
+		case !errors.IsNotFound(err):
+			errs = append(errs, err)
+			continue
+		}
+
+		err = c.spokeKubeClient.CoreV1().Secrets(secret.Namespace).Delete(ctx, secret.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("unable to delete secret %q: %w", secret.Namespace+"/"+secret.Name, err))
+			continue
+		}
+		syncCtx.Recorder().Eventf("OrphanedAddOnSecretDeleted", "secret %q of the deleted addon %q is deleted",
+			secret.Namespace+"/"+secret.Name, addOnName)
+	}
+
+	return operatorhelpers.NewMultiLineAggregate(errs)
+}
@@ -0,0 +1,95 @@
+package addon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestAddOnSecretJanitorSync(t *testing.T) {
+	clusterName := testinghelpers.TestManagedClusterName
+	newSecret := func(name, addOnName string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "addon-ns",
+				Name:      name,
+				Labels: map[string]string{
+					clientcert.ClusterNameLabel: clusterName,
+					clientcert.AddonNameLabel:   addOnName,
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		name            string
+		addOns          []runtime.Object
+		secrets         []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:    "no addon secrets",
+			addOns:  []runtime.Object{},
+			secrets: []runtime.Object{},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+		},
+		{
+			name:    "addon exists",
+			addOns:  []runtime.Object{newManagedClusterAddOn(clusterName, "addon1", nil)},
+			secrets: []runtime.Object{newSecret("addon1-hub-kubeconfig", "addon1")},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+		},
+		{
+			name:    "addon is deleted",
+			addOns:  []runtime.Object{newManagedClusterAddOn(clusterName, "addon1", nil)},
+			secrets: []runtime.Object{newSecret("addon1-hub-kubeconfig", "addon1"), newSecret("addon2-hub-kubeconfig", "addon2")},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list", "delete")
+				name := actions[1].(clienttesting.DeleteActionImpl).Name
+				if name != "addon2-hub-kubeconfig" {
+					t.Errorf("expected secret addon2-hub-kubeconfig is deleted, but got %q", name)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			addOnClient := addonfake.NewSimpleClientset(c.addOns...)
+			addOnInformerFactory := addoninformers.NewSharedInformerFactory(addOnClient, time.Minute*10)
+			addOnStore := addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer().GetStore()
+			for _, addOn := range c.addOns {
+				addOnStore.Add(addOn)
+			}
+
+			kubeClient := kubefake.NewSimpleClientset(c.secrets...)
+
+			ctrl := &addOnSecretJanitorController{
+				clusterName:     clusterName,
+				spokeKubeClient: kubeClient,
+				hubAddOnLister:  addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+			}
+			err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "key"))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			c.validateActions(t, kubeClient.Actions())
+		})
+	}
+}
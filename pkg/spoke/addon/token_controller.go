@@ -0,0 +1,219 @@
+package addon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const (
+	// TokenFile is the name of the file in the secret containing the bound service account token
+	TokenFile = "token"
+
+	// tokenExpirationAnnotation records the expiration time of the token saved in the secret
+	tokenExpirationAnnotation = "addon.open-cluster-management.io/token-expiration"
+	// tokenIssuedAnnotation records the time the token saved in the secret was issued at
+	tokenIssuedAnnotation = "addon.open-cluster-management.io/token-issued"
+
+	// tokenRefreshRatio is the ratio of the lifetime of a token remaining at which the token is requested again
+	tokenRefreshRatio = 0.2
+)
+
+// AddOnTokenExpirationSeconds is the requested lifetime of the addon tokens. It is exposed so that integration
+// tests can shorten it.
+var AddOnTokenExpirationSeconds int64 = 24 * 60 * 60
+
+// addOnTokenController requests a bound service account token from the hub for an addon and saves it, together
+// with a kubeconfig using the token, in a secret on the managed cluster. The token is requested again once it has
+// less than 20% of its life remaining. The lifetime is the one of the issued token, which may differ from the
+// requested one because the hub apiserver is allowed to shorten or extend it.
+type addOnTokenController struct {
+	clusterName        string
+	serviceAccountName string
+	secretNamespace    string
+	secretName         string
+	secretLabels       map[string]string
+	kubeconfigData     []byte
+	spokeCoreClient    corev1client.CoreV1Interface
+	hubCoreClient      corev1client.CoreV1Interface
+	controllerName     string
+}
+
+// NewAddOnTokenController returns an instance of addOnTokenController
+func NewAddOnTokenController(
+	clusterName string,
+	serviceAccountName string,
+	secretNamespace string,
+	secretName string,
+	secretLabels map[string]string,
+	hubKubeconfigData []byte,
+	spokeSecretInformer corev1informers.SecretInformer,
+	spokeCoreClient corev1client.CoreV1Interface,
+	hubCoreClient corev1client.CoreV1Interface,
+	recorder events.Recorder,
+	controllerName string,
+) (factory.Controller, error) {
+	kubeconfigData, err := buildTokenKubeconfig(hubKubeconfigData, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &addOnTokenController{
+		clusterName:        clusterName,
+		serviceAccountName: serviceAccountName,
+		secretNamespace:    secretNamespace,
+		secretName:         secretName,
+		secretLabels:       secretLabels,
+		kubeconfigData:     kubeconfigData,
+		spokeCoreClient:    spokeCoreClient,
+		hubCoreClient:      hubCoreClient,
+		controllerName:     controllerName,
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			// only enqueue the secret of the addon token
+			return accessor.GetNamespace() == secretNamespace && accessor.GetName() == secretName
+		}, spokeSecretInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ResyncEvery(clientcert.ControllerResyncInterval).
+		ToController(controllerName, recorder), nil
+}
+
+func (c *addOnTokenController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	secret, err := c.spokeCoreClient.Secrets(c.secretNamespace).Get(ctx, c.secretName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: c.secretNamespace,
+				Name:      c.secretName,
+			},
+		}
+	case err != nil:
+		return fmt.Errorf("unable to get secret %q: %w", c.secretNamespace+"/"+c.secretName, err)
+	}
+
+	if !c.shouldRequestToken(ctx, secret, time.Now()) {
+		return nil
+	}
+
+	issued := time.Now()
+	expirationSeconds := AddOnTokenExpirationSeconds
+	tokenRequest, err := c.hubCoreClient.ServiceAccounts(c.clusterName).CreateToken(ctx, c.serviceAccountName, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			ExpirationSeconds: &expirationSeconds,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to request token for service account %q: %w", c.clusterName+"/"+c.serviceAccountName, err)
+	}
+
+	// only the fields managed by the controller are applied, the others are kept
+	appliedSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: c.secretNamespace,
+			Name:      c.secretName,
+			Labels:    c.secretLabels,
+			Annotations: map[string]string{
+				tokenExpirationAnnotation: tokenRequest.Status.ExpirationTimestamp.UTC().Format(time.RFC3339),
+				tokenIssuedAnnotation:     issued.UTC().Format(time.RFC3339),
+			},
+		},
+		Data: map[string][]byte{
+			TokenFile:                 []byte(tokenRequest.Status.Token),
+			clientcert.KubeconfigFile: c.kubeconfigData,
+		},
+	}
+	if _, err := helpers.ApplySecret(ctx, c.spokeCoreClient, secret, appliedSecret); err != nil {
+		return err
+	}
+
+	syncCtx.Recorder().Eventf("AddOnTokenCreated", "A new token for %s is available", c.controllerName)
+	return nil
+}
+
+// shouldRequestToken returns true if the secret has no token, the kubeconfig in the secret is stale, or the token
+// has less than 20% of its life remaining.
+func (c *addOnTokenController) shouldRequestToken(ctx context.Context, secret *corev1.Secret, now time.Time) bool {
+	if len(secret.Data[TokenFile]) == 0 {
+		return true
+	}
+
+	if string(secret.Data[clientcert.KubeconfigFile]) != string(c.kubeconfigData) {
+		return true
+	}
+
+	expiration, err := time.Parse(time.RFC3339, secret.Annotations[tokenExpirationAnnotation])
+	if err != nil {
+		helpers.ControllerLogger(ctx, c.controllerName).V(helpers.LogLevelDebug).Info("Unable to parse the token expiration",
+			helpers.LogKeyCluster, c.clusterName, helpers.LogKeyResource, klog.KObj(secret), helpers.LogKeyReason, err.Error())
+		return true
+	}
+	issued, err := time.Parse(time.RFC3339, secret.Annotations[tokenIssuedAnnotation])
+	if err != nil {
+		helpers.ControllerLogger(ctx, c.controllerName).V(helpers.LogLevelDebug).Info("Unable to parse the token issued time",
+			helpers.LogKeyCluster, c.clusterName, helpers.LogKeyResource, klog.KObj(secret), helpers.LogKeyReason, err.Error())
+		return true
+	}
+
+	refreshThreshold := time.Duration(float64(expiration.Sub(issued)) * tokenRefreshRatio)
+	return now.Add(refreshThreshold).After(expiration)
+}
+
+// buildTokenKubeconfig builds a kubeconfig which connects to the same hub cluster as the given kubeconfig but
+// authenticates with the token file in the same directory. The namespace of the context is the managed cluster
+// namespace, which the service account of the addon is in.
+func buildTokenKubeconfig(hubKubeconfigData []byte, clusterName string) ([]byte, error) {
+	hubKubeconfig, err := clientcmd.Load(hubKubeconfigData)
+	if err != nil {
+		return nil, err
+	}
+
+	currentContext, ok := hubKubeconfig.Contexts[hubKubeconfig.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("current context %q is not found in hub kubeconfig", hubKubeconfig.CurrentContext)
+	}
+	cluster, ok := hubKubeconfig.Clusters[currentContext.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("cluster %q is not found in hub kubeconfig", currentContext.Cluster)
+	}
+
+	kubeconfig := clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{"default-cluster": cluster},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{"default-auth": {
+			TokenFile: TokenFile,
+		}},
+		Contexts: map[string]*clientcmdapi.Context{"default-context": {
+			Cluster:   "default-cluster",
+			AuthInfo:  "default-auth",
+			Namespace: clusterName,
+		}},
+		CurrentContext: "default-context",
+	}
+
+	return clientcmd.Write(kubeconfig)
+}
@@ -0,0 +1,164 @@
+package addon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestAddOnTokenSync(t *testing.T) {
+	clusterName := testinghelpers.TestManagedClusterName
+	addOnNamespace := "addon-ns"
+	secretName := "addon1-hub-token"
+
+	kubeconfigData, err := buildTokenKubeconfig(testinghelpers.NewKubeconfig(nil, nil), clusterName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	newSecret := func(issued, expiration time.Time, kubeconfig []byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       addOnNamespace,
+				Name:            secretName,
+				ResourceVersion: "1",
+				Annotations: map[string]string{
+					tokenExpirationAnnotation: expiration.UTC().Format(time.RFC3339),
+					tokenIssuedAnnotation:     issued.UTC().Format(time.RFC3339),
+				},
+			},
+			Data: map[string][]byte{
+				TokenFile:                 []byte("token"),
+				clientcert.KubeconfigFile: kubeconfig,
+			},
+		}
+	}
+
+	cases := []struct {
+		name                 string
+		secrets              []runtime.Object
+		validateHubActions   func(t *testing.T, actions []clienttesting.Action)
+		validateSpokeActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:    "no token secret",
+			secrets: []runtime.Object{},
+			validateHubActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "create")
+				if actions[0].GetSubresource() != "token" {
+					t.Errorf("expected token is requested, but got %q", actions[0].GetSubresource())
+				}
+			},
+			validateSpokeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				secret := testinghelpers.AppliedSecret(t, actions[1])
+				if string(secret.Data[TokenFile]) != "new-token" {
+					t.Errorf("expected new token is saved, but got %q", string(secret.Data[TokenFile]))
+				}
+			},
+		},
+		{
+			name:    "valid token",
+			secrets: []runtime.Object{newSecret(time.Now(), time.Now().Add(time.Hour), kubeconfigData)},
+			validateHubActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+			validateSpokeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name:    "valid token with shortened lifetime",
+			secrets: []runtime.Object{newSecret(time.Now().Add(-50*time.Minute), time.Now().Add(15*time.Minute), kubeconfigData)},
+			validateHubActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+			validateSpokeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name:    "expiring token",
+			secrets: []runtime.Object{newSecret(time.Now().Add(-55*time.Minute), time.Now().Add(5*time.Minute), kubeconfigData)},
+			validateHubActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "create")
+			},
+			validateSpokeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+			},
+		},
+		{
+			name: "token without issued time",
+			secrets: []runtime.Object{func() *corev1.Secret {
+				secret := newSecret(time.Now(), time.Now().Add(time.Hour), kubeconfigData)
+				delete(secret.Annotations, tokenIssuedAnnotation)
+				return secret
+			}()},
+			validateHubActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "create")
+			},
+			validateSpokeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+			},
+		},
+		{
+			name:    "stale kubeconfig",
+			secrets: []runtime.Object{newSecret(time.Now(), time.Now().Add(time.Hour), []byte("stale"))},
+			validateHubActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "create")
+			},
+			validateSpokeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			spokeKubeClient := kubefake.NewSimpleClientset(c.secrets...)
+			testinghelpers.AddSecretApplyReactor(&spokeKubeClient.Fake, spokeKubeClient.Tracker())
+			hubKubeClient := kubefake.NewSimpleClientset()
+			hubKubeClient.PrependReactor(
+				"create",
+				"serviceaccounts",
+				func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &authenticationv1.TokenRequest{
+						Status: authenticationv1.TokenRequestStatus{
+							Token:               "new-token",
+							ExpirationTimestamp: metav1.NewTime(time.Now().Add(time.Hour)),
+						},
+					}, nil
+				},
+			)
+
+			ctrl := &addOnTokenController{
+				clusterName:        clusterName,
+				serviceAccountName: "addon1-addon-agent",
+				secretNamespace:    addOnNamespace,
+				secretName:         secretName,
+				kubeconfigData:     kubeconfigData,
+				spokeCoreClient:    spokeKubeClient.CoreV1(),
+				hubCoreClient:      hubKubeClient.CoreV1(),
+				controllerName:     "test",
+			}
+
+			err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "test"))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			c.validateHubActions(t, hubKubeClient.Actions())
+			c.validateSpokeActions(t, spokeKubeClient.Actions())
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package spoke
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"open-cluster-management.io/registration/pkg/sdk"
+)
+
+// serviceAccountTokenFile is the token of the service account mounted in the pod of the agent
+const serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// serviceAccountClaims are the claims of a service account token identifying the service account, the bound tokens
+// have the claims in kubernetes.io, and the legacy tokens have them in the flat claims.
+type serviceAccountClaims struct {
+	Kubernetes struct {
+		Namespace      string `json:"namespace"`
+		ServiceAccount struct {
+			UID string `json:"uid"`
+		} `json:"serviceaccount"`
+	} `json:"kubernetes.io"`
+	LegacyNamespace string `json:"kubernetes.io/serviceaccount/namespace"`
+	LegacyUID       string `json:"kubernetes.io/serviceaccount/service-account.uid"`
+}
+
+// deriveAgentName returns the agent name derived from the service account the agent runs with, see
+// sdk.DeriveAgentName. The service account is identified with the claims of its token in the file, the signature of
+// the token is not verified since it is only used to name the agent.
+func deriveAgentName(tokenFile string) (string, error) {
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the service account token %q: %w", tokenFile, err)
+	}
+	parts := strings.Split(strings.TrimSpace(string(token)), ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("the service account token %q is not a JWT", tokenFile)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("unable to decode the service account token %q: %w", tokenFile, err)
+	}
+	claims := &serviceAccountClaims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return "", fmt.Errorf("unable to decode the service account token %q: %w", tokenFile, err)
+	}
+
+	namespace, uid := claims.Kubernetes.Namespace, claims.Kubernetes.ServiceAccount.UID
+	if len(uid) == 0 {
+		namespace, uid = claims.LegacyNamespace, claims.LegacyUID
+	}
+	if len(namespace) == 0 || len(uid) == 0 {
+		return "", fmt.Errorf("no service account is found in the token %q", tokenFile)
+	}
+	return sdk.DeriveAgentName(namespace, uid), nil
+}
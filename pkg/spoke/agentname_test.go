@@ -0,0 +1,67 @@
+package spoke
+
+import (
+	"encoding/base64"
+	"path"
+	"strings"
+	"testing"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+	"open-cluster-management.io/registration/pkg/sdk"
+)
+
+func newToken(claims string) []byte {
+	return []byte("header." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature")
+}
+
+func TestDeriveAgentName(t *testing.T) {
+	uid := "0f6a2b7e-1c3d-4e5f-8a9b-0c1d2e3f4a5b"
+	cases := []struct {
+		name              string
+		token             []byte
+		expectedAgentName string
+		expectedErr       string
+	}{
+		{
+			name:              "bound token",
+			token:             newToken(`{"kubernetes.io":{"namespace":"open-cluster-management-agent","serviceaccount":{"name":"klusterlet","uid":"` + uid + `"}}}`),
+			expectedAgentName: sdk.DeriveAgentName("open-cluster-management-agent", uid),
+		},
+		{
+			name: "legacy token",
+			token: newToken(`{"kubernetes.io/serviceaccount/namespace":"open-cluster-management-agent",` +
+				`"kubernetes.io/serviceaccount/service-account.uid":"` + uid + `"}`),
+			expectedAgentName: sdk.DeriveAgentName("open-cluster-management-agent", uid),
+		},
+		{
+			name:        "not a jwt",
+			token:       []byte("token"),
+			expectedErr: "is not a JWT",
+		},
+		{
+			name:        "no service account",
+			token:       newToken(`{"sub":"system:serviceaccount:open-cluster-management-agent:klusterlet"}`),
+			expectedErr: "no service account is found in the token",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tokenFile := path.Join(t.TempDir(), "token")
+			testinghelpers.WriteFile(tokenFile, c.token)
+
+			agentName, err := deriveAgentName(tokenFile)
+			if len(c.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), c.expectedErr) {
+					t.Errorf("expected error %q, but got %v", c.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if agentName != c.expectedAgentName {
+				t.Errorf("expected agent name %q, but got %q", c.expectedAgentName, agentName)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package bootstrapcredential
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const controllerName = "BootstrapCredentialController"
+
+// PollInterval is the interval the files of the bootstrap credential are checked for changes in. The secrets-store
+// CSI driver rotates the mounted files in place, so the files are polled instead of watched.
+var PollInterval = 30 * time.Second
+
+// bootstrapCredentialController detects the changes of the files the bootstrap client config is assembled from,
+// and calls onChange once they are changed, so the agent re-assembles the config.
+type bootstrapCredentialController struct {
+	dir      string
+	digest   string
+	changed  bool
+	onChange func()
+}
+
+// NewBootstrapCredentialController returns an instance of bootstrapCredentialController. The digest is the Digest of
+// the files the current client config is assembled from.
+func NewBootstrapCredentialController(dir, digest string, onChange func(), recorder events.Recorder) factory.Controller {
+	c := &bootstrapCredentialController{
+		dir:      dir,
+		digest:   digest,
+		onChange: onChange,
+	}
+
+	return factory.New().
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ResyncEvery(PollInterval).
+		ToController(controllerName, recorder)
+}
+
+func (c *bootstrapCredentialController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	if c.changed {
+		return nil
+	}
+
+	digest, err := Digest(c.dir)
+	if err != nil {
+		return err
+	}
+	if digest == c.digest {
+		return nil
+	}
+
+	c.changed = true
+	helpers.ControllerLogger(ctx, controllerName).Info("The bootstrap credential is changed", "dir", c.dir)
+	syncCtx.Recorder().Eventf("BootstrapCredentialChanged",
+		"The bootstrap credential in %q is changed, the bootstrap kubeconfig is re-assembled", c.dir)
+	c.onChange()
+	return nil
+}
@@ -0,0 +1,58 @@
+package bootstrapcredential
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestSync(t *testing.T) {
+	cases := []struct {
+		name            string
+		changedFiles    map[string]string
+		expectedChanged bool
+	}{
+		{
+			name: "not changed",
+		},
+		{
+			name:         "token rotated",
+			changedFiles: map[string]string{TokenFile: "token2"},
+		},
+		{
+			name:            "server changed",
+			changedFiles:    map[string]string{ServerFile: "https://hub2.example.com:6443"},
+			expectedChanged: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{ServerFile: "https://hub.example.com:6443", TokenFile: "token1"})
+			digest, err := Digest(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeFiles(t, dir, c.changedFiles)
+
+			changes := 0
+			ctrl := &bootstrapCredentialController{
+				dir:      dir,
+				digest:   digest,
+				onChange: func() { changes++ },
+			}
+			// the change is only reported once
+			for i := 0; i < 2; i++ {
+				if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey)); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+			if changed := changes == 1; changed != c.expectedChanged || changes > 1 {
+				t.Errorf("expected changed %v, but the change is reported %d times", c.expectedChanged, changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+// package bootstrapcredential assembles the bootstrap kubeconfig of the registration agent from the files in a
+// directory at runtime, e.g. a volume mounted by the secrets-store CSI driver, for the orgs which forbid static
+// kubeconfig Secrets. The directory holds either a complete kubeconfig or its parts, and the changes of the files
+// are detected so the agent re-assembles the kubeconfig.
+package bootstrapcredential
@@ -0,0 +1,108 @@
+package bootstrapcredential
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// The files in the directory of the bootstrap credential
+const (
+	// KubeconfigFile is a complete bootstrap kubeconfig, the parts are ignored if it exists
+	KubeconfigFile = "kubeconfig"
+	// ServerFile is the URL of the hub kube-apiserver
+	ServerFile = "server"
+	// CAFile is the CA bundle to verify the serving certificate of the hub kube-apiserver, the system roots are used
+	// if it does not exist
+	CAFile = "ca.crt"
+	// TokenFile is the bearer token the agent authenticates to the hub with
+	TokenFile = "token"
+	// TLSCertFile and TLSKeyFile are the client certificate and key the agent authenticates to the hub with if
+	// there is no token
+	TLSCertFile = "tls.crt"
+	TLSKeyFile  = "tls.key"
+)
+
+// LoadClientConfig assembles the client config of the hub from the files in the directory. The token and the
+// client certificate are referenced by their paths, so the rotations of them are picked up by the clients without
+// re-assembling the config.
+func LoadClientConfig(dir string) (*rest.Config, error) {
+	kubeconfig := path.Join(dir, KubeconfigFile)
+	if exists, err := fileExists(kubeconfig); err != nil {
+		return nil, err
+	} else if exists {
+		return clientcmd.BuildConfigFromFlags("", kubeconfig)
+	}
+
+	server, err := ioutil.ReadFile(path.Join(dir, ServerFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the hub server of the bootstrap credential: %w", err)
+	}
+	config := &rest.Config{Host: strings.TrimSpace(string(server))}
+	if len(config.Host) == 0 {
+		return nil, fmt.Errorf("the hub server in %q is empty", path.Join(dir, ServerFile))
+	}
+
+	if exists, err := fileExists(path.Join(dir, CAFile)); err != nil {
+		return nil, err
+	} else if exists {
+		config.TLSClientConfig.CAFile = path.Join(dir, CAFile)
+	}
+
+	hasToken, err := fileExists(path.Join(dir, TokenFile))
+	if err != nil {
+		return nil, err
+	}
+	hasCert, err := fileExists(path.Join(dir, TLSCertFile))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case hasToken:
+		config.BearerTokenFile = path.Join(dir, TokenFile)
+	case hasCert:
+		config.TLSClientConfig.CertFile = path.Join(dir, TLSCertFile)
+		config.TLSClientConfig.KeyFile = path.Join(dir, TLSKeyFile)
+	default:
+		return nil, fmt.Errorf("neither %s nor %s is found in the bootstrap credential directory %q",
+			TokenFile, TLSCertFile, dir)
+	}
+	return config, nil
+}
+
+// Digest returns the digest of the files the client config is assembled from, a change of the digest means the
+// config has to be re-assembled. The token and the client certificate are not included, since they are reloaded
+// by the clients.
+func Digest(dir string) (string, error) {
+	h := sha256.New()
+	for _, name := range []string{KubeconfigFile, ServerFile, CAFile} {
+		data, err := ioutil.ReadFile(path.Join(dir, name))
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			return "", err
+		}
+		fmt.Fprintf(h, "%s:%d:", name, len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func fileExists(name string) (bool, error) {
+	_, err := os.Stat(name)
+	switch {
+	case os.IsNotExist(err):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return true, nil
+}
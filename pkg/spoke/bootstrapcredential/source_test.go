@@ -0,0 +1,105 @@
+package bootstrapcredential
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/rest"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, data := range files {
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadClientConfig(t *testing.T) {
+	cases := []struct {
+		name        string
+		files       map[string]string
+		expectedErr string
+		validate    func(t *testing.T, dir string, config *rest.Config)
+	}{
+		{
+			name:  "complete kubeconfig",
+			files: map[string]string{KubeconfigFile: string(testinghelpers.NewKubeconfig([]byte("key"), []byte("cert"))), ServerFile: "https://ignored"},
+			validate: func(t *testing.T, dir string, config *rest.Config) {
+				if config.Host != "https://127.0.0.1:6001" {
+					t.Errorf("expected the server of the kubeconfig, but got %q", config.Host)
+				}
+			},
+		},
+		{
+			name:  "token parts",
+			files: map[string]string{ServerFile: "https://hub.example.com:6443\n", CAFile: "ca", TokenFile: "token"},
+			validate: func(t *testing.T, dir string, config *rest.Config) {
+				if config.Host != "https://hub.example.com:6443" || config.CAFile != path.Join(dir, CAFile) ||
+					config.BearerTokenFile != path.Join(dir, TokenFile) {
+					t.Errorf("unexpected config %v", config)
+				}
+			},
+		},
+		{
+			name:  "client certificate parts",
+			files: map[string]string{ServerFile: "https://hub.example.com:6443", TLSCertFile: "cert", TLSKeyFile: "key"},
+			validate: func(t *testing.T, dir string, config *rest.Config) {
+				if len(config.CAFile) > 0 || config.CertFile != path.Join(dir, TLSCertFile) || config.KeyFile != path.Join(dir, TLSKeyFile) {
+					t.Errorf("unexpected config %v", config)
+				}
+			},
+		},
+		{
+			name:        "no credential",
+			files:       map[string]string{ServerFile: "https://hub.example.com:6443"},
+			expectedErr: "neither token nor tls.crt is found in the bootstrap credential directory",
+		},
+		{
+			name:        "empty server",
+			files:       map[string]string{ServerFile: " \n", TokenFile: "token"},
+			expectedErr: "the hub server in",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, c.files)
+			config, err := LoadClientConfig(dir)
+			switch {
+			case len(c.expectedErr) > 0:
+				if err == nil || !strings.Contains(err.Error(), c.expectedErr) {
+					t.Errorf("expected error %q, but got %v", c.expectedErr, err)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			default:
+				c.validate(t, dir, config)
+			}
+		})
+	}
+}
+
+func TestDigest(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{ServerFile: "https://hub.example.com:6443", TokenFile: "token1"})
+	digest, err := Digest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the token is reloaded by the clients, so its rotation does not change the digest
+	writeFiles(t, dir, map[string]string{TokenFile: "token2"})
+	if rotated, _ := Digest(dir); rotated != digest {
+		t.Errorf("expected the digest not to be changed by the token rotation")
+	}
+
+	writeFiles(t, dir, map[string]string{CAFile: "ca"})
+	if changed, _ := Digest(dir); changed == digest {
+		t.Errorf("expected the digest to be changed by the new CA bundle")
+	}
+}
@@ -0,0 +1,327 @@
+package spoke
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/component-base/featuregate"
+	"sigs.k8s.io/yaml"
+
+	configv1alpha1 "open-cluster-management.io/registration/pkg/apis/config/v1alpha1"
+	"open-cluster-management.io/registration/pkg/features"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// reloadableFeatures are the features which are checked whenever they are used instead of when the agent starts,
+// so they are enabled or disabled without restarting the agent once the configuration file is changed.
+var reloadableFeatures = sets.NewString(
+	string(features.V1beta1CSRAPICompatibility),
+)
+
+// runtimeSettingsLock serializes the changes of the settings applied while the agent runs, which are applied by both
+// the configuration file and the agent settings published by the hub
+var runtimeSettingsLock sync.Mutex
+
+// applyComponentConfig applies the SpokeAgentConfiguration in the file passed with --config to the options. The
+// flags set on the command line take precedence over the configuration file. The file is ignored if it is not a
+// SpokeAgentConfiguration, e.g. a GenericOperatorConfig of library-go.
+func (o *SpokeAgentOptions) applyComponentConfig(componentConfig *unstructured.Unstructured) error {
+	if componentConfig == nil || componentConfig.GroupVersionKind().GroupVersion() != configv1alpha1.SchemeGroupVersion {
+		return nil
+	}
+
+	data, err := componentConfig.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	config, err := decodeConfiguration(data)
+	if err != nil {
+		return err
+	}
+	return o.applyConfiguration(config)
+}
+
+// TakeOverConfigFile takes the configuration file passed with --config over from library-go if it is a
+// SpokeAgentConfiguration. library-go restarts the agent on any change of the file, while the agent applies the
+// changed settings it reads while running in place, and restarts itself only if the other settings are changed.
+// It is called once the flags are parsed and before the agent is started by library-go.
+func (o *SpokeAgentOptions) TakeOverConfigFile(fs *pflag.FlagSet) error {
+	configFlag := fs.Lookup("config")
+	if configFlag == nil || len(configFlag.Value.String()) == 0 {
+		return nil
+	}
+
+	filename := configFlag.Value.String()
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		// the file is left to library-go, which reports the error
+		return nil
+	}
+	typeMeta := metav1.TypeMeta{}
+	if err := yaml.Unmarshal(data, &typeMeta); err != nil ||
+		typeMeta.GroupVersionKind() != configv1alpha1.SchemeGroupVersion.WithKind("SpokeAgentConfiguration") {
+		return nil
+	}
+
+	o.configFile = filename
+	return configFlag.Value.Set("")
+}
+
+// loadConfiguration applies the configuration file to the options when the agent starts, which is either the file
+// taken over from library-go, or the component config of library-go. It returns the data of the file taken over.
+func (o *SpokeAgentOptions) loadConfiguration(componentConfig *unstructured.Unstructured) ([]byte, error) {
+	if len(o.configFile) == 0 {
+		return nil, o.applyComponentConfig(componentConfig)
+	}
+
+	data, err := ioutil.ReadFile(o.configFile)
+	if err != nil {
+		return nil, err
+	}
+	config, err := decodeConfiguration(data)
+	if err != nil {
+		return nil, err
+	}
+	return data, o.applyConfiguration(config)
+}
+
+// reloadConfigFile applies the changed configuration file to the running agent, restart is called if the agent has
+// to be restarted to apply it.
+func (o *SpokeAgentOptions) reloadConfigFile(data []byte, restart func()) error {
+	config, err := decodeConfiguration(data)
+	if err != nil {
+		return err
+	}
+	restartRequired, err := o.reloadConfiguration(config)
+	if err != nil {
+		return err
+	}
+	if restartRequired {
+		restart()
+	}
+	return nil
+}
+
+// reloadConfiguration applies a changed configuration to the running agent. The reloadable features, the period
+// of the health check of the managed cluster, and the settings of the controller runtime are applied in place. It
+// returns true without applying anything if any of the other settings is changed, since they are only read when
+// the agent starts.
+func (o *SpokeAgentOptions) reloadConfiguration(config *configv1alpha1.SpokeAgentConfiguration) (bool, error) {
+	runtimeSettingsLock.Lock()
+	defer runtimeSettingsLock.Unlock()
+
+	next := *o
+	next.applyFields(config)
+	if err := next.Validate(); err != nil {
+		return false, err
+	}
+	if startupSettingsChanged(o, &next) || o.startupFeatureGatesChanged(config) {
+		return true, nil
+	}
+
+	if err := o.reloadFeatureGates(config.FeatureGates); err != nil {
+		return false, err
+	}
+	o.applyFields(config)
+	o.appliedConfig = config
+	o.applyRuntimeSettings()
+	return false, nil
+}
+
+// applyConfiguration applies a configuration to the options before the agent starts
+func (o *SpokeAgentOptions) applyConfiguration(config *configv1alpha1.SpokeAgentConfiguration) error {
+	if !o.flagChanged("feature-gates") && len(config.FeatureGates) > 0 {
+		if err := features.DefaultSpokeMutableFeatureGate.SetFromMap(config.FeatureGates); err != nil {
+			return err
+		}
+	}
+	o.applyFields(config)
+	o.appliedConfig = config
+	return nil
+}
+
+// applyFields applies the fields of a configuration which are not set on the command line to the options
+func (o *SpokeAgentOptions) applyFields(config *configv1alpha1.SpokeAgentConfiguration) {
+	if !o.flagChanged("cluster-name") && len(config.ClusterName) > 0 {
+		o.ClusterName = config.ClusterName
+	}
+	if !o.flagChanged("bootstrap-kubeconfig") && len(config.BootstrapKubeconfig) > 0 {
+		o.BootstrapKubeconfig = config.BootstrapKubeconfig
+	}
+	if !o.flagChanged("hub-kubeconfig-secret") {
+		o.HubKubeconfigSecret = config.HubKubeconfigSecret
+	}
+	if !o.flagChanged("hub-kubeconfig-dir") {
+		o.HubKubeconfigDir = config.HubKubeconfigDir
+	}
+	if !o.flagChanged("spoke-kubeconfig") && len(config.SpokeKubeconfig) > 0 {
+		o.SpokeKubeconfig = config.SpokeKubeconfig
+	}
+	if !o.flagChanged("spoke-external-server-urls") && len(config.SpokeExternalServerURLs) > 0 {
+		o.SpokeExternalServerURLs = config.SpokeExternalServerURLs
+	}
+	if !o.flagChanged("cluster-healthcheck-period") {
+		o.ClusterHealthCheckPeriod = config.ClusterHealthCheckPeriod.Duration
+	}
+	if !o.flagChanged("max-custom-cluster-claims") {
+		o.MaxCustomClusterClaims = int(*config.MaxCustomClusterClaims)
+	}
+	if !o.flagChanged("max-concurrent-addon-registrations") {
+		o.MaxConcurrentAddOnRegistrations = int(*config.MaxConcurrentAddOnRegistrations)
+	}
+	if !o.flagChanged("addon-registration-stagger-interval") {
+		o.AddOnRegistrationStaggerInterval = config.AddOnRegistrationStaggerInterval.Duration
+	}
+	if !o.flagChanged("controller-progress-deadline") {
+		o.ControllerProgressDeadline = config.ControllerProgressDeadline.Duration
+	}
+	if !o.flagChanged("slow-sync-threshold") {
+		o.SlowSyncThreshold = config.SlowSyncThreshold.Duration
+	}
+	if !o.flagChanged("retry-base-delay") {
+		o.RetryBaseDelay = config.RetryBaseDelay.Duration
+	}
+	if !o.flagChanged("retry-max-delay") {
+		o.RetryMaxDelay = config.RetryMaxDelay.Duration
+	}
+	if !o.flagChanged("retry-qps") {
+		o.RetryQPS = float64(config.RetryQPS)
+	}
+}
+
+// applyRuntimeSettings applies the settings the agent reads while running, they are applied when the agent starts
+// and once they are changed in the configuration file.
+func (o *SpokeAgentOptions) applyRuntimeSettings() {
+	atomic.StoreInt64(&o.clusterHealthCheckPeriodNanos, int64(o.ClusterHealthCheckPeriod))
+	health.SetProgressDeadline(o.ControllerProgressDeadline)
+	health.SetSlowSyncThreshold(o.SlowSyncThreshold)
+	health.SetRetryLimits(o.RetryBaseDelay, o.RetryMaxDelay, o.RetryQPS)
+}
+
+// clusterHealthCheckPeriod returns the current period to check the health of the managed cluster, which may be
+// changed in the configuration file while the agent runs.
+func (o *SpokeAgentOptions) clusterHealthCheckPeriod() time.Duration {
+	return time.Duration(atomic.LoadInt64(&o.clusterHealthCheckPeriodNanos))
+}
+
+// startupFeatureGatesChanged returns true if a feature which is only checked when the agent starts is enabled or
+// disabled in a configuration, compared with the configuration the agent runs with.
+func (o *SpokeAgentOptions) startupFeatureGatesChanged(config *configv1alpha1.SpokeAgentConfiguration) bool {
+	if o.flagChanged("feature-gates") {
+		return false
+	}
+	current := map[string]bool{}
+	if o.appliedConfig != nil {
+		current = o.appliedConfig.FeatureGates
+	}
+	names := sets.StringKeySet(current).Union(sets.StringKeySet(config.FeatureGates))
+	for _, name := range names.List() {
+		if reloadableFeatures.Has(name) {
+			continue
+		}
+		enabled, ok := current[name]
+		newEnabled, newOk := config.FeatureGates[name]
+		if ok != newOk || enabled != newEnabled {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadFeatureGates enables or disables the reloadable features as the feature gates of a configuration, the
+// features removed from the configuration are reset to their defaults. The feature gates published by the hub take
+// precedence over the configuration.
+func (o *SpokeAgentOptions) reloadFeatureGates(configGates map[string]bool) error {
+	if o.flagChanged("feature-gates") {
+		return nil
+	}
+	specs := features.DefaultSpokeMutableFeatureGate.GetAll()
+	gates := map[string]bool{}
+	for _, name := range reloadableFeatures.List() {
+		if enabled, ok := o.hubFeatureGates[name]; ok {
+			gates[name] = enabled
+			continue
+		}
+		if enabled, ok := configGates[name]; ok {
+			gates[name] = enabled
+			continue
+		}
+		if spec, ok := specs[featuregate.Feature(name)]; ok {
+			gates[name] = spec.Default
+		}
+	}
+	if err := features.DefaultSpokeMutableFeatureGate.SetFromMap(gates); err != nil {
+		return err
+	}
+	features.ReportFeatureGates(features.Spoke)
+	return nil
+}
+
+// applyHubSettings applies the agent settings published by the hub to the running agent, see
+// managedcluster.NewAgentConfigController. The log level and the reloadable features are applied in place, and the
+// settings removed on the hub are reset to the ones the agent is started with. The other features may not be set
+// from the hub, since they are only checked when the agent starts. The lease duration is not applied by the agent,
+// the hub sets it on the spec of the managed cluster.
+func (o *SpokeAgentOptions) applyHubSettings(settings *helpers.AgentSettings) error {
+	for _, name := range sets.StringKeySet(settings.FeatureGates).List() {
+		if !reloadableFeatures.Has(name) {
+			return fmt.Errorf("feature %s may not be set from the hub, it is only checked when the agent starts", name)
+		}
+	}
+
+	runtimeSettingsLock.Lock()
+	defer runtimeSettingsLock.Unlock()
+
+	o.hubFeatureGates = settings.FeatureGates
+	configGates := map[string]bool{}
+	if o.appliedConfig != nil {
+		configGates = o.appliedConfig.FeatureGates
+	}
+	if err := o.reloadFeatureGates(configGates); err != nil {
+		return err
+	}
+
+	level := o.startupLogLevel
+	if settings.LogLevel != nil {
+		level = *settings.LogLevel
+	}
+	return helpers.SetLogLevel(level)
+}
+
+func (o *SpokeAgentOptions) flagChanged(name string) bool {
+	return o.flags != nil && o.flags.Changed(name)
+}
+
+// startupSettingsChanged returns true if any of the settings which are only read when the agent starts differs
+func startupSettingsChanged(current, next *SpokeAgentOptions) bool {
+	return current.ClusterName != next.ClusterName ||
+		current.BootstrapKubeconfig != next.BootstrapKubeconfig ||
+		current.HubKubeconfigSecret != next.HubKubeconfigSecret ||
+		current.HubKubeconfigDir != next.HubKubeconfigDir ||
+		current.SpokeKubeconfig != next.SpokeKubeconfig ||
+		!reflect.DeepEqual(current.SpokeExternalServerURLs, next.SpokeExternalServerURLs) ||
+		current.MaxCustomClusterClaims != next.MaxCustomClusterClaims ||
+		current.MaxConcurrentAddOnRegistrations != next.MaxConcurrentAddOnRegistrations ||
+		current.AddOnRegistrationStaggerInterval != next.AddOnRegistrationStaggerInterval
+}
+
+// decodeConfiguration decodes and validates an agent configuration
+func decodeConfiguration(data []byte) (*configv1alpha1.SpokeAgentConfiguration, error) {
+	config, err := configv1alpha1.DecodeSpokeAgentConfiguration(data)
+	if err != nil {
+		return nil, err
+	}
+	if errs := configv1alpha1.ValidateSpokeAgentConfiguration(config); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+	return config, nil
+}
@@ -0,0 +1,373 @@
+package spoke
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+
+	"open-cluster-management.io/registration/pkg/features"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const configHeader = `apiVersion: registration.config.open-cluster-management.io/v1alpha1
+kind: SpokeAgentConfiguration
+`
+
+func TestApplyComponentConfig(t *testing.T) {
+	cases := []struct {
+		name                   string
+		args                   []string
+		componentConfig        *unstructured.Unstructured
+		expectedErr            bool
+		expectedClusterName    string
+		expectedHealthCheck    time.Duration
+		expectedMaxConcurrency int
+	}{
+		{
+			name:                   "no configuration file",
+			expectedHealthCheck:    time.Minute,
+			expectedMaxConcurrency: 10,
+		},
+		{
+			name: "other configuration file",
+			componentConfig: &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion":  "operator.openshift.io/v1alpha1",
+				"kind":        "GenericOperatorConfig",
+				"clusterName": "cluster1",
+			}},
+			expectedHealthCheck:    time.Minute,
+			expectedMaxConcurrency: 10,
+		},
+		{
+			name: "apply configuration file",
+			componentConfig: &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion":                      "registration.config.open-cluster-management.io/v1alpha1",
+				"kind":                            "SpokeAgentConfiguration",
+				"clusterName":                     "cluster1",
+				"clusterHealthCheckPeriod":        "30s",
+				"maxConcurrentAddOnRegistrations": int64(0),
+			}},
+			expectedClusterName:    "cluster1",
+			expectedHealthCheck:    30 * time.Second,
+			expectedMaxConcurrency: 0,
+		},
+		{
+			name: "flags take precedence",
+			args: []string{"--cluster-name=cluster2", "--cluster-healthcheck-period=2m"},
+			componentConfig: &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion":               "registration.config.open-cluster-management.io/v1alpha1",
+				"kind":                     "SpokeAgentConfiguration",
+				"clusterName":              "cluster1",
+				"clusterHealthCheckPeriod": "30s",
+			}},
+			expectedClusterName:    "cluster2",
+			expectedHealthCheck:    2 * time.Minute,
+			expectedMaxConcurrency: 10,
+		},
+		{
+			name: "invalid configuration file",
+			componentConfig: &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion":               "registration.config.open-cluster-management.io/v1alpha1",
+				"kind":                     "SpokeAgentConfiguration",
+				"clusterHealthCheckPeriod": "-1s",
+			}},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			options := NewSpokeAgentOptions()
+			flags := pflag.NewFlagSet("agent", pflag.ContinueOnError)
+			options.AddFlags(flags)
+			if err := flags.Parse(c.args); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err := options.applyComponentConfig(c.componentConfig)
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if options.ClusterName != c.expectedClusterName {
+				t.Errorf("expected cluster name %q, but got %q", c.expectedClusterName, options.ClusterName)
+			}
+			if options.ClusterHealthCheckPeriod != c.expectedHealthCheck {
+				t.Errorf("expected health check period %v, but got %v", c.expectedHealthCheck, options.ClusterHealthCheckPeriod)
+			}
+			if options.MaxConcurrentAddOnRegistrations != c.expectedMaxConcurrency {
+				t.Errorf("expected max concurrent addon registrations %d, but got %d",
+					c.expectedMaxConcurrency, options.MaxConcurrentAddOnRegistrations)
+			}
+		})
+	}
+}
+
+func TestTakeOverConfigFile(t *testing.T) {
+	cases := []struct {
+		name             string
+		data             string
+		expectedTakeOver bool
+	}{
+		{
+			name: "other configuration file",
+			data: "apiVersion: operator.openshift.io/v1alpha1\nkind: GenericOperatorConfig\n",
+		},
+		{
+			name:             "agent configuration file",
+			data:             configHeader + "clusterName: cluster1\n",
+			expectedTakeOver: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			filename := path.Join(t.TempDir(), "config.yaml")
+			if err := ioutil.WriteFile(filename, []byte(c.data), 0600); err != nil {
+				t.Fatal(err)
+			}
+			flags := pflag.NewFlagSet("agent", pflag.ContinueOnError)
+			configFile := flags.String("config", "", "")
+			if err := flags.Parse([]string{"--config=" + filename}); err != nil {
+				t.Fatal(err)
+			}
+
+			options := NewSpokeAgentOptions()
+			if err := options.TakeOverConfigFile(flags); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if takenOver := options.configFile == filename && len(*configFile) == 0; takenOver != c.expectedTakeOver {
+				t.Errorf("expected taken over %v, but got config file %q and flag %q", c.expectedTakeOver, options.configFile, *configFile)
+			}
+		})
+	}
+}
+
+func TestReloadConfigFile(t *testing.T) {
+	// the feature gates are the last field of the configuration the agent runs with, so that more gates are appended
+	runningConfig := configHeader + "clusterName: cluster1\nfeatureGates:\n  AddonManagement: true\n"
+	resetFeatureGates := func() {
+		specs := features.DefaultSpokeMutableFeatureGate.GetAll()
+		if err := features.DefaultSpokeMutableFeatureGate.SetFromMap(map[string]bool{
+			string(features.AddonManagement):            specs[features.AddonManagement].Default,
+			string(features.V1beta1CSRAPICompatibility): specs[features.V1beta1CSRAPICompatibility].Default,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		resetFeatureGates()
+		health.SetProgressDeadline(health.DefaultProgressDeadline)
+		health.SetSlowSyncThreshold(health.DefaultSlowSyncThreshold)
+		health.SetRetryLimits(health.DefaultRetryBaseDelay, health.DefaultRetryMaxDelay, health.DefaultRetryQPS)
+	}()
+
+	cases := []struct {
+		name                string
+		args                []string
+		data                string
+		expectedErr         bool
+		expectedRestart     bool
+		expectedHealthCheck time.Duration
+		expectedRetryQPS    float64
+		expectedV1beta1CSR  bool
+	}{
+		{
+			name:                "health check period changed",
+			data:                runningConfig + "clusterHealthCheckPeriod: 2m\n",
+			expectedHealthCheck: 2 * time.Minute,
+			expectedRetryQPS:    10,
+		},
+		{
+			name:                "retry qps changed",
+			data:                runningConfig + "retryQPS: 5\n",
+			expectedHealthCheck: time.Minute,
+			expectedRetryQPS:    5,
+		},
+		{
+			name:                "reloadable feature enabled",
+			data:                runningConfig + "  V1beta1CSRAPICompatibility: true\n",
+			expectedHealthCheck: time.Minute,
+			expectedRetryQPS:    10,
+			expectedV1beta1CSR:  true,
+		},
+		{
+			name:                "feature removed",
+			data:                configHeader + "clusterName: cluster1\nclusterHealthCheckPeriod: 2m\n",
+			expectedRestart:     true,
+			expectedHealthCheck: time.Minute,
+			expectedRetryQPS:    10,
+		},
+		{
+			name:                "cluster name changed",
+			data:                strings.Replace(runningConfig, "cluster1", "cluster2", 1) + "clusterHealthCheckPeriod: 2m\n",
+			expectedRestart:     true,
+			expectedHealthCheck: time.Minute,
+			expectedRetryQPS:    10,
+		},
+		{
+			name:                "flags take precedence",
+			args:                []string{"--cluster-healthcheck-period=3m"},
+			data:                runningConfig + "clusterHealthCheckPeriod: 2m\n",
+			expectedHealthCheck: 3 * time.Minute,
+			expectedRetryQPS:    10,
+		},
+		{
+			name:        "invalid configuration file",
+			data:        runningConfig + "clusterHealthCheckPeriod: -1s\n",
+			expectedErr: true,
+		},
+		{
+			name:        "retry max delay less than the base delay",
+			data:        runningConfig + "retryBaseDelay: 1m\nretryMaxDelay: 1s\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			options := NewSpokeAgentOptions()
+			flags := pflag.NewFlagSet("agent", pflag.ContinueOnError)
+			options.AddFlags(flags)
+			if err := flags.Parse(c.args); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resetFeatureGates()
+			config, err := decodeConfiguration([]byte(runningConfig))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := options.applyConfiguration(config); err != nil {
+				t.Fatal(err)
+			}
+			// the options of the running agent are completed
+			options.AgentName = "agent1"
+			options.BootstrapKubeconfig = "/spoke/bootstrap/kubeconfig"
+			options.applyRuntimeSettings()
+
+			restarted := false
+			err = options.reloadConfigFile([]byte(c.data), func() { restarted = true })
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if restarted != c.expectedRestart {
+				t.Errorf("expected restart %v, but got %v", c.expectedRestart, restarted)
+			}
+			if period := options.clusterHealthCheckPeriod(); period != c.expectedHealthCheck {
+				t.Errorf("expected health check period %v, but got %v", c.expectedHealthCheck, period)
+			}
+			if options.RetryQPS != c.expectedRetryQPS {
+				t.Errorf("expected retry qps %v, but got %v", c.expectedRetryQPS, options.RetryQPS)
+			}
+			if enabled := features.DefaultSpokeMutableFeatureGate.Enabled(features.V1beta1CSRAPICompatibility); enabled != c.expectedV1beta1CSR {
+				t.Errorf("expected V1beta1CSRAPICompatibility enabled %v, but got %v", c.expectedV1beta1CSR, enabled)
+			}
+		})
+	}
+}
+
+func TestApplyHubSettings(t *testing.T) {
+	defer func() {
+		if err := features.DefaultSpokeMutableFeatureGate.SetFromMap(map[string]bool{
+			string(features.V1beta1CSRAPICompatibility): false,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := helpers.SetLogLevel(0); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	cases := []struct {
+		name               string
+		args               []string
+		settings           *helpers.AgentSettings
+		expectedErr        string
+		expectedLogLevel   int32
+		expectedV1beta1CSR bool
+	}{
+		{
+			name: "apply the settings",
+			settings: &helpers.AgentSettings{
+				LogLevel:     pointer.Int32(4),
+				FeatureGates: map[string]bool{string(features.V1beta1CSRAPICompatibility): true},
+			},
+			expectedLogLevel:   4,
+			expectedV1beta1CSR: true,
+		},
+		{
+			name:             "reset the settings removed",
+			settings:         &helpers.AgentSettings{},
+			expectedLogLevel: 1,
+		},
+		{
+			name: "feature gates set on the command line take precedence",
+			args: []string{"--feature-gates=V1beta1CSRAPICompatibility=false"},
+			settings: &helpers.AgentSettings{
+				FeatureGates: map[string]bool{string(features.V1beta1CSRAPICompatibility): true},
+			},
+			expectedLogLevel: 1,
+		},
+		{
+			name: "feature checked when the agent starts",
+			settings: &helpers.AgentSettings{
+				FeatureGates: map[string]bool{string(features.AddonManagement): true},
+			},
+			expectedErr: "feature AddonManagement may not be set from the hub, it is only checked when the agent starts",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			options := NewSpokeAgentOptions()
+			flags := pflag.NewFlagSet("agent", pflag.ContinueOnError)
+			options.AddFlags(flags)
+			if err := flags.Parse(c.args); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := features.DefaultSpokeMutableFeatureGate.SetFromMap(map[string]bool{
+				string(features.V1beta1CSRAPICompatibility): false,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			options.startupLogLevel = 1
+
+			err := options.applyHubSettings(c.settings)
+			if len(c.expectedErr) > 0 {
+				if err == nil || err.Error() != c.expectedErr {
+					t.Fatalf("expected error %q, but got %v", c.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if level := helpers.LogLevel(); level != c.expectedLogLevel {
+				t.Errorf("expected log level %d, but got %d", c.expectedLogLevel, level)
+			}
+			if enabled := features.DefaultSpokeMutableFeatureGate.Enabled(features.V1beta1CSRAPICompatibility); enabled != c.expectedV1beta1CSR {
+				t.Errorf("expected V1beta1CSRAPICompatibility enabled %v, but got %v", c.expectedV1beta1CSR, enabled)
+			}
+		})
+	}
+}
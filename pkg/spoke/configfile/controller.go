@@ -0,0 +1,71 @@
+package configfile
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const controllerName = "ConfigFileController"
+
+// PollInterval is the interval the configuration file is checked for changes in. The kubelet replaces the files of
+// a configmap volume with a symlink swap, so the file is polled instead of watched.
+var PollInterval = 30 * time.Second
+
+// configFileController detects the changes of the configuration file and reloads the changed file. A file failed
+// to reload is not reloaded again until it is changed, so that an invalid file is reported once.
+type configFileController struct {
+	filename string
+	digest   string
+	reload   func(ctx context.Context, data []byte) error
+}
+
+// NewConfigFileController returns an instance of configFileController. The data is the content of the file the
+// agent is started with.
+func NewConfigFileController(filename string, data []byte, reload func(ctx context.Context, data []byte) error,
+	recorder events.Recorder) factory.Controller {
+	c := &configFileController{
+		filename: filename,
+		digest:   digest(data),
+		reload:   reload,
+	}
+
+	return factory.New().
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ResyncEvery(PollInterval).
+		ToController(controllerName, recorder)
+}
+
+func (c *configFileController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	data, err := ioutil.ReadFile(c.filename)
+	if err != nil {
+		return err
+	}
+	digest := digest(data)
+	if digest == c.digest {
+		return nil
+	}
+	c.digest = digest
+
+	if err := c.reload(ctx, data); err != nil {
+		syncCtx.Recorder().Warningf("ConfigFileReloadFailed",
+			"Failed to reload the changed configuration file %q, the agent runs with the previous one: %v", c.filename, err)
+		return nil
+	}
+	helpers.ControllerLogger(ctx, controllerName).Info("The configuration file is reloaded", "file", c.filename)
+	syncCtx.Recorder().Eventf("ConfigFileReloaded", "The changed configuration file %q is reloaded", c.filename)
+	return nil
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,68 @@
+package configfile
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestSync(t *testing.T) {
+	cases := []struct {
+		name            string
+		data            string
+		reloadErr       error
+		expectedReloads int
+	}{
+		{
+			name: "not changed",
+			data: "clusterHealthCheckPeriod: 1m",
+		},
+		{
+			name:            "changed",
+			data:            "clusterHealthCheckPeriod: 2m",
+			expectedReloads: 1,
+		},
+		{
+			name:            "failed to reload",
+			data:            "clusterHealthCheckPeriod: -1m",
+			reloadErr:       fmt.Errorf("invalid configuration"),
+			expectedReloads: 1,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			filename := path.Join(t.TempDir(), "config.yaml")
+			if err := ioutil.WriteFile(filename, []byte(c.data), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			reloads := 0
+			ctrl := &configFileController{
+				filename: filename,
+				digest:   digest([]byte("clusterHealthCheckPeriod: 1m")),
+				reload: func(ctx context.Context, data []byte) error {
+					reloads++
+					if string(data) != c.data {
+						t.Errorf("expected data %q, but got %q", c.data, string(data))
+					}
+					return c.reloadErr
+				},
+			}
+			// the changed file is only reloaded once
+			for i := 0; i < 2; i++ {
+				if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey)); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+			if reloads != c.expectedReloads {
+				t.Errorf("expected %d reloads, but got %d", c.expectedReloads, reloads)
+			}
+		})
+	}
+}
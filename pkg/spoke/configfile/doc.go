@@ -0,0 +1,3 @@
+// package configfile contains the controller which reloads the configuration file of the agent once it is changed,
+// so that the settings read by the agent while running are changed without restarting the agent.
+package configfile
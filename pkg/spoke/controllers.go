@@ -0,0 +1,462 @@
+package spoke
+
+import (
+	"context"
+	"fmt"
+
+	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	"open-cluster-management.io/registration/pkg/cloudevents"
+	"open-cluster-management.io/registration/pkg/features"
+	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/sdk"
+	"open-cluster-management.io/registration/pkg/spoke/addon"
+	"open-cluster-management.io/registration/pkg/spoke/lease"
+	"open-cluster-management.io/registration/pkg/spoke/managedcluster"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// spokeControllerContext holds the options, the clients and the shared informer factories the controllers of the
+// agent are created with once the hub kubeconfig is ready. The clients and the informer factories of the managed
+// cluster are nil in the claims-only mode.
+type spokeControllerContext struct {
+	*SpokeAgentOptions
+
+	recorder events.Recorder
+	// clusterNameConfigured is true if the cluster name is configured explicitly, the cluster is not re-registered
+	// with the new name once it is renamed on the hub then
+	clusterNameConfigured bool
+
+	managementKubeClient kubernetes.Interface
+	spokeClientConfig    *rest.Config
+	spokeKubeClient      kubernetes.Interface
+	spokeClusterClient   clusterv1client.Interface
+	hubClientConfig      *rest.Config
+	hubKubeClient        kubernetes.Interface
+	hubClusterClient     clusterv1client.Interface
+	addOnClient          addonclient.Interface
+	// kubeconfigData is the hub kubeconfig referring to the key and the certificate files in the same secret
+	kubeconfigData []byte
+	// publisher publishes the heartbeats and the status of the cluster to the broker, it is nil if the status is
+	// written to the hub directly
+	publisher *cloudevents.MQTTClient
+	// leaseRenewer writes the lease of the cluster and the health summary of the addons on it in batched passes
+	leaseRenewer *lease.Renewer
+
+	spokeKubeInformerFactory                informers.SharedInformerFactory
+	spokeClusterInformerFactory             clusterv1informers.SharedInformerFactory
+	namespacedManagementKubeInformerFactory informers.SharedInformerFactory
+	// hubKubeInformerFactory only watches the csrs of the managed cluster while a csr is pending
+	hubKubeInformerFactory informers.SharedInformerFactory
+	// namespacedHubKubeInformerFactory watches the managed cluster namespace on the hub
+	namespacedHubKubeInformerFactory informers.SharedInformerFactory
+	// hubClusterInformerFactory only watches the managed cluster of the agent
+	hubClusterInformerFactory clusterv1informers.SharedInformerFactory
+	addOnInformerFactory      addoninformers.SharedInformerFactory
+	// reverseTunnelKubeInformerFactory watches the namespace of the reverse tunnel agent on the managed cluster, it
+	// is nil if the reverse tunnel is not bootstrapped
+	reverseTunnelKubeInformerFactory informers.SharedInformerFactory
+
+	// reregistered and hubCABundleChanged are called once the agent should exit to re-run bootstrap or to trust
+	// the new hub CA bundle
+	reregistered       func()
+	hubCABundleChanged func()
+}
+
+// newSpokeControllersFunc creates controllers of the agent. It returns no controller if the controllers are not
+// required by the options or the feature gates.
+type newSpokeControllersFunc func(c *spokeControllerContext) ([]factory.Controller, error)
+
+// spokeControllers are the functions creating the controllers of the agent which run once the hub kubeconfig is
+// ready
+var spokeControllers = []newSpokeControllersFunc{
+	newClientCertForHubController,
+	newManagedClusterJoiningController,
+	newAddOnRegistrationAnnotationController,
+	newManagedClusterLeaseController,
+	newManagedClusterStatusController,
+	newReregistrationController,
+	newHubStateController,
+	newRenameController,
+	newHubPermissionController,
+	newManagedClusterClaimController,
+	newClusterPropertyController,
+	newReverseTunnelCertController,
+	newHubCABundleController,
+	newAgentConfigController,
+	newAddOnControllers,
+}
+
+// newControllers creates the controllers of the agent which are required by the options and the feature gates
+func (c *spokeControllerContext) newControllers() ([]factory.Controller, error) {
+	controllers := []factory.Controller{}
+	for _, newControllers := range spokeControllers {
+		ctrls, err := newControllers(c)
+		if err != nil {
+			return nil, err
+		}
+		controllers = append(controllers, ctrls...)
+	}
+	return controllers, nil
+}
+
+// newClientCertForHubController creates the controller rotating the credential of the agent in the hub kubeconfig
+func newClientCertForHubController(c *spokeControllerContext) ([]factory.Controller, error) {
+	controllerName := fmt.Sprintf("ClientCertController@cluster:%s", c.ClusterName)
+	var controller factory.Controller
+	var err error
+	switch {
+	case len(c.VaultAddress) > 0:
+		controller, err = c.newVaultClientCertController(
+			c.hubClientConfig,
+			c.namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			c.managementKubeClient,
+			c.recorder,
+			controllerName,
+		)
+	case len(c.SpiffeEndpointSocket) > 0:
+		controller, err = c.newSVIDCredentialController(
+			c.hubClientConfig,
+			c.namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			c.managementKubeClient,
+			c.recorder,
+			controllerName,
+		)
+	case c.RegistrationDriver == helpers.TokenRegistrationDriver:
+		controller, err = c.newTokenForHubController(
+			c.hubClientConfig,
+			c.namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			c.managementKubeClient,
+			c.hubKubeClient,
+			c.recorder,
+			controllerName,
+		)
+	case c.RegistrationDriver == helpers.AzureRegistrationDriver || c.RegistrationDriver == helpers.GCPRegistrationDriver:
+		controller, err = managedcluster.NewExecCredentialForHubController(
+			c.ClusterName, c.AgentName,
+			c.ComponentNamespace, c.HubKubeconfigSecret,
+			c.hubClientConfig,
+			c.execConfig(),
+			c.namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			c.managementKubeClient.CoreV1(),
+			c.recorder,
+			controllerName,
+		)
+	case c.RegistrationDriver == helpers.AWSIAMRegistrationDriver:
+		controller, err = c.newAWSIAMForHubController(
+			c.hubClientConfig,
+			c.namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			c.managementKubeClient,
+			c.hubClusterClient,
+			c.recorder,
+			controllerName,
+		)
+	default:
+		controller, err = managedcluster.NewClientCertForHubController(
+			c.ClusterName, c.AgentName, c.RegistrationSignerName, c.ComponentNamespace, c.HubKubeconfigSecret,
+			c.kubeconfigData,
+			c.namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			c.hubKubeInformerFactory.Certificates(),
+			c.managementKubeClient,
+			c.hubKubeClient,
+			c.recorder,
+			controllerName,
+			c.clientCertOptions()...,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []factory.Controller{controller}, nil
+}
+
+// newManagedClusterJoiningController creates the controller reconciling the ManagedCluster on the managed cluster
+func newManagedClusterJoiningController(c *spokeControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{managedcluster.NewManagedClusterJoiningController(
+		c.ClusterName,
+		c.hubClusterClient,
+		c.hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+		c.recorder,
+	)}, nil
+}
+
+// newAddOnRegistrationAnnotationController creates the controller keeping the addon registration disabled
+// annotation of the ManagedCluster in sync with the options
+func newAddOnRegistrationAnnotationController(c *spokeControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{managedcluster.NewAddOnRegistrationAnnotationController(
+		c.ClusterName,
+		c.DisableAddOnRegistration,
+		c.hubClusterClient,
+		c.hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+		c.recorder,
+	)}, nil
+}
+
+// newManagedClusterLeaseController creates the controller keeping the heartbeat of the cluster, which is published
+// to the broker if the status is not written to the hub directly
+func newManagedClusterLeaseController(c *spokeControllerContext) ([]factory.Controller, error) {
+	if c.publisher != nil {
+		return []factory.Controller{managedcluster.NewCloudEventsHeartbeatController(
+			c.ClusterName, c.AgentName,
+			c.publisher,
+			c.hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+			c.recorder,
+		)}, nil
+	}
+	return []factory.Controller{sdk.NewHeartbeatController(
+		c.ClusterName,
+		c.leaseRenewer,
+		c.hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+		c.recorder,
+	)}, nil
+}
+
+// newManagedClusterStatusController creates the controller updating the status of the cluster, which is published
+// to the broker if the status is not written to the hub directly
+func newManagedClusterStatusController(c *spokeControllerContext) ([]factory.Controller, error) {
+	switch {
+	case c.publisher != nil:
+		return []factory.Controller{managedcluster.NewCloudEventsStatusController(
+			c.ClusterName, c.AgentName,
+			c.publisher,
+			c.hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+			c.spokeKubeClient.Discovery(),
+			c.spokeKubeInformerFactory.Core().V1().Nodes(),
+			c.clusterHealthCheckPeriod,
+			c.recorder,
+		)}, nil
+	case c.ClaimsOnly:
+		// report the configured claims as the status of the endpoint, which is not a Kubernetes cluster
+		return []factory.Controller{managedcluster.NewClaimsOnlyStatusController(
+			c.ClusterName,
+			c.hubClusterClient,
+			c.hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+			c.Claims,
+			c.clusterHealthCheckPeriod,
+			c.recorder,
+		)}, nil
+	}
+	return []factory.Controller{managedcluster.NewManagedClusterStatusController(
+		c.ClusterName,
+		c.hubClusterClient,
+		c.hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+		c.spokeKubeClient.Discovery(),
+		c.spokeKubeInformerFactory.Core().V1().Nodes(),
+		c.clusterHealthCheckPeriod,
+		c.recorder,
+	)}, nil
+}
+
+// newReregistrationController creates the controller discarding the hub credentials on request, the agent exits
+// afterwards and re-runs bootstrap once it is restarted
+func newReregistrationController(c *spokeControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{managedcluster.NewReregistrationController(
+		c.hubKubeconfigDir(), c.ComponentNamespace, c.HubKubeconfigSecret,
+		c.managementKubeClient.CoreV1(),
+		c.namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+		c.reregistered,
+		c.recorder,
+	)}, nil
+}
+
+// newHubStateController creates the controller mirroring the state of the managed cluster on the hub with the
+// local events
+func newHubStateController(c *spokeControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{managedcluster.NewHubStateController(
+		c.ClusterName,
+		c.hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+		c.recorder,
+	)}, nil
+}
+
+// newRenameController creates the controller re-registering with the new name once the managed cluster is renamed
+// on the hub
+func newRenameController(c *spokeControllerContext) ([]factory.Controller, error) {
+	return []factory.Controller{managedcluster.NewRenameController(
+		c.ClusterName,
+		!c.clusterNameConfigured,
+		c.ComponentNamespace, c.HubKubeconfigSecret,
+		c.managementKubeClient.CoreV1(),
+		c.hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+		c.recorder,
+	)}, nil
+}
+
+// newHubPermissionController creates the controller reporting a drift of the rbac of the agent on the hub, which is
+// not reached with the kube-apiserver of the hub when the status is published to the broker
+func newHubPermissionController(c *spokeControllerContext) ([]factory.Controller, error) {
+	if c.publisher != nil {
+		return nil, nil
+	}
+	csrRequired := len(c.SpiffeEndpointSocket) == 0 &&
+		(len(c.RegistrationDriver) == 0 || c.RegistrationDriver == helpers.CSRRegistrationDriver)
+	return []factory.Controller{managedcluster.NewHubPermissionController(
+		c.ClusterName,
+		csrRequired,
+		c.hubKubeClient,
+		c.hubClusterClient,
+		c.hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+		c.recorder,
+	)}, nil
+}
+
+// newManagedClusterClaimController creates the controller syncing the cluster claims, it is not created in the
+// claims-only mode
+func newManagedClusterClaimController(c *spokeControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultSpokeMutableFeatureGate.Enabled(features.ClusterClaim) || c.ClaimsOnly {
+		return nil, nil
+	}
+	var claimTemplates *managedcluster.ClaimTemplateRenderer
+	if len(c.ClaimTemplates) > 0 {
+		var err error
+		claimTemplates, err = managedcluster.NewClaimTemplateRenderer(
+			c.ClaimTemplates,
+			c.spokeKubeClient.Discovery(),
+			c.spokeKubeInformerFactory.Core().V1().Nodes().Lister(),
+			c.spokeKubeClient.CoreV1().Namespaces(),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return []factory.Controller{managedcluster.NewManagedClusterClaimController(
+		c.ClusterName,
+		c.MaxCustomClusterClaims,
+		claimTemplates,
+		c.hubClusterClient,
+		c.hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+		c.spokeClusterInformerFactory.Cluster().V1alpha1().ClusterClaims(),
+		c.spokeKubeInformerFactory.Core().V1().Nodes(),
+		c.recorder,
+	)}, nil
+}
+
+// newClusterPropertyController creates the controller bridging the ClusterProperties with the cluster claims, it
+// is not created in the claims-only mode
+func newClusterPropertyController(c *spokeControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultSpokeMutableFeatureGate.Enabled(features.ClusterProperty) || c.ClaimsOnly {
+		return nil, nil
+	}
+	spokeDynamicClient, err := dynamic.NewForConfig(c.spokeClientConfig)
+	if err != nil {
+		return nil, err
+	}
+	return []factory.Controller{managedcluster.NewClusterPropertyController(
+		c.spokeClusterClient,
+		spokeDynamicClient,
+		c.spokeClusterInformerFactory.Cluster().V1alpha1().ClusterClaims(),
+		c.WriteBackClusterProperties,
+		c.recorder,
+	)}, nil
+}
+
+// newReverseTunnelCertController creates the controller bootstrapping the client certificate of the reverse
+// tunnel agent on the managed cluster
+func newReverseTunnelCertController(c *spokeControllerContext) ([]factory.Controller, error) {
+	if !c.BootstrapReverseTunnel {
+		return nil, nil
+	}
+	controller, err := managedcluster.NewReverseTunnelCertController(
+		c.ClusterName, c.AgentName, c.ReverseTunnelSignerName,
+		c.ReverseTunnelSecretNamespace, c.ReverseTunnelSecretName,
+		c.reverseTunnelKubeInformerFactory.Core().V1().Secrets(),
+		c.hubKubeInformerFactory.Certificates(),
+		c.spokeKubeClient,
+		c.hubKubeClient,
+		c.recorder,
+		fmt.Sprintf("ClientCertController@reverse-tunnel:%s", c.ClusterName),
+		c.clientCertOptions()...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return []factory.Controller{controller}, nil
+}
+
+// newHubCABundleController creates the controller updating the CA of the hub kubeconfig with the hub CA bundle
+// published on the hub, the agent exits afterwards and is restarted with the new CA
+func newHubCABundleController(c *spokeControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultSpokeMutableFeatureGate.Enabled(features.HubCARotation) {
+		return nil, nil
+	}
+	return []factory.Controller{managedcluster.NewHubCABundleController(
+		c.ClusterName, c.hubKubeconfigDir(), c.ComponentNamespace, c.HubKubeconfigSecret,
+		c.managementKubeClient.CoreV1(),
+		c.namespacedHubKubeInformerFactory.Core().V1().ConfigMaps(),
+		c.hubClusterClient,
+		c.hubCABundleChanged,
+		c.recorder,
+	)}, nil
+}
+
+// newAgentConfigController creates the controller applying the agent settings published by the hub, e.g. the log
+// level, while the agent runs
+func newAgentConfigController(c *spokeControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultSpokeMutableFeatureGate.Enabled(features.AgentConfigPropagation) {
+		return nil, nil
+	}
+	return []factory.Controller{managedcluster.NewAgentConfigController(
+		c.ClusterName,
+		c.namespacedHubKubeInformerFactory.Core().V1().ConfigMaps(),
+		func(ctx context.Context, settings *helpers.AgentSettings) error {
+			return c.applyHubSettings(settings)
+		},
+		c.recorder,
+	)}, nil
+}
+
+// newAddOnControllers creates the controllers managing the addons on the managed cluster, they are not created in
+// the claims-only mode or if the addon registration is disabled
+func newAddOnControllers(c *spokeControllerContext) ([]factory.Controller, error) {
+	if !features.DefaultSpokeMutableFeatureGate.Enabled(features.AddonManagement) || c.ClaimsOnly ||
+		c.DisableAddOnRegistration {
+		return nil, nil
+	}
+	return []factory.Controller{
+		addon.NewManagedClusterAddOnLeaseController(
+			c.ClusterName,
+			c.addOnClient,
+			c.addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns(),
+			c.hubKubeClient.CoordinationV1(),
+			c.spokeKubeClient.CoordinationV1(),
+			features.DefaultSpokeMutableFeatureGate.Enabled(features.AggregatedAddOnHeartbeat),
+			c.leaseRenewer,
+			AddOnLeaseControllerSyncInterval, //TODO: this interval time should be allowed to change from outside
+			c.recorder,
+		),
+		addon.NewAddOnRegistrationController(
+			c.ClusterName,
+			c.AgentName,
+			c.kubeconfigData,
+			// TODO(zhujian7): By now, we only support all addon agents running on the managed cluster.
+			// In the future we need to maintain the hub cluster kubeconfig secret on the **management**
+			// cluster when there is an appropriate way to deploy addon agents on the management cluster.
+			c.spokeKubeClient,
+			c.hubKubeInformerFactory.Certificates(),
+			c.addOnClient,
+			c.addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns(),
+			c.namespacedHubKubeInformerFactory.Core().V1().ConfigMaps(),
+			c.hubKubeClient,
+			c.MaxConcurrentAddOnRegistrations,
+			c.AddOnRegistrationStaggerInterval,
+			c.signerChecker(),
+			c.recorder,
+			c.clientCertOptions()...,
+		),
+		addon.NewAddOnSecretJanitorController(
+			c.ClusterName,
+			c.spokeKubeClient,
+			c.addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns(),
+			c.recorder,
+		),
+	}, nil
+}
@@ -0,0 +1,60 @@
+package spoke
+
+import (
+	"testing"
+
+	"open-cluster-management.io/registration/pkg/cloudevents"
+)
+
+func TestSpokeControllersNotRequired(t *testing.T) {
+	cases := []struct {
+		name           string
+		context        *spokeControllerContext
+		newControllers newSpokeControllersFunc
+	}{
+		{
+			name:           "addon controllers with the addon registration disabled",
+			context:        &spokeControllerContext{SpokeAgentOptions: &SpokeAgentOptions{DisableAddOnRegistration: true}},
+			newControllers: newAddOnControllers,
+		},
+		{
+			name:           "addon controllers in the claims-only mode",
+			context:        &spokeControllerContext{SpokeAgentOptions: &SpokeAgentOptions{ClaimsOnly: true}},
+			newControllers: newAddOnControllers,
+		},
+		{
+			name:           "cluster claim controller in the claims-only mode",
+			context:        &spokeControllerContext{SpokeAgentOptions: &SpokeAgentOptions{ClaimsOnly: true}},
+			newControllers: newManagedClusterClaimController,
+		},
+		{
+			name:           "cluster property controller in the claims-only mode",
+			context:        &spokeControllerContext{SpokeAgentOptions: &SpokeAgentOptions{ClaimsOnly: true}},
+			newControllers: newClusterPropertyController,
+		},
+		{
+			name: "hub permission controller with the status published to the broker",
+			context: &spokeControllerContext{
+				SpokeAgentOptions: &SpokeAgentOptions{},
+				publisher:         cloudevents.NewMQTTClient(cloudevents.MQTTConfig{}),
+			},
+			newControllers: newHubPermissionController,
+		},
+		{
+			name:           "reverse tunnel cert controller without the reverse tunnel bootstrapped",
+			context:        &spokeControllerContext{SpokeAgentOptions: &SpokeAgentOptions{}},
+			newControllers: newReverseTunnelCertController,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			controllers, err := c.newControllers(c.context)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(controllers) != 0 {
+				t.Errorf("expected no controller, but got %d", len(controllers))
+			}
+		})
+	}
+}
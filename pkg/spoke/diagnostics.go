@@ -0,0 +1,114 @@
+package spoke
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/diagnostics"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// DiagnosticsSources returns the sources of the objects in the diagnostics bundle of the agent. The hub kubeconfig
+// secret and the events of the agent are read from the cluster the agent runs on with the kubeconfig, and the
+// managed cluster, its recent conditions, addons, csrs and lease are read from the hub with the hub kubeconfig in
+// the directory the agent materialized the hub kubeconfig secret in. The addons are not read if the addon registration is disabled.
+func (o *SpokeAgentOptions) DiagnosticsSources(kubeConfig *rest.Config) ([]diagnostics.Source, error) {
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	namespace := o.ComponentNamespace
+	if len(namespace) == 0 {
+		namespace = componentNamespace()
+	}
+
+	sources := []diagnostics.Source{
+		func(ctx context.Context, bundle *diagnostics.Bundle) error {
+			secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, o.HubKubeconfigSecret, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("unable to get the hub kubeconfig secret %s/%s: %w", namespace, o.HubKubeconfigSecret, err)
+			}
+			return bundle.AddObject("hub-kubeconfig-secret.yaml", secret)
+		},
+		func(ctx context.Context, bundle *diagnostics.Bundle) error {
+			events, err := kubeClient.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("unable to list the events in %s: %w", namespace, err)
+			}
+			return bundle.AddObject("events.yaml", events)
+		},
+	}
+
+	hubKubeconfigPath := path.Join(o.materializedHubKubeconfigDir(), clientcert.KubeconfigFile)
+	if _, err := os.Stat(hubKubeconfigPath); err != nil {
+		return append(sources, func(ctx context.Context, bundle *diagnostics.Bundle) error {
+			return fmt.Errorf("unable to collect the objects on the hub, the hub kubeconfig is not found: %w", err)
+		}), nil
+	}
+	hubKubeConfig, err := clientcmd.BuildConfigFromFlags("", hubKubeconfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load hub kubeconfig from file %q: %w", hubKubeconfigPath, err)
+	}
+	hubKubeClient, err := kubernetes.NewForConfig(hubKubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	hubClusterClient, err := clusterv1client.NewForConfig(hubKubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	addOnClient, err := addonclient.NewForConfig(hubKubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterName, _ := o.getOrGenerateClusterAgentNames()
+	// the agent may not be allowed to list the addons if the addon registration is disabled
+	if !o.DisableAddOnRegistration {
+		sources = append(sources, func(ctx context.Context, bundle *diagnostics.Bundle) error {
+			addOns, err := addOnClient.AddonV1alpha1().ManagedClusterAddOns(clusterName).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("unable to list the addons of managed cluster %q: %w", clusterName, err)
+			}
+			return bundle.AddObject("managedclusteraddons.yaml", addOns)
+		})
+	}
+	return append(sources,
+		func(ctx context.Context, bundle *diagnostics.Bundle) error {
+			cluster, err := hubClusterClient.ClusterV1().ManagedClusters().Get(ctx, clusterName, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("unable to get managed cluster %q: %w", clusterName, err)
+			}
+			conditions := map[string][]metav1.Condition{"managedcluster/" + cluster.Name: cluster.Status.Conditions}
+			if err := bundle.AddYAML("conditions.yaml", diagnostics.RecentConditions(conditions, diagnostics.DefaultConditionLimit)); err != nil {
+				return err
+			}
+			return bundle.AddObject("managedcluster.yaml", cluster)
+		},
+		func(ctx context.Context, bundle *diagnostics.Bundle) error {
+			csrs, err := hubKubeClient.CertificatesV1().CertificateSigningRequests().List(ctx, metav1.ListOptions{
+				LabelSelector: fmt.Sprintf("%s=%s", clientcert.ClusterNameLabel, clusterName),
+			})
+			if err != nil {
+				return fmt.Errorf("unable to list the csrs of managed cluster %q: %w", clusterName, err)
+			}
+			return bundle.AddObject("certificatesigningrequests.yaml", csrs)
+		},
+		func(ctx context.Context, bundle *diagnostics.Bundle) error {
+			leases, err := hubKubeClient.CoordinationV1().Leases(clusterName).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("unable to list the leases of managed cluster %q: %w", clusterName, err)
+			}
+			return bundle.AddObject("leases.yaml", leases)
+		},
+	), nil
+}
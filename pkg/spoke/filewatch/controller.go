@@ -0,0 +1,97 @@
+package filewatch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const controllerName = "FileWatchController"
+
+// PollInterval is the interval the files are checked for changes in. The files mounted from secrets and configmaps
+// are replaced with a symlink swap by the kubelet, so the files are polled instead of watched.
+var PollInterval = 30 * time.Second
+
+// fileWatchController detects the changes of the files, and calls onChange with the changed file once any of them
+// is changed, created or removed.
+type fileWatchController struct {
+	files    []string
+	digests  map[string]string
+	changed  bool
+	onChange func(file string)
+}
+
+// NewFileWatchController returns an instance of fileWatchController. The digests are the Digests of the files when
+// the agent starts.
+func NewFileWatchController(digests map[string]string, onChange func(file string), recorder events.Recorder) factory.Controller {
+	c := &fileWatchController{
+		files:    sets.StringKeySet(digests).List(),
+		digests:  digests,
+		onChange: onChange,
+	}
+
+	return factory.New().
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ResyncEvery(PollInterval).
+		ToController(controllerName, recorder)
+}
+
+func (c *fileWatchController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	if c.changed {
+		return nil
+	}
+
+	for _, file := range c.files {
+		digest, err := Digest(file)
+		if err != nil {
+			return err
+		}
+		if digest == c.digests[file] {
+			continue
+		}
+
+		c.changed = true
+		helpers.ControllerLogger(ctx, controllerName).Info("The watched file is changed", "file", file)
+		syncCtx.Recorder().Eventf("WatchedFileChanged", "The watched file %q is changed, the agent is restarting", file)
+		c.onChange(file)
+		return nil
+	}
+	return nil
+}
+
+// Digests returns the digests of the files
+func Digests(files []string) (map[string]string, error) {
+	digests := map[string]string{}
+	for _, file := range files {
+		digest, err := Digest(file)
+		if err != nil {
+			return nil, err
+		}
+		digests[file] = digest
+	}
+	return digests, nil
+}
+
+// Digest returns the digest of the content of a file, it is empty if the file does not exist
+func Digest(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	switch {
+	case os.IsNotExist(err):
+		return "", nil
+	case err != nil:
+		return "", fmt.Errorf("unable to read the watched file %q: %w", file, err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,85 @@
+package filewatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestSync(t *testing.T) {
+	cases := []struct {
+		name            string
+		changeFiles     func(t *testing.T, dir string)
+		expectedChanged string
+	}{
+		{
+			name:        "not changed",
+			changeFiles: func(t *testing.T, dir string) {},
+		},
+		{
+			name: "ca bundle rotated",
+			changeFiles: func(t *testing.T, dir string) {
+				writeFile(t, path.Join(dir, "ca.crt"), "ca2")
+			},
+			expectedChanged: "ca.crt",
+		},
+		{
+			name: "proxy certificate created",
+			changeFiles: func(t *testing.T, dir string) {
+				writeFile(t, path.Join(dir, "proxy.crt"), "proxy")
+			},
+			expectedChanged: "proxy.crt",
+		},
+		{
+			name: "ca bundle removed",
+			changeFiles: func(t *testing.T, dir string) {
+				if err := os.Remove(path.Join(dir, "ca.crt")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expectedChanged: "ca.crt",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, path.Join(dir, "ca.crt"), "ca1")
+			digests, err := Digests([]string{path.Join(dir, "ca.crt"), path.Join(dir, "proxy.crt")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.changeFiles(t, dir)
+
+			changes := []string{}
+			ctrl := &fileWatchController{
+				files:    []string{path.Join(dir, "ca.crt"), path.Join(dir, "proxy.crt")},
+				digests:  digests,
+				onChange: func(file string) { changes = append(changes, path.Base(file)) },
+			}
+			// the change is only reported once
+			for i := 0; i < 2; i++ {
+				if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey)); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+			switch {
+			case len(c.expectedChanged) == 0 && len(changes) > 0:
+				t.Errorf("expected no change, but got %v", changes)
+			case len(c.expectedChanged) > 0 && (len(changes) != 1 || changes[0] != c.expectedChanged):
+				t.Errorf("expected the change of %q reported once, but got %v", c.expectedChanged, changes)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, name, data string) {
+	if err := ioutil.WriteFile(name, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,4 @@
+// package filewatch contains the controller which restarts the agent once any of the files injected into the agent
+// pod is changed, e.g. a CA bundle, the certificates of a proxy or a configuration file, which are read only when
+// the agent starts.
+package filewatch
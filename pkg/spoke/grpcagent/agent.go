@@ -0,0 +1,151 @@
+package grpcagent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/grpcregistration"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/spoke/managedcluster"
+)
+
+// DefaultLeaseDuration is the interval the agent heartbeats if the hub does not return the lease duration of
+// the cluster
+const DefaultLeaseDuration = 60 * time.Second
+
+// RegisterInterval is the interval the agent registers the cluster until it is accepted by the hub. It is exposed
+// so that integration tests can shorten it.
+var RegisterInterval = 10 * time.Second
+
+// WaitForAcceptance registers the cluster with req every RegisterInterval until the hub accepts it or the
+// context is done. The failures of the calls are recorded and retried.
+func WaitForAcceptance(ctx context.Context, client grpcregistration.RegistrationClient, req *grpcregistration.RegisterRequest,
+	recorder events.Recorder) error {
+	logger := helpers.ControllerLogger(ctx, "grpc-registration")
+	registered := false
+	return wait.PollImmediateUntil(RegisterInterval, func() (bool, error) {
+		resp, err := client.Register(ctx, req)
+		if err != nil {
+			recorder.Warningf("ManagedClusterRegistrationFailed", "Unable to register managed cluster %q: %v",
+				req.ClusterName, err)
+			return false, nil
+		}
+		if !registered {
+			registered = true
+			recorder.Eventf("ManagedClusterRegistered", "Managed cluster %q is registered to the hub", req.ClusterName)
+		}
+		if !resp.Accepted {
+			logger.V(helpers.LogLevelDebug).Info("Managed cluster is not accepted by the hub yet",
+				helpers.LogKeyCluster, req.ClusterName, helpers.LogKeyReason, resp.Message)
+			return false, nil
+		}
+		recorder.Eventf("ManagedClusterAccepted", "Managed cluster %q is accepted by the hub", req.ClusterName)
+		return true, nil
+	}, ctx.Done())
+}
+
+// heartbeatController renews the lease of the cluster on the hub over the Registration service
+type heartbeatController struct {
+	clusterName string
+	agentName   string
+	client      grpcregistration.RegistrationClient
+}
+
+// NewHeartbeatController returns a controller which heartbeats the accepted cluster in the lease duration
+// returned by the hub, or DefaultLeaseDuration if the hub does not return it.
+func NewHeartbeatController(clusterName, agentName string, client grpcregistration.RegistrationClient,
+	recorder events.Recorder) factory.Controller {
+	c := &heartbeatController{
+		clusterName: clusterName,
+		agentName:   agentName,
+		client:      client,
+	}
+
+	return factory.New().
+		WithSync(health.WrapSync("GRPCHeartbeatController", c.sync)).
+		ResyncEvery(DefaultLeaseDuration).
+		ToController("GRPCHeartbeatController", recorder)
+}
+
+func (c *heartbeatController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	health.EnterPhase(ctx, "heartbeat")
+	resp, err := c.client.Heartbeat(ctx, &grpcregistration.HeartbeatRequest{
+		ClusterName: c.clusterName,
+		AgentName:   c.agentName,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to heartbeat managed cluster %q: %w", c.clusterName, err)
+	}
+
+	// heartbeat again in the lease duration of the hub if it is shorter than the resync interval
+	if leaseDuration := time.Duration(resp.LeaseDurationSeconds) * time.Second; leaseDuration > 0 && leaseDuration < DefaultLeaseDuration {
+		syncCtx.Queue().AddAfter(syncCtx.QueueKey(), leaseDuration)
+	}
+	return nil
+}
+
+// statusController reports the status of the cluster to the hub over the Registration service
+type statusController struct {
+	clusterName     string
+	agentName       string
+	client          grpcregistration.RegistrationClient
+	statusCollector managedcluster.StatusCollector
+
+	// conditions are the conditions last reported, the transition time of the available condition is kept while
+	// its status does not change
+	lock       sync.Mutex
+	conditions []metav1.Condition
+}
+
+// NewStatusController returns a controller which reports the status of the accepted cluster collected with
+// statusCollector every resyncInterval.
+func NewStatusController(clusterName, agentName string, client grpcregistration.RegistrationClient,
+	statusCollector managedcluster.StatusCollector, resyncInterval func() time.Duration,
+	recorder events.Recorder) factory.Controller {
+	c := &statusController{
+		clusterName:     clusterName,
+		agentName:       agentName,
+		client:          client,
+		statusCollector: statusCollector,
+	}
+
+	return factory.New().
+		WithSync(health.WrapSync("GRPCStatusController", c.sync)).
+		WithPostStartHooks(health.ResyncEvery(resyncInterval)).
+		ToController("GRPCStatusController", recorder)
+}
+
+func (c *statusController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	condition, status, err := c.statusCollector.Collect(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to collect status of managed cluster %q: %w", c.clusterName, err)
+	}
+	if status == nil {
+		status = &clusterv1.ManagedClusterStatus{}
+	}
+
+	c.lock.Lock()
+	meta.SetStatusCondition(&c.conditions, condition)
+	status.Conditions = append([]metav1.Condition{}, c.conditions...)
+	c.lock.Unlock()
+
+	health.EnterPhase(ctx, "report status")
+	if _, err := c.client.ReportStatus(ctx, &grpcregistration.ReportStatusRequest{
+		ClusterName: c.clusterName,
+		AgentName:   c.agentName,
+		Status:      grpcregistration.NewClusterStatus(*status),
+	}); err != nil {
+		return fmt.Errorf("unable to report status of managed cluster %q: %w", c.clusterName, err)
+	}
+	return nil
+}
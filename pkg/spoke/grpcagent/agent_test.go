@@ -0,0 +1,172 @@
+package grpcagent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"google.golang.org/grpc"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/grpcregistration"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+	"open-cluster-management.io/registration/pkg/spoke/managedcluster"
+)
+
+// fakeRegistrationClient accepts the cluster after acceptAfter registrations, and records the reported status
+type fakeRegistrationClient struct {
+	acceptAfter   int
+	registrations int
+	heartbeatErr  error
+	leaseDuration int32
+	status        *clusterv1.ManagedClusterStatus
+}
+
+func (c *fakeRegistrationClient) Register(ctx context.Context, req *grpcregistration.RegisterRequest,
+	opts ...grpc.CallOption) (*grpcregistration.RegisterResponse, error) {
+	c.registrations++
+	if c.registrations <= c.acceptAfter {
+		return &grpcregistration.RegisterResponse{Message: "waiting for approval"}, nil
+	}
+	return &grpcregistration.RegisterResponse{Accepted: true}, nil
+}
+
+func (c *fakeRegistrationClient) Heartbeat(ctx context.Context, req *grpcregistration.HeartbeatRequest,
+	opts ...grpc.CallOption) (*grpcregistration.HeartbeatResponse, error) {
+	if c.heartbeatErr != nil {
+		return nil, c.heartbeatErr
+	}
+	return &grpcregistration.HeartbeatResponse{LeaseDurationSeconds: c.leaseDuration}, nil
+}
+
+func (c *fakeRegistrationClient) ReportStatus(ctx context.Context, req *grpcregistration.ReportStatusRequest,
+	opts ...grpc.CallOption) (*grpcregistration.ReportStatusResponse, error) {
+	status, err := grpcregistration.ToManagedClusterStatus(req.Status)
+	if err != nil {
+		return nil, err
+	}
+	c.status = &status
+	return &grpcregistration.ReportStatusResponse{}, nil
+}
+
+func TestWaitForAcceptance(t *testing.T) {
+	interval := RegisterInterval
+	RegisterInterval = 10 * time.Millisecond
+	defer func() { RegisterInterval = interval }()
+
+	client := &fakeRegistrationClient{acceptAfter: 2}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := WaitForAcceptance(ctx, client, &grpcregistration.RegisterRequest{ClusterName: "cluster1", AgentName: "agent1"},
+		eventstesting.NewTestingEventRecorder(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.registrations != 3 {
+		t.Errorf("expected the cluster to be accepted on the 3rd registration, but got %d", client.registrations)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	cases := []struct {
+		name        string
+		client      *fakeRegistrationClient
+		expectedErr string
+	}{
+		{
+			name:        "heartbeat failed",
+			client:      &fakeRegistrationClient{heartbeatErr: errors.New("unavailable")},
+			expectedErr: "unable to heartbeat managed cluster \"cluster1\": unavailable",
+		},
+		{
+			name:   "default lease duration",
+			client: &fakeRegistrationClient{},
+		},
+		{
+			name:   "lease duration of the hub",
+			client: &fakeRegistrationClient{leaseDuration: 10},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := &heartbeatController{clusterName: "cluster1", agentName: "agent1", client: c.client}
+			err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1"))
+			testinghelpers.AssertError(t, err, c.expectedErr)
+		})
+	}
+}
+
+func TestReportStatus(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/livez":
+			w.WriteHeader(http.StatusOK)
+		case "/version":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(version.Info{GitVersion: "test-version"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer apiServer.Close()
+
+	kubeClient := kubefake.NewSimpleClientset([]runtime.Object{
+		testinghelpers.NewNode("testnode1", testinghelpers.NewResourceList(32, 64), testinghelpers.NewResourceList(16, 32)),
+	}...)
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, time.Minute*10)
+	nodeStore := kubeInformerFactory.Core().V1().Nodes().Informer().GetStore()
+	nodes, _ := kubeClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	for i := range nodes.Items {
+		if err := nodeStore.Add(&nodes.Items[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := &fakeRegistrationClient{}
+	ctrl := &statusController{
+		clusterName: "cluster1",
+		agentName:   "agent1",
+		client:      client,
+		statusCollector: managedcluster.NewClusterStatusCollector(
+			discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{Host: apiServer.URL}),
+			kubeInformerFactory.Core().V1().Nodes().Lister()),
+	}
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.status == nil {
+		t.Fatalf("expected the status to be reported")
+	}
+	testinghelpers.AssertManagedClusterCondition(t, client.status.Conditions, metav1.Condition{
+		Type:    clusterv1.ManagedClusterConditionAvailable,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ManagedClusterAvailable",
+		Message: "Managed cluster is available",
+	})
+	if client.status.Version.Kubernetes != "test-version" {
+		t.Errorf("expected the version to be reported, but got %v", client.status.Version)
+	}
+	transitionTime := client.status.Conditions[0].LastTransitionTime
+
+	// the transition time is kept while the condition does not change
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !client.status.Conditions[0].LastTransitionTime.Equal(&transitionTime) {
+		t.Errorf("expected the transition time %v, but got %v", transitionTime, client.status.Conditions[0].LastTransitionTime)
+	}
+}
@@ -0,0 +1,4 @@
+// package grpcagent runs the registration of the agent over the gRPC Registration service of the hub, see
+// grpcregistration, for the hubs which are not Kubernetes clusters. The agent registers the cluster until the hub
+// accepts it, then heartbeats and reports the status of the cluster to the hub periodically.
+package grpcagent
@@ -0,0 +1,59 @@
+package spoke
+
+import (
+	"context"
+	"time"
+
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// The informers below are registered with cache transforms, which drop the managed fields and the data the agent
+// never reads. The informer factories do not pass their list options to the registered informers, so the options
+// of a factory are passed along with it.
+
+func installNodeInformerTransforms(spokeInformers informers.SharedInformerFactory) {
+	spokeInformers.InformerFor(&corev1.Node{},
+		func(client kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			nodes := client.CoreV1().Nodes()
+			return helpers.NewTransformingInformer(
+				func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+					return nodes.List(ctx, options)
+				},
+				nodes.Watch,
+				&corev1.Node{},
+				resyncPeriod,
+				helpers.TransformNode,
+			)
+		})
+}
+
+func installHubClusterInformerTransforms(hubInformers clusterv1informers.SharedInformerFactory, tweakListOptions func(*metav1.ListOptions)) {
+	hubInformers.InformerFor(&clusterv1.ManagedCluster{},
+		func(client clusterv1client.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+			clusters := client.ClusterV1().ManagedClusters()
+			return helpers.NewTransformingInformer(
+				func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+					tweakListOptions(&options)
+					return clusters.List(ctx, options)
+				},
+				func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+					tweakListOptions(&options)
+					return clusters.Watch(ctx, options)
+				},
+				&clusterv1.ManagedCluster{},
+				resyncPeriod,
+				helpers.StripManagedFields,
+			)
+		})
+}
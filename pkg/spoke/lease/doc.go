@@ -0,0 +1,3 @@
+// package lease contains the renewer of the leases of the agent on the hub, which is shared by the controllers of
+// the agent, so the heartbeats of the managed cluster and its addons are written in batched passes of one routine.
+package lease
@@ -0,0 +1,238 @@
+package lease
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+const leaseUpdateJitterFactor = 0.25
+
+// leaseUpdateRetryInterval is the interval a failed renewal is retried after, it is cut to the jitter of the lease
+// duration, so a transient error does not make the hub consider the cluster unavailable.
+const leaseUpdateRetryInterval = 5 * time.Second
+
+// renewedLease is a lease renewed by the Renewer
+type renewedLease struct {
+	duration time.Duration
+	// due is the time the lease is renewed at the latest
+	due time.Time
+}
+
+// Renewer renews the leases of the agent on the hub with one client in periodic passes of a single routine,
+// instead of an independent ticker per lease. A pass renews all of the leases which are due within the jitter of
+// their durations, so the leases with close due times are renewed in the same pass. It is shared by the
+// controllers of the agent, the annotations they set on the leases are written in the next pass along with the
+// renewal of the leases.
+type Renewer struct {
+	hubClient clientset.Interface
+	recorder  events.Recorder
+	now       func() time.Time
+
+	lock   sync.Mutex
+	leases map[types.NamespacedName]*renewedLease
+	// annotations are the annotations of the leases written in the next pass
+	annotations map[types.NamespacedName]map[string]string
+	// changed wakes up the renewal routine once a lease is added, its duration is changed or it is annotated
+	changed   chan struct{}
+	startOnce sync.Once
+}
+
+// NewRenewer returns a Renewer which renews the leases with the hub client
+func NewRenewer(hubClient clientset.Interface, recorder events.Recorder) *Renewer {
+	return &Renewer{
+		hubClient:   hubClient,
+		recorder:    recorder,
+		now:         time.Now,
+		leases:      map[types.NamespacedName]*renewedLease{},
+		annotations: map[types.NamespacedName]map[string]string{},
+		changed:     make(chan struct{}, 1),
+	}
+}
+
+// Start starts the renewal routine with the context, it is a no-op once the routine is started.
+func (r *Renewer) Start(ctx context.Context) {
+	r.startOnce.Do(func() {
+		go r.run(ctx)
+	})
+}
+
+// Renew adds a lease which is renewed with the given duration, the lease is renewed in the next pass if it is
+// added or its duration is changed.
+func (r *Renewer) Renew(namespace, name string, duration time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	if lease, ok := r.leases[key]; ok && lease.duration == duration {
+		return
+	}
+	r.leases[key] = &renewedLease{duration: duration}
+	r.recorder.Eventf("ManagedClusterLeaseUpdateStarted", "Start to update lease %q on cluster %q", name, namespace)
+	r.wakeUp()
+}
+
+// Stop stops renewing a lease
+func (r *Renewer) Stop(namespace, name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	if _, ok := r.leases[key]; !ok {
+		return
+	}
+	delete(r.leases, key)
+	r.recorder.Eventf("ManagedClusterLeaseUpdateStoped", "Stop to update lease %q on cluster %q", name, namespace)
+}
+
+// Annotate sets an annotation of a lease in the next pass. The lease is renewed in the same patch if it is renewed
+// by the Renewer, so the annotation costs no extra request. The annotation is retried along with the lease if the
+// patch fails, unless it is set again in the meantime.
+func (r *Renewer) Annotate(namespace, name, key, value string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	leaseKey := types.NamespacedName{Namespace: namespace, Name: name}
+	if _, ok := r.annotations[leaseKey]; !ok {
+		r.annotations[leaseKey] = map[string]string{}
+	}
+	r.annotations[leaseKey][key] = value
+	r.wakeUp()
+}
+
+// wakeUp wakes up the renewal routine, the lock is held by the caller
+func (r *Renewer) wakeUp() {
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Renewer) run(ctx context.Context) {
+	for {
+		var timer *time.Timer
+		var next <-chan time.Time
+		if period, ok := r.renewDue(ctx); ok {
+			timer = time.NewTimer(period)
+			next = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-r.changed:
+		case <-next:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// renewDue renews the leases due in the current pass along with the annotated leases, and returns the period until
+// the next pass. It returns false if there is no lease to renew. The due time of a lease only moves forward once
+// it is renewed, a failed renewal is retried after leaseUpdateRetryInterval.
+func (r *Renewer) renewDue(ctx context.Context) (time.Duration, bool) {
+	r.lock.Lock()
+	now := r.now()
+	renewed := map[types.NamespacedName]bool{}
+	for key, lease := range r.leases {
+		_, annotated := r.annotations[key]
+		if annotated || !lease.due.After(now.Add(time.Duration(float64(lease.duration)*leaseUpdateJitterFactor))) {
+			renewed[key] = true
+		}
+	}
+	annotations := r.annotations
+	r.annotations = map[types.NamespacedName]map[string]string{}
+	r.lock.Unlock()
+
+	failed := map[types.NamespacedName]bool{}
+	for key := range renewed {
+		if err := r.update(ctx, key, true, annotations[key]); err != nil {
+			failed[key] = true
+		}
+	}
+	for key, leaseAnnotations := range annotations {
+		if renewed[key] {
+			continue
+		}
+		if err := r.update(ctx, key, false, leaseAnnotations); err != nil {
+			failed[key] = true
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	now = r.now()
+	var next time.Time
+	for key := range failed {
+		// the annotations set again in the meantime are newer than the failed ones
+		for name, value := range annotations[key] {
+			if _, ok := r.annotations[key]; !ok {
+				r.annotations[key] = map[string]string{}
+			}
+			if _, ok := r.annotations[key][name]; !ok {
+				r.annotations[key][name] = value
+			}
+		}
+		if _, ok := r.leases[key]; !ok && len(annotations[key]) > 0 {
+			next = now.Add(leaseUpdateRetryInterval)
+		}
+	}
+	for key, lease := range r.leases {
+		switch {
+		case failed[key]:
+			retry := time.Duration(float64(lease.duration) * leaseUpdateJitterFactor)
+			if retry > leaseUpdateRetryInterval {
+				retry = leaseUpdateRetryInterval
+			}
+			lease.due = now.Add(retry)
+		case renewed[key]:
+			lease.due = now.Add(wait.Jitter(lease.duration, leaseUpdateJitterFactor))
+		}
+		if next.IsZero() || lease.due.Before(next) {
+			next = lease.due
+		}
+	}
+	if next.IsZero() {
+		return 0, false
+	}
+	return next.Sub(now), true
+}
+
+// update the renew time and the annotations of a lease. Only the changed fields are sent to the hub with a json
+// merge patch, the lease is not read before the update and no conflict is possible. The error is logged and
+// returned, so the caller retries the lease.
+func (r *Renewer) update(ctx context.Context, key types.NamespacedName, renew bool, annotations map[string]string) error {
+	fields := map[string]interface{}{}
+	if renew {
+		fields["spec"] = map[string]interface{}{"renewTime": metav1.MicroTime{Time: r.now()}}
+	}
+	if len(annotations) > 0 {
+		fields["metadata"] = map[string]interface{}{"annotations": annotations}
+	}
+	patch, err := json.Marshal(fields)
+	if err != nil {
+		err = fmt.Errorf("unable to create the patch of lease %q: %w", key, err)
+		utilruntime.HandleError(err)
+		return err
+	}
+	if _, err = r.hubClient.CoordinationV1().Leases(key.Namespace).Patch(ctx, key.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		err = fmt.Errorf("unable to update lease %q on hub cluster: %w", key, err)
+		utilruntime.HandleError(err)
+		return err
+	}
+	return nil
+}
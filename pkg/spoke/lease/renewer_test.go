@@ -0,0 +1,147 @@
+package lease
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	coordv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestLeaseRenewer(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	hubClient := kubefake.NewSimpleClientset(
+		testinghelpers.NewAddOnLease("cluster1", "lease1", now), testinghelpers.NewAddOnLease("cluster1", "lease2", now))
+	renewer := NewRenewer(hubClient, eventstesting.NewTestingEventRecorder(t))
+	renewer.now = func() time.Time { return now }
+
+	renewedLeases := func() []string {
+		names := []string{}
+		for _, action := range hubClient.Actions() {
+			names = append(names, action.(clienttesting.PatchAction).GetName())
+		}
+		sort.Strings(names)
+		hubClient.ClearActions()
+		return names
+	}
+
+	// the added leases are renewed in the first pass
+	renewer.Renew("cluster1", "lease1", time.Minute)
+	renewer.Renew("cluster1", "lease2", time.Minute)
+	period, ok := renewer.renewDue(context.TODO())
+	if !ok {
+		t.Fatalf("expected the next pass to be scheduled")
+	}
+	if period < time.Minute || period > time.Minute+time.Minute/4 {
+		t.Errorf("expected the next pass in a jittered minute, but got %v", period)
+	}
+	if names := renewedLeases(); !reflect.DeepEqual(names, []string{"lease1", "lease2"}) {
+		t.Errorf("expected both leases to be renewed in the first pass, but got %v", names)
+	}
+
+	// the leases due within the jitter are renewed in the same pass
+	now = now.Add(period)
+	if _, ok := renewer.renewDue(context.TODO()); !ok {
+		t.Fatalf("expected the next pass to be scheduled")
+	}
+	if names := renewedLeases(); !reflect.DeepEqual(names, []string{"lease1", "lease2"}) {
+		t.Errorf("expected both leases to be renewed in the same pass, but got %v", names)
+	}
+
+	// a lease is renewed right away once its duration is changed, and is not renewed once it is stopped
+	renewer.Renew("cluster1", "lease1", 2*time.Minute)
+	renewer.Stop("cluster1", "lease2")
+	if _, ok := renewer.renewDue(context.TODO()); !ok {
+		t.Fatalf("expected the next pass to be scheduled")
+	}
+	if names := renewedLeases(); !reflect.DeepEqual(names, []string{"lease1"}) {
+		t.Errorf("expected lease1 to be renewed, but got %v", names)
+	}
+
+	// an annotated lease is renewed along with the annotation in the next pass, even if it is not due
+	renewer.Annotate("cluster1", "lease1", "summary", "{}")
+	if _, ok := renewer.renewDue(context.TODO()); !ok {
+		t.Fatalf("expected the next pass to be scheduled")
+	}
+	actions := hubClient.Actions()
+	if names := renewedLeases(); !reflect.DeepEqual(names, []string{"lease1"}) {
+		t.Fatalf("expected lease1 to be renewed, but got %v", names)
+	}
+	patched := &coordv1.Lease{}
+	if err := json.Unmarshal(actions[0].(clienttesting.PatchAction).GetPatch(), patched); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patched.Annotations["summary"] != "{}" || patched.Spec.RenewTime == nil {
+		t.Errorf("expected the annotation to be patched with the renew time, but got %v", patched)
+	}
+
+	renewer.Stop("cluster1", "lease1")
+	if _, ok := renewer.renewDue(context.TODO()); ok {
+		t.Errorf("expected no pass to be scheduled without leases")
+	}
+}
+
+func TestLeaseRenewerRetry(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	hubClient := kubefake.NewSimpleClientset(testinghelpers.NewAddOnLease("cluster1", "lease1", now))
+	renewer := NewRenewer(hubClient, eventstesting.NewTestingEventRecorder(t))
+	renewer.now = func() time.Time { return now }
+
+	failing := true
+	hubClient.PrependReactor("patch", "leases", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if failing {
+			return true, nil, fmt.Errorf("connection refused")
+		}
+		return false, nil, nil
+	})
+
+	// a failed renewal is retried shortly instead of a lease duration later, with the annotations of the lease
+	renewer.Renew("cluster1", "lease1", time.Minute)
+	renewer.Annotate("cluster1", "lease1", "summary", "{}")
+	period, ok := renewer.renewDue(context.TODO())
+	if !ok {
+		t.Fatalf("expected the next pass to be scheduled")
+	}
+	if period != leaseUpdateRetryInterval {
+		t.Errorf("expected the failed renewal to be retried in %v, but got %v", leaseUpdateRetryInterval, period)
+	}
+
+	failing = false
+	hubClient.ClearActions()
+	now = now.Add(period)
+	if _, ok := renewer.renewDue(context.TODO()); !ok {
+		t.Fatalf("expected the next pass to be scheduled")
+	}
+	actions := hubClient.Actions()
+	if len(actions) != 1 {
+		t.Fatalf("expected the lease to be renewed again, but got %v", actions)
+	}
+	patched := &coordv1.Lease{}
+	if err := json.Unmarshal(actions[0].(clienttesting.PatchAction).GetPatch(), patched); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patched.Annotations["summary"] != "{}" || patched.Spec.RenewTime == nil {
+		t.Errorf("expected the annotation to be retried with the renew time, but got %v", patched)
+	}
+
+	// the lease is not due until the next period once it is renewed
+	hubClient.ClearActions()
+	now = now.Add(leaseUpdateRetryInterval)
+	if _, ok := renewer.renewDue(context.TODO()); !ok {
+		t.Fatalf("expected the next pass to be scheduled")
+	}
+	if actions := hubClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected no renewal before the lease is due, but got %v", actions)
+	}
+}
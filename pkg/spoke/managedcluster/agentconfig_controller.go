@@ -0,0 +1,99 @@
+package managedcluster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const agentConfigControllerName = "AgentConfigController"
+
+// agentConfigController applies the agent settings published by the hub in the agent config configmap in the
+// cluster namespace, see helpers.AgentConfigConfigMapName. The settings are applied once they are changed, and
+// the settings failed to parse or to apply are reported once until they are changed again. The settings removed
+// from the configmap, or the configmap removed, are reset by applying the empty settings.
+type agentConfigController struct {
+	clusterName     string
+	configMapLister corev1listers.ConfigMapLister
+	apply           func(ctx context.Context, settings *helpers.AgentSettings) error
+	digest          string
+}
+
+// NewAgentConfigController returns an instance of agentConfigController. The configmap informer should watch the
+// namespace of the cluster on the hub.
+func NewAgentConfigController(
+	clusterName string,
+	configMapInformer corev1informers.ConfigMapInformer,
+	apply func(ctx context.Context, settings *helpers.AgentSettings) error,
+	recorder events.Recorder) factory.Controller {
+	c := &agentConfigController{
+		clusterName:     clusterName,
+		configMapLister: configMapInformer.Lister(),
+		apply:           apply,
+		digest:          agentConfigDigest(map[string]string{}),
+	}
+
+	return factory.New().
+		WithFilteredEventsInformers(func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			return accessor.GetNamespace() == clusterName && accessor.GetName() == helpers.AgentConfigConfigMapName
+		}, configMapInformer.Informer()).
+		WithSync(health.WrapSync(agentConfigControllerName, c.sync)).
+		ToController(agentConfigControllerName, recorder)
+}
+
+func (c *agentConfigController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	data := map[string]string{}
+	configMap, err := c.configMapLister.ConfigMaps(c.clusterName).Get(helpers.AgentConfigConfigMapName)
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		return err
+	default:
+		data = configMap.Data
+	}
+
+	digest := agentConfigDigest(data)
+	if digest == c.digest {
+		return nil
+	}
+	c.digest = digest
+
+	settings, err := helpers.ParseAgentSettings(data)
+	if err != nil {
+		syncCtx.Recorder().Warningf("AgentConfigInvalid",
+			"The agent settings published by the hub are invalid, the agent runs with the previous ones: %v", err)
+		return nil
+	}
+	if err := c.apply(ctx, settings); err != nil {
+		syncCtx.Recorder().Warningf("AgentConfigApplyFailed",
+			"Failed to apply the agent settings published by the hub, the agent runs with the previous ones: %v", err)
+		return nil
+	}
+	helpers.ControllerLogger(ctx, agentConfigControllerName).Info("The agent settings published by the hub are applied",
+		helpers.LogKeyCluster, c.clusterName)
+	syncCtx.Recorder().Eventf("AgentConfigApplied", "The agent settings published by the hub are applied")
+	return nil
+}
+
+// agentConfigDigest returns the digest of the data of the agent config configmap, the keys of the data are sorted
+// when it is marshalled
+func agentConfigDigest(data map[string]string) string {
+	raw, _ := json.Marshal(data)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
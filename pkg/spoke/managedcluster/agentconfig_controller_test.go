@@ -0,0 +1,102 @@
+package managedcluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestAgentConfigSync(t *testing.T) {
+	newConfigMap := func(data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testinghelpers.TestManagedClusterName, Name: helpers.AgentConfigConfigMapName},
+			Data:       data,
+		}
+	}
+
+	cases := []struct {
+		name             string
+		configMap        *corev1.ConfigMap
+		appliedData      map[string]string
+		applyErr         error
+		expectedApplied  bool
+		expectedSettings *helpers.AgentSettings
+	}{
+		{
+			name: "no settings",
+		},
+		{
+			name:             "apply the settings",
+			configMap:        newConfigMap(map[string]string{helpers.AgentConfigLogLevelKey: "4"}),
+			expectedApplied:  true,
+			expectedSettings: &helpers.AgentSettings{LogLevel: pointer.Int32(4)},
+		},
+		{
+			name:        "settings not changed",
+			configMap:   newConfigMap(map[string]string{helpers.AgentConfigLogLevelKey: "4"}),
+			appliedData: map[string]string{helpers.AgentConfigLogLevelKey: "4"},
+		},
+		{
+			name:             "reset the settings removed",
+			appliedData:      map[string]string{helpers.AgentConfigLogLevelKey: "4"},
+			expectedApplied:  true,
+			expectedSettings: &helpers.AgentSettings{},
+		},
+		{
+			name:      "invalid settings",
+			configMap: newConfigMap(map[string]string{helpers.AgentConfigLogLevelKey: "debug"}),
+		},
+		{
+			name:             "failed to apply the settings",
+			configMap:        newConfigMap(map[string]string{helpers.AgentConfigFeatureGatesKey: "AddonManagement=true"}),
+			applyErr:         fmt.Errorf("feature AddonManagement may not be set from the hub"),
+			expectedApplied:  true,
+			expectedSettings: &helpers.AgentSettings{FeatureGates: map[string]bool{"AddonManagement": true}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), time.Minute*10)
+			if c.configMap != nil {
+				if err := kubeInformerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(c.configMap); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if c.appliedData == nil {
+				c.appliedData = map[string]string{}
+			}
+
+			var applied *helpers.AgentSettings
+			ctrl := &agentConfigController{
+				clusterName:     testinghelpers.TestManagedClusterName,
+				configMapLister: kubeInformerFactory.Core().V1().ConfigMaps().Lister(),
+				apply: func(ctx context.Context, settings *helpers.AgentSettings) error {
+					applied = settings
+					return c.applyErr
+				},
+				digest: agentConfigDigest(c.appliedData),
+			}
+			if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (applied != nil) != c.expectedApplied {
+				t.Fatalf("expected settings applied %v, but got %v", c.expectedApplied, applied)
+			}
+			if c.expectedApplied && !reflect.DeepEqual(applied, c.expectedSettings) {
+				t.Errorf("expected settings %+v, but got %+v", c.expectedSettings, applied)
+			}
+		})
+	}
+}
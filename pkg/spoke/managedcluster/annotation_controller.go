@@ -0,0 +1,86 @@
+package managedcluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// addOnRegistrationAnnotationController keeps the addon registration disabled annotation of the ManagedCluster on
+// the hub in sync with the agent. The annotation is only set on the ManagedCluster created by the agent otherwise,
+// so the hub would not unbind the addon role of the agent once the addon registration is disabled for an existing
+// cluster, or bind it again once the addon registration is enabled.
+type addOnRegistrationAnnotationController struct {
+	clusterName               string
+	addOnRegistrationDisabled bool
+	hubClusterClient          clientset.Interface
+	hubClusterLister          clusterv1listers.ManagedClusterLister
+}
+
+// NewAddOnRegistrationAnnotationController creates a new addon registration annotation controller on the managed
+// cluster.
+func NewAddOnRegistrationAnnotationController(
+	clusterName string,
+	addOnRegistrationDisabled bool,
+	hubClusterClient clientset.Interface,
+	hubManagedClusterInformer clusterv1informer.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &addOnRegistrationAnnotationController{
+		clusterName:               clusterName,
+		addOnRegistrationDisabled: addOnRegistrationDisabled,
+		hubClusterClient:          hubClusterClient,
+		hubClusterLister:          hubManagedClusterInformer.Lister(),
+	}
+
+	return factory.New().
+		WithInformers(hubManagedClusterInformer.Informer()).
+		WithSync(health.WrapSync("AddOnRegistrationAnnotationController", c.sync)).
+		ResyncEvery(5*time.Minute).
+		ToController("AddOnRegistrationAnnotationController", recorder)
+}
+
+func (c *addOnRegistrationAnnotationController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	managedCluster, err := c.hubClusterLister.Get(c.clusterName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get managed cluster with name %q from hub: %w", c.clusterName, err)
+	}
+
+	annotated := managedCluster.Annotations[helpers.AddOnRegistrationDisabledAnnotation] == "true"
+	if annotated == c.addOnRegistrationDisabled {
+		return nil
+	}
+
+	managedCluster = managedCluster.DeepCopy()
+	if c.addOnRegistrationDisabled {
+		if managedCluster.Annotations == nil {
+			managedCluster.Annotations = map[string]string{}
+		}
+		managedCluster.Annotations[helpers.AddOnRegistrationDisabledAnnotation] = "true"
+	} else {
+		delete(managedCluster.Annotations, helpers.AddOnRegistrationDisabledAnnotation)
+	}
+
+	_, err = c.hubClusterClient.ClusterV1().ManagedClusters().Update(ctx, managedCluster, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to update the annotations of managed cluster %q: %w", c.clusterName, err)
+	}
+	syncCtx.Recorder().Eventf("AddOnRegistrationAnnotationUpdated",
+		"Addon registration disabled annotation of managed cluster %q is updated to %v", c.clusterName,
+		c.addOnRegistrationDisabled)
+	return nil
+}
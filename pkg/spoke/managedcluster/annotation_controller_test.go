@@ -0,0 +1,94 @@
+package managedcluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestSyncAddOnRegistrationAnnotation(t *testing.T) {
+	annotatedCluster := func() *clusterv1.ManagedCluster {
+		cluster := testinghelpers.NewAcceptedManagedCluster()
+		cluster.Annotations = map[string]string{helpers.AddOnRegistrationDisabledAnnotation: "true"}
+		return cluster
+	}
+
+	cases := []struct {
+		name                      string
+		startingObjects           []runtime.Object
+		addOnRegistrationDisabled bool
+		validateActions           func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:            "sync no managed cluster",
+			startingObjects: []runtime.Object{},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "addon registration enabled",
+			startingObjects: []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:                      "addon registration disabled for an existing cluster",
+			startingObjects:           []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			addOnRegistrationDisabled: true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				cluster := actions[0].(clienttesting.UpdateActionImpl).Object.(*clusterv1.ManagedCluster)
+				if cluster.Annotations[helpers.AddOnRegistrationDisabledAnnotation] != "true" {
+					t.Errorf("expected the addon registration disabled annotation, but got %v", cluster.Annotations)
+				}
+			},
+		},
+		{
+			name:                      "addon registration disabled",
+			startingObjects:           []runtime.Object{annotatedCluster()},
+			addOnRegistrationDisabled: true,
+			validateActions:           testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "addon registration enabled again",
+			startingObjects: []runtime.Object{annotatedCluster()},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				cluster := actions[0].(clienttesting.UpdateActionImpl).Object.(*clusterv1.ManagedCluster)
+				if _, ok := cluster.Annotations[helpers.AddOnRegistrationDisabledAnnotation]; ok {
+					t.Errorf("expected no addon registration disabled annotation, but got %v", cluster.Annotations)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.startingObjects...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+			for _, cluster := range c.startingObjects {
+				clusterStore.Add(cluster)
+			}
+
+			ctrl := addOnRegistrationAnnotationController{
+				clusterName:               testinghelpers.TestManagedClusterName,
+				addOnRegistrationDisabled: c.addOnRegistrationDisabled,
+				hubClusterClient:          clusterClient,
+				hubClusterLister:          clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+			}
+
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
+			testinghelpers.AssertError(t, syncErr, "")
+
+			c.validateActions(t, clusterClient.Actions())
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package managedcluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// AWSIAMAuthenticatorCommand is the exec credential plugin the hub kubeconfig of the aws iam registration driver
+// gets the tokens of the IAM role with
+const AWSIAMAuthenticatorCommand = "aws-iam-authenticator"
+
+// AWSIAMSyncInterval is the interval the agent checks whether its IAM role is mapped by the hub. It is exposed so
+// that integration tests can shorten it.
+var AWSIAMSyncInterval = time.Minute
+
+// awsIAMForHubController saves a kubeconfig authenticating to the hub with the IAM role of the agent in the hub
+// kubeconfig secret, once the hub has mapped the role to the agent.
+type awsIAMForHubController struct {
+	clusterName      string
+	agentName        string
+	roleARN          string
+	secretNamespace  string
+	secretName       string
+	kubeconfigData   []byte
+	hubClusterClient clientset.Interface
+	spokeCoreClient  corev1client.CoreV1Interface
+	controllerName   string
+}
+
+// NewAWSIAMForHubController returns a controller which keeps the kubeconfig of the aws iam registration driver in
+// the hub kubeconfig secret. The managed cluster is read with the hubClusterClient, which is the client of the
+// bootstrap kubeconfig until the cluster joins the hub.
+func NewAWSIAMForHubController(
+	clusterName string,
+	agentName string,
+	roleARN string,
+	secretNamespace string,
+	secretName string,
+	kubeconfigData []byte,
+	spokeSecretInformer corev1informers.SecretInformer,
+	spokeCoreClient corev1client.CoreV1Interface,
+	hubClusterClient clientset.Interface,
+	recorder events.Recorder,
+	controllerName string,
+) factory.Controller {
+	c := &awsIAMForHubController{
+		clusterName:      clusterName,
+		agentName:        agentName,
+		roleARN:          roleARN,
+		secretNamespace:  secretNamespace,
+		secretName:       secretName,
+		kubeconfigData:   kubeconfigData,
+		hubClusterClient: hubClusterClient,
+		spokeCoreClient:  spokeCoreClient,
+		controllerName:   controllerName,
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			// only enqueue the hub kubeconfig secret
+			return accessor.GetNamespace() == secretNamespace && accessor.GetName() == secretName
+		}, spokeSecretInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ResyncEvery(AWSIAMSyncInterval).
+		ToController(controllerName, recorder)
+}
+
+func (c *awsIAMForHubController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	cluster, err := c.hubClusterClient.ClusterV1().ManagedClusters().Get(ctx, c.clusterName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err), errors.IsForbidden(err):
+		helpers.ControllerLogger(ctx, c.controllerName).V(helpers.LogLevelDebug).Info("Waiting for the managed cluster",
+			helpers.LogKeyCluster, c.clusterName, helpers.LogKeyReason, err.Error())
+		return nil
+	case err != nil:
+		return fmt.Errorf("unable to get managed cluster %q: %w", c.clusterName, err)
+	}
+	// the role is mapped once the cluster is accepted by the hub
+	if cluster.Annotations[helpers.AWSIAMRoleMappedAnnotation] != c.roleARN {
+		helpers.ControllerLogger(ctx, c.controllerName).V(helpers.LogLevelDebug).Info("Waiting for the IAM role to be mapped",
+			helpers.LogKeyCluster, c.clusterName, "roleARN", c.roleARN)
+		return nil
+	}
+
+	secret, err := c.spokeCoreClient.Secrets(c.secretNamespace).Get(ctx, c.secretName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		secret = nil
+	case err != nil:
+		return fmt.Errorf("unable to get secret %q: %w", c.secretNamespace+"/"+c.secretName, err)
+	}
+
+	// only the fields managed by the controller are applied, the others are kept
+	appliedSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: c.secretNamespace,
+			Name:      c.secretName,
+		},
+		Data: map[string][]byte{
+			clientcert.ClusterNameFile: []byte(c.clusterName),
+			clientcert.AgentNameFile:   []byte(c.agentName),
+			clientcert.KubeconfigFile:  c.kubeconfigData,
+		},
+	}
+	if _, err := helpers.ApplySecret(ctx, c.spokeCoreClient, secret, appliedSecret); err != nil {
+		return err
+	}
+
+	if secret == nil || !bytes.Equal(secret.Data[clientcert.KubeconfigFile], c.kubeconfigData) {
+		syncCtx.Recorder().Eventf("AWSIAMKubeconfigUpdated", "The kubeconfig authenticating with IAM role %q is saved in secret %s/%s",
+			c.roleARN, c.secretNamespace, c.secretName)
+	}
+	return nil
+}
+
+// BuildAWSIAMKubeconfig builds a kubeconfig based on a rest config template, which authenticates to the EKS hub
+// with the IAM role. The agent name is the session name of the role, which the hub maps to the username of the agent.
+func BuildAWSIAMKubeconfig(clientConfig *rest.Config, hubClusterName, roleARN, agentName string) clientcmdapi.Config {
+	return clientcert.BuildExecKubeconfig(clientConfig, &clientcmdapi.ExecConfig{
+		APIVersion: clientcert.ExecPluginAPIVersion,
+		Command:    AWSIAMAuthenticatorCommand,
+		Args:       []string{"token", "-i", hubClusterName, "-r", roleARN, "--session-name", agentName},
+		InstallHint: AWSIAMAuthenticatorCommand + " is required to authenticate to the hub with the IAM role, see " +
+			"https://docs.aws.amazon.com/eks/latest/userguide/install-aws-iam-authenticator.html",
+	})
+}
@@ -0,0 +1,130 @@
+package managedcluster
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+const testRoleARN = "arn:aws:iam::123456789012:role/ocm-testmanagedcluster"
+
+func TestAWSIAMForHubSync(t *testing.T) {
+	kubeconfigData := testinghelpers.NewKubeconfig(nil, nil)
+	newMappedCluster := func(roleARN string) *clusterv1.ManagedCluster {
+		cluster := testinghelpers.NewAcceptedManagedCluster()
+		cluster.Annotations = map[string]string{helpers.AWSIAMRoleMappedAnnotation: roleARN}
+		return cluster
+	}
+
+	cases := []struct {
+		name            string
+		clusters        []runtime.Object
+		spokeSecrets    []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name: "cluster not created",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "role not mapped",
+			clusters: []runtime.Object{testinghelpers.NewAcceptingManagedCluster()},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "another role mapped",
+			clusters: []runtime.Object{newMappedCluster("arn:aws:iam::123456789012:role/other")},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "role mapped",
+			clusters: []runtime.Object{newMappedCluster(testRoleARN)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				secret := testinghelpers.AppliedSecret(t, actions[1])
+				if string(secret.Data[clientcert.KubeconfigFile]) != string(kubeconfigData) {
+					t.Errorf("expected the kubeconfig is saved, but got %q", string(secret.Data[clientcert.KubeconfigFile]))
+				}
+			},
+		},
+		{
+			name:     "saved kubeconfig",
+			clusters: []runtime.Object{newMappedCluster(testRoleARN)},
+			spokeSecrets: []runtime.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: testNamespace,
+					Name:      testSecretName,
+					UID:       "3e5f7a9b-1c2d-4e6f-8a0b-2c4d6e8f0a1b",
+				},
+				Data: map[string][]byte{
+					clientcert.ClusterNameFile: []byte(testinghelpers.TestManagedClusterName),
+					clientcert.AgentNameFile:   []byte("agent1"),
+					clientcert.KubeconfigFile:  kubeconfigData,
+				},
+			}},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hubClusterClient := clusterfake.NewSimpleClientset(c.clusters...)
+			spokeKubeClient := kubefake.NewSimpleClientset(c.spokeSecrets...)
+			testinghelpers.AddSecretApplyReactor(&spokeKubeClient.Fake, spokeKubeClient.Tracker())
+
+			ctrl := &awsIAMForHubController{
+				clusterName:      testinghelpers.TestManagedClusterName,
+				agentName:        "agent1",
+				roleARN:          testRoleARN,
+				secretNamespace:  testNamespace,
+				secretName:       testSecretName,
+				kubeconfigData:   kubeconfigData,
+				hubClusterClient: hubClusterClient,
+				spokeCoreClient:  spokeKubeClient.CoreV1(),
+				controllerName:   "test",
+			}
+
+			if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "test")); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			c.validateActions(t, spokeKubeClient.Actions())
+		})
+	}
+}
+
+func TestBuildAWSIAMKubeconfig(t *testing.T) {
+	kubeconfig := BuildAWSIAMKubeconfig(&rest.Config{Host: "https://hub.eks.amazonaws.com"}, "hub", testRoleARN, "agent1")
+	exec := kubeconfig.AuthInfos["default-auth"].Exec
+	if exec == nil || exec.Command != AWSIAMAuthenticatorCommand {
+		t.Fatalf("expected the kubeconfig to authenticate with %s, but got %v", AWSIAMAuthenticatorCommand, exec)
+	}
+	expectedArgs := []string{"token", "-i", "hub", "-r", testRoleARN, "--session-name", "agent1"}
+	if len(exec.Args) != len(expectedArgs) {
+		t.Fatalf("expected args %v, but got %v", expectedArgs, exec.Args)
+	}
+	for i := range expectedArgs {
+		if exec.Args[i] != expectedArgs[i] {
+			t.Errorf("expected args %v, but got %v", expectedArgs, exec.Args)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"k8s.io/apimachinery/pkg/selection"
 	"sort"
+	"time"
 
 	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
@@ -13,6 +14,7 @@ import (
 	clusterv1alpha1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1alpha1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+	"open-cluster-management.io/registration/pkg/health"
 	"open-cluster-management.io/registration/pkg/helpers"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
@@ -22,10 +24,16 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
-	"k8s.io/klog/v2"
+	corev1informers "k8s.io/client-go/informers/core/v1"
 )
 
-const labelCustomizedOnly = "open-cluster-management.io/spoke-only"
+const (
+	labelCustomizedOnly = "open-cluster-management.io/spoke-only"
+
+	// claimTemplateResyncInterval is the interval the templated claims are rendered in, since the changes of the
+	// namespaces and the server version are not watched
+	claimTemplateResyncInterval = 5 * time.Minute
+)
 
 // managedClusterClaimController exposes cluster claims created on managed cluster on hub after it joins the hub.
 type managedClusterClaimController struct {
@@ -34,31 +42,40 @@ type managedClusterClaimController struct {
 	hubClusterLister       clusterv1listers.ManagedClusterLister
 	claimLister            clusterv1alpha1listers.ClusterClaimLister
 	maxCustomClusterClaims int
+	claimTemplates         *ClaimTemplateRenderer
 }
 
 // NewManagedClusterClaimController creates a new managed cluster claim controller on the managed cluster.
+// The claimTemplates is optional, the claims rendered from the templates are exposed along with the cluster claims,
+// and they are rendered again once the nodes change or every claimTemplateResyncInterval.
 func NewManagedClusterClaimController(
 	clusterName string,
 	maxCustomClusterClaims int,
+	claimTemplates *ClaimTemplateRenderer,
 	hubClusterClient clientset.Interface,
 	hubManagedClusterInformer clusterv1informer.ManagedClusterInformer,
 	claimInformer clusterv1alpha1informer.ClusterClaimInformer,
+	nodeInformer corev1informers.NodeInformer,
 	recorder events.Recorder) factory.Controller {
 	c := &managedClusterClaimController{
 		clusterName:            clusterName,
 		maxCustomClusterClaims: maxCustomClusterClaims,
+		claimTemplates:         claimTemplates,
 		hubClusterClient:       hubClusterClient,
 		hubClusterLister:       hubManagedClusterInformer.Lister(),
 		claimLister:            claimInformer.Lister(),
 	}
 
-	return factory.New().
+	f := factory.New().
 		WithInformers(claimInformer.Informer()).
 		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
 			accessor, _ := meta.Accessor(obj)
 			return accessor.GetName()
-		}, hubManagedClusterInformer.Informer()).
-		WithSync(c.sync).
+		}, hubManagedClusterInformer.Informer())
+	if claimTemplates != nil {
+		f = f.WithInformers(nodeInformer.Informer()).ResyncEvery(claimTemplateResyncInterval)
+	}
+	return f.WithSync(health.WrapSync("ClusterClaimController", c.sync)).
 		ToController("ClusterClaimController", recorder)
 }
 
@@ -107,6 +124,23 @@ func (c managedClusterClaimController) exposeClaims(ctx context.Context, syncCtx
 		customClaims = append(customClaims, managedClusterClaim)
 	}
 
+	// the cluster claims take precedence over the templated claims of the same names
+	if c.claimTemplates != nil {
+		claimNames := sets.NewString()
+		for _, clusterClaim := range clusterClaims {
+			claimNames.Insert(clusterClaim.Name)
+		}
+		templatedClaims, errs := c.claimTemplates.Render(ctx)
+		for _, name := range sets.StringKeySet(errs).List() {
+			syncCtx.Recorder().Warningf("ClusterClaimTemplateFailed", "Unable to render the value of cluster claim %q: %v", name, errs[name])
+		}
+		for _, claim := range templatedClaims {
+			if !claimNames.Has(claim.Name) {
+				customClaims = append(customClaims, claim)
+			}
+		}
+	}
+
 	// sort claims by name
 	sort.SliceStable(reservedClaims, func(i, j int) bool {
 		return reservedClaims[i].Name < reservedClaims[j].Name
@@ -131,12 +165,22 @@ func (c managedClusterClaimController) exposeClaims(ctx context.Context, syncCtx
 		ClusterClaims: claims,
 	})}
 
-	_, updated, err := helpers.UpdateManagedClusterStatus(ctx, c.hubClusterClient, c.clusterName, updateStatusFuncs...)
+	// skip the claims the hub already has, the claims of a cluster may be many
+	upToDate, err := helpers.ClusterStatusUpToDate(&managedCluster.Status, updateStatusFuncs...)
+	if err != nil {
+		return err
+	}
+	if upToDate {
+		return nil
+	}
+
+	_, updated, err := helpers.PatchManagedClusterStatus(ctx, c.hubClusterClient, c.clusterName, updateStatusFuncs...)
 	if err != nil {
 		return fmt.Errorf("unable to update status of managed cluster %q: %w", c.clusterName, err)
 	}
 	if updated {
-		klog.V(4).Infof("The cluster claims in status of managed cluster %q has been updated", c.clusterName)
+		helpers.ControllerLogger(ctx, "ClusterClaimController").V(helpers.LogLevelChange).Info(
+			"The cluster claims in status of managed cluster has been updated", helpers.LogKeyCluster, c.clusterName)
 	}
 	return nil
 }
@@ -49,15 +49,15 @@ func TestSync(t *testing.T) {
 				},
 			},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "get", "update")
-				cluster := actions[1].(clienttesting.UpdateActionImpl).Object
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				cluster := testinghelpers.PatchedManagedCluster(t, actions[1])
 				expected := []clusterv1.ManagedClusterClaim{
 					{
 						Name:  "a",
 						Value: "b",
 					},
 				}
-				actual := cluster.(*clusterv1.ManagedCluster).Status.ClusterClaims
+				actual := cluster.Status.ClusterClaims
 				if !reflect.DeepEqual(actual, expected) {
 					t.Errorf("expected cluster claim %v but got: %v", expected, actual)
 				}
@@ -103,6 +103,7 @@ func TestExposeClaims(t *testing.T) {
 		name                   string
 		cluster                *clusterv1.ManagedCluster
 		claims                 []*clusterv1alpha1.ClusterClaim
+		claimTemplates         map[string]string
 		maxCustomClusterClaims int
 		validateActions        func(t *testing.T, actions []clienttesting.Action)
 		expectedErr            string
@@ -121,15 +122,15 @@ func TestExposeClaims(t *testing.T) {
 				},
 			},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "get", "update")
-				cluster := actions[1].(clienttesting.UpdateActionImpl).Object
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				cluster := testinghelpers.PatchedManagedCluster(t, actions[1])
 				expected := []clusterv1.ManagedClusterClaim{
 					{
 						Name:  "a",
 						Value: "b",
 					},
 				}
-				actual := cluster.(*clusterv1.ManagedCluster).Status.ClusterClaims
+				actual := cluster.Status.ClusterClaims
 				if !reflect.DeepEqual(actual, expected) {
 					t.Errorf("expected cluster claim %v but got: %v", expected, actual)
 				}
@@ -174,8 +175,8 @@ func TestExposeClaims(t *testing.T) {
 			},
 			maxCustomClusterClaims: 2,
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "get", "update")
-				cluster := actions[1].(clienttesting.UpdateActionImpl).Object
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				cluster := testinghelpers.PatchedManagedCluster(t, actions[1])
 				expected := []clusterv1.ManagedClusterClaim{
 					{
 						Name:  "id.k8s.io",
@@ -190,7 +191,7 @@ func TestExposeClaims(t *testing.T) {
 						Value: "d",
 					},
 				}
-				actual := cluster.(*clusterv1.ManagedCluster).Status.ClusterClaims
+				actual := cluster.Status.ClusterClaims
 				if !reflect.DeepEqual(actual, expected) {
 					t.Errorf("expected cluster claim %v but got: %v", expected, actual)
 				}
@@ -205,9 +206,9 @@ func TestExposeClaims(t *testing.T) {
 				},
 			}),
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "get", "update")
-				cluster := actions[1].(clienttesting.UpdateActionImpl).Object
-				actual := cluster.(*clusterv1.ManagedCluster).Status.ClusterClaims
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				cluster := testinghelpers.PatchedManagedCluster(t, actions[1])
+				actual := cluster.Status.ClusterClaims
 				if len(actual) > 0 {
 					t.Errorf("expected no cluster claim but got: %v", actual)
 				}
@@ -236,15 +237,71 @@ func TestExposeClaims(t *testing.T) {
 				},
 			},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "get", "update")
-				cluster := actions[1].(clienttesting.UpdateActionImpl).Object
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				cluster := testinghelpers.PatchedManagedCluster(t, actions[1])
 				expected := []clusterv1.ManagedClusterClaim{
 					{
 						Name:  "c",
 						Value: "d",
 					},
 				}
-				actual := cluster.(*clusterv1.ManagedCluster).Status.ClusterClaims
+				actual := cluster.Status.ClusterClaims
+				if !reflect.DeepEqual(actual, expected) {
+					t.Errorf("expected cluster claim %v but got: %v", expected, actual)
+				}
+			},
+		},
+		{
+			name: "claims are up to date",
+			cluster: newManagedCluster([]clusterv1.ManagedClusterClaim{
+				{
+					Name:  "a",
+					Value: "b",
+				},
+			}),
+			claims: []*clusterv1alpha1.ClusterClaim{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "a",
+					},
+					Spec: clusterv1alpha1.ClusterClaimSpec{
+						Value: "b",
+					},
+				},
+			},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:    "expose templated claims",
+			cluster: testinghelpers.NewJoinedManagedCluster(),
+			claims: []*clusterv1alpha1.ClusterClaim{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "owner.open-cluster-management.io",
+					},
+					Spec: clusterv1alpha1.ClusterClaimSpec{
+						Value: "team-b",
+					},
+				},
+			},
+			claimTemplates: map[string]string{
+				"owner.open-cluster-management.io": `{{ .NamespaceAnnotation "kube-system" "owner" }}`,
+				"zones.open-cluster-management.io": `{{ .NodeLabel "topology.kubernetes.io/zone" }}`,
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				cluster := testinghelpers.PatchedManagedCluster(t, actions[1])
+				expected := []clusterv1.ManagedClusterClaim{
+					{
+						Name:  "owner.open-cluster-management.io",
+						Value: "team-b",
+					},
+					{
+						Name:  "zones.open-cluster-management.io",
+						Value: "zone-a,zone-b",
+					},
+				}
+				actual := cluster.Status.ClusterClaims
 				if !reflect.DeepEqual(actual, expected) {
 					t.Errorf("expected cluster claim %v but got: %v", expected, actual)
 				}
@@ -280,6 +337,9 @@ func TestExposeClaims(t *testing.T) {
 				hubClusterLister:       clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
 				claimLister:            clusterInformerFactory.Cluster().V1alpha1().ClusterClaims().Lister(),
 			}
+			if len(c.claimTemplates) > 0 {
+				ctrl.claimTemplates = newClaimTemplateRenderer(t, c.claimTemplates)
+			}
 
 			syncErr := ctrl.exposeClaims(context.TODO(), testinghelpers.NewFakeSyncContext(t, c.cluster.Name), c.cluster)
 			testinghelpers.AssertError(t, syncErr, c.expectedErr)
@@ -0,0 +1,131 @@
+package managedcluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	corev1lister "k8s.io/client-go/listers/core/v1"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// ParseClaimTemplate parses the template of the value of a claim, see ClaimTemplateRenderer
+func ParseClaimTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+// ClaimTemplateRenderer renders the claims whose values are templated from the data of the managed cluster, so the
+// dynamic metadata of the cluster is exposed without a ClusterClaim maintained by another controller. The templates
+// are in the syntax of text/template, and the following data is available:
+//   - .ServerVersion is the git version of the kube-apiserver, e.g. v1.24.0;
+//   - .NodeLabel "key" is the distinct values of the label on the nodes, sorted and joined with commas;
+//   - .NamespaceAnnotation "namespace" "key" is the value of the annotation on the namespace.
+//
+// A claim rendered to an empty value is not exposed.
+type ClaimTemplateRenderer struct {
+	templates       map[string]*template.Template
+	discoveryClient discovery.ServerVersionInterface
+	nodeLister      corev1lister.NodeLister
+	namespaces      corev1client.NamespaceInterface
+}
+
+// NewClaimTemplateRenderer returns a ClaimTemplateRenderer of the templates by the names of the claims
+func NewClaimTemplateRenderer(
+	templates map[string]string,
+	discoveryClient discovery.ServerVersionInterface,
+	nodeLister corev1lister.NodeLister,
+	namespaces corev1client.NamespaceInterface) (*ClaimTemplateRenderer, error) {
+	r := &ClaimTemplateRenderer{
+		templates:       map[string]*template.Template{},
+		discoveryClient: discoveryClient,
+		nodeLister:      nodeLister,
+		namespaces:      namespaces,
+	}
+	for name, text := range templates {
+		tmpl, err := ParseClaimTemplate(name, text)
+		if err != nil {
+			return nil, err
+		}
+		r.templates[name] = tmpl
+	}
+	return r, nil
+}
+
+// Render returns the rendered claims sorted by their names, the claims failed to render are returned in the errors
+// by their names
+func (r *ClaimTemplateRenderer) Render(ctx context.Context) ([]clusterv1.ManagedClusterClaim, map[string]error) {
+	claims := []clusterv1.ManagedClusterClaim{}
+	errs := map[string]error{}
+	data := &claimTemplateData{ctx: ctx, renderer: r}
+	for _, name := range r.names() {
+		value := &strings.Builder{}
+		if err := r.templates[name].Execute(value, data); err != nil {
+			errs[name] = err
+			continue
+		}
+		if value.Len() == 0 {
+			continue
+		}
+		claims = append(claims, clusterv1.ManagedClusterClaim{Name: name, Value: value.String()})
+	}
+	return claims, errs
+}
+
+func (r *ClaimTemplateRenderer) names() []string {
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// claimTemplateData is the data the claim templates are executed with, the server version is fetched once in a
+// rendering no matter how many templates refer to it
+type claimTemplateData struct {
+	ctx           context.Context
+	renderer      *ClaimTemplateRenderer
+	serverVersion string
+}
+
+func (d *claimTemplateData) ServerVersion() (string, error) {
+	if len(d.serverVersion) > 0 {
+		return d.serverVersion, nil
+	}
+	version, err := d.renderer.discoveryClient.ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("unable to get server version of the managed cluster: %w", err)
+	}
+	d.serverVersion = version.GitVersion
+	return d.serverVersion, nil
+}
+
+func (d *claimTemplateData) NodeLabel(key string) (string, error) {
+	nodes, err := d.renderer.nodeLister.List(labels.Everything())
+	if err != nil {
+		return "", fmt.Errorf("unable to list nodes of the managed cluster: %w", err)
+	}
+	values := sets.NewString()
+	for _, node := range nodes {
+		if value, ok := node.Labels[key]; ok && len(value) > 0 {
+			values.Insert(value)
+		}
+	}
+	return strings.Join(values.List(), ","), nil
+}
+
+func (d *claimTemplateData) NamespaceAnnotation(namespace, key string) (string, error) {
+	ns, err := d.renderer.namespaces.Get(d.ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("unable to get namespace %q of the managed cluster: %w", namespace, err)
+	}
+	return ns.Annotations[key], nil
+}
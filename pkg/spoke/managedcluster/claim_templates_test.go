@@ -0,0 +1,100 @@
+package managedcluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func newClaimTemplateRenderer(t *testing.T, templates map[string]string) *ClaimTemplateRenderer {
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"topology.kubernetes.io/zone": "zone-b"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"topology.kubernetes.io/zone": "zone-a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{"topology.kubernetes.io/zone": "zone-a"}}},
+	}
+	kubeClient := kubefake.NewSimpleClientset(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-system", Annotations: map[string]string{"owner": "team-a"}},
+	})
+	kubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.24.0"}
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+	for _, node := range nodes {
+		if err := kubeInformerFactory.Core().V1().Nodes().Informer().GetStore().Add(node); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	renderer, err := NewClaimTemplateRenderer(templates, kubeClient.Discovery(),
+		kubeInformerFactory.Core().V1().Nodes().Lister(), kubeClient.CoreV1().Namespaces())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return renderer
+}
+
+func TestRenderClaimTemplates(t *testing.T) {
+	cases := []struct {
+		name           string
+		templates      map[string]string
+		expectedClaims []clusterv1.ManagedClusterClaim
+		expectedErrs   []string
+	}{
+		{
+			name: "render claims",
+			templates: map[string]string{
+				"zones.open-cluster-management.io":   `{{ .NodeLabel "topology.kubernetes.io/zone" }}`,
+				"owner.open-cluster-management.io":   `{{ .NamespaceAnnotation "kube-system" "owner" }}`,
+				"version.open-cluster-management.io": `kube-{{ .ServerVersion }}`,
+			},
+			expectedClaims: []clusterv1.ManagedClusterClaim{
+				{Name: "owner.open-cluster-management.io", Value: "team-a"},
+				{Name: "version.open-cluster-management.io", Value: "kube-v1.24.0"},
+				{Name: "zones.open-cluster-management.io", Value: "zone-a,zone-b"},
+			},
+		},
+		{
+			name: "skip empty claims",
+			templates: map[string]string{
+				"region.open-cluster-management.io": `{{ .NodeLabel "topology.kubernetes.io/region" }}`,
+			},
+			expectedClaims: []clusterv1.ManagedClusterClaim{},
+		},
+		{
+			name: "namespace not found",
+			templates: map[string]string{
+				"owner.open-cluster-management.io": `{{ .NamespaceAnnotation "default" "owner" }}`,
+				"zones.open-cluster-management.io": `{{ .NodeLabel "topology.kubernetes.io/zone" }}`,
+			},
+			expectedClaims: []clusterv1.ManagedClusterClaim{
+				{Name: "zones.open-cluster-management.io", Value: "zone-a,zone-b"},
+			},
+			expectedErrs: []string{"owner.open-cluster-management.io"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			claims, errs := newClaimTemplateRenderer(t, c.templates).Render(context.TODO())
+			if !reflect.DeepEqual(claims, c.expectedClaims) {
+				t.Errorf("expected claims %v, but got %v", c.expectedClaims, claims)
+			}
+			if len(errs) != len(c.expectedErrs) {
+				t.Errorf("expected errors of %v, but got %v", c.expectedErrs, errs)
+			}
+			for _, name := range c.expectedErrs {
+				if _, ok := errs[name]; !ok {
+					t.Errorf("expected error of claim %q, but got %v", name, errs)
+				}
+			}
+		})
+	}
+}
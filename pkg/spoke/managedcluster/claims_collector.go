@@ -0,0 +1,43 @@
+package managedcluster
+
+import (
+	"context"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// ClaimsStatusCollector collects the status of an endpoint which is not a Kubernetes cluster, e.g. an edge gateway
+// or a VM, in the claims-only mode. The endpoint has no kube-apiserver to check and no nodes to count, so it is
+// available as long as its agent is running, and only the configured claims are reported.
+type ClaimsStatusCollector struct {
+	claims []clusterv1.ManagedClusterClaim
+}
+
+// NewClaimsStatusCollector returns a ClaimsStatusCollector reporting the claims by their names
+func NewClaimsStatusCollector(claims map[string]string) *ClaimsStatusCollector {
+	c := &ClaimsStatusCollector{claims: []clusterv1.ManagedClusterClaim{}}
+	for name, value := range claims {
+		c.claims = append(c.claims, clusterv1.ManagedClusterClaim{Name: name, Value: value})
+	}
+	sort.Slice(c.claims, func(i, j int) bool {
+		return c.claims[i].Name < c.claims[j].Name
+	})
+	return c
+}
+
+// Collect returns the available condition of the endpoint and the status with the claims
+func (c *ClaimsStatusCollector) Collect(ctx context.Context) (metav1.Condition, *clusterv1.ManagedClusterStatus, error) {
+	condition := metav1.Condition{
+		Type:    clusterv1.ManagedClusterConditionAvailable,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ManagedClusterAvailable",
+		Message: "The agent of the claims-only cluster is running",
+	}
+	status := &clusterv1.ManagedClusterStatus{
+		ClusterClaims: append([]clusterv1.ManagedClusterClaim{}, c.claims...),
+	}
+	return condition, status, nil
+}
@@ -0,0 +1,137 @@
+package managedcluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/cloudevents"
+	"open-cluster-management.io/registration/pkg/health"
+)
+
+// cloudEventsHeartbeatController publishes the heartbeats of the accepted managed cluster as CloudEvents, the hub
+// renews the lease of the cluster with them.
+type cloudEventsHeartbeatController struct {
+	clusterName      string
+	agentName        string
+	hubClusterLister clusterv1listers.ManagedClusterLister
+	publisher        cloudevents.Publisher
+}
+
+// NewCloudEventsHeartbeatController returns a controller which publishes a heartbeat event of the cluster in each
+// lease duration of the cluster once the cluster is accepted by the hub.
+func NewCloudEventsHeartbeatController(
+	clusterName, agentName string,
+	publisher cloudevents.Publisher,
+	hubClusterInformer clusterv1informer.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &cloudEventsHeartbeatController{
+		clusterName:      clusterName,
+		agentName:        agentName,
+		hubClusterLister: hubClusterInformer.Lister(),
+		publisher:        publisher,
+	}
+
+	return factory.New().
+		WithInformers(hubClusterInformer.Informer()).
+		WithSync(health.WrapSync("CloudEventsHeartbeatController", c.sync)).
+		ToController("CloudEventsHeartbeatController", recorder)
+}
+
+func (c *cloudEventsHeartbeatController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	cluster, err := c.hubClusterLister.Get(c.clusterName)
+	if err != nil {
+		return fmt.Errorf("unable to get managed cluster %q from hub: %w", c.clusterName, err)
+	}
+	// the heartbeats of the clusters which are not accepted are ignored by the hub
+	if !meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
+		return nil
+	}
+
+	leaseDurationSeconds := cluster.Spec.LeaseDurationSeconds
+	if leaseDurationSeconds == 0 {
+		leaseDurationSeconds = 60
+	}
+
+	health.EnterPhase(ctx, "publish heartbeat")
+	event, err := cloudevents.NewEvent(cloudevents.ClusterHeartbeatEventType, c.clusterName, c.agentName, nil)
+	if err != nil {
+		return err
+	}
+	if err := c.publisher.PublishEvent(ctx, event); err != nil {
+		return fmt.Errorf("unable to publish the heartbeat of managed cluster %q: %w", c.clusterName, err)
+	}
+
+	syncCtx.Queue().AddAfter(syncCtx.QueueKey(), time.Duration(leaseDurationSeconds)*time.Second)
+	return nil
+}
+
+// cloudEventsStatusController publishes the status of the managed cluster as CloudEvents, the hub updates the
+// status of the cluster with them.
+type cloudEventsStatusController struct {
+	clusterName      string
+	agentName        string
+	hubClusterLister clusterv1listers.ManagedClusterLister
+	statusCollector  *ClusterStatusCollector
+	publisher        cloudevents.Publisher
+}
+
+// NewCloudEventsStatusController returns a controller which publishes the available condition, the version and
+// the resources of the managed cluster every resyncInterval, see NewManagedClusterStatusController.
+func NewCloudEventsStatusController(
+	clusterName, agentName string,
+	publisher cloudevents.Publisher,
+	hubClusterInformer clusterv1informer.ManagedClusterInformer,
+	managedClusterDiscoveryClient discovery.DiscoveryInterface,
+	nodeInformer corev1informers.NodeInformer,
+	resyncInterval func() time.Duration,
+	recorder events.Recorder) factory.Controller {
+	c := &cloudEventsStatusController{
+		clusterName:      clusterName,
+		agentName:        agentName,
+		hubClusterLister: hubClusterInformer.Lister(),
+		statusCollector:  NewClusterStatusCollector(managedClusterDiscoveryClient, nodeInformer.Lister()),
+		publisher:        publisher,
+	}
+
+	return factory.New().
+		WithInformers(hubClusterInformer.Informer(), nodeInformer.Informer()).
+		WithSync(health.WrapSync("CloudEventsStatusController", c.sync)).
+		WithPostStartHooks(health.ResyncEvery(resyncInterval)).
+		ToController("CloudEventsStatusController", recorder)
+}
+
+func (c *cloudEventsStatusController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	if _, err := c.hubClusterLister.Get(c.clusterName); err != nil {
+		return fmt.Errorf("unable to get managed cluster %q from hub: %w", c.clusterName, err)
+	}
+
+	condition, status, err := c.statusCollector.Collect(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to collect status of managed cluster %q: %w", c.clusterName, err)
+	}
+	if status == nil {
+		status = &clusterv1.ManagedClusterStatus{}
+	}
+	status.Conditions = []metav1.Condition{condition}
+
+	health.EnterPhase(ctx, "publish status")
+	event, err := cloudevents.NewEvent(cloudevents.ClusterStatusEventType, c.clusterName, c.agentName, status)
+	if err != nil {
+		return err
+	}
+	if err := c.publisher.PublishEvent(ctx, event); err != nil {
+		return fmt.Errorf("unable to publish the status of managed cluster %q: %w", c.clusterName, err)
+	}
+	return nil
+}
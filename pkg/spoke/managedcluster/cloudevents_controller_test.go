@@ -0,0 +1,154 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/cloudevents"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	discovery "k8s.io/client-go/discovery"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+// fakePublisher records the published events, or fails with err
+type fakePublisher struct {
+	events []*cloudevents.Event
+	err    error
+}
+
+func (p *fakePublisher) PublishEvent(ctx context.Context, event *cloudevents.Event) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestCloudEventsHeartbeat(t *testing.T) {
+	cases := []struct {
+		name           string
+		clusters       []runtime.Object
+		publisher      *fakePublisher
+		expectedEvents int
+		expectedErr    string
+	}{
+		{
+			name:        "no managed cluster",
+			publisher:   &fakePublisher{},
+			expectedErr: "unable to get managed cluster \"testmanagedcluster\" from hub: managedcluster.cluster.open-cluster-management.io \"testmanagedcluster\" not found",
+		},
+		{
+			name:      "managed cluster is not accepted",
+			clusters:  []runtime.Object{testinghelpers.NewManagedCluster()},
+			publisher: &fakePublisher{},
+		},
+		{
+			name:        "publish failed",
+			clusters:    []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			publisher:   &fakePublisher{err: errors.New("connection refused")},
+			expectedErr: "unable to publish the heartbeat of managed cluster \"testmanagedcluster\": connection refused",
+		},
+		{
+			name:           "heartbeat published",
+			clusters:       []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			publisher:      &fakePublisher{},
+			expectedEvents: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.clusters...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+			for _, cluster := range c.clusters {
+				clusterStore.Add(cluster)
+			}
+
+			ctrl := &cloudEventsHeartbeatController{
+				clusterName:      testinghelpers.TestManagedClusterName,
+				agentName:        "agent1",
+				hubClusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				publisher:        c.publisher,
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
+			testinghelpers.AssertError(t, syncErr, c.expectedErr)
+			if len(c.publisher.events) != c.expectedEvents {
+				t.Fatalf("expected %d events, but got %d", c.expectedEvents, len(c.publisher.events))
+			}
+			for _, event := range c.publisher.events {
+				if event.Type() != cloudevents.ClusterHeartbeatEventType || cloudevents.Topic(event) != "open-cluster-management/registration/testmanagedcluster/heartbeat" {
+					t.Errorf("unexpected event %v", event)
+				}
+			}
+		})
+	}
+}
+
+func TestCloudEventsStatus(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/livez":
+			w.WriteHeader(http.StatusOK)
+		case "/version":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(version.Info{GitVersion: "test-version"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer apiServer.Close()
+
+	cluster := testinghelpers.NewAcceptedManagedCluster()
+	clusterClient := clusterfake.NewSimpleClientset(cluster)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+	clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster)
+
+	node := testinghelpers.NewNode("testnode1", testinghelpers.NewResourceList(32, 64), testinghelpers.NewResourceList(16, 32))
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubefake.NewSimpleClientset(node), time.Minute*10)
+	kubeInformerFactory.Core().V1().Nodes().Informer().GetStore().Add(node)
+
+	publisher := &fakePublisher{}
+	ctrl := &cloudEventsStatusController{
+		clusterName:      testinghelpers.TestManagedClusterName,
+		agentName:        "agent1",
+		hubClusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+		statusCollector: NewClusterStatusCollector(discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{Host: apiServer.URL}),
+			kubeInformerFactory.Core().V1().Nodes().Lister()),
+		publisher: publisher,
+	}
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(publisher.events) != 1 || publisher.events[0].Type() != cloudevents.ClusterStatusEventType {
+		t.Fatalf("expected a status event, but got %v", publisher.events)
+	}
+
+	status := &clusterv1.ManagedClusterStatus{}
+	if err := cloudevents.DecodeData(publisher.events[0], status); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testinghelpers.AssertManagedClusterCondition(t, status.Conditions, metav1.Condition{
+		Type:    clusterv1.ManagedClusterConditionAvailable,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ManagedClusterAvailable",
+		Message: "Managed cluster is available",
+	})
+	if status.Version.Kubernetes != "test-version" || len(status.Capacity) == 0 {
+		t.Errorf("expected the version and the resources to be published, but got %v", status)
+	}
+}
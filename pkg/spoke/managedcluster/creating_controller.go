@@ -8,13 +8,14 @@ import (
 
 	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/klog/v2"
 )
 
 // well-known anonymous user
@@ -30,24 +31,28 @@ type managedClusterCreatingController struct {
 	clusterName             string
 	spokeExternalServerURLs []string
 	spokeCABundle           []byte
+	annotations             map[string]string
 	hubClusterClient        clientset.Interface
 }
 
-// NewManagedClusterCreatingController creates a new managedClusterCreatingController on the managed cluster.
+// NewManagedClusterCreatingController creates a new managedClusterCreatingController on the managed cluster. The
+// annotations are set on the ManagedCluster when it is created.
 func NewManagedClusterCreatingController(
 	clusterName string, spokeExternalServerURLs []string,
 	spokeCABundle []byte,
+	annotations map[string]string,
 	hubClusterClient clientset.Interface,
 	recorder events.Recorder) factory.Controller {
 	c := &managedClusterCreatingController{
 		clusterName:             clusterName,
 		spokeExternalServerURLs: spokeExternalServerURLs,
 		spokeCABundle:           spokeCABundle,
+		annotations:             annotations,
 		hubClusterClient:        hubClusterClient,
 	}
 
 	return factory.New().
-		WithSync(c.sync).
+		WithSync(health.WrapSync("ManagedClusterCreatingController", c.sync)).
 		ResyncEvery(wait.Jitter(CreatingControllerSyncInterval, 1.0)).
 		ToController("ManagedClusterCreatingController", recorder)
 }
@@ -57,7 +62,8 @@ func (c *managedClusterCreatingController) sync(ctx context.Context, syncCtx fac
 	switch {
 	case errors.IsUnauthorized(err),
 		errors.IsForbidden(err) && strings.Contains(err.Error(), anonymous):
-		klog.V(4).Infof("unable to get the managed cluster %q from hub: %v", c.clusterName, err)
+		helpers.ControllerLogger(ctx, "ManagedClusterCreatingController").V(helpers.LogLevelDebug).Info(
+			"Unable to get the managed cluster from hub", helpers.LogKeyCluster, c.clusterName, helpers.LogKeyReason, err.Error())
 		return nil
 	case errors.IsNotFound(err):
 	case err == nil:
@@ -68,7 +74,8 @@ func (c *managedClusterCreatingController) sync(ctx context.Context, syncCtx fac
 
 	managedCluster := &clusterv1.ManagedCluster{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: c.clusterName,
+			Name:        c.clusterName,
+			Annotations: c.annotations,
 		},
 	}
 
@@ -6,6 +6,7 @@ import (
 
 	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -34,6 +35,9 @@ func TestCreateSpokeCluster(t *testing.T) {
 				actual := actions[1].(clienttesting.CreateActionImpl).Object
 				actualClientConfigs := actual.(*clusterv1.ManagedCluster).Spec.ManagedClusterClientConfigs
 				testinghelpers.AssertManagedClusterClientConfigs(t, actualClientConfigs, expectedClientConfigs)
+				if driver := actual.(*clusterv1.ManagedCluster).Annotations[helpers.RegistrationDriverAnnotation]; driver != helpers.TokenRegistrationDriver {
+					t.Errorf("expected the registration driver annotation, but got %q", driver)
+				}
 			},
 		},
 		{
@@ -52,6 +56,7 @@ func TestCreateSpokeCluster(t *testing.T) {
 				clusterName:             testinghelpers.TestManagedClusterName,
 				spokeExternalServerURLs: []string{testSpokeExternalServerUrl},
 				spokeCABundle:           []byte("testcabundle"),
+				annotations:             map[string]string{helpers.RegistrationDriverAnnotation: helpers.TokenRegistrationDriver},
 				hubClusterClient:        clusterClient,
 			}
 
@@ -0,0 +1,163 @@
+package managedcluster
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// ExecCredentialSyncInterval is the max interval the agent runs the exec credential plugin of the hub kubeconfig
+// to check the credential can be refreshed. It is exposed so that integration tests can shorten it.
+var ExecCredentialSyncInterval = 10 * time.Minute
+
+// execCredentialForHubController saves a kubeconfig authenticating to the hub with an exec credential plugin, e.g.
+// kubelogin or gke-gcloud-auth-plugin, in the hub kubeconfig secret once the plugin returns a credential. The
+// credential is refreshed by the clients of the kubeconfig, the controller runs the plugin again before the
+// credential expires, so the failures of the plugin are surfaced before the clients are not able to refresh it.
+type execCredentialForHubController struct {
+	clusterName     string
+	agentName       string
+	secretNamespace string
+	secretName      string
+	kubeconfigData  []byte
+	execConfig      *clientcmdapi.ExecConfig
+	cluster         *clientcmdapi.Cluster
+	spokeCoreClient corev1client.CoreV1Interface
+	exec            clientcert.ExecFunc
+}
+
+// NewExecCredentialForHubController returns a controller which keeps the kubeconfig connecting to the hub with
+// hubClientConfig and authenticating with the exec credential plugin in the hub kubeconfig secret. The identity
+// of the plugin is expected to be bound to the roles of the agent on the hub by the hub admin.
+func NewExecCredentialForHubController(
+	clusterName string,
+	agentName string,
+	secretNamespace string,
+	secretName string,
+	hubClientConfig *rest.Config,
+	execConfig *clientcmdapi.ExecConfig,
+	spokeSecretInformer corev1informers.SecretInformer,
+	spokeCoreClient corev1client.CoreV1Interface,
+	recorder events.Recorder,
+	controllerName string,
+) (factory.Controller, error) {
+	kubeconfig := clientcert.BuildExecKubeconfig(hubClientConfig, execConfig)
+	kubeconfigData, err := clientcmd.Write(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &execCredentialForHubController{
+		clusterName:     clusterName,
+		agentName:       agentName,
+		secretNamespace: secretNamespace,
+		secretName:      secretName,
+		kubeconfigData:  kubeconfigData,
+		execConfig:      execConfig,
+		cluster:         kubeconfig.Clusters["default-cluster"],
+		spokeCoreClient: spokeCoreClient,
+		exec:            clientcert.RunExecPlugin,
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			// only enqueue the hub kubeconfig secret
+			return accessor.GetNamespace() == secretNamespace && accessor.GetName() == secretName
+		}, spokeSecretInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ResyncEvery(ExecCredentialSyncInterval).
+		ToController(controllerName, recorder), nil
+}
+
+func (c *execCredentialForHubController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	health.EnterPhase(ctx, "exec plugin")
+	credential, err := c.exec(ctx, c.execConfig, c.cluster)
+	if err != nil {
+		syncCtx.Recorder().Warningf("ExecCredentialFailed", "Unable to get the credential for the hub: %v", err)
+		return err
+	}
+
+	secret, err := c.spokeCoreClient.Secrets(c.secretNamespace).Get(ctx, c.secretName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		secret = nil
+	case err != nil:
+		return err
+	}
+
+	// only the fields managed by the controller are applied, the others are kept
+	appliedSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: c.secretNamespace,
+			Name:      c.secretName,
+		},
+		Data: map[string][]byte{
+			clientcert.ClusterNameFile: []byte(c.clusterName),
+			clientcert.AgentNameFile:   []byte(c.agentName),
+			clientcert.KubeconfigFile:  c.kubeconfigData,
+		},
+	}
+	if _, err := helpers.ApplySecret(ctx, c.spokeCoreClient, secret, appliedSecret); err != nil {
+		return err
+	}
+	if secret == nil || !bytes.Equal(secret.Data[clientcert.KubeconfigFile], c.kubeconfigData) {
+		syncCtx.Recorder().Eventf("ExecKubeconfigUpdated", "The kubeconfig authenticating with exec plugin %q is saved in secret %s/%s",
+			c.execConfig.Command, c.secretNamespace, c.secretName)
+	}
+
+	// run the plugin again once 80% of the remaining lifetime of the credential has passed
+	if credential.ExpirationTimestamp != nil {
+		if refresh := time.Until(*credential.ExpirationTimestamp) * 4 / 5; refresh < ExecCredentialSyncInterval {
+			syncCtx.Queue().AddAfter(syncCtx.QueueKey(), refresh)
+		}
+	}
+	return nil
+}
+
+// HasValidHubExecKubeconfig returns true if KubeconfigFile exists in hubKubeconfigDir and the names of the
+// cluster and the agent in ClusterNameFile and AgentNameFile are the given ones. It is used for the kubeconfigs
+// authenticating with exec credential plugins, which are saved only once the plugins return the credentials, and
+// refresh the credentials by themselves.
+func HasValidHubExecKubeconfig(hubKubeconfigDir, clusterName, agentName string) (bool, error) {
+	kubeconfigPath := path.Join(hubKubeconfigDir, clientcert.KubeconfigFile)
+	if _, err := os.Stat(kubeconfigPath); os.IsNotExist(err) {
+		klog.V(4).Infof("Kubeconfig file %q not found", kubeconfigPath)
+		return false, nil
+	}
+
+	for file, expected := range map[string]string{clientcert.ClusterNameFile: clusterName, clientcert.AgentNameFile: agentName} {
+		data, err := ioutil.ReadFile(path.Clean(path.Join(hubKubeconfigDir, file)))
+		if err != nil || string(data) != expected {
+			klog.V(4).Infof("Kubeconfig file %q is saved for another agent than %q", kubeconfigPath, clusterName+":"+agentName)
+			return false, nil
+		}
+	}
+	return true, nil
+}
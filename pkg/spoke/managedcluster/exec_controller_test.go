@@ -0,0 +1,116 @@
+package managedcluster
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestExecCredentialForHubSync(t *testing.T) {
+	kubeconfigData := testinghelpers.NewKubeconfig(nil, nil)
+	expiration := time.Now().Add(time.Hour)
+
+	cases := []struct {
+		name            string
+		spokeSecrets    []runtime.Object
+		credential      *clientcert.ExecCredentialStatus
+		execErr         error
+		expectedErr     string
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:        "plugin failed",
+			execErr:     fmt.Errorf("identity not found"),
+			expectedErr: "identity not found",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:       "no hub kubeconfig secret",
+			credential: &clientcert.ExecCredentialStatus{Token: "token", ExpirationTimestamp: &expiration},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				secret := testinghelpers.AppliedSecret(t, actions[1])
+				if string(secret.Data[clientcert.KubeconfigFile]) != string(kubeconfigData) {
+					t.Errorf("expected the kubeconfig is saved, but got %q", string(secret.Data[clientcert.KubeconfigFile]))
+				}
+				if _, ok := secret.Data["token"]; ok {
+					t.Errorf("expected the credential not to be saved")
+				}
+			},
+		},
+		{
+			name:       "saved kubeconfig",
+			credential: &clientcert.ExecCredentialStatus{Token: "token"},
+			spokeSecrets: []runtime.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: testNamespace,
+					Name:      testSecretName,
+					UID:       "9c8b7a6d-5e4f-4a3b-2c1d-0e9f8a7b6c5d",
+				},
+				Data: map[string][]byte{
+					clientcert.ClusterNameFile: []byte(testinghelpers.TestManagedClusterName),
+					clientcert.AgentNameFile:   []byte("agent1"),
+					clientcert.KubeconfigFile:  kubeconfigData,
+				},
+			}},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			spokeKubeClient := kubefake.NewSimpleClientset(c.spokeSecrets...)
+			testinghelpers.AddSecretApplyReactor(&spokeKubeClient.Fake, spokeKubeClient.Tracker())
+
+			ctrl := &execCredentialForHubController{
+				clusterName:     testinghelpers.TestManagedClusterName,
+				agentName:       "agent1",
+				secretNamespace: testNamespace,
+				secretName:      testSecretName,
+				kubeconfigData:  kubeconfigData,
+				execConfig:      clientcert.NewGCPWorkloadIdentityExecConfig(),
+				spokeCoreClient: spokeKubeClient.CoreV1(),
+				exec: func(ctx context.Context, execConfig *clientcmdapi.ExecConfig, cluster *clientcmdapi.Cluster) (*clientcert.ExecCredentialStatus, error) {
+					return c.credential, c.execErr
+				},
+			}
+
+			err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "test"))
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			c.validateActions(t, spokeKubeClient.Actions())
+		})
+	}
+}
+
+func TestHasValidHubExecKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	if isValid, _ := HasValidHubExecKubeconfig(dir, "cluster1", "agent1"); isValid {
+		t.Errorf("expected the kubeconfig to be invalid without kubeconfig file")
+	}
+
+	testinghelpers.WriteFile(path.Join(dir, clientcert.KubeconfigFile), testinghelpers.NewKubeconfig(nil, nil))
+	testinghelpers.WriteFile(path.Join(dir, clientcert.ClusterNameFile), []byte("cluster1"))
+	testinghelpers.WriteFile(path.Join(dir, clientcert.AgentNameFile), []byte("agent1"))
+	if isValid, _ := HasValidHubExecKubeconfig(dir, "cluster1", "agent2"); isValid {
+		t.Errorf("expected the kubeconfig of another agent to be invalid")
+	}
+	if isValid, err := HasValidHubExecKubeconfig(dir, "cluster1", "agent1"); err != nil || !isValid {
+		t.Errorf("expected the kubeconfig to be valid, but got %t, %v", isValid, err)
+	}
+}
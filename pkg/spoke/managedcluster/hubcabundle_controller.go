@@ -0,0 +1,228 @@
+package managedcluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// hubVerificationTimeout is the timeout of the request the hub is verified with against a CA bundle
+const hubVerificationTimeout = 10 * time.Second
+
+// hubCABundleController keeps the CA of the hub kubeconfig in sync with the hub CA bundle published in the
+// managed cluster namespace on the hub. A new bundle is written into both the hub kubeconfig secret and the hub
+// kubeconfig directory only after the hub is verified with it, and then the agent is restarted, since the hub
+// clients do not reload their CA. While a new hub CA is rolled out, the agent verifies the hub with the new CA in
+// each resync, and reports in the HubCABundleTrusted condition of the cluster once the hub is verified, so that
+// the hub retires the old CA only after the hub is reachable with the new one from all clusters.
+type hubCABundleController struct {
+	clusterName                  string
+	hubKubeconfigDir             string
+	hubKubeconfigSecretNamespace string
+	hubKubeconfigSecretName      string
+	spokeCoreClient              corev1client.CoreV1Interface
+	hubConfigMapLister           corev1listers.ConfigMapLister
+	hubClusterClient             clientset.Interface
+	verifyHub                    func(cluster *clientcmdapi.Cluster, caData []byte) error
+	restart                      func()
+}
+
+// NewHubCABundleController returns a new hubCABundleController. The configmap informer should watch the managed
+// cluster namespace on the hub. The restart func is called once the CA of the hub kubeconfig is updated, it is
+// expected to stop the agent.
+func NewHubCABundleController(
+	clusterName, hubKubeconfigDir, hubKubeconfigSecretNamespace, hubKubeconfigSecretName string,
+	spokeCoreClient corev1client.CoreV1Interface,
+	hubConfigMapInformer corev1informers.ConfigMapInformer,
+	hubClusterClient clientset.Interface,
+	restart func(),
+	recorder events.Recorder) factory.Controller {
+	c := &hubCABundleController{
+		clusterName:                  clusterName,
+		hubKubeconfigDir:             hubKubeconfigDir,
+		hubKubeconfigSecretNamespace: hubKubeconfigSecretNamespace,
+		hubKubeconfigSecretName:      hubKubeconfigSecretName,
+		spokeCoreClient:              spokeCoreClient,
+		hubConfigMapLister:           hubConfigMapInformer.Lister(),
+		hubClusterClient:             hubClusterClient,
+		verifyHub:                    verifyHubServer,
+		restart:                      restart,
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(
+			func(obj runtime.Object) string {
+				return factory.DefaultQueueKey
+			},
+			func(obj interface{}) bool {
+				accessor, err := meta.Accessor(obj)
+				if err != nil {
+					return false
+				}
+				return accessor.GetNamespace() == clusterName && accessor.GetName() == helpers.HubCABundleConfigMapName
+			}, hubConfigMapInformer.Informer()).
+		WithSync(health.WrapSync("HubCABundleController", c.sync)).
+		ResyncEvery(5*time.Minute).
+		ToController("HubCABundleController", recorder)
+}
+
+func (c *hubCABundleController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	configMap, err := c.hubConfigMapLister.ConfigMaps(c.clusterName).Get(helpers.HubCABundleConfigMapName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	bundle := []byte(configMap.Data[helpers.HubCABundleKey])
+	if len(bundle) == 0 {
+		return nil
+	}
+	digest := helpers.HubCABundleDigest(bundle)
+
+	secret, err := c.spokeCoreClient.Secrets(c.hubKubeconfigSecretNamespace).Get(ctx, c.hubKubeconfigSecretName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := clientcmd.Load(secret.Data[clientcert.KubeconfigFile])
+	if err != nil {
+		return fmt.Errorf("unable to load kubeconfig in secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+	cluster, err := currentCluster(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("invalid kubeconfig in secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	if !bytes.Equal(cluster.CertificateAuthorityData, bundle) {
+		if err := c.verifyHub(cluster, bundle); err != nil {
+			if condErr := c.updateCondition(ctx, metav1.ConditionFalse, "HubCABundleUntrusted",
+				fmt.Sprintf("Failed to verify the hub with the hub CA bundle %s: %v", digest, err)); condErr != nil {
+				return condErr
+			}
+			return fmt.Errorf("unable to verify the hub with the hub CA bundle %s: %w", digest, err)
+		}
+
+		cluster.CertificateAuthority = ""
+		cluster.CertificateAuthorityData = bundle
+		kubeconfigData, err := clientcmd.Write(*kubeconfig)
+		if err != nil {
+			return err
+		}
+		if err := c.writeKubeconfig(ctx, kubeconfigData); err != nil {
+			return err
+		}
+		syncCtx.Recorder().Eventf("HubCABundleUpdated",
+			"The CA of the hub kubeconfig is updated with the hub CA bundle %s, the agent is restarting", digest)
+		c.restart()
+		return nil
+	}
+
+	if newCA := []byte(configMap.Data[helpers.HubNewCAKey]); len(newCA) > 0 {
+		if err := c.verifyHub(cluster, newCA); err != nil {
+			return c.updateCondition(ctx, metav1.ConditionFalse, "NewHubCAUnverified",
+				fmt.Sprintf("The hub CA bundle %s is trusted, but the hub is not verified with the new CA: %v", digest, err))
+		}
+	}
+	return c.updateCondition(ctx, metav1.ConditionTrue, "HubCABundleTrusted", helpers.HubCABundleTrustedMessage(digest))
+}
+
+// writeKubeconfig writes the hub kubeconfig into the hub kubeconfig secret and the hub kubeconfig directory. The
+// file is written as well, otherwise the agent might be restarted with the stale file before it is dumped from the
+// secret.
+func (c *hubCABundleController) writeKubeconfig(ctx context.Context, kubeconfigData []byte) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"data": map[string][]byte{clientcert.KubeconfigFile: kubeconfigData},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.spokeCoreClient.Secrets(c.hubKubeconfigSecretNamespace).Patch(
+		ctx, c.hubKubeconfigSecretName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+
+	filename := path.Clean(path.Join(c.hubKubeconfigDir, clientcert.KubeconfigFile))
+	if err := ioutil.WriteFile(filename, kubeconfigData, 0600); err != nil {
+		return fmt.Errorf("unable to write file %q: %w", filename, err)
+	}
+	return nil
+}
+
+func (c *hubCABundleController) updateCondition(ctx context.Context, status metav1.ConditionStatus, reason, message string) error {
+	_, _, err := helpers.PatchManagedClusterStatus(ctx, c.hubClusterClient, c.clusterName,
+		helpers.UpdateManagedClusterConditionFn(metav1.Condition{
+			Type:    helpers.HubCABundleTrustedCondition,
+			Status:  status,
+			Reason:  reason,
+			Message: message,
+		}))
+	return err
+}
+
+// currentCluster returns the cluster of the current context of a kubeconfig
+func currentCluster(kubeconfig *clientcmdapi.Config) (*clientcmdapi.Cluster, error) {
+	kubeContext, ok := kubeconfig.Contexts[kubeconfig.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("current context %q is not found", kubeconfig.CurrentContext)
+	}
+	cluster, ok := kubeconfig.Clusters[kubeContext.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("cluster %q is not found", kubeContext.Cluster)
+	}
+	return cluster, nil
+}
+
+// verifyHubServer sends a request to the hub apiserver with the serving certificate verified against the CA
+// bundle. The request is not authenticated, any response of the hub means the hub is verified.
+func verifyHubServer(cluster *clientcmdapi.Cluster, caData []byte) error {
+	config := &rest.Config{
+		Host:    cluster.Server,
+		Timeout: hubVerificationTimeout,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData:     caData,
+			ServerName: cluster.TLSServerName,
+		},
+	}
+	if len(cluster.ProxyURL) > 0 {
+		proxyURL, err := url.Parse(cluster.ProxyURL)
+		if err != nil {
+			return err
+		}
+		config.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	client, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Get(cluster.Server + "/version")
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,221 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestHubCABundleSync(t *testing.T) {
+	oldCA := testinghelpers.NewTestCert("old-ca", time.Hour).Cert
+	newCA := testinghelpers.NewTestCert("new-ca", time.Hour).Cert
+	combined := helpers.MergeCABundles(oldCA, newCA)
+
+	newKubeconfig := func(caData []byte) []byte {
+		data, err := clientcmd.Write(clientcmdapi.Config{
+			Clusters:       map[string]*clientcmdapi.Cluster{"default-cluster": {Server: "https://hub.example.com:6443", CertificateAuthorityData: caData}},
+			AuthInfos:      map[string]*clientcmdapi.AuthInfo{"default-auth": {ClientCertificate: clientcert.TLSCertFile}},
+			Contexts:       map[string]*clientcmdapi.Context{"default-context": {Cluster: "default-cluster", AuthInfo: "default-auth"}},
+			CurrentContext: "default-context",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	newConfigMap := func(bundle, newCA []byte) *corev1.ConfigMap {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testinghelpers.TestManagedClusterName, Name: helpers.HubCABundleConfigMapName},
+			Data:       map[string]string{helpers.HubCABundleKey: string(bundle)},
+		}
+		if len(newCA) > 0 {
+			configMap.Data[helpers.HubNewCAKey] = string(newCA)
+		}
+		return configMap
+	}
+
+	cases := []struct {
+		name              string
+		configMap         *corev1.ConfigMap
+		caData            []byte
+		verifyErr         map[string]error
+		expectedErr       string
+		expectedRestart   bool
+		expectedCondition *metav1.Condition
+		validateActions   func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:            "no hub ca bundle",
+			caData:          oldCA,
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "update the ca of the hub kubeconfig",
+			configMap:       newConfigMap(combined, newCA),
+			caData:          oldCA,
+			expectedRestart: true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+			},
+		},
+		{
+			name:        "hub is not verified with the bundle",
+			configMap:   newConfigMap(combined, newCA),
+			caData:      oldCA,
+			verifyErr:   map[string]error{string(combined): fmt.Errorf("x509: certificate signed by unknown authority")},
+			expectedErr: fmt.Sprintf("unable to verify the hub with the hub CA bundle %s: x509: certificate signed by unknown authority", helpers.HubCABundleDigest(combined)),
+			expectedCondition: &metav1.Condition{
+				Type:   helpers.HubCABundleTrustedCondition,
+				Status: metav1.ConditionFalse,
+				Reason: "HubCABundleUntrusted",
+				Message: fmt.Sprintf("Failed to verify the hub with the hub CA bundle %s: x509: certificate signed by unknown authority",
+					helpers.HubCABundleDigest(combined)),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name:      "hub is not verified with the new ca",
+			configMap: newConfigMap(combined, newCA),
+			caData:    combined,
+			verifyErr: map[string]error{string(newCA): fmt.Errorf("x509: certificate signed by unknown authority")},
+			expectedCondition: &metav1.Condition{
+				Type:   helpers.HubCABundleTrustedCondition,
+				Status: metav1.ConditionFalse,
+				Reason: "NewHubCAUnverified",
+				Message: fmt.Sprintf("The hub CA bundle %s is trusted, but the hub is not verified with the new CA: "+
+					"x509: certificate signed by unknown authority", helpers.HubCABundleDigest(combined)),
+			},
+		},
+		{
+			name:      "hub is verified with the new ca",
+			configMap: newConfigMap(combined, newCA),
+			caData:    combined,
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.HubCABundleTrustedCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  "HubCABundleTrusted",
+				Message: helpers.HubCABundleTrustedMessage(helpers.HubCABundleDigest(combined)),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hubKubeconfigDir := t.TempDir()
+			secret := testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "", nil, map[string][]byte{
+				clientcert.KubeconfigFile: newKubeconfig(c.caData),
+			})
+			kubeClient := kubefake.NewSimpleClientset(secret)
+			clusterClient := clusterfake.NewSimpleClientset(testinghelpers.NewAcceptedManagedCluster())
+			hubKubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), time.Minute*10)
+			if c.configMap != nil {
+				if err := hubKubeInformerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(c.configMap); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			restarted := false
+			ctrl := &hubCABundleController{
+				clusterName:                  testinghelpers.TestManagedClusterName,
+				hubKubeconfigDir:             hubKubeconfigDir,
+				hubKubeconfigSecretNamespace: testNamespace,
+				hubKubeconfigSecretName:      testSecretName,
+				spokeCoreClient:              kubeClient.CoreV1(),
+				hubConfigMapLister:           hubKubeInformerFactory.Core().V1().ConfigMaps().Lister(),
+				hubClusterClient:             clusterClient,
+				verifyHub: func(cluster *clientcmdapi.Cluster, caData []byte) error {
+					return c.verifyErr[string(caData)]
+				},
+				restart: func() { restarted = true },
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
+			testinghelpers.AssertError(t, syncErr, c.expectedErr)
+
+			if restarted != c.expectedRestart {
+				t.Errorf("expected restart %v, but got %v", c.expectedRestart, restarted)
+			}
+			if c.validateActions != nil {
+				c.validateActions(t, kubeClient.Actions())
+			}
+			if c.expectedRestart {
+				data, err := ioutil.ReadFile(path.Join(hubKubeconfigDir, clientcert.KubeconfigFile))
+				if err != nil {
+					t.Fatal(err)
+				}
+				kubeconfig, err := clientcmd.Load(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if caData := kubeconfig.Clusters["default-cluster"].CertificateAuthorityData; string(caData) != string(c.configMap.Data[helpers.HubCABundleKey]) {
+					t.Errorf("expected the ca of the hub kubeconfig to be updated, but got %q", string(caData))
+				}
+			}
+
+			cluster, err := clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), testinghelpers.TestManagedClusterName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cond := meta.FindStatusCondition(cluster.Status.Conditions, helpers.HubCABundleTrustedCondition)
+			switch {
+			case c.expectedCondition == nil && cond != nil:
+				t.Errorf("expected no condition, but got %v", cond)
+			case c.expectedCondition != nil:
+				testinghelpers.AssertManagedClusterCondition(t, cluster.Status.Conditions, *c.expectedCondition)
+			}
+		})
+	}
+}
+
+func TestVerifyHubServer(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	serverCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	cases := []struct {
+		name        string
+		caData      []byte
+		expectedErr bool
+	}{
+		{
+			name:   "verified",
+			caData: serverCA,
+		},
+		{
+			name:        "unknown authority",
+			caData:      testinghelpers.NewTestCert("other-ca", time.Hour).Cert,
+			expectedErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := verifyHubServer(&clientcmdapi.Cluster{Server: server.URL}, c.caData)
+			if (err != nil) != c.expectedErr {
+				t.Errorf("expected error %v, but got %v", c.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package managedcluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+)
+
+const hubStateControllerName = "HubStateController"
+
+// hubClusterState is the state of the managed cluster on the hub mirrored by hubStateController
+type hubClusterState struct {
+	accepted bool
+	// available is the status of the available condition set by the hub, it is empty if the condition is not set
+	available metav1.ConditionStatus
+	// availableMessage is the message of the available condition set by the hub
+	availableMessage string
+	// taints are the taints of the cluster in the format of <key>=<value>:<effect>
+	taints sets.String
+}
+
+// hubStateController mirrors the state of the managed cluster on the hub with the events of the agent on the
+// managed cluster, so the operators of the managed cluster see the cluster is not accepted, tainted or marked
+// unknown by the hub without access to the hub. The events are recorded when the state changes, and the abnormal
+// state is recorded again once the agent restarts.
+type hubStateController struct {
+	clusterName      string
+	hubClusterLister clusterv1listers.ManagedClusterLister
+
+	lock      sync.Mutex
+	lastState *hubClusterState
+}
+
+// NewHubStateController returns an instance of hubStateController, the events are recorded with the recorder of
+// the agent on the managed cluster.
+func NewHubStateController(
+	clusterName string,
+	hubClusterInformer clusterv1informer.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &hubStateController{
+		clusterName:      clusterName,
+		hubClusterLister: hubClusterInformer.Lister(),
+	}
+
+	return factory.New().
+		WithInformers(hubClusterInformer.Informer()).
+		WithSync(health.WrapSync(hubStateControllerName, c.sync)).
+		ToController(hubStateControllerName, recorder)
+}
+
+func (c *hubStateController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	cluster, err := c.hubClusterLister.Get(c.clusterName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	state := newHubClusterState(cluster)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	recordStateChanges(syncCtx.Recorder(), c.clusterName, c.lastState, state)
+	c.lastState = state
+	return nil
+}
+
+func newHubClusterState(cluster *clusterv1.ManagedCluster) *hubClusterState {
+	state := &hubClusterState{
+		accepted: cluster.Spec.HubAcceptsClient &&
+			!meta.IsStatusConditionFalse(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted),
+		taints: sets.NewString(),
+	}
+	if condition := meta.FindStatusCondition(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable); condition != nil {
+		state.available = condition.Status
+		state.availableMessage = condition.Message
+	}
+	for _, taint := range cluster.Spec.Taints {
+		state.taints.Insert(fmt.Sprintf("%s=%s:%s", taint.Key, taint.Value, taint.Effect))
+	}
+	return state
+}
+
+// recordStateChanges records the changes of the state of the cluster on the hub, the last state is nil when the
+// agent starts, so only the abnormal state is recorded.
+func recordStateChanges(recorder events.Recorder, clusterName string, last, current *hubClusterState) {
+	switch {
+	case !current.accepted && (last == nil || last.accepted):
+		recorder.Warningf("HubClusterNotAccepted", "The managed cluster %q is not accepted by the hub", clusterName)
+	case current.accepted && last != nil && !last.accepted:
+		recorder.Eventf("HubClusterAccepted", "The managed cluster %q is accepted by the hub", clusterName)
+	}
+
+	// the available condition is only set by the hub once the cluster is accepted
+	if current.accepted && (last == nil || last.available != current.available) {
+		switch current.available {
+		case metav1.ConditionUnknown:
+			recorder.Warningf("HubClusterUnknown", "The managed cluster %q is marked unknown by the hub: %s",
+				clusterName, current.availableMessage)
+		case metav1.ConditionFalse:
+			recorder.Warningf("HubClusterUnavailable", "The managed cluster %q is marked unavailable by the hub: %s",
+				clusterName, current.availableMessage)
+		case metav1.ConditionTrue:
+			if last != nil {
+				recorder.Eventf("HubClusterAvailable", "The managed cluster %q is marked available by the hub", clusterName)
+			}
+		}
+	}
+
+	lastTaints := sets.NewString()
+	if last != nil {
+		lastTaints = last.taints
+	}
+	if added := current.taints.Difference(lastTaints); added.Len() > 0 {
+		recorder.Warningf("HubClusterTainted", "The managed cluster %q is tainted by the hub: %s",
+			clusterName, strings.Join(added.List(), ", "))
+	}
+	if removed := lastTaints.Difference(current.taints); removed.Len() > 0 {
+		recorder.Eventf("HubClusterUntainted", "The taints of the managed cluster %q are removed by the hub: %s",
+			clusterName, strings.Join(removed.List(), ", "))
+	}
+}
@@ -0,0 +1,95 @@
+package managedcluster
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func newTaintedManagedCluster() *clusterv1.ManagedCluster {
+	cluster := testinghelpers.NewUnknownManagedCluster()
+	cluster.Spec.Taints = []clusterv1.Taint{
+		{Key: clusterv1.ManagedClusterTaintUnreachable, Effect: clusterv1.TaintEffectNoSelect},
+	}
+	return cluster
+}
+
+func TestRecordStateChanges(t *testing.T) {
+	cases := []struct {
+		name           string
+		last           *clusterv1.ManagedCluster
+		current        *clusterv1.ManagedCluster
+		expectedEvents []string
+	}{
+		{
+			name:    "agent starts with an available cluster",
+			current: testinghelpers.NewAvailableManagedCluster(),
+		},
+		{
+			name:           "agent starts with a cluster not accepted",
+			current:        testinghelpers.NewManagedCluster(),
+			expectedEvents: []string{"HubClusterNotAccepted"},
+		},
+		{
+			name:           "cluster is accepted",
+			last:           testinghelpers.NewManagedCluster(),
+			current:        testinghelpers.NewAcceptedManagedCluster(),
+			expectedEvents: []string{"HubClusterAccepted"},
+		},
+		{
+			name:           "cluster is denied",
+			last:           testinghelpers.NewAvailableManagedCluster(),
+			current:        testinghelpers.NewDeniedManagedCluster(),
+			expectedEvents: []string{"HubClusterNotAccepted"},
+		},
+		{
+			name:           "cluster is marked unknown and tainted",
+			last:           testinghelpers.NewAvailableManagedCluster(),
+			current:        newTaintedManagedCluster(),
+			expectedEvents: []string{"HubClusterUnknown", "HubClusterTainted"},
+		},
+		{
+			name:           "cluster is marked unavailable",
+			last:           testinghelpers.NewAvailableManagedCluster(),
+			current:        testinghelpers.NewUnAvailableManagedCluster(),
+			expectedEvents: []string{"HubClusterUnavailable"},
+		},
+		{
+			name:           "cluster recovers",
+			last:           newTaintedManagedCluster(),
+			current:        testinghelpers.NewAvailableManagedCluster(),
+			expectedEvents: []string{"HubClusterAvailable", "HubClusterUntainted"},
+		},
+		{
+			name:    "nothing changed",
+			last:    newTaintedManagedCluster(),
+			current: newTaintedManagedCluster(),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var last *hubClusterState
+			if c.last != nil {
+				last = newHubClusterState(c.last)
+			}
+			recorder := events.NewInMemoryRecorder("")
+			recordStateChanges(recorder, testinghelpers.TestManagedClusterName, last, newHubClusterState(c.current))
+
+			reasons := []string{}
+			for _, event := range recorder.Events() {
+				reasons = append(reasons, event.Reason)
+			}
+			if len(c.expectedEvents) == 0 {
+				c.expectedEvents = []string{}
+			}
+			if !reflect.DeepEqual(reasons, c.expectedEvents) {
+				t.Errorf("expected events %v, but got %v", c.expectedEvents, reasons)
+			}
+		})
+	}
+}
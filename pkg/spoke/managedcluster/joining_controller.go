@@ -9,6 +9,7 @@ import (
 	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
 	"open-cluster-management.io/registration/pkg/helpers"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
@@ -39,7 +40,7 @@ func NewManagedClusterJoiningController(
 
 	return factory.New().
 		WithInformers(hubManagedClusterInformer.Informer()).
-		WithSync(c.sync).
+		WithSync(health.WrapSync("ManagedClusterJoiningController", c.sync)).
 		ResyncEvery(5*time.Minute).
 		ToController("ManagedClusterJoiningController", recorder)
 }
@@ -65,7 +66,7 @@ func (c managedClusterJoiningController) sync(ctx context.Context, syncCtx facto
 	}
 
 	// current managed cluster did not join the hub cluster, join it.
-	_, updated, err := helpers.UpdateManagedClusterStatus(
+	_, updated, err := helpers.PatchManagedClusterStatus(
 		ctx,
 		c.hubClusterClient,
 		c.clusterName,
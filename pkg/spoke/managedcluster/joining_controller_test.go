@@ -43,9 +43,9 @@ func TestSyncManagedCluster(t *testing.T) {
 					Reason:  "ManagedClusterJoined",
 					Message: "Managed cluster joined",
 				}
-				testinghelpers.AssertActions(t, actions, "get", "update")
-				actual := actions[1].(clienttesting.UpdateActionImpl).Object
-				testinghelpers.AssertManagedClusterCondition(t, actual.(*clusterv1.ManagedCluster).Status.Conditions, expectedCondition)
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				actual := testinghelpers.PatchedManagedCluster(t, actions[1])
+				testinghelpers.AssertManagedClusterCondition(t, actual.Status.Conditions, expectedCondition)
 			},
 		},
 	}
@@ -8,10 +8,10 @@ import (
 	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+	"open-cluster-management.io/registration/pkg/spoke/lease"
 
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 
-	coordinationv1 "k8s.io/api/coordination/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
@@ -29,24 +29,24 @@ func TestLeaseUpdate(t *testing.T) {
 			name:     "start lease update routine",
 			clusters: []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertUpdateActions(t, actions)
-				leaseObj := actions[1].(clienttesting.UpdateActionImpl).Object
-				lastLeaseObj := actions[len(actions)-1].(clienttesting.UpdateActionImpl).Object
-				testinghelpers.AssertLeaseUpdated(t, leaseObj.(*coordinationv1.Lease), lastLeaseObj.(*coordinationv1.Lease))
+				testinghelpers.AssertPatchActions(t, actions)
+				lease := testinghelpers.PatchedLease(t, actions[0])
+				lastLease := testinghelpers.PatchedLease(t, actions[len(actions)-1])
+				testinghelpers.AssertLeaseUpdated(t, lease, lastLease)
 			},
 		},
 		{
 			name:                    "delete a managed cluster after lease update routine is started",
 			clusters:                []runtime.Object{},
 			needToStartUpdateBefore: true,
-			validateActions:         testinghelpers.AssertNoMoreUpdates,
+			validateActions:         testinghelpers.AssertNoMorePatches,
 			expectedErr:             "unable to get managed cluster \"testmanagedcluster\" from hub: managedcluster.cluster.open-cluster-management.io \"testmanagedcluster\" not found",
 		},
 		{
 			name:                    "unaccept a managed cluster after lease update routine is started",
 			clusters:                []runtime.Object{testinghelpers.NewManagedCluster()},
 			needToStartUpdateBefore: true,
-			validateActions:         testinghelpers.AssertNoMoreUpdates,
+			validateActions:         testinghelpers.AssertNoMorePatches,
 		},
 	}
 
@@ -61,15 +61,12 @@ func TestLeaseUpdate(t *testing.T) {
 
 			hubClient := kubefake.NewSimpleClientset(testinghelpers.NewManagedClusterLease("managed-cluster-lease", time.Now()))
 
-			leaseUpdater := &leaseUpdater{
-				hubClient:   hubClient,
-				clusterName: testinghelpers.TestManagedClusterName,
-				leaseName:   "managed-cluster-lease",
-				recorder:    eventstesting.NewTestingEventRecorder(t),
-			}
+			leaseRenewer := lease.NewRenewer(hubClient, eventstesting.NewTestingEventRecorder(t))
+			leaseRenewer.Start(context.TODO())
 
 			if c.needToStartUpdateBefore {
-				leaseUpdater.start(context.TODO(), time.Duration(testinghelpers.TestLeaseDurationSeconds)*time.Second)
+				leaseRenewer.Renew(testinghelpers.TestManagedClusterName, managedClusterLeaseName,
+					time.Duration(testinghelpers.TestLeaseDurationSeconds)*time.Second)
 				// wait a few milliseconds to start the lease update routine
 				time.Sleep(200 * time.Millisecond)
 			}
@@ -77,13 +74,13 @@ func TestLeaseUpdate(t *testing.T) {
 			ctrl := &managedClusterLeaseController{
 				clusterName:      testinghelpers.TestManagedClusterName,
 				hubClusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
-				leaseUpdater:     leaseUpdater,
+				leaseRenewer:     leaseRenewer,
 			}
 			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
 			testinghelpers.AssertError(t, syncErr, c.expectedErr)
 
-			// wait one cycle
-			time.Sleep(1200 * time.Millisecond)
+			// wait one cycle, which is up to 1.25 times the lease duration with the jitter
+			time.Sleep(1500 * time.Millisecond)
 			c.validateActions(t, hubClient.Actions())
 		})
 	}
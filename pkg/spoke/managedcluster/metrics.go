@@ -0,0 +1,74 @@
+package managedcluster
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// originUnknown is the origin of a hub credential stored before the origin is recorded on the secret
+const originUnknown = "unknown"
+
+var (
+	hubCredentialAge = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name: "open_cluster_management_registration_hub_credential_age_seconds",
+			Help: "Seconds since the client certificate in the hub kubeconfig secret was issued, partitioned by managed cluster.",
+		},
+		[]string{helpers.MetricLabelManagedCluster},
+	)
+
+	hubCredentialExpiration = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name: "open_cluster_management_registration_hub_credential_expiration_timestamp_seconds",
+			Help: "Expiration time of the client certificate in the hub kubeconfig secret in unix seconds, partitioned by " +
+				"managed cluster. The days to expiry are (open_cluster_management_registration_hub_credential_expiration_timestamp_seconds - time()) / 86400.",
+		},
+		[]string{helpers.MetricLabelManagedCluster},
+	)
+
+	hubCredentialOrigin = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name: "open_cluster_management_registration_hub_credential_origin",
+			Help: "Origin of the client certificate in the hub kubeconfig secret, which is bootstrap, rotated or unknown. " +
+				"The value is 1 for the origin of the current certificate.",
+		},
+		[]string{helpers.MetricLabelManagedCluster, "origin"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(hubCredentialAge, hubCredentialExpiration, hubCredentialOrigin)
+}
+
+// recordHubCredentialMetrics records the age, the expiration and the origin of the client certificate in the hub
+// kubeconfig secret. The metrics are removed if there is no certificate in the secret.
+func recordHubCredentialMetrics(secret *corev1.Secret, now time.Time) {
+	hubCredentialAge.Reset()
+	hubCredentialExpiration.Reset()
+	hubCredentialOrigin.Reset()
+
+	if secret == nil {
+		return
+	}
+	certs, err := certutil.ParseCertsPEM(secret.Data[clientcert.TLSCertFile])
+	if err != nil || len(certs) == 0 {
+		return
+	}
+
+	clusterName := string(secret.Data[clientcert.ClusterNameFile])
+	origin := secret.Annotations[clientcert.CertificateOriginAnnotation]
+	if len(origin) == 0 {
+		origin = originUnknown
+	}
+
+	hubCredentialAge.WithLabelValues(clusterName).Set(now.Sub(certs[0].NotBefore).Seconds())
+	hubCredentialExpiration.WithLabelValues(clusterName).Set(float64(certs[0].NotAfter.Unix()))
+	hubCredentialOrigin.WithLabelValues(clusterName, origin).Set(1)
+}
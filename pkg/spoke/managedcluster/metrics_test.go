@@ -0,0 +1,112 @@
+package managedcluster
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestRecordHubCredentialMetrics(t *testing.T) {
+	cert := testinghelpers.NewTestCert("test", time.Hour)
+	certs, err := certutil.ParseCertsPEM(cert.Cert)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := certs[0].NotBefore.Add(10 * time.Minute)
+
+	cases := []struct {
+		name           string
+		secret         *corev1.Secret
+		expectedOrigin string
+	}{
+		{
+			name:   "no secret",
+			secret: nil,
+		},
+		{
+			name:   "no certificate",
+			secret: testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "", nil, map[string][]byte{}),
+		},
+		{
+			name: "certificate without origin",
+			secret: testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "", cert, map[string][]byte{
+				clientcert.ClusterNameFile: []byte("cluster1"),
+			}),
+			expectedOrigin: originUnknown,
+		},
+		{
+			name: "rotated certificate",
+			secret: func() *corev1.Secret {
+				secret := testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "", cert, map[string][]byte{
+					clientcert.ClusterNameFile: []byte("cluster1"),
+				})
+				secret.Annotations = map[string]string{clientcert.CertificateOriginAnnotation: clientcert.CertificateOriginRotated}
+				return secret
+			}(),
+			expectedOrigin: clientcert.CertificateOriginRotated,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// record a stale origin to make sure it is removed
+			hubCredentialOrigin.WithLabelValues("cluster1", clientcert.CertificateOriginBootstrap).Set(1)
+
+			recordHubCredentialMetrics(c.secret, now)
+
+			if len(c.expectedOrigin) == 0 {
+				for _, name := range []string{"age_seconds", "expiration_timestamp_seconds", "origin"} {
+					if count := countMetrics(t, "open_cluster_management_registration_hub_credential_"+name); count != 0 {
+						t.Errorf("expected no %s metric, but got %d", name, count)
+					}
+				}
+				return
+			}
+
+			age, err := testutil.GetGaugeMetricValue(hubCredentialAge.WithLabelValues("cluster1"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if age != 600 {
+				t.Errorf("expected age 600, but got %v", age)
+			}
+			expiration, err := testutil.GetGaugeMetricValue(hubCredentialExpiration.WithLabelValues("cluster1"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expiration != float64(certs[0].NotAfter.Unix()) {
+				t.Errorf("expected expiration %v, but got %v", certs[0].NotAfter.Unix(), expiration)
+			}
+			if count := countMetrics(t, "open_cluster_management_registration_hub_credential_origin"); count != 1 {
+				t.Errorf("expected one origin, but got %d", count)
+			}
+			origin, err := testutil.GetGaugeMetricValue(hubCredentialOrigin.WithLabelValues("cluster1", c.expectedOrigin))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if origin != 1 {
+				t.Errorf("expected origin %q, but got %v", c.expectedOrigin, origin)
+			}
+		})
+	}
+}
+
+func countMetrics(t *testing.T, name string) int {
+	families, err := legacyregistry.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == name {
+			return len(family.GetMetric())
+		}
+	}
+	return 0
+}
@@ -0,0 +1,164 @@
+package managedcluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const hubPermissionControllerName = "HubPermissionController"
+
+// HubPermissionsGrantedCondition is the condition of a managed cluster reported by the registration agent, it is
+// false once any of the permissions the agent requires on the hub is denied.
+const HubPermissionsGrantedCondition = "HubPermissionsGranted"
+
+// HubPermissionCheckInterval is the interval the permissions of the agent on the hub are reviewed in. It is
+// exposed so that integration tests can shorten it.
+var HubPermissionCheckInterval = 10 * time.Minute
+
+// hubPermissionController reviews the permissions the agent requires on the hub periodically with
+// SelfSubjectAccessReviews, and reports them with the HubPermissionsGranted condition of the managed cluster, so
+// that a drift of the rbac on the hub is noticed before it breaks the rotation of the client certificate or the
+// renewal of the lease. The permissions are granted once the cluster is accepted, so they are not reviewed before.
+// The permissions on the csrs are reviewed only if csrRequired is true, i.e. the agent rotates its client
+// certificate with csrs.
+type hubPermissionController struct {
+	clusterName      string
+	csrRequired      bool
+	hubKubeClient    kubernetes.Interface
+	hubClusterClient clientset.Interface
+	hubClusterLister clusterv1listers.ManagedClusterLister
+}
+
+// NewHubPermissionController returns an instance of hubPermissionController
+func NewHubPermissionController(
+	clusterName string,
+	csrRequired bool,
+	hubKubeClient kubernetes.Interface,
+	hubClusterClient clientset.Interface,
+	hubClusterInformer clusterv1informer.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &hubPermissionController{
+		clusterName:      clusterName,
+		csrRequired:      csrRequired,
+		hubKubeClient:    hubKubeClient,
+		hubClusterClient: hubClusterClient,
+		hubClusterLister: hubClusterInformer.Lister(),
+	}
+
+	return factory.New().
+		WithInformers(hubClusterInformer.Informer()).
+		WithSync(health.WrapSync(hubPermissionControllerName, c.sync)).
+		ResyncEvery(HubPermissionCheckInterval).
+		ToController(hubPermissionControllerName, recorder)
+}
+
+func (c *hubPermissionController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	cluster, err := c.hubClusterLister.Get(c.clusterName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
+		return nil
+	}
+
+	denied, err := c.reviewAccess(ctx)
+	if err != nil {
+		return err
+	}
+
+	condition := metav1.Condition{
+		Type:    HubPermissionsGrantedCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  "HubPermissionsGranted",
+		Message: "The permissions required by the registration agent are granted on the hub",
+	}
+	if len(denied) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "HubPermissionsDenied"
+		condition.Message = fmt.Sprintf("The registration agent is not allowed to %s on the hub", strings.Join(denied, ", "))
+		if !meta.IsStatusConditionFalse(cluster.Status.Conditions, HubPermissionsGrantedCondition) {
+			syncCtx.Recorder().Warning("HubPermissionsDenied", condition.Message)
+		}
+	}
+
+	// the condition is not reported if the agent is not allowed to patch the status of its cluster any more, the
+	// event above is the only report in that case
+	_, _, err = helpers.PatchManagedClusterStatus(ctx, c.hubClusterClient, c.clusterName,
+		helpers.UpdateManagedClusterConditionFn(condition))
+	return err
+}
+
+// requiredAccess returns the permissions the agent requires on the hub once the cluster is accepted, which are to
+// renew the lease of the cluster created by the hub in the cluster namespace, to update the managed cluster and its
+// status, and to request the client certificates if csrRequired is true.
+func (c *hubPermissionController) requiredAccess() []authorizationv1.ResourceAttributes {
+	attributes := []authorizationv1.ResourceAttributes{
+		{Group: "coordination.k8s.io", Resource: "leases", Namespace: c.clusterName, Name: managedClusterLeaseName, Verb: "get"},
+		{Group: "coordination.k8s.io", Resource: "leases", Namespace: c.clusterName, Name: managedClusterLeaseName, Verb: "patch"},
+		{Group: "cluster.open-cluster-management.io", Resource: "managedclusters", Name: c.clusterName, Verb: "update"},
+		{Group: "cluster.open-cluster-management.io", Resource: "managedclusters", Subresource: "status", Name: c.clusterName, Verb: "patch"},
+	}
+	if c.csrRequired {
+		attributes = append(attributes,
+			authorizationv1.ResourceAttributes{Group: "certificates.k8s.io", Resource: "certificatesigningrequests", Verb: "create"},
+			authorizationv1.ResourceAttributes{Group: "certificates.k8s.io", Resource: "certificatesigningrequests", Verb: "get"},
+		)
+	}
+	return attributes
+}
+
+// reviewAccess reviews the required permissions with SelfSubjectAccessReviews, and returns the denied ones
+func (c *hubPermissionController) reviewAccess(ctx context.Context) ([]string, error) {
+	denied := []string{}
+	attributes := c.requiredAccess()
+	for i := range attributes {
+		sar := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attributes[i]},
+		}
+		sar, err := c.hubKubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, sar, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to review the access on the hub: %w", err)
+		}
+		if !sar.Status.Allowed {
+			denied = append(denied, formatAttributes(attributes[i]))
+		}
+	}
+	return denied, nil
+}
+
+func formatAttributes(attributes authorizationv1.ResourceAttributes) string {
+	resource := attributes.Resource
+	if len(attributes.Subresource) > 0 {
+		resource = resource + "/" + attributes.Subresource
+	}
+	if len(attributes.Group) > 0 {
+		resource = resource + "." + attributes.Group
+	}
+	if len(attributes.Name) > 0 {
+		resource = resource + " " + attributes.Name
+	}
+	if len(attributes.Namespace) > 0 {
+		return fmt.Sprintf("%s %s in %s", attributes.Verb, resource, attributes.Namespace)
+	}
+	return fmt.Sprintf("%s %s", attributes.Verb, resource)
+}
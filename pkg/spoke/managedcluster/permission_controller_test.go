@@ -0,0 +1,107 @@
+package managedcluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestHubPermissionSync(t *testing.T) {
+	cases := []struct {
+		name              string
+		clusters          []runtime.Object
+		csrRequired       bool
+		deniedResources   []string
+		expectedReviews   int
+		expectedCondition *metav1.Condition
+	}{
+		{
+			name: "no cluster",
+		},
+		{
+			name:     "cluster not accepted",
+			clusters: []runtime.Object{testinghelpers.NewManagedCluster()},
+		},
+		{
+			name:            "permissions granted",
+			clusters:        []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			csrRequired:     true,
+			expectedReviews: 6,
+			expectedCondition: &metav1.Condition{
+				Type:    HubPermissionsGrantedCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  "HubPermissionsGranted",
+				Message: "The permissions required by the registration agent are granted on the hub",
+			},
+		},
+		{
+			name:            "permissions denied",
+			clusters:        []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			deniedResources: []string{"leases", "certificatesigningrequests"},
+			expectedReviews: 4,
+			expectedCondition: &metav1.Condition{
+				Type:   HubPermissionsGrantedCondition,
+				Status: metav1.ConditionFalse,
+				Reason: "HubPermissionsDenied",
+				Message: "The registration agent is not allowed to get leases.coordination.k8s.io managed-cluster-lease " +
+					"in testmanagedcluster, patch leases.coordination.k8s.io managed-cluster-lease in testmanagedcluster on the hub",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hubKubeClient := kubefake.NewSimpleClientset()
+			reviews := 0
+			hubKubeClient.PrependReactor("create", "selfsubjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				reviews++
+				sar := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+				sar.Status.Allowed = true
+				for _, resource := range c.deniedResources {
+					sar.Status.Allowed = sar.Status.Allowed && sar.Spec.ResourceAttributes.Resource != resource
+				}
+				return true, sar, nil
+			})
+
+			clusterClient := clusterfake.NewSimpleClientset(c.clusters...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+			for _, cluster := range c.clusters {
+				clusterStore.Add(cluster)
+			}
+
+			ctrl := &hubPermissionController{
+				clusterName:      testinghelpers.TestManagedClusterName,
+				csrRequired:      c.csrRequired,
+				hubKubeClient:    hubKubeClient,
+				hubClusterClient: clusterClient,
+				hubClusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+			}
+			if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "")); err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+
+			if reviews != c.expectedReviews {
+				t.Errorf("expected %d reviews, but got %d", c.expectedReviews, reviews)
+			}
+			if c.expectedCondition == nil {
+				testinghelpers.AssertNoActions(t, clusterClient.Actions())
+				return
+			}
+			actions := clusterClient.Actions()
+			testinghelpers.AssertActions(t, actions, "get", "patch")
+			managedCluster := testinghelpers.PatchedManagedCluster(t, actions[1])
+			testinghelpers.AssertManagedClusterCondition(t, managedCluster.Status.Conditions, *c.expectedCondition)
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package managedcluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1alpha1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1alpha1"
+	clusterv1alpha1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1alpha1"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const clusterPropertyControllerName = "ClusterPropertyController"
+
+// ClusterPropertyGVR is the resource of the ClusterProperties of the About API (KEP-2149)
+var ClusterPropertyGVR = schema.GroupVersionResource{Group: "about.k8s.io", Version: "v1alpha1", Resource: "clusterproperties"}
+
+const (
+	// ClusterPropertyLabel is the label of the ClusterClaims imported from the ClusterProperties
+	ClusterPropertyLabel = "open-cluster-management.io/cluster-property"
+
+	// ClusterClaimLabel is the label of the ClusterProperties written back from the ClusterClaims
+	ClusterClaimLabel = "open-cluster-management.io/cluster-claim"
+)
+
+// ClusterPropertyPollInterval is the interval the ClusterProperties are read in. The ClusterProperties are not
+// watched, so the agent does not depend on the CRD of the About API unless the feature is enabled. It is exposed so
+// that integration tests can shorten it.
+var ClusterPropertyPollInterval = time.Minute
+
+// propertyClaimNames maps the names of the well-known ClusterProperties to the names of the reserved claims with
+// the same meaning, the other ClusterProperties are imported as the claims of their own names
+var propertyClaimNames = map[string]string{
+	"cluster.clusterset.k8s.io": "id.k8s.io",
+}
+
+// clusterPropertyController bridges the ClusterProperties of the About API with the ClusterClaims on the managed
+// cluster. Each ClusterProperty is imported as a ClusterClaim labeled with ClusterPropertyLabel, so it is exposed on
+// the hub as any other claim, and each other ClusterClaim is written back as a ClusterProperty labeled with
+// ClusterClaimLabel if writeBack is true. The ClusterClaims and ClusterProperties created by the users are never
+// overwritten, and the ones created by the controller are deleted once their sources are gone.
+type clusterPropertyController struct {
+	spokeClusterClient clientset.Interface
+	dynamicClient      dynamic.Interface
+	claimLister        clusterv1alpha1listers.ClusterClaimLister
+	writeBack          bool
+	eventRecorder      events.Recorder
+}
+
+// NewClusterPropertyController returns an instance of clusterPropertyController
+func NewClusterPropertyController(
+	spokeClusterClient clientset.Interface,
+	dynamicClient dynamic.Interface,
+	claimInformer clusterv1alpha1informer.ClusterClaimInformer,
+	writeBack bool,
+	recorder events.Recorder) factory.Controller {
+	c := &clusterPropertyController{
+		spokeClusterClient: spokeClusterClient,
+		dynamicClient:      dynamicClient,
+		claimLister:        claimInformer.Lister(),
+		writeBack:          writeBack,
+		eventRecorder:      recorder.WithComponentSuffix("cluster-property-controller"),
+	}
+
+	return factory.New().
+		WithInformers(claimInformer.Informer()).
+		WithSync(health.WrapSync(clusterPropertyControllerName, c.sync)).
+		ResyncEvery(ClusterPropertyPollInterval).
+		ToController(clusterPropertyControllerName, recorder)
+}
+
+func (c *clusterPropertyController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	helpers.ControllerLogger(ctx, clusterPropertyControllerName).V(helpers.LogLevelDebug).Info(
+		"Reconciling ClusterProperties", "writeBack", c.writeBack)
+
+	propertyList, err := c.dynamicClient.Resource(ClusterPropertyGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	properties := map[string]*unstructured.Unstructured{}
+	for i := range propertyList.Items {
+		properties[propertyList.Items[i].GetName()] = &propertyList.Items[i]
+	}
+
+	claimList, err := c.claimLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	claims := map[string]*clusterv1alpha1.ClusterClaim{}
+	for _, claim := range claimList {
+		claims[claim.Name] = claim
+	}
+
+	errs := c.importProperties(ctx, properties, claims)
+	errs = append(errs, c.writeBackClaims(ctx, properties, claims)...)
+	return operatorhelpers.NewMultiLineAggregate(errs)
+}
+
+// importProperties applies a ClusterClaim for each ClusterProperty which is not written back from a claim, and
+// deletes the imported ClusterClaims whose ClusterProperties are gone
+func (c *clusterPropertyController) importProperties(ctx context.Context,
+	properties map[string]*unstructured.Unstructured, claims map[string]*clusterv1alpha1.ClusterClaim) []error {
+	errs := []error{}
+	imported := map[string]bool{}
+	for _, name := range sets.StringKeySet(properties).List() {
+		property := properties[name]
+		if isWrittenBackProperty(property) {
+			continue
+		}
+		claimName := claimNameOfProperty(name)
+		imported[claimName] = true
+		value, _, _ := unstructured.NestedString(property.Object, "spec", "value")
+
+		claim, ok := claims[claimName]
+		switch {
+		case !ok:
+			_, err := c.spokeClusterClient.ClusterV1alpha1().ClusterClaims().Create(ctx, &clusterv1alpha1.ClusterClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   claimName,
+					Labels: map[string]string{ClusterPropertyLabel: "true"},
+				},
+				Spec: clusterv1alpha1.ClusterClaimSpec{Value: value},
+			}, metav1.CreateOptions{})
+			if err != nil && !errors.IsAlreadyExists(err) {
+				errs = append(errs, err)
+				continue
+			}
+			c.eventRecorder.Eventf("ClusterPropertyImported", "ClusterProperty %q is imported as claim %q", name, claimName)
+		case !isImportedClaim(claim):
+			// the claims created by the users take precedence over the ClusterProperties
+			continue
+		case claim.Spec.Value != value:
+			claim = claim.DeepCopy()
+			claim.Spec.Value = value
+			if _, err := c.spokeClusterClient.ClusterV1alpha1().ClusterClaims().Update(ctx, claim, metav1.UpdateOptions{}); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	for _, name := range sets.StringKeySet(claims).List() {
+		if !isImportedClaim(claims[name]) || imported[name] {
+			continue
+		}
+		err := c.spokeClusterClient.ClusterV1alpha1().ClusterClaims().Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// writeBackClaims applies a ClusterProperty for each ClusterClaim which is not imported from a ClusterProperty, and
+// deletes the written back ClusterProperties whose claims are gone. All of the written back ClusterProperties are
+// deleted if writeBack is false.
+func (c *clusterPropertyController) writeBackClaims(ctx context.Context,
+	properties map[string]*unstructured.Unstructured, claims map[string]*clusterv1alpha1.ClusterClaim) []error {
+	errs := []error{}
+	writtenBack := map[string]bool{}
+	for _, name := range sets.StringKeySet(claims).List() {
+		claim := claims[name]
+		if !c.writeBack || isImportedClaim(claim) {
+			continue
+		}
+		propertyName := propertyNameOfClaim(name)
+		writtenBack[propertyName] = true
+
+		property, ok := properties[propertyName]
+		switch {
+		case !ok:
+			property = &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"value": claim.Spec.Value},
+			}}
+			property.SetAPIVersion(ClusterPropertyGVR.GroupVersion().String())
+			property.SetKind("ClusterProperty")
+			property.SetName(propertyName)
+			property.SetLabels(map[string]string{ClusterClaimLabel: "true"})
+			_, err := c.dynamicClient.Resource(ClusterPropertyGVR).Create(ctx, property, metav1.CreateOptions{})
+			if err != nil && !errors.IsAlreadyExists(err) {
+				errs = append(errs, err)
+			}
+		case !isWrittenBackProperty(property):
+			// the ClusterProperties created by the users or the other tools are never overwritten
+			continue
+		default:
+			value, _, _ := unstructured.NestedString(property.Object, "spec", "value")
+			if value == claim.Spec.Value {
+				continue
+			}
+			property = property.DeepCopy()
+			if err := unstructured.SetNestedField(property.Object, claim.Spec.Value, "spec", "value"); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if _, err := c.dynamicClient.Resource(ClusterPropertyGVR).Update(ctx, property, metav1.UpdateOptions{}); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	for _, name := range sets.StringKeySet(properties).List() {
+		if !isWrittenBackProperty(properties[name]) || writtenBack[name] {
+			continue
+		}
+		err := c.dynamicClient.Resource(ClusterPropertyGVR).Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// isImportedClaim returns true if the ClusterClaim is imported from a ClusterProperty
+func isImportedClaim(claim *clusterv1alpha1.ClusterClaim) bool {
+	return claim.Labels[ClusterPropertyLabel] == "true"
+}
+
+// isWrittenBackProperty returns true if the ClusterProperty is written back from a ClusterClaim
+func isWrittenBackProperty(property *unstructured.Unstructured) bool {
+	return property.GetLabels()[ClusterClaimLabel] == "true"
+}
+
+// claimNameOfProperty returns the name of the ClusterClaim a ClusterProperty is imported as
+func claimNameOfProperty(propertyName string) string {
+	if claimName, ok := propertyClaimNames[propertyName]; ok {
+		return claimName
+	}
+	return propertyName
+}
+
+// propertyNameOfClaim returns the name of the ClusterProperty a ClusterClaim is written back as
+func propertyNameOfClaim(claimName string) string {
+	for propertyName, name := range propertyClaimNames {
+		if name == claimName {
+			return propertyName
+		}
+	}
+	return claimName
+}
@@ -0,0 +1,188 @@
+package managedcluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func newClusterProperty(name, value string, writtenBack bool) *unstructured.Unstructured {
+	property := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"value": value},
+	}}
+	property.SetAPIVersion(ClusterPropertyGVR.GroupVersion().String())
+	property.SetKind("ClusterProperty")
+	property.SetName(name)
+	if writtenBack {
+		property.SetLabels(map[string]string{ClusterClaimLabel: "true"})
+	}
+	return property
+}
+
+func newClusterClaim(name, value string, imported bool) *clusterv1alpha1.ClusterClaim {
+	claim := &clusterv1alpha1.ClusterClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       clusterv1alpha1.ClusterClaimSpec{Value: value},
+	}
+	if imported {
+		claim.Labels = map[string]string{ClusterPropertyLabel: "true"}
+	}
+	return claim
+}
+
+func TestClusterPropertySync(t *testing.T) {
+	cases := []struct {
+		name                   string
+		writeBack              bool
+		properties             []runtime.Object
+		claims                 []runtime.Object
+		validateClaimActions   func(t *testing.T, actions []clienttesting.Action)
+		validateDynamicActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:       "import ClusterProperties",
+			properties: []runtime.Object{newClusterProperty("cluster.clusterset.k8s.io", "cluster1-id", false)},
+			validateClaimActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "create")
+				claim := actions[0].(clienttesting.CreateActionImpl).Object.(*clusterv1alpha1.ClusterClaim)
+				if claim.Name != "id.k8s.io" || claim.Spec.Value != "cluster1-id" || !isImportedClaim(claim) {
+					t.Errorf("expected the id claim to be imported, but got %v", claim)
+				}
+			},
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+		},
+		{
+			name:       "ClusterProperty changed",
+			properties: []runtime.Object{newClusterProperty("clusterset.k8s.io", "set2", false)},
+			claims:     []runtime.Object{newClusterClaim("clusterset.k8s.io", "set1", true)},
+			validateClaimActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				claim := actions[0].(clienttesting.UpdateActionImpl).Object.(*clusterv1alpha1.ClusterClaim)
+				if claim.Spec.Value != "set2" {
+					t.Errorf("expected the claim to be updated, but got %v", claim)
+				}
+			},
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+		},
+		{
+			name:                 "claim created by user",
+			properties:           []runtime.Object{newClusterProperty("cluster.clusterset.k8s.io", "cluster1-id", false)},
+			claims:               []runtime.Object{newClusterClaim("id.k8s.io", "user-id", false)},
+			validateClaimActions: testinghelpers.AssertNoActions,
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+		},
+		{
+			name:   "ClusterProperty deleted",
+			claims: []runtime.Object{newClusterClaim("clusterset.k8s.io", "set1", true)},
+			validateClaimActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete")
+			},
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+		},
+		{
+			name:      "write back claims",
+			writeBack: true,
+			properties: []runtime.Object{
+				newClusterProperty("clusterset.k8s.io", "set1", false),
+				newClusterProperty("platform.open-cluster-management.io", "GCP", true),
+			},
+			claims: []runtime.Object{
+				newClusterClaim("clusterset.k8s.io", "set1", true),
+				newClusterClaim("id.k8s.io", "cluster1-id", false),
+				newClusterClaim("platform.open-cluster-management.io", "AWS", false),
+			},
+			validateClaimActions: testinghelpers.AssertNoActions,
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list", "create", "update")
+				created := actions[1].(clienttesting.CreateActionImpl).Object.(*unstructured.Unstructured)
+				if value, _, _ := unstructured.NestedString(created.Object, "spec", "value"); created.GetName() != "cluster.clusterset.k8s.io" ||
+					value != "cluster1-id" || !isWrittenBackProperty(created) {
+					t.Errorf("expected the id claim to be written back, but got %v", created)
+				}
+				updated := actions[2].(clienttesting.UpdateActionImpl).Object.(*unstructured.Unstructured)
+				if value, _, _ := unstructured.NestedString(updated.Object, "spec", "value"); updated.GetName() != "platform.open-cluster-management.io" ||
+					value != "AWS" {
+					t.Errorf("expected the platform property to be updated, but got %v", updated)
+				}
+			},
+		},
+		{
+			name:      "ClusterProperty created by user",
+			writeBack: true,
+			properties: []runtime.Object{
+				newClusterProperty("cluster.clusterset.k8s.io", "cluster1-id", false),
+			},
+			claims: []runtime.Object{
+				newClusterClaim("id.k8s.io", "user-id", false),
+			},
+			validateClaimActions: testinghelpers.AssertNoActions,
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list")
+			},
+		},
+		{
+			name: "write back disabled",
+			properties: []runtime.Object{
+				newClusterProperty("platform.open-cluster-management.io", "AWS", true),
+			},
+			claims: []runtime.Object{
+				newClusterClaim("platform.open-cluster-management.io", "AWS", false),
+			},
+			validateClaimActions: testinghelpers.AssertNoActions,
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "list", "delete")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.claims...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			for _, claim := range c.claims {
+				if err := clusterInformerFactory.Cluster().V1alpha1().ClusterClaims().Informer().GetStore().Add(claim); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{ClusterPropertyGVR: "ClusterPropertyList"}, c.properties...)
+
+			ctrl := &clusterPropertyController{
+				spokeClusterClient: clusterClient,
+				dynamicClient:      dynamicClient,
+				claimLister:        clusterInformerFactory.Cluster().V1alpha1().ClusterClaims().Lister(),
+				writeBack:          c.writeBack,
+				eventRecorder:      eventstesting.NewTestingEventRecorder(t),
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateClaimActions(t, clusterClient.Actions())
+			c.validateDynamicActions(t, dynamicClient.Actions())
+		})
+	}
+}
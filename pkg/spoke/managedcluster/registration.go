@@ -7,7 +7,6 @@ import (
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
-	certificates "k8s.io/api/certificates/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	certificatesinformers "k8s.io/client-go/informers/certificates"
@@ -16,15 +15,20 @@ import (
 	certutil "k8s.io/client-go/util/cert"
 
 	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
 	"open-cluster-management.io/registration/pkg/hub/user"
 )
 
 // NewClientCertForHubController returns a controller to
 // 1). Create a new client certificate and build a hub kubeconfig for the registration agent;
 // 2). Or rotate the client certificate referenced by the hub kubeconfig before it become expired;
+// The csrs are requested with the signerName, which is the kube-apiserver-client signer if it is empty. The opts
+// are applied after the options of the registration agent, e.g. clientcert.WithCSRControl to request the client
+// certificates from Vault instead of the csrs on the hub.
 func NewClientCertForHubController(
 	clusterName string,
 	agentName string,
+	signerName string,
 	clientCertSecretNamespace string,
 	clientCertSecretName string,
 	kubeconfigData []byte,
@@ -34,33 +38,43 @@ func NewClientCertForHubController(
 	hubKubeClient kubernetes.Interface,
 	recorder events.Recorder,
 	controllerName string,
+	opts ...clientcert.Option,
 ) (factory.Controller, error) {
-	clientCertOption := clientcert.ClientCertOption{
-		SecretNamespace: clientCertSecretNamespace,
-		SecretName:      clientCertSecretName,
-		AdditionalSecretData: map[string][]byte{
+	return clientcert.NewController(
+		clientCertSecretNamespace,
+		clientCertSecretName,
+		hubCSRInformer,
+		spokeSecretInformer,
+		spokeKubeClient,
+		hubKubeClient,
+		recorder,
+		controllerName,
+		append(newClientCertOptions(clusterName, agentName, signerName, kubeconfigData), opts...)...,
+	)
+}
+
+// newClientCertOptions returns the options of the client certificate of the registration agent
+func newClientCertOptions(clusterName, agentName, signerName string, kubeconfigData []byte) []clientcert.Option {
+	opts := []clientcert.Option{
+		clientcert.WithSecretData(map[string][]byte{
 			clientcert.ClusterNameFile: []byte(clusterName),
 			clientcert.AgentNameFile:   []byte(agentName),
 			clientcert.KubeconfigFile:  kubeconfigData,
-		},
-	}
-	csrOption := clientcert.CSROption{
-		ObjectMeta: metav1.ObjectMeta{
-			GenerateName: fmt.Sprintf("%s-", clusterName),
-			Labels: map[string]string{
-				// the label is only an hint for cluster name. Anyone could set/modify it.
-				clientcert.ClusterNameLabel: clusterName,
-			},
-		},
-		Subject: &pkix.Name{
+		}, false),
+		clientcert.WithSubject(&pkix.Name{
 			Organization: []string{
 				fmt.Sprintf("%s%s", user.SubjectPrefix, clusterName),
 				user.ManagedClustersGroup,
 			},
 			CommonName: fmt.Sprintf("%s%s:%s", user.SubjectPrefix, clusterName, agentName),
-		},
-		SignerName: certificates.KubeAPIServerClientSignerName,
-		EventFilterFunc: func(obj interface{}) bool {
+		}),
+		clientcert.WithCSRObjectMeta(metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-", clusterName),
+			Labels: map[string]string{
+				// the label is only an hint for cluster name. Anyone could set/modify it.
+				clientcert.ClusterNameLabel: clusterName,
+			},
+		}, func(obj interface{}) bool {
 			accessor, err := meta.Accessor(obj)
 			if err != nil {
 				return false
@@ -73,21 +87,86 @@ func NewClientCertForHubController(
 
 			// only enqueue csr whose name starts with the cluster name
 			return strings.HasPrefix(accessor.GetName(), fmt.Sprintf("%s-", clusterName))
-		},
+		}),
+	}
+	if len(signerName) > 0 {
+		opts = append(opts, clientcert.WithSigner(signerName))
 	}
+	return opts
+}
 
-	return clientcert.NewClientCertificateController(
-		clientCertOption,
-		csrOption,
+// NewReverseTunnelCertController returns a controller to create and rotate the client certificate of the reverse
+// tunnel agent of the managed cluster, e.g. the proxy agent of cluster-proxy, in a secret on the managed cluster. The
+// csrs are requested with the signer of the reverse tunnel, which signs them once the hub approves them, so the
+// tunnel is established along with the registration instead of waiting for an addon to be installed.
+func NewReverseTunnelCertController(
+	clusterName string,
+	agentName string,
+	signerName string,
+	secretNamespace string,
+	secretName string,
+	spokeSecretInformer corev1informers.SecretInformer,
+	hubCSRInformer certificatesinformers.Interface,
+	spokeKubeClient kubernetes.Interface,
+	hubKubeClient kubernetes.Interface,
+	recorder events.Recorder,
+	controllerName string,
+	opts ...clientcert.Option,
+) (factory.Controller, error) {
+	objectMeta, eventFilterFunc := reverseTunnelCSRObjectMeta(clusterName)
+	return clientcert.NewController(
+		secretNamespace,
+		secretName,
 		hubCSRInformer,
 		spokeSecretInformer,
 		spokeKubeClient,
 		hubKubeClient,
 		recorder,
 		controllerName,
+		append([]clientcert.Option{
+			clientcert.WithSecretData(map[string][]byte{
+				clientcert.ClusterNameFile: []byte(clusterName),
+				clientcert.AgentNameFile:   []byte(agentName),
+			}, false),
+			clientcert.WithSubject(reverseTunnelSubject(clusterName, agentName)),
+			clientcert.WithSigner(signerName),
+			clientcert.WithCSRObjectMeta(objectMeta, eventFilterFunc),
+		}, opts...)...,
 	)
 }
 
+// reverseTunnelSubject returns the subject of the client certificate of the reverse tunnel agent
+func reverseTunnelSubject(clusterName, agentName string) *pkix.Name {
+	return &pkix.Name{
+		Organization: []string{helpers.ReverseTunnelGroup(clusterName)},
+		CommonName:   helpers.ReverseTunnelUser(clusterName, agentName),
+	}
+}
+
+// reverseTunnelCSRObjectMeta returns the ObjectMeta of the reverse tunnel csrs and the function to match them
+func reverseTunnelCSRObjectMeta(clusterName string) (metav1.ObjectMeta, factory.EventFilterFunc) {
+	generateName := fmt.Sprintf("reverse-tunnel-%s-", clusterName)
+	return metav1.ObjectMeta{
+		GenerateName: generateName,
+		Labels: map[string]string{
+			// the labels are only hints. Anyone could set/modify them.
+			clientcert.ClusterNameLabel: clusterName,
+			helpers.ReverseTunnelLabel:  "true",
+		},
+	}, func(obj interface{}) bool {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return false
+		}
+		labels := accessor.GetLabels()
+		// only enqueue the reverse tunnel csrs of the managed cluster
+		if labels[clientcert.ClusterNameLabel] != clusterName || labels[helpers.ReverseTunnelLabel] != "true" {
+			return false
+		}
+		return strings.HasPrefix(accessor.GetName(), generateName)
+	}
+}
+
 // GetClusterAgentNamesFromCertificate returns the cluster name and agent name by parsing
 // the common name of the certification
 func GetClusterAgentNamesFromCertificate(certData []byte) (clusterName, agentName string, err error) {
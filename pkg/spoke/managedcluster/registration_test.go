@@ -1,9 +1,15 @@
 package managedcluster
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
+	certificatesv1 "k8s.io/api/certificates/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 )
 
@@ -46,3 +52,48 @@ func TestGetClusterAgentNamesFromCertificate(t *testing.T) {
 		})
 	}
 }
+
+func TestReverseTunnelCSRObjectMeta(t *testing.T) {
+	subject := reverseTunnelSubject("cluster1", "agent1")
+	if subject.CommonName != "system:open-cluster-management:cluster:cluster1:reverse-tunnel:agent:agent1" ||
+		!reflect.DeepEqual(subject.Organization, []string{"system:open-cluster-management:cluster:cluster1:reverse-tunnel"}) {
+		t.Errorf("unexpected subject %v", subject)
+	}
+
+	_, eventFilterFunc := reverseTunnelCSRObjectMeta("cluster1")
+	cases := []struct {
+		name     string
+		csr      *certificatesv1.CertificateSigningRequest
+		expected bool
+	}{
+		{
+			name: "reverse tunnel csr",
+			csr: &certificatesv1.CertificateSigningRequest{ObjectMeta: metav1.ObjectMeta{
+				Name:   "reverse-tunnel-cluster1-abcde",
+				Labels: map[string]string{clientcert.ClusterNameLabel: "cluster1", helpers.ReverseTunnelLabel: "true"},
+			}},
+			expected: true,
+		},
+		{
+			name: "csr of the registration agent",
+			csr: &certificatesv1.CertificateSigningRequest{ObjectMeta: metav1.ObjectMeta{
+				Name:   "cluster1-abcde",
+				Labels: map[string]string{clientcert.ClusterNameLabel: "cluster1"},
+			}},
+		},
+		{
+			name: "reverse tunnel csr of another cluster",
+			csr: &certificatesv1.CertificateSigningRequest{ObjectMeta: metav1.ObjectMeta{
+				Name:   "reverse-tunnel-cluster2-abcde",
+				Labels: map[string]string{clientcert.ClusterNameLabel: "cluster2", helpers.ReverseTunnelLabel: "true"},
+			}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := eventFilterFunc(c.csr); actual != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
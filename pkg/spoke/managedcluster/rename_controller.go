@@ -0,0 +1,105 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const renameControllerName = "RenameController"
+
+// renameController re-registers the agent with the new name once the managed cluster is renamed on the hub, see
+// helpers.RenameTargetAnnotation. The new name is written into the hub kubeconfig secret, which is annotated with
+// ReregistrationAnnotation, so the hub credentials of the old name are discarded by the reregistrationController
+// and the agent bootstraps again with the new name. The cluster cannot be renamed if the agent is configured with
+// the cluster name explicitly.
+type renameController struct {
+	clusterName                  string
+	renamable                    bool
+	hubKubeconfigSecretNamespace string
+	hubKubeconfigSecretName      string
+	spokeCoreClient              corev1client.CoreV1Interface
+	hubClusterLister             clusterv1listers.ManagedClusterLister
+}
+
+// NewRenameController returns an instance of renameController
+func NewRenameController(
+	clusterName string,
+	renamable bool,
+	hubKubeconfigSecretNamespace, hubKubeconfigSecretName string,
+	spokeCoreClient corev1client.CoreV1Interface,
+	hubClusterInformer clusterv1informer.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &renameController{
+		clusterName:                  clusterName,
+		renamable:                    renamable,
+		hubKubeconfigSecretNamespace: hubKubeconfigSecretNamespace,
+		hubKubeconfigSecretName:      hubKubeconfigSecretName,
+		spokeCoreClient:              spokeCoreClient,
+		hubClusterLister:             hubClusterInformer.Lister(),
+	}
+
+	return factory.New().
+		WithInformers(hubClusterInformer.Informer()).
+		WithSync(health.WrapSync(renameControllerName, c.sync)).
+		ToController(renameControllerName, recorder)
+}
+
+func (c *renameController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	cluster, err := c.hubClusterLister.Get(c.clusterName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	newName := cluster.Annotations[helpers.RenameTargetAnnotation]
+	if len(newName) == 0 || newName == c.clusterName {
+		return nil
+	}
+	if !c.renamable {
+		syncCtx.Recorder().Warningf("ManagedClusterRenameUnsupported",
+			"The managed cluster %q cannot be renamed to %q since the agent is configured with the cluster name",
+			c.clusterName, newName)
+		return nil
+	}
+
+	secret, err := c.spokeCoreClient.Secrets(c.hubKubeconfigSecretNamespace).Get(ctx, c.hubKubeconfigSecretName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if string(secret.Data[clientcert.ClusterNameFile]) == newName {
+		// the agent is re-registering with the new name
+		return nil
+	}
+
+	// the secret is patched instead of updated, so the fields written by other tools are not stomped
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{ReregistrationAnnotation: "true"},
+		},
+		"data": map[string]interface{}{clientcert.ClusterNameFile: []byte(newName)},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.spokeCoreClient.Secrets(secret.Namespace).Patch(ctx, secret.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+	syncCtx.Recorder().Eventf("ManagedClusterRenaming", "The managed cluster %q is renamed to %q on the hub, the agent re-registers with the new name",
+		c.clusterName, newName)
+	return nil
+}
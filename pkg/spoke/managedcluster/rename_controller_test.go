@@ -0,0 +1,98 @@
+package managedcluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestRenameSync(t *testing.T) {
+	newSecret := func(clusterName string) *corev1.Secret {
+		return testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "", nil, map[string][]byte{
+			clientcert.ClusterNameFile: []byte(clusterName),
+		})
+	}
+
+	cases := []struct {
+		name            string
+		renameTarget    string
+		renamable       bool
+		secret          *corev1.Secret
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:            "cluster is not renamed",
+			renamable:       true,
+			secret:          newSecret(testinghelpers.TestManagedClusterName),
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "cluster name is configured",
+			renameTarget:    "cluster2",
+			secret:          newSecret(testinghelpers.TestManagedClusterName),
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:         "re-register with the new name",
+			renameTarget: "cluster2",
+			renamable:    true,
+			secret:       newSecret(testinghelpers.TestManagedClusterName),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				patch := string(actions[1].(clienttesting.PatchActionImpl).Patch)
+				expectedPatch := `{"data":{"cluster-name":"Y2x1c3RlcjI="},` +
+					`"metadata":{"annotations":{"open-cluster-management.io/force-reregistration":"true"}}}`
+				if patch != expectedPatch {
+					t.Errorf("expected patch %s, but got %s", expectedPatch, patch)
+				}
+			},
+		},
+		{
+			name:         "re-registering with the new name",
+			renameTarget: "cluster2",
+			renamable:    true,
+			secret:       newSecret("cluster2"),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := testinghelpers.NewAvailableManagedCluster()
+			if len(c.renameTarget) > 0 {
+				cluster.Annotations = map[string]string{helpers.RenameTargetAnnotation: c.renameTarget}
+			}
+			clusterClient := clusterfake.NewSimpleClientset(cluster)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, 10*time.Minute)
+			if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+				t.Fatal(err)
+			}
+			kubeClient := kubefake.NewSimpleClientset(c.secret)
+
+			ctrl := &renameController{
+				clusterName:                  testinghelpers.TestManagedClusterName,
+				renamable:                    c.renamable,
+				hubKubeconfigSecretNamespace: testNamespace,
+				hubKubeconfigSecretName:      testSecretName,
+				spokeCoreClient:              kubeClient.CoreV1(),
+				hubClusterLister:             clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+			}
+			if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "")); err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+			c.validateActions(t, kubeClient.Actions())
+		})
+	}
+}
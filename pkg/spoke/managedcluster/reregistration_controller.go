@@ -0,0 +1,145 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// ReregistrationAnnotation is the annotation on the hub kubeconfig secret which asks the agent to discard its
+// current hub credentials and re-run bootstrap, e.g. when the cluster is migrated to another hub.
+const ReregistrationAnnotation = "open-cluster-management.io/force-reregistration"
+
+// hubCredentialFiles are the keys of the hub kubeconfig secret which are discarded on re-registration. The cluster
+// name and the agent name are kept, so the agent registers again with the same identity.
+var hubCredentialFiles = []string{
+	clientcert.KubeconfigFile,
+	clientcert.TLSCertFile,
+	clientcert.TLSKeyFile,
+}
+
+// reregistrationController watches the hub kubeconfig secret, once the secret is annotated with
+// ReregistrationAnnotation, the controller removes the hub credentials from both the secret and the hub kubeconfig
+// directory, and then asks the agent to restart so that the bootstrap is run again.
+type reregistrationController struct {
+	hubKubeconfigDir             string
+	hubKubeconfigSecretNamespace string
+	hubKubeconfigSecretName      string
+	spokeCoreClient              corev1client.CoreV1Interface
+	spokeSecretLister            corev1listers.SecretLister
+	reregister                   func()
+}
+
+// NewReregistrationController returns a new reregistrationController. The reregister func is called once the hub
+// credentials are discarded, it is expected to stop the agent.
+func NewReregistrationController(
+	hubKubeconfigDir, hubKubeconfigSecretNamespace, hubKubeconfigSecretName string,
+	spokeCoreClient corev1client.CoreV1Interface,
+	spokeSecretInformer corev1informers.SecretInformer,
+	reregister func(),
+	recorder events.Recorder) factory.Controller {
+	c := &reregistrationController{
+		hubKubeconfigDir:             hubKubeconfigDir,
+		hubKubeconfigSecretNamespace: hubKubeconfigSecretNamespace,
+		hubKubeconfigSecretName:      hubKubeconfigSecretName,
+		spokeCoreClient:              spokeCoreClient,
+		spokeSecretLister:            spokeSecretInformer.Lister(),
+		reregister:                   reregister,
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(
+			func(obj runtime.Object) string {
+				accessor, _ := meta.Accessor(obj)
+				return accessor.GetName()
+			},
+			func(obj interface{}) bool {
+				accessor, err := meta.Accessor(obj)
+				if err != nil {
+					return false
+				}
+				return accessor.GetNamespace() == hubKubeconfigSecretNamespace && accessor.GetName() == hubKubeconfigSecretName
+			}, spokeSecretInformer.Informer()).
+		WithSync(health.WrapSync("ReregistrationController", c.sync)).
+		ResyncEvery(5*time.Minute).
+		ToController("ReregistrationController", recorder)
+}
+
+func (c *reregistrationController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	secret, err := c.spokeSecretLister.Secrets(c.hubKubeconfigSecretNamespace).Get(c.hubKubeconfigSecretName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if secret.Annotations[ReregistrationAnnotation] != "true" {
+		return nil
+	}
+	helpers.ControllerLogger(ctx, "ReregistrationController").Info("Re-registration is requested on hub kubeconfig secret",
+		helpers.LogKeyResource, klog.KObj(secret))
+
+	// discard the credentials in the secret first, otherwise they might be dumped into the hub kubeconfig
+	// directory again after the files are removed. The annotation is kept until the files are removed as well.
+	// The secret is patched instead of updated, so the fields written by other tools are not stomped.
+	discarded := map[string]interface{}{}
+	for _, key := range hubCredentialFiles {
+		if _, ok := secret.Data[key]; ok {
+			discarded[key] = nil
+		}
+	}
+	if len(discarded) > 0 {
+		return c.patchSecret(ctx, secret.Namespace, secret.Name, map[string]interface{}{"data": discarded})
+	}
+
+	for _, key := range hubCredentialFiles {
+		filename := path.Clean(path.Join(c.hubKubeconfigDir, key))
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("unable to remove file %q: %w", filename, err)
+		}
+	}
+
+	if err := c.patchSecret(ctx, secret.Namespace, secret.Name, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{ReregistrationAnnotation: nil},
+		},
+	}); err != nil {
+		return err
+	}
+	syncCtx.Recorder().Eventf("HubCredentialsDiscarded",
+		"Hub credentials in secret %s/%s are discarded, the agent is restarting to re-run bootstrap", secret.Namespace, secret.Name)
+
+	c.reregister()
+	return nil
+}
+
+// patchSecret applies a json merge patch to the hub kubeconfig secret
+func (c *reregistrationController) patchSecret(ctx context.Context, namespace, name string, patch map[string]interface{}) error {
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	_, err = c.spokeCoreClient.Secrets(namespace).Patch(ctx, name, types.MergePatchType, data, metav1.PatchOptions{})
+	return err
+}
@@ -0,0 +1,144 @@
+package managedcluster
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestReregistrationSync(t *testing.T) {
+	newSecret := func(reregister bool, withCredentials bool) *corev1.Secret {
+		data := map[string][]byte{
+			clientcert.ClusterNameFile: []byte("cluster1"),
+			clientcert.AgentNameFile:   []byte("agent1"),
+		}
+		var cert *testinghelpers.TestCert
+		if withCredentials {
+			data[clientcert.KubeconfigFile] = testinghelpers.NewKubeconfig(nil, nil)
+			cert = testinghelpers.NewTestCert("system:open-cluster-management:cluster1:agent1", 60*time.Second)
+		}
+		secret := testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "", cert, data)
+		if reregister {
+			secret.Annotations = map[string]string{ReregistrationAnnotation: "true"}
+		}
+		return secret
+	}
+
+	cases := []struct {
+		name               string
+		secret             *corev1.Secret
+		files              []string
+		expectedReregister bool
+		validateActions    func(t *testing.T, actions []clienttesting.Action)
+		expectedFiles      []string
+	}{
+		{
+			name:            "no secret",
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "re-registration is not requested",
+			secret:          newSecret(false, true),
+			files:           []string{clientcert.KubeconfigFile, clientcert.TLSCertFile},
+			validateActions: testinghelpers.AssertNoActions,
+			expectedFiles:   []string{clientcert.KubeconfigFile, clientcert.TLSCertFile},
+		},
+		{
+			name:   "discard credentials in secret",
+			secret: newSecret(true, true),
+			files:  []string{clientcert.KubeconfigFile, clientcert.TLSCertFile},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				patch := string(actions[0].(clienttesting.PatchActionImpl).Patch)
+				expectedPatch := `{"data":{"kubeconfig":null,"tls.crt":null,"tls.key":null}}`
+				if patch != expectedPatch {
+					t.Errorf("expected the credentials to be discarded with patch %s, but got %s", expectedPatch, patch)
+				}
+			},
+			expectedFiles: []string{clientcert.KubeconfigFile, clientcert.TLSCertFile},
+		},
+		{
+			name:               "discard credential files and reregister",
+			secret:             newSecret(true, false),
+			files:              []string{clientcert.KubeconfigFile, clientcert.TLSCertFile, clientcert.ClusterNameFile},
+			expectedReregister: true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				patch := string(actions[0].(clienttesting.PatchActionImpl).Patch)
+				expectedPatch := `{"metadata":{"annotations":{"open-cluster-management.io/force-reregistration":null}}}`
+				if patch != expectedPatch {
+					t.Errorf("expected the annotation to be removed with patch %s, but got %s", expectedPatch, patch)
+				}
+			},
+			expectedFiles: []string{clientcert.ClusterNameFile},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hubKubeconfigDir, err := ioutil.TempDir("", "reregistration")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer os.RemoveAll(hubKubeconfigDir)
+			for _, file := range c.files {
+				testinghelpers.WriteFile(path.Join(hubKubeconfigDir, file), []byte("data"))
+			}
+
+			objects := []runtime.Object{}
+			if c.secret != nil {
+				objects = append(objects, c.secret)
+			}
+			kubeClient := kubefake.NewSimpleClientset(objects...)
+			informerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+			if c.secret != nil {
+				if err := informerFactory.Core().V1().Secrets().Informer().GetStore().Add(c.secret); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			reregistered := false
+			ctrl := &reregistrationController{
+				hubKubeconfigDir:             hubKubeconfigDir,
+				hubKubeconfigSecretNamespace: testNamespace,
+				hubKubeconfigSecretName:      testSecretName,
+				spokeCoreClient:              kubeClient.CoreV1(),
+				spokeSecretLister:            informerFactory.Core().V1().Secrets().Lister(),
+				reregister:                   func() { reregistered = true },
+			}
+
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testSecretName))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateActions(t, kubeClient.Actions())
+			if reregistered != c.expectedReregister {
+				t.Errorf("expected reregister %v, but got %v", c.expectedReregister, reregistered)
+			}
+
+			files, err := ioutil.ReadDir(hubKubeconfigDir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(files) != len(c.expectedFiles) {
+				t.Errorf("expected %d files, but got %d", len(c.expectedFiles), len(files))
+			}
+			for _, file := range c.expectedFiles {
+				testinghelpers.AssertFileExist(t, path.Join(hubKubeconfigDir, file))
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+)
+
+// ClusterIdentityAnnotation is the annotation on the hub kubeconfig secret which records the UID of the kube-system
+// namespace of the managed cluster the hub credentials in the secret are issued to. Backup tools are expected to
+// keep the annotation when the secret is backed up and restored. Once the secret is restored into another cluster,
+// the UIDs mismatch and the agent discards the restored identity and bootstraps again, so two clusters never share
+// one identity on the hub.
+const ClusterIdentityAnnotation = "open-cluster-management.io/cluster-identity"
+
+// restoredIdentityFiles are the keys of the hub kubeconfig secret which are discarded once the secret is restored
+// into another cluster. Unlike the re-registration, the names of the cluster and the agent are discarded as well,
+// so a new identity is used unless the cluster name is specified explicitly.
+var restoredIdentityFiles = []string{
+	clientcert.KubeconfigFile,
+	clientcert.TLSCertFile,
+	clientcert.TLSKeyFile,
+	TokenFile,
+	clientcert.ClusterNameFile,
+	clientcert.AgentNameFile,
+}
+
+// EnsureClusterIdentity compares the cluster identity recorded on the hub kubeconfig secret with the UID of the
+// kube-system namespace of the managed cluster. The identity is recorded if it is missing. If it mismatches, the
+// secret is restored from the backup of another cluster, so the hub credentials and the names in both the secret
+// and the hub kubeconfig directory are discarded, and the agent bootstraps again with a new identity. It is called
+// before the secret is dumped into the hub kubeconfig directory.
+func EnsureClusterIdentity(
+	ctx context.Context,
+	managementCoreClient, spokeCoreClient corev1client.CoreV1Interface,
+	secretNamespace, secretName, hubKubeconfigDir string,
+	recorder events.Recorder) error {
+	secret, err := managementCoreClient.Secrets(secretNamespace).Get(ctx, secretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get secret %s/%s : %w", secretNamespace, secretName, err)
+	}
+
+	kubeSystem, err := spokeCoreClient.Namespaces().Get(ctx, metav1.NamespaceSystem, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to get the identity of the managed cluster: %w", err)
+	}
+	identity := string(kubeSystem.UID)
+
+	recorded := secret.Annotations[ClusterIdentityAnnotation]
+	if recorded == identity {
+		return nil
+	}
+
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{ClusterIdentityAnnotation: identity},
+		},
+	}
+	if len(recorded) > 0 {
+		// the files are removed before the secret is patched, so the discarding is retried once the agent restarts
+		// if the patch fails.
+		for _, key := range restoredIdentityFiles {
+			filename := path.Clean(path.Join(hubKubeconfigDir, key))
+			if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("unable to remove file %q: %w", filename, err)
+			}
+		}
+
+		discarded := map[string]interface{}{}
+		for _, key := range restoredIdentityFiles {
+			if _, ok := secret.Data[key]; ok {
+				discarded[key] = nil
+			}
+		}
+		if len(discarded) > 0 {
+			patch["data"] = discarded
+		}
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	if _, err := managementCoreClient.Secrets(secretNamespace).Patch(
+		ctx, secretName, types.MergePatchType, data, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("unable to record the cluster identity on secret %s/%s: %w", secretNamespace, secretName, err)
+	}
+
+	if len(recorded) > 0 {
+		recorder.Warningf("HubIdentityDiscarded",
+			"Secret %s/%s is restored from cluster %q into cluster %q, the identity in it is discarded and the agent bootstraps again",
+			secretNamespace, secretName, recorded, identity)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package managedcluster
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestEnsureClusterIdentity(t *testing.T) {
+	newSecret := func(identity string) *corev1.Secret {
+		secret := testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "", nil, map[string][]byte{
+			clientcert.ClusterNameFile: []byte("cluster1"),
+			clientcert.AgentNameFile:   []byte("agent1"),
+			clientcert.KubeconfigFile:  testinghelpers.NewKubeconfig(nil, nil),
+		})
+		if len(identity) > 0 {
+			secret.Annotations = map[string]string{ClusterIdentityAnnotation: identity}
+		}
+		return secret
+	}
+
+	allFiles := []string{clientcert.ClusterNameFile, clientcert.AgentNameFile, clientcert.KubeconfigFile, "ca.crt"}
+	cases := []struct {
+		name            string
+		secret          *corev1.Secret
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+		expectedFiles   []string
+		expectedEvents  int
+	}{
+		{
+			name:            "no secret",
+			validateActions: testinghelpers.AssertNoActions,
+			expectedFiles:   allFiles,
+		},
+		{
+			name:   "record the identity",
+			secret: newSecret(""),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				patch := string(actions[0].(clienttesting.PatchActionImpl).Patch)
+				expectedPatch := `{"metadata":{"annotations":{"open-cluster-management.io/cluster-identity":"uid1"}}}`
+				if patch != expectedPatch {
+					t.Errorf("expected the identity to be recorded with patch %s, but got %s", expectedPatch, patch)
+				}
+			},
+			expectedFiles: allFiles,
+		},
+		{
+			name:            "identity matches",
+			secret:          newSecret("uid1"),
+			validateActions: testinghelpers.AssertNoActions,
+			expectedFiles:   allFiles,
+		},
+		{
+			name:   "secret is restored into another cluster",
+			secret: newSecret("uid0"),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				patch := string(actions[0].(clienttesting.PatchActionImpl).Patch)
+				expectedPatch := `{"data":{"agent-name":null,"cluster-name":null,"kubeconfig":null},` +
+					`"metadata":{"annotations":{"open-cluster-management.io/cluster-identity":"uid1"}}}`
+				if patch != expectedPatch {
+					t.Errorf("expected the identity to be discarded with patch %s, but got %s", expectedPatch, patch)
+				}
+			},
+			expectedFiles:  []string{"ca.crt"},
+			expectedEvents: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hubKubeconfigDir, err := ioutil.TempDir("", "restore")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer os.RemoveAll(hubKubeconfigDir)
+			for _, file := range allFiles {
+				testinghelpers.WriteFile(path.Join(hubKubeconfigDir, file), []byte("data"))
+			}
+
+			objects := []runtime.Object{}
+			if c.secret != nil {
+				objects = append(objects, c.secret)
+			}
+			managementClient := kubefake.NewSimpleClientset(objects...)
+			spokeClient := kubefake.NewSimpleClientset(&corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: metav1.NamespaceSystem, UID: "uid1"},
+			})
+
+			recorder := events.NewInMemoryRecorder("")
+			if err := EnsureClusterIdentity(context.TODO(), managementClient.CoreV1(), spokeClient.CoreV1(),
+				testNamespace, testSecretName, hubKubeconfigDir, recorder); err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+
+			// the get action is always issued first
+			c.validateActions(t, managementClient.Actions()[1:])
+			if len(recorder.Events()) != c.expectedEvents {
+				t.Errorf("expected %d events, but got %d", c.expectedEvents, len(recorder.Events()))
+			}
+
+			files, err := ioutil.ReadDir(hubKubeconfigDir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(files) != len(c.expectedFiles) {
+				t.Errorf("expected %d files, but got %d", len(c.expectedFiles), len(files))
+			}
+			for _, file := range c.expectedFiles {
+				testinghelpers.AssertFileExist(t, path.Join(hubKubeconfigDir, file))
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,6 +20,9 @@ import (
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
 )
 
 // hubKubeconfigSecretController watches the HubKubeconfig secret, if the secret is changed, this controller creates/updates the
@@ -60,14 +64,25 @@ func NewHubKubeconfigSecretController(
 				}
 				return false
 			}, spokeSecretInformer.Informer()).
-		WithSync(s.sync).
+		WithSync(health.WrapSync("HubKubeconfigSecretController", s.sync)).
 		ResyncEvery(5*time.Minute).
 		ToController("HubKubeconfigSecretController", recorder)
 }
 
 func (s *hubKubeconfigSecretController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
-	klog.V(4).Infof("Reconciling Hub KubeConfig secret %q", s.hubKubeconfigSecretName)
-	return DumpSecret(s.spokeCoreClient, s.hubKubeconfigSecretNamespace, s.hubKubeconfigSecretName, s.hubKubeconfigDir, ctx, syncCtx.Recorder())
+	helpers.ControllerLogger(ctx, "HubKubeconfigSecretController").V(helpers.LogLevelDebug).Info("Reconciling Hub KubeConfig secret",
+		helpers.LogKeyResource, klog.KRef(s.hubKubeconfigSecretNamespace, s.hubKubeconfigSecretName))
+	secret, err := s.spokeCoreClient.Secrets(s.hubKubeconfigSecretNamespace).Get(ctx, s.hubKubeconfigSecretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		recordHubCredentialMetrics(nil, time.Now())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get secret %s/%s : %w", s.hubKubeconfigSecretNamespace, s.hubKubeconfigSecretName, err)
+	}
+
+	recordHubCredentialMetrics(secret, time.Now())
+	return dumpSecretData(secret, s.hubKubeconfigDir, syncCtx.Recorder())
 }
 
 // DumpSecret dumps the data in the given seccret into a directory in file system.
@@ -86,6 +101,11 @@ func DumpSecret(
 		return fmt.Errorf("unable to get secret %s/%s : %w", secretNamespace, secretName, err)
 	}
 
+	return dumpSecretData(secret, outputDir, recorder)
+}
+
+// dumpSecretData dumps the data in the secret into a directory in file system.
+func dumpSecretData(secret *corev1.Secret, outputDir string, recorder events.Recorder) error {
 	if err := os.MkdirAll(outputDir, 0700); err != nil {
 		return fmt.Errorf("unable to create dir %q : %w", outputDir, err)
 	}
@@ -100,7 +120,7 @@ func DumpSecret(
 			if err := ioutil.WriteFile(filename, data, 0600); err != nil {
 				return fmt.Errorf("unable to write file %q: %w", filename, err)
 			}
-			recorder.Event("FileCreated", fmt.Sprintf("File %q is created from secret %s/%s", filename, secretNamespace, secretName))
+			recorder.Event("FileCreated", fmt.Sprintf("File %q is created from secret %s/%s", filename, secret.Namespace, secret.Name))
 		case err != nil:
 			return fmt.Errorf("unable to read file %q: %w", filename, err)
 		case bytes.Equal(lastData, data):
@@ -111,7 +131,7 @@ func DumpSecret(
 			if err := ioutil.WriteFile(path.Clean(filename), data, 0600); err != nil {
 				return fmt.Errorf("unable to write file %q: %w", filename, err)
 			}
-			recorder.Event("FileUpdated", fmt.Sprintf("File %q is updated from secret %s/%s", filename, secretNamespace, secretName))
+			recorder.Event("FileUpdated", fmt.Sprintf("File %q is updated from secret %s/%s", filename, secret.Namespace, secret.Name))
 		}
 	}
 	return nil
@@ -10,11 +10,13 @@ import (
 	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/health"
 	"open-cluster-management.io/registration/pkg/helpers"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -26,70 +28,122 @@ import (
 // managedClusterStatusController checks the kube-apiserver health on managed cluster to determine it whether is available
 // and ensure that the managed cluster resources and version are up to date.
 type managedClusterStatusController struct {
-	clusterName                   string
-	hubClusterClient              clientset.Interface
-	hubClusterLister              clusterv1listers.ManagedClusterLister
+	clusterName      string
+	hubClusterClient clientset.Interface
+	hubClusterLister clusterv1listers.ManagedClusterLister
+	statusCollector  StatusCollector
+}
+
+// StatusCollector collects the status of the managed cluster reported to the hub. It returns the available
+// condition of the cluster, and the other status of the cluster if it is available.
+type StatusCollector interface {
+	Collect(ctx context.Context) (metav1.Condition, *clusterv1.ManagedClusterStatus, error)
+}
+
+// ClusterStatusCollector collects the status of the managed cluster reported to the hub, which is the available
+// condition checked with the kube-apiserver health endpoints, and the version and resources of the cluster.
+type ClusterStatusCollector struct {
 	managedClusterDiscoveryClient discovery.DiscoveryInterface
 	nodeLister                    corev1lister.NodeLister
 }
 
-// NewManagedClusterStatusController creates a managed cluster status controller on managed cluster.
+// NewClusterStatusCollector returns a ClusterStatusCollector of the managed cluster
+func NewClusterStatusCollector(managedClusterDiscoveryClient discovery.DiscoveryInterface,
+	nodeLister corev1lister.NodeLister) *ClusterStatusCollector {
+	return &ClusterStatusCollector{
+		managedClusterDiscoveryClient: managedClusterDiscoveryClient,
+		nodeLister:                    nodeLister,
+	}
+}
+
+// NewManagedClusterStatusController creates a managed cluster status controller on managed cluster. The status is
+// resynced every resyncInterval, which may be changed while the controller runs.
 func NewManagedClusterStatusController(
 	clusterName string,
 	hubClusterClient clientset.Interface,
 	hubClusterInformer clusterv1informer.ManagedClusterInformer,
 	managedClusterDiscoveryClient discovery.DiscoveryInterface,
 	nodeInformer corev1informers.NodeInformer,
-	resyncInterval time.Duration,
+	resyncInterval func() time.Duration,
 	recorder events.Recorder) factory.Controller {
 	c := &managedClusterStatusController{
-		clusterName:                   clusterName,
-		hubClusterClient:              hubClusterClient,
-		hubClusterLister:              hubClusterInformer.Lister(),
-		managedClusterDiscoveryClient: managedClusterDiscoveryClient,
-		nodeLister:                    nodeInformer.Lister(),
+		clusterName:      clusterName,
+		hubClusterClient: hubClusterClient,
+		hubClusterLister: hubClusterInformer.Lister(),
+		statusCollector:  NewClusterStatusCollector(managedClusterDiscoveryClient, nodeInformer.Lister()),
 	}
 
 	return factory.New().
 		WithInformers(hubClusterInformer.Informer(), nodeInformer.Informer()).
-		WithSync(c.sync).
-		ResyncEvery(resyncInterval).
+		WithSync(health.WrapSync("ManagedClusterStatusController", c.sync)).
+		WithPostStartHooks(health.ResyncEvery(resyncInterval)).
+		ToController("ManagedClusterStatusController", recorder)
+}
+
+// NewClaimsOnlyStatusController creates a managed cluster status controller for the endpoints which are not
+// Kubernetes clusters, it reports the configured claims of the endpoint instead of checking a kube-apiserver.
+func NewClaimsOnlyStatusController(
+	clusterName string,
+	hubClusterClient clientset.Interface,
+	hubClusterInformer clusterv1informer.ManagedClusterInformer,
+	claims map[string]string,
+	resyncInterval func() time.Duration,
+	recorder events.Recorder) factory.Controller {
+	c := &managedClusterStatusController{
+		clusterName:      clusterName,
+		hubClusterClient: hubClusterClient,
+		hubClusterLister: hubClusterInformer.Lister(),
+		statusCollector:  NewClaimsStatusCollector(claims),
+	}
+
+	return factory.New().
+		WithInformers(hubClusterInformer.Informer()).
+		WithSync(health.WrapSync("ManagedClusterStatusController", c.sync)).
+		WithPostStartHooks(health.ResyncEvery(resyncInterval)).
 		ToController("ManagedClusterStatusController", recorder)
 }
 
 // sync updates managed cluster available condition by checking kube-apiserver health on managed cluster.
 // if the kube-apiserver is health, it will ensure that managed cluster resources and version are up to date.
 func (c *managedClusterStatusController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
-	if _, err := c.hubClusterLister.Get(c.clusterName); err != nil {
+	cluster, err := c.hubClusterLister.Get(c.clusterName)
+	if err != nil {
 		return fmt.Errorf("unable to get managed cluster %q from hub: %w", c.clusterName, err)
 	}
 
 	updateStatusFuncs := []helpers.UpdateManagedClusterStatusFunc{}
 
-	// check the kube-apiserver health on managed cluster.
-	condition := c.checkKubeAPIServerStatus(ctx)
-
+	condition, status, err := c.statusCollector.Collect(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to collect status of managed cluster %q: %w", c.clusterName, err)
+	}
 	// the managed cluster kube-apiserver is health, update its version and resources if necessary.
-	if condition.Status == metav1.ConditionTrue {
-		clusterVersion, err := c.getClusterVersion()
-		if err != nil {
-			return fmt.Errorf("unable to get server version of managed cluster %q: %w", c.clusterName, err)
-		}
+	if status != nil {
+		updateStatusFuncs = append(updateStatusFuncs, helpers.UpdateManagedClusterResourcesFn(*status))
+	}
+	// the claims are collected with the status in the claims-only mode, otherwise they are maintained by the
+	// cluster claim controller
+	if status != nil && status.ClusterClaims != nil {
+		updateStatusFuncs = append(updateStatusFuncs, updateClusterClaimsFn(*status))
+	}
+	if status != nil && len(status.Version.Kubernetes) > 0 {
+		updateStatusFuncs = append(updateStatusFuncs, updateKubernetesVersionConditionFn(
+			cluster.Annotations[helpers.SupportedKubernetesVersionsAnnotation], status.Version.Kubernetes))
+	}
 
-		capacity, allocatable, err := c.getClusterResources()
-		if err != nil {
-			return fmt.Errorf("unable to get capacity and allocatable of managed cluster %q: %w", c.clusterName, err)
-		}
+	updateStatusFuncs = append(updateStatusFuncs, helpers.UpdateManagedClusterConditionFn(condition))
 
-		updateStatusFuncs = append(updateStatusFuncs, updateClusterResourcesFn(clusterv1.ManagedClusterStatus{
-			Capacity:    capacity,
-			Allocatable: allocatable,
-			Version:     *clusterVersion,
-		}))
+	// skip the status the hub already has, which saves a get and a patch on the hub in each resync
+	upToDate, err := helpers.ClusterStatusUpToDate(&cluster.Status, updateStatusFuncs...)
+	if err != nil {
+		return err
+	}
+	if upToDate {
+		return nil
 	}
 
-	updateStatusFuncs = append(updateStatusFuncs, helpers.UpdateManagedClusterConditionFn(condition))
-	_, updated, err := helpers.UpdateManagedClusterStatus(ctx, c.hubClusterClient, c.clusterName, updateStatusFuncs...)
+	health.EnterPhase(ctx, "update status")
+	_, updated, err := helpers.PatchManagedClusterStatus(ctx, c.hubClusterClient, c.clusterName, updateStatusFuncs...)
 	if err != nil {
 		return fmt.Errorf("unable to update status of managed cluster %q: %w", c.clusterName, err)
 	}
@@ -100,8 +154,36 @@ func (c *managedClusterStatusController) sync(ctx context.Context, syncCtx facto
 	return nil
 }
 
+// Collect returns the available condition of the managed cluster, and the status with the version and resources
+// of the cluster if it is available.
+func (c *ClusterStatusCollector) Collect(ctx context.Context) (metav1.Condition, *clusterv1.ManagedClusterStatus, error) {
+	// check the kube-apiserver health on managed cluster.
+	health.EnterPhase(ctx, "check kube-apiserver")
+	condition := c.checkKubeAPIServerStatus(ctx)
+	if condition.Status != metav1.ConditionTrue {
+		return condition, nil, nil
+	}
+
+	health.EnterPhase(ctx, "collect resources")
+	clusterVersion, err := c.getClusterVersion()
+	if err != nil {
+		return condition, nil, fmt.Errorf("unable to get server version: %w", err)
+	}
+
+	capacity, allocatable, err := c.getClusterResources()
+	if err != nil {
+		return condition, nil, fmt.Errorf("unable to get capacity and allocatable: %w", err)
+	}
+
+	return condition, &clusterv1.ManagedClusterStatus{
+		Capacity:    capacity,
+		Allocatable: allocatable,
+		Version:     *clusterVersion,
+	}, nil
+}
+
 // using readyz api to check the status of kube apiserver
-func (c *managedClusterStatusController) checkKubeAPIServerStatus(ctx context.Context) metav1.Condition {
+func (c *ClusterStatusCollector) checkKubeAPIServerStatus(ctx context.Context) metav1.Condition {
 	statusCode := 0
 	condition := metav1.Condition{Type: clusterv1.ManagedClusterConditionAvailable}
 	result := c.managedClusterDiscoveryClient.RESTClient().Get().AbsPath("/livez").Do(ctx).StatusCode(&statusCode)
@@ -136,7 +218,7 @@ func (c *managedClusterStatusController) checkKubeAPIServerStatus(ctx context.Co
 	return condition
 }
 
-func (c *managedClusterStatusController) getClusterVersion() (*clusterv1.ManagedClusterVersion, error) {
+func (c *ClusterStatusCollector) getClusterVersion() (*clusterv1.ManagedClusterVersion, error) {
 	serverVersion, err := c.managedClusterDiscoveryClient.ServerVersion()
 	if err != nil {
 		return nil, err
@@ -144,7 +226,57 @@ func (c *managedClusterStatusController) getClusterVersion() (*clusterv1.Managed
 	return &clusterv1.ManagedClusterVersion{Kubernetes: serverVersion.String()}, nil
 }
 
-func (c *managedClusterStatusController) getClusterResources() (capacity, allocatable clusterv1.ResourceList, err error) {
+// updateKubernetesVersionConditionFn reports the skew of the Kubernetes version of the cluster from the range of the
+// supported versions advertised by the hub, see helpers.SupportedKubernetesVersionsAnnotation. The condition is
+// removed if no range is advertised.
+func updateKubernetesVersionConditionFn(supportedVersions, kubernetesVersion string) helpers.UpdateManagedClusterStatusFunc {
+	return func(oldStatus *clusterv1.ManagedClusterStatus) error {
+		if len(supportedVersions) == 0 {
+			meta.RemoveStatusCondition(&oldStatus.Conditions, helpers.KubernetesVersionSupportedCondition)
+			return nil
+		}
+		meta.SetStatusCondition(&oldStatus.Conditions, kubernetesVersionCondition(supportedVersions, kubernetesVersion))
+		return nil
+	}
+}
+
+func kubernetesVersionCondition(supportedVersions, kubernetesVersion string) metav1.Condition {
+	condition := metav1.Condition{Type: helpers.KubernetesVersionSupportedCondition}
+	versionRange, err := helpers.ParseKubernetesVersionRange(supportedVersions)
+	if err != nil {
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = "InvalidSupportedKubernetesVersions"
+		condition.Message = err.Error()
+		return condition
+	}
+	version, err := helpers.ParseMinorVersion(kubernetesVersion)
+	if err != nil {
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = "InvalidKubernetesVersion"
+		condition.Message = err.Error()
+		return condition
+	}
+
+	switch skew := versionRange.Skew(version); {
+	case skew < 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "KubernetesVersionTooOld"
+		condition.Message = fmt.Sprintf("Kubernetes version %s is %d minor versions older than the supported versions %s",
+			kubernetesVersion, -skew, versionRange)
+	case skew > 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "KubernetesVersionTooNew"
+		condition.Message = fmt.Sprintf("Kubernetes version %s is %d minor versions newer than the supported versions %s",
+			kubernetesVersion, skew, versionRange)
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "KubernetesVersionSupported"
+		condition.Message = fmt.Sprintf("Kubernetes version %s is in the supported versions %s", kubernetesVersion, versionRange)
+	}
+	return condition
+}
+
+func (c *ClusterStatusCollector) getClusterResources() (capacity, allocatable clusterv1.ResourceList, err error) {
 	nodes, err := c.nodeLister.List(labels.Everything())
 	if err != nil {
 		return nil, nil, err
@@ -180,20 +312,3 @@ func (c *managedClusterStatusController) getClusterResources() (capacity, alloca
 
 	return capacityList, allocatableList, nil
 }
-
-func updateClusterResourcesFn(status clusterv1.ManagedClusterStatus) helpers.UpdateManagedClusterStatusFunc {
-	return func(oldStatus *clusterv1.ManagedClusterStatus) error {
-		// merge the old capacity to new capacity, if one old capacity entry does not exist in new capacity,
-		// we add it back to new capacity
-		for key, val := range oldStatus.Capacity {
-			if _, ok := status.Capacity[key]; !ok {
-				status.Capacity[key] = val
-				continue
-			}
-		}
-		oldStatus.Capacity = status.Capacity
-		oldStatus.Allocatable = status.Allocatable
-		oldStatus.Version = status.Version
-		return nil
-	}
-}
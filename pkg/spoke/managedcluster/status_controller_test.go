@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 	"time"
 
 	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 
 	corev1 "k8s.io/api/core/v1"
@@ -86,11 +88,28 @@ func TestHealthCheck(t *testing.T) {
 					Reason:  "ManagedClusterKubeAPIServerUnavailable",
 					Message: "The kube-apiserver is not ok, status code: 500, an error on the server (\"internal server error\") has prevented the request from succeeding",
 				}
-				testinghelpers.AssertActions(t, actions, "get", "update")
-				actual := actions[1].(clienttesting.UpdateActionImpl).Object
-				testinghelpers.AssertManagedClusterCondition(t, actual.(*clusterv1.ManagedCluster).Status.Conditions, expectedCondition)
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				actual := testinghelpers.PatchedManagedCluster(t, actions[1])
+				testinghelpers.AssertManagedClusterCondition(t, actual.Status.Conditions, expectedCondition)
 			},
 		},
+		{
+			name: "status is up to date",
+			clusters: []runtime.Object{func() *clusterv1.ManagedCluster {
+				cluster := testinghelpers.NewAcceptedManagedCluster()
+				cluster.Status.Conditions = append(cluster.Status.Conditions, metav1.Condition{
+					Type:               clusterv1.ManagedClusterConditionAvailable,
+					Status:             metav1.ConditionFalse,
+					Reason:             "ManagedClusterKubeAPIServerUnavailable",
+					Message:            "The kube-apiserver is not ok, status code: 500, an error on the server (\"internal server error\") has prevented the request from succeeding",
+					LastTransitionTime: metav1.Now(),
+				})
+				return cluster
+			}()},
+			httpStatus:      http.StatusInternalServerError,
+			responseMsg:     "internal server error",
+			validateActions: testinghelpers.AssertNoActions,
+		},
 		{
 			name:     "kube-apiserver is ok",
 			clusters: []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
@@ -118,10 +137,10 @@ func TestHealthCheck(t *testing.T) {
 						clusterv1.ResourceMemory: *resource.NewQuantity(int64(1024*1024*32), resource.BinarySI),
 					},
 				}
-				testinghelpers.AssertActions(t, actions, "get", "update")
-				actual := actions[1].(clienttesting.UpdateActionImpl).Object
-				testinghelpers.AssertManagedClusterCondition(t, actual.(*clusterv1.ManagedCluster).Status.Conditions, expectedCondition)
-				testinghelpers.AssertManagedClusterStatus(t, actual.(*clusterv1.ManagedCluster).Status, expectedStatus)
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				actual := testinghelpers.PatchedManagedCluster(t, actions[1])
+				testinghelpers.AssertManagedClusterCondition(t, actual.Status.Conditions, expectedCondition)
+				testinghelpers.AssertManagedClusterStatus(t, actual.Status, expectedStatus)
 			},
 		},
 		{
@@ -136,9 +155,9 @@ func TestHealthCheck(t *testing.T) {
 					Reason:  "ManagedClusterAvailable",
 					Message: "Managed cluster is available",
 				}
-				testinghelpers.AssertActions(t, actions, "get", "update")
-				actual := actions[1].(clienttesting.UpdateActionImpl).Object
-				testinghelpers.AssertManagedClusterCondition(t, actual.(*clusterv1.ManagedCluster).Status.Conditions, expectedCondition)
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				actual := testinghelpers.PatchedManagedCluster(t, actions[1])
+				testinghelpers.AssertManagedClusterCondition(t, actual.Status.Conditions, expectedCondition)
 			},
 		},
 		{
@@ -153,9 +172,9 @@ func TestHealthCheck(t *testing.T) {
 					Reason:  "ManagedClusterAvailable",
 					Message: "Managed cluster is available",
 				}
-				testinghelpers.AssertActions(t, actions, "get", "update")
-				actual := actions[1].(clienttesting.UpdateActionImpl).Object
-				testinghelpers.AssertManagedClusterCondition(t, actual.(*clusterv1.ManagedCluster).Status.Conditions, expectedCondition)
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				actual := testinghelpers.PatchedManagedCluster(t, actions[1])
+				testinghelpers.AssertManagedClusterCondition(t, actual.Status.Conditions, expectedCondition)
 			},
 		},
 		{
@@ -195,10 +214,10 @@ func TestHealthCheck(t *testing.T) {
 						clusterv1.ResourceMemory: *resource.NewQuantity(int64(1024*1024*64), resource.BinarySI),
 					},
 				}
-				testinghelpers.AssertActions(t, actions, "get", "update")
-				actual := actions[1].(clienttesting.UpdateActionImpl).Object
-				testinghelpers.AssertManagedClusterCondition(t, actual.(*clusterv1.ManagedCluster).Status.Conditions, expectedCondition)
-				testinghelpers.AssertManagedClusterStatus(t, actual.(*clusterv1.ManagedCluster).Status, expectedStatus)
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				actual := testinghelpers.PatchedManagedCluster(t, actions[1])
+				testinghelpers.AssertManagedClusterCondition(t, actual.Status.Conditions, expectedCondition)
+				testinghelpers.AssertManagedClusterStatus(t, actual.Status, expectedStatus)
 			},
 		},
 	}
@@ -222,11 +241,10 @@ func TestHealthCheck(t *testing.T) {
 			serverResponse.responseMsg = c.responseMsg
 
 			ctrl := &managedClusterStatusController{
-				clusterName:                   testinghelpers.TestManagedClusterName,
-				hubClusterClient:              clusterClient,
-				hubClusterLister:              clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
-				managedClusterDiscoveryClient: discoveryClient,
-				nodeLister:                    kubeInformerFactory.Core().V1().Nodes().Lister(),
+				clusterName:      testinghelpers.TestManagedClusterName,
+				hubClusterClient: clusterClient,
+				hubClusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				statusCollector:  NewClusterStatusCollector(discoveryClient, kubeInformerFactory.Core().V1().Nodes().Lister()),
 			}
 			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
 			testinghelpers.AssertError(t, syncErr, c.expectedErr)
@@ -235,3 +253,120 @@ func TestHealthCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestClaimsOnlyStatus(t *testing.T) {
+	cluster := testinghelpers.NewAcceptedManagedCluster()
+	clusterClient := clusterfake.NewSimpleClientset(cluster)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+	if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := &managedClusterStatusController{
+		clusterName:      testinghelpers.TestManagedClusterName,
+		hubClusterClient: clusterClient,
+		hubClusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+		statusCollector: NewClaimsStatusCollector(map[string]string{
+			"product.open-cluster-management.io": "EdgeGateway",
+			"location.example.com":               "store-42",
+		}),
+	}
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "")); err != nil {
+		t.Errorf("unexpected err: %v", err)
+	}
+
+	actions := clusterClient.Actions()
+	testinghelpers.AssertActions(t, actions, "get", "patch")
+	actual := testinghelpers.PatchedManagedCluster(t, actions[1])
+	testinghelpers.AssertManagedClusterCondition(t, actual.Status.Conditions, metav1.Condition{
+		Type:    clusterv1.ManagedClusterConditionAvailable,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ManagedClusterAvailable",
+		Message: "The agent of the claims-only cluster is running",
+	})
+	expectedClaims := []clusterv1.ManagedClusterClaim{
+		{Name: "location.example.com", Value: "store-42"},
+		{Name: "product.open-cluster-management.io", Value: "EdgeGateway"},
+	}
+	if !reflect.DeepEqual(actual.Status.ClusterClaims, expectedClaims) {
+		t.Errorf("expected claims %v, but got %v", expectedClaims, actual.Status.ClusterClaims)
+	}
+	if len(actual.Status.Capacity) != 0 || len(actual.Status.Version.Kubernetes) != 0 {
+		t.Errorf("expected no capacity and version, but got %v", actual.Status)
+	}
+}
+
+func TestKubernetesVersionCondition(t *testing.T) {
+	cases := []struct {
+		name              string
+		supportedVersions string
+		kubernetesVersion string
+		expectedCondition *metav1.Condition
+	}{
+		{
+			name:              "no supported versions",
+			kubernetesVersion: "v1.28.3",
+		},
+		{
+			name:              "supported version",
+			supportedVersions: "1.27-1.30",
+			kubernetesVersion: "v1.28.3",
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.KubernetesVersionSupportedCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  "KubernetesVersionSupported",
+				Message: "Kubernetes version v1.28.3 is in the supported versions 1.27-1.30",
+			},
+		},
+		{
+			name:              "old version",
+			supportedVersions: "1.27-",
+			kubernetesVersion: "v1.25.0",
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.KubernetesVersionSupportedCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  "KubernetesVersionTooOld",
+				Message: "Kubernetes version v1.25.0 is 2 minor versions older than the supported versions 1.27-",
+			},
+		},
+		{
+			name:              "new version",
+			supportedVersions: "1.27-1.30",
+			kubernetesVersion: "v1.31.0+k3s1",
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.KubernetesVersionSupportedCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  "KubernetesVersionTooNew",
+				Message: "Kubernetes version v1.31.0+k3s1 is 1 minor versions newer than the supported versions 1.27-1.30",
+			},
+		},
+		{
+			name:              "invalid supported versions",
+			supportedVersions: "1.27",
+			kubernetesVersion: "v1.28.3",
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.KubernetesVersionSupportedCondition,
+				Status:  metav1.ConditionUnknown,
+				Reason:  "InvalidSupportedKubernetesVersions",
+				Message: "invalid range \"1.27\", must be in the format of <min>-<max>, e.g. 1.27-1.30",
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			status := &clusterv1.ManagedClusterStatus{
+				Conditions: []metav1.Condition{{Type: helpers.KubernetesVersionSupportedCondition, Status: metav1.ConditionTrue}},
+			}
+			if err := updateKubernetesVersionConditionFn(c.supportedVersions, c.kubernetesVersion)(status); err != nil {
+				t.Fatal(err)
+			}
+			if c.expectedCondition == nil {
+				if len(status.Conditions) != 0 {
+					t.Errorf("expected the condition is removed, but got %v", status.Conditions)
+				}
+				return
+			}
+			testinghelpers.AssertManagedClusterCondition(t, status.Conditions, *c.expectedCondition)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package managedcluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// TokenFile is the name of the file in the hub kubeconfig secret containing the token of the registration agent
+// service account, which the hub kubeconfig of the token registration driver authenticates with
+const TokenFile = "token"
+
+// TokenSyncInterval is the interval the token of the registration agent is read from the hub. The hub rotates
+// the token once it has less than 20% of its life remaining. It is exposed so that integration tests can shorten it.
+var TokenSyncInterval = time.Minute
+
+// tokenForHubController copies the token of the registration agent service account, which the hub rotates in the
+// managed cluster namespace, into the hub kubeconfig secret with a kubeconfig authenticating with the token file.
+type tokenForHubController struct {
+	clusterName     string
+	agentName       string
+	secretNamespace string
+	secretName      string
+	kubeconfigData  []byte
+	hubCoreClient   corev1client.CoreV1Interface
+	spokeCoreClient corev1client.CoreV1Interface
+	controllerName  string
+}
+
+// NewTokenForHubController returns a controller which keeps the token of the registration agent in the hub
+// kubeconfig secret for the token registration driver. The token is read with the hubCoreClient, which is the
+// client of the bootstrap kubeconfig until the cluster joins the hub.
+func NewTokenForHubController(
+	clusterName string,
+	agentName string,
+	secretNamespace string,
+	secretName string,
+	kubeconfigData []byte,
+	spokeSecretInformer corev1informers.SecretInformer,
+	spokeCoreClient corev1client.CoreV1Interface,
+	hubCoreClient corev1client.CoreV1Interface,
+	recorder events.Recorder,
+	controllerName string,
+) factory.Controller {
+	c := &tokenForHubController{
+		clusterName:     clusterName,
+		agentName:       agentName,
+		secretNamespace: secretNamespace,
+		secretName:      secretName,
+		kubeconfigData:  kubeconfigData,
+		hubCoreClient:   hubCoreClient,
+		spokeCoreClient: spokeCoreClient,
+		controllerName:  controllerName,
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			// only enqueue the hub kubeconfig secret
+			return accessor.GetNamespace() == secretNamespace && accessor.GetName() == secretName
+		}, spokeSecretInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ResyncEvery(TokenSyncInterval).
+		ToController(controllerName, recorder)
+}
+
+func (c *tokenForHubController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	tokenSecret, err := c.hubCoreClient.Secrets(c.clusterName).Get(ctx, helpers.RegistrationTokenSecretName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err), errors.IsForbidden(err):
+		// the token is provisioned once the cluster is accepted by the hub
+		helpers.ControllerLogger(ctx, c.controllerName).V(helpers.LogLevelDebug).Info("Waiting for the token of the registration agent",
+			helpers.LogKeyCluster, c.clusterName, helpers.LogKeyReason, err.Error())
+		return nil
+	case err != nil:
+		return fmt.Errorf("unable to get the token of the registration agent: %w", err)
+	}
+	token := tokenSecret.Data[helpers.RegistrationTokenKey]
+	if len(token) == 0 {
+		return fmt.Errorf("no token found in secret %q on the hub", c.clusterName+"/"+helpers.RegistrationTokenSecretName)
+	}
+
+	secret, err := c.spokeCoreClient.Secrets(c.secretNamespace).Get(ctx, c.secretName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		secret = nil
+	case err != nil:
+		return fmt.Errorf("unable to get secret %q: %w", c.secretNamespace+"/"+c.secretName, err)
+	}
+
+	// only the fields managed by the controller are applied, the others are kept
+	appliedSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: c.secretNamespace,
+			Name:      c.secretName,
+		},
+		Data: map[string][]byte{
+			clientcert.ClusterNameFile: []byte(c.clusterName),
+			clientcert.AgentNameFile:   []byte(c.agentName),
+			clientcert.KubeconfigFile:  c.kubeconfigData,
+			TokenFile:                  token,
+		},
+	}
+	if _, err := helpers.ApplySecret(ctx, c.spokeCoreClient, secret, appliedSecret); err != nil {
+		return err
+	}
+
+	if secret == nil || !bytes.Equal(secret.Data[TokenFile], token) {
+		syncCtx.Recorder().Eventf("RegistrationTokenUpdated", "The token of the registration agent is saved in secret %s/%s",
+			c.secretNamespace, c.secretName)
+	}
+	return nil
+}
+
+// BuildTokenKubeconfig builds a kubeconfig based on a rest config template, which authenticates with the token
+// file in the same directory.
+func BuildTokenKubeconfig(clientConfig *rest.Config) clientcmdapi.Config {
+	kubeconfig := clientcert.BuildKubeconfig(clientConfig, "", "")
+	kubeconfig.AuthInfos = map[string]*clientcmdapi.AuthInfo{"default-auth": {
+		TokenFile: TokenFile,
+	}}
+	return kubeconfig
+}
+
+// HasValidHubTokenKubeconfig returns true if all the conditions below are met:
+//  1. KubeconfigFile exists in hubKubeconfigDir;
+//  2. TokenFile exists and the token is not expired;
+//  3. The names of the cluster and the agent in ClusterNameFile and AgentNameFile are the given ones;
+func HasValidHubTokenKubeconfig(hubKubeconfigDir, clusterName, agentName string) (bool, error) {
+	kubeconfigPath := path.Join(hubKubeconfigDir, clientcert.KubeconfigFile)
+	if _, err := os.Stat(kubeconfigPath); os.IsNotExist(err) {
+		klog.V(4).Infof("Kubeconfig file %q not found", kubeconfigPath)
+		return false, nil
+	}
+
+	tokenPath := path.Join(hubKubeconfigDir, TokenFile)
+	token, err := ioutil.ReadFile(path.Clean(tokenPath))
+	if err != nil || len(token) == 0 {
+		klog.V(4).Infof("Unable to load token file %q", tokenPath)
+		return false, nil
+	}
+	expiration, err := tokenExpiration(token)
+	if err != nil {
+		klog.V(4).Infof("Unable to parse token file %q: %v", tokenPath, err)
+		return false, nil
+	}
+	if !time.Now().Before(expiration) {
+		klog.V(4).Infof("Token in file %q expired at %v", tokenPath, expiration)
+		return false, nil
+	}
+
+	for file, expected := range map[string]string{clientcert.ClusterNameFile: clusterName, clientcert.AgentNameFile: agentName} {
+		data, err := ioutil.ReadFile(path.Clean(path.Join(hubKubeconfigDir, file)))
+		if err != nil || string(data) != expected {
+			klog.V(4).Infof("Token in file %q is issued for another agent than %q", tokenPath, clusterName+":"+agentName)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// tokenExpiration returns the expiration time in the claims of a service account token. The signature of the
+// token is not verified, the hub verifies it.
+func tokenExpiration(token []byte) (time.Time, error) {
+	parts := strings.Split(strings.TrimSpace(string(token)), ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("the token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, err
+	}
+	claims := struct {
+		Expiration int64 `json:"exp"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, err
+	}
+	if claims.Expiration == 0 {
+		return time.Time{}, fmt.Errorf("no expiration found in the token")
+	}
+	return time.Unix(claims.Expiration, 0), nil
+}
@@ -0,0 +1,190 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+// newTestToken returns an unsigned service account token which expires at the given time
+func newTestToken(expiration time.Time) []byte {
+	claims := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, expiration.Unix())))
+	return []byte("eyJhbGciOiJSUzI1NiJ9." + claims + ".signature")
+}
+
+func TestTokenForHubSync(t *testing.T) {
+	token := newTestToken(time.Now().Add(time.Hour))
+	kubeconfigData := testinghelpers.NewKubeconfig(nil, nil)
+
+	newTokenSecret := func(token []byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testinghelpers.TestManagedClusterName, Name: helpers.RegistrationTokenSecretName},
+			Data:       map[string][]byte{helpers.RegistrationTokenKey: token},
+		}
+	}
+	newHubKubeconfigSecret := func(token []byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testNamespace,
+				Name:      testSecretName,
+				UID:       "7a3c1f2e-5d4b-4e6a-9b8c-0d1e2f3a4b5c",
+			},
+			Data: map[string][]byte{
+				clientcert.ClusterNameFile: []byte(testinghelpers.TestManagedClusterName),
+				clientcert.AgentNameFile:   []byte("agent1"),
+				clientcert.KubeconfigFile:  kubeconfigData,
+				TokenFile:                  token,
+			},
+		}
+	}
+
+	cases := []struct {
+		name            string
+		hubSecrets      []runtime.Object
+		spokeSecrets    []runtime.Object
+		expectedErr     string
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name: "token not provisioned",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:        "empty token",
+			hubSecrets:  []runtime.Object{newTokenSecret(nil)},
+			expectedErr: "no token found in secret \"testmanagedcluster/registration-agent-token\" on the hub",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:       "no hub kubeconfig secret",
+			hubSecrets: []runtime.Object{newTokenSecret(token)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				secret := testinghelpers.AppliedSecret(t, actions[1])
+				if string(secret.Data[TokenFile]) != string(token) {
+					t.Errorf("expected the token is saved, but got %q", string(secret.Data[TokenFile]))
+				}
+				if string(secret.Data[clientcert.AgentNameFile]) != "agent1" {
+					t.Errorf("expected the agent name is saved, but got %q", string(secret.Data[clientcert.AgentNameFile]))
+				}
+			},
+		},
+		{
+			name:         "rotated token",
+			hubSecrets:   []runtime.Object{newTokenSecret(token)},
+			spokeSecrets: []runtime.Object{newHubKubeconfigSecret([]byte("stale"))},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+			},
+		},
+		{
+			name:         "saved token",
+			hubSecrets:   []runtime.Object{newTokenSecret(token)},
+			spokeSecrets: []runtime.Object{newHubKubeconfigSecret(token)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hubKubeClient := kubefake.NewSimpleClientset(c.hubSecrets...)
+			spokeKubeClient := kubefake.NewSimpleClientset(c.spokeSecrets...)
+			testinghelpers.AddSecretApplyReactor(&spokeKubeClient.Fake, spokeKubeClient.Tracker())
+
+			ctrl := &tokenForHubController{
+				clusterName:     testinghelpers.TestManagedClusterName,
+				agentName:       "agent1",
+				secretNamespace: testNamespace,
+				secretName:      testSecretName,
+				kubeconfigData:  kubeconfigData,
+				hubCoreClient:   hubKubeClient.CoreV1(),
+				spokeCoreClient: spokeKubeClient.CoreV1(),
+				controllerName:  "test",
+			}
+
+			err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "test"))
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			c.validateActions(t, spokeKubeClient.Actions())
+		})
+	}
+}
+
+func TestHasValidHubTokenKubeconfig(t *testing.T) {
+	cases := []struct {
+		name        string
+		clusterName string
+		agentName   string
+		token       []byte
+		isValid     bool
+	}{
+		{
+			name:        "no token",
+			clusterName: "cluster1",
+			agentName:   "agent1",
+		},
+		{
+			name:        "malformed token",
+			clusterName: "cluster1",
+			agentName:   "agent1",
+			token:       []byte("token"),
+		},
+		{
+			name:        "expired token",
+			clusterName: "cluster1",
+			agentName:   "agent1",
+			token:       newTestToken(time.Now().Add(-time.Minute)),
+		},
+		{
+			name:        "token of another agent",
+			clusterName: "cluster1",
+			agentName:   "agent2",
+			token:       newTestToken(time.Now().Add(time.Hour)),
+		},
+		{
+			name:        "valid token",
+			clusterName: "cluster1",
+			agentName:   "agent1",
+			token:       newTestToken(time.Now().Add(time.Hour)),
+			isValid:     true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			testinghelpers.WriteFile(path.Join(dir, clientcert.KubeconfigFile), testinghelpers.NewKubeconfig(nil, nil))
+			testinghelpers.WriteFile(path.Join(dir, clientcert.ClusterNameFile), []byte("cluster1"))
+			testinghelpers.WriteFile(path.Join(dir, clientcert.AgentNameFile), []byte("agent1"))
+			if c.token != nil {
+				testinghelpers.WriteFile(path.Join(dir, TokenFile), c.token)
+			}
+
+			isValid, err := HasValidHubTokenKubeconfig(dir, c.clusterName, c.agentName)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if isValid != c.isValid {
+				t.Errorf("expected %t, but got %t", c.isValid, isValid)
+			}
+		})
+	}
+}
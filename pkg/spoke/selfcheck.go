@@ -0,0 +1,45 @@
+package spoke
+
+import (
+	"fmt"
+	"time"
+
+	"open-cluster-management.io/registration/pkg/spoke/selfcheck"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// SelfChecker returns the checker of the prerequisites of the agent, the kubeconfig is of the cluster the agent
+// runs on, and the managed cluster is reached with the SpokeKubeconfig if it is set.
+func (o *SpokeAgentOptions) SelfChecker(kubeConfig *rest.Config, maxClockSkew time.Duration) (*selfcheck.Checker, error) {
+	managementKubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	spokeKubeClient := managementKubeClient
+	if len(o.SpokeKubeconfig) > 0 {
+		spokeKubeConfig, err := clientcmd.BuildConfigFromFlags("", o.SpokeKubeconfig)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load spoke kubeconfig from file %q: %w", o.SpokeKubeconfig, err)
+		}
+		spokeKubeClient, err = kubernetes.NewForConfig(spokeKubeConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	checker := selfcheck.NewChecker(managementKubeClient, spokeKubeClient)
+	checker.BootstrapKubeconfig = o.BootstrapKubeconfig
+	checker.BootstrapCredentialDir = o.BootstrapCredentialDir
+	checker.ComponentNamespace = o.ComponentNamespace
+	if len(checker.ComponentNamespace) == 0 {
+		checker.ComponentNamespace = componentNamespace()
+	}
+	checker.HubKubeconfigSecret = o.HubKubeconfigSecret
+	if maxClockSkew > 0 {
+		checker.MaxClockSkew = maxClockSkew
+	}
+	return checker, nil
+}
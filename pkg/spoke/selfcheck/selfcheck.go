@@ -0,0 +1,306 @@
+// package selfcheck verifies the prerequisites of the registration agent before it is deployed, e.g. in an
+// onboarding pipeline. It checks the bootstrap kubeconfig, the reachability of the hub, the rbac of the agent on
+// the hub and the managed cluster, the writability of the hub kubeconfig secret and the clock skew to the hub.
+package selfcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	certutil "k8s.io/client-go/util/cert"
+
+	"open-cluster-management.io/registration/pkg/spoke/bootstrapcredential"
+)
+
+// DefaultMaxClockSkew is the default max clock skew between the managed cluster and the hub
+const DefaultMaxClockSkew = 30 * time.Second
+
+// Status is the status of a check
+type Status string
+
+const (
+	StatusPass Status = "PASS"
+	StatusFail Status = "FAIL"
+	// StatusSkip is the status of a check which is not run because a check it depends on failed
+	StatusSkip Status = "SKIP"
+)
+
+// Result is the result of a check
+type Result struct {
+	Name    string
+	Status  Status
+	Message string
+}
+
+// Checker runs the self checks of the agent
+type Checker struct {
+	// BootstrapKubeconfig is the path of the bootstrap kubeconfig of the agent
+	BootstrapKubeconfig string
+	// BootstrapCredentialDir is the directory the bootstrap kubeconfig is assembled from instead of
+	// BootstrapKubeconfig
+	BootstrapCredentialDir string
+	// ComponentNamespace and HubKubeconfigSecret are the namespace and name of the hub kubeconfig secret
+	ComponentNamespace  string
+	HubKubeconfigSecret string
+	// MaxClockSkew is the max clock skew allowed between the managed cluster and the hub
+	MaxClockSkew time.Duration
+
+	// managementKubeClient is the client of the cluster the agent runs on, and spokeKubeClient is the client of
+	// the managed cluster, they are the same cluster unless the agent runs in the detached mode.
+	managementKubeClient kubernetes.Interface
+	spokeKubeClient      kubernetes.Interface
+
+	newHubKubeClient func(config *rest.Config) (kubernetes.Interface, error)
+	hubTime          func(ctx context.Context, config *rest.Config) (time.Time, error)
+	now              func() time.Time
+}
+
+// NewChecker returns a Checker with the clients of the cluster the agent runs on and the managed cluster
+func NewChecker(managementKubeClient, spokeKubeClient kubernetes.Interface) *Checker {
+	return &Checker{
+		MaxClockSkew:         DefaultMaxClockSkew,
+		managementKubeClient: managementKubeClient,
+		spokeKubeClient:      spokeKubeClient,
+		newHubKubeClient: func(config *rest.Config) (kubernetes.Interface, error) {
+			return kubernetes.NewForConfig(config)
+		},
+		hubTime: hubTime,
+		now:     time.Now,
+	}
+}
+
+// Run runs the checks in order. The checks against the hub are skipped if the bootstrap kubeconfig is invalid.
+func (c *Checker) Run(ctx context.Context) []Result {
+	results := []Result{}
+	hubConfig, message, err := c.checkBootstrapKubeconfig()
+	results = append(results, newResult("bootstrap kubeconfig", message, err))
+
+	var hubKubeClient kubernetes.Interface
+	if err == nil {
+		hubKubeClient, err = c.newHubKubeClient(hubConfig)
+		if err != nil {
+			results[0] = newResult("bootstrap kubeconfig", "", err)
+		}
+	}
+
+	hubChecks := []struct {
+		name  string
+		check func(ctx context.Context, hubConfig *rest.Config, hubKubeClient kubernetes.Interface) (string, error)
+	}{
+		{name: "hub reachability", check: c.checkHubReachability},
+		{name: "hub rbac", check: c.checkHubRBAC},
+		{name: "clock skew", check: c.checkClockSkew},
+	}
+	for _, hubCheck := range hubChecks {
+		if hubKubeClient == nil {
+			results = append(results, Result{Name: hubCheck.name, Status: StatusSkip, Message: "the bootstrap kubeconfig is invalid"})
+			continue
+		}
+		message, err := hubCheck.check(ctx, hubConfig, hubKubeClient)
+		results = append(results, newResult(hubCheck.name, message, err))
+	}
+
+	message, err = c.checkSpokeRBAC(ctx)
+	results = append(results, newResult("spoke rbac", message, err))
+	message, err = c.checkSecretWritability(ctx)
+	results = append(results, newResult("secret writability", message, err))
+	return results
+}
+
+func newResult(name, message string, err error) Result {
+	if err != nil {
+		return Result{Name: name, Status: StatusFail, Message: err.Error()}
+	}
+	return Result{Name: name, Status: StatusPass, Message: message}
+}
+
+// PrintReport prints the results of the checks, and returns the number of the failed checks
+func PrintReport(w io.Writer, results []Result) int {
+	failed := 0
+	for _, result := range results {
+		if result.Status == StatusFail {
+			failed++
+		}
+		fmt.Fprintf(w, "[%s] %s: %s\n", result.Status, result.Name, result.Message)
+	}
+	return failed
+}
+
+// checkBootstrapKubeconfig loads the bootstrap kubeconfig, and checks the client certificate is not expired if
+// the kubeconfig authenticates with a certificate.
+func (c *Checker) checkBootstrapKubeconfig() (*rest.Config, string, error) {
+	var config *rest.Config
+	var err error
+	switch {
+	case len(c.BootstrapCredentialDir) > 0:
+		config, err = bootstrapcredential.LoadClientConfig(c.BootstrapCredentialDir)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to assemble the bootstrap kubeconfig from %q: %w", c.BootstrapCredentialDir, err)
+		}
+	case len(c.BootstrapKubeconfig) > 0:
+		config, err = clientcmd.BuildConfigFromFlags("", c.BootstrapKubeconfig)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to load the bootstrap kubeconfig %q: %w", c.BootstrapKubeconfig, err)
+		}
+	default:
+		return nil, "", fmt.Errorf("the bootstrap kubeconfig is not specified")
+	}
+	if err := rest.LoadTLSFiles(config); err != nil {
+		return nil, "", fmt.Errorf("unable to load the files of the bootstrap kubeconfig: %w", err)
+	}
+	if len(config.CertData) == 0 {
+		return config, fmt.Sprintf("the kubeconfig of %s is loaded", config.Host), nil
+	}
+
+	certs, err := certutil.ParseCertsPEM(config.CertData)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to parse the client certificate of the bootstrap kubeconfig: %w", err)
+	}
+	if now := c.now(); now.After(certs[0].NotAfter) {
+		return nil, "", fmt.Errorf("the client certificate of the bootstrap kubeconfig expired at %s",
+			certs[0].NotAfter.UTC().Format(time.RFC3339))
+	}
+	return config, fmt.Sprintf("the kubeconfig of %s is loaded, the client certificate expires at %s",
+		config.Host, certs[0].NotAfter.UTC().Format(time.RFC3339)), nil
+}
+
+// checkHubReachability gets the version of the hub kube-apiserver
+func (c *Checker) checkHubReachability(ctx context.Context, _ *rest.Config, hubKubeClient kubernetes.Interface) (string, error) {
+	serverVersion, err := hubKubeClient.Discovery().ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("unable to reach the hub: %w", err)
+	}
+	return fmt.Sprintf("the hub kube-apiserver %s is reachable", serverVersion.GitVersion), nil
+}
+
+// checkHubRBAC checks the bootstrap user is allowed to create a csr and the managed cluster on the hub
+func (c *Checker) checkHubRBAC(ctx context.Context, _ *rest.Config, hubKubeClient kubernetes.Interface) (string, error) {
+	return checkAccess(ctx, hubKubeClient, []authorizationv1.ResourceAttributes{
+		{Group: "certificates.k8s.io", Resource: "certificatesigningrequests", Verb: "create"},
+		{Group: "certificates.k8s.io", Resource: "certificatesigningrequests", Verb: "get"},
+		{Group: "certificates.k8s.io", Resource: "certificatesigningrequests", Verb: "list"},
+		{Group: "certificates.k8s.io", Resource: "certificatesigningrequests", Verb: "watch"},
+		{Group: "cluster.open-cluster-management.io", Resource: "managedclusters", Verb: "create"},
+		{Group: "cluster.open-cluster-management.io", Resource: "managedclusters", Verb: "get"},
+	})
+}
+
+// checkClockSkew compares the time of the hub with the local time, the certificates issued by the hub are not
+// valid yet or expire early on a skewed clock.
+func (c *Checker) checkClockSkew(ctx context.Context, hubConfig *rest.Config, _ kubernetes.Interface) (string, error) {
+	hubNow, err := c.hubTime(ctx, hubConfig)
+	if err != nil {
+		return "", fmt.Errorf("unable to get the time of the hub: %w", err)
+	}
+	skew := c.now().Sub(hubNow)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > c.MaxClockSkew {
+		return "", fmt.Errorf("the clock skew to the hub is %v, which is greater than %v", skew.Round(time.Second), c.MaxClockSkew)
+	}
+	return fmt.Sprintf("the clock skew to the hub is %v", skew.Round(time.Second)), nil
+}
+
+// checkSpokeRBAC checks the agent is allowed to read the resources of the managed cluster and to write the hub
+// kubeconfig secret and the events in its namespace.
+func (c *Checker) checkSpokeRBAC(ctx context.Context) (string, error) {
+	if _, err := checkAccess(ctx, c.spokeKubeClient, []authorizationv1.ResourceAttributes{
+		{Resource: "nodes", Verb: "list"},
+		{Resource: "nodes", Verb: "watch"},
+		{Group: "cluster.open-cluster-management.io", Resource: "clusterclaims", Verb: "list"},
+		{Group: "cluster.open-cluster-management.io", Resource: "clusterclaims", Verb: "watch"},
+	}); err != nil {
+		return "", err
+	}
+	return checkAccess(ctx, c.managementKubeClient, []authorizationv1.ResourceAttributes{
+		{Namespace: c.ComponentNamespace, Resource: "secrets", Verb: "get"},
+		{Namespace: c.ComponentNamespace, Resource: "secrets", Verb: "list"},
+		{Namespace: c.ComponentNamespace, Resource: "secrets", Verb: "watch"},
+		{Namespace: c.ComponentNamespace, Resource: "secrets", Verb: "create"},
+		{Namespace: c.ComponentNamespace, Resource: "secrets", Verb: "update"},
+		{Namespace: c.ComponentNamespace, Resource: "events", Verb: "create"},
+	})
+}
+
+// checkSecretWritability writes the hub kubeconfig secret in the dry run mode, so the admission of the secret is
+// checked as well as the rbac.
+func (c *Checker) checkSecretWritability(ctx context.Context) (string, error) {
+	secrets := c.managementKubeClient.CoreV1().Secrets(c.ComponentNamespace)
+	dryRun := []string{metav1.DryRunAll}
+	secret, err := secrets.Get(ctx, c.HubKubeconfigSecret, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: c.ComponentNamespace, Name: c.HubKubeconfigSecret},
+		}
+		_, err = secrets.Create(ctx, secret, metav1.CreateOptions{DryRun: dryRun})
+	case err == nil:
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{DryRun: dryRun})
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to write the secret %s/%s: %w", c.ComponentNamespace, c.HubKubeconfigSecret, err)
+	}
+	return fmt.Sprintf("the secret %s/%s is writable", c.ComponentNamespace, c.HubKubeconfigSecret), nil
+}
+
+// checkAccess checks the current user is allowed to access the resources with self subject access reviews
+func checkAccess(ctx context.Context, kubeClient kubernetes.Interface, attributes []authorizationv1.ResourceAttributes) (string, error) {
+	denied := []string{}
+	for i := range attributes {
+		sar := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attributes[i]},
+		}
+		sar, err := kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, sar, metav1.CreateOptions{})
+		if err != nil {
+			return "", fmt.Errorf("unable to review the access: %w", err)
+		}
+		if !sar.Status.Allowed {
+			denied = append(denied, formatAttributes(attributes[i]))
+		}
+	}
+	if len(denied) > 0 {
+		return "", fmt.Errorf("not allowed to %s", strings.Join(denied, ", "))
+	}
+	return fmt.Sprintf("%d permissions are granted", len(attributes)), nil
+}
+
+func formatAttributes(attributes authorizationv1.ResourceAttributes) string {
+	resource := attributes.Resource
+	if len(attributes.Group) > 0 {
+		resource = resource + "." + attributes.Group
+	}
+	if len(attributes.Namespace) > 0 {
+		return fmt.Sprintf("%s %s in %s", attributes.Verb, resource, attributes.Namespace)
+	}
+	return fmt.Sprintf("%s %s", attributes.Verb, resource)
+}
+
+// hubTime returns the time of the hub from the date header of the response of the version endpoint
+func hubTime(ctx context.Context, config *rest.Config) (time.Time, error) {
+	client, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return time.Time{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(config.Host, "/")+"/version", nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+	return http.ParseTime(resp.Header.Get("Date"))
+}
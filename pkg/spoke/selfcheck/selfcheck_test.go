@@ -0,0 +1,158 @@
+package selfcheck
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	clienttesting "k8s.io/client-go/testing"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+// newKubeClient returns a fake client which denies the access to the given resources
+func newKubeClient(deniedResources ...string) *kubefake.Clientset {
+	kubeClient := kubefake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "open-cluster-management-agent", Name: "hub-kubeconfig-secret"},
+	})
+	kubeClient.PrependReactor("create", "selfsubjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		sar := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		allowed := true
+		for _, resource := range deniedResources {
+			allowed = allowed && sar.Spec.ResourceAttributes.Resource != resource
+		}
+		sar.Status.Allowed = allowed
+		return true, sar, nil
+	})
+	return kubeClient
+}
+
+func TestRun(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "selfcheck")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(testDir)
+
+	validCert := testinghelpers.NewTestCert("system:bootstrap:abc", 60*time.Second)
+	validKubeconfig := path.Join(testDir, "valid")
+	testinghelpers.WriteFile(validKubeconfig, testinghelpers.NewKubeconfig(validCert.Key, validCert.Cert))
+	expiredCert := testinghelpers.NewTestCert("system:bootstrap:abc", -60*time.Second)
+	expiredKubeconfig := path.Join(testDir, "expired")
+	testinghelpers.WriteFile(expiredKubeconfig, testinghelpers.NewKubeconfig(expiredCert.Key, expiredCert.Cert))
+
+	now := time.Now()
+	cases := []struct {
+		name                string
+		bootstrapKubeconfig string
+		hubKubeClient       kubernetes.Interface
+		spokeKubeClient     kubernetes.Interface
+		hubTime             time.Time
+		expectedStatuses    []Status
+		expectedMessage     string
+	}{
+		{
+			name:                "all checks pass",
+			bootstrapKubeconfig: validKubeconfig,
+			hubKubeClient:       newKubeClient(),
+			spokeKubeClient:     newKubeClient(),
+			hubTime:             now.Add(10 * time.Second),
+			expectedStatuses:    []Status{StatusPass, StatusPass, StatusPass, StatusPass, StatusPass, StatusPass},
+		},
+		{
+			name:                "expired bootstrap kubeconfig",
+			bootstrapKubeconfig: expiredKubeconfig,
+			spokeKubeClient:     newKubeClient(),
+			expectedStatuses:    []Status{StatusFail, StatusSkip, StatusSkip, StatusSkip, StatusPass, StatusPass},
+			expectedMessage:     "[FAIL] bootstrap kubeconfig: the client certificate of the bootstrap kubeconfig expired at",
+		},
+		{
+			name:                "missing bootstrap kubeconfig",
+			bootstrapKubeconfig: path.Join(testDir, "missing"),
+			spokeKubeClient:     newKubeClient(),
+			expectedStatuses:    []Status{StatusFail, StatusSkip, StatusSkip, StatusSkip, StatusPass, StatusPass},
+			expectedMessage:     "[SKIP] hub reachability: the bootstrap kubeconfig is invalid",
+		},
+		{
+			name:                "denied on the hub",
+			bootstrapKubeconfig: validKubeconfig,
+			hubKubeClient:       newKubeClient("managedclusters"),
+			spokeKubeClient:     newKubeClient(),
+			hubTime:             now,
+			expectedStatuses:    []Status{StatusPass, StatusPass, StatusFail, StatusPass, StatusPass, StatusPass},
+			expectedMessage: "[FAIL] hub rbac: not allowed to create managedclusters.cluster.open-cluster-management.io, " +
+				"get managedclusters.cluster.open-cluster-management.io",
+		},
+		{
+			name:                "denied on the spoke",
+			bootstrapKubeconfig: validKubeconfig,
+			hubKubeClient:       newKubeClient(),
+			spokeKubeClient:     newKubeClient("events"),
+			hubTime:             now,
+			expectedStatuses:    []Status{StatusPass, StatusPass, StatusPass, StatusPass, StatusFail, StatusPass},
+			expectedMessage:     "[FAIL] spoke rbac: not allowed to create events in open-cluster-management-agent",
+		},
+		{
+			name:                "clock skew",
+			bootstrapKubeconfig: validKubeconfig,
+			hubKubeClient:       newKubeClient(),
+			spokeKubeClient:     newKubeClient(),
+			hubTime:             now.Add(-2 * time.Minute),
+			expectedStatuses:    []Status{StatusPass, StatusPass, StatusPass, StatusFail, StatusPass, StatusPass},
+			expectedMessage:     "[FAIL] clock skew: the clock skew to the hub is 2m0s, which is greater than 30s",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			checker := NewChecker(c.spokeKubeClient, c.spokeKubeClient)
+			checker.BootstrapKubeconfig = c.bootstrapKubeconfig
+			checker.ComponentNamespace = "open-cluster-management-agent"
+			checker.HubKubeconfigSecret = "hub-kubeconfig-secret"
+			checker.newHubKubeClient = func(config *rest.Config) (kubernetes.Interface, error) {
+				return c.hubKubeClient, nil
+			}
+			checker.hubTime = func(ctx context.Context, config *rest.Config) (time.Time, error) {
+				return c.hubTime, nil
+			}
+			checker.now = func() time.Time { return now }
+
+			results := checker.Run(context.TODO())
+			statuses := []Status{}
+			for _, result := range results {
+				statuses = append(statuses, result.Status)
+			}
+			if !reflect.DeepEqual(statuses, c.expectedStatuses) {
+				t.Errorf("expected statuses %v, but got %v", c.expectedStatuses, results)
+			}
+
+			output := &bytes.Buffer{}
+			failed := PrintReport(output, results)
+			expectedFailed := 0
+			for _, status := range c.expectedStatuses {
+				if status == StatusFail {
+					expectedFailed++
+				}
+			}
+			if failed != expectedFailed {
+				t.Errorf("expected %d failed checks, but got %d", expectedFailed, failed)
+			}
+			if !strings.Contains(output.String(), c.expectedMessage) {
+				t.Errorf("expected %q in the report:\n%s", c.expectedMessage, output.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package spiffe
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/health"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// SVIDRefreshInterval is the interval the SVID of the agent is fetched from the Workload API. The SPIRE agent
+// rotates the SVIDs at the half of their lifetime, so the rotated SVID is picked up long before the previous one
+// expires. It is exposed so that integration tests can shorten it.
+var SVIDRefreshInterval = time.Minute
+
+// svidCredentialController fetches the X.509 SVID of the agent from the Workload API and saves it, together with a
+// kubeconfig using it, in the hub kubeconfig secret on the managed cluster.
+type svidCredentialController struct {
+	socketAddress   string
+	trustDomain     string
+	clusterName     string
+	agentName       string
+	secretNamespace string
+	secretName      string
+	kubeconfigData  []byte
+	spokeCoreClient corev1client.CoreV1Interface
+	fetch           FetchFunc
+}
+
+// NewSVIDCredentialController returns a controller which keeps the X.509 SVID of the agent fetched from the
+// Workload API at socketAddress in the hub kubeconfig secret. The SPIFFE ID of the SVID must be the id of the
+// cluster and the agent, and the SVID must be issued with the subject the hub expects, see ParseID.
+func NewSVIDCredentialController(
+	socketAddress, trustDomain string,
+	clusterName, agentName string,
+	secretNamespace, secretName string,
+	kubeconfigData []byte,
+	spokeSecretInformer corev1informers.SecretInformer,
+	spokeCoreClient corev1client.CoreV1Interface,
+	recorder events.Recorder,
+	controllerName string,
+) factory.Controller {
+	c := &svidCredentialController{
+		socketAddress:   socketAddress,
+		trustDomain:     trustDomain,
+		clusterName:     clusterName,
+		agentName:       agentName,
+		secretNamespace: secretNamespace,
+		secretName:      secretName,
+		kubeconfigData:  kubeconfigData,
+		spokeCoreClient: spokeCoreClient,
+		fetch:           FetchX509SVIDs,
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			// only enqueue the hub kubeconfig secret
+			return accessor.GetNamespace() == secretNamespace && accessor.GetName() == secretName
+		}, spokeSecretInformer.Informer()).
+		WithSync(health.WrapSync(controllerName, c.sync)).
+		ResyncEvery(SVIDRefreshInterval).
+		ToController(controllerName, recorder)
+}
+
+func (c *svidCredentialController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	svids, err := c.fetch(ctx, c.socketAddress)
+	if err != nil {
+		return err
+	}
+	svid, _, agentName, err := SelectSVID(svids, c.trustDomain, c.clusterName)
+	if err != nil {
+		return err
+	}
+	if agentName != c.agentName {
+		return fmt.Errorf("SVID %q is issued for agent %q instead of %q", svid.ID, agentName, c.agentName)
+	}
+
+	secret, err := c.spokeCoreClient.Secrets(c.secretNamespace).Get(ctx, c.secretName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		secret = nil
+	case err != nil:
+		return fmt.Errorf("unable to get secret %q: %w", c.secretNamespace+"/"+c.secretName, err)
+	}
+
+	// only the fields managed by the controller are applied, the others are kept
+	appliedSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: c.secretNamespace,
+			Name:      c.secretName,
+		},
+		Data: map[string][]byte{
+			clientcert.ClusterNameFile: []byte(c.clusterName),
+			clientcert.AgentNameFile:   []byte(c.agentName),
+			clientcert.KubeconfigFile:  c.kubeconfigData,
+			clientcert.TLSCertFile:     svid.CertData,
+			clientcert.TLSKeyFile:      svid.KeyData,
+		},
+	}
+	if _, err := helpers.ApplySecret(ctx, c.spokeCoreClient, secret, appliedSecret); err != nil {
+		return err
+	}
+
+	if secret == nil || !bytes.Equal(secret.Data[clientcert.TLSCertFile], svid.CertData) {
+		syncCtx.Recorder().Eventf("SVIDUpdated", "The X.509 SVID %q of the agent is saved in secret %s/%s",
+			svid.ID, c.secretNamespace, c.secretName)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package spiffe
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestSVIDCredentialSync(t *testing.T) {
+	svids := newTestX509SVIDs(t,
+		newTestSVID(t, "spiffe://example.org/ocm/cluster/cluster1/agent/agent1", newAgentSubject("cluster1", "agent1")))
+	kubeconfigData := testinghelpers.NewKubeconfig(nil, nil)
+
+	newSecret := func(certData []byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "open-cluster-management-agent",
+				Name:            "hub-kubeconfig-secret",
+				UID:             "b5a1c0d4-8a6f-4f0c-9c2c-2f4e3d1a6b7e",
+				ResourceVersion: "1",
+			},
+			Data: map[string][]byte{
+				clientcert.ClusterNameFile: []byte("cluster1"),
+				clientcert.AgentNameFile:   []byte("agent1"),
+				clientcert.KubeconfigFile:  kubeconfigData,
+				clientcert.TLSCertFile:     certData,
+				clientcert.TLSKeyFile:      svids[0].KeyData,
+			},
+		}
+	}
+
+	cases := []struct {
+		name            string
+		agentName       string
+		secrets         []runtime.Object
+		fetchErr        error
+		expectedErr     string
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:        "workload api unavailable",
+			agentName:   "agent1",
+			fetchErr:    fmt.Errorf("connection refused"),
+			expectedErr: "connection refused",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:        "svid of another agent",
+			agentName:   "agent2",
+			expectedErr: "SVID \"spiffe://example.org/ocm/cluster/cluster1/agent/agent1\" is issued for agent \"agent1\" instead of \"agent2\"",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:      "no hub kubeconfig secret",
+			agentName: "agent1",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				secret := testinghelpers.AppliedSecret(t, actions[1])
+				if string(secret.Data[clientcert.TLSCertFile]) != string(svids[0].CertData) {
+					t.Errorf("expected the svid is saved, but got %q", string(secret.Data[clientcert.TLSCertFile]))
+				}
+				if string(secret.Data[clientcert.AgentNameFile]) != "agent1" {
+					t.Errorf("expected the agent name is saved, but got %q", string(secret.Data[clientcert.AgentNameFile]))
+				}
+			},
+		},
+		{
+			name:      "rotated svid",
+			agentName: "agent1",
+			secrets:   []runtime.Object{newSecret([]byte("stale"))},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+			},
+		},
+		{
+			name:      "saved svid",
+			agentName: "agent1",
+			secrets:   []runtime.Object{newSecret(svids[0].CertData)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			spokeKubeClient := kubefake.NewSimpleClientset(c.secrets...)
+			testinghelpers.AddSecretApplyReactor(&spokeKubeClient.Fake, spokeKubeClient.Tracker())
+
+			ctrl := &svidCredentialController{
+				socketAddress:   "unix:///run/spire/sockets/agent.sock",
+				trustDomain:     "example.org",
+				clusterName:     "cluster1",
+				agentName:       c.agentName,
+				secretNamespace: "open-cluster-management-agent",
+				secretName:      "hub-kubeconfig-secret",
+				kubeconfigData:  kubeconfigData,
+				spokeCoreClient: spokeKubeClient.CoreV1(),
+				fetch: func(ctx context.Context, address string) ([]X509SVID, error) {
+					return svids, c.fetchErr
+				},
+			}
+
+			err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "test"))
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			c.validateActions(t, spokeKubeClient.Actions())
+		})
+	}
+}
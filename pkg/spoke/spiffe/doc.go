@@ -0,0 +1,3 @@
+// package spiffe acquires the hub identity of the registration agent as an X.509 SVID from the SPIFFE Workload API
+// served by a local SPIRE agent, instead of the csrs on the hub.
+package spiffe
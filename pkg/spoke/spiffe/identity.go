@@ -0,0 +1,88 @@
+package spiffe
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"open-cluster-management.io/registration/pkg/hub/user"
+)
+
+// IDPathPrefix is the prefix of the path of the SPIFFE ID of a registration agent. The SPIFFE ID is
+// spiffe://<trust domain>/ocm/cluster/<cluster name>/agent/<agent name>.
+const IDPathPrefix = "/ocm/cluster/"
+
+// ParseID returns the names of the cluster and the agent in the SPIFFE ID of a registration agent. The trust
+// domain of the SPIFFE ID is verified if trustDomain is not empty.
+func ParseID(id, trustDomain string) (clusterName, agentName string, err error) {
+	u, err := url.Parse(id)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid SPIFFE ID %q: %w", id, err)
+	}
+	if u.Scheme != "spiffe" || len(u.Host) == 0 {
+		return "", "", fmt.Errorf("invalid SPIFFE ID %q", id)
+	}
+	if len(trustDomain) > 0 && u.Host != trustDomain {
+		return "", "", fmt.Errorf("SPIFFE ID %q is not in trust domain %q", id, trustDomain)
+	}
+
+	// <cluster name>/agent/<agent name>
+	segments := strings.Split(strings.TrimPrefix(u.Path, IDPathPrefix), "/")
+	if !strings.HasPrefix(u.Path, IDPathPrefix) || len(segments) != 3 || segments[1] != "agent" ||
+		len(segments[0]) == 0 || len(segments[2]) == 0 {
+		return "", "", fmt.Errorf("SPIFFE ID %q is not an id of a registration agent, %s<cluster name>/agent/<agent name> is expected",
+			id, IDPathPrefix)
+	}
+	return segments[0], segments[2], nil
+}
+
+// CommonName returns the common name the hub expects in the client certificate of the agent
+func CommonName(clusterName, agentName string) string {
+	return fmt.Sprintf("%s%s:%s", user.SubjectPrefix, clusterName, agentName)
+}
+
+// Groups returns the groups the hub expects in the organizations of the client certificate of the agent
+func Groups(clusterName string) []string {
+	return []string{user.SubjectPrefix + clusterName, user.ManagedClustersGroup}
+}
+
+// validateSubject returns an error if the leaf certificate of the SVID is not issued with the subject the hub
+// expects for the agent. The SPIRE server maps the SPIFFE ID to the subject with the X.509 SVID template of the
+// registration entry.
+func validateSubject(cert *x509.Certificate, clusterName, agentName string) error {
+	if cn := CommonName(clusterName, agentName); cert.Subject.CommonName != cn {
+		return fmt.Errorf("the common name of the SVID is %q instead of %q", cert.Subject.CommonName, cn)
+	}
+	if missing := sets.NewString(Groups(clusterName)...).Difference(sets.NewString(cert.Subject.Organization...)); missing.Len() > 0 {
+		return fmt.Errorf("the organizations of the SVID do not have %q", missing.List())
+	}
+	return nil
+}
+
+// SelectSVID returns the SVID of the registration agent among the SVIDs of the workload, and the names of the
+// cluster and the agent in its SPIFFE ID. If clusterName is not empty, only the SVID of the cluster is selected.
+func SelectSVID(svids []X509SVID, trustDomain, clusterName string) (svid X509SVID, cluster, agent string, err error) {
+	ids := []string{}
+	for _, svid := range svids {
+		ids = append(ids, svid.ID)
+		cluster, agent, err := ParseID(svid.ID, trustDomain)
+		if err != nil {
+			continue
+		}
+		if len(clusterName) > 0 && cluster != clusterName {
+			continue
+		}
+		if err := validateSubject(svid.Certificates[0], cluster, agent); err != nil {
+			return X509SVID{}, "", "", fmt.Errorf("invalid SVID %q: %w", svid.ID, err)
+		}
+		return svid, cluster, agent, nil
+	}
+
+	if len(clusterName) > 0 {
+		return X509SVID{}, "", "", fmt.Errorf("no SVID of a registration agent of cluster %q is found in %q", clusterName, ids)
+	}
+	return X509SVID{}, "", "", fmt.Errorf("no SVID of a registration agent is found in %q", ids)
+}
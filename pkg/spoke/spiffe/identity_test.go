@@ -0,0 +1,208 @@
+package spiffe
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+// newTestSVID returns an X.509 SVID of the SPIFFE ID with a certificate issued with the subject
+func newTestSVID(t *testing.T, id string, subject pkix.Name) *x509svid.SVID {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	uri, err := url.Parse(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      subject,
+		URIs:         []*url.URL{uri},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svid, err := x509svid.ParseRaw(certDER, keyDER)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return svid
+}
+
+// newTestX509SVIDs returns the X509SVIDs of the SVIDs
+func newTestX509SVIDs(t *testing.T, svids ...*x509svid.SVID) []X509SVID {
+	x509SVIDs := []X509SVID{}
+	for _, svid := range svids {
+		x509SVID, err := newX509SVID(svid)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		x509SVIDs = append(x509SVIDs, x509SVID)
+	}
+	return x509SVIDs
+}
+
+func newAgentSubject(clusterName, agentName string) pkix.Name {
+	return pkix.Name{CommonName: CommonName(clusterName, agentName), Organization: Groups(clusterName)}
+}
+
+func TestParseID(t *testing.T) {
+	cases := []struct {
+		name            string
+		id              string
+		trustDomain     string
+		expectedCluster string
+		expectedAgent   string
+		expectedErr     string
+	}{
+		{
+			name:            "agent id",
+			id:              "spiffe://example.org/ocm/cluster/cluster1/agent/agent1",
+			expectedCluster: "cluster1",
+			expectedAgent:   "agent1",
+		},
+		{
+			name:            "agent id in the trust domain",
+			id:              "spiffe://example.org/ocm/cluster/cluster1/agent/agent1",
+			trustDomain:     "example.org",
+			expectedCluster: "cluster1",
+			expectedAgent:   "agent1",
+		},
+		{
+			name:        "another trust domain",
+			id:          "spiffe://example.com/ocm/cluster/cluster1/agent/agent1",
+			trustDomain: "example.org",
+			expectedErr: "SPIFFE ID \"spiffe://example.com/ocm/cluster/cluster1/agent/agent1\" is not in trust domain \"example.org\"",
+		},
+		{
+			name:        "not a spiffe id",
+			id:          "https://example.org/ocm/cluster/cluster1/agent/agent1",
+			expectedErr: "invalid SPIFFE ID \"https://example.org/ocm/cluster/cluster1/agent/agent1\"",
+		},
+		{
+			name: "workload id",
+			id:   "spiffe://example.org/ns/open-cluster-management-agent/sa/klusterlet",
+			expectedErr: "SPIFFE ID \"spiffe://example.org/ns/open-cluster-management-agent/sa/klusterlet\" is not an id of a " +
+				"registration agent, /ocm/cluster/<cluster name>/agent/<agent name> is expected",
+		},
+		{
+			name: "no agent name",
+			id:   "spiffe://example.org/ocm/cluster/cluster1/agent/",
+			expectedErr: "SPIFFE ID \"spiffe://example.org/ocm/cluster/cluster1/agent/\" is not an id of a " +
+				"registration agent, /ocm/cluster/<cluster name>/agent/<agent name> is expected",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterName, agentName, err := ParseID(c.id, c.trustDomain)
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if clusterName != c.expectedCluster || agentName != c.expectedAgent {
+				t.Errorf("expected %s:%s, but got %s:%s", c.expectedCluster, c.expectedAgent, clusterName, agentName)
+			}
+		})
+	}
+}
+
+func TestSelectSVID(t *testing.T) {
+	workloadSVID := newTestSVID(t, "spiffe://example.org/ns/open-cluster-management-agent/sa/klusterlet",
+		pkix.Name{CommonName: "klusterlet"})
+	agentSVID := newTestSVID(t, "spiffe://example.org/ocm/cluster/cluster1/agent/agent1",
+		newAgentSubject("cluster1", "agent1"))
+	noGroupSVID := newTestSVID(t, "spiffe://example.org/ocm/cluster/cluster1/agent/agent1",
+		pkix.Name{CommonName: CommonName("cluster1", "agent1")})
+
+	cases := []struct {
+		name            string
+		svids           []*x509svid.SVID
+		clusterName     string
+		expectedCluster string
+		expectedAgent   string
+		expectedErr     string
+	}{
+		{
+			name:            "agent svid",
+			svids:           []*x509svid.SVID{workloadSVID, agentSVID},
+			expectedCluster: "cluster1",
+			expectedAgent:   "agent1",
+		},
+		{
+			name:            "agent svid of the cluster",
+			svids:           []*x509svid.SVID{agentSVID},
+			clusterName:     "cluster1",
+			expectedCluster: "cluster1",
+			expectedAgent:   "agent1",
+		},
+		{
+			name:        "agent svid of another cluster",
+			svids:       []*x509svid.SVID{agentSVID},
+			clusterName: "cluster2",
+			expectedErr: "no SVID of a registration agent of cluster \"cluster2\" is found in [\"spiffe://example.org/ocm/cluster/cluster1/agent/agent1\"]",
+		},
+		{
+			name:        "no agent svid",
+			svids:       []*x509svid.SVID{workloadSVID},
+			expectedErr: "no SVID of a registration agent is found in [\"spiffe://example.org/ns/open-cluster-management-agent/sa/klusterlet\"]",
+		},
+		{
+			name:  "no groups",
+			svids: []*x509svid.SVID{noGroupSVID},
+			expectedErr: "invalid SVID \"spiffe://example.org/ocm/cluster/cluster1/agent/agent1\": the organizations of the SVID do not have " +
+				"[\"system:open-cluster-management:cluster1\" \"system:open-cluster-management:managed-clusters\"]",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			svid, clusterName, agentName, err := SelectSVID(newTestX509SVIDs(t, c.svids...), "example.org", c.clusterName)
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if err != nil {
+				return
+			}
+			if clusterName != c.expectedCluster || agentName != c.expectedAgent {
+				t.Errorf("expected %s:%s, but got %s:%s", c.expectedCluster, c.expectedAgent, clusterName, agentName)
+			}
+			if key, ok := mustParseKey(t, svid.KeyData).(*ecdsa.PrivateKey); !ok || !key.PublicKey.Equal(svid.Certificates[0].PublicKey) {
+				t.Errorf("expected the key of the leaf certificate")
+			}
+		})
+	}
+}
+
+func mustDecodePEM(t *testing.T, data []byte) []byte {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatalf("invalid pem data %q", string(data))
+	}
+	return block.Bytes
+}
+
+func mustParseKey(t *testing.T, data []byte) interface{} {
+	key, err := x509.ParsePKCS8PrivateKey(mustDecodePEM(t, data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return key
+}
@@ -0,0 +1,77 @@
+package spiffe
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+)
+
+// unixSocketPrefix is the scheme of the address of the Workload API endpoint
+const unixSocketPrefix = "unix://"
+
+// X509SVID is an X.509 SVID fetched from the Workload API
+type X509SVID struct {
+	// ID is the SPIFFE ID of the SVID
+	ID string
+	// CertData is the PEM encoded certificate chain, the leaf certificate comes first
+	CertData []byte
+	// KeyData is the PEM encoded private key of the leaf certificate in PKCS#8
+	KeyData []byte
+	// Certificates is the parsed certificate chain
+	Certificates []*x509.Certificate
+}
+
+// FetchFunc fetches the X.509 SVIDs of the agent from the Workload API at the address
+type FetchFunc func(ctx context.Context, address string) ([]X509SVID, error)
+
+// IsValidSocketAddress returns true if the address is a unix socket address of the Workload API, e.g.
+// unix:///run/spire/sockets/agent.sock
+func IsValidSocketAddress(address string) bool {
+	return strings.HasPrefix(address, unixSocketPrefix) && len(strings.TrimPrefix(address, unixSocketPrefix)) > 0
+}
+
+// FetchX509SVIDs fetches the X.509 SVIDs of the agent from the Workload API at the unix socket address with the
+// Workload API client of go-spiffe. The SVIDs are fetched once instead of being watched, because they are fetched
+// again before they expire.
+func FetchX509SVIDs(ctx context.Context, address string) ([]X509SVID, error) {
+	if !IsValidSocketAddress(address) {
+		return nil, fmt.Errorf("invalid address of the Workload API %q, a unix socket address is expected", address)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	fetched, err := workloadapi.FetchX509SVIDs(ctx, workloadapi.WithAddr(address))
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch the X.509 SVIDs from %q: %w", address, err)
+	}
+
+	svids := []X509SVID{}
+	for _, svid := range fetched {
+		x509SVID, err := newX509SVID(svid)
+		if err != nil {
+			return nil, err
+		}
+		svids = append(svids, x509SVID)
+	}
+	return svids, nil
+}
+
+// newX509SVID returns the X509SVID with the PEM encoded certificate chain and private key of the SVID
+func newX509SVID(svid *x509svid.SVID) (X509SVID, error) {
+	certData, keyData, err := svid.Marshal()
+	if err != nil {
+		return X509SVID{}, fmt.Errorf("unable to encode SVID %q: %w", svid.ID, err)
+	}
+	return X509SVID{
+		ID:           svid.ID.String(),
+		CertData:     certData,
+		KeyData:      keyData,
+		Certificates: svid.Certificates,
+	}, nil
+}
@@ -0,0 +1,101 @@
+package spiffe
+
+import (
+	"context"
+	"net"
+	"path"
+	"testing"
+
+	"github.com/spiffe/go-spiffe/v2/proto/spiffe/workload"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"google.golang.org/grpc"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+// fakeWorkloadAPI serves the X.509 SVIDs on the FetchX509SVID method of the Workload API
+type fakeWorkloadAPI struct {
+	workload.UnimplementedSpiffeWorkloadAPIServer
+	svids []*x509svid.SVID
+}
+
+func (f *fakeWorkloadAPI) FetchX509SVID(req *workload.X509SVIDRequest, stream workload.SpiffeWorkloadAPI_FetchX509SVIDServer) error {
+	resp := &workload.X509SVIDResponse{}
+	for _, svid := range f.svids {
+		certDER, keyDER, err := svid.MarshalRaw()
+		if err != nil {
+			return err
+		}
+		resp.Svids = append(resp.Svids, &workload.X509SVID{
+			SpiffeId:    svid.ID.String(),
+			X509Svid:    certDER,
+			X509SvidKey: keyDER,
+		})
+	}
+	if err := stream.Send(resp); err != nil {
+		return err
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+// newFakeWorkloadAPI serves the Workload API with the SVIDs on a unix socket, and returns the address of the socket
+func newFakeWorkloadAPI(t *testing.T, svids ...*x509svid.SVID) string {
+	socketPath := path.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server := grpc.NewServer()
+	workload.RegisterSpiffeWorkloadAPIServer(server, &fakeWorkloadAPI{svids: svids})
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return unixSocketPrefix + socketPath
+}
+
+func TestFetchX509SVIDs(t *testing.T) {
+	address := newFakeWorkloadAPI(t,
+		newTestSVID(t, "spiffe://example.org/ocm/cluster/cluster1/agent/agent1", newAgentSubject("cluster1", "agent1")))
+	noSVIDAddress := newFakeWorkloadAPI(t)
+
+	cases := []struct {
+		name        string
+		address     string
+		expectedErr string
+	}{
+		{
+			name:        "not a unix socket",
+			address:     "tcp://127.0.0.1:8081",
+			expectedErr: "invalid address of the Workload API \"tcp://127.0.0.1:8081\", a unix socket address is expected",
+		},
+		{
+			name:        "no svid",
+			address:     noSVIDAddress,
+			expectedErr: "unable to fetch the X.509 SVIDs from \"" + noSVIDAddress + "\": no SVIDs in response",
+		},
+		{
+			name:    "svids fetched",
+			address: address,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			svids, err := FetchX509SVIDs(context.TODO(), c.address)
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if err != nil {
+				return
+			}
+			if len(svids) != 1 || svids[0].ID != "spiffe://example.org/ocm/cluster/cluster1/agent/agent1" {
+				t.Fatalf("unexpected svids %v", svids)
+			}
+			if svids[0].Certificates[0].Subject.CommonName != "system:open-cluster-management:cluster1:agent1" {
+				t.Errorf("unexpected subject %v", svids[0].Certificates[0].Subject)
+			}
+		})
+	}
+}
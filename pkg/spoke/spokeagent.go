@@ -2,45 +2,63 @@ package spoke
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io/ioutil"
-	"os"
 	"path"
+	"strings"
+	"sync"
 	"time"
 
 	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
 	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
 	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterv1informers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+	configv1alpha1 "open-cluster-management.io/registration/pkg/apis/config/v1alpha1"
 	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/cloudevents"
+	"open-cluster-management.io/registration/pkg/debug"
 	"open-cluster-management.io/registration/pkg/features"
+	"open-cluster-management.io/registration/pkg/fips"
+	"open-cluster-management.io/registration/pkg/grpcregistration"
+	"open-cluster-management.io/registration/pkg/health"
 	"open-cluster-management.io/registration/pkg/helpers"
-	"open-cluster-management.io/registration/pkg/spoke/addon"
+	"open-cluster-management.io/registration/pkg/sdk"
+	"open-cluster-management.io/registration/pkg/secureserving"
+	"open-cluster-management.io/registration/pkg/spoke/bootstrapcredential"
+	"open-cluster-management.io/registration/pkg/spoke/configfile"
+	"open-cluster-management.io/registration/pkg/spoke/filewatch"
+	"open-cluster-management.io/registration/pkg/spoke/grpcagent"
+	"open-cluster-management.io/registration/pkg/spoke/lease"
 	"open-cluster-management.io/registration/pkg/spoke/managedcluster"
+	"open-cluster-management.io/registration/pkg/spoke/spiffe"
 
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 
 	"github.com/spf13/pflag"
+	"google.golang.org/grpc/credentials"
 
+	certificatesv1 "k8s.io/api/certificates/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
-	utilrand "k8s.io/apimachinery/pkg/util/rand"
-	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
+	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/klog/v2"
 )
 
 const (
-	// spokeAgentNameLength is the length of the spoke agent name which is generated automatically
-	spokeAgentNameLength = 5
 	// defaultSpokeComponentNamespace is the default namespace in which the spoke agent is deployed
 	defaultSpokeComponentNamespace = "open-cluster-management-agent"
 )
@@ -49,6 +67,13 @@ const (
 // TODO if we register the lease informer to the lease controller, we need to increase this time
 var AddOnLeaseControllerSyncInterval = 30 * time.Second
 
+// The default secret of the client certificate of the reverse tunnel agent, which is the one the proxy agent of
+// cluster-proxy reads
+const (
+	defaultReverseTunnelSecretNamespace = "open-cluster-management-cluster-proxy"
+	defaultReverseTunnelSecretName      = "cluster-proxy-open-cluster-management.io-proxy-agent-signer-client-cert"
+)
+
 // SpokeAgentOptions holds configuration for spoke cluster agent
 type SpokeAgentOptions struct {
 	ComponentNamespace       string
@@ -61,6 +86,187 @@ type SpokeAgentOptions struct {
 	ClusterHealthCheckPeriod time.Duration
 	MaxCustomClusterClaims   int
 	SpokeKubeconfig          string
+
+	// HubKubeconfigStorage is where the files of the hub kubeconfig secret are materialized, one of dir, memory and
+	// auto. The files are materialized in HubKubeconfigMemoryDir on the memory-backed filesystem in the memory
+	// storage, or in the auto storage if HubKubeconfigDir is not writable, e.g. on a read-only root filesystem.
+	HubKubeconfigStorage   string
+	HubKubeconfigMemoryDir string
+
+	// AgentNameFromServiceAccount derives the agent name from the service account the agent runs with instead of
+	// generating a random one, see sdk.DeriveAgentName. The existing agents keep their names in the hub kubeconfig.
+	AgentNameFromServiceAccount bool
+
+	// BootstrapCredentialDir is the directory the bootstrap kubeconfig is assembled from instead of
+	// BootstrapKubeconfig, e.g. a volume mounted by the secrets-store CSI driver, see bootstrapcredential. The agent
+	// restarts to re-assemble the kubeconfig once the files are changed during the bootstrap.
+	BootstrapCredentialDir string
+
+	// RegistrationSignerName is the signer name of the csrs of the client certificate of the agent, it is
+	// open-cluster-management.io/cert-manager if the hub signs the client certificates with a cert-manager issuer.
+	// The kube-apiserver-client signer is used if it is empty.
+	RegistrationSignerName string
+
+	// AllowedSignerNames are the signers the agent is allowed to request certificates from. The registration and
+	// the reverse tunnel signers must be in the list, and no csr is created for the addon registrations with the
+	// other signers, which is reported in the ClientCertificateRotated condition of the addons instead. All
+	// signers are allowed if it is empty.
+	AllowedSignerNames []string
+
+	// MaxConcurrentAddOnRegistrations limits the addon registrations in flight, i.e. waiting for their client
+	// certificates to be issued, when many addons are enabled at once. The registrations beyond the limit are
+	// retried after AddOnRegistrationStaggerInterval. A registration whose csr is denied or not approved in 10
+	// minutes is no longer counted.
+	MaxConcurrentAddOnRegistrations  int
+	AddOnRegistrationStaggerInterval time.Duration
+
+	// ClientCertKeyType and ClientCertKeyBitSize are the type and the size of the private keys of the client
+	// certificates requested by the agent, i.e. the certificates of the agent, the reverse tunnel and the addons.
+	// An ECDSA P-256 key is generated by default.
+	ClientCertKeyType    string
+	ClientCertKeyBitSize int
+
+	// ClientCertRenewalJitter is the ratio of the certificate lifetime the renewals of the client certificates are
+	// spread over, so that the certificates of many clusters issued together are not renewed at once. A quarter of
+	// the renewal threshold is used if it is negative, which is the default, and 0 turns the jitter off.
+	ClientCertRenewalJitter float64
+
+	// DebugBindAddress is the address the internal state of the controllers is served on for troubleshooting, the
+	// state is not served if it is empty.
+	DebugBindAddress string
+
+	// SecureBindAddress is the address the metrics and the internal state of the controllers are served on over
+	// TLS to the users authorized by the kube apiserver, they are not served securely if it is empty. The serving
+	// certificate is loaded from SecureServingCertFile and SecureServingKeyFile, or self-signed if they are empty.
+	SecureBindAddress     string
+	SecureServingCertFile string
+	SecureServingKeyFile  string
+
+	// HealthProbeBindAddress is the address the health checks of the controllers are served on, a check fails if
+	// the controller processes no key in ControllerProgressDeadline while it has pending work.
+	HealthProbeBindAddress     string
+	ControllerProgressDeadline time.Duration
+
+	// SlowSyncThreshold is the duration after which a sync of a controller is logged and counted as slow
+	SlowSyncThreshold time.Duration
+
+	// RetryBaseDelay, RetryMaxDelay and RetryQPS limit the retries of the failed keys of the controllers. A failed
+	// key is retried after a delay growing exponentially from RetryBaseDelay up to RetryMaxDelay, and the retries of
+	// a controller are limited to RetryQPS.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	RetryQPS       float64
+
+	// VaultAddress, VaultCAFile, VaultAuthPath, VaultAuthRole, VaultPKIPath and VaultPKIRole configure the agent to
+	// request its client certificate from a Vault PKI role instead of the csrs on the hub, see clientcert.VaultConfig.
+	// The csrs on the hub are used if VaultAddress is empty.
+	VaultAddress  string
+	VaultCAFile   string
+	VaultAuthPath string
+	VaultAuthRole string
+	VaultPKIPath  string
+	VaultPKIRole  string
+
+	// SpiffeEndpointSocket is the unix socket address of the SPIFFE Workload API served by a local SPIRE agent. The
+	// agent uses its X.509 SVID as the client certificate for the hub instead of requesting one with the csrs on the
+	// hub if it is set. The names of the cluster and the agent are taken from the SPIFFE ID of the SVID, see
+	// spiffe.ParseID. Only the SPIFFE IDs in SpiffeTrustDomain are accepted if it is set.
+	SpiffeEndpointSocket string
+	SpiffeTrustDomain    string
+
+	// RegistrationDriver is the way the agent authenticates to the hub, csr for a client certificate signed with a csr
+	// on the hub, token for the token of the registration agent service account provisioned by the hub in the
+	// managed cluster namespace, which is used for the hubs whose api server does not accept client certificates,
+	// awsiam for the IAM role AWSIAMRoleARN, which the EKS hub AWSHubClusterName maps to the agent, or azure and
+	// gcp for the workload identity of the agent pod on Azure and GCP, which the hub admin binds to the roles of
+	// the agent. The Azure AD tokens are requested for the AKS AAD server AzureServerID.
+	RegistrationDriver string
+	AWSHubClusterName  string
+	AWSIAMRoleARN      string
+	AzureServerID      string
+
+	// RegistrationTransport is the way the agent registers to the hub, kube for the apis of the hub kube-apiserver,
+	// or grpc for the gRPC Registration service at GRPCServerAddress, for the hubs which are not Kubernetes
+	// clusters. The serving certificate of the service is verified with GRPCCAFile, and the agent authenticates
+	// with the client certificate in GRPCClientCertFile and GRPCClientKeyFile.
+	RegistrationTransport string
+	GRPCServerAddress     string
+	GRPCCAFile            string
+	GRPCClientCertFile    string
+	GRPCClientKeyFile     string
+
+	// CloudEventsBrokerAddress is the address of the MQTT broker the status and the heartbeats of the cluster are
+	// published to as CloudEvents instead of the hub kube-apiserver, for the edge fleets behind unreliable links.
+	// The serving certificate of the broker is verified with CloudEventsCAFile, and the agent authenticates with
+	// the client certificate in CloudEventsClientCertFile and CloudEventsClientKeyFile, which may be the client
+	// certificate of the agent in the hub kubeconfig secret.
+	CloudEventsBrokerAddress  string
+	CloudEventsCAFile         string
+	CloudEventsClientCertFile string
+	CloudEventsClientKeyFile  string
+
+	// ClaimsOnly registers an endpoint which is not a Kubernetes cluster, e.g. an edge gateway or a VM. The agent
+	// has no spoke kubeconfig, collects no nodes or capacity, and reports only the Claims and the heartbeats. The
+	// controllers depending on the kube-apiserver of the managed cluster, e.g. the addon management, are not started.
+	ClaimsOnly bool
+	Claims     map[string]string
+
+	// ClaimTemplates are the cluster claims whose values are templated from the data of the managed cluster, e.g.
+	// the labels of the nodes, the annotations of the namespaces and the server version, by the names of the
+	// claims. They are rendered by the cluster claim controller, see managedcluster.ClaimTemplateRenderer.
+	ClaimTemplates map[string]string
+
+	// DisableAddOnRegistration disables the addon management of the agent for the minimal installations which only
+	// register the cluster. The addon informers and controllers are not started, and the cluster is annotated with
+	// the addon registration disabled on each start, so the hub does not bind the addon role of the agent. The
+	// addon clusterrole of the agent on the managed cluster is not required either.
+	DisableAddOnRegistration bool
+
+	// WriteBackClusterProperties writes the cluster claims back as the ClusterProperties of the About API on the
+	// managed cluster, it is used when the feature ClusterProperty is enabled.
+	WriteBackClusterProperties bool
+
+	// BootstrapReverseTunnel requests the client certificate of the reverse tunnel agent, e.g. the proxy agent of
+	// cluster-proxy, with ReverseTunnelSignerName along with the registration, and keeps it in the secret
+	// ReverseTunnelSecretName in ReverseTunnelSecretNamespace on the managed cluster. It is used when the feature
+	// ReverseTunnelBootstrap is enabled.
+	BootstrapReverseTunnel       bool
+	ReverseTunnelSignerName      string
+	ReverseTunnelSecretNamespace string
+	ReverseTunnelSecretName      string
+
+	// FIPSMode restricts the crypto of the agent to the FIPS-approved algorithms, see fips. The bootstrap kubeconfig
+	// must verify the hub, and the plaintext connections to the broker are not allowed. It is always enabled in a
+	// binary built with boringcrypto.
+	FIPSMode bool
+
+	// TerminationMessagePath is the file the reason of a fatal error is written to before the agent exits
+	TerminationMessagePath string
+
+	// TerminateOnChangeFiles are the files injected into the agent, e.g. CA bundles and proxy certificates, which are
+	// only read when the agent starts. The agent restarts once any of them is changed, created or removed, and
+	// reports the changed file as the reason, unlike the terminate-on-files of library-go, see filewatch.
+	TerminateOnChangeFiles []string
+
+	// flags are the command line flags registered by AddFlags, they take precedence over the configuration file
+	flags *pflag.FlagSet
+
+	// configFile is the configuration file taken over from library-go, which is reloaded once it is changed, and
+	// appliedConfig is the configuration the agent runs with, see TakeOverConfigFile.
+	configFile    string
+	appliedConfig *configv1alpha1.SpokeAgentConfiguration
+
+	// resolvedHubKubeconfigDir is the directory the files of the hub kubeconfig secret are materialized in, it is
+	// resolved from HubKubeconfigStorage when the agent starts
+	resolvedHubKubeconfigDir string
+
+	// hubFeatureGates are the feature gates published by the hub, and startupLogLevel is the log level the agent is
+	// started with, which is restored once the log level published by the hub is removed, see applyHubSettings.
+	hubFeatureGates map[string]bool
+	startupLogLevel int32
+
+	// clusterHealthCheckPeriodNanos is the ClusterHealthCheckPeriod read by the controllers while the agent runs
+	clusterHealthCheckPeriodNanos int64
 }
 
 // NewSpokeAgentOptions returns a SpokeAgentOptions
@@ -68,8 +274,32 @@ func NewSpokeAgentOptions() *SpokeAgentOptions {
 	return &SpokeAgentOptions{
 		HubKubeconfigSecret:      "hub-kubeconfig-secret",
 		HubKubeconfigDir:         "/spoke/hub-kubeconfig",
+		HubKubeconfigStorage:     HubKubeconfigStorageDir,
+		HubKubeconfigMemoryDir:   defaultHubKubeconfigMemoryDir,
 		ClusterHealthCheckPeriod: 1 * time.Minute,
 		MaxCustomClusterClaims:   20,
+		RegistrationSignerName:   certificatesv1.KubeAPIServerClientSignerName,
+		ClientCertRenewalJitter:  -1,
+		VaultAuthPath:            clientcert.DefaultVaultAuthPath,
+		VaultPKIPath:             clientcert.DefaultVaultPKIPath,
+		RegistrationDriver:       helpers.CSRRegistrationDriver,
+		AzureServerID:            clientcert.AzureServerID,
+		RegistrationTransport:    helpers.KubeRegistrationTransport,
+		FIPSMode:                 fips.BuiltIn(),
+
+		ReverseTunnelSignerName:      helpers.DefaultReverseTunnelSignerName,
+		ReverseTunnelSecretNamespace: defaultReverseTunnelSecretNamespace,
+		ReverseTunnelSecretName:      defaultReverseTunnelSecretName,
+
+		MaxConcurrentAddOnRegistrations:  10,
+		AddOnRegistrationStaggerInterval: 2 * time.Second,
+
+		TerminationMessagePath:     defaultTerminationMessagePath,
+		ControllerProgressDeadline: health.DefaultProgressDeadline,
+		SlowSyncThreshold:          health.DefaultSlowSyncThreshold,
+		RetryBaseDelay:             health.DefaultRetryBaseDelay,
+		RetryMaxDelay:              health.DefaultRetryMaxDelay,
+		RetryQPS:                   health.DefaultRetryQPS,
 	}
 }
 
@@ -102,7 +332,41 @@ func NewSpokeAgentOptions() *SpokeAgentOptions {
 // and started if the hub kubeconfig does not exist or is invalid and used to
 // create a valid hub kubeconfig. Once the hub kubeconfig is valid, the
 // temporary controller is stopped and the main controllers are started.
+//
+// If the registration transport is grpc, the agent registers over the gRPC Registration service of the hub
+// instead, and neither the bootstrap kubeconfig nor the hub kubeconfig is used, see runGRPCAgent.
+//
+// If the agent runs with claims-only, it registers an endpoint which is not a Kubernetes cluster. No spoke client
+// is created, and only the configured claims and the heartbeats are reported with either transport.
+//
+// If the agent exits with a fatal error, e.g. the bootstrap kubeconfig is invalid or the agent is denied by rbac,
+// the reason is written to the termination message path and reported with an event.
 func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
+	err := o.runSpokeAgent(ctx, controllerContext)
+	if err != nil {
+		o.reportTermination(controllerContext.EventRecorder, err)
+	}
+	return err
+}
+
+func (o *SpokeAgentOptions) runSpokeAgent(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
+	// the configuration file is applied before the options are completed and validated
+	configFileData, err := o.loadConfiguration(controllerContext.ComponentConfig)
+	if err != nil {
+		return newTerminationError(TerminationReasonInvalidOptions, err)
+	}
+	features.ReportFeatureGates(features.Spoke)
+
+	// the files of the hub kubeconfig secret are materialized in the directory resolved from the storage
+	o.resolvedHubKubeconfigDir, err = o.resolveHubKubeconfigDir()
+	if err != nil {
+		return newTerminationError(TerminationReasonInvalidOptions, err)
+	}
+
+	// the controllers log with the logger of the agent in the context
+	ctx = helpers.NewComponentContext(ctx, "registration-agent")
+	logger := klog.FromContext(ctx)
+
 	// create management kube client
 	managementKubeClient, err := kubernetes.NewForConfig(controllerContext.KubeConfig)
 	if err != nil {
@@ -111,43 +375,155 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 
 	// load spoke client config and create spoke clients,
 	// the registration agent may not running in the spoke/managed cluster.
-	spokeClientConfig, err := o.spokeKubeConfig(controllerContext)
-	if err != nil {
-		return err
+	// There is no kube-apiserver on the managed cluster in the claims-only mode, so no spoke client is created.
+	var spokeClientConfig *rest.Config
+	var spokeKubeClient kubernetes.Interface
+	if !o.ClaimsOnly {
+		spokeClientConfig, err = o.spokeKubeConfig(controllerContext)
+		if err != nil {
+			return err
+		}
+
+		spokeKubeClient, err = kubernetes.NewForConfig(spokeClientConfig)
+		if err != nil {
+			return err
+		}
 	}
 
-	spokeKubeClient, err := kubernetes.NewForConfig(spokeClientConfig)
-	if err != nil {
-		return err
+	// discard the identity in the hub kubeconfig secret before it is dumped, if the secret is restored from the
+	// backup of another cluster
+	if spokeKubeClient != nil {
+		if err := managedcluster.EnsureClusterIdentity(ctx, managementKubeClient.CoreV1(), spokeKubeClient.CoreV1(),
+			componentNamespace(), o.HubKubeconfigSecret, o.hubKubeconfigDir(), controllerContext.EventRecorder); err != nil {
+			return err
+		}
 	}
 
+	// the cluster is renamed on the hub only if its name is not configured explicitly
+	clusterNameConfigured := len(o.ClusterName) > 0 || len(o.SpiffeEndpointSocket) > 0
+
 	// the hub kubeconfig secret stored in the cluster where the agent pod runs
 	if err := o.Complete(managementKubeClient.CoreV1(), ctx, controllerContext.EventRecorder); err != nil {
-		klog.Fatal(err)
+		return err
 	}
 
 	if err := o.Validate(); err != nil {
-		klog.Fatal(err)
+		return newTerminationError(TerminationReasonInvalidOptions, err)
+	}
+	if o.FIPSMode {
+		fips.Enable()
 	}
 
-	klog.Infof("Cluster name is %q and agent name is %q", o.ClusterName, o.AgentName)
+	logger.Info("Starting the registration agent", helpers.LogKeyCluster, o.ClusterName, "agent", o.AgentName)
+
+	if len(o.DebugBindAddress) > 0 {
+		if err := debug.Serve(ctx, o.DebugBindAddress); err != nil {
+			return err
+		}
+	}
+	if len(o.SecureBindAddress) > 0 {
+		if err := secureserving.Serve(ctx, o.SecureBindAddress, o.SecureServingCertFile, o.SecureServingKeyFile,
+			managementKubeClient); err != nil {
+			return err
+		}
+	}
 
-	// create shared informer factory for spoke cluster
-	spokeKubeInformerFactory := informers.NewSharedInformerFactory(spokeKubeClient, 10*time.Minute)
+	o.applyRuntimeSettings()
+	o.startupLogLevel = helpers.LogLevel()
+	if len(o.HealthProbeBindAddress) > 0 {
+		if err := health.Serve(ctx, o.HealthProbeBindAddress); err != nil {
+			return err
+		}
+	}
 
-	// get spoke cluster CA bundle
-	spokeClusterCABundle, err := o.getSpokeClusterCABundle(spokeClientConfig)
-	if err != nil {
-		return err
+	// reload the configuration file taken over from library-go once it is changed, the agent is restarted if a
+	// setting which is only read when the agent starts is changed
+	configFileChanged := make(chan struct{})
+	if len(o.configFile) > 0 {
+		var configFileChangedOnce sync.Once
+		configFileController := configfile.NewConfigFileController(o.configFile, configFileData,
+			func(ctx context.Context, data []byte) error {
+				return o.reloadConfigFile(data, func() { configFileChangedOnce.Do(func() { close(configFileChanged) }) })
+			},
+			controllerContext.EventRecorder,
+		)
+		go health.RunController(ctx, configFileController, 1)
+	}
+
+	// restart the agent once any of the files read when the agent starts is changed, so that the rotated files are
+	// not left stale in memory
+	watchedFileChanged := make(chan struct{})
+	var changedWatchedFile string
+	if len(o.TerminateOnChangeFiles) > 0 {
+		digests, err := filewatch.Digests(o.TerminateOnChangeFiles)
+		if err != nil {
+			return err
+		}
+		var watchedFileChangedOnce sync.Once
+		fileWatchController := filewatch.NewFileWatchController(digests,
+			func(file string) {
+				watchedFileChangedOnce.Do(func() {
+					changedWatchedFile = file
+					close(watchedFileChanged)
+				})
+			},
+			controllerContext.EventRecorder,
+		)
+		go health.RunController(ctx, fileWatchController, 1)
+	}
+
+	// restarting returns the error the agent exits with once it is restarting, or nil if it is not
+	restarting := func() error {
+		select {
+		case <-configFileChanged:
+			return errConfigFileChanged
+		case <-watchedFileChanged:
+			return watchedFileChangedError(changedWatchedFile)
+		default:
+			return nil
+		}
+	}
+
+	var spokeKubeInformerFactory informers.SharedInformerFactory
+	var spokeClusterCABundle []byte
+	if !o.ClaimsOnly {
+		// create shared informer factory for spoke cluster
+		spokeKubeInformerFactory = informers.NewSharedInformerFactory(spokeKubeClient, 10*time.Minute)
+		installNodeInformerTransforms(spokeKubeInformerFactory)
+
+		// get spoke cluster CA bundle
+		spokeClusterCABundle, err = o.getSpokeClusterCABundle(spokeClientConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.RegistrationTransport == helpers.GRPCRegistrationTransport {
+		grpcCtx, stopGRPCAgent := context.WithCancel(ctx)
+		defer stopGRPCAgent()
+		go func() {
+			select {
+			case <-configFileChanged:
+				stopGRPCAgent()
+			case <-watchedFileChanged:
+				stopGRPCAgent()
+			case <-grpcCtx.Done():
+			}
+		}()
+		if err := o.runGRPCAgent(grpcCtx, o.newStatusCollector(spokeKubeClient, spokeKubeInformerFactory),
+			spokeKubeInformerFactory, spokeClusterCABundle, controllerContext.EventRecorder); err != nil {
+			return err
+		}
+		return restarting()
 	}
 
 	// create a shared informer factory with specific namespace for the management cluster.
 	namespacedManagementKubeInformerFactory := informers.NewSharedInformerFactoryWithOptions(managementKubeClient, 10*time.Minute, informers.WithNamespace(o.ComponentNamespace))
 
 	// load bootstrap client config and create bootstrap clients
-	bootstrapClientConfig, err := clientcmd.BuildConfigFromFlags("", o.BootstrapKubeconfig)
+	bootstrapClientConfig, bootstrapCredentialDigest, err := o.loadBootstrapClientConfig()
 	if err != nil {
-		return fmt.Errorf("unable to load bootstrap kubeconfig from file %q: %w", o.BootstrapKubeconfig, err)
+		return newTerminationError(TerminationReasonInvalidBootstrapKubeconfig, err)
 	}
 	bootstrapKubeClient, err := kubernetes.NewForConfig(bootstrapClientConfig)
 	if err != nil {
@@ -162,19 +538,20 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 	spokeClusterCreatingController := managedcluster.NewManagedClusterCreatingController(
 		o.ClusterName, o.SpokeExternalServerURLs,
 		spokeClusterCABundle,
+		o.managedClusterAnnotations(),
 		bootstrapClusterClient,
 		controllerContext.EventRecorder,
 	)
-	go spokeClusterCreatingController.Run(ctx, 1)
+	go health.RunController(ctx, spokeClusterCreatingController, 1)
 
 	hubKubeconfigSecretController := managedcluster.NewHubKubeconfigSecretController(
-		o.HubKubeconfigDir, o.ComponentNamespace, o.HubKubeconfigSecret,
+		o.hubKubeconfigDir(), o.ComponentNamespace, o.HubKubeconfigSecret,
 		// the hub kubeconfig secret stored in the cluster where the agent pod runs
 		managementKubeClient.CoreV1(),
 		namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
 		controllerContext.EventRecorder,
 	)
-	go hubKubeconfigSecretController.Run(ctx, 1)
+	go health.RunController(ctx, hubKubeconfigSecretController, 1)
 
 	// check if there already exists a valid client config for hub
 	ok, err := o.hasValidHubClientConfig()
@@ -182,59 +559,16 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 		return err
 	}
 
-	// create and start a ClientCertForHubController for spoke agent bootstrap to deal with scenario #1 and #4.
-	// Running the bootstrap ClientCertForHubController is optional. If always run it no matter if there already
-	// exists a valid client config for hub or not, the controller will be started and then stopped immediately
-	// in scenario #2 and #3, which results in an error message in log: 'Observed a panic: timeout waiting for
-	// informer cache'
 	if !ok {
-		// create a ClientCertForHubController for spoke agent bootstrap
-		bootstrapInformerFactory := informers.NewSharedInformerFactory(bootstrapKubeClient, 10*time.Minute)
-
-		// create a kubeconfig with references to the key/cert files in the same secret
-		kubeconfig := clientcert.BuildKubeconfig(bootstrapClientConfig, clientcert.TLSCertFile, clientcert.TLSKeyFile)
-		kubeconfigData, err := clientcmd.Write(kubeconfig)
-		if err != nil {
+		if err := o.bootstrap(ctx, bootstrapClientConfig, bootstrapCredentialDigest, bootstrapKubeClient,
+			bootstrapClusterClient, managementKubeClient, spokeKubeClient, namespacedManagementKubeInformerFactory,
+			controllerContext.EventRecorder, restarting); err != nil {
 			return err
 		}
-
-		controllerName := fmt.Sprintf("BootstrapClientCertController@cluster:%s", o.ClusterName)
-		clientCertForHubController, err := managedcluster.NewClientCertForHubController(
-			o.ClusterName, o.AgentName, o.ComponentNamespace, o.HubKubeconfigSecret,
-			kubeconfigData,
-			// store the secret in the cluster where the agent pod runs
-			namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
-			bootstrapInformerFactory.Certificates(),
-			managementKubeClient,
-			bootstrapKubeClient,
-			controllerContext.EventRecorder,
-			controllerName,
-		)
-		if err != nil {
-			return err
-		}
-
-		bootstrapCtx, stopBootstrap := context.WithCancel(ctx)
-
-		go bootstrapInformerFactory.Start(bootstrapCtx.Done())
-		go namespacedManagementKubeInformerFactory.Start(bootstrapCtx.Done())
-
-		go clientCertForHubController.Run(bootstrapCtx, 1)
-
-		// wait for the hub client config is ready.
-		klog.Info("Waiting for hub client config and managed cluster to be ready")
-		if err := wait.PollImmediateInfinite(1*time.Second, o.hasValidHubClientConfig); err != nil {
-			// TODO need run the bootstrap CSR forever to re-establish the client-cert if it is ever lost.
-			stopBootstrap()
-			return err
-		}
-
-		// stop the clientCertForHubController for bootstrap once the hub client config is ready
-		stopBootstrap()
 	}
 
 	// create hub clients and shared informer factories from hub kube config
-	hubClientConfig, err := clientcmd.BuildConfigFromFlags("", path.Join(o.HubKubeconfigDir, clientcert.KubeconfigFile))
+	hubClientConfig, err := clientcmd.BuildConfigFromFlags("", path.Join(o.hubKubeconfigDir(), clientcert.KubeconfigFile))
 	if err != nil {
 		return err
 	}
@@ -254,23 +588,22 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 		return err
 	}
 
-	hubKubeInformerFactory := informers.NewSharedInformerFactoryWithOptions(
-		hubKubeClient,
-		10*time.Minute,
-		informers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
-			listOptions.LabelSelector = fmt.Sprintf("%s=%s", clientcert.ClusterNameLabel, o.ClusterName)
-		}),
-	)
+	// only watch the csrs of the managed cluster, and only while a csr created by the agent or an addon is pending,
+	// the certificates are rotated rarely, so the csr informer is idle most of the time
+	hubKubeInformerFactory := helpers.NewLazyCSRInformerFactory(hubKubeClient, 10*time.Minute, o.clusterCSRListOptions)
+	// create a kube informer factory for the managed cluster namespace on the hub, which watches the addon
+	// registration configuration and the hub CA bundle
+	namespacedHubKubeInformerFactory := informers.NewSharedInformerFactoryWithOptions(
+		hubKubeClient, 10*time.Minute, informers.WithNamespace(o.ClusterName))
 	addOnInformerFactory := addoninformers.NewSharedInformerFactoryWithOptions(
 		addOnClient, 10*time.Minute, addoninformers.WithNamespace(o.ClusterName))
 	// create a cluster informer factory with name field selector because we just need to handle the current spoke cluster
+	hubClusterListOptions := func(listOptions *metav1.ListOptions) {
+		listOptions.FieldSelector = fields.OneTermEqualSelector("metadata.name", o.ClusterName).String()
+	}
 	hubClusterInformerFactory := clusterv1informers.NewSharedInformerFactoryWithOptions(
-		hubClusterClient,
-		10*time.Minute,
-		clusterv1informers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
-			listOptions.FieldSelector = fields.OneTermEqualSelector("metadata.name", o.ClusterName).String()
-		}),
-	)
+		hubClusterClient, 10*time.Minute, clusterv1informers.WithTweakListOptions(hubClusterListOptions))
+	installHubClusterInformerTransforms(hubClusterInformerFactory, hubClusterListOptions)
 
 	controllerContext.EventRecorder.Event("HubClientConfigReady", "Client config for hub is ready.")
 
@@ -281,129 +614,276 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 		return err
 	}
 
-	// create another ClientCertForHubController for client certificate rotation
-	controllerName := fmt.Sprintf("ClientCertController@cluster:%s", o.ClusterName)
-	clientCertForHubController, err := managedcluster.NewClientCertForHubController(
-		o.ClusterName, o.AgentName, o.ComponentNamespace, o.HubKubeconfigSecret,
-		kubeconfigData,
-		namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
-		hubKubeInformerFactory.Certificates(),
-		managementKubeClient,
-		hubKubeClient,
-		controllerContext.EventRecorder,
-		controllerName,
-	)
-	if err != nil {
-		return err
+	var publisher *cloudevents.MQTTClient
+	if len(o.CloudEventsBrokerAddress) > 0 {
+		// publish the heartbeats and the status of the spoke cluster to the broker, the hub consumes them from it
+		tlsConfig, err := cloudevents.NewMQTTTLSConfig(o.CloudEventsCAFile, o.CloudEventsClientCertFile, o.CloudEventsClientKeyFile)
+		if err != nil {
+			return newTerminationError(TerminationReasonInvalidOptions, err)
+		}
+		publisher = cloudevents.NewMQTTClient(cloudevents.MQTTConfig{
+			BrokerAddress: o.CloudEventsBrokerAddress,
+			ClientID:      fmt.Sprintf("%s-%s", o.ClusterName, o.AgentName),
+			TLSConfig:     tlsConfig,
+		})
+		defer publisher.Close()
 	}
 
-	// create ManagedClusterJoiningController to reconcile instances of ManagedCluster on the managed cluster
-	managedClusterJoiningController := managedcluster.NewManagedClusterJoiningController(
-		o.ClusterName,
-		hubClusterClient,
-		hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
-		controllerContext.EventRecorder,
-	)
+	var spokeClusterClient clusterv1client.Interface
+	var spokeClusterInformerFactory clusterv1informers.SharedInformerFactory
+	if !o.ClaimsOnly {
+		spokeClusterClient, err = clusterv1client.NewForConfig(spokeClientConfig)
+		if err != nil {
+			return err
+		}
+		spokeClusterInformerFactory = clusterv1informers.NewSharedInformerFactory(spokeClusterClient, 10*time.Minute)
+	}
 
-	// create ManagedClusterLeaseController to keep the spoke cluster heartbeat
-	managedClusterLeaseController := managedcluster.NewManagedClusterLeaseController(
-		o.ClusterName,
-		hubKubeClient,
-		hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
-		controllerContext.EventRecorder,
-	)
+	var reverseTunnelKubeInformerFactory informers.SharedInformerFactory
+	if o.BootstrapReverseTunnel {
+		// only watch the secrets in the namespace of the reverse tunnel agent on the managed cluster
+		reverseTunnelKubeInformerFactory = informers.NewSharedInformerFactoryWithOptions(
+			spokeKubeClient, 10*time.Minute, informers.WithNamespace(o.ReverseTunnelSecretNamespace))
+	}
 
-	// create NewManagedClusterStatusController to update the spoke cluster status
-	managedClusterHealthCheckController := managedcluster.NewManagedClusterStatusController(
-		o.ClusterName,
-		hubClusterClient,
-		hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
-		spokeKubeClient.Discovery(),
-		spokeKubeInformerFactory.Core().V1().Nodes(),
-		o.ClusterHealthCheckPeriod,
-		controllerContext.EventRecorder,
-	)
-	spokeClusterClient, err := clusterv1client.NewForConfig(spokeClientConfig)
+	// the lease of the cluster and the health summary of the addons on it are written by one renewer in batched passes
+	leaseRenewer := lease.NewRenewer(hubKubeClient, controllerContext.EventRecorder)
+
+	reregistered := make(chan struct{})
+	var reregisterOnce sync.Once
+	hubCABundleChanged := make(chan struct{})
+	var hubCABundleChangedOnce sync.Once
+	controllers, err := (&spokeControllerContext{
+		SpokeAgentOptions:                       o,
+		recorder:                                controllerContext.EventRecorder,
+		clusterNameConfigured:                   clusterNameConfigured,
+		managementKubeClient:                    managementKubeClient,
+		spokeClientConfig:                       spokeClientConfig,
+		spokeKubeClient:                         spokeKubeClient,
+		spokeClusterClient:                      spokeClusterClient,
+		hubClientConfig:                         hubClientConfig,
+		hubKubeClient:                           hubKubeClient,
+		hubClusterClient:                        hubClusterClient,
+		addOnClient:                             addOnClient,
+		kubeconfigData:                          kubeconfigData,
+		publisher:                               publisher,
+		leaseRenewer:                            leaseRenewer,
+		spokeKubeInformerFactory:                spokeKubeInformerFactory,
+		spokeClusterInformerFactory:             spokeClusterInformerFactory,
+		namespacedManagementKubeInformerFactory: namespacedManagementKubeInformerFactory,
+		hubKubeInformerFactory:                  hubKubeInformerFactory,
+		namespacedHubKubeInformerFactory:        namespacedHubKubeInformerFactory,
+		hubClusterInformerFactory:               hubClusterInformerFactory,
+		addOnInformerFactory:                    addOnInformerFactory,
+		reverseTunnelKubeInformerFactory:        reverseTunnelKubeInformerFactory,
+		reregistered:                            func() { reregisterOnce.Do(func() { close(reregistered) }) },
+		hubCABundleChanged:                      func() { hubCABundleChangedOnce.Do(func() { close(hubCABundleChanged) }) },
+	}).newControllers()
 	if err != nil {
 		return err
 	}
-	spokeClusterInformerFactory := clusterv1informers.NewSharedInformerFactory(spokeClusterClient, 10*time.Minute)
-
-	var managedClusterClaimController factory.Controller
-	if features.DefaultSpokeMutableFeatureGate.Enabled(features.ClusterClaim) {
-		// create managedClusterClaimController to sync cluster claims
-		managedClusterClaimController = managedcluster.NewManagedClusterClaimController(
-			o.ClusterName,
-			o.MaxCustomClusterClaims,
-			hubClusterClient,
-			hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
-			spokeClusterInformerFactory.Cluster().V1alpha1().ClusterClaims(),
-			controllerContext.EventRecorder,
-		)
+
+	// the factories only start the informers requested by the created controllers
+	go hubKubeInformerFactory.Start(ctx.Done())
+	go hubClusterInformerFactory.Start(ctx.Done())
+	go namespacedManagementKubeInformerFactory.Start(ctx.Done())
+	go namespacedHubKubeInformerFactory.Start(ctx.Done())
+	go addOnInformerFactory.Start(ctx.Done())
+	if !o.ClaimsOnly {
+		go spokeKubeInformerFactory.Start(ctx.Done())
+		go spokeClusterInformerFactory.Start(ctx.Done())
+	}
+	if reverseTunnelKubeInformerFactory != nil {
+		go reverseTunnelKubeInformerFactory.Start(ctx.Done())
 	}
 
-	var addOnLeaseController factory.Controller
-	var addOnRegistrationController factory.Controller
-	if features.DefaultSpokeMutableFeatureGate.Enabled(features.AddonManagement) {
-		addOnLeaseController = addon.NewManagedClusterAddOnLeaseController(
-			o.ClusterName,
-			addOnClient,
-			addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns(),
-			hubKubeClient.CoordinationV1(),
-			spokeKubeClient.CoordinationV1(),
-			AddOnLeaseControllerSyncInterval, //TODO: this interval time should be allowed to change from outside
-			controllerContext.EventRecorder,
-		)
+	// the renewer writes the annotations of the leases even if no lease is renewed by the agent
+	leaseRenewer.Start(ctx)
 
-		addOnRegistrationController = addon.NewAddOnRegistrationController(
-			o.ClusterName,
-			o.AgentName,
-			kubeconfigData,
-			// TODO(zhujian7): By now, we only support all addon agents running on the managed cluster.
-			// In the future we need to maintain the hub cluster kubeconfig secret on the **management**
-			// cluster when there is an appropriate way to deploy addon agents on the management cluster.
-			spokeKubeClient,
-			hubKubeInformerFactory.Certificates(),
-			addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns(),
-			hubKubeClient,
-			controllerContext.EventRecorder,
+	for _, controller := range controllers {
+		go health.RunController(ctx, controller, 1)
+	}
+
+	select {
+	case <-reregistered:
+		return newTerminationError(TerminationReasonReregistration,
+			fmt.Errorf("hub credentials are discarded, the agent is restarting to re-run bootstrap"))
+	case <-hubCABundleChanged:
+		return newTerminationError(TerminationReasonHubCABundleChanged,
+			fmt.Errorf("the CA of the hub kubeconfig is updated, the agent is restarting to trust the new hub CA bundle"))
+	case <-configFileChanged:
+		return errConfigFileChanged
+	case <-watchedFileChanged:
+		return watchedFileChangedError(changedWatchedFile)
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// bootstrap runs the credential controller with the bootstrap kubeconfig until the hub kubeconfig is ready, to deal
+// with scenario #1 and #4, and records the identity of the managed cluster on the hub kubeconfig secret created by
+// it. It returns the error of restarting once the agent is restarting in the meantime.
+//
+// The controller is only run if there is no valid hub kubeconfig. If it always runs, it will be started and then
+// stopped immediately in scenario #2 and #3, which results in an error message in log: 'Observed a panic: timeout
+// waiting for informer cache'.
+func (o *SpokeAgentOptions) bootstrap(ctx context.Context, bootstrapClientConfig *rest.Config,
+	bootstrapCredentialDigest string, bootstrapKubeClient kubernetes.Interface,
+	bootstrapClusterClient clusterv1client.Interface, managementKubeClient, spokeKubeClient kubernetes.Interface,
+	namespacedManagementKubeInformerFactory informers.SharedInformerFactory, recorder events.Recorder,
+	restarting func() error) error {
+	logger := klog.FromContext(ctx)
+
+	// create a ClientCertForHubController for spoke agent bootstrap
+	bootstrapInformerFactory := helpers.NewFilteredCSRInformerFactory(bootstrapKubeClient, 10*time.Minute, o.clusterCSRListOptions)
+
+	bootstrapCtx, stopBootstrap := context.WithCancel(ctx)
+
+	controllerName := fmt.Sprintf("BootstrapClientCertController@cluster:%s", o.ClusterName)
+	var clientCertForHubController factory.Controller
+	var err error
+	switch {
+	case len(o.VaultAddress) > 0:
+		clientCertForHubController, err = o.newVaultClientCertController(
+			bootstrapClientConfig,
+			namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			managementKubeClient,
+			recorder,
+			controllerName,
+		)
+	case len(o.SpiffeEndpointSocket) > 0:
+		clientCertForHubController, err = o.newSVIDCredentialController(
+			bootstrapClientConfig,
+			namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			managementKubeClient,
+			recorder,
+			controllerName,
 		)
+	case o.RegistrationDriver == helpers.TokenRegistrationDriver:
+		clientCertForHubController, err = o.newTokenForHubController(
+			bootstrapClientConfig,
+			namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			managementKubeClient,
+			bootstrapKubeClient,
+			recorder,
+			controllerName,
+		)
+	case o.RegistrationDriver == helpers.AzureRegistrationDriver || o.RegistrationDriver == helpers.GCPRegistrationDriver:
+		clientCertForHubController, err = managedcluster.NewExecCredentialForHubController(
+			o.ClusterName, o.AgentName,
+			o.ComponentNamespace, o.HubKubeconfigSecret,
+			bootstrapClientConfig,
+			o.execConfig(),
+			namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			managementKubeClient.CoreV1(),
+			recorder,
+			controllerName,
+		)
+	case o.RegistrationDriver == helpers.AWSIAMRegistrationDriver:
+		clientCertForHubController, err = o.newAWSIAMForHubController(
+			bootstrapClientConfig,
+			namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			managementKubeClient,
+			bootstrapClusterClient,
+			recorder,
+			controllerName,
+		)
+	default:
+		clientCertForHubController, err = sdk.NewCredentialController(
+			sdk.Identity{ClusterName: o.ClusterName, AgentName: o.AgentName},
+			o.RegistrationSignerName,
+			o.ComponentNamespace, o.HubKubeconfigSecret,
+			bootstrapClientConfig,
+			// store the secret in the cluster where the agent pod runs
+			namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			bootstrapInformerFactory.Certificates(),
+			managementKubeClient,
+			bootstrapKubeClient,
+			recorder,
+			controllerName,
+			o.clientCertOptions()...,
+		)
+	}
+	if err != nil {
+		stopBootstrap()
+		return err
 	}
 
-	go hubKubeInformerFactory.Start(ctx.Done())
-	go hubClusterInformerFactory.Start(ctx.Done())
-	go spokeKubeInformerFactory.Start(ctx.Done())
-	go namespacedManagementKubeInformerFactory.Start(ctx.Done())
-	go spokeClusterInformerFactory.Start(ctx.Done())
-	go addOnInformerFactory.Start(ctx.Done())
+	go bootstrapInformerFactory.Start(bootstrapCtx.Done())
+	go namespacedManagementKubeInformerFactory.Start(bootstrapCtx.Done())
+
+	go health.RunController(bootstrapCtx, clientCertForHubController, 1)
 
-	go clientCertForHubController.Run(ctx, 1)
-	go managedClusterJoiningController.Run(ctx, 1)
-	go managedClusterLeaseController.Run(ctx, 1)
-	go managedClusterHealthCheckController.Run(ctx, 1)
-	if features.DefaultSpokeMutableFeatureGate.Enabled(features.ClusterClaim) {
-		go managedClusterClaimController.Run(ctx, 1)
+	// the bootstrap kubeconfig assembled from the bootstrap credential directory is re-assembled by restarting
+	// the agent once the files are changed
+	bootstrapCredentialChanged := make(chan struct{})
+	if len(o.BootstrapCredentialDir) > 0 {
+		var changeOnce sync.Once
+		bootstrapCredentialController := bootstrapcredential.NewBootstrapCredentialController(
+			o.BootstrapCredentialDir, bootstrapCredentialDigest,
+			func() { changeOnce.Do(func() { close(bootstrapCredentialChanged) }) },
+			recorder,
+		)
+		go health.RunController(bootstrapCtx, bootstrapCredentialController, 1)
 	}
-	if features.DefaultSpokeMutableFeatureGate.Enabled(features.AddonManagement) {
-		go addOnLeaseController.Run(ctx, 1)
-		go addOnRegistrationController.Run(ctx, 1)
+
+	// wait for the hub client config is ready.
+	logger.Info("Waiting for hub client config and managed cluster to be ready", helpers.LogKeyCluster, o.ClusterName)
+	if err := wait.PollImmediateInfinite(1*time.Second, func() (bool, error) {
+		select {
+		case <-bootstrapCredentialChanged:
+			return false, newTerminationError(TerminationReasonBootstrapCredentialChanged,
+				fmt.Errorf("the bootstrap credential is changed, the agent is restarting to re-assemble the bootstrap kubeconfig"))
+		default:
+		}
+		if err := restarting(); err != nil {
+			return false, err
+		}
+		return o.hasValidHubClientConfig()
+	}); err != nil {
+		// TODO need run the bootstrap CSR forever to re-establish the client-cert if it is ever lost.
+		stopBootstrap()
+		return err
 	}
 
-	<-ctx.Done()
-	return nil
+	// stop the clientCertForHubController for bootstrap once the hub client config is ready
+	stopBootstrap()
+
+	// record the identity of the managed cluster on the hub kubeconfig secret created by the bootstrap
+	if spokeKubeClient == nil {
+		return nil
+	}
+	return managedcluster.EnsureClusterIdentity(ctx, managementKubeClient.CoreV1(), spokeKubeClient.CoreV1(),
+		o.ComponentNamespace, o.HubKubeconfigSecret, o.hubKubeconfigDir(), recorder)
 }
 
 // AddFlags registers flags for Agent
 func (o *SpokeAgentOptions) AddFlags(fs *pflag.FlagSet) {
+	o.flags = fs
 	features.DefaultSpokeMutableFeatureGate.AddFlag(fs)
 	fs.StringVar(&o.ClusterName, "cluster-name", o.ClusterName,
 		"If non-empty, will use as cluster name instead of generated random name.")
+	fs.BoolVar(&o.AgentNameFromServiceAccount, "agent-name-from-service-account", o.AgentNameFromServiceAccount,
+		"Derive the agent name from the namespace and the uid of the service account the agent runs with instead of "+
+			"generating a random one, so the identity in the client certificate of the agent is stable once the hub "+
+			"kubeconfig secret is re-created. The agent keeps the name in the existing hub kubeconfig secret.")
 	fs.StringVar(&o.BootstrapKubeconfig, "bootstrap-kubeconfig", o.BootstrapKubeconfig,
 		"The path of the kubeconfig file for agent bootstrap.")
+	fs.StringVar(&o.BootstrapCredentialDir, "bootstrap-credential-dir", o.BootstrapCredentialDir,
+		"The directory the bootstrap kubeconfig is assembled from instead of bootstrap-kubeconfig, e.g. a secrets-store "+
+			"CSI volume. It holds either a complete kubeconfig in the file kubeconfig, or the hub server in server, the "+
+			"CA bundle in ca.crt, and the token in token or the client certificate in tls.crt and tls.key.")
 	fs.StringVar(&o.HubKubeconfigSecret, "hub-kubeconfig-secret", o.HubKubeconfigSecret,
 		"The name of secret in component namespace storing kubeconfig for hub.")
 	fs.StringVar(&o.HubKubeconfigDir, "hub-kubeconfig-dir", o.HubKubeconfigDir,
 		"The mount path of hub-kubeconfig-secret in the container.")
+	fs.StringVar(&o.HubKubeconfigStorage, "hub-kubeconfig-storage", o.HubKubeconfigStorage,
+		"Where the files of hub-kubeconfig-secret are materialized, one of dir, memory and auto. They are materialized "+
+			"in hub-kubeconfig-dir with dir, in hub-kubeconfig-memory-dir with memory, and in hub-kubeconfig-memory-dir "+
+			"only if hub-kubeconfig-dir is not writable with auto, e.g. on a read-only root filesystem.")
+	fs.StringVar(&o.HubKubeconfigMemoryDir, "hub-kubeconfig-memory-dir", o.HubKubeconfigMemoryDir,
+		"The directory on a memory-backed filesystem the files of hub-kubeconfig-secret are materialized in with the "+
+			"memory or auto hub-kubeconfig-storage.")
 	fs.StringVar(&o.SpokeKubeconfig, "spoke-kubeconfig", o.SpokeKubeconfig,
 		"The path of the kubeconfig file for managed/spoke cluster. If this is not set, will use '--kubeconfig' to build client to connect to the managed cluster.")
 	fs.StringArrayVar(&o.SpokeExternalServerURLs, "spoke-external-server-urls", o.SpokeExternalServerURLs,
@@ -412,180 +892,737 @@ func (o *SpokeAgentOptions) AddFlags(fs *pflag.FlagSet) {
 		"The period to check managed cluster kube-apiserver health")
 	fs.IntVar(&o.MaxCustomClusterClaims, "max-custom-cluster-claims", o.MaxCustomClusterClaims,
 		"The max number of custom cluster claims to expose.")
+	fs.StringVar(&o.RegistrationSignerName, "registration-signer-name", o.RegistrationSignerName,
+		"The signer name of the csrs of the client certificate of the agent, "+certificatesv1.KubeAPIServerClientSignerName+
+			", or "+helpers.CertManagerSignerName+" if the hub signs the client certificates with a cert-manager issuer.")
+	fs.StringSliceVar(&o.AllowedSignerNames, "allowed-signer-names", o.AllowedSignerNames,
+		"The signers the agent is allowed to request certificates from, the csrs of the addons with the other signers "+
+			"are not created. All signers are allowed if it is empty.")
+	fs.StringVar(&o.VaultAddress, "vault-address", o.VaultAddress,
+		"The address of Vault, e.g. https://vault.example.com:8200. The agent requests its client certificate from "+
+			"the Vault PKI role vault-pki-role instead of the csrs on the hub if it is set.")
+	fs.StringVar(&o.VaultCAFile, "vault-ca-file", o.VaultCAFile,
+		"The CA bundle to verify the serving certificate of Vault. The system roots are used if it is empty.")
+	fs.StringVar(&o.VaultAuthPath, "vault-auth-path", o.VaultAuthPath,
+		"The mount path of the Kubernetes auth method in Vault, which the agent logs in with its service account token.")
+	fs.StringVar(&o.VaultAuthRole, "vault-auth-role", o.VaultAuthRole,
+		"The role of the Kubernetes auth method the agent logs in to Vault with.")
+	fs.StringVar(&o.VaultPKIPath, "vault-pki-path", o.VaultPKIPath,
+		"The mount path of the PKI secrets engine in Vault.")
+	fs.StringVar(&o.VaultPKIRole, "vault-pki-role", o.VaultPKIRole,
+		"The role of the PKI secrets engine which signs the client certificate of the agent. The role has to set the "+
+			"organizations of the agent and allow its common name, system:open-cluster-management:<cluster>:<agent>.")
+	fs.StringVar(&o.SpiffeEndpointSocket, "spiffe-endpoint-socket", o.SpiffeEndpointSocket,
+		"The unix socket address of the SPIFFE Workload API, e.g. unix:///run/spire/sockets/agent.sock. The agent "+
+			"uses its X.509 SVID as the client certificate for the hub instead of the csrs on the hub if it is set.")
+	fs.StringVar(&o.SpiffeTrustDomain, "spiffe-trust-domain", o.SpiffeTrustDomain,
+		"The trust domain of the SPIFFE ID of the agent. The SPIFFE IDs in any trust domain are accepted if it is empty.")
+	fs.StringVar(&o.ClientCertKeyType, "client-cert-key-type", o.ClientCertKeyType,
+		"The type of the private keys of the client certificates requested by the agent, "+clientcert.KeyTypeECDSA+
+			" or "+clientcert.KeyTypeRSA+". ECDSA is used if it is empty.")
+	fs.IntVar(&o.ClientCertKeyBitSize, "client-cert-key-bit-size", o.ClientCertKeyBitSize,
+		"The size of the private keys of the client certificates requested by the agent, 256 or 384 for ECDSA, and "+
+			"2048, 3072 or 4096 for RSA. The default size of the key type is used if it is 0.")
+	fs.Float64Var(&o.ClientCertRenewalJitter, "client-cert-renewal-jitter", o.ClientCertRenewalJitter,
+		"The ratio of the certificate lifetime the renewals of the client certificates requested by the agent are "+
+			"spread over, a certificate is renewed between threshold and threshold+jitter of its lifetime remaining. "+
+			"The renewal threshold plus the jitter must be less than 1. A quarter of the renewal threshold is used if it is "+
+			"negative, and 0 turns the jitter off, so the certificates are renewed right at the renewal threshold.")
+	fs.StringVar(&o.RegistrationDriver, "registration-driver", o.RegistrationDriver,
+		"The way the agent authenticates to the hub, "+helpers.CSRRegistrationDriver+" for a client certificate, "+
+			helpers.TokenRegistrationDriver+" for a service account token provisioned by the hub once the cluster is accepted, "+
+			helpers.AWSIAMRegistrationDriver+" for the IAM role aws-iam-role-arn mapped by the EKS hub once the cluster is accepted, or "+
+			helpers.AzureRegistrationDriver+" and "+helpers.GCPRegistrationDriver+" for the workload identity of the agent pod "+
+			"with kubelogin and gke-gcloud-auth-plugin, which the hub admin binds to the roles of the agent.")
+	fs.StringVar(&o.AWSHubClusterName, "aws-hub-cluster-name", o.AWSHubClusterName,
+		"The name of the EKS cluster of the hub, which the tokens of the IAM role are requested for.")
+	fs.StringVar(&o.AWSIAMRoleARN, "aws-iam-role-arn", o.AWSIAMRoleARN,
+		"The ARN of the IAM role of the managed cluster, e.g. arn:aws:iam::123456789012:role/ocm-cluster1. The agent "+
+			"assumes the role with the AWS credentials of its pod and "+managedcluster.AWSIAMAuthenticatorCommand+".")
+	fs.StringVar(&o.AzureServerID, "azure-server-id", o.AzureServerID,
+		"The application id of the Azure AD server application of the AKS hub, which the Azure AD tokens are requested for.")
+	fs.StringVar(&o.RegistrationTransport, "registration-transport", o.RegistrationTransport,
+		"The way the agent registers to the hub, "+helpers.KubeRegistrationTransport+" for the apis of the hub kube-apiserver, or "+
+			helpers.GRPCRegistrationTransport+" for the gRPC Registration service at grpc-server-address, for the hubs which "+
+			"are not Kubernetes clusters. The "+helpers.GRPCRegistrationTransport+" transport requires the feature gate "+
+			string(features.GRPCRegistration)+".")
+	fs.StringVar(&o.GRPCServerAddress, "grpc-server-address", o.GRPCServerAddress,
+		"The address of the gRPC Registration service of the hub, e.g. hub.example.com:8443.")
+	fs.StringVar(&o.GRPCCAFile, "grpc-ca-file", o.GRPCCAFile,
+		"The CA bundle to verify the serving certificate of the gRPC Registration service. The system roots are used if it is empty.")
+	fs.StringVar(&o.GRPCClientCertFile, "grpc-client-cert-file", o.GRPCClientCertFile,
+		"The client certificate the agent authenticates to the gRPC Registration service with.")
+	fs.StringVar(&o.GRPCClientKeyFile, "grpc-client-key-file", o.GRPCClientKeyFile,
+		"The key of the client certificate in grpc-client-cert-file.")
+	fs.StringVar(&o.CloudEventsBrokerAddress, "cloudevents-broker-address", o.CloudEventsBrokerAddress,
+		"The address of the MQTT broker the status and the heartbeats of the cluster are published to as CloudEvents "+
+			"instead of the hub kube-apiserver, e.g. tls://broker.example.com:8883. It requires the feature gate "+
+			string(features.CloudEventsTransport)+".")
+	fs.StringVar(&o.CloudEventsCAFile, "cloudevents-ca-file", o.CloudEventsCAFile,
+		"The CA bundle to verify the serving certificate of the MQTT broker. The system roots are used if it is empty.")
+	fs.StringVar(&o.CloudEventsClientCertFile, "cloudevents-client-cert-file", o.CloudEventsClientCertFile,
+		"The client certificate the agent authenticates to the MQTT broker with, e.g. the tls.crt in hub-kubeconfig-dir. "+
+			"It is reloaded on reconnection.")
+	fs.StringVar(&o.CloudEventsClientKeyFile, "cloudevents-client-key-file", o.CloudEventsClientKeyFile,
+		"The key of the client certificate in cloudevents-client-cert-file.")
+	fs.BoolVar(&o.ClaimsOnly, "claims-only", o.ClaimsOnly,
+		"Register an endpoint which is not a Kubernetes cluster, which reports only the claims in claims and the "+
+			"heartbeats. It requires the feature gate "+string(features.ClaimsOnlyRegistration)+".")
+	fs.StringToStringVar(&o.Claims, "claims", o.Claims,
+		"The claims of the endpoint registered with claims-only, e.g. product.open-cluster-management.io=EdgeGateway.")
+	fs.StringToStringVar(&o.ClaimTemplates, "claim-templates", o.ClaimTemplates,
+		"The cluster claims whose values are templated from the managed cluster with .ServerVersion, .NodeLabel \"key\" "+
+			"and .NamespaceAnnotation \"namespace\" \"key\", e.g. region.open-cluster-management.io={{ .NodeLabel "+
+			"\"topology.kubernetes.io/region\" }}. It requires the feature gate "+string(features.ClusterClaim)+".")
+	fs.BoolVar(&o.DisableAddOnRegistration, "disable-addon-registration", o.DisableAddOnRegistration,
+		"Disable the addon management of the agent, the addon informers and controllers are not started and the "+
+			"addons of the cluster are neither registered nor report their health. The hub does not grant the agent "+
+			"the access to the addons of the cluster then.")
+	fs.BoolVar(&o.WriteBackClusterProperties, "write-back-cluster-properties", o.WriteBackClusterProperties,
+		"Write the cluster claims back as the ClusterProperties of the About API on the managed cluster. It requires "+
+			"the feature gate "+string(features.ClusterProperty)+".")
+	fs.BoolVar(&o.BootstrapReverseTunnel, "bootstrap-reverse-tunnel", o.BootstrapReverseTunnel,
+		"Request the client certificate of the reverse tunnel agent, e.g. the proxy agent of cluster-proxy, along with "+
+			"the registration. It requires the feature gate "+string(features.ReverseTunnelBootstrap)+".")
+	fs.StringVar(&o.ReverseTunnelSignerName, "reverse-tunnel-signer-name", o.ReverseTunnelSignerName,
+		"The signer of the client certificate of the reverse tunnel agent.")
+	fs.StringVar(&o.ReverseTunnelSecretNamespace, "reverse-tunnel-secret-namespace", o.ReverseTunnelSecretNamespace,
+		"The namespace of the secret of the client certificate of the reverse tunnel agent on the managed cluster.")
+	fs.StringVar(&o.ReverseTunnelSecretName, "reverse-tunnel-secret-name", o.ReverseTunnelSecretName,
+		"The name of the secret of the client certificate of the reverse tunnel agent on the managed cluster.")
+	fs.IntVar(&o.MaxConcurrentAddOnRegistrations, "max-concurrent-addon-registrations", o.MaxConcurrentAddOnRegistrations,
+		"The max number of addon registrations in flight, i.e. waiting for their client certificates to be issued. "+
+			"A registration is no longer counted once it waits for 10 minutes. Set it to 0 to disable the limit.")
+	fs.DurationVar(&o.AddOnRegistrationStaggerInterval, "addon-registration-stagger-interval", o.AddOnRegistrationStaggerInterval,
+		"The interval to retry the addon registrations deferred once max-concurrent-addon-registrations is reached.")
+	fs.StringVar(&o.DebugBindAddress, "debug-bind-address", o.DebugBindAddress,
+		"The address to serve the internal state of the controllers on /debug/registration without authentication, "+
+			"e.g. 127.0.0.1:8000. The state is not served if it is empty.")
+	fs.StringVar(&o.SecureBindAddress, "secure-bind-address", o.SecureBindAddress,
+		"The address to serve the metrics on /metrics and the internal state of the controllers on "+
+			"/debug/registration over TLS, e.g. :8443. The requests are authenticated with TokenReviews and authorized "+
+			"with SubjectAccessReviews. They are not served securely if it is empty.")
+	fs.StringVar(&o.SecureServingCertFile, "secure-serving-cert-file", o.SecureServingCertFile,
+		"The serving certificate of secure-bind-address. A self-signed certificate is generated if it is empty.")
+	fs.StringVar(&o.SecureServingKeyFile, "secure-serving-key-file", o.SecureServingKeyFile,
+		"The private key of secure-serving-cert-file.")
+	fs.StringVar(&o.TerminationMessagePath, "termination-message-path", o.TerminationMessagePath,
+		"The file the reason of a fatal error is written to before the agent exits. It is not written if it is empty.")
+	fs.StringSliceVar(&o.TerminateOnChangeFiles, "terminate-on-change", o.TerminateOnChangeFiles,
+		"The files read when the agent starts, e.g. CA bundles and proxy certificates, the agent restarts once any of "+
+			"them is changed, created or removed.")
+	fs.BoolVar(&o.FIPSMode, "fips-mode", o.FIPSMode,
+		"Restrict the crypto to the FIPS-approved algorithms. The bootstrap kubeconfig must verify the hub, and the "+
+			"broker must be a tls:// address. It is always enabled in a binary built with boringcrypto.")
+	fs.StringVar(&o.HealthProbeBindAddress, "health-probe-bind-address", o.HealthProbeBindAddress,
+		"The address to serve the health checks of the controllers on /healthz without authentication, e.g. :8000. "+
+			"The checks are not served if it is empty.")
+	fs.DurationVar(&o.ControllerProgressDeadline, "controller-progress-deadline", o.ControllerProgressDeadline,
+		"The period a controller with pending work is allowed to process no key before its health check fails. "+
+			"The default deadline is used if it is zero.")
+	fs.DurationVar(&o.SlowSyncThreshold, "slow-sync-threshold", o.SlowSyncThreshold,
+		"The duration after which a sync of a controller is logged and counted as slow with the key and the phase "+
+			"it spent most of its time in. The default threshold is used if it is zero.")
+	fs.DurationVar(&o.RetryBaseDelay, "retry-base-delay", o.RetryBaseDelay,
+		"The delay before the first retry of a key failed to sync, it doubles on each failure up to retry-max-delay. "+
+			"The default delay is used if it is zero.")
+	fs.DurationVar(&o.RetryMaxDelay, "retry-max-delay", o.RetryMaxDelay,
+		"The max delay before a retry of a key failed to sync. The default delay is used if it is zero.")
+	fs.Float64Var(&o.RetryQPS, "retry-qps", o.RetryQPS,
+		"The max number of the retries of the failed keys per second of a controller. The default qps is used if it is zero.")
+}
+
+// clusterCSRListOptions selects the csrs of the managed cluster, which are labeled with the cluster name
+func (o *SpokeAgentOptions) clusterCSRListOptions(listOptions *metav1.ListOptions) {
+	listOptions.LabelSelector = fmt.Sprintf("%s=%s", clientcert.ClusterNameLabel, o.ClusterName)
 }
 
-// Validate verifies the inputs.
+// Validate verifies the inputs. The errors of all of the invalid flags are aggregated, see ValidateFields.
 func (o *SpokeAgentOptions) Validate() error {
-	if o.BootstrapKubeconfig == "" {
-		return errors.New("bootstrap-kubeconfig is required")
+	return o.ValidateFields().ToAggregate()
+}
+
+// ValidateFields verifies the inputs and returns an error for each invalid input. The field paths of the errors
+// are named after the flags, so the misconfigured flags are reported precisely.
+func (o *SpokeAgentOptions) ValidateFields() field.ErrorList {
+	errs := field.ErrorList{}
+
+	switch o.RegistrationTransport {
+	case "", helpers.KubeRegistrationTransport:
+		if o.BootstrapKubeconfig == "" && o.BootstrapCredentialDir == "" {
+			errs = append(errs, field.Required(field.NewPath("bootstrap-kubeconfig"), ""))
+		}
+		if o.BootstrapKubeconfig != "" && o.BootstrapCredentialDir != "" {
+			errs = append(errs, field.Forbidden(field.NewPath("bootstrap-credential-dir"), "may not be set with bootstrap-kubeconfig"))
+		}
+	case helpers.GRPCRegistrationTransport:
+		if !features.DefaultSpokeMutableFeatureGate.Enabled(features.GRPCRegistration) {
+			errs = append(errs, field.Forbidden(field.NewPath("registration-transport"),
+				fmt.Sprintf("requires the feature gate %s", features.GRPCRegistration)))
+		}
+		if len(o.GRPCServerAddress) == 0 {
+			errs = append(errs, field.Required(field.NewPath("grpc-server-address"), "required by the grpc registration transport"))
+		}
+		if (len(o.GRPCClientCertFile) == 0) != (len(o.GRPCClientKeyFile) == 0) {
+			errs = append(errs, field.Required(field.NewPath("grpc-client-key-file"), "must be set with grpc-client-cert-file"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(field.NewPath("registration-transport"), o.RegistrationTransport,
+			[]string{helpers.KubeRegistrationTransport, helpers.GRPCRegistrationTransport}))
 	}
 
 	if o.ClusterName == "" {
-		return errors.New("cluster name is empty")
+		errs = append(errs, field.Required(field.NewPath("cluster-name"), ""))
 	}
 
 	if o.AgentName == "" {
-		return errors.New("agent name is empty")
+		errs = append(errs, field.Required(field.NewPath("agent-name"), ""))
+	}
+
+	switch o.HubKubeconfigStorage {
+	case "", HubKubeconfigStorageDir:
+	case HubKubeconfigStorageMemory, HubKubeconfigStorageAuto:
+		if len(o.HubKubeconfigMemoryDir) == 0 {
+			errs = append(errs, field.Required(field.NewPath("hub-kubeconfig-memory-dir"),
+				fmt.Sprintf("required by the %s hub kubeconfig storage", o.HubKubeconfigStorage)))
+		}
+	default:
+		errs = append(errs, field.NotSupported(field.NewPath("hub-kubeconfig-storage"), o.HubKubeconfigStorage,
+			[]string{HubKubeconfigStorageDir, HubKubeconfigStorageMemory, HubKubeconfigStorageAuto}))
 	}
 
 	// if SpokeExternalServerURLs is specified we validate every URL in it, we expect the spoke external server URL is https
-	if len(o.SpokeExternalServerURLs) != 0 {
-		for _, serverURL := range o.SpokeExternalServerURLs {
-			if !helpers.IsValidHTTPSURL(serverURL) {
-				return fmt.Errorf("%q is invalid", serverURL)
+	for i, serverURL := range o.SpokeExternalServerURLs {
+		if !helpers.IsValidHTTPSURL(serverURL) {
+			errs = append(errs, field.Invalid(field.NewPath("spoke-external-server-urls").Index(i), serverURL, "must be a https url"))
+		}
+	}
+
+	if len(o.RegistrationSignerName) > 0 && !helpers.IsClusterClientSignerName(o.RegistrationSignerName) {
+		errs = append(errs, field.NotSupported(field.NewPath("registration-signer-name"), o.RegistrationSignerName,
+			[]string{certificatesv1.KubeAPIServerClientSignerName, helpers.CertManagerSignerName}))
+	}
+
+	if len(o.AllowedSignerNames) > 0 {
+		for i, signerName := range o.AllowedSignerNames {
+			for _, msg := range validation.IsQualifiedName(signerName) {
+				errs = append(errs, field.Invalid(field.NewPath("allowed-signer-names").Index(i), signerName, msg))
 			}
 		}
+		allowed := sets.NewString(o.AllowedSignerNames...)
+		if len(o.RegistrationSignerName) > 0 && !allowed.Has(o.RegistrationSignerName) {
+			errs = append(errs, field.NotSupported(field.NewPath("registration-signer-name"), o.RegistrationSignerName,
+				o.AllowedSignerNames))
+		}
+		if o.BootstrapReverseTunnel && !allowed.Has(o.ReverseTunnelSignerName) {
+			errs = append(errs, field.NotSupported(field.NewPath("reverse-tunnel-signer-name"), o.ReverseTunnelSignerName,
+				o.AllowedSignerNames))
+		}
+	}
+
+	if len(o.VaultAddress) > 0 {
+		if !helpers.IsValidHTTPSURL(o.VaultAddress) {
+			errs = append(errs, field.Invalid(field.NewPath("vault-address"), o.VaultAddress, "must be a https url"))
+		}
+		if len(o.VaultAuthRole) == 0 {
+			errs = append(errs, field.Required(field.NewPath("vault-auth-role"), "required by vault-address"))
+		}
+		if len(o.VaultPKIRole) == 0 {
+			errs = append(errs, field.Required(field.NewPath("vault-pki-role"), "required by vault-address"))
+		}
+	}
+
+	if err := clientcert.ValidateKey(o.ClientCertKeyType, 0); err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("client-cert-key-type"), o.ClientCertKeyType, err.Error()))
+	} else if err := clientcert.ValidateKey(o.ClientCertKeyType, o.ClientCertKeyBitSize); err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("client-cert-key-bit-size"), o.ClientCertKeyBitSize, err.Error()))
+	}
+	// the agent requests its own certificates with the default renewal threshold
+	if err := clientcert.ValidateRenewal(0, o.ClientCertRenewalJitter); err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("client-cert-renewal-jitter"), o.ClientCertRenewalJitter,
+			err.Error()))
+	}
+
+	if len(o.SpiffeEndpointSocket) > 0 {
+		if !spiffe.IsValidSocketAddress(o.SpiffeEndpointSocket) {
+			errs = append(errs, field.Invalid(field.NewPath("spiffe-endpoint-socket"), o.SpiffeEndpointSocket,
+				"must be a unix socket address, e.g. unix:///run/spire/sockets/agent.sock"))
+		}
+		if len(o.VaultAddress) > 0 {
+			errs = append(errs, field.Forbidden(field.NewPath("spiffe-endpoint-socket"), "may not be set with vault-address"))
+		}
+		// the agent name is in the SPIFFE ID of the SVID
+		if o.AgentNameFromServiceAccount {
+			errs = append(errs, field.Forbidden(field.NewPath("agent-name-from-service-account"),
+				"may not be set with spiffe-endpoint-socket"))
+		}
+	}
+
+	switch o.RegistrationDriver {
+	case "", helpers.CSRRegistrationDriver:
+	case helpers.TokenRegistrationDriver, helpers.AWSIAMRegistrationDriver, helpers.AzureRegistrationDriver, helpers.GCPRegistrationDriver:
+		if len(o.VaultAddress) > 0 {
+			errs = append(errs, field.Forbidden(field.NewPath("registration-driver"),
+				fmt.Sprintf("may not be %s with vault-address", o.RegistrationDriver)))
+		}
+		if len(o.SpiffeEndpointSocket) > 0 {
+			errs = append(errs, field.Forbidden(field.NewPath("registration-driver"),
+				fmt.Sprintf("may not be %s with spiffe-endpoint-socket", o.RegistrationDriver)))
+		}
+	default:
+		errs = append(errs, field.NotSupported(field.NewPath("registration-driver"), o.RegistrationDriver,
+			[]string{helpers.CSRRegistrationDriver, helpers.TokenRegistrationDriver, helpers.AWSIAMRegistrationDriver,
+				helpers.AzureRegistrationDriver, helpers.GCPRegistrationDriver}))
+	}
+	if o.RegistrationDriver == helpers.AzureRegistrationDriver && len(o.AzureServerID) == 0 {
+		errs = append(errs, field.Required(field.NewPath("azure-server-id"), "required by the azure registration driver"))
+	}
+	if o.RegistrationDriver == helpers.AWSIAMRegistrationDriver {
+		if len(o.AWSHubClusterName) == 0 {
+			errs = append(errs, field.Required(field.NewPath("aws-hub-cluster-name"), "required by the awsiam registration driver"))
+		}
+		if !helpers.IsValidAWSIAMRoleARN(o.AWSIAMRoleARN) {
+			errs = append(errs, field.Invalid(field.NewPath("aws-iam-role-arn"), o.AWSIAMRoleARN,
+				"must be the ARN of an IAM role, e.g. arn:aws:iam::123456789012:role/ocm-cluster1"))
+		}
+	}
+
+	if len(o.CloudEventsBrokerAddress) > 0 {
+		if !features.DefaultSpokeMutableFeatureGate.Enabled(features.CloudEventsTransport) {
+			errs = append(errs, field.Forbidden(field.NewPath("cloudevents-broker-address"),
+				fmt.Sprintf("requires the feature gate %s", features.CloudEventsTransport)))
+		}
+		if !cloudevents.IsValidBrokerAddress(o.CloudEventsBrokerAddress) {
+			errs = append(errs, field.Invalid(field.NewPath("cloudevents-broker-address"), o.CloudEventsBrokerAddress,
+				"must be a tls:// or tcp:// address with a port, e.g. tls://broker.example.com:8883"))
+		}
+		if o.RegistrationTransport == helpers.GRPCRegistrationTransport {
+			errs = append(errs, field.Forbidden(field.NewPath("cloudevents-broker-address"),
+				"may not be set with the grpc registration transport"))
+		}
+		if (len(o.CloudEventsClientCertFile) == 0) != (len(o.CloudEventsClientKeyFile) == 0) {
+			errs = append(errs, field.Required(field.NewPath("cloudevents-client-key-file"), "must be set with cloudevents-client-cert-file"))
+		}
+	}
+
+	if o.ClaimsOnly {
+		if !features.DefaultSpokeMutableFeatureGate.Enabled(features.ClaimsOnlyRegistration) {
+			errs = append(errs, field.Forbidden(field.NewPath("claims-only"),
+				fmt.Sprintf("requires the feature gate %s", features.ClaimsOnlyRegistration)))
+		}
+		if len(o.SpokeKubeconfig) > 0 {
+			errs = append(errs, field.Forbidden(field.NewPath("spoke-kubeconfig"), "may not be set with claims-only"))
+		}
+		if len(o.SpokeExternalServerURLs) > 0 {
+			errs = append(errs, field.Forbidden(field.NewPath("spoke-external-server-urls"), "may not be set with claims-only"))
+		}
+		if len(o.CloudEventsBrokerAddress) > 0 {
+			errs = append(errs, field.Forbidden(field.NewPath("cloudevents-broker-address"), "may not be set with claims-only"))
+		}
+	}
+	if len(o.Claims) > 0 && !o.ClaimsOnly {
+		errs = append(errs, field.Forbidden(field.NewPath("claims"), "may only be set with claims-only"))
+	}
+	for _, name := range sets.StringKeySet(o.Claims).List() {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			errs = append(errs, field.Invalid(field.NewPath("claims").Key(name), name, msg))
+		}
+	}
+	if len(o.ClaimTemplates) > 0 {
+		if o.ClaimsOnly {
+			errs = append(errs, field.Forbidden(field.NewPath("claim-templates"), "may not be set with claims-only"))
+		}
+		if !features.DefaultSpokeMutableFeatureGate.Enabled(features.ClusterClaim) {
+			errs = append(errs, field.Forbidden(field.NewPath("claim-templates"),
+				fmt.Sprintf("requires the feature gate %s", features.ClusterClaim)))
+		}
+	}
+	reservedClaimNames := sets.NewString(clusterv1alpha1.ReservedClusterClaimNames[:]...)
+	for _, name := range sets.StringKeySet(o.ClaimTemplates).List() {
+		fldPath := field.NewPath("claim-templates").Key(name)
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			errs = append(errs, field.Invalid(fldPath, name, msg))
+		}
+		if reservedClaimNames.Has(name) {
+			errs = append(errs, field.Forbidden(fldPath, "may not be a reserved cluster claim"))
+		}
+		if _, err := managedcluster.ParseClaimTemplate(name, o.ClaimTemplates[name]); err != nil {
+			errs = append(errs, field.Invalid(fldPath, o.ClaimTemplates[name], err.Error()))
+		}
+	}
+
+	if o.WriteBackClusterProperties && !features.DefaultSpokeMutableFeatureGate.Enabled(features.ClusterProperty) {
+		errs = append(errs, field.Forbidden(field.NewPath("write-back-cluster-properties"),
+			fmt.Sprintf("requires the feature gate %s", features.ClusterProperty)))
+	}
+
+	if o.BootstrapReverseTunnel {
+		if !features.DefaultSpokeMutableFeatureGate.Enabled(features.ReverseTunnelBootstrap) {
+			errs = append(errs, field.Forbidden(field.NewPath("bootstrap-reverse-tunnel"),
+				fmt.Sprintf("requires the feature gate %s", features.ReverseTunnelBootstrap)))
+		}
+		if o.ClaimsOnly {
+			errs = append(errs, field.Forbidden(field.NewPath("bootstrap-reverse-tunnel"), "may not be set with claims-only"))
+		}
+		if o.RegistrationTransport == helpers.GRPCRegistrationTransport {
+			errs = append(errs, field.Forbidden(field.NewPath("bootstrap-reverse-tunnel"),
+				"may not be set with the grpc registration transport"))
+		}
+		for _, msg := range validation.IsQualifiedName(o.ReverseTunnelSignerName) {
+			errs = append(errs, field.Invalid(field.NewPath("reverse-tunnel-signer-name"), o.ReverseTunnelSignerName, msg))
+		}
+		// the reverse tunnel credential must not authenticate to the hub kube-apiserver
+		if helpers.IsClusterClientSignerName(o.ReverseTunnelSignerName) {
+			errs = append(errs, field.Invalid(field.NewPath("reverse-tunnel-signer-name"), o.ReverseTunnelSignerName,
+				"must not be a signer of the client certificates of the hub"))
+		}
+		for _, msg := range validation.IsDNS1123Label(o.ReverseTunnelSecretNamespace) {
+			errs = append(errs, field.Invalid(field.NewPath("reverse-tunnel-secret-namespace"), o.ReverseTunnelSecretNamespace, msg))
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(o.ReverseTunnelSecretName) {
+			errs = append(errs, field.Invalid(field.NewPath("reverse-tunnel-secret-name"), o.ReverseTunnelSecretName, msg))
+		}
+	}
+
+	for i, file := range o.TerminateOnChangeFiles {
+		if len(file) == 0 {
+			errs = append(errs, field.Invalid(field.NewPath("terminate-on-change").Index(i), file, "must not be empty"))
+		}
+	}
+
+	if !o.FIPSMode && fips.BuiltIn() {
+		errs = append(errs, field.Invalid(field.NewPath("fips-mode"), o.FIPSMode,
+			"may not be disabled in a binary built with boringcrypto"))
+	}
+	if o.FIPSMode && strings.HasPrefix(o.CloudEventsBrokerAddress, "tcp://") {
+		errs = append(errs, field.Forbidden(field.NewPath("cloudevents-broker-address"),
+			"must be a tls:// address in the FIPS mode"))
 	}
 
 	if o.ClusterHealthCheckPeriod <= 0 {
-		return errors.New("cluster healthcheck period must greater than zero")
+		errs = append(errs, field.Invalid(field.NewPath("cluster-healthcheck-period"), o.ClusterHealthCheckPeriod.String(),
+			"must be greater than zero"))
 	}
 
-	return nil
+	if o.MaxConcurrentAddOnRegistrations > 0 && o.AddOnRegistrationStaggerInterval <= 0 {
+		errs = append(errs, field.Invalid(field.NewPath("addon-registration-stagger-interval"), o.AddOnRegistrationStaggerInterval.String(),
+			"must be greater than zero when max-concurrent-addon-registrations is set"))
+	}
+
+	errs = append(errs, secureserving.ValidateFields(o.SecureBindAddress, o.SecureServingCertFile, o.SecureServingKeyFile)...)
+
+	if o.ControllerProgressDeadline < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("controller-progress-deadline"), o.ControllerProgressDeadline.String(),
+			"must not be negative"))
+	}
+	if o.SlowSyncThreshold < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("slow-sync-threshold"), o.SlowSyncThreshold.String(),
+			"must not be negative"))
+	}
+	if o.RetryBaseDelay < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("retry-base-delay"), o.RetryBaseDelay.String(),
+			"must not be negative"))
+	}
+	if o.RetryMaxDelay < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("retry-max-delay"), o.RetryMaxDelay.String(),
+			"must not be negative"))
+	}
+	if o.RetryBaseDelay > 0 && o.RetryMaxDelay > 0 && o.RetryMaxDelay < o.RetryBaseDelay {
+		errs = append(errs, field.Invalid(field.NewPath("retry-max-delay"), o.RetryMaxDelay.String(),
+			"must not be less than retry-base-delay"))
+	}
+	if o.RetryQPS < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("retry-qps"), o.RetryQPS,
+			"must not be negative"))
+	}
+
+	return errs
 }
 
-// Complete fills in missing values.
-func (o *SpokeAgentOptions) Complete(coreV1Client corev1client.CoreV1Interface, ctx context.Context, recorder events.Recorder) error {
-	// get component namespace of spoke agent
-	nsBytes, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+// newVaultClientCertController returns a controller which requests the client certificate of the agent from Vault,
+// and stores it with a kubeconfig connecting to the hub with hubClientConfig.
+func (o *SpokeAgentOptions) newVaultClientCertController(hubClientConfig *rest.Config,
+	secretInformer corev1informers.SecretInformer, managementKubeClient kubernetes.Interface,
+	recorder events.Recorder, controllerName string) (factory.Controller, error) {
+	kubeconfig := clientcert.BuildKubeconfig(hubClientConfig, clientcert.TLSCertFile, clientcert.TLSKeyFile)
+	kubeconfigData, err := clientcmd.Write(kubeconfig)
 	if err != nil {
-		o.ComponentNamespace = defaultSpokeComponentNamespace
-	} else {
-		o.ComponentNamespace = string(nsBytes)
+		return nil, err
 	}
 
-	// dump data in hub kubeconfig secret into file system if it exists
-	err = managedcluster.DumpSecret(coreV1Client, o.ComponentNamespace, o.HubKubeconfigSecret,
-		o.HubKubeconfigDir, ctx, recorder)
+	issuer, err := clientcert.NewVaultIssuer(clientcert.VaultConfig{
+		Address:  o.VaultAddress,
+		CAFile:   o.VaultCAFile,
+		AuthPath: o.VaultAuthPath,
+		AuthRole: o.VaultAuthRole,
+		PKIPath:  o.VaultPKIPath,
+		PKIRole:  o.VaultPKIRole,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return managedcluster.NewClientCertForHubController(
+		o.ClusterName, o.AgentName, "", o.ComponentNamespace, o.HubKubeconfigSecret,
+		kubeconfigData,
+		secretInformer,
+		nil,
+		managementKubeClient,
+		nil,
+		recorder,
+		controllerName,
+		append([]clientcert.Option{clientcert.WithCertificateIssuer(issuer)}, o.clientCertOptions()...)...,
+	)
+}
+
+// newSVIDCredentialController returns a controller which keeps the X.509 SVID of the agent fetched from the SPIFFE
+// Workload API, with a kubeconfig connecting to the hub with hubClientConfig, in the hub kubeconfig secret.
+func (o *SpokeAgentOptions) newSVIDCredentialController(hubClientConfig *rest.Config,
+	secretInformer corev1informers.SecretInformer, managementKubeClient kubernetes.Interface,
+	recorder events.Recorder, controllerName string) (factory.Controller, error) {
+	kubeconfig := clientcert.BuildKubeconfig(hubClientConfig, clientcert.TLSCertFile, clientcert.TLSKeyFile)
+	kubeconfigData, err := clientcmd.Write(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return spiffe.NewSVIDCredentialController(
+		o.SpiffeEndpointSocket, o.SpiffeTrustDomain,
+		o.ClusterName, o.AgentName,
+		o.ComponentNamespace, o.HubKubeconfigSecret,
+		kubeconfigData,
+		secretInformer,
+		managementKubeClient.CoreV1(),
+		recorder,
+		controllerName,
+	), nil
+}
+
+// newTokenForHubController returns a controller which keeps the token of the registration agent read with
+// hubKubeClient, with a kubeconfig connecting to the hub with hubClientConfig, in the hub kubeconfig secret.
+func (o *SpokeAgentOptions) newTokenForHubController(hubClientConfig *rest.Config,
+	secretInformer corev1informers.SecretInformer, managementKubeClient, hubKubeClient kubernetes.Interface,
+	recorder events.Recorder, controllerName string) (factory.Controller, error) {
+	kubeconfigData, err := clientcmd.Write(managedcluster.BuildTokenKubeconfig(hubClientConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	return managedcluster.NewTokenForHubController(
+		o.ClusterName, o.AgentName,
+		o.ComponentNamespace, o.HubKubeconfigSecret,
+		kubeconfigData,
+		secretInformer,
+		managementKubeClient.CoreV1(),
+		hubKubeClient.CoreV1(),
+		recorder,
+		controllerName,
+	), nil
+}
+
+// newAWSIAMForHubController returns a controller which saves the kubeconfig connecting to the hub with
+// hubClientConfig and authenticating with the IAM role of the agent in the hub kubeconfig secret, once the role is
+// mapped by the hub. The managed cluster is read with hubClusterClient.
+func (o *SpokeAgentOptions) newAWSIAMForHubController(hubClientConfig *rest.Config,
+	secretInformer corev1informers.SecretInformer, managementKubeClient kubernetes.Interface,
+	hubClusterClient clusterv1client.Interface, recorder events.Recorder, controllerName string) (factory.Controller, error) {
+	kubeconfigData, err := clientcmd.Write(managedcluster.BuildAWSIAMKubeconfig(hubClientConfig,
+		o.AWSHubClusterName, o.AWSIAMRoleARN, o.AgentName))
+	if err != nil {
+		return nil, err
+	}
+
+	return managedcluster.NewAWSIAMForHubController(
+		o.ClusterName, o.AgentName, o.AWSIAMRoleARN,
+		o.ComponentNamespace, o.HubKubeconfigSecret,
+		kubeconfigData,
+		secretInformer,
+		managementKubeClient.CoreV1(),
+		hubClusterClient,
+		recorder,
+		controllerName,
+	), nil
+}
+
+// execConfig returns the exec credential plugin config of the azure and gcp registration drivers
+func (o *SpokeAgentOptions) execConfig() *clientcmdapi.ExecConfig {
+	if o.RegistrationDriver == helpers.AzureRegistrationDriver {
+		return clientcert.NewAzureWorkloadIdentityExecConfig(o.AzureServerID)
+	}
+	return clientcert.NewGCPWorkloadIdentityExecConfig()
+}
+
+// runGRPCAgent registers the cluster over the gRPC Registration service of the hub, and heartbeats and reports the
+// status of the cluster once the hub accepts it. It blocks until the context is done.
+func (o *SpokeAgentOptions) runGRPCAgent(ctx context.Context, statusCollector managedcluster.StatusCollector,
+	spokeKubeInformerFactory informers.SharedInformerFactory, spokeClusterCABundle []byte, recorder events.Recorder) error {
+	tlsConfig, err := grpcregistration.NewClientTLSConfig(o.GRPCCAFile, o.GRPCClientCertFile, o.GRPCClientKeyFile)
+	if err != nil {
+		return newTerminationError(TerminationReasonInvalidOptions, err)
+	}
+	client, err := grpcregistration.Dial(ctx, o.GRPCServerAddress, credentials.NewTLS(tlsConfig))
 	if err != nil {
 		return err
 	}
 
-	// load or generate cluster/agent names
-	o.ClusterName, o.AgentName = o.getOrGenerateClusterAgentNames()
+	clientConfigs := []clusterv1.ClientConfig{}
+	for _, serverURL := range o.SpokeExternalServerURLs {
+		clientConfigs = append(clientConfigs, clusterv1.ClientConfig{URL: serverURL, CABundle: spokeClusterCABundle})
+	}
+	if err := grpcagent.WaitForAcceptance(ctx, client, &grpcregistration.RegisterRequest{
+		ClusterName:   o.ClusterName,
+		AgentName:     o.AgentName,
+		ClientConfigs: grpcregistration.NewClientConfigs(clientConfigs),
+		Annotations:   o.managedClusterAnnotations(),
+	}, recorder); err != nil {
+		// the context is done before the cluster is accepted
+		return nil
+	}
+
+	heartbeatController := grpcagent.NewHeartbeatController(o.ClusterName, o.AgentName, client, recorder)
+	statusController := grpcagent.NewStatusController(o.ClusterName, o.AgentName, client, statusCollector,
+		o.clusterHealthCheckPeriod, recorder)
 
+	if spokeKubeInformerFactory != nil {
+		go spokeKubeInformerFactory.Start(ctx.Done())
+	}
+	go health.RunController(ctx, heartbeatController, 1)
+	go health.RunController(ctx, statusController, 1)
+
+	<-ctx.Done()
 	return nil
 }
 
-// generateClusterName generates a name for spoke cluster
-func generateClusterName() string {
-	return string(uuid.NewUUID())
+// newStatusCollector returns the collector of the status of the managed cluster reported to the hub, only the
+// configured claims are reported in the claims-only mode
+func (o *SpokeAgentOptions) newStatusCollector(spokeKubeClient kubernetes.Interface,
+	spokeKubeInformerFactory informers.SharedInformerFactory) managedcluster.StatusCollector {
+	if o.ClaimsOnly {
+		return managedcluster.NewClaimsStatusCollector(o.Claims)
+	}
+	return managedcluster.NewClusterStatusCollector(spokeKubeClient.Discovery(), spokeKubeInformerFactory.Core().V1().Nodes().Lister())
 }
 
-// generateAgentName generates a random name for spoke cluster agent
-func generateAgentName() string {
-	return utilrand.String(spokeAgentNameLength)
+// managedClusterAnnotations returns the annotations of the managed cluster created by the agent, the hub
+// provisions the token of the registration agent for the clusters annotated with the token registration driver,
+// and maps the IAM role in the annotation for the clusters annotated with the aws iam registration driver. The hub
+// does not bind the addon role of the agent for the clusters annotated with the addon registration disabled.
+func (o *SpokeAgentOptions) managedClusterAnnotations() map[string]string {
+	annotations := map[string]string{}
+	switch o.RegistrationDriver {
+	case helpers.TokenRegistrationDriver, helpers.AzureRegistrationDriver, helpers.GCPRegistrationDriver:
+		annotations[helpers.RegistrationDriverAnnotation] = o.RegistrationDriver
+	case helpers.AWSIAMRegistrationDriver:
+		annotations[helpers.RegistrationDriverAnnotation] = helpers.AWSIAMRegistrationDriver
+		annotations[helpers.AWSIAMRoleARNAnnotation] = o.AWSIAMRoleARN
+	}
+	if o.DisableAddOnRegistration {
+		annotations[helpers.AddOnRegistrationDisabledAnnotation] = "true"
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+	return annotations
 }
 
-// hasValidHubClientConfig returns ture if all the conditions below are met:
-//   1. KubeconfigFile exists;
-//   2. TLSKeyFile exists;
-//   3. TLSCertFile exists;
-//   4. Certificate in TLSCertFile is issued for the current cluster/agent;
-//   5. Certificate in TLSCertFile is not expired;
-// Normally, KubeconfigFile/TLSKeyFile/TLSCertFile will be created once the bootstrap process
-// completes. Changing the name of the cluster will make the existing hub kubeconfig invalid,
-// because certificate in TLSCertFile is issued to a specific cluster/agent.
-func (o *SpokeAgentOptions) hasValidHubClientConfig() (bool, error) {
-	kubeconfigPath := path.Join(o.HubKubeconfigDir, clientcert.KubeconfigFile)
-	if _, err := os.Stat(kubeconfigPath); os.IsNotExist(err) {
-		klog.V(4).Infof("Kubeconfig file %q not found", kubeconfigPath)
-		return false, nil
+// Complete fills in missing values.
+func (o *SpokeAgentOptions) Complete(coreV1Client corev1client.CoreV1Interface, ctx context.Context, recorder events.Recorder) error {
+	// get component namespace of spoke agent
+	o.ComponentNamespace = componentNamespace()
+
+	// dump data in hub kubeconfig secret into file system if it exists
+	err := managedcluster.DumpSecret(coreV1Client, o.ComponentNamespace, o.HubKubeconfigSecret,
+		o.hubKubeconfigDir(), ctx, recorder)
+	if err != nil {
+		return err
 	}
 
-	keyPath := path.Join(o.HubKubeconfigDir, clientcert.TLSKeyFile)
-	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
-		klog.V(4).Infof("TLS key file %q not found", keyPath)
-		return false, nil
+	// the names of the cluster and the agent are in the SPIFFE ID of the SVID
+	if len(o.SpiffeEndpointSocket) > 0 {
+		svids, err := spiffe.FetchX509SVIDs(ctx, o.SpiffeEndpointSocket)
+		if err != nil {
+			return err
+		}
+		_, o.ClusterName, o.AgentName, err = spiffe.SelectSVID(svids, o.SpiffeTrustDomain, o.ClusterName)
+		return err
 	}
 
-	certPath := path.Join(o.HubKubeconfigDir, clientcert.TLSCertFile)
-	certData, err := ioutil.ReadFile(path.Clean(certPath))
-	if err != nil {
-		klog.V(4).Infof("Unable to load TLS cert file %q", certPath)
-		return false, nil
+	// the agent name derived from the service account is used instead of a random one
+	agentName := ""
+	if o.AgentNameFromServiceAccount {
+		agentName, err = deriveAgentName(serviceAccountTokenFile)
+		if err != nil {
+			return err
+		}
 	}
 
-	// check if the tls certificate is issued for the current cluster/agent
-	clusterName, agentName, err := managedcluster.GetClusterAgentNamesFromCertificate(certData)
+	// load or generate cluster/agent names
+	identity := sdk.ResolveIdentityWithAgentName(o.ClusterName, o.hubKubeconfigDir(), agentName)
+	o.ClusterName, o.AgentName = identity.ClusterName, identity.AgentName
+
+	return nil
+}
+
+// componentNamespace returns the namespace the agent is running in, it defaults to the
+// open-cluster-management-agent if the agent is not running in a pod.
+func componentNamespace() string {
+	nsBytes, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
 	if err != nil {
-		return false, nil
+		return defaultSpokeComponentNamespace
 	}
-	if clusterName != o.ClusterName || agentName != o.AgentName {
-		klog.V(4).Infof("Certificate in file %q is issued for agent %q instead of %q",
-			certPath, fmt.Sprintf("%s:%s", clusterName, agentName),
-			fmt.Sprintf("%s:%s", o.ClusterName, o.AgentName))
-		return false, nil
+	return string(nsBytes)
+}
+
+// loadBootstrapClientConfig loads the bootstrap kubeconfig, or assembles it from the files in BootstrapCredentialDir.
+// The digest of the files is returned as well, so their changes are detected.
+func (o *SpokeAgentOptions) loadBootstrapClientConfig() (*rest.Config, string, error) {
+	if len(o.BootstrapCredentialDir) == 0 {
+		config, err := clientcmd.BuildConfigFromFlags("", o.BootstrapKubeconfig)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to load bootstrap kubeconfig from file %q: %w", o.BootstrapKubeconfig, err)
+		}
+		return config, "", o.validateBootstrapClientConfig(config)
 	}
 
-	return clientcert.IsCertificateValid(certData, nil)
+	// the digest is taken first, so a change while the config is assembled is detected
+	digest, err := bootstrapcredential.Digest(o.BootstrapCredentialDir)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read bootstrap credential from directory %q: %w", o.BootstrapCredentialDir, err)
+	}
+	config, err := bootstrapcredential.LoadClientConfig(o.BootstrapCredentialDir)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to assemble bootstrap kubeconfig from directory %q: %w", o.BootstrapCredentialDir, err)
+	}
+	return config, digest, o.validateBootstrapClientConfig(config)
 }
 
-// getOrGenerateClusterAgentNames returns cluster name and agent name.
-// Rules for picking up cluster name:
-//   1. Use cluster name from input arguments if 'cluster-name' is specified;
-//   2. Parse cluster name from the common name of the certification subject if the certification exists;
-//   3. Fallback to cluster name in the mounted secret if it exists;
-//   4. TODO: Read cluster name from openshift struct if the agent is running in an openshift cluster;
-//   5. Generate a random cluster name then;
-
-// Rules for picking up agent name:
-//   1. Parse agent name from the common name of the certification subject if the certification exists;
-//   2. Fallback to agent name in the mounted secret if it exists;
-//   3. Generate a random agent name then;
-func (o *SpokeAgentOptions) getOrGenerateClusterAgentNames() (string, string) {
-	// try to load cluster/agent name from tls certification
-	var clusterNameInCert, agentNameInCert string
-	certPath := path.Join(o.HubKubeconfigDir, clientcert.TLSCertFile)
-	certData, certErr := ioutil.ReadFile(path.Clean(certPath))
-	if certErr == nil {
-		clusterNameInCert, agentNameInCert, _ = managedcluster.GetClusterAgentNamesFromCertificate(certData)
-	}
-
-	clusterName := o.ClusterName
-	// if cluster name is not specified with input argument, try to load it from file
-	if clusterName == "" {
-		// TODO, read cluster name from openshift struct if the spoke agent is running in an openshift cluster
-
-		// and then load the cluster name from the mounted secret
-		clusterNameFilePath := path.Join(o.HubKubeconfigDir, clientcert.ClusterNameFile)
-		clusterNameBytes, err := ioutil.ReadFile(path.Clean(clusterNameFilePath))
-		switch {
-		case len(clusterNameInCert) > 0:
-			// use cluster name loaded from the tls certification
-			clusterName = clusterNameInCert
-			if clusterNameInCert != string(clusterNameBytes) {
-				klog.Warningf("Use cluster name %q in certification instead of %q in the mounted secret", clusterNameInCert, string(clusterNameBytes))
-			}
-		case err == nil:
-			// use cluster name load from the mounted secret
-			clusterName = string(clusterNameBytes)
-		default:
-			// generate random cluster name
-			clusterName = generateClusterName()
-		}
+// validateBootstrapClientConfig checks the bootstrap client config is allowed in the FIPS mode, it must verify the
+// serving certificate of the hub.
+func (o *SpokeAgentOptions) validateBootstrapClientConfig(config *rest.Config) error {
+	if fips.Enabled() && config.Insecure {
+		return fmt.Errorf("the bootstrap kubeconfig which skips the tls verification is not allowed in the FIPS mode")
 	}
+	return nil
+}
 
-	// try to load agent name from the mounted secret
-	agentNameFilePath := path.Join(o.HubKubeconfigDir, clientcert.AgentNameFile)
-	agentNameBytes, err := ioutil.ReadFile(path.Clean(agentNameFilePath))
-	var agentName string
-	switch {
-	case len(agentNameInCert) > 0:
-		// use agent name loaded from the tls certification
-		agentName = agentNameInCert
-		if agentNameInCert != string(agentNameBytes) {
-			klog.Warningf("Use agent name %q in certification instead of %q in the mounted secret", agentNameInCert, string(agentNameBytes))
-		}
-	case err == nil:
-		// use agent name loaded from the mounted secret
-		agentName = string(agentNameBytes)
-	default:
-		// generate random agent name
-		agentName = generateAgentName()
+// hasValidHubClientConfig returns ture if there is a valid hub kubeconfig for the current cluster/agent in
+// the hub kubeconfig directory, see sdk.HasValidHubKubeconfig, or managedcluster.HasValidHubTokenKubeconfig for the token
+// registration driver, and managedcluster.HasValidHubExecKubeconfig for the drivers with exec credential plugins.
+func (o *SpokeAgentOptions) hasValidHubClientConfig() (bool, error) {
+	switch o.RegistrationDriver {
+	case helpers.TokenRegistrationDriver:
+		return managedcluster.HasValidHubTokenKubeconfig(o.hubKubeconfigDir(), o.ClusterName, o.AgentName)
+	case helpers.AWSIAMRegistrationDriver, helpers.AzureRegistrationDriver, helpers.GCPRegistrationDriver:
+		return managedcluster.HasValidHubExecKubeconfig(o.hubKubeconfigDir(), o.ClusterName, o.AgentName)
 	}
+	return sdk.HasValidHubKubeconfig(o.hubKubeconfigDir(), sdk.Identity{ClusterName: o.ClusterName, AgentName: o.AgentName})
+}
 
-	return clusterName, agentName
+// getOrGenerateClusterAgentNames returns cluster name and agent name, see sdk.ResolveIdentity for the rules of
+// picking up them.
+func (o *SpokeAgentOptions) getOrGenerateClusterAgentNames() (string, string) {
+	identity := sdk.ResolveIdentity(o.ClusterName, o.hubKubeconfigDir())
+	return identity.ClusterName, identity.AgentName
 }
 
 // getSpokeClusterCABundle returns the spoke cluster Kubernetes client CA data when SpokeExternalServerURLs is specified
@@ -615,3 +1652,20 @@ func (o *SpokeAgentOptions) spokeKubeConfig(controllerContext *controllercmd.Con
 	}
 	return config, nil
 }
+
+// signerChecker returns the checker of the signers of the addon registrations, it is nil if all signers are allowed
+func (o *SpokeAgentOptions) signerChecker() clientcert.SignerChecker {
+	if len(o.AllowedSignerNames) == 0 {
+		return nil
+	}
+	return clientcert.NewSignerAllowlist(o.AllowedSignerNames...)
+}
+
+// clientCertOptions returns the options of the client certificate controllers of the agent, the reverse tunnel and
+// the addons
+func (o *SpokeAgentOptions) clientCertOptions() []clientcert.Option {
+	return []clientcert.Option{
+		clientcert.WithKey(o.ClientCertKeyType, o.ClientCertKeyBitSize),
+		clientcert.WithRenewalJitter(o.ClientCertRenewalJitter),
+	}
+}
@@ -6,11 +6,13 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 
 	corev1 "k8s.io/api/core/v1"
@@ -149,17 +151,17 @@ func TestValidate(t *testing.T) {
 		{
 			name:        "no bootstrap kubeconfig",
 			options:     &SpokeAgentOptions{},
-			expectedErr: "bootstrap-kubeconfig is required",
+			expectedErr: "[bootstrap-kubeconfig: Required value, cluster-name: Required value, agent-name: Required value, cluster-healthcheck-period: Invalid value: \"0s\": must be greater than zero]",
 		},
 		{
 			name:        "no cluster name",
 			options:     &SpokeAgentOptions{BootstrapKubeconfig: "/spoke/bootstrap/kubeconfig"},
-			expectedErr: "cluster name is empty",
+			expectedErr: "[cluster-name: Required value, agent-name: Required value, cluster-healthcheck-period: Invalid value: \"0s\": must be greater than zero]",
 		},
 		{
 			name:        "no agent name",
 			options:     &SpokeAgentOptions{BootstrapKubeconfig: "/spoke/bootstrap/kubeconfig", ClusterName: "testcluster"},
-			expectedErr: "agent name is empty",
+			expectedErr: "[agent-name: Required value, cluster-healthcheck-period: Invalid value: \"0s\": must be greater than zero]",
 		},
 		{
 			name: "invalid external server URLs",
@@ -169,7 +171,7 @@ func TestValidate(t *testing.T) {
 				AgentName:               "testagent",
 				SpokeExternalServerURLs: []string{"https://127.0.0.1:64433", "http://127.0.0.1:8080"},
 			},
-			expectedErr: "\"http://127.0.0.1:8080\" is invalid",
+			expectedErr: "[spoke-external-server-urls[1]: Invalid value: \"http://127.0.0.1:8080\": must be a https url, cluster-healthcheck-period: Invalid value: \"0s\": must be greater than zero]",
 		},
 		{
 			name: "invalid cluster healthcheck period",
@@ -179,7 +181,351 @@ func TestValidate(t *testing.T) {
 				AgentName:                "testagent",
 				ClusterHealthCheckPeriod: 0,
 			},
-			expectedErr: "cluster healthcheck period must greater than zero",
+			expectedErr: "cluster-healthcheck-period: Invalid value: \"0s\": must be greater than zero",
+		},
+		{
+			name: "invalid addon registration stagger interval",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:             "/spoke/bootstrap/kubeconfig",
+				ClusterName:                     "testcluster",
+				AgentName:                       "testagent",
+				ClusterHealthCheckPeriod:        1 * time.Minute,
+				MaxConcurrentAddOnRegistrations: 10,
+			},
+			expectedErr: "addon-registration-stagger-interval: Invalid value: \"0s\": must be greater than zero when max-concurrent-addon-registrations is set",
+		},
+		{
+			name: "invalid retry limits",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				RetryBaseDelay:           time.Second,
+				RetryMaxDelay:            time.Millisecond,
+				RetryQPS:                 -1,
+			},
+			expectedErr: "[retry-max-delay: Invalid value: \"1ms\": must not be less than retry-base-delay, retry-qps: Invalid value: -1: must not be negative]",
+		},
+		{
+			name: "unsupported registration signer name",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				RegistrationSignerName:   "example.com/signer",
+			},
+			expectedErr: "registration-signer-name: Unsupported value: \"example.com/signer\": supported values: \"kubernetes.io/kube-apiserver-client\", \"open-cluster-management.io/cert-manager\"",
+		},
+		{
+			name: "unsupported client cert key type",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				ClientCertKeyType:        "DSA",
+			},
+			expectedErr: "client-cert-key-type: Invalid value: \"DSA\": the key type \"DSA\" is not supported, it must be ECDSA or RSA",
+		},
+		{
+			name: "unsupported client cert key bit size",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				ClientCertKeyType:        "RSA",
+				ClientCertKeyBitSize:     1024,
+			},
+			expectedErr: "client-cert-key-bit-size: Invalid value: 1024: the key bit size 1024 is not supported by RSA, it must be one of [2048 3072 4096]",
+		},
+		{
+			name: "client cert renewal jitter up to the lifetime",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				ClientCertRenewalJitter:  0.8,
+			},
+			expectedErr: "client-cert-renewal-jitter: Invalid value: 0.8: the renewal threshold 0.2 plus the renewal jitter 0.8 must be less than 1",
+		},
+		{
+			name: "invalid vault options",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				VaultAddress:             "http://vault:8200",
+				VaultAuthRole:            "agent",
+			},
+			expectedErr: "[vault-address: Invalid value: \"http://vault:8200\": must be a https url, vault-pki-role: Required value: required by vault-address]",
+		},
+		{
+			name: "invalid spiffe endpoint socket",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				SpiffeEndpointSocket:     "/run/spire/sockets/agent.sock",
+			},
+			expectedErr: "spiffe-endpoint-socket: Invalid value: \"/run/spire/sockets/agent.sock\": must be a unix socket address, e.g. unix:///run/spire/sockets/agent.sock",
+		},
+		{
+			name: "spiffe endpoint socket with vault",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				VaultAddress:             "https://vault:8200",
+				VaultAuthRole:            "agent",
+				VaultPKIRole:             "cluster",
+				SpiffeEndpointSocket:     "unix:///run/spire/sockets/agent.sock",
+			},
+			expectedErr: "spiffe-endpoint-socket: Forbidden: may not be set with vault-address",
+		},
+		{
+			name: "agent name from service account with spiffe",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:         "/spoke/bootstrap/kubeconfig",
+				ClusterName:                 "testcluster",
+				AgentName:                   "testagent",
+				ClusterHealthCheckPeriod:    1 * time.Minute,
+				SpiffeEndpointSocket:        "unix:///run/spire/sockets/agent.sock",
+				AgentNameFromServiceAccount: true,
+			},
+			expectedErr: "agent-name-from-service-account: Forbidden: may not be set with spiffe-endpoint-socket",
+		},
+		{
+			name: "unsupported registration driver",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				RegistrationDriver:       "oidc",
+			},
+			expectedErr: "registration-driver: Unsupported value: \"oidc\": supported values: \"csr\", \"token\", \"awsiam\", \"azure\", \"gcp\"",
+		},
+		{
+			name: "token registration driver with spiffe",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				SpiffeEndpointSocket:     "unix:///run/spire/sockets/agent.sock",
+				RegistrationDriver:       "token",
+			},
+			expectedErr: "registration-driver: Forbidden: may not be token with spiffe-endpoint-socket",
+		},
+		{
+			name: "azure registration driver without server id",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				RegistrationDriver:       "azure",
+			},
+			expectedErr: "azure-server-id: Required value: required by the azure registration driver",
+		},
+		{
+			name: "aws iam registration driver without role",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				RegistrationDriver:       "awsiam",
+				AWSIAMRoleARN:            "arn:aws:iam::123456789012:user/ocm",
+			},
+			expectedErr: "[aws-hub-cluster-name: Required value: required by the awsiam registration driver, aws-iam-role-arn: Invalid value: \"arn:aws:iam::123456789012:user/ocm\": must be the ARN of an IAM role, e.g. arn:aws:iam::123456789012:role/ocm-cluster1]",
+		},
+		{
+			name: "unsupported registration transport",
+			options: &SpokeAgentOptions{
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				RegistrationTransport:    "http",
+			},
+			expectedErr: "registration-transport: Unsupported value: \"http\": supported values: \"kube\", \"grpc\"",
+		},
+		{
+			name: "grpc registration transport without feature gate",
+			options: &SpokeAgentOptions{
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				RegistrationTransport:    "grpc",
+				GRPCClientCertFile:       "/spoke/grpc/tls.crt",
+			},
+			expectedErr: "[registration-transport: Forbidden: requires the feature gate GRPCRegistration, grpc-server-address: Required value: required by the grpc registration transport, grpc-client-key-file: Required value: must be set with grpc-client-cert-file]",
+		},
+		{
+			name: "secure serving key file not set",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				SecureBindAddress:        ":8443",
+				SecureServingCertFile:    "/serving-cert/tls.crt",
+			},
+			expectedErr: "secure-serving-key-file: Required value: must be set with secure-serving-cert-file",
+		},
+		{
+			name: "cloudevents broker without feature gate",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				CloudEventsBrokerAddress: "mqtt://broker.example.com:8883",
+			},
+			expectedErr: "[cloudevents-broker-address: Forbidden: requires the feature gate CloudEventsTransport, cloudevents-broker-address: Invalid value: \"mqtt://broker.example.com:8883\": must be a tls:// or tcp:// address with a port, e.g. tls://broker.example.com:8883]",
+		},
+		{
+			name: "plaintext cloudevents broker in fips mode",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				CloudEventsBrokerAddress: "tcp://broker.example.com:1883",
+				FIPSMode:                 true,
+			},
+			expectedErr: "[cloudevents-broker-address: Forbidden: requires the feature gate CloudEventsTransport, " +
+				"cloudevents-broker-address: Forbidden: must be a tls:// address in the FIPS mode]",
+		},
+		{
+			name: "cluster property write back without feature gate",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:        "/spoke/bootstrap/kubeconfig",
+				ClusterName:                "testcluster",
+				AgentName:                  "testagent",
+				ClusterHealthCheckPeriod:   1 * time.Minute,
+				WriteBackClusterProperties: true,
+			},
+			expectedErr: "write-back-cluster-properties: Forbidden: requires the feature gate ClusterProperty",
+		},
+		{
+			name: "bootstrap credential dir with bootstrap kubeconfig",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				BootstrapCredentialDir:   "/spoke/bootstrap-credential",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+			},
+			expectedErr: "bootstrap-credential-dir: Forbidden: may not be set with bootstrap-kubeconfig",
+		},
+		{
+			name: "registration signer not allowed",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				RegistrationSignerName:   "kubernetes.io/kube-apiserver-client",
+				AllowedSignerNames:       []string{"open-cluster-management.io/cert-manager", "example.com/mtls"},
+			},
+			expectedErr: "registration-signer-name: Unsupported value: \"kubernetes.io/kube-apiserver-client\": " +
+				"supported values: \"open-cluster-management.io/cert-manager\", \"example.com/mtls\"",
+		},
+		{
+			name: "reverse tunnel bootstrap without feature gate",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:          "/spoke/bootstrap/kubeconfig",
+				ClusterName:                  "testcluster",
+				AgentName:                    "testagent",
+				ClusterHealthCheckPeriod:     1 * time.Minute,
+				BootstrapReverseTunnel:       true,
+				ReverseTunnelSignerName:      "kubernetes.io/kube-apiserver-client",
+				ReverseTunnelSecretNamespace: "open-cluster-management-cluster-proxy",
+				ReverseTunnelSecretName:      "proxy-agent-client-cert",
+			},
+			expectedErr: "[bootstrap-reverse-tunnel: Forbidden: requires the feature gate ReverseTunnelBootstrap, " +
+				"reverse-tunnel-signer-name: Invalid value: \"kubernetes.io/kube-apiserver-client\": must not be a signer of the client certificates of the hub]",
+		},
+		{
+			name: "claims-only without feature gate",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				ClaimsOnly:               true,
+				SpokeKubeconfig:          "/spoke/kubeconfig",
+				Claims:                   map[string]string{"Invalid_Claim": "value"},
+			},
+			expectedErr: "[claims-only: Forbidden: requires the feature gate ClaimsOnlyRegistration, spoke-kubeconfig: Forbidden: may not be set with claims-only, claims[Invalid_Claim]: Invalid value: \"Invalid_Claim\": a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')]",
+		},
+		{
+			name: "claims without claims-only",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				Claims:                   map[string]string{"product.open-cluster-management.io": "EdgeGateway"},
+			},
+			expectedErr: "claims: Forbidden: may only be set with claims-only",
+		},
+		{
+			name: "invalid claim templates",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				ClaimTemplates: map[string]string{
+					"id.k8s.io":                         "{{ .NodeLabel \"id\" }}",
+					"region.open-cluster-management.io": "{{ .NodeLabel ",
+				},
+			},
+			expectedErr: "[claim-templates[id.k8s.io]: Forbidden: may not be a reserved cluster claim, " +
+				"claim-templates[region.open-cluster-management.io]: Invalid value: \"{{ .NodeLabel \": " +
+				"template: region.open-cluster-management.io:1: unclosed action]",
+		},
+		{
+			name: "empty terminate-on-change file",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				TerminateOnChangeFiles:   []string{"/etc/pki/ca.crt", ""},
+			},
+			expectedErr: "terminate-on-change[1]: Invalid value: \"\": must not be empty",
+		},
+		{
+			name: "unsupported hub kubeconfig storage",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				HubKubeconfigStorage:     "tmpfs",
+			},
+			expectedErr: "hub-kubeconfig-storage: Unsupported value: \"tmpfs\": supported values: \"dir\", \"memory\", \"auto\"",
+		},
+		{
+			name: "no hub kubeconfig memory dir",
+			options: &SpokeAgentOptions{
+				BootstrapKubeconfig:      "/spoke/bootstrap/kubeconfig",
+				ClusterName:              "testcluster",
+				AgentName:                "testagent",
+				ClusterHealthCheckPeriod: 1 * time.Minute,
+				HubKubeconfigStorage:     HubKubeconfigStorageMemory,
+			},
+			expectedErr: "hub-kubeconfig-memory-dir: Required value: required by the memory hub kubeconfig storage",
 		},
 		{
 			name:        "default completed options",
@@ -388,3 +734,33 @@ func TestGetSpokeClusterCABundle(t *testing.T) {
 		})
 	}
 }
+
+func TestManagedClusterAnnotations(t *testing.T) {
+	cases := []struct {
+		name     string
+		options  *SpokeAgentOptions
+		expected map[string]string
+	}{
+		{
+			name:    "csr registration",
+			options: &SpokeAgentOptions{},
+		},
+		{
+			name:     "token registration",
+			options:  &SpokeAgentOptions{RegistrationDriver: helpers.TokenRegistrationDriver},
+			expected: map[string]string{helpers.RegistrationDriverAnnotation: helpers.TokenRegistrationDriver},
+		},
+		{
+			name:     "addon registration disabled",
+			options:  &SpokeAgentOptions{DisableAddOnRegistration: true},
+			expected: map[string]string{helpers.AddOnRegistrationDisabledAnnotation: "true"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := c.options.managedClusterAnnotations(); !reflect.DeepEqual(actual, c.expected) {
+				t.Errorf("expected annotations %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package spoke
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+)
+
+const (
+	// HubKubeconfigStorageDir materializes the files of the hub kubeconfig secret in HubKubeconfigDir
+	HubKubeconfigStorageDir = "dir"
+	// HubKubeconfigStorageMemory materializes the files of the hub kubeconfig secret in HubKubeconfigMemoryDir on the
+	// memory-backed filesystem, so the agent runs with a read-only root filesystem without a volume for the files
+	HubKubeconfigStorageMemory = "memory"
+	// HubKubeconfigStorageAuto materializes the files of the hub kubeconfig secret in HubKubeconfigDir if it is
+	// writable, and falls back to HubKubeconfigMemoryDir otherwise
+	HubKubeconfigStorageAuto = "auto"
+
+	// defaultHubKubeconfigMemoryDir is on the tmpfs mounted at /dev/shm by the container runtimes, which stays
+	// writable with a read-only root filesystem
+	defaultHubKubeconfigMemoryDir = "/dev/shm/hub-kubeconfig"
+)
+
+// resolveHubKubeconfigDir returns the directory the files of the hub kubeconfig secret are materialized in according
+// to the HubKubeconfigStorage. The directory is created if it does not exist.
+func (o *SpokeAgentOptions) resolveHubKubeconfigDir() (string, error) {
+	switch o.HubKubeconfigStorage {
+	case "", HubKubeconfigStorageDir:
+		return o.HubKubeconfigDir, nil
+	case HubKubeconfigStorageMemory:
+		return o.HubKubeconfigMemoryDir, os.MkdirAll(o.HubKubeconfigMemoryDir, 0700)
+	case HubKubeconfigStorageAuto:
+		if err := checkWritable(o.HubKubeconfigDir); err == nil {
+			return o.HubKubeconfigDir, nil
+		}
+		return o.HubKubeconfigMemoryDir, os.MkdirAll(o.HubKubeconfigMemoryDir, 0700)
+	}
+	return "", fmt.Errorf("unsupported hub kubeconfig storage %q", o.HubKubeconfigStorage)
+}
+
+// hubKubeconfigDir returns the directory the files of the hub kubeconfig secret are materialized in, it is
+// HubKubeconfigDir until the directory is resolved when the agent starts.
+func (o *SpokeAgentOptions) hubKubeconfigDir() string {
+	if len(o.resolvedHubKubeconfigDir) > 0 {
+		return o.resolvedHubKubeconfigDir
+	}
+	return o.HubKubeconfigDir
+}
+
+// materializedHubKubeconfigDir returns the directory the running agent materialized the files of the hub kubeconfig
+// secret in, it is used by the tools running beside the agent, e.g. the diagnostics, which do not resolve the
+// directory themselves.
+func (o *SpokeAgentOptions) materializedHubKubeconfigDir() string {
+	switch o.HubKubeconfigStorage {
+	case HubKubeconfigStorageMemory:
+		return o.HubKubeconfigMemoryDir
+	case HubKubeconfigStorageAuto:
+		if _, err := os.Stat(path.Join(o.HubKubeconfigDir, clientcert.KubeconfigFile)); err != nil {
+			return o.HubKubeconfigMemoryDir
+		}
+	}
+	return o.HubKubeconfigDir
+}
+
+// checkWritable returns an error if a file cannot be created in the directory, e.g. on a read-only filesystem
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(dir, ".writable-")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
@@ -0,0 +1,82 @@
+package spoke
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestResolveHubKubeconfigDir(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "testresolvehubkubeconfigdir")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	hubKubeconfigDir := path.Join(tempDir, "hub-kubeconfig")
+	memoryDir := path.Join(tempDir, "memory")
+	// no dir can be created under a regular file, even by root
+	regularFile := path.Join(tempDir, "file")
+	if err := ioutil.WriteFile(regularFile, []byte("data"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		name             string
+		storage          string
+		hubKubeconfigDir string
+		expectedDir      string
+		expectedErr      bool
+	}{
+		{
+			name:             "default storage",
+			hubKubeconfigDir: hubKubeconfigDir,
+			expectedDir:      hubKubeconfigDir,
+		},
+		{
+			name:             "memory storage",
+			storage:          HubKubeconfigStorageMemory,
+			hubKubeconfigDir: hubKubeconfigDir,
+			expectedDir:      memoryDir,
+		},
+		{
+			name:             "auto storage with a writable dir",
+			storage:          HubKubeconfigStorageAuto,
+			hubKubeconfigDir: hubKubeconfigDir,
+			expectedDir:      hubKubeconfigDir,
+		},
+		{
+			name:             "auto storage falls back to memory",
+			storage:          HubKubeconfigStorageAuto,
+			hubKubeconfigDir: path.Join(regularFile, "hub-kubeconfig"),
+			expectedDir:      memoryDir,
+		},
+		{
+			name:        "unsupported storage",
+			storage:     "tmpfs",
+			expectedErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			options := &SpokeAgentOptions{
+				HubKubeconfigStorage:   c.storage,
+				HubKubeconfigDir:       c.hubKubeconfigDir,
+				HubKubeconfigMemoryDir: memoryDir,
+			}
+			dir, err := options.resolveHubKubeconfigDir()
+			if c.expectedErr != (err != nil) {
+				t.Fatalf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if dir != c.expectedDir {
+				t.Errorf("expected dir %q, but got %q", c.expectedDir, dir)
+			}
+			if dir == memoryDir {
+				if _, err := os.Stat(dir); err != nil {
+					t.Errorf("expected dir %q is created, but got %v", dir, err)
+				}
+			}
+		})
+	}
+}
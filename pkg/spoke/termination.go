@@ -0,0 +1,122 @@
+package spoke
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// defaultTerminationMessagePath is the default path the kubelet reads the termination message of a container from
+	defaultTerminationMessagePath = "/dev/termination-log"
+
+	// maxTerminationMessageLength is the max length of the termination message the kubelet accepts
+	maxTerminationMessageLength = 4096
+
+	// terminatedEventReason is the reason of the event emitted when the agent is terminated by a fatal error
+	terminatedEventReason = "RegistrationAgentTerminated"
+)
+
+// The reasons of the fatal errors the agent is terminated with
+const (
+	TerminationReasonInvalidOptions             = "InvalidOptions"
+	TerminationReasonInvalidBootstrapKubeconfig = "InvalidBootstrapKubeconfig"
+	TerminationReasonUnauthorized               = "Unauthorized"
+	TerminationReasonForbidden                  = "Forbidden"
+	TerminationReasonReregistration             = "Reregistration"
+	TerminationReasonBootstrapCredentialChanged = "BootstrapCredentialChanged"
+	TerminationReasonHubCABundleChanged         = "HubCABundleChanged"
+	TerminationReasonConfigFileChanged          = "ConfigFileChanged"
+	TerminationReasonWatchedFileChanged         = "WatchedFileChanged"
+	TerminationReasonUnknown                    = "Unknown"
+)
+
+// errConfigFileChanged is returned once a setting which is only read when the agent starts is changed in the
+// configuration file, the agent is restarted with the changed settings.
+var errConfigFileChanged = newTerminationError(TerminationReasonConfigFileChanged,
+	fmt.Errorf("the configuration file is changed, the agent is restarting to apply the settings read at startup"))
+
+// watchedFileChangedError is returned once a file in TerminateOnChangeFiles is changed, the agent is restarted to
+// read the changed file.
+func watchedFileChangedError(file string) error {
+	return newTerminationError(TerminationReasonWatchedFileChanged,
+		fmt.Errorf("the watched file %q is changed, the agent is restarting to read it", file))
+}
+
+// terminationError is a fatal error of the agent with the reason why the agent is terminated
+type terminationError struct {
+	reason string
+	err    error
+}
+
+func newTerminationError(reason string, err error) error {
+	return &terminationError{reason: reason, err: err}
+}
+
+func (e *terminationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *terminationError) Unwrap() error {
+	return e.err
+}
+
+// terminationMessage is the structured termination message of the agent
+type terminationMessage struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// terminationReason returns the reason of a fatal error. The errors without a reason are classified by their
+// status, so that the rbac denials of the apiservers are reported as well.
+func terminationReason(err error) string {
+	var terminationErr *terminationError
+	switch {
+	case errors.As(err, &terminationErr):
+		return terminationErr.reason
+	case apierrors.IsUnauthorized(err):
+		return TerminationReasonUnauthorized
+	case apierrors.IsForbidden(err):
+		return TerminationReasonForbidden
+	default:
+		return TerminationReasonUnknown
+	}
+}
+
+// reportTermination writes the reason of a fatal error to the termination message path and emits a final event,
+// so that "kubectl describe pod" explains why the agent is crash-looping.
+func (o *SpokeAgentOptions) reportTermination(recorder events.Recorder, err error) {
+	message := terminationMessage{Reason: terminationReason(err), Message: err.Error()}
+
+	if recorder != nil {
+		recorder.Warningf(terminatedEventReason, "The registration agent is terminated (%s): %s", message.Reason, message.Message)
+	}
+
+	if len(o.TerminationMessagePath) == 0 {
+		return
+	}
+	data, marshalErr := json.Marshal(message)
+	if marshalErr != nil {
+		klog.Errorf("Unable to marshal the termination message: %v", marshalErr)
+		return
+	}
+	// the message is truncated to keep the reason readable
+	for len(data) > maxTerminationMessageLength && len(message.Message) > 0 {
+		cut := len(data) - maxTerminationMessageLength
+		if cut > len(message.Message) {
+			cut = len(message.Message)
+		}
+		message.Message = message.Message[:len(message.Message)-cut]
+		if data, marshalErr = json.Marshal(message); marshalErr != nil {
+			return
+		}
+	}
+	if writeErr := ioutil.WriteFile(o.TerminationMessagePath, data, 0600); writeErr != nil {
+		klog.Errorf("Unable to write the termination message to %q: %v", o.TerminationMessagePath, writeErr)
+	}
+}
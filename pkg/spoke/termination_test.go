@@ -0,0 +1,97 @@
+package spoke
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestReportTermination(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "testtermination")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	cases := []struct {
+		name            string
+		err             error
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name:            "invalid bootstrap kubeconfig",
+			err:             newTerminationError(TerminationReasonInvalidBootstrapKubeconfig, fmt.Errorf("no such file")),
+			expectedReason:  TerminationReasonInvalidBootstrapKubeconfig,
+			expectedMessage: "no such file",
+		},
+		{
+			name: "wrapped termination error",
+			err: fmt.Errorf("unable to start: %w",
+				newTerminationError(TerminationReasonInvalidOptions, fmt.Errorf("cluster-name: Required value"))),
+			expectedReason:  TerminationReasonInvalidOptions,
+			expectedMessage: "unable to start: cluster-name: Required value",
+		},
+		{
+			name: "rbac denial",
+			err: apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "hub-kubeconfig-secret",
+				fmt.Errorf("access denied")),
+			expectedReason:  TerminationReasonForbidden,
+			expectedMessage: "secrets \"hub-kubeconfig-secret\" is forbidden: access denied",
+		},
+		{
+			name:            "watched file changed",
+			err:             watchedFileChangedError("/etc/pki/ca.crt"),
+			expectedReason:  TerminationReasonWatchedFileChanged,
+			expectedMessage: "the watched file \"/etc/pki/ca.crt\" is changed, the agent is restarting to read it",
+		},
+		{
+			name:            "unknown error",
+			err:             fmt.Errorf("boom"),
+			expectedReason:  TerminationReasonUnknown,
+			expectedMessage: "boom",
+		},
+		{
+			name:            "truncated message",
+			err:             errors.New(strings.Repeat("x", 2*maxTerminationMessageLength)),
+			expectedReason:  TerminationReasonUnknown,
+			expectedMessage: strings.Repeat("x", maxTerminationMessageLength-len(`{"reason":"Unknown","message":""}`)),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			options := NewSpokeAgentOptions()
+			options.TerminationMessagePath = path.Join(tempDir, "termination-log")
+
+			options.reportTermination(eventstesting.NewTestingEventRecorder(t), c.err)
+
+			data, err := ioutil.ReadFile(options.TerminationMessagePath)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(data) > maxTerminationMessageLength {
+				t.Errorf("expected the termination message is truncated, but got %d bytes", len(data))
+			}
+			message := &terminationMessage{}
+			if err := json.Unmarshal(data, message); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if message.Reason != c.expectedReason {
+				t.Errorf("expected reason %q, but got %q", c.expectedReason, message.Reason)
+			}
+			if message.Message != c.expectedMessage {
+				t.Errorf("expected message %q, but got %q", c.expectedMessage, message.Message)
+			}
+		})
+	}
+}
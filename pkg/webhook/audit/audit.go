@@ -0,0 +1,73 @@
+// package audit contains the helpers to attach audit annotations to the responses of the admission hooks, so that
+// the audit events of the kube apiserver record how the registration webhook made its decision.
+package audit
+
+import (
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+)
+
+const (
+	// DecisionAnnotation is the audit annotation recording whether the request is allowed or denied
+	DecisionAnnotation = "decision"
+	// PolicyAnnotation is the audit annotation recording the policy rule which made the decision
+	PolicyAnnotation = "policy"
+	// DryRunAnnotation is the audit annotation set on the responses of the dry-run requests
+	DryRunAnnotation = "dry-run"
+)
+
+// The policy rules of the registration webhook
+const (
+	// PolicyNotApplicable means the request is not subject to any policy rule of the webhook
+	PolicyNotApplicable = "not-applicable"
+	// PolicyAllPassed means the request complies with all of the policy rules of the webhook
+	PolicyAllPassed = "all-policies-passed"
+	// PolicyObjectValidation requires the requested object to be valid
+	PolicyObjectValidation = "object-validation"
+	// PolicyClusterNaming requires the name of a new managed cluster to comply with the naming policy
+	PolicyClusterNaming = "cluster-naming"
+	// PolicyHubAcceptsClient requires the permission to accept a managed cluster
+	PolicyHubAcceptsClient = "hub-accepts-client"
+	// PolicySystemTaints requires the permission to change the system taints of a managed cluster
+	PolicySystemTaints = "system-taints"
+	// PolicyClusterSetJoin requires the permission to join a managed cluster to a managed cluster set
+	PolicyClusterSetJoin = "clusterset-join"
+	// PolicyIdentityImmutability requires the identity-bearing fields of a managed cluster to be changed only by force
+	PolicyIdentityImmutability = "identity-immutability"
+	// PolicyClusterSetBindingName requires a managed cluster set binding to have the name of its managed cluster set
+	PolicyClusterSetBindingName = "clustersetbinding-name"
+	// PolicyClusterSetBind requires the permission to bind a managed cluster set to a namespace
+	PolicyClusterSetBind = "clusterset-bind"
+)
+
+// WithPolicy records the policy rule which made the decision on the response
+func WithPolicy(response *admissionv1beta1.AdmissionResponse, policy string) *admissionv1beta1.AdmissionResponse {
+	if response.AuditAnnotations == nil {
+		response.AuditAnnotations = map[string]string{}
+	}
+	response.AuditAnnotations[PolicyAnnotation] = policy
+	return response
+}
+
+// Annotate completes the audit annotations of the response. The policy rule of an allowed response defaults to
+// PolicyAllPassed if it is not recorded, and the dry-run requests are marked.
+func Annotate(request *admissionv1beta1.AdmissionRequest, response *admissionv1beta1.AdmissionResponse) *admissionv1beta1.AdmissionResponse {
+	if response.AuditAnnotations == nil {
+		response.AuditAnnotations = map[string]string{}
+	}
+
+	if response.Allowed {
+		response.AuditAnnotations[DecisionAnnotation] = "allowed"
+	} else {
+		response.AuditAnnotations[DecisionAnnotation] = "denied"
+	}
+
+	if _, ok := response.AuditAnnotations[PolicyAnnotation]; !ok {
+		response.AuditAnnotations[PolicyAnnotation] = PolicyAllPassed
+	}
+
+	if request.DryRun != nil && *request.DryRun {
+		response.AuditAnnotations[DryRunAnnotation] = "true"
+	}
+
+	return response
+}
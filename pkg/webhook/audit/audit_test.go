@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+)
+
+func TestAnnotate(t *testing.T) {
+	dryRun := true
+
+	cases := []struct {
+		name                string
+		request             *admissionv1beta1.AdmissionRequest
+		response            *admissionv1beta1.AdmissionResponse
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:     "allowed request",
+			request:  &admissionv1beta1.AdmissionRequest{},
+			response: &admissionv1beta1.AdmissionResponse{Allowed: true},
+			expectedAnnotations: map[string]string{
+				DecisionAnnotation: "allowed",
+				PolicyAnnotation:   PolicyAllPassed,
+			},
+		},
+		{
+			name:     "denied request",
+			request:  &admissionv1beta1.AdmissionRequest{},
+			response: WithPolicy(&admissionv1beta1.AdmissionResponse{}, PolicyClusterSetJoin),
+			expectedAnnotations: map[string]string{
+				DecisionAnnotation: "denied",
+				PolicyAnnotation:   PolicyClusterSetJoin,
+			},
+		},
+		{
+			name:     "dry-run request",
+			request:  &admissionv1beta1.AdmissionRequest{DryRun: &dryRun},
+			response: WithPolicy(&admissionv1beta1.AdmissionResponse{Allowed: true}, PolicyNotApplicable),
+			expectedAnnotations: map[string]string{
+				DecisionAnnotation: "allowed",
+				PolicyAnnotation:   PolicyNotApplicable,
+				DryRunAnnotation:   "true",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			response := Annotate(c.request, c.response)
+			if !reflect.DeepEqual(response.AuditAnnotations, c.expectedAnnotations) {
+				t.Errorf("expected %v, but got %v", c.expectedAnnotations, response.AuditAnnotations)
+			}
+		})
+	}
+}
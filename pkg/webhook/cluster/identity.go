@@ -0,0 +1,173 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/hub/user"
+)
+
+// ForceIdentityChangeAnnotation must be set to "true" on a ManagedCluster to change its identity-bearing fields.
+// The request user is also required to be allowed to update the managedclusters/identity subresource.
+const ForceIdentityChangeAnnotation = "cluster.open-cluster-management.io/force-identity-change"
+
+// identityChanges returns the descriptions of the identity-bearing fields changed by the update. The fields are:
+//  1. the CA bundles of the client configs, once the ManagedCluster has joined the hub. The agent of the
+//     ManagedCluster is allowed to change them, since it rotates the CA of the managed cluster.
+//  2. the clusterset label, once the ManagedClusterSet has been bound to a namespace. Otherwise the workloads
+//     placed with the binding could be moved to another ManagedClusterSet silently.
+func (a *ManagedClusterValidatingAdmissionHook) identityChanges(userInfo authenticationv1.UserInfo,
+	oldManagedCluster, newManagedCluster *clusterv1.ManagedCluster) ([]string, error) {
+	changes := []string{}
+
+	if meta.IsStatusConditionTrue(oldManagedCluster.Status.Conditions, clusterv1.ManagedClusterConditionJoined) &&
+		!isClusterAgent(userInfo, oldManagedCluster.Name) &&
+		caBundlesChanged(oldManagedCluster.Spec.ManagedClusterClientConfigs, newManagedCluster.Spec.ManagedClusterClientConfigs) {
+		changes = append(changes, "the CA bundles of the client configs of a joined ManagedCluster")
+	}
+
+	originalClusterSet := oldManagedCluster.Labels[helpers.ClusterSetLabel]
+	if len(originalClusterSet) > 0 && originalClusterSet != newManagedCluster.Labels[helpers.ClusterSetLabel] {
+		bound, err := a.isClusterSetBound(originalClusterSet)
+		if err != nil {
+			return nil, err
+		}
+		if bound {
+			changes = append(changes, fmt.Sprintf("the clusterset label of a ManagedCluster in the bound ManagedClusterSet %q", originalClusterSet))
+		}
+	}
+
+	return changes, nil
+}
+
+// allowIdentityChange checks whether the identity-bearing fields of the ManagedCluster are changed, and if so,
+// whether the change is forced by a request user who has been authorized to change them.
+func (a *ManagedClusterValidatingAdmissionHook) allowIdentityChange(userInfo authenticationv1.UserInfo,
+	oldManagedCluster, newManagedCluster *clusterv1.ManagedCluster) *admissionv1beta1.AdmissionResponse {
+	status := &admissionv1beta1.AdmissionResponse{}
+
+	changes, err := a.identityChanges(userInfo, oldManagedCluster, newManagedCluster)
+	if err != nil {
+		status.Allowed = false
+		status.Result = &metav1.Status{
+			Status: metav1.StatusFailure, Code: http.StatusInternalServerError, Reason: metav1.StatusReasonInternalError,
+			Message: err.Error(),
+		}
+		return status
+	}
+	if len(changes) == 0 {
+		status.Allowed = true
+		return status
+	}
+
+	if newManagedCluster.Annotations[ForceIdentityChangeAnnotation] != "true" {
+		status.Allowed = false
+		status.Result = &metav1.Status{
+			Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+			Message: fmt.Sprintf("%s cannot be changed unless the annotation \"%s=true\" is set",
+				strings.Join(changes, " and "), ForceIdentityChangeAnnotation),
+		}
+		return status
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue)
+	for k, v := range userInfo.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   userInfo.Username,
+			UID:    userInfo.UID,
+			Groups: userInfo.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Group:       "register.open-cluster-management.io",
+				Resource:    "managedclusters",
+				Verb:        "update",
+				Subresource: "identity",
+				Name:        newManagedCluster.Name,
+			},
+		},
+	}
+	sar, err = a.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), sar, metav1.CreateOptions{})
+	if err != nil {
+		status.Allowed = false
+		status.Result = &metav1.Status{
+			Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+			Message: err.Error(),
+		}
+		return status
+	}
+
+	if !sar.Status.Allowed {
+		status.Allowed = false
+		status.Result = &metav1.Status{
+			Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+			Message: fmt.Sprintf("user %q cannot force the change of %s", userInfo.Username, strings.Join(changes, " and ")),
+		}
+		return status
+	}
+
+	status.Allowed = true
+	return status
+}
+
+// isClusterSetBound returns true if the ManagedClusterSet is bound to any namespace
+func (a *ManagedClusterValidatingAdmissionHook) isClusterSetBound(clusterSetName string) (bool, error) {
+	if a.clusterSetBindingLister == nil {
+		return false, nil
+	}
+	// an unsynced cache may miss the bindings, so the bound ManagedClusterSet would be considered as unbound
+	if a.clusterSetBindingSynced != nil && !a.clusterSetBindingSynced() {
+		return false, fmt.Errorf("the cache of ManagedClusterSetBindings is not synced yet")
+	}
+
+	bindings, err := a.clusterSetBindingLister.List(labels.Everything())
+	if err != nil {
+		return false, err
+	}
+	for _, binding := range bindings {
+		if binding.Spec.ClusterSet == clusterSetName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isClusterAgent returns true if the user is the registration agent of the ManagedCluster, which authenticates
+// with either a client certificate or the token of the registration agent service account
+func isClusterAgent(userInfo authenticationv1.UserInfo, clusterName string) bool {
+	return sets.NewString(userInfo.Groups...).Has(user.SubjectPrefix+clusterName) ||
+		helpers.IsRegistrationAgentServiceAccount(userInfo.Username, clusterName)
+}
+
+// caBundlesChanged returns true if the CA bundle of any existing client config is changed or the client config
+// is removed
+func caBundlesChanged(oldClientConfigs, newClientConfigs []clusterv1.ClientConfig) bool {
+	caBundles := map[string][]byte{}
+	for _, clientConfig := range newClientConfigs {
+		caBundles[clientConfig.URL] = clientConfig.CABundle
+	}
+
+	for _, clientConfig := range oldClientConfigs {
+		caBundle, ok := caBundles[clientConfig.URL]
+		if !ok || !bytes.Equal(caBundle, clientConfig.CABundle) {
+			return true
+		}
+	}
+	return false
+}
@@ -16,6 +16,8 @@ import (
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	"open-cluster-management.io/registration/pkg/features"
 	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/webhook/audit"
+	"open-cluster-management.io/registration/pkg/webhook/metrics"
 )
 
 var nowFunc = time.Now
@@ -28,7 +30,13 @@ type jsonPatchOperation struct {
 }
 
 // ManagedClusterMutatingAdmissionHook will mutate the creating/updating managedcluster request.
-type ManagedClusterMutatingAdmissionHook struct{}
+type ManagedClusterMutatingAdmissionHook struct {
+	// DefaultClusterSetName is the name of the clusterset which a managedcluster without clusterset label is
+	// added to when the DefaultClusterSet feature is enabled. "default" is used if it is empty.
+	DefaultClusterSetName string
+	// DefaultTaints are added to a managedcluster on its creation unless it already has a taint with the same key.
+	DefaultTaints []clusterv1.Taint
+}
 
 // MutatingResource is called by generic-admission-server on startup to register the returned REST resource through which the
 // webhook is accessed by the kube apiserver.
@@ -43,6 +51,14 @@ func (a *ManagedClusterMutatingAdmissionHook) MutatingResource() (schema.GroupVe
 
 // Admit is called by generic-admission-server when the registered REST resource above is called with an admission request.
 func (a *ManagedClusterMutatingAdmissionHook) Admit(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	start := time.Now()
+	response := audit.Annotate(req, a.admit(req))
+	metrics.ObserveAdmission("managedclustermutators", req, response, start)
+	return response
+}
+
+// admit mutates the creating/updating managedcluster request
+func (a *ManagedClusterMutatingAdmissionHook) admit(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
 	klog.V(4).Infof("mutate %q operation for object %q", req.Operation, req.Object)
 
 	status := &admissionv1beta1.AdmissionResponse{
@@ -52,12 +68,12 @@ func (a *ManagedClusterMutatingAdmissionHook) Admit(req *admissionv1beta1.Admiss
 	// only mutate the request for managedcluster
 	if req.Resource.Group != "cluster.open-cluster-management.io" ||
 		req.Resource.Resource != "managedclusters" {
-		return status
+		return audit.WithPolicy(status, audit.PolicyNotApplicable)
 	}
 
 	// only mutate create and update operation
 	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
-		return status
+		return audit.WithPolicy(status, audit.PolicyNotApplicable)
 	}
 
 	managedCluster := &clusterv1.ManagedCluster{}
@@ -67,7 +83,7 @@ func (a *ManagedClusterMutatingAdmissionHook) Admit(req *admissionv1beta1.Admiss
 			Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
 			Message: err.Error(),
 		}
-		return status
+		return audit.WithPolicy(status, audit.PolicyObjectValidation)
 	}
 
 	var jsonPatches []jsonPatchOperation
@@ -79,6 +95,11 @@ func (a *ManagedClusterMutatingAdmissionHook) Admit(req *admissionv1beta1.Admiss
 	}
 	jsonPatches = append(jsonPatches, taintJsonPatches...)
 
+	// add the default taints to the newly created managedcluster
+	if req.Operation == admissionv1beta1.Create {
+		jsonPatches = append(jsonPatches, a.addDefaultTaints(managedCluster)...)
+	}
+
 	if utilfeature.DefaultMutableFeatureGate.Enabled(features.DefaultClusterSet) {
 		labelJsonPatches, status := a.addDefaultClusterSetLabel(managedCluster, req.Object.Raw)
 		if !status.Allowed {
@@ -111,6 +132,11 @@ func (a *ManagedClusterMutatingAdmissionHook) Admit(req *admissionv1beta1.Admiss
 func (a *ManagedClusterMutatingAdmissionHook) addDefaultClusterSetLabel(managedCluster *clusterv1.ManagedCluster, clusterObj []byte) ([]jsonPatchOperation, *admissionv1beta1.AdmissionResponse) {
 	var jsonPatches []jsonPatchOperation
 
+	defaultClusterSetName := defaultClusterSetName
+	if len(a.DefaultClusterSetName) > 0 {
+		defaultClusterSetName = a.DefaultClusterSetName
+	}
+
 	status := &admissionv1beta1.AdmissionResponse{
 		Allowed: true,
 	}
@@ -121,14 +147,14 @@ func (a *ManagedClusterMutatingAdmissionHook) addDefaultClusterSetLabel(managedC
 				Operation: "add",
 				Path:      "/metadata/labels",
 				Value: map[string]string{
-					clusterSetLabel: defaultClusterSetName,
+					helpers.ClusterSetLabel: defaultClusterSetName,
 				},
 			},
 		}
 		return jsonPatches, status
 	}
 
-	clusterSetName, ok := managedCluster.Labels[clusterSetLabel]
+	clusterSetName, ok := managedCluster.Labels[helpers.ClusterSetLabel]
 	// Clusterset label do not exist
 	if !ok {
 		jsonPatches = []jsonPatchOperation{
@@ -237,6 +263,66 @@ func (a *ManagedClusterMutatingAdmissionHook) processTaints(managedCluster *clus
 	return nil, status
 }
 
+// addDefaultTaints generates json patches to add the default taints which the managed cluster does not have yet
+func (a *ManagedClusterMutatingAdmissionHook) addDefaultTaints(managedCluster *clusterv1.ManagedCluster) []jsonPatchOperation {
+	var jsonPatches []jsonPatchOperation
+	hasTaints := len(managedCluster.Spec.Taints) > 0
+	now := metav1.NewTime(nowFunc())
+	for _, defaultTaint := range a.DefaultTaints {
+		if helpers.FindTaintByKey(managedCluster, defaultTaint.Key) != nil {
+			continue
+		}
+
+		taint := defaultTaint
+		taint.TimeAdded = now
+		if !hasTaints {
+			jsonPatches = append(jsonPatches, jsonPatchOperation{
+				Operation: "add",
+				Path:      "/spec/taints",
+				Value:     []clusterv1.Taint{taint},
+			})
+			hasTaints = true
+			continue
+		}
+		jsonPatches = append(jsonPatches, jsonPatchOperation{
+			Operation: "add",
+			Path:      "/spec/taints/-",
+			Value:     taint,
+		})
+	}
+	return jsonPatches
+}
+
+// ParseTaints parses taints in the format of "key=value:effect" or "key:effect"
+func ParseTaints(specs []string) ([]clusterv1.Taint, error) {
+	var taints []clusterv1.Taint
+	for _, spec := range specs {
+		index := strings.LastIndex(spec, ":")
+		if index <= 0 {
+			return nil, fmt.Errorf("invalid taint %q: it must be in the format of key=value:effect or key:effect", spec)
+		}
+
+		taint := clusterv1.Taint{
+			Key:    spec[:index],
+			Effect: clusterv1.TaintEffect(spec[index+1:]),
+		}
+		if kv := strings.SplitN(taint.Key, "=", 2); len(kv) == 2 {
+			taint.Key, taint.Value = kv[0], kv[1]
+		}
+		if len(taint.Key) == 0 {
+			return nil, fmt.Errorf("invalid taint %q: the key is empty", spec)
+		}
+
+		switch taint.Effect {
+		case clusterv1.TaintEffectNoSelect, clusterv1.TaintEffectPreferNoSelect, clusterv1.TaintEffectNoSelectIfNew:
+		default:
+			return nil, fmt.Errorf("invalid taint %q: unsupported effect %q", spec, taint.Effect)
+		}
+		taints = append(taints, taint)
+	}
+	return taints, nil
+}
+
 // Initialize is called by generic-admission-server on startup to setup initialization that managedclusters webhook needs.
 func (a *ManagedClusterMutatingAdmissionHook) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
 	// do nothing
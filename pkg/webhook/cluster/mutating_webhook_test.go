@@ -16,6 +16,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"open-cluster-management.io/registration/pkg/features"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 )
 
@@ -26,6 +27,8 @@ func TestManagedClusterMutate(t *testing.T) {
 		request                *admissionv1beta1.AdmissionRequest
 		expectedResponse       *admissionv1beta1.AdmissionResponse
 		allowUpdateAcceptField bool
+		defaultClusterSetName  string
+		defaultTaints          []clusterv1.Taint
 	}{
 		{
 			name: "mutate non-managedclusters request",
@@ -55,7 +58,7 @@ func TestManagedClusterMutate(t *testing.T) {
 					withLeaseDurationSeconds(60).
 					addTaint(newTaint("a", "b", clusterv1.TaintEffectNoSelect, nil)).
 					addTaint(newTaint("c", "d", clusterv1.TaintEffectPreferNoSelect, nil)).
-					addLabels(map[string]string{clusterSetLabel: defaultClusterSetName}).
+					addLabels(map[string]string{helpers.ClusterSetLabel: defaultClusterSetName}).
 					build(),
 			},
 			expectedResponse: newAdmissionResponse(true).
@@ -72,7 +75,7 @@ func TestManagedClusterMutate(t *testing.T) {
 					withLeaseDurationSeconds(60).
 					addTaint(newTaint("a", "b", clusterv1.TaintEffectNoSelect, nil)).
 					addTaint(newTaint("c", "d", clusterv1.TaintEffectPreferNoSelect, newTime(now, 0))).
-					addLabels(map[string]string{clusterSetLabel: defaultClusterSetName}).
+					addLabels(map[string]string{helpers.ClusterSetLabel: defaultClusterSetName}).
 					build(),
 			},
 			expectedResponse: newAdmissionResponse(true).
@@ -90,13 +93,13 @@ func TestManagedClusterMutate(t *testing.T) {
 					withLeaseDurationSeconds(60).
 					addTaint(newTaint("a", "b", clusterv1.TaintEffectNoSelect, newTime(now, -10*time.Second))).
 					addTaint(newTaint("c", "d", clusterv1.TaintEffectNoSelect, newTime(now, -10*time.Second))).
-					addLabels(map[string]string{clusterSetLabel: defaultClusterSetName}).
+					addLabels(map[string]string{helpers.ClusterSetLabel: defaultClusterSetName}).
 					build(),
 				Object: newManagedCluster().
 					withLeaseDurationSeconds(60).
 					addTaint(newTaint("a", "b", clusterv1.TaintEffectNoSelect, newTime(now, -10*time.Second))). // no change
 					addTaint(newTaint("c", "d", clusterv1.TaintEffectNoSelectIfNew, nil)).                      // effect modified
-					addLabels(map[string]string{clusterSetLabel: defaultClusterSetName}).
+					addLabels(map[string]string{helpers.ClusterSetLabel: defaultClusterSetName}).
 					build(),
 			},
 			expectedResponse: newAdmissionResponse(true).
@@ -132,12 +135,12 @@ func TestManagedClusterMutate(t *testing.T) {
 					withLeaseDurationSeconds(60).
 					addTaint(newTaint("a", "b", clusterv1.TaintEffectNoSelect, newTime(now, -10*time.Second))).
 					addTaint(newTaint("c", "d", clusterv1.TaintEffectNoSelect, newTime(now, -10*time.Second))).
-					addLabels(map[string]string{clusterSetLabel: defaultClusterSetName}).
+					addLabels(map[string]string{helpers.ClusterSetLabel: defaultClusterSetName}).
 					build(),
 				Object: newManagedCluster().
 					withLeaseDurationSeconds(60).
 					addTaint(newTaint("a", "b", clusterv1.TaintEffectNoSelect, newTime(now, -10*time.Second))).
-					addLabels(map[string]string{clusterSetLabel: defaultClusterSetName}).
+					addLabels(map[string]string{helpers.ClusterSetLabel: defaultClusterSetName}).
 					build(),
 			},
 			expectedResponse: newAdmissionResponse(true).build(),
@@ -156,7 +159,7 @@ func TestManagedClusterMutate(t *testing.T) {
 					Operation: "add",
 					Path:      "/metadata/labels",
 					Value: map[string]string{
-						clusterSetLabel: defaultClusterSetName,
+						helpers.ClusterSetLabel: defaultClusterSetName,
 					},
 				}).
 				build(),
@@ -168,7 +171,7 @@ func TestManagedClusterMutate(t *testing.T) {
 				Operation: admissionv1beta1.Create,
 				Object: newManagedCluster().
 					withLeaseDurationSeconds(60).
-					addLabels(map[string]string{clusterSetLabel: "c1"}).
+					addLabels(map[string]string{helpers.ClusterSetLabel: "c1"}).
 					build(),
 			},
 			expectedResponse: newAdmissionResponse(true).
@@ -181,7 +184,7 @@ func TestManagedClusterMutate(t *testing.T) {
 				Operation: admissionv1beta1.Create,
 				Object: newManagedCluster().
 					withLeaseDurationSeconds(60).
-					addLabels(map[string]string{clusterSetLabel: defaultClusterSetName}).
+					addLabels(map[string]string{helpers.ClusterSetLabel: defaultClusterSetName}).
 					build(),
 			},
 			expectedResponse: newAdmissionResponse(true).
@@ -194,7 +197,7 @@ func TestManagedClusterMutate(t *testing.T) {
 				Operation: admissionv1beta1.Create,
 				Object: newManagedCluster().
 					withLeaseDurationSeconds(60).
-					addLabels(map[string]string{clusterSetLabel: ""}).
+					addLabels(map[string]string{helpers.ClusterSetLabel: ""}).
 					build(),
 			},
 			expectedResponse: newAdmissionResponse(true).
@@ -223,6 +226,94 @@ func TestManagedClusterMutate(t *testing.T) {
 				}).
 				build(),
 		},
+		{
+			name: "has no label with configured default clusterset",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Create,
+				Object: newManagedCluster().
+					withLeaseDurationSeconds(60).
+					build(),
+			},
+			defaultClusterSetName: "clusterset1",
+			expectedResponse: newAdmissionResponse(true).
+				addJsonPatch(jsonPatchOperation{
+					Operation: "add",
+					Path:      "/metadata/labels",
+					Value:     map[string]string{helpers.ClusterSetLabel: "clusterset1"},
+				}).
+				build(),
+		},
+		{
+			name: "add default taints to new cluster",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Create,
+				Object: newManagedCluster().
+					withLeaseDurationSeconds(60).
+					addLabels(map[string]string{helpers.ClusterSetLabel: defaultClusterSetName}).
+					build(),
+			},
+			defaultTaints: []clusterv1.Taint{
+				newTaint("a", "b", clusterv1.TaintEffectNoSelect, nil),
+				newTaint("c", "", clusterv1.TaintEffectNoSelectIfNew, nil),
+			},
+			expectedResponse: newAdmissionResponse(true).
+				addJsonPatch(jsonPatchOperation{
+					Operation: "add",
+					Path:      "/spec/taints",
+					Value:     []clusterv1.Taint{newTaint("a", "b", clusterv1.TaintEffectNoSelect, newTime(now, 0))},
+				}).
+				addJsonPatch(jsonPatchOperation{
+					Operation: "add",
+					Path:      "/spec/taints/-",
+					Value:     newTaint("c", "", clusterv1.TaintEffectNoSelectIfNew, newTime(now, 0)),
+				}).
+				build(),
+		},
+		{
+			name: "skip default taints existing in new cluster",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Create,
+				Object: newManagedCluster().
+					withLeaseDurationSeconds(60).
+					addTaint(newTaint("a", "d", clusterv1.TaintEffectPreferNoSelect, nil)).
+					addLabels(map[string]string{helpers.ClusterSetLabel: defaultClusterSetName}).
+					build(),
+			},
+			defaultTaints: []clusterv1.Taint{
+				newTaint("a", "b", clusterv1.TaintEffectNoSelect, nil),
+				newTaint("c", "", clusterv1.TaintEffectNoSelectIfNew, nil),
+			},
+			expectedResponse: newAdmissionResponse(true).
+				addJsonPatch(newTaintTimeAddedJsonPatch(0, now)).
+				addJsonPatch(jsonPatchOperation{
+					Operation: "add",
+					Path:      "/spec/taints/-",
+					Value:     newTaint("c", "", clusterv1.TaintEffectNoSelectIfNew, newTime(now, 0)),
+				}).
+				build(),
+		},
+		{
+			name: "no default taints added to existing cluster",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				Object: newManagedCluster().
+					withLeaseDurationSeconds(60).
+					addLabels(map[string]string{helpers.ClusterSetLabel: defaultClusterSetName}).
+					build(),
+				OldObject: newManagedCluster().
+					withLeaseDurationSeconds(60).
+					addLabels(map[string]string{helpers.ClusterSetLabel: defaultClusterSetName}).
+					build(),
+			},
+			defaultTaints: []clusterv1.Taint{
+				newTaint("a", "b", clusterv1.TaintEffectNoSelect, nil),
+			},
+			expectedResponse: newAdmissionResponse(true).build(),
+		},
 	}
 
 	nowFunc = func() time.Time {
@@ -231,8 +322,13 @@ func TestManagedClusterMutate(t *testing.T) {
 	utilfeature.DefaultMutableFeatureGate.Set(fmt.Sprintf("%s=true", string(features.DefaultClusterSet)))
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			admissionHook := &ManagedClusterMutatingAdmissionHook{}
+			admissionHook := &ManagedClusterMutatingAdmissionHook{
+				DefaultClusterSetName: c.defaultClusterSetName,
+				DefaultTaints:         c.defaultTaints,
+			}
 			actualResponse := admissionHook.Admit(c.request)
+			// the audit annotations are verified in the audit package
+			actualResponse.AuditAnnotations = nil
 			if !reflect.DeepEqual(actualResponse, c.expectedResponse) {
 				t.Errorf("expected \n%#v but got: \n%#v", c.expectedResponse, actualResponse)
 			}
@@ -240,6 +336,58 @@ func TestManagedClusterMutate(t *testing.T) {
 	}
 }
 
+func TestParseTaints(t *testing.T) {
+	cases := []struct {
+		name           string
+		specs          []string
+		expectedErr    bool
+		expectedTaints []clusterv1.Taint
+	}{
+		{
+			name: "no taints",
+		},
+		{
+			name:  "valid taints",
+			specs: []string{"a=b:NoSelect", "c:PreferNoSelect", "d=:NoSelectIfNew"},
+			expectedTaints: []clusterv1.Taint{
+				newTaint("a", "b", clusterv1.TaintEffectNoSelect, nil),
+				newTaint("c", "", clusterv1.TaintEffectPreferNoSelect, nil),
+				newTaint("d", "", clusterv1.TaintEffectNoSelectIfNew, nil),
+			},
+		},
+		{
+			name:        "no effect",
+			specs:       []string{"a=b"},
+			expectedErr: true,
+		},
+		{
+			name:        "no key",
+			specs:       []string{"=b:NoSelect"},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid effect",
+			specs:       []string{"a=b:NoSchedule"},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			taints, err := ParseTaints(c.specs)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but got nil")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(taints, c.expectedTaints) {
+				t.Errorf("expected %v, but got %v", c.expectedTaints, taints)
+			}
+		})
+	}
+}
+
 type admissionResponseBuilder struct {
 	jsonPatchOperations []jsonPatchOperation
 	response            admissionv1beta1.AdmissionResponse
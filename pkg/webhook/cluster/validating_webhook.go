@@ -5,30 +5,79 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
+	"strings"
+	"time"
 
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1beta1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/webhook/audit"
+	"open-cluster-management.io/registration/pkg/webhook/metrics"
 
 	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
 
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	authenticationv1 "k8s.io/api/authentication/v1"
 	authorizationv1 "k8s.io/api/authorization/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	certutil "k8s.io/client-go/util/cert"
 	"k8s.io/klog/v2"
 )
 
-const (
-	clusterSetLabel = "cluster.open-cluster-management.io/clusterset"
-)
-
 // ManagedClusterValidatingAdmissionHook will validate the creating/updating managedcluster request.
 type ManagedClusterValidatingAdmissionHook struct {
-	kubeClient kubernetes.Interface
+	kubeClient              kubernetes.Interface
+	clusterSetBindingLister clusterv1beta1listers.ManagedClusterSetBindingLister
+	// clusterSetBindingSynced returns true once the ManagedClusterSetBindings are cached, the requests which
+	// depend on them are rejected until then.
+	clusterSetBindingSynced cache.InformerSynced
+
+	// NamingPolicy is the policy which the names of the newly created managedclusters must comply with.
+	NamingPolicy ClusterNamingPolicy
+}
+
+// ClusterNamingPolicy restricts the names of managedclusters. Since a namespace and the rbac resources are
+// created on the hub with the name of each managedcluster, an invalid name is rejected on the creation of the
+// managedcluster rather than failing later.
+type ClusterNamingPolicy struct {
+	// Pattern is the regular expression which the names must match. Any name is allowed if it is nil.
+	Pattern *regexp.Regexp
+	// EnforceDNS1123 requires the names to be valid DNS-1123 labels.
+	EnforceDNS1123 bool
+	// ReservedPrefixes are the prefixes which the names are not allowed to start with.
+	ReservedPrefixes []string
+}
+
+// Validate returns an error if the cluster name does not comply with the policy
+func (p ClusterNamingPolicy) Validate(clusterName string) error {
+	if p.EnforceDNS1123 {
+		if errs := validation.IsDNS1123Label(clusterName); len(errs) > 0 {
+			return fmt.Errorf("cluster name %q is not a valid DNS-1123 label: %s", clusterName, strings.Join(errs, "; "))
+		}
+	}
+
+	for _, prefix := range p.ReservedPrefixes {
+		if strings.HasPrefix(clusterName, prefix) {
+			return fmt.Errorf("cluster name %q starts with the reserved prefix %q", clusterName, prefix)
+		}
+	}
+
+	if p.Pattern != nil && !p.Pattern.MatchString(clusterName) {
+		return fmt.Errorf("cluster name %q does not match the pattern %q", clusterName, p.Pattern.String())
+	}
+
+	return nil
 }
 
 // ValidatingResource is called by generic-admission-server on startup to register the returned REST resource through which the
@@ -44,6 +93,14 @@ func (a *ManagedClusterValidatingAdmissionHook) ValidatingResource() (plural sch
 
 // Validate is called by generic-admission-server when the registered REST resource above is called with an admission request.
 func (a *ManagedClusterValidatingAdmissionHook) Validate(admissionSpec *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	start := time.Now()
+	response := audit.Annotate(admissionSpec, a.validate(admissionSpec))
+	metrics.ObserveAdmission("managedclustervalidators", admissionSpec, response, start)
+	return response
+}
+
+// validate validates the creating/updating managedcluster request
+func (a *ManagedClusterValidatingAdmissionHook) validate(admissionSpec *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
 	klog.V(4).Infof("validate %q operation for object %q", admissionSpec.Operation, admissionSpec.Object)
 
 	status := &admissionv1beta1.AdmissionResponse{}
@@ -52,7 +109,7 @@ func (a *ManagedClusterValidatingAdmissionHook) Validate(admissionSpec *admissio
 	if admissionSpec.Resource.Group != "cluster.open-cluster-management.io" ||
 		admissionSpec.Resource.Resource != "managedclusters" {
 		status.Allowed = true
-		return status
+		return audit.WithPolicy(status, audit.PolicyNotApplicable)
 	}
 
 	switch admissionSpec.Operation {
@@ -62,7 +119,7 @@ func (a *ManagedClusterValidatingAdmissionHook) Validate(admissionSpec *admissio
 		return a.validateUpdateRequest(admissionSpec)
 	default:
 		status.Allowed = true
-		return status
+		return audit.WithPolicy(status, audit.PolicyNotApplicable)
 	}
 }
 
@@ -70,7 +127,25 @@ func (a *ManagedClusterValidatingAdmissionHook) Validate(admissionSpec *admissio
 func (a *ManagedClusterValidatingAdmissionHook) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
 	var err error
 	a.kubeClient, err = kubernetes.NewForConfig(kubeClientConfig)
-	return err
+	if err != nil {
+		return err
+	}
+
+	clusterClient, err := clusterv1client.NewForConfig(kubeClientConfig)
+	if err != nil {
+		return err
+	}
+	clusterInformers := clusterv1informers.NewSharedInformerFactory(clusterClient, 10*time.Minute)
+	clusterSetBindingInformer := clusterInformers.Cluster().V1beta1().ManagedClusterSetBindings()
+	a.clusterSetBindingLister = clusterSetBindingInformer.Lister()
+	a.clusterSetBindingSynced = clusterSetBindingInformer.Informer().HasSynced
+	clusterInformers.Start(stopCh)
+
+	// the server is not ready until the hook is initialized
+	if !cache.WaitForCacheSync(stopCh, a.clusterSetBindingSynced) {
+		return fmt.Errorf("unable to sync the cache of ManagedClusterSetBindings")
+	}
+	return nil
 }
 
 // validateCreateRequest validates create managed cluster operation
@@ -78,31 +153,49 @@ func (a *ManagedClusterValidatingAdmissionHook) validateCreateRequest(request *a
 	status := &admissionv1beta1.AdmissionResponse{}
 
 	// validate ManagedCluster object firstly
-	managedCluster, err := a.validateManagedClusterObj(request.Object)
+	managedCluster, err := a.validateManagedClusterObj(request.Object, nil)
 	if err != nil {
 		status.Allowed = false
 		status.Result = &metav1.Status{
 			Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
 			Message: err.Error(),
 		}
-		return status
+		return audit.WithPolicy(status, audit.PolicyObjectValidation)
+	}
+
+	// the name of ManagedCluster cannot be changed, so only validate it on creation
+	if err := a.NamingPolicy.Validate(managedCluster.Name); err != nil {
+		status.Allowed = false
+		status.Result = &metav1.Status{
+			Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
+			Message: err.Error(),
+		}
+		return audit.WithPolicy(status, audit.PolicyClusterNaming)
 	}
 
 	if managedCluster.Spec.HubAcceptsClient {
 		// the HubAcceptsClient field is changed, we need to check the request user whether
 		// has been allowed to change the HubAcceptsClient field with SubjectAccessReview api
 		if status := a.allowUpdateAcceptField(managedCluster.Name, request.UserInfo); !status.Allowed {
-			return status
+			return audit.WithPolicy(status, audit.PolicyHubAcceptsClient)
 		}
 	}
 
+	if status := a.allowUpdateSystemTaints(managedCluster.Name, request.UserInfo, nil, managedCluster.Spec.Taints); !status.Allowed {
+		return audit.WithPolicy(status, audit.PolicySystemTaints)
+	}
+
 	// check whether the request user has been allowed to set clusterset label
 	var clusterSetName string
 	if len(managedCluster.Labels) > 0 {
-		clusterSetName = managedCluster.Labels[clusterSetLabel]
+		clusterSetName = managedCluster.Labels[helpers.ClusterSetLabel]
+	}
+
+	if status := a.allowSetClusterSetLabel(request.UserInfo, "", clusterSetName); !status.Allowed {
+		return audit.WithPolicy(status, audit.PolicyClusterSetJoin)
 	}
 
-	return a.allowSetClusterSetLabel(request.UserInfo, "", clusterSetName)
+	return &admissionv1beta1.AdmissionResponse{Allowed: true}
 }
 
 // validateUpdateRequest validates update managed cluster operation.
@@ -116,42 +209,58 @@ func (a *ManagedClusterValidatingAdmissionHook) validateUpdateRequest(request *a
 			Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
 			Message: err.Error(),
 		}
-		return status
+		return audit.WithPolicy(status, audit.PolicyObjectValidation)
 	}
 
 	// validate the updating ManagedCluster object firstly
-	newManagedCluster, err := a.validateManagedClusterObj(request.Object)
+	newManagedCluster, err := a.validateManagedClusterObj(request.Object, oldManagedCluster)
 	if err != nil {
 		status.Allowed = false
 		status.Result = &metav1.Status{
 			Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
 			Message: err.Error(),
 		}
-		return status
+		return audit.WithPolicy(status, audit.PolicyObjectValidation)
 	}
 
 	if newManagedCluster.Spec.HubAcceptsClient != oldManagedCluster.Spec.HubAcceptsClient {
 		// the HubAcceptsClient field is changed, we need to check the request user whether
 		// has been allowed to update the HubAcceptsClient field with SubjectAccessReview api
 		if status := a.allowUpdateAcceptField(newManagedCluster.Name, request.UserInfo); !status.Allowed {
-			return status
+			return audit.WithPolicy(status, audit.PolicyHubAcceptsClient)
 		}
 	}
 
+	if status := a.allowUpdateSystemTaints(newManagedCluster.Name, request.UserInfo,
+		oldManagedCluster.Spec.Taints, newManagedCluster.Spec.Taints); !status.Allowed {
+		return audit.WithPolicy(status, audit.PolicySystemTaints)
+	}
+
+	if status := a.allowIdentityChange(request.UserInfo, oldManagedCluster, newManagedCluster); !status.Allowed {
+		return audit.WithPolicy(status, audit.PolicyIdentityImmutability)
+	}
+
 	// check whether the request user has been allowed to set clusterset label
 	var originalClusterSetName, currentClusterSetName string
 	if len(oldManagedCluster.Labels) > 0 {
-		originalClusterSetName = oldManagedCluster.Labels[clusterSetLabel]
+		originalClusterSetName = oldManagedCluster.Labels[helpers.ClusterSetLabel]
 	}
 	if len(newManagedCluster.Labels) > 0 {
-		currentClusterSetName = newManagedCluster.Labels[clusterSetLabel]
+		currentClusterSetName = newManagedCluster.Labels[helpers.ClusterSetLabel]
 	}
 
-	return a.allowSetClusterSetLabel(request.UserInfo, originalClusterSetName, currentClusterSetName)
+	if status := a.allowSetClusterSetLabel(request.UserInfo, originalClusterSetName, currentClusterSetName); !status.Allowed {
+		return audit.WithPolicy(status, audit.PolicyClusterSetJoin)
+	}
+
+	return &admissionv1beta1.AdmissionResponse{Allowed: true}
 }
 
-// validateManagedClusterObj validates the fileds of ManagedCluster object
-func (a *ManagedClusterValidatingAdmissionHook) validateManagedClusterObj(requestObj runtime.RawExtension) (*clusterv1.ManagedCluster, error) {
+// validateManagedClusterObj validates the fileds of ManagedCluster object. The old ManagedCluster is nil on creation.
+// The duplicated urls and the ca bundles in spoke client configs are only validated on creation or when the client
+// configs are changed, so the existing clusters with such client configs are still able to be updated.
+func (a *ManagedClusterValidatingAdmissionHook) validateManagedClusterObj(requestObj runtime.RawExtension,
+	oldManagedCluster *clusterv1.ManagedCluster) (*clusterv1.ManagedCluster, error) {
 	errs := []error{}
 
 	managedCluster := &clusterv1.ManagedCluster{}
@@ -164,10 +273,30 @@ func (a *ManagedClusterValidatingAdmissionHook) validateManagedClusterObj(reques
 		return managedCluster, operatorhelpers.NewMultiLineAggregate(errs)
 	}
 
-	// validate the url in spoke client configs
-	for _, clientConfig := range managedCluster.Spec.ManagedClusterClientConfigs {
+	// validate the url and the ca bundle in spoke client configs
+	clientConfigsChanged := oldManagedCluster == nil || !apiequality.Semantic.DeepEqual(
+		oldManagedCluster.Spec.ManagedClusterClientConfigs, managedCluster.Spec.ManagedClusterClientConfigs)
+	urls := sets.NewString()
+	for index, clientConfig := range managedCluster.Spec.ManagedClusterClientConfigs {
 		if !helpers.IsValidHTTPSURL(clientConfig.URL) {
-			errs = append(errs, fmt.Errorf("url %q is invalid in client configs", clientConfig.URL))
+			errs = append(errs, fmt.Errorf("url %q is invalid in client configs, it must be an https url", clientConfig.URL))
+		}
+
+		if !clientConfigsChanged {
+			continue
+		}
+
+		if urls.Has(clientConfig.URL) {
+			errs = append(errs, fmt.Errorf("url %q is duplicated in client configs, each url can only be specified once", clientConfig.URL))
+		}
+		urls.Insert(clientConfig.URL)
+
+		if len(clientConfig.CABundle) == 0 {
+			continue
+		}
+		if _, err := certutil.ParseCertsPEM(clientConfig.CABundle); err != nil {
+			errs = append(errs, fmt.Errorf("caBundle of client config %d with url %q is invalid, it must contain PEM encoded certificates: %v",
+				index, clientConfig.URL, err))
 		}
 	}
 
@@ -222,32 +351,15 @@ func (a *ManagedClusterValidatingAdmissionHook) allowUpdateAcceptField(clusterNa
 	return status
 }
 
-// allowSetClusterSetLabel checks whether a request user has been authorized to set clusterset label
-func (a *ManagedClusterValidatingAdmissionHook) allowSetClusterSetLabel(userInfo authenticationv1.UserInfo, originalClusterSet, newClusterSet string) *admissionv1beta1.AdmissionResponse {
-	if originalClusterSet == newClusterSet {
+// allowUpdateSystemTaints checks whether a request user has been authorized to add/update/remove the system taints,
+// which are maintained by the registration hub controller according to the availability of the managed cluster.
+// Otherwise, the eviction of an unavailable managed cluster from placements could be bypassed silently.
+func (a *ManagedClusterValidatingAdmissionHook) allowUpdateSystemTaints(clusterName string, userInfo authenticationv1.UserInfo,
+	oldTaints, newTaints []clusterv1.Taint) *admissionv1beta1.AdmissionResponse {
+	if apiequality.Semantic.DeepEqual(helpers.SystemTaints(oldTaints), helpers.SystemTaints(newTaints)) {
 		return &admissionv1beta1.AdmissionResponse{Allowed: true}
 	}
 
-	if len(originalClusterSet) > 0 {
-		if status := a.allowUpdateClusterSet(userInfo, originalClusterSet); !status.Allowed {
-			return status
-		}
-	}
-
-	if len(newClusterSet) > 0 {
-		if status := a.allowUpdateClusterSet(userInfo, newClusterSet); !status.Allowed {
-			return status
-		}
-	}
-
-	return &admissionv1beta1.AdmissionResponse{
-		Allowed: true,
-	}
-}
-
-// allowUpdateClusterSet checks whether a request user has been authorized to add/remove a ManagedCluster
-// to/from the ManagedClusterSet
-func (a *ManagedClusterValidatingAdmissionHook) allowUpdateClusterSet(userInfo authenticationv1.UserInfo, clusterSetName string) *admissionv1beta1.AdmissionResponse {
 	status := &admissionv1beta1.AdmissionResponse{}
 
 	extra := make(map[string]authorizationv1.ExtraValue)
@@ -262,11 +374,11 @@ func (a *ManagedClusterValidatingAdmissionHook) allowUpdateClusterSet(userInfo a
 			Groups: userInfo.Groups,
 			Extra:  extra,
 			ResourceAttributes: &authorizationv1.ResourceAttributes{
-				Group:       "cluster.open-cluster-management.io",
-				Resource:    "managedclustersets",
-				Subresource: "join",
-				Name:        clusterSetName,
-				Verb:        "create",
+				Group:       "register.open-cluster-management.io",
+				Resource:    "managedclusters",
+				Verb:        "update",
+				Subresource: "systemtaints",
+				Name:        clusterName,
 			},
 		},
 	}
@@ -284,7 +396,8 @@ func (a *ManagedClusterValidatingAdmissionHook) allowUpdateClusterSet(userInfo a
 		status.Allowed = false
 		status.Result = &metav1.Status{
 			Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
-			Message: fmt.Sprintf("user %q cannot add/remove a ManagedCluster to/from ManagedClusterSet %q", userInfo.Username, clusterSetName),
+			Message: fmt.Sprintf("user %q cannot add/update/remove the system taints %q and %q",
+				userInfo.Username, clusterv1.ManagedClusterTaintUnavailable, clusterv1.ManagedClusterTaintUnreachable),
 		}
 		return status
 	}
@@ -292,3 +405,86 @@ func (a *ManagedClusterValidatingAdmissionHook) allowUpdateClusterSet(userInfo a
 	status.Allowed = true
 	return status
 }
+
+// allowSetClusterSetLabel checks whether a request user has been authorized to set clusterset label. Moving a
+// ManagedCluster from one ManagedClusterSet to another requires the permission on both of them, otherwise a
+// user could take a ManagedCluster away from a ManagedClusterSet it cannot manage by relabeling the cluster.
+func (a *ManagedClusterValidatingAdmissionHook) allowSetClusterSetLabel(userInfo authenticationv1.UserInfo, originalClusterSet, newClusterSet string) *admissionv1beta1.AdmissionResponse {
+	if originalClusterSet == newClusterSet {
+		return &admissionv1beta1.AdmissionResponse{Allowed: true}
+	}
+
+	// check the permission on both the source and the destination clustersets, so that the user knows all
+	// the permissions it lacks
+	deniedClusterSets := []string{}
+	for _, clusterSet := range []string{originalClusterSet, newClusterSet} {
+		if len(clusterSet) == 0 {
+			continue
+		}
+
+		allowed, err := a.allowUpdateClusterSet(userInfo, clusterSet)
+		if err != nil {
+			// the permission is unknown if the subject access review fails, which is not a forbidden request
+			return &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusInternalServerError, Reason: metav1.StatusReasonInternalError,
+					Message: err.Error(),
+				},
+			}
+		}
+		if !allowed {
+			deniedClusterSets = append(deniedClusterSets, fmt.Sprintf("%q", clusterSet))
+		}
+	}
+
+	if len(deniedClusterSets) == 0 {
+		return &admissionv1beta1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+
+	message := fmt.Sprintf("user %q cannot add/remove a ManagedCluster to/from ManagedClusterSet %s",
+		userInfo.Username, strings.Join(deniedClusterSets, " and "))
+	if len(originalClusterSet) > 0 && len(newClusterSet) > 0 {
+		message = fmt.Sprintf("%s, which is required to move the ManagedCluster from ManagedClusterSet %q to %q",
+			message, originalClusterSet, newClusterSet)
+	}
+	return &admissionv1beta1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+			Message: message,
+		},
+	}
+}
+
+// allowUpdateClusterSet checks whether a request user has been authorized to add/remove a ManagedCluster
+// to/from the ManagedClusterSet
+func (a *ManagedClusterValidatingAdmissionHook) allowUpdateClusterSet(userInfo authenticationv1.UserInfo, clusterSetName string) (bool, error) {
+	extra := make(map[string]authorizationv1.ExtraValue)
+	for k, v := range userInfo.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   userInfo.Username,
+			UID:    userInfo.UID,
+			Groups: userInfo.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Group:       "cluster.open-cluster-management.io",
+				Resource:    "managedclustersets",
+				Subresource: "join",
+				Name:        clusterSetName,
+				Verb:        "create",
+			},
+		},
+	}
+	sar, err := a.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), sar, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return sar.Status.Allowed, nil
+}
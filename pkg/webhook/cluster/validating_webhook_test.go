@@ -2,12 +2,19 @@ package cluster
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"reflect"
+	"regexp"
 	"testing"
+	"time"
 
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+	"open-cluster-management.io/registration/pkg/webhook/audit"
 
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	authenticationv1 "k8s.io/api/authentication/v1"
@@ -26,14 +33,22 @@ var managedclustersSchema = metav1.GroupVersionResource{
 
 func TestManagedClusterValidate(t *testing.T) {
 	cases := []struct {
-		name                   string
-		request                *admissionv1beta1.AdmissionRequest
-		expectedResponse       *admissionv1beta1.AdmissionResponse
-		allowUpdateAcceptField bool
-		allowUpdateClusterSets map[string]bool
+		name                    string
+		request                 *admissionv1beta1.AdmissionRequest
+		expectedResponse        *admissionv1beta1.AdmissionResponse
+		allowUpdateAcceptField  bool
+		allowUpdateClusterSets  map[string]bool
+		sarErr                  error
+		allowUpdateSystemTaints bool
+		allowChangeIdentity     bool
+		clusterSetBindings      []runtime.Object
+		unsyncedBindings        bool
+		namingPolicy            ClusterNamingPolicy
+		expectedPolicy          string
 	}{
 		{
-			name: "validate non-managedclusters request",
+			name:           "validate non-managedclusters request",
+			expectedPolicy: audit.PolicyNotApplicable,
 			request: &admissionv1beta1.AdmissionRequest{
 				Resource: metav1.GroupVersionResource{
 					Group:    "test.open-cluster-management.io",
@@ -46,7 +61,8 @@ func TestManagedClusterValidate(t *testing.T) {
 			},
 		},
 		{
-			name: "validate deleting operation",
+			name:           "validate deleting operation",
+			expectedPolicy: audit.PolicyNotApplicable,
 			request: &admissionv1beta1.AdmissionRequest{
 				Resource:  managedclustersSchema,
 				Operation: admissionv1beta1.Delete,
@@ -56,7 +72,8 @@ func TestManagedClusterValidate(t *testing.T) {
 			},
 		},
 		{
-			name: "validate creating ManagedCluster",
+			name:           "validate creating ManagedCluster",
+			expectedPolicy: audit.PolicyAllPassed,
 			request: &admissionv1beta1.AdmissionRequest{
 				Resource:  managedclustersSchema,
 				Operation: admissionv1beta1.Create,
@@ -67,7 +84,76 @@ func TestManagedClusterValidate(t *testing.T) {
 			},
 		},
 		{
-			name: "validate creating ManagedCluster with invalid fields",
+			name:           "validate creating ManagedCluster with invalid DNS-1123 name",
+			expectedPolicy: audit.PolicyClusterNaming,
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Create,
+				Object:    newManagedClusterObjWithName("Cluster_1"),
+			},
+			namingPolicy: ClusterNamingPolicy{EnforceDNS1123: true},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
+					Message: "cluster name \"Cluster_1\" is not a valid DNS-1123 label: " +
+						"a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', " +
+						"and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', " +
+						"regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')",
+				},
+			},
+		},
+		{
+			name: "validate creating ManagedCluster with reserved name prefix",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Create,
+				Object:    newManagedClusterObjWithName("kube-cluster1"),
+			},
+			namingPolicy: ClusterNamingPolicy{EnforceDNS1123: true, ReservedPrefixes: []string{"openshift-", "kube-"}},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
+					Message: "cluster name \"kube-cluster1\" starts with the reserved prefix \"kube-\"",
+				},
+			},
+		},
+		{
+			name: "validate creating ManagedCluster with name not matching pattern",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Create,
+				Object:    newManagedClusterObjWithName("cluster1"),
+			},
+			namingPolicy: ClusterNamingPolicy{Pattern: regexp.MustCompile("^prod-.*$")},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
+					Message: "cluster name \"cluster1\" does not match the pattern \"^prod-.*$\"",
+				},
+			},
+		},
+		{
+			name: "validate creating ManagedCluster with name complying with naming policy",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Create,
+				Object:    newManagedClusterObjWithName("prod-cluster1"),
+			},
+			namingPolicy: ClusterNamingPolicy{
+				Pattern:          regexp.MustCompile("^prod-.*$"),
+				EnforceDNS1123:   true,
+				ReservedPrefixes: []string{"kube-"},
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: true,
+			},
+		},
+		{
+			name:           "validate creating ManagedCluster with invalid fields",
+			expectedPolicy: audit.PolicyObjectValidation,
 			request: &admissionv1beta1.AdmissionRequest{
 				Resource:  managedclustersSchema,
 				Operation: admissionv1beta1.Create,
@@ -77,12 +163,64 @@ func TestManagedClusterValidate(t *testing.T) {
 				Allowed: false,
 				Result: &metav1.Status{
 					Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
-					Message: "url \"http://127.0.0.1:8001\" is invalid in client configs",
+					Message: "url \"http://127.0.0.1:8001\" is invalid in client configs, it must be an https url",
+				},
+			},
+		},
+		{
+			name: "validate creating ManagedCluster with invalid ca bundle",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Create,
+				Object: newManagedClusterObjWithClientConfigs(clusterv1.ClientConfig{
+					URL:      "https://127.0.0.1:8001",
+					CABundle: []byte("invalid"),
+				}),
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
+					Message: "caBundle of client config 0 with url \"https://127.0.0.1:8001\" is invalid, " +
+						"it must contain PEM encoded certificates: data does not contain any valid RSA or ECDSA certificates",
 				},
 			},
 		},
 		{
-			name: "validate creating an accepted ManagedCluster without update acceptance permission",
+			name: "validate creating ManagedCluster with duplicated client configs",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Create,
+				Object: newManagedClusterObjWithClientConfigs(
+					clusterv1.ClientConfig{URL: "https://127.0.0.1:8001"},
+					clusterv1.ClientConfig{URL: "https://127.0.0.1:8001"},
+				),
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
+					Message: "url \"https://127.0.0.1:8001\" is duplicated in client configs, each url can only be specified once",
+				},
+			},
+		},
+		{
+			name: "validate creating ManagedCluster with valid client configs",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Create,
+				Object: newManagedClusterObjWithClientConfigs(clusterv1.ClientConfig{
+					URL:      "https://127.0.0.1:8001",
+					CABundle: testinghelpers.NewTestCert("ca", time.Hour).Cert,
+				}),
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: true,
+			},
+		},
+		{
+			name:           "validate creating an accepted ManagedCluster without update acceptance permission",
+			expectedPolicy: audit.PolicyHubAcceptsClient,
 			request: &admissionv1beta1.AdmissionRequest{
 				Resource:  managedclustersSchema,
 				Operation: admissionv1beta1.Create,
@@ -121,6 +259,43 @@ func TestManagedClusterValidate(t *testing.T) {
 				Allowed: true,
 			},
 		},
+		{
+			name: "validate updating ManagedCluster with unchanged duplicated client configs",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newManagedClusterObjWithClientConfigs(
+					clusterv1.ClientConfig{URL: "https://127.0.0.1:8001", CABundle: []byte("invalid")},
+					clusterv1.ClientConfig{URL: "https://127.0.0.1:8001"},
+				),
+				Object: newManagedClusterObjWithClientConfigs(
+					clusterv1.ClientConfig{URL: "https://127.0.0.1:8001", CABundle: []byte("invalid")},
+					clusterv1.ClientConfig{URL: "https://127.0.0.1:8001"},
+				),
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: true,
+			},
+		},
+		{
+			name: "validate updating ManagedCluster with changed duplicated client configs",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newManagedClusterObjWithClientConfigs(clusterv1.ClientConfig{URL: "https://127.0.0.1:8001"}),
+				Object: newManagedClusterObjWithClientConfigs(
+					clusterv1.ClientConfig{URL: "https://127.0.0.1:8001"},
+					clusterv1.ClientConfig{URL: "https://127.0.0.1:8001"},
+				),
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
+					Message: "url \"https://127.0.0.1:8001\" is duplicated in client configs, each url can only be specified once",
+				},
+			},
+		},
 		{
 			name: "validate updating HubAcceptsClient field without update acceptance permission",
 			request: &admissionv1beta1.AdmissionRequest{
@@ -166,7 +341,8 @@ func TestManagedClusterValidate(t *testing.T) {
 			},
 		},
 		{
-			name: "validate setting clusterset label without permission",
+			name:           "validate setting clusterset label without permission",
+			expectedPolicy: audit.PolicyClusterSetJoin,
 			request: &admissionv1beta1.AdmissionRequest{
 				Resource:  managedclustersSchema,
 				Operation: admissionv1beta1.Create,
@@ -183,6 +359,22 @@ func TestManagedClusterValidate(t *testing.T) {
 				"clusterset1": false,
 			},
 		},
+		{
+			name: "validate setting clusterset label when the subject access review fails",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Create,
+				Object:    newManagedClusterObjWithClientSet("clusterset1"),
+			},
+			sarErr: fmt.Errorf("etcdserver: request timed out"),
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusInternalServerError, Reason: metav1.StatusReasonInternalError,
+					Message: "etcdserver: request timed out",
+				},
+			},
+		},
 		{
 			name: "validate updating clusterset label",
 			request: &admissionv1beta1.AdmissionRequest{
@@ -211,7 +403,8 @@ func TestManagedClusterValidate(t *testing.T) {
 				Allowed: false,
 				Result: &metav1.Status{
 					Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
-					Message: "user \"\" cannot add/remove a ManagedCluster to/from ManagedClusterSet \"clusterset1\"",
+					Message: "user \"\" cannot add/remove a ManagedCluster to/from ManagedClusterSet \"clusterset1\" and \"clusterset2\", " +
+						"which is required to move the ManagedCluster from ManagedClusterSet \"clusterset1\" to \"clusterset2\"",
 				},
 			},
 		},
@@ -227,13 +420,50 @@ func TestManagedClusterValidate(t *testing.T) {
 				Allowed: false,
 				Result: &metav1.Status{
 					Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
-					Message: "user \"\" cannot add/remove a ManagedCluster to/from ManagedClusterSet \"clusterset2\"",
+					Message: "user \"\" cannot add/remove a ManagedCluster to/from ManagedClusterSet \"clusterset2\", " +
+						"which is required to move the ManagedCluster from ManagedClusterSet \"clusterset1\" to \"clusterset2\"",
 				},
 			},
 			allowUpdateClusterSets: map[string]bool{
 				"clusterset1": true,
 			},
 		},
+		{
+			name: "validate moving a cluster out of a clusterset without permission",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newManagedClusterObjWithClientSet("clusterset1"),
+				Object:    newManagedClusterObjWithClientSet("clusterset2"),
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+					Message: "user \"\" cannot add/remove a ManagedCluster to/from ManagedClusterSet \"clusterset1\", " +
+						"which is required to move the ManagedCluster from ManagedClusterSet \"clusterset1\" to \"clusterset2\"",
+				},
+			},
+			allowUpdateClusterSets: map[string]bool{
+				"clusterset2": true,
+			},
+		},
+		{
+			name: "validate removing clusterset label without permission",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newManagedClusterObjWithClientSet("clusterset1"),
+				Object:    newManagedClusterObjWithClientSet(""),
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+					Message: "user \"\" cannot add/remove a ManagedCluster to/from ManagedClusterSet \"clusterset1\"",
+				},
+			},
+		},
 		{
 			name: "validate resetting clusterset label",
 			request: &admissionv1beta1.AdmissionRequest{
@@ -249,6 +479,237 @@ func TestManagedClusterValidate(t *testing.T) {
 				"clusterset1": true,
 			},
 		},
+		{
+			name:           "validate adding a system taint without permission",
+			expectedPolicy: audit.PolicySystemTaints,
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newManagedClusterObjWithTaints(),
+				Object:    newManagedClusterObjWithTaints(unreachableTaint),
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+					Message: "user \"\" cannot add/update/remove the system taints \"cluster.open-cluster-management.io/unavailable\" " +
+						"and \"cluster.open-cluster-management.io/unreachable\"",
+				},
+			},
+		},
+		{
+			name: "validate removing a system taint without permission",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newManagedClusterObjWithTaints(unreachableTaint, userTaint),
+				Object:    newManagedClusterObjWithTaints(userTaint),
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+					Message: "user \"\" cannot add/update/remove the system taints \"cluster.open-cluster-management.io/unavailable\" " +
+						"and \"cluster.open-cluster-management.io/unreachable\"",
+				},
+			},
+		},
+		{
+			name: "validate creating a managed cluster with a system taint without permission",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Create,
+				Object:    newManagedClusterObjWithTaints(unreachableTaint),
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+					Message: "user \"\" cannot add/update/remove the system taints \"cluster.open-cluster-management.io/unavailable\" " +
+						"and \"cluster.open-cluster-management.io/unreachable\"",
+				},
+			},
+		},
+		{
+			name: "validate removing a system taint with permission",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newManagedClusterObjWithTaints(unreachableTaint),
+				Object:    newManagedClusterObjWithTaints(),
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: true,
+			},
+			allowUpdateSystemTaints: true,
+		},
+		{
+			name: "validate updating user taints",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newManagedClusterObjWithTaints(unreachableTaint),
+				Object:    newManagedClusterObjWithTaints(userTaint, unreachableTaint),
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: true,
+			},
+		},
+		{
+			name: "validate changing the CA bundle of a joined managed cluster without force annotation",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newJoinedManagedClusterObj(false, clusterv1.ClientConfig{URL: "https://127.0.0.1:8001", CABundle: testCA1}),
+				Object:    newJoinedManagedClusterObj(false, clusterv1.ClientConfig{URL: "https://127.0.0.1:8001", CABundle: testCA2}),
+			},
+			expectedPolicy: audit.PolicyIdentityImmutability,
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+					Message: "the CA bundles of the client configs of a joined ManagedCluster cannot be changed unless " +
+						"the annotation \"cluster.open-cluster-management.io/force-identity-change=true\" is set",
+				},
+			},
+		},
+		{
+			name: "validate forcing the CA bundle change of a joined managed cluster without permission",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newJoinedManagedClusterObj(false, clusterv1.ClientConfig{URL: "https://127.0.0.1:8001", CABundle: testCA1}),
+				Object:    newJoinedManagedClusterObj(true),
+				UserInfo:  authenticationv1.UserInfo{Username: "tester"},
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+					Message: "user \"tester\" cannot force the change of the CA bundles of the client configs of a joined ManagedCluster",
+				},
+			},
+		},
+		{
+			name: "validate forcing the CA bundle change of a joined managed cluster with permission",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newJoinedManagedClusterObj(false, clusterv1.ClientConfig{URL: "https://127.0.0.1:8001", CABundle: testCA1}),
+				Object:    newJoinedManagedClusterObj(true, clusterv1.ClientConfig{URL: "https://127.0.0.1:8001", CABundle: testCA2}),
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: true,
+			},
+			allowChangeIdentity: true,
+		},
+		{
+			name: "validate changing the CA bundle of a joined managed cluster by its agent",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newJoinedManagedClusterObj(false, clusterv1.ClientConfig{URL: "https://127.0.0.1:8001", CABundle: testCA1}),
+				Object:    newJoinedManagedClusterObj(false, clusterv1.ClientConfig{URL: "https://127.0.0.1:8001", CABundle: testCA2}),
+				UserInfo: authenticationv1.UserInfo{
+					Username: "system:open-cluster-management:testmanagedcluster:agent1",
+					Groups:   []string{"system:open-cluster-management:testmanagedcluster"},
+				},
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: true,
+			},
+		},
+		{
+			name: "validate changing the CA bundle of a joined managed cluster by its agent service account",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newJoinedManagedClusterObj(false, clusterv1.ClientConfig{URL: "https://127.0.0.1:8001", CABundle: testCA1}),
+				Object:    newJoinedManagedClusterObj(false, clusterv1.ClientConfig{URL: "https://127.0.0.1:8001", CABundle: testCA2}),
+				UserInfo: authenticationv1.UserInfo{
+					Username: "system:serviceaccount:testmanagedcluster:registration-agent",
+					Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:testmanagedcluster"},
+				},
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: true,
+			},
+		},
+		{
+			name: "validate adding a client config to a joined managed cluster",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newJoinedManagedClusterObj(false, clusterv1.ClientConfig{URL: "https://127.0.0.1:8001", CABundle: testCA1}),
+				Object: newJoinedManagedClusterObj(false,
+					clusterv1.ClientConfig{URL: "https://127.0.0.1:8001", CABundle: testCA1},
+					clusterv1.ClientConfig{URL: "https://127.0.0.1:8002", CABundle: testCA2}),
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: true,
+			},
+		},
+		{
+			name: "validate moving a managed cluster out of a bound clusterset without force annotation",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newManagedClusterObjWithClientSet("clusterset1"),
+				Object:    newManagedClusterObjWithClientSet("clusterset2"),
+			},
+			clusterSetBindings: []runtime.Object{testinghelpers.NewManagedClusterSetBinding("ns1", "clusterset1")},
+			allowUpdateClusterSets: map[string]bool{
+				"clusterset1": true,
+				"clusterset2": true,
+			},
+			expectedPolicy: audit.PolicyIdentityImmutability,
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+					Message: "the clusterset label of a ManagedCluster in the bound ManagedClusterSet \"clusterset1\" cannot be changed unless " +
+						"the annotation \"cluster.open-cluster-management.io/force-identity-change=true\" is set",
+				},
+			},
+		},
+		{
+			name: "validate moving a managed cluster before the clusterset bindings are synced",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newManagedClusterObjWithClientSet("clusterset1"),
+				Object:    newManagedClusterObjWithClientSet("clusterset2"),
+			},
+			unsyncedBindings: true,
+			allowUpdateClusterSets: map[string]bool{
+				"clusterset1": true,
+				"clusterset2": true,
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status: metav1.StatusFailure, Code: http.StatusInternalServerError, Reason: metav1.StatusReasonInternalError,
+					Message: "the cache of ManagedClusterSetBindings is not synced yet",
+				},
+			},
+		},
+		{
+			name: "validate moving a managed cluster out of an unbound clusterset",
+			request: &admissionv1beta1.AdmissionRequest{
+				Resource:  managedclustersSchema,
+				Operation: admissionv1beta1.Update,
+				OldObject: newManagedClusterObjWithClientSet("clusterset1"),
+				Object:    newManagedClusterObjWithClientSet("clusterset2"),
+			},
+			clusterSetBindings: []runtime.Object{testinghelpers.NewManagedClusterSetBinding("ns1", "clusterset2")},
+			allowUpdateClusterSets: map[string]bool{
+				"clusterset1": true,
+				"clusterset2": true,
+			},
+			expectedResponse: &admissionv1beta1.AdmissionResponse{
+				Allowed: true,
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -258,12 +719,22 @@ func TestManagedClusterValidate(t *testing.T) {
 				"create",
 				"subjectaccessreviews",
 				func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+					if c.sarErr != nil {
+						return true, nil, c.sarErr
+					}
 					allowed := false
 
 					sar := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
 					switch sar.Spec.ResourceAttributes.Resource {
 					case "managedclusters":
-						allowed = c.allowUpdateAcceptField
+						switch sar.Spec.ResourceAttributes.Subresource {
+						case "accept":
+							allowed = c.allowUpdateAcceptField
+						case "systemtaints":
+							allowed = c.allowUpdateSystemTaints
+						case "identity":
+							allowed = c.allowChangeIdentity
+						}
 					case "managedclustersets":
 						allowed = c.allowUpdateClusterSets[sar.Spec.ResourceAttributes.Name]
 					}
@@ -276,10 +747,30 @@ func TestManagedClusterValidate(t *testing.T) {
 				},
 			)
 
-			admissionHook := &ManagedClusterValidatingAdmissionHook{kubeClient: kubeClient}
+			clusterClient := clusterfake.NewSimpleClientset(c.clusterSetBindings...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, 10*time.Minute)
+			bindingStore := clusterInformerFactory.Cluster().V1beta1().ManagedClusterSetBindings().Informer().GetStore()
+			for _, binding := range c.clusterSetBindings {
+				if err := bindingStore.Add(binding); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			admissionHook := &ManagedClusterValidatingAdmissionHook{
+				kubeClient:              kubeClient,
+				clusterSetBindingLister: clusterInformerFactory.Cluster().V1beta1().ManagedClusterSetBindings().Lister(),
+				clusterSetBindingSynced: func() bool { return !c.unsyncedBindings },
+				NamingPolicy:            c.namingPolicy,
+			}
 
 			actualResponse := admissionHook.Validate(c.request)
 
+			if len(c.expectedPolicy) > 0 && actualResponse.AuditAnnotations[audit.PolicyAnnotation] != c.expectedPolicy {
+				t.Errorf("expected policy %q, but got %q", c.expectedPolicy, actualResponse.AuditAnnotations[audit.PolicyAnnotation])
+			}
+			// the audit annotations are verified in the audit package
+			actualResponse.AuditAnnotations = nil
+
 			if !reflect.DeepEqual(actualResponse, c.expectedResponse) {
 				t.Errorf("expected %#v but got: %#v", c.expectedResponse.Result, actualResponse.Result)
 			}
@@ -295,6 +786,15 @@ func newManagedClusterObj() runtime.RawExtension {
 	}
 }
 
+func newManagedClusterObjWithName(name string) runtime.RawExtension {
+	managedCluster := testinghelpers.NewManagedCluster()
+	managedCluster.Name = name
+	clusterObj, _ := json.Marshal(managedCluster)
+	return runtime.RawExtension{
+		Raw: clusterObj,
+	}
+}
+
 func newManagedClusterObjWithHubAcceptsClient(hubAcceptsClient bool) runtime.RawExtension {
 	managedCluster := testinghelpers.NewManagedCluster()
 	managedCluster.Spec.HubAcceptsClient = hubAcceptsClient
@@ -304,9 +804,48 @@ func newManagedClusterObjWithHubAcceptsClient(hubAcceptsClient bool) runtime.Raw
 	}
 }
 
-func newManagedClusterObjWithClientConfigs(clientConfig clusterv1.ClientConfig) runtime.RawExtension {
+func newManagedClusterObjWithClientConfigs(clientConfigs ...clusterv1.ClientConfig) runtime.RawExtension {
 	managedCluster := testinghelpers.NewManagedCluster()
-	managedCluster.Spec.ManagedClusterClientConfigs = []clusterv1.ClientConfig{clientConfig}
+	managedCluster.Spec.ManagedClusterClientConfigs = clientConfigs
+	clusterObj, _ := json.Marshal(managedCluster)
+	return runtime.RawExtension{
+		Raw: clusterObj,
+	}
+}
+
+var (
+	testCA1 = testinghelpers.NewTestCert("ca1", time.Hour).Cert
+	testCA2 = testinghelpers.NewTestCert("ca2", time.Hour).Cert
+
+	unreachableTaint = clusterv1.Taint{
+		Key:    clusterv1.ManagedClusterTaintUnreachable,
+		Effect: clusterv1.TaintEffectNoSelect,
+	}
+
+	userTaint = clusterv1.Taint{
+		Key:    "key1",
+		Value:  "value1",
+		Effect: clusterv1.TaintEffectNoSelect,
+	}
+)
+
+func newManagedClusterObjWithTaints(taints ...clusterv1.Taint) runtime.RawExtension {
+	managedCluster := testinghelpers.NewManagedCluster()
+	managedCluster.Spec.Taints = taints
+	clusterObj, _ := json.Marshal(managedCluster)
+	return runtime.RawExtension{
+		Raw: clusterObj,
+	}
+}
+
+func newJoinedManagedClusterObj(forceIdentityChange bool, clientConfigs ...clusterv1.ClientConfig) runtime.RawExtension {
+	managedCluster := testinghelpers.NewJoinedManagedCluster()
+	managedCluster.Spec.ManagedClusterClientConfigs = clientConfigs
+	if forceIdentityChange {
+		managedCluster.Annotations = map[string]string{
+			ForceIdentityChangeAnnotation: "true",
+		}
+	}
 	clusterObj, _ := json.Marshal(managedCluster)
 	return runtime.RawExtension{
 		Raw: clusterObj,
@@ -316,7 +855,7 @@ func newManagedClusterObjWithClientConfigs(clientConfig clusterv1.ClientConfig)
 func newManagedClusterObjWithClientSet(clusterSetName string) runtime.RawExtension {
 	managedCluster := testinghelpers.NewManagedCluster()
 	managedCluster.Labels = map[string]string{
-		clusterSetLabel: clusterSetName,
+		helpers.ClusterSetLabel: clusterSetName,
 	}
 	clusterObj, _ := json.Marshal(managedCluster)
 	return runtime.RawExtension{
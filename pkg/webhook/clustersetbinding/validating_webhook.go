@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	authenticationv1 "k8s.io/api/authentication/v1"
@@ -15,6 +16,8 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	"open-cluster-management.io/registration/pkg/webhook/audit"
+	"open-cluster-management.io/registration/pkg/webhook/metrics"
 )
 
 // ManagedClusterSetBindingValidatingAdmissionHook will validate the creating/updating ManagedClusterSetBinding request.
@@ -35,34 +38,44 @@ func (a *ManagedClusterSetBindingValidatingAdmissionHook) ValidatingResource() (
 
 // Validate is called by generic-admission-server when the registered REST resource above is called with an admission request.
 func (a *ManagedClusterSetBindingValidatingAdmissionHook) Validate(admissionSpec *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	start := time.Now()
+	response := audit.Annotate(admissionSpec, a.validate(admissionSpec))
+	metrics.ObserveAdmission("managedclustersetbindingvalidators", admissionSpec, response, start)
+	return response
+}
+
+// validate validates the creating/updating ManagedClusterSetBinding request
+func (a *ManagedClusterSetBindingValidatingAdmissionHook) validate(admissionSpec *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
 	klog.V(4).Infof("validate %q operation for object %q", admissionSpec.Operation, admissionSpec.Object)
 
 	// only validate the request for ManagedClusterSetBinding
 	if admissionSpec.Resource.Group != "cluster.open-cluster-management.io" ||
 		admissionSpec.Resource.Resource != "managedclustersetbindings" {
-		return acceptRequest()
+		return audit.WithPolicy(acceptRequest(), audit.PolicyNotApplicable)
 	}
 
 	// only handle Create/Update Operation
 	if admissionSpec.Operation != admissionv1beta1.Create && admissionSpec.Operation != admissionv1beta1.Update {
-		return acceptRequest()
+		return audit.WithPolicy(acceptRequest(), audit.PolicyNotApplicable)
 	}
 
 	binding := &clusterv1beta1.ManagedClusterSetBinding{}
 	if err := json.Unmarshal(admissionSpec.Object.Raw, binding); err != nil {
-		return denyRequest(http.StatusBadRequest, metav1.StatusReasonBadRequest,
-			fmt.Sprintf("Unable to unmarshal the ManagedClusterSetBinding object: %v", err))
+		return audit.WithPolicy(denyRequest(http.StatusBadRequest, metav1.StatusReasonBadRequest,
+			fmt.Sprintf("Unable to unmarshal the ManagedClusterSetBinding object: %v", err)), audit.PolicyObjectValidation)
 	}
 
 	// force the instance name to match the target cluster set name
 	if binding.Name != binding.Spec.ClusterSet {
-		return denyRequest(http.StatusBadRequest, metav1.StatusReasonBadRequest,
-			"The ManagedClusterSetBinding must have the same name as the target ManagedClusterSet")
+		return audit.WithPolicy(denyRequest(http.StatusBadRequest, metav1.StatusReasonBadRequest,
+			"The ManagedClusterSetBinding must have the same name as the target ManagedClusterSet"), audit.PolicyClusterSetBindingName)
 	}
 
 	// check if the request user has permission to bind the target cluster set
 	if admissionSpec.Operation == admissionv1beta1.Create {
-		return a.allowBindingToClusterSet(binding.Spec.ClusterSet, admissionSpec.UserInfo)
+		if status := a.allowBindingToClusterSet(binding.Spec.ClusterSet, admissionSpec.UserInfo); !status.Allowed {
+			return audit.WithPolicy(status, audit.PolicyClusterSetBind)
+		}
 	}
 
 	return acceptRequest()
@@ -0,0 +1,164 @@
+// package conversion contains the conversion webhook of the ManagedClusterSet between the label-based v1alpha1
+// version and the selector-based v1beta1 version.
+package conversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+)
+
+// ManagedClusterSetConversionPath is the path on which the conversion webhook of ManagedClusterSet is served
+const ManagedClusterSetConversionPath = "/convert/managedclustersets"
+
+// ServeManagedClusterSetConversion handles the ConversionReview requests of ManagedClusterSets from the kube apiserver
+func ServeManagedClusterSetConversion(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	review := &apiextensionsv1.ConversionReview{}
+	if err := json.Unmarshal(body, review); err != nil {
+		http.Error(w, fmt.Sprintf("unable to decode the conversion review: %v", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "the conversion review has no request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = convertManagedClusterSets(review.Request)
+	review.Request = nil
+
+	data, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		klog.Errorf("failed to write the conversion review response: %v", err)
+	}
+}
+
+func convertManagedClusterSets(request *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+	response := &apiextensionsv1.ConversionResponse{
+		UID: request.UID,
+	}
+
+	for _, object := range request.Objects {
+		clusterSet := &unstructured.Unstructured{}
+		if err := clusterSet.UnmarshalJSON(object.Raw); err != nil {
+			response.Result = failure(err)
+			return response
+		}
+
+		converted, err := ConvertManagedClusterSet(clusterSet, request.DesiredAPIVersion)
+		if err != nil {
+			response.Result = failure(err)
+			return response
+		}
+
+		raw, err := converted.MarshalJSON()
+		if err != nil {
+			response.Result = failure(err)
+			return response
+		}
+		response.ConvertedObjects = append(response.ConvertedObjects, runtime.RawExtension{Raw: raw})
+	}
+
+	response.Result = metav1.Status{Status: metav1.StatusSuccess}
+	return response
+}
+
+// ClusterSelectorAnnotation keeps the cluster selector of a v1beta1 ManagedClusterSet, which is not the default
+// LegacyClusterSetLabel selector, while the ManagedClusterSet is served in v1alpha1, so the conversion is lossless.
+// The selector is restored from the annotation once the ManagedClusterSet is converted back to v1beta1.
+const ClusterSelectorAnnotation = "cluster.open-cluster-management.io/v1beta1-cluster-selector"
+
+// ConvertManagedClusterSet converts a ManagedClusterSet to the desired api version. Each ManagedClusterSet is
+// converted from its own api version, so the ManagedClusterSets in one request do not depend on each other. The
+// v1alpha1 ManagedClusterSets select the ManagedClusters by the clusterset label, which is equivalent to the
+// LegacyClusterSetLabel selector of v1beta1, any other selector is kept in ClusterSelectorAnnotation in v1alpha1.
+func ConvertManagedClusterSet(clusterSet *unstructured.Unstructured, desiredAPIVersion string) (*unstructured.Unstructured, error) {
+	converted := clusterSet.DeepCopy()
+	fromVersion := clusterSet.GetAPIVersion()
+	if fromVersion == desiredAPIVersion {
+		return converted, nil
+	}
+
+	v1alpha1Version := clusterv1alpha1.GroupVersion.String()
+	v1beta1Version := clusterv1beta1.GroupVersion.String()
+	switch {
+	case fromVersion == v1alpha1Version && desiredAPIVersion == v1beta1Version:
+		selector := map[string]interface{}{"selectorType": string(clusterv1beta1.LegacyClusterSetLabel)}
+		annotations := converted.GetAnnotations()
+		if data, ok := annotations[ClusterSelectorAnnotation]; ok {
+			if err := json.Unmarshal([]byte(data), &selector); err != nil {
+				return nil, fmt.Errorf("invalid annotation %q of ManagedClusterSet %q: %w",
+					ClusterSelectorAnnotation, clusterSet.GetName(), err)
+			}
+			delete(annotations, ClusterSelectorAnnotation)
+			if len(annotations) == 0 {
+				annotations = nil
+			}
+			converted.SetAnnotations(annotations)
+		}
+		if err := unstructured.SetNestedMap(converted.Object, selector, "spec", "clusterSelector"); err != nil {
+			return nil, err
+		}
+	case fromVersion == v1beta1Version && desiredAPIVersion == v1alpha1Version:
+		selector, found, err := unstructured.NestedMap(converted.Object, "spec", "clusterSelector")
+		if err != nil {
+			return nil, err
+		}
+		if found && !isLegacyClusterSetLabelSelector(selector) {
+			data, err := json.Marshal(selector)
+			if err != nil {
+				return nil, err
+			}
+			annotations := converted.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[ClusterSelectorAnnotation] = string(data)
+			converted.SetAnnotations(annotations)
+		}
+		unstructured.RemoveNestedField(converted.Object, "spec", "clusterSelector")
+	default:
+		return nil, fmt.Errorf("unexpected conversion of ManagedClusterSet %q from %s to %s",
+			clusterSet.GetName(), fromVersion, desiredAPIVersion)
+	}
+
+	converted.SetAPIVersion(desiredAPIVersion)
+	return converted, nil
+}
+
+// isLegacyClusterSetLabelSelector returns true if the selector is the LegacyClusterSetLabel selector without any
+// other field, which is implied by a v1alpha1 ManagedClusterSet
+func isLegacyClusterSetLabelSelector(selector map[string]interface{}) bool {
+	for key, value := range selector {
+		if key != "selectorType" || value != string(clusterv1beta1.LegacyClusterSetLabel) {
+			return false
+		}
+	}
+	return true
+}
+
+func failure(err error) metav1.Status {
+	return metav1.Status{
+		Status:  metav1.StatusFailure,
+		Message: err.Error(),
+	}
+}
@@ -0,0 +1,188 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	v1alpha1Version = "cluster.open-cluster-management.io/v1alpha1"
+	v1beta1Version  = "cluster.open-cluster-management.io/v1beta1"
+)
+
+func TestConvertManagedClusterSet(t *testing.T) {
+	cases := []struct {
+		name              string
+		clusterSet        *unstructured.Unstructured
+		desiredAPIVersion string
+		expectedErr       bool
+		expected          *unstructured.Unstructured
+	}{
+		{
+			name:              "same version",
+			clusterSet:        newClusterSet(v1beta1Version, "LegacyClusterSetLabel"),
+			desiredAPIVersion: v1beta1Version,
+			expected:          newClusterSet(v1beta1Version, "LegacyClusterSetLabel"),
+		},
+		{
+			name:              "v1alpha1 to v1beta1",
+			clusterSet:        newClusterSet(v1alpha1Version, ""),
+			desiredAPIVersion: v1beta1Version,
+			expected:          newClusterSet(v1beta1Version, "LegacyClusterSetLabel"),
+		},
+		{
+			name:              "v1beta1 to v1alpha1",
+			clusterSet:        newClusterSet(v1beta1Version, "LegacyClusterSetLabel"),
+			desiredAPIVersion: v1alpha1Version,
+			expected:          newClusterSet(v1alpha1Version, ""),
+		},
+		{
+			name:              "v1beta1 without selector to v1alpha1",
+			clusterSet:        newClusterSet(v1beta1Version, ""),
+			desiredAPIVersion: v1alpha1Version,
+			expected:          newClusterSet(v1alpha1Version, ""),
+		},
+		{
+			name:              "v1beta1 with label selector to v1alpha1",
+			clusterSet:        newLabelSelectorClusterSet(v1beta1Version),
+			desiredAPIVersion: v1alpha1Version,
+			expected: withAnnotation(newClusterSet(v1alpha1Version, ""),
+				`{"labelSelector":{"matchLabels":{"vendor":"OpenShift"}},"selectorType":"LabelSelector"}`),
+		},
+		{
+			name: "v1alpha1 with selector annotation to v1beta1",
+			clusterSet: withAnnotation(newClusterSet(v1alpha1Version, ""),
+				`{"labelSelector":{"matchLabels":{"vendor":"OpenShift"}},"selectorType":"LabelSelector"}`),
+			desiredAPIVersion: v1beta1Version,
+			expected:          newLabelSelectorClusterSet(v1beta1Version),
+		},
+		{
+			name:              "v1alpha1 with invalid selector annotation to v1beta1",
+			clusterSet:        withAnnotation(newClusterSet(v1alpha1Version, ""), "invalid"),
+			desiredAPIVersion: v1beta1Version,
+			expectedErr:       true,
+		},
+		{
+			name:              "unknown version",
+			clusterSet:        newClusterSet(v1beta1Version, "LegacyClusterSetLabel"),
+			desiredAPIVersion: "cluster.open-cluster-management.io/v1",
+			expectedErr:       true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			converted, err := ConvertManagedClusterSet(c.clusterSet, c.desiredAPIVersion)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but got nil")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if c.expected != nil && !reflect.DeepEqual(converted, c.expected) {
+				t.Errorf("expected %v, but got %v", c.expected, converted)
+			}
+		})
+	}
+}
+
+func TestServeManagedClusterSetConversion(t *testing.T) {
+	clusterSetData, err := newClusterSet(v1alpha1Version, "").MarshalJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	review := &apiextensionsv1.ConversionReview{
+		Request: &apiextensionsv1.ConversionRequest{
+			UID:               "uid1",
+			DesiredAPIVersion: v1beta1Version,
+			Objects:           []runtime.RawExtension{{Raw: clusterSetData}},
+		},
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	ServeManagedClusterSetConversion(recorder, httptest.NewRequest(http.MethodPost, ManagedClusterSetConversionPath, bytes.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status code 200, but got %d", recorder.Code)
+	}
+
+	actual := &apiextensionsv1.ConversionReview{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), actual); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual.Response.UID != "uid1" || actual.Response.Result.Status != metav1.StatusSuccess {
+		t.Errorf("unexpected response %#v", actual.Response)
+	}
+	if len(actual.Response.ConvertedObjects) != 1 {
+		t.Fatalf("expected 1 converted object, but got %d", len(actual.Response.ConvertedObjects))
+	}
+
+	converted := &unstructured.Unstructured{}
+	if err := converted.UnmarshalJSON(actual.Response.ConvertedObjects[0].Raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(converted, newClusterSet(v1beta1Version, "LegacyClusterSetLabel")) {
+		t.Errorf("unexpected converted object %v", converted)
+	}
+}
+
+func TestConvertManagedClusterSetRoundTrip(t *testing.T) {
+	clusterSets := []*unstructured.Unstructured{
+		newClusterSet(v1beta1Version, "LegacyClusterSetLabel"),
+		newLabelSelectorClusterSet(v1beta1Version),
+	}
+	for _, clusterSet := range clusterSets {
+		v1alpha1ClusterSet, err := ConvertManagedClusterSet(clusterSet, v1alpha1Version)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		v1beta1ClusterSet, err := ConvertManagedClusterSet(v1alpha1ClusterSet, v1beta1Version)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(v1beta1ClusterSet, clusterSet) {
+			t.Errorf("expected %v after the round trip, but got %v", clusterSet, v1beta1ClusterSet)
+		}
+	}
+}
+
+func newLabelSelectorClusterSet(apiVersion string) *unstructured.Unstructured {
+	clusterSet := newClusterSet(apiVersion, "LabelSelector")
+	_ = unstructured.SetNestedStringMap(clusterSet.Object, map[string]string{"vendor": "OpenShift"},
+		"spec", "clusterSelector", "labelSelector", "matchLabels")
+	return clusterSet
+}
+
+func withAnnotation(clusterSet *unstructured.Unstructured, selector string) *unstructured.Unstructured {
+	clusterSet.SetAnnotations(map[string]string{ClusterSelectorAnnotation: selector})
+	return clusterSet
+}
+
+func newClusterSet(apiVersion, selectorType string) *unstructured.Unstructured {
+	clusterSet := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       "ManagedClusterSet",
+			"metadata": map[string]interface{}{
+				"name": "clusterset1",
+			},
+			"spec": map[string]interface{}{},
+		},
+	}
+	if len(selectorType) > 0 {
+		_ = unstructured.SetNestedField(clusterSet.Object, selectorType, "spec", "clusterSelector", "selectorType")
+	}
+	return clusterSet
+}
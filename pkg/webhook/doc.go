@@ -1,2 +1,7 @@
 // package webhook contains the managed cluster admission hooks to mutate and validate the ManagedCluster create and update operations
+//
+// The admission hooks have no side effects, so they handle the dry-run requests in the same way as the other
+// requests: the SubjectAccessReviews they create to check the permissions of the request users are not
+// persisted. The responses carry audit annotations describing which policy rule allowed or denied the request,
+// see package audit.
 package webhook
@@ -0,0 +1,65 @@
+// package metrics contains the metrics of the admission hooks of the registration webhook
+package metrics
+
+import (
+	"time"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const subsystem = "open_cluster_management_registration_webhook"
+
+var (
+	admissionTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "admission_total",
+			Help:      "Number of admission requests handled by the registration webhook, partitioned by webhook, operation and whether the request is allowed.",
+		},
+		[]string{"webhook", "operation", "allowed"},
+	)
+
+	admissionDeniedTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "admission_denied_total",
+			Help:      "Number of admission requests denied by the registration webhook, partitioned by webhook, operation and reason.",
+		},
+		[]string{"webhook", "operation", "reason"},
+	)
+
+	admissionDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "admission_duration_seconds",
+			Help:      "Latency of the admission requests handled by the registration webhook, partitioned by webhook and operation.",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+		},
+		[]string{"webhook", "operation"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(admissionTotal, admissionDeniedTotal, admissionDuration)
+}
+
+// ObserveAdmission records the result and the latency of an admission request handled by the webhook
+func ObserveAdmission(webhook string, request *admissionv1beta1.AdmissionRequest, response *admissionv1beta1.AdmissionResponse, start time.Time) {
+	operation := string(request.Operation)
+	allowed := response != nil && response.Allowed
+
+	admissionDuration.WithLabelValues(webhook, operation).Observe(time.Since(start).Seconds())
+	if allowed {
+		admissionTotal.WithLabelValues(webhook, operation, "true").Inc()
+		return
+	}
+
+	admissionTotal.WithLabelValues(webhook, operation, "false").Inc()
+	reason := "Unknown"
+	if response != nil && response.Result != nil && len(response.Result.Reason) > 0 {
+		reason = string(response.Result.Reason)
+	}
+	admissionDeniedTotal.WithLabelValues(webhook, operation, reason).Inc()
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestObserveAdmission(t *testing.T) {
+	cases := []struct {
+		name             string
+		webhook          string
+		operation        admissionv1beta1.Operation
+		response         *admissionv1beta1.AdmissionResponse
+		expectedAllowed  string
+		expectedReason   string
+		expectedDenied   float64
+		expectedObserved uint64
+	}{
+		{
+			name:             "allowed request",
+			webhook:          "webhook1",
+			operation:        admissionv1beta1.Create,
+			response:         &admissionv1beta1.AdmissionResponse{Allowed: true},
+			expectedAllowed:  "true",
+			expectedObserved: 1,
+		},
+		{
+			name:      "denied request",
+			webhook:   "webhook2",
+			operation: admissionv1beta1.Update,
+			response: &admissionv1beta1.AdmissionResponse{
+				Result: &metav1.Status{Reason: metav1.StatusReasonForbidden},
+			},
+			expectedAllowed:  "false",
+			expectedReason:   string(metav1.StatusReasonForbidden),
+			expectedDenied:   1,
+			expectedObserved: 1,
+		},
+		{
+			name:             "denied request without reason",
+			webhook:          "webhook3",
+			operation:        admissionv1beta1.Create,
+			response:         &admissionv1beta1.AdmissionResponse{},
+			expectedAllowed:  "false",
+			expectedReason:   "Unknown",
+			expectedDenied:   1,
+			expectedObserved: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			request := &admissionv1beta1.AdmissionRequest{Operation: c.operation}
+			ObserveAdmission(c.webhook, request, c.response, time.Now())
+
+			total, err := testutil.GetCounterMetricValue(admissionTotal.WithLabelValues(c.webhook, string(c.operation), c.expectedAllowed))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if total != 1 {
+				t.Errorf("expected 1 admission, but got %v", total)
+			}
+
+			if len(c.expectedReason) > 0 {
+				denied, err := testutil.GetCounterMetricValue(admissionDeniedTotal.WithLabelValues(c.webhook, string(c.operation), c.expectedReason))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if denied != c.expectedDenied {
+					t.Errorf("expected %v denied admissions, but got %v", c.expectedDenied, denied)
+				}
+			}
+
+			observed, err := testutil.GetHistogramMetricCount(admissionDuration.WithLabelValues(c.webhook, string(c.operation)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if observed != c.expectedObserved {
+				t.Errorf("expected %d observations, but got %d", c.expectedObserved, observed)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package webhook
+
+import (
+	"os"
+	"regexp"
+
+	admissionserver "github.com/openshift/generic-admission-server/pkg/cmd/server"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+
+	clusterwebhook "open-cluster-management.io/registration/pkg/webhook/cluster"
+	clustersetbindingwebhook "open-cluster-management.io/registration/pkg/webhook/clustersetbinding"
+	"open-cluster-management.io/registration/pkg/webhook/conversion"
+)
+
+// WebhookOptions holds the configuration of the registration webhook server. The server is run separately from
+// the hub controllers, so it has its own serving, TLS and feature gate options.
+type WebhookOptions struct {
+	ServerOptions *admissionserver.AdmissionServerOptions
+
+	DefaultTaints      []string
+	ClusterNamePattern string
+
+	validatingAdmissionHook *clusterwebhook.ManagedClusterValidatingAdmissionHook
+	mutatingAdmissionHook   *clusterwebhook.ManagedClusterMutatingAdmissionHook
+}
+
+// NewWebhookOptions returns a WebhookOptions
+func NewWebhookOptions() *WebhookOptions {
+	validatingAdmissionHook := &clusterwebhook.ManagedClusterValidatingAdmissionHook{}
+	mutatingAdmissionHook := &clusterwebhook.ManagedClusterMutatingAdmissionHook{}
+
+	serverOptions := admissionserver.NewAdmissionServerOptions(
+		os.Stdout,
+		os.Stderr,
+		validatingAdmissionHook,
+		mutatingAdmissionHook,
+		&clustersetbindingwebhook.ManagedClusterSetBindingValidatingAdmissionHook{})
+	// the kube apiserver calls the conversion webhook without credentials, and the conversion has no side effects
+	serverOptions.RecommendedOptions.Authorization = serverOptions.RecommendedOptions.Authorization.
+		WithAlwaysAllowPaths(conversion.ManagedClusterSetConversionPath)
+
+	return &WebhookOptions{
+		ServerOptions:           serverOptions,
+		validatingAdmissionHook: validatingAdmissionHook,
+		mutatingAdmissionHook:   mutatingAdmissionHook,
+	}
+}
+
+// AddFlags registers flags for the webhook server
+func (o *WebhookOptions) AddFlags(flags *pflag.FlagSet) {
+	featureGate := utilfeature.DefaultMutableFeatureGate
+	featureGate.AddFlag(flags)
+	o.ServerOptions.RecommendedOptions.FeatureGate = featureGate
+
+	flags.StringVar(&o.mutatingAdmissionHook.DefaultClusterSetName, "default-clusterset", "default",
+		"The clusterset which the managed clusters without clusterset label are added to when the DefaultClusterSet feature is enabled.")
+	flags.StringSliceVar(&o.DefaultTaints, "default-taints", o.DefaultTaints,
+		"The taints added to the newly created managed clusters, in the format of key=value:effect or key:effect.")
+	flags.StringVar(&o.ClusterNamePattern, "cluster-name-pattern", o.ClusterNamePattern,
+		"The regular expression which the names of the newly created managed clusters must match.")
+	flags.BoolVar(&o.validatingAdmissionHook.NamingPolicy.EnforceDNS1123, "enforce-dns1123-cluster-name", true,
+		"Require the names of the newly created managed clusters to be valid DNS-1123 labels.")
+	flags.StringSliceVar(&o.validatingAdmissionHook.NamingPolicy.ReservedPrefixes, "reserved-cluster-name-prefixes", []string{},
+		"The prefixes which the names of the newly created managed clusters are not allowed to start with.")
+
+	o.ServerOptions.RecommendedOptions.AddFlags(flags)
+}
+
+// Complete applies the options to the admission hooks. The errors of all of the invalid flags are aggregated,
+// and the field paths of the errors are named after the flags.
+func (o *WebhookOptions) Complete() error {
+	errs := field.ErrorList{}
+
+	taints, err := clusterwebhook.ParseTaints(o.DefaultTaints)
+	if err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("default-taints"), o.DefaultTaints, err.Error()))
+	}
+	o.mutatingAdmissionHook.DefaultTaints = taints
+
+	if len(o.ClusterNamePattern) > 0 {
+		pattern, err := regexp.Compile(o.ClusterNamePattern)
+		if err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("cluster-name-pattern"), o.ClusterNamePattern, err.Error()))
+		}
+		o.validatingAdmissionHook.NamingPolicy.Pattern = pattern
+	}
+
+	if len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+	return o.ServerOptions.Complete()
+}
+
+// RunWebhookServer starts the webhook server, it blocks until the stopCh is closed. Besides the admission hooks,
+// the server serves the conversion webhook of ManagedClusterSet.
+func (o *WebhookOptions) RunWebhookServer(args []string, stopCh <-chan struct{}) error {
+	if err := o.ServerOptions.Validate(args); err != nil {
+		return err
+	}
+
+	config, err := o.ServerOptions.Config()
+	if err != nil {
+		return err
+	}
+
+	server, err := config.Complete().New()
+	if err != nil {
+		return err
+	}
+	server.GenericAPIServer.Handler.NonGoRestfulMux.HandleFunc(
+		conversion.ManagedClusterSetConversionPath, conversion.ServeManagedClusterSetConversion)
+
+	return server.GenericAPIServer.PrepareRun().Run(stopCh)
+}
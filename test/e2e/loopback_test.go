@@ -629,6 +629,22 @@ func spokeCR(suffix string) (*unstructured.Unstructured, error) {
 	if err != nil {
 		return nil, err
 	}
+	// the loopback agents register the addons, so the rules of the addon clusterrole are granted along with it
+	addOnCR, err := assetToUnstructured("spoke/addon_clusterrole.yaml")
+	if err != nil {
+		return nil, err
+	}
+	rules, _, err := unstructured.NestedSlice(cr.Object, "rules")
+	if err != nil {
+		return nil, err
+	}
+	addOnRules, _, err := unstructured.NestedSlice(addOnCR.Object, "rules")
+	if err != nil {
+		return nil, err
+	}
+	if err := unstructured.SetNestedSlice(cr.Object, append(rules, addOnRules...), "rules"); err != nil {
+		return nil, err
+	}
 	name := cr.GetName()
 	name = fmt.Sprintf("%v-%v", name, suffix)
 	cr.SetName(name)
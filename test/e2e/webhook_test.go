@@ -200,7 +200,7 @@ var _ = ginkgo.Describe("Admission webhook", func() {
 				gomega.Expect(err).To(gomega.HaveOccurred())
 				gomega.Expect(errors.IsBadRequest(err)).Should(gomega.BeTrue())
 				gomega.Expect(err.Error()).Should(gomega.Equal(fmt.Sprintf(
-					"admission webhook \"%s\" denied the request: url \"%s\" is invalid in client configs",
+					"admission webhook \"%s\" denied the request: url \"%s\" is invalid in client configs, it must be an https url",
 					admissionName,
 					invalidURL,
 				)))
@@ -447,7 +447,7 @@ var _ = ginkgo.Describe("Admission webhook", func() {
 				gomega.Expect(err).To(gomega.HaveOccurred())
 				gomega.Expect(errors.IsBadRequest(err)).Should(gomega.BeTrue())
 				gomega.Expect(err.Error()).Should(gomega.Equal(fmt.Sprintf(
-					"admission webhook \"%s\" denied the request: url \"%s\" is invalid in client configs",
+					"admission webhook \"%s\" denied the request: url \"%s\" is invalid in client configs, it must be an https url",
 					admissionName,
 					invalidURL,
 				)))
@@ -578,7 +578,8 @@ var _ = ginkgo.Describe("Admission webhook", func() {
 				})
 				gomega.Expect(err).To(gomega.HaveOccurred())
 				gomega.Expect(errors.IsForbidden(err)).Should(gomega.BeTrue())
-				gomega.Expect(err.Error()).Should(gomega.Equal(fmt.Sprintf(
+				// the message also explains the move if the managed cluster is in the default clusterset
+				gomega.Expect(err.Error()).Should(gomega.HavePrefix(fmt.Sprintf(
 					"admission webhook \"%s\" denied the request: user \"system:serviceaccount:%s:%s\" cannot add/remove a ManagedCluster to/from ManagedClusterSet \"%s\"",
 					admissionName,
 					saNamespace,
@@ -81,8 +81,9 @@ var _ = ginkgo.Describe("Disaster Recovery", func() {
 		// start hub controller
 		go func() {
 			err := hub.RunControllerManager(ctx, &controllercmd.ControllerContext{
-				KubeConfig:    cfg,
-				EventRecorder: util.NewIntegrationTestEventRecorder("hub"),
+				KubeConfig:        cfg,
+				OperatorNamespace: "open-cluster-management-hub",
+				EventRecorder:     util.NewIntegrationTestEventRecorder("hub"),
 			})
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 		}()
@@ -4,4 +4,5 @@
 // - registration agent rotate its certificate after its certificate is expired
 // - registration agent recovery from invalid bootstrap kubeconfig
 // - registration agent recovery from invalid hub kubeconfig
+// - registration agent joins the fake hub of pkg/helpers/testing/fakehub
 package integration
@@ -0,0 +1,51 @@
+package integration_test
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+
+	"open-cluster-management.io/registration/pkg/helpers/testing/fakehub"
+	"open-cluster-management.io/registration/pkg/spoke"
+	"open-cluster-management.io/registration/test/integration/util"
+)
+
+var _ = ginkgo.Describe("Fake Hub", func() {
+	ginkgo.It("managedcluster should join the fake hub and renew its lease", func() {
+		managedClusterName := "fakehubtest-managedcluster"
+		bootstrapFile := path.Join(util.TestDir, "fakehubtest", "bootstrap", "kubeconfig")
+		hubKubeconfigDir := path.Join(util.TestDir, "fakehubtest", "hub-kubeconfig")
+
+		hub, err := fakehub.Start(context.Background(), fakehub.Options{
+			CRDDirectoryPaths:    []string{filepath.Join(".", "deploy", "hub")},
+			LeaseDurationSeconds: util.TestLeaseDurationSeconds,
+		})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		defer func() {
+			gomega.Expect(hub.Stop()).NotTo(gomega.HaveOccurred())
+		}()
+
+		err = hub.WriteBootstrapKubeconfig(bootstrapFile)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		// run registration agent against the fake hub, the csr is signed and the cluster is accepted automatically
+		agentOptions := spoke.SpokeAgentOptions{
+			ClusterName:              managedClusterName,
+			BootstrapKubeconfig:      bootstrapFile,
+			HubKubeconfigSecret:      "fakehubtest-hub-kubeconfig-secret",
+			HubKubeconfigDir:         hubKubeconfigDir,
+			ClusterHealthCheckPeriod: 1 * time.Minute,
+		}
+		cancel := util.RunAgent("fakehubtest", agentOptions, spokeCfg)
+		defer cancel()
+
+		waitCtx, waitCancel := context.WithTimeout(context.Background(), eventuallyTimeout*time.Second)
+		defer waitCancel()
+		err = hub.WaitForLeaseRenewal(waitCtx, managedClusterName, time.Now())
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+})
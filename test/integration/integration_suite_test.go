@@ -158,8 +158,9 @@ var _ = ginkgo.BeforeSuite(func(done ginkgo.Done) {
 	// start hub controller
 	go func() {
 		err := hub.RunControllerManager(ctx, &controllercmd.ControllerContext{
-			KubeConfig:    cfg,
-			EventRecorder: util.NewIntegrationTestEventRecorder("hub"),
+			KubeConfig:        cfg,
+			OperatorNamespace: "open-cluster-management-hub",
+			EventRecorder:     util.NewIntegrationTestEventRecorder("hub"),
 		})
 		gomega.Expect(err).NotTo(gomega.HaveOccurred())
 	}()